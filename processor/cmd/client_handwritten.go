@@ -0,0 +1,14 @@
+//go:build !openapi
+
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/processor"
+)
+
+// newLeadAPIClient builds the hand-written API client wrapped for the processor.
+// Build with -tags openapi to use the client in internal/api/openapi instead.
+func newLeadAPIClient(apiURL string, opts ...api.ClientOption) processor.APIClient {
+	return &APIClientAdapter{client: api.NewAPIClient(apiURL, opts...)}
+}
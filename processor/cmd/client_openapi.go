@@ -0,0 +1,154 @@
+//go:build openapi
+
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/api/openapi"
+	"code/internal/errcode"
+	"code/internal/models"
+	"code/internal/processor"
+	"context"
+	"fmt"
+)
+
+// newLeadAPIClient builds the client generated from api/openapi.yaml. It shares
+// the hand-written client's HTTP transport so the middleware chain still applies.
+func newLeadAPIClient(apiURL string, opts ...api.ClientOption) processor.APIClient {
+	base := api.NewAPIClient(apiURL, opts...)
+
+	client, err := openapi.NewClientWithResponses(base.BaseURL(), openapi.WithHTTPClient(base.HTTPClient()))
+	if err != nil {
+		LogError("Failed to create OpenAPI client, falling back to hand-written client", err, "apiURL", apiURL)
+		return &APIClientAdapter{client: api.NewAPIClient(apiURL, opts...)}
+	}
+
+	return &OpenAPIClientAdapter{client: client, rawSource: base.RawSource()}
+}
+
+// OpenAPIClientAdapter adapts the openapi.ClientWithResponses to the processor.APIClient interface
+type OpenAPIClientAdapter struct {
+	client    *openapi.ClientWithResponses
+	rawSource bool
+}
+
+func (a *OpenAPIClientAdapter) LookupLead(email string) (*processor.LookupResponse, error) {
+	resp, err := a.client.LookupLeadWithResponse(context.Background(), &openapi.LookupLeadParams{Email: email})
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	if resp.JSON200 == nil {
+		return nil, &errcode.StatusError{Status: resp.StatusCode()}
+	}
+
+	return &processor.LookupResponse{
+		Found: resp.JSON200.Found,
+		Lead:  convertOpenAPIToProcessorLead(resp.JSON200.Lead),
+	}, nil
+}
+
+func (a *OpenAPIClientAdapter) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	input := toLeadInput(lead)
+	if lead.ID != "" {
+		input.ClientId = &lead.ID
+	}
+	if a.rawSource && len(lead.Raw) > 0 {
+		input.RawSource = &lead.Raw
+	}
+	resp, err := a.client.CreateLeadWithResponse(context.Background(), input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	if resp.JSON201 == nil {
+		return nil, &errcode.StatusError{Status: resp.StatusCode()}
+	}
+
+	return convertOpenAPIToProcessorLead(resp.JSON201.Lead), nil
+}
+
+func (a *OpenAPIClientAdapter) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	resp, err := a.client.UpdateLeadWithResponse(context.Background(), toLeadInput(lead))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	if resp.JSON200 == nil {
+		return nil, &errcode.StatusError{Status: resp.StatusCode()}
+	}
+
+	return convertOpenAPIToProcessorLead(resp.JSON200.Lead), nil
+}
+
+func toLeadInput(lead *models.Lead) openapi.LeadInput {
+	source := openapi.Source(lead.Source)
+	input := openapi.LeadInput{
+		Name:    &lead.Name,
+		Email:   lead.Email,
+		Company: &lead.Company,
+		Source:  &source,
+	}
+	if lead.Status != "" {
+		status := openapi.Status(lead.Status)
+		input.Status = &status
+	}
+	if lead.Territory != "" {
+		input.Territory = &lead.Territory
+	}
+	if lead.Language != "" {
+		input.Language = &lead.Language
+	}
+	if lead.HasConsentDetails() {
+		input.ConsentGiven = &lead.ConsentGiven
+		input.ConsentTimestamp = lead.ConsentTimestamp
+		input.ConsentSource = &lead.ConsentSource
+	}
+	return input
+}
+
+func convertOpenAPIToProcessorLead(apiLead *openapi.Lead) *models.Lead {
+	if apiLead == nil {
+		return nil
+	}
+
+	var status string
+	if apiLead.Status != nil {
+		status = string(*apiLead.Status)
+	}
+	var consentGiven bool
+	if apiLead.ConsentGiven != nil {
+		consentGiven = *apiLead.ConsentGiven
+	}
+	var consentSource string
+	if apiLead.ConsentSource != nil {
+		consentSource = *apiLead.ConsentSource
+	}
+	var territory string
+	if apiLead.Territory != nil {
+		territory = *apiLead.Territory
+	}
+	var language string
+	if apiLead.Language != nil {
+		language = *apiLead.Language
+	}
+	var owner string
+	if apiLead.Owner != nil {
+		owner = *apiLead.Owner
+	}
+
+	return &models.Lead{
+		ID:        apiLead.Id,
+		Name:      apiLead.Name,
+		Email:     apiLead.Email,
+		Company:   apiLead.Company,
+		Source:    string(apiLead.Source),
+		Status:    status,
+		CreatedAt: apiLead.CreatedAt,
+		UpdatedAt: apiLead.UpdatedAt,
+		Owner:     owner,
+		Territory: territory,
+		Language:  language,
+
+		ConsentGiven:     consentGiven,
+		ConsentTimestamp: apiLead.ConsentTimestamp,
+		ConsentSource:    consentSource,
+	}
+}
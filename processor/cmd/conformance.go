@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/conformance"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Check that a lead API answers the way lead-processor expects",
+	Long: `Run a suite of requests against the API at --api-url and report which parts
+of the contract it follows: lookups of unknown and known emails, creates,
+refused duplicate creates, updates, updates of unknown emails, validation
+errors, the error format and how it rate-limits. Run it against a new CRM
+backend deployment before switching traffic to it.
+
+The suite creates and updates one test lead with a random example.com email,
+and deletes it again where the API allows. Requests are sent without retries or
+pacing, so the rate-limit check sees the API's own 429 answers. Exits non-zero
+if any check fails.`,
+	Example: `  # Check a staging deployment
+  lead-processor conformance --api-url https://leads.staging.example.com --config staging.yaml
+
+  # Skip the burst of lookups, e.g. against production
+  lead-processor conformance --api-url https://leads.example.com --burst 0`,
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+	RunE:    runConformanceCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+	conformanceCmd.Flags().Int("burst", 50, "Lookups sent at once to provoke a 429 (0 skips the rate-limit check)")
+	conformanceCmd.Flags().String("format", reportText, "Report format: text or json")
+	conformanceCmd.Flags().Duration("timeout", 10*time.Second, "Timeout of each request")
+
+	_ = conformanceCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{reportText, reportJSON}, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runConformanceCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	burst, _ := cmd.Flags().GetInt("burst")
+	format, _ := cmd.Flags().GetString("format")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	if format != reportText && format != reportJSON {
+		return fmt.Errorf("invalid --format %q (use text or json)", format)
+	}
+	if burst < 0 {
+		return fmt.Errorf("invalid --burst: cannot be negative")
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Only the credentials are added; retries and pacing would hide the
+	// API's own behaviour
+	transport, baseURL := api.BaseTransport(apiURL)
+	if debugHTTP {
+		transport = api.DebugMiddleware(os.Stderr)(transport)
+	}
+	if cfg.APIToken != "" {
+		transport = api.BearerAuthMiddleware(cfg.APIToken)(transport)
+	}
+	client := &http.Client{Timeout: timeout, Transport: transport}
+
+	LogInfo("Running conformance suite", "apiURL", apiURL, "burst", burst)
+	report := conformance.New(client, baseURL, conformance.Options{Burst: burst}).Run()
+
+	if format == reportJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printer.Printf("Conformance of %s\n", report.Target)
+		for _, result := range report.Results {
+			marker := symbols.ok
+			switch result.Status {
+			case conformance.StatusSkip:
+				marker = symbols.warn
+			case conformance.StatusFail:
+				marker = symbols.fail
+			}
+			fmt.Printf("%s %s: %s\n", marker, result.Name, result.Message)
+		}
+		printer.Printf("\n%d passed, %d failed, %d skipped\n", report.Count(conformance.StatusPass), report.Count(conformance.StatusFail), report.Count(conformance.StatusSkip))
+	}
+
+	// A failed check is a finding about the API, not a usage mistake
+	cmd.SilenceUsage = true
+	if failed := report.Count(conformance.StatusFail); failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
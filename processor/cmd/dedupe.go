@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"code/internal/csv"
+	"code/internal/dedupe"
+	"code/internal/history"
+	"code/internal/mirror"
+	"code/internal/models"
+	"code/internal/processor"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Inspect or rebuild the index of leads processed in earlier runs",
+	Long: `process keeps a dedupe index next to the run history: for every lead it
+processed successfully, by normalized email, the CRM's lead ID, the content
+hash, the last action and when it was last seen. It answers --skip-seen,
+delta --against-index and dedupe check without reading the whole lead history
+or calling the API.`,
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+}
+
+var dedupeShowCmd = &cobra.Command{
+	Use:   "show [email...]",
+	Short: "Summarize the dedupe index, or show what it knows of given leads",
+	Example: `  # How many leads the index holds, by last action
+  lead-processor dedupe show
+
+  # When a lead was last processed, and its lead ID
+  lead-processor dedupe show jane@example.com`,
+	RunE: runDedupeShowCommand,
+}
+
+var dedupeRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the dedupe index from the run history",
+	Long: `Rebuild the dedupe index from the latest record of every lead in the run
+history, e.g. after the index file was lost or damaged. process builds it the
+same way the first time it runs without one.
+
+The run history doesn't keep lead IDs, so the rebuilt index only has those
+known from the index it replaces; with --mirror, the rest are filled in from
+the lead mirror kept by sync snapshot.`,
+	Example: `  # Rebuild the index, taking lead IDs from the mirror
+  lead-processor dedupe rebuild --mirror leads-mirror.json`,
+	Args: cobra.NoArgs,
+	RunE: runDedupeRebuildCommand,
+}
+
+var dedupeCheckCmd = &cobra.Command{
+	Use:   "check <file>",
+	Short: "List the leads of a file already processed in earlier runs, without the API",
+	Long: `Check a lead file against the dedupe index, offline: each lead processed in
+an earlier run is listed with when and how, and whether its content changed
+since, as are rows repeating an email earlier in the file. Nothing is sent.`,
+	Example: `  # Which leads of a partner's file were already imported
+  lead-processor dedupe check partner-leads.csv`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"csv", "parquet", "avro", "xml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runDedupeCheckCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+	dedupeCmd.AddCommand(dedupeShowCmd)
+	dedupeCmd.AddCommand(dedupeRebuildCmd)
+	dedupeCmd.AddCommand(dedupeCheckCmd)
+	dedupeCmd.PersistentFlags().String("history-dir", "", "Directory of the local run-history store, which holds the index")
+	dedupeRebuildCmd.Flags().String("mirror", "", "Fill in lead IDs from this mirror file, kept by sync snapshot")
+	dedupeCheckCmd.Flags().String("input-format", "", "Format of the file: csv, parquet, avro, xml or fixed-width (default from its extension)")
+	dedupeCheckCmd.Flags().Bool("quiet", false, "Only print the summary, not each lead")
+
+	_ = dedupeCmd.MarkPersistentFlagDirname("history-dir")
+	_ = dedupeRebuildCmd.MarkFlagFilename("mirror", "json")
+	_ = dedupeCheckCmd.RegisterFlagCompletionFunc("input-format", cobra.FixedCompletions(inputFormats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runDedupeShowCommand(cmd *cobra.Command, args []string) error {
+	index, err := openDedupeIndexFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if !index.Exists() {
+		printer.Printf("No dedupe index yet, it is built by the next process run or dedupe rebuild\n")
+		return nil
+	}
+
+	if len(args) > 0 {
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, strings.Join([]string{printer.Text("EMAIL"), printer.Text("LEAD ID"), printer.Text("LAST ACTION"), printer.Text("LAST SEEN"), printer.Text("RUN")}, "\t"))
+		for _, email := range args {
+			entry, ok := index.Get(email)
+			if !ok {
+				fmt.Fprintf(table, "%s\t%s\n", email, printer.Text("not in the index"))
+				continue
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", entry.Email, orDash(entry.LeadID), entry.LastAction, entry.LastSeen.Local().Format("2006-01-02 15:04"), orDash(entry.RunID))
+		}
+		return table.Flush()
+	}
+
+	actions := make(map[string]int)
+	withID := 0
+	entries := index.Entries()
+	for _, entry := range entries {
+		actions[entry.LastAction]++
+		if entry.LeadID != "" {
+			withID++
+		}
+	}
+	printer.Printf("Dedupe index: %d lead(s), %d with a lead ID, updated %s\n", len(entries), withID, index.UpdatedAt().Local().Format("2006-01-02 15:04"))
+	names := make([]string, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+	for _, action := range names {
+		fmt.Printf("  %s: %d\n", action, actions[action])
+	}
+	return nil
+}
+
+func runDedupeRebuildCommand(cmd *cobra.Command, args []string) error {
+	historyDir, _ := cmd.Flags().GetString("history-dir")
+	historyDir = cleanPath(historyDir)
+	mirrorFile, _ := cmd.Flags().GetString("mirror")
+	mirrorFile = cleanPath(mirrorFile)
+
+	if historyDir == "" {
+		return fmt.Errorf("--history-dir is required")
+	}
+
+	initLogger("info")
+
+	var leadMirror *mirror.Mirror
+	if mirrorFile != "" {
+		var err error
+		leadMirror, err = mirror.Open(mirrorFile)
+		if err != nil {
+			return err
+		}
+	}
+	store, err := history.Open(historyDir)
+	if err != nil {
+		return fmt.Errorf("failed to open run history: %w", err)
+	}
+	defer store.Close()
+	index, err := dedupe.Open(filepath.Join(historyDir, dedupe.FileName))
+	if err != nil {
+		return err
+	}
+
+	if err := rebuildDedupeIndex(index, store); err != nil {
+		return fmt.Errorf("failed to read run history: %w", err)
+	}
+	fromMirror := 0
+	if leadMirror != nil {
+		for _, entry := range index.Entries() {
+			if entry.LeadID != "" {
+				continue
+			}
+			if lead, ok := leadMirror.Get(entry.Email); ok && index.SetLeadID(entry.Email, lead.ID) {
+				fromMirror++
+			}
+		}
+	}
+	if err := index.Save(); err != nil {
+		LogError("Failed to save dedupe index", err, "historyDir", historyDir)
+		return err
+	}
+	LogInfo("Rebuilt dedupe index", "historyDir", historyDir, "leads", index.Len(), "leadIdsFromMirror", fromMirror)
+
+	printer.Printf("Rebuilt the dedupe index from the run history: %d lead(s)\n", index.Len())
+	if leadMirror != nil {
+		printer.Printf("Lead IDs filled in from %s: %d\n", mirrorFile, fromMirror)
+	}
+	return nil
+}
+
+func runDedupeCheckCommand(cmd *cobra.Command, args []string) error {
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	input := cleanPath(args[0])
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	index, err := openDedupeIndexFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if !index.Exists() {
+		return fmt.Errorf("no dedupe index in --history-dir yet, run process or dedupe rebuild first")
+	}
+
+	csvReader := csv.NewCSVReader()
+	csvReader.SetMapping(cfg.Mapping)
+	csvReader.SetIDGenerator(cfg.IDGenerator())
+	source, err := newFileSource(input, inputFormat, cfg, csvReader)
+	if err != nil {
+		return err
+	}
+
+	printer.Printf("Checking %s against the dedupe index (%d leads)\n", input, index.Len())
+	var fresh, unchanged, changed, repeated int
+	inFile := make(map[string]bool)
+	err = source.StreamLeads(func(lead *models.Lead) error {
+		lead = models.Sanitize(lead)
+		key := dedupe.Key(lead.Email)
+		if key != "" && inFile[key] {
+			repeated++
+			if !quiet {
+				fmt.Printf("  = %s\n", printer.Sprintf("%s: repeated in the file", lead.Email))
+			}
+			return nil
+		}
+		inFile[key] = true
+
+		entry, ok := index.Get(key)
+		switch {
+		case !ok || key == "":
+			fresh++
+		case entry.ContentHash == lead.ContentHash():
+			unchanged++
+			if !quiet {
+				fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("%s: processed %s (%s, lead %s)", lead.Email, entry.LastSeen.Local().Format("2006-01-02 15:04"), entry.LastAction, orDash(entry.LeadID)))
+			}
+		default:
+			changed++
+			if !quiet {
+				fmt.Printf("  ~ %s\n", printer.Sprintf("%s: processed %s (%s, lead %s), changed since", lead.Email, entry.LastSeen.Local().Format("2006-01-02 15:04"), entry.LastAction, orDash(entry.LeadID)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	printer.Printf("\n=== Dedupe Summary ===\n")
+	printer.Printf("New: %d\n", fresh)
+	printer.Printf("Already processed, unchanged: %d\n", unchanged)
+	printer.Printf("Already processed, changed: %d\n", changed)
+	printer.Printf("Repeated in the file: %d\n", repeated)
+	return nil
+}
+
+// openDedupeIndexFlag opens the dedupe index in the command's --history-dir
+func openDedupeIndexFlag(cmd *cobra.Command) (*dedupe.Index, error) {
+	historyDir, _ := cmd.Flags().GetString("history-dir")
+	historyDir = cleanPath(historyDir)
+	if historyDir == "" {
+		return nil, fmt.Errorf("--history-dir is required")
+	}
+	return dedupe.Open(filepath.Join(historyDir, dedupe.FileName))
+}
+
+// openDedupeIndex opens the dedupe index in the run-history directory,
+// building it from the run history the first time
+func openDedupeIndex(historyDir string, store *history.Store) (*dedupe.Index, error) {
+	index, err := dedupe.Open(filepath.Join(historyDir, dedupe.FileName))
+	if err != nil {
+		return nil, err
+	}
+	if !index.Exists() {
+		if err := rebuildDedupeIndex(index, store); err != nil {
+			return nil, fmt.Errorf("failed to read run history: %w", err)
+		}
+		LogInfo("Built dedupe index from run history", "historyDir", historyDir, "leads", index.Len())
+	}
+	return index, nil
+}
+
+// rebuildDedupeIndex replaces the index's entries with the latest record of
+// every lead in the run history, keeping the lead IDs it already knew
+func rebuildDedupeIndex(index *dedupe.Index, store *history.Store) error {
+	ids := make(map[string]string)
+	for _, entry := range index.Entries() {
+		if entry.LeadID != "" {
+			ids[dedupe.Key(entry.Email)] = entry.LeadID
+		}
+	}
+	index.Reset()
+	return store.EachLead(func(record history.LeadRecord) {
+		index.Record(dedupe.Entry{
+			Email:       record.Email,
+			LeadID:      ids[dedupe.Key(record.Email)],
+			ContentHash: record.ContentHash,
+			LastAction:  record.Action,
+			LastSeen:    record.ProcessedAt,
+			RunID:       record.RunID,
+		})
+	})
+}
+
+// resultLeadID returns the CRM's ID of the lead a result wrote, or empty
+// when the result doesn't say
+func resultLeadID(result *processor.ProcessResult) string {
+	for _, lead := range []*models.Lead{result.CreatedLead, result.UpdatedLead, result.PreviousLead} {
+		if lead != nil && lead.ID != "" {
+			return lead.ID
+		}
+	}
+	return ""
+}
+
+// orDash returns s, or a dash when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
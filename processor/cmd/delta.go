@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"code/internal/csv"
+	"code/internal/dedupe"
+	"code/internal/delta"
+	"code/internal/emailcheck"
+	"code/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var deltaCmd = &cobra.Command{
+	Use:   "delta <old-file> <new-file> | delta --against-index <new-file>",
+	Short: "Compare two snapshots of a lead file and process only what changed",
+	Long: `Compare two full exports of the same feed, matching leads by email, and list
+the leads added, changed and removed since the old one. Only the lead fields
+sent to the API are compared, so a change to an unmapped column isn't one.
+
+With --process, the added and changed leads are sent as process would send
+them, and unchanged rows cost no API calls. With --output, they are written as
+rows of the new file's layout, for a later process run. Removed leads are only
+listed: a partner dropping a row from its export doesn't delete the lead.
+
+With --against-index, the file is compared with the dedupe index that process
+keeps in --history-dir instead of an old snapshot: a lead is added if no run
+processed it and changed if its content differs from the last run's. The index
+only keeps a hash of each lead, so changed fields aren't listed, and as it
+spans every feed, nothing counts as removed.`,
+	Example: `  # List what changed between yesterday's and today's export
+  lead-processor delta partner-2026-10-15.csv partner-2026-10-16.csv
+
+  # Send only the added and changed leads
+  lead-processor delta partner-2026-10-15.csv partner-2026-10-16.csv --process
+
+  # Write the delta to a file to process later
+  lead-processor delta partner-2026-10-15.csv partner-2026-10-16.csv --output partner-delta.csv
+
+  # Send only what changed since the leads were last processed
+  lead-processor delta --against-index partner-2026-10-16.csv --process`,
+	GroupID: groupLeads,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if againstIndex, _ := cmd.Flags().GetBool("against-index"); againstIndex {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if againstIndex, _ := cmd.Flags().GetBool("against-index"); len(args) > 1 || (againstIndex && len(args) > 0) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"csv", "parquet", "avro", "xml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runDeltaCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(deltaCmd)
+	deltaCmd.Flags().Bool("process", false, "Send the added and changed leads to the API")
+	deltaCmd.Flags().StringP("output", "o", "", "Write the added and changed leads to this CSV file, in the new file's columns")
+	deltaCmd.Flags().Bool("quiet", false, "Only print the summary, not each lead")
+	deltaCmd.Flags().String("input-format", "", "Format of both files: csv, parquet, avro, xml or fixed-width (default from their extensions)")
+	deltaCmd.Flags().Int("workers", 1, "Number of leads processed concurrently with --process")
+	deltaCmd.Flags().String("email-validation", string(emailcheck.LevelSyntax), emailValidationUsage)
+	deltaCmd.Flags().Bool("against-index", false, "Compare one file with the dedupe index of earlier runs instead of an old file")
+	deltaCmd.Flags().String("history-dir", "", "Directory of the local run-history store, which holds the dedupe index")
+
+	_ = deltaCmd.MarkFlagFilename("output", "csv")
+	_ = deltaCmd.MarkFlagDirname("history-dir")
+	_ = deltaCmd.RegisterFlagCompletionFunc("input-format", cobra.FixedCompletions(inputFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = deltaCmd.RegisterFlagCompletionFunc("email-validation", completeEmailLevels)
+}
+
+func runDeltaCommand(cmd *cobra.Command, args []string) error {
+	process, _ := cmd.Flags().GetBool("process")
+	output, _ := cmd.Flags().GetString("output")
+	output = cleanPath(output)
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	workers, _ := cmd.Flags().GetInt("workers")
+	emailValidation, _ := cmd.Flags().GetString("email-validation")
+	againstIndex, _ := cmd.Flags().GetBool("against-index")
+	newInput := cleanPath(args[len(args)-1])
+
+	emailLevel, err := emailcheck.ParseLevel(emailValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --email-validation: %w", err)
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	csvReader := csv.NewCSVReader()
+	csvReader.SetMapping(cfg.Mapping)
+	csvReader.SetIDGenerator(cfg.IDGenerator())
+
+	// The old side is either a snapshot or what earlier runs processed
+	var oldInput string
+	var oldSource csv.LeadSource
+	var index *dedupe.Index
+	if againstIndex {
+		index, err = openDedupeIndexFlag(cmd)
+		if err != nil {
+			return err
+		}
+		if !index.Exists() {
+			return fmt.Errorf("invalid --against-index: no dedupe index in --history-dir yet, run process or dedupe rebuild first")
+		}
+		oldInput = printer.Text("the dedupe index")
+	} else {
+		oldInput = cleanPath(args[0])
+		oldSource, err = newFileSource(oldInput, inputFormat, cfg, csvReader)
+		if err != nil {
+			return err
+		}
+	}
+	newSource, err := newFileSource(newInput, inputFormat, cfg, csvReader)
+	if err != nil {
+		return err
+	}
+
+	var rows *csv.RowWriter
+	if output != "" {
+		header, err := newSource.ReadHeader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", newInput, err)
+		}
+		rows = csv.NewRowWriter(output, header)
+		defer rows.Close()
+	}
+
+	LogInfo("Comparing lead files", "old", oldInput, "new", newInput)
+	printer.Printf("Comparing %s with %s\n", newInput, oldInput)
+
+	// Added and changed leads are kept for --process; the rest only counted
+	var pending []*models.Lead
+	collect := func(change delta.Change) error {
+		if !quiet {
+			printChange(change)
+		}
+		if change.Kind == delta.KindRemoved {
+			return nil
+		}
+		if process {
+			pending = append(pending, change.Lead)
+		}
+		if rows != nil {
+			return rows.Write(change.Lead)
+		}
+		return nil
+	}
+	var stats delta.Stats
+	if index != nil {
+		stats, err = delta.CompareIndex(index, newSource, collect)
+	} else {
+		stats, err = delta.Compare(oldSource, newSource, collect)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compare %s with %s: %w", oldInput, newInput, err)
+	}
+	LogInfo("Compared lead files", "added", stats.Added, "changed", stats.Changed, "removed", stats.Removed, "unchanged", stats.Unchanged)
+
+	printer.Printf("\n=== Delta Summary ===\n")
+	printer.Printf("Added: %d\n", stats.Added)
+	printer.Printf("Changed: %d\n", stats.Changed)
+	if index == nil {
+		printer.Printf("Removed: %d\n", stats.Removed)
+	}
+	printer.Printf("Unchanged: %d\n", stats.Unchanged)
+	if rows != nil && rows.Count() > 0 {
+		if err := rows.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		printer.Printf("Delta written to: %s (%d leads)\n", output, rows.Count())
+	}
+
+	if !process || len(pending) == 0 {
+		return nil
+	}
+	printer.Printf("\nProcessing %d added or changed lead(s)\n", len(pending))
+	tally, err := sendLeads(cmd, cfg, workers, emailLevel, pending, func(i int, lead *models.Lead, action string, failure error) error {
+		LogWarn("Lead failed", "email", lead.Email, "action", action, "error", failure.Error())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	printer.Printf("\n=== Processing Summary ===\n")
+	tally.print()
+	if err := runFailure(tally.errorCodes); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return nil
+}
+
+// printChange prints a line for a lead of the delta
+func printChange(change delta.Change) {
+	switch change.Kind {
+	case delta.KindAdded:
+		fmt.Printf("  + %s\n", printer.Sprintf("%s: added", change.Lead.Email))
+	case delta.KindChanged:
+		if len(change.Fields) == 0 {
+			fmt.Printf("  ~ %s\n", printer.Sprintf("%s: changed", change.Lead.Email))
+			break
+		}
+		fmt.Printf("  ~ %s\n", printer.Sprintf("%s: changed %s", change.Lead.Email, strings.Join(change.Fields, ", ")))
+	case delta.KindRemoved:
+		fmt.Printf("  - %s\n", printer.Sprintf("%s: removed", change.Lead.Email))
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/doctor"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [sample.csv]",
+	Short: "Check the config, API access and column mapping before a run",
+	Long: `Check that the config file is valid, the API is reachable and accepts the
+configured credentials, and, given a sample CSV file, that the column mapping
+matches its header and its first leads pass validation. Exits non-zero if any
+check fails, so it can gate scheduled runs.`,
+	Example: `  # Check the config and API access
+  lead-processor doctor --config lead-processor.yaml
+
+  # Also check the mapping against a partner's file
+  lead-processor doctor partner-export.csv --config lead-processor.yaml`,
+	GroupID:           groupOperations,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeCSVFiles,
+	RunE:              runDoctorCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().Int("sample", 20, "Number of leads from the CSV file to validate")
+}
+
+func runDoctorCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	configPath, _ := cmd.Flags().GetString("config")
+	sampleSize, _ := cmd.Flags().GetInt("sample")
+
+	initLogger("info")
+
+	report := &doctor.Report{}
+
+	cfg, configCheck := doctor.CheckConfig(cleanPath(configPath))
+	report.Add(configCheck)
+
+	client := api.NewAPIClient(apiURL, configClientOptions(cfg)...)
+	report.Add(doctor.CheckAPI(client.HTTPClient(), client.BaseURL(), cfg.APIToken != "")...)
+
+	if len(args) == 1 {
+		report.Add(doctor.CheckCSV(cleanPath(args[0]), cfg.Mapping, sampleSize)...)
+	} else if len(cfg.Mapping) > 0 {
+		report.Add(doctor.Check{
+			Name:    "CSV columns",
+			Status:  doctor.StatusWarn,
+			Message: "mapping not checked",
+			Hint:    "Pass a sample CSV file to check the mapping against its header",
+		})
+	}
+
+	for _, check := range report.Checks {
+		marker := symbols.ok
+		switch check.Status {
+		case doctor.StatusWarn:
+			marker = symbols.warn
+		case doctor.StatusFail:
+			marker = symbols.fail
+		}
+		fmt.Printf("%s %s: %s\n", marker, check.Name, check.Message)
+		if check.Hint != "" {
+			fmt.Printf("    %s\n", check.Hint)
+		}
+	}
+
+	// Failed checks are not usage mistakes, so don't bury them under the help text
+	cmd.SilenceUsage = true
+	failed := report.Count(doctor.StatusFail)
+	printer.Printf("\n%d passed, %d warning(s), %d failed\n", report.Count(doctor.StatusOK), report.Count(doctor.StatusWarn), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+
+	return nil
+}
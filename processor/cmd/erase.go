@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"code/internal/erasure"
+	"code/internal/processor"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var eraseCmd = &cobra.Command{
+	Use:   "erase",
+	Short: "Anonymize or delete leads for GDPR erasure requests",
+	Long: `Look up leads by email and anonymize or delete them via the API. Every
+attempt is appended to an audit log as evidence of the erasure; the log stores
+a hash of each email rather than the address itself.
+
+With --mode delete, leads are anonymized and archived unless --deletion-mode
+delete is given, so an erasure can't lose more than the personal data. Where
+the server can't archive, they are deleted instead, with a warning, unless
+--deletion-mode archive is given explicitly.`,
+	Example: `  # Anonymize a single lead
+  lead-processor erase --email jane@example.com
+
+  # Anonymize and archive every lead listed in a file
+  lead-processor erase --file requests.txt --mode delete --operator dpo
+
+  # Delete them permanently
+  lead-processor erase --file requests.txt --mode delete --deletion-mode delete`,
+	GroupID: groupLeads,
+	Args:    cobra.NoArgs,
+	RunE:    runEraseCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(eraseCmd)
+	eraseCmd.Flags().StringSlice("email", nil, "Email address to erase (repeatable)")
+	eraseCmd.Flags().String("file", "", "File with one email address per line")
+	eraseCmd.Flags().String("mode", string(erasure.ModeAnonymize), "How to erase leads: anonymize or delete")
+	eraseCmd.Flags().String("deletion-mode", string(processor.DeletionArchive), deletionModeUsage)
+	eraseCmd.Flags().String("audit-file", "erasure-audit.ndjson", "Append-only audit log of erasures")
+	eraseCmd.Flags().String("operator", os.Getenv("USER"), "Who requested the erasure, recorded in the audit log")
+
+	setFlagGroup(eraseCmd, "Input Flags", "email", "file")
+	setFlagGroup(eraseCmd, "Audit Flags", "audit-file", "operator")
+	_ = eraseCmd.MarkFlagFilename("file")
+	_ = eraseCmd.MarkFlagFilename("audit-file", "ndjson")
+	_ = eraseCmd.RegisterFlagCompletionFunc("mode", cobra.FixedCompletions(
+		[]string{string(erasure.ModeAnonymize), string(erasure.ModeDelete)}, cobra.ShellCompDirectiveNoFileComp))
+	_ = eraseCmd.RegisterFlagCompletionFunc("deletion-mode", cobra.FixedCompletions(
+		processor.DeletionModes(), cobra.ShellCompDirectiveNoFileComp))
+}
+
+func runEraseCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	emails, _ := cmd.Flags().GetStringSlice("email")
+	emailFile, _ := cmd.Flags().GetString("file")
+	emailFile = cleanPath(emailFile)
+	modeValue, _ := cmd.Flags().GetString("mode")
+	auditFile, _ := cmd.Flags().GetString("audit-file")
+	auditFile = cleanPath(auditFile)
+	operator, _ := cmd.Flags().GetString("operator")
+	deletionModeValue, _ := cmd.Flags().GetString("deletion-mode")
+
+	mode, err := erasure.ParseMode(modeValue)
+	if err != nil {
+		return err
+	}
+	deletionMode, err := processor.ParseDeletionMode(deletionModeValue)
+	if err != nil {
+		return fmt.Errorf("invalid --deletion-mode: %w", err)
+	}
+
+	if emailFile != "" {
+		fileEmails, err := erasure.ReadEmails(emailFile)
+		if err != nil {
+			return fmt.Errorf("failed to read email file: %w", err)
+		}
+		emails = append(emails, fileEmails...)
+	}
+	if len(emails) == 0 {
+		return fmt.Errorf("no emails given; use --email or --file")
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	leadClient, err := newLeadClient(cmd, cfg, apiURL)
+	if err != nil {
+		return err
+	}
+	client, ok := leadClient.(erasure.Client)
+	if !ok {
+		return fmt.Errorf("erasure is not supported by this API client")
+	}
+
+	audit, err := erasure.OpenAuditLog(auditFile)
+	if err != nil {
+		return err
+	}
+	defer audit.Close()
+
+	eraser := erasure.New(client, audit, mode, operator)
+	if mode == erasure.ModeDelete {
+		var features processor.ServerFeatures
+		if detector, ok := leadClient.(featureDetector); ok {
+			if features, err = detector.DetectFeatures(); err != nil {
+				LogWarn("Failed to detect server capabilities, using legacy behaviour", "error", err.Error())
+			}
+		}
+		deletionMode = resolveDeletionMode(cmd, leadClient, deletionMode, features)
+		remover, err := processor.NewRemover(leadClient, deletionMode, features)
+		if err != nil {
+			return fmt.Errorf("--mode delete: %w%s", err, deletionHint(deletionMode))
+		}
+		eraser.SetRemover(remover)
+	}
+
+	LogInfo("Starting erasure", "count", len(emails), "mode", mode, "auditFile", auditFile)
+	printer.Printf("Erasing %d lead(s) (%s) via %s\n", len(emails), mode, apiURL)
+
+	erased, notFound, failed := 0, 0, 0
+	for _, email := range emails {
+		record, err := eraser.Erase(email)
+		if err != nil {
+			LogError("Failed to write erasure audit record", err, "emailHash", record.EmailHash)
+			return fmt.Errorf("stopping erasure, audit log is not writable: %w", err)
+		}
+
+		switch record.Outcome {
+		case erasure.OutcomeErased:
+			LogInfo("Lead erased", "emailHash", record.EmailHash, "leadId", record.LeadID, "mode", mode, "archived", record.Archived)
+			if record.Archived {
+				fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: anonymized and archived lead %s", email, record.LeadID))
+			} else {
+				fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: erased lead %s", email, record.LeadID))
+			}
+			erased++
+		case erasure.OutcomeNotFound:
+			LogInfo("No lead to erase", "emailHash", record.EmailHash)
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("%s: no matching lead", email))
+			notFound++
+		default:
+			LogWarn("Erasure failed", "emailHash", record.EmailHash, "anonymized", record.Anonymized, "error", record.Error)
+			if record.Anonymized {
+				fmt.Printf("  %s %s: %s\n", symbols.fail, printer.Sprintf("%s: anonymized lead %s, but not archived", email, record.LeadID), record.Error)
+			} else {
+				fmt.Printf("  %s %s: %s\n", symbols.fail, email, record.Error)
+			}
+			failed++
+		}
+	}
+
+	printer.Printf("\n=== Erasure Summary ===\n")
+	printer.Printf("Erased: %d\n", erased)
+	printer.Printf("Not found: %d\n", notFound)
+	printer.Printf("Failed: %d\n", failed)
+	printer.Printf("Audit log: %s\n", auditFile)
+
+	if failed > 0 {
+		return fmt.Errorf("%d erasure(s) failed", failed)
+	}
+	return nil
+}
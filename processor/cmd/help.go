@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"code/internal/emailcheck"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Command groups shown in the root help
+const (
+	groupLeads      = "leads"
+	groupOperations = "operations"
+)
+
+// flagGroupAnnotation names the help section a flag is listed under
+const flagGroupAnnotation = "lead-processor/group"
+
+// usageTemplate is cobra's default usage template with the local flags
+// rendered by groupedFlagUsages, so related flags are listed together
+const usageTemplate = `Usage:{{if .Runnable}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}{{$cmds := .Commands}}{{if eq (len .Groups) 0}}
+
+Available Commands:{{range $cmds}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{else}}{{range $group := .Groups}}
+
+{{.Title}}{{range $cmds}}{{if (and (eq .GroupID $group.ID) (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if not .AllChildCommandsHaveGroup}}
+
+Additional Commands:{{range $cmds}}{{if (and (eq .GroupID "") (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}{{groupedFlagUsages .}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+Global Flags:
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasHelpSubCommands}}
+
+Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .CommandPath .CommandPathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Print usage for every command as Markdown",
+	Long:  `Print the generated usage of every command as Markdown, for wikis and runbooks.`,
+	Example: `  # Write a command reference for the team wiki
+  lead-processor docs > COMMANDS.md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeDocs(cmd.OutOrStdout(), rootCmd)
+	},
+}
+
+func init() {
+	cobra.AddTemplateFunc("groupedFlagUsages", groupedFlagUsages)
+	rootCmd.SetUsageTemplate(usageTemplate)
+
+	rootCmd.AddGroup(
+		&cobra.Group{ID: groupLeads, Title: "Lead Commands:"},
+		&cobra.Group{ID: groupOperations, Title: "Operations Commands:"},
+	)
+	rootCmd.SetHelpCommandGroupID(groupOperations)
+	rootCmd.SetCompletionCommandGroupID(groupOperations)
+
+	docsCmd.GroupID = groupOperations
+	rootCmd.AddCommand(docsCmd)
+}
+
+// setFlagGroup lists the named flags under title in cmd's help
+func setFlagGroup(cmd *cobra.Command, title string, names ...string) {
+	for _, name := range names {
+		if err := cmd.Flags().SetAnnotation(name, flagGroupAnnotation, []string{title}); err != nil {
+			panic(fmt.Sprintf("flag group %q: %v", title, err))
+		}
+	}
+}
+
+// completeCSVFiles completes the file argument of commands that read a CSV
+func completeCSVFiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return []string{"csv"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completeLeadFiles completes the file argument of commands that also read
+// Parquet and Avro files
+func completeLeadFiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return []string{"csv", "parquet", "avro"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// groupedFlagUsages renders cmd's local flags, ungrouped flags first under
+// "Flags:" and then each group in the order its first flag was defined
+func groupedFlagUsages(cmd *cobra.Command) string {
+	ungrouped := pflag.NewFlagSet("flags", pflag.ContinueOnError)
+	groups := make(map[string]*pflag.FlagSet)
+	var titles []string
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		group := flag.Annotations[flagGroupAnnotation]
+		if len(group) == 0 {
+			ungrouped.AddFlag(flag)
+			return
+		}
+		title := group[0]
+		if groups[title] == nil {
+			groups[title] = pflag.NewFlagSet(title, pflag.ContinueOnError)
+			titles = append(titles, title)
+		}
+		groups[title].AddFlag(flag)
+	})
+
+	var b strings.Builder
+	if ungrouped.HasAvailableFlags() {
+		fmt.Fprintf(&b, "\n\nFlags:\n%s", strings.TrimRight(ungrouped.FlagUsages(), " \n"))
+	}
+	for _, title := range titles {
+		fmt.Fprintf(&b, "\n\n%s:\n%s", title, strings.TrimRight(groups[title].FlagUsages(), " \n"))
+	}
+
+	return b.String()
+}
+
+// writeDocs writes a Markdown section with the usage of cmd and each of its
+// available subcommands
+func writeDocs(w io.Writer, cmd *cobra.Command) error {
+	if !cmd.IsAvailableCommand() && cmd.HasParent() {
+		return nil
+	}
+
+	level := strings.Repeat("#", min(strings.Count(cmd.CommandPath(), " ")+1, 4))
+	fmt.Fprintf(w, "%s %s\n\n", level, cmd.CommandPath())
+
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	fmt.Fprintf(w, "%s\n\n```\n%s```\n\n", description, cmd.UsageString())
+
+	for _, sub := range cmd.Commands() {
+		if err := writeDocs(w, sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completeEmailLevels completes --email-validation values
+func completeEmailLevels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	levels := make([]string, 0, len(emailcheck.Levels()))
+	for _, level := range emailcheck.Levels() {
+		levels = append(levels, string(level))
+	}
+	return levels, cobra.ShellCompDirectiveNoFileComp
+}
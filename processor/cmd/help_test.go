@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupedFlagUsages(t *testing.T) {
+	t.Run("lists grouped flags under their own heading", func(t *testing.T) {
+		// Act
+		usage := groupedFlagUsages(processCmd)
+
+		// Assert
+		flags := strings.Index(usage, "Flags:\n")
+		performance := strings.Index(usage, "Performance Flags:\n")
+		history := strings.Index(usage, "History Flags:\n")
+		assert.True(t, flags >= 0 && flags < performance && performance < history, usage)
+		assert.Contains(t, usage[performance:history], "--workers")
+		assert.Contains(t, usage[history:], "--skip-seen")
+		assert.NotContains(t, usage[:performance], "--workers")
+	})
+}
+
+func TestWriteDocs(t *testing.T) {
+	t.Run("documents every available command", func(t *testing.T) {
+		// Arrange
+		rootCmd.InitDefaultCompletionCmd()
+		var out bytes.Buffer
+
+		// Act
+		err := writeDocs(&out, rootCmd)
+
+		// Assert
+		assert.NoError(t, err)
+		for _, heading := range []string{"# lead-processor\n", "## lead-processor process\n", "## lead-processor erase\n", "### lead-processor completion bash\n"} {
+			assert.Contains(t, out.String(), heading)
+		}
+		assert.NotContains(t, out.String(), "lead-processor __complete")
+	})
+}
+
+func TestPrintsBanner(t *testing.T) {
+	t.Run("stays quiet for machine-readable output", func(t *testing.T) {
+		// Arrange
+		rootCmd.InitDefaultCompletionCmd()
+		completion, _, _ := rootCmd.Find([]string{"completion", "bash"})
+
+		// Assert
+		assert.True(t, printsBanner(processCmd))
+		assert.False(t, printsBanner(docsCmd))
+		assert.False(t, printsBanner(completion))
+	})
+
+	t.Run("stays quiet while streaming decisions", func(t *testing.T) {
+		// Arrange
+		processCmd.Flags().Set("output", "tsv")
+		defer processCmd.Flags().Set("output", "")
+
+		// Assert
+		assert.False(t, printsBanner(processCmd))
+	})
+}
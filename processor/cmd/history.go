@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"code/internal/history"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recorded runs, optionally only those with given labels",
+	Long: `List the runs in the run-history store, oldest first, with their outcome and
+labels. With --label, only runs carrying every given label are listed, so the
+imports of one initiative can be found together across months, and their
+figures are totalled.`,
+	Example: `  # The latest runs
+  lead-processor history
+
+  # Every import of the Q3 webinar campaign
+  lead-processor history --label campaign=q3-webinar`,
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+	RunE:    runHistoryCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("history-dir", "", "Directory of the local run-history store")
+	historyCmd.Flags().StringToString("label", nil, "Only list runs with this label, e.g. campaign=q3-webinar (repeatable; all must match)")
+	historyCmd.Flags().Int("limit", 20, "List at most this many of the latest matching runs (0 = all)")
+
+	_ = historyCmd.MarkFlagDirname("history-dir")
+}
+
+func runHistoryCommand(cmd *cobra.Command, args []string) error {
+	historyDir, _ := cmd.Flags().GetString("history-dir")
+	historyDir = cleanPath(historyDir)
+	labelPairs, _ := cmd.Flags().GetStringToString("label")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	if historyDir == "" {
+		return fmt.Errorf("--history-dir is required")
+	}
+	filter, err := history.ParseLabels(labelPairs)
+	if err != nil {
+		return fmt.Errorf("invalid --label: %w", err)
+	}
+	if limit < 0 {
+		return fmt.Errorf("invalid --limit: cannot be negative")
+	}
+
+	store, err := history.Open(historyDir)
+	if err != nil {
+		return fmt.Errorf("failed to open run history: %w", err)
+	}
+	defer store.Close()
+
+	runs := store.RunsLabelled(filter)
+	if len(runs) == 0 {
+		printer.Printf("No runs found\n")
+		return nil
+	}
+
+	// The totals cover every matching run, not only the listed ones
+	matched := len(runs)
+	var total history.Run
+	for _, run := range runs {
+		total.Created += run.Created
+		total.Updated += run.Updated
+		total.Skipped += run.Skipped
+		total.Errors += run.Errors
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[len(runs)-limit:]
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join([]string{printer.Text("RUN"), printer.Text("STARTED"), printer.Text("FILE"), printer.Text("CREATED"), printer.Text("UPDATED"), printer.Text("SKIPPED"), printer.Text("ERRORS"), printer.Text("LABELS")}, "\t"))
+	for _, run := range runs {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n", run.ID, run.StartedAt.Local().Format("2006-01-02 15:04"), run.File, run.Created, run.Updated, run.Skipped, run.Errors, run.Labels)
+	}
+	table.Flush()
+
+	if len(filter) > 0 {
+		printer.Printf("%d run(s) labelled %s: %d created, %d updated, %d skipped, %d errors\n", matched, filter, total.Created, total.Updated, total.Skipped, total.Errors)
+	}
+	return nil
+}
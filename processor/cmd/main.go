@@ -1,12 +1,27 @@
 package cmd
 
 import (
+	"code/internal/anomaly"
 	"code/internal/api"
-	"code/internal/csv"
+	"code/internal/config"
+	"code/internal/emailcheck"
+	"code/internal/errcode"
+	"code/internal/i18n"
+	"code/internal/inference"
+	"code/internal/mailchimp"
+	"code/internal/mirror"
 	"code/internal/models"
+	"code/internal/pipedrive"
 	"code/internal/processor"
+	"code/internal/secrets"
+	"code/internal/territory"
+	"code/internal/zoho"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -115,6 +130,53 @@ func (a *APIClientAdapter) UpdateLead(lead *models.Lead) (*models.Lead, error) {
 	return a.client.UpdateLead(lead)
 }
 
+func (a *APIClientAdapter) PatchLead(id string, changes map[string]string) (*models.Lead, error) {
+	return a.client.PatchLead(id, changes)
+}
+
+func (a *APIClientAdapter) MergeLeads(primaryID, duplicateID string, merged *models.Lead) (*models.Lead, error) {
+	return a.client.MergeLeads(primaryID, duplicateID, merged)
+}
+
+func (a *APIClientAdapter) AnonymizeLead(id string) error {
+	return a.client.AnonymizeLead(id)
+}
+
+func (a *APIClientAdapter) ArchiveLead(id string) error {
+	return a.client.ArchiveLead(id)
+}
+
+func (a *APIClientAdapter) AddNote(id string, text string) error {
+	return a.client.AddNote(id, text)
+}
+
+func (a *APIClientAdapter) AttachToLead(id string, attachment *models.Attachment) error {
+	return a.client.AttachToLead(id, attachment)
+}
+
+func (a *APIClientAdapter) DeleteLead(id string) error {
+	return a.client.DeleteLead(id)
+}
+
+// DetectFeatures probes the server's capabilities and maps them onto processor features
+func (a *APIClientAdapter) DetectFeatures() (processor.ServerFeatures, error) {
+	caps, err := a.client.DetectCapabilities()
+	if err != nil {
+		return processor.ServerFeatures{}, err
+	}
+
+	return processor.ServerFeatures{
+		Patch:        caps.Patch,
+		DomainLookup: caps.DomainLookup,
+		Archive:      caps.Archive,
+	}, nil
+}
+
+// featureDetector is implemented by API clients that can report server capabilities
+type featureDetector interface {
+	DetectFeatures() (processor.ServerFeatures, error)
+}
+
 func convertAPIToProcessorLead(apiLead *api.Lead) *models.Lead {
 	if apiLead == nil {
 		return nil
@@ -126,7 +188,16 @@ func convertAPIToProcessorLead(apiLead *api.Lead) *models.Lead {
 		Email:     apiLead.Email,
 		Company:   apiLead.Company,
 		Source:    apiLead.Source,
+		Status:    apiLead.Status,
 		CreatedAt: apiLead.CreatedAt,
+		UpdatedAt: apiLead.UpdatedAt,
+		Owner:     apiLead.Owner,
+		Territory: apiLead.Territory,
+		Language:  apiLead.Language,
+
+		ConsentGiven:     apiLead.ConsentGiven,
+		ConsentTimestamp: apiLead.ConsentTimestamp,
+		ConsentSource:    apiLead.ConsentSource,
 	}
 }
 
@@ -134,127 +205,317 @@ var rootCmd = &cobra.Command{
 	Use:   "lead-processor",
 	Short: "Lead ingestion automation tool",
 	Long:  `A CLI tool for processing lead data from CSV files and managing them via external APIs.`,
+	Example: `  # Process a file against a staging API
+  lead-processor process leads.csv --api-url https://staging.example.com
+
+  # Enable tab completion in the current bash session
+  source <(lead-processor completion bash)`,
 }
 
+// Exit codes; any other failure exits with 1
+const (
+	ExitOK              = 0
+	ExitFailure         = 1
+	ExitBudgetExhausted = 3
+	ExitPaused          = 4
+	ExitAuth            = 5
+	ExitNetwork         = 6
+	ExitRateLimited     = 7
+	ExitRetryBudget     = 8
+	ExitAnomaly         = 9
+	ExitPreflight       = 10
+	ExitDrift           = 11
+)
+
 // Execute runs the CLI application
 func Execute() error {
 	return rootCmd.Execute()
 }
 
+// ExitCode maps an error returned by Execute to the process exit code, so
+// scheduled jobs can tell an exhausted run budget or a closed run window
+// from a failure, and branch on the class of a failure
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, processor.ErrBudgetExhausted):
+		return ExitBudgetExhausted
+	case errors.Is(err, processor.ErrRunPaused):
+		return ExitPaused
+	case errors.Is(err, processor.ErrRetryBudgetExhausted):
+		return ExitRetryBudget
+	case errors.Is(err, anomaly.ErrAnomaly):
+		return ExitAnomaly
+	case errors.Is(err, processor.ErrPreflightFailed):
+		return ExitPreflight
+	case errors.Is(err, mirror.ErrDrift):
+		return ExitDrift
+	case errors.Is(err, errcode.ErrAuth):
+		return ExitAuth
+	case errors.Is(err, errcode.ErrNetwork):
+		return ExitNetwork
+	case errors.Is(err, errcode.ErrRateLimited):
+		return ExitRateLimited
+	}
+	return ExitFailure
+}
+
 func init() {
 	// Add global flags here
-	rootCmd.PersistentFlags().StringP("api-url", "u", "http://localhost:3030", "API base URL")
+	rootCmd.PersistentFlags().StringP("api-url", "u", "http://localhost:3030", "API base URL, or unix:///path/to/api.sock to reach it over a unix domain socket")
+	rootCmd.PersistentFlags().String("config", "", "Path to a YAML config file")
+	rootCmd.PersistentFlags().String("provider", providerAPI, "CRM that leads are written to: api (--api-url), pipedrive or zoho")
+	rootCmd.PersistentFlags().Bool("ascii", false, "Use plain ASCII status markers instead of unicode symbols")
+	rootCmd.PersistentFlags().String("lang", "", "Output language: en, de or fr (default from LANG)")
+	rootCmd.PersistentFlags().String("max-response-size", "16MiB", "Fail API responses larger than this, e.g. 64MiB; 0 accepts any size")
+	rootCmd.PersistentFlags().String("compression", string(api.CompressionAuto), "When to gzip large request bodies to the lead API: auto (if the server supports it), always or off (also no compressed responses)")
+	rootCmd.PersistentFlags().Bool("debug-http", false, "Trace every HTTP request and response to stderr, with credentials and personal data redacted")
+
+	_ = rootCmd.MarkPersistentFlagFilename("config", "yaml", "yml")
+	_ = rootCmd.RegisterFlagCompletionFunc("provider", cobra.FixedCompletions(
+		providers, cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("compression", cobra.FixedCompletions(
+		api.CompressionModes(), cobra.ShellCompDirectiveNoFileComp))
+	_ = rootCmd.RegisterFlagCompletionFunc("lang", cobra.FixedCompletions(
+		[]string{"en", "de", "fr"}, cobra.ShellCompDirectiveNoFileComp))
 }
 
-var processCmd = &cobra.Command{
-	Use:   "process [file]",
-	Short: "Process leads from a CSV file",
-	Long:  `Process leads from a CSV file and manage them via external APIs.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runProcessCommand,
+// loadConfig loads the file named by --config, or the defaults if none was given
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	path = cleanPath(path)
+	if path == "" {
+		limiters = api.NewLimiters(nil)
+		return config.Default(), nil
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	LogInfo("Loaded config file", "path", path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+	resolved, err := cfg.ResolveSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if resolved > 0 {
+		LogInfo("Resolved config secrets", "references", resolved)
+	}
+
+	limiters = api.NewLimiters(cfg.RateLimits)
+	return cfg, nil
 }
 
-func init() {
-	rootCmd.AddCommand(processCmd)
+// resolveSecret returns the secret a flag gives, resolving it when it is a
+// reference such as vault://secret/data/forms#token, or fallback, already
+// resolved with the config, when the flag is empty
+func resolveSecret(flag, fallback string) (string, error) {
+	if flag == "" {
+		return fallback, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+	return secrets.Resolve(ctx, flag)
 }
 
-func runProcessCommand(cmd *cobra.Command, args []string) error {
-	// Get flags
-	apiURL, _ := cmd.Flags().GetString("api-url")
+// secretsTimeout bounds fetching the config's secrets from their stores
+const secretsTimeout = 30 * time.Second
 
-	// Initialize structured logging with default level
-	initLogger("info")
+// limiters holds back the requests to each target by the config's
+// rate_limits; every client of a target shares its limiter
+var limiters = api.NewLimiters(nil)
 
-	// Get CSV file path
-	csvFile := args[0]
+// debugHTTP is set by --debug-http
+var debugHTTP bool
 
-	LogInfo("Starting lead processing", "csvFile", csvFile, "apiURL", apiURL)
+// maxResponseSize is set by --max-response-size
+var maxResponseSize int64 = api.DefaultMaxResponseSize
 
-	fmt.Printf("Processing leads from: %s\n", csvFile)
-	fmt.Printf("API URL: %s\n", apiURL)
+// compression is set by --compression
+var compression = api.CompressionAuto
 
-	// Initialize components
-	apiClient := api.NewAPIClient(apiURL)
-	csvReader := csv.NewCSVReader()
+// targetOption returns the client option every client of target gets: the
+// target's rate limit, a cap on response sizes except for storage, whose
+// files may be of any size, and with --debug-http a redacted trace of its
+// requests
+func targetOption(target string) api.ClientOption {
+	opts := []api.ClientOption{limiters.Option(target)}
+	if target != api.TargetStorage {
+		opts = append(opts, api.WithMaxResponseSize(maxResponseSize))
+	}
+	if debugHTTP {
+		opts = append(opts, api.WithDebug(os.Stderr))
+	}
+	return func(c *api.APIClient) {
+		for _, opt := range opts {
+			opt(c)
+		}
+	}
+}
 
-	// Create adapter to make API client compatible with processor interface
-	apiAdapter := &APIClientAdapter{client: apiClient}
-	leadProcessor := processor.NewLeadProcessor(apiAdapter)
+// CRM providers selectable with --provider
+const (
+	providerAPI       = "api"
+	providerPipedrive = "pipedrive"
+	providerZoho      = "zoho"
+)
 
-	// Read leads from CSV
-	LogInfo("Reading leads from CSV file")
-	fmt.Println("Reading leads from CSV file...")
-	leads, err := csvReader.ReadLeads(csvFile)
-	if err != nil {
-		LogError("Failed to read CSV file", err, "csvFile", csvFile)
-		return fmt.Errorf("failed to read CSV file: %w", err)
+var providers = []string{providerAPI, providerPipedrive, providerZoho}
+
+// newLeadClient builds the client for the CRM chosen with --provider. opts
+// add middleware, e.g. usage metering, to whichever client it is.
+func newLeadClient(cmd *cobra.Command, cfg *config.Config, apiURL string, opts ...api.ClientOption) (processor.APIClient, error) {
+	provider, _ := cmd.Flags().GetString("provider")
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case providerAPI:
+		return newLeadAPIClient(apiURL, append(configClientOptions(cfg), opts...)...), nil
+	case providerPipedrive:
+		pipedriveCfg := cfg.Pipedrive
+		if pipedriveCfg.APIToken == "" {
+			pipedriveCfg.APIToken = os.Getenv("PIPEDRIVE_API_TOKEN")
+		}
+		limiter := pipedrive.NewRateLimiter()
+		httpClient := api.NewAPIClient("", append(opts, api.WithMiddleware(limiter.Middleware), targetOption(api.TargetPipedrive))...).HTTPClient()
+		return pipedrive.New(pipedriveCfg, httpClient)
+	case providerZoho:
+		zohoCfg := cfg.Zoho
+		if zohoCfg.ClientSecret == "" {
+			zohoCfg.ClientSecret = os.Getenv("ZOHO_CLIENT_SECRET")
+		}
+		if zohoCfg.RefreshToken == "" {
+			zohoCfg.RefreshToken = os.Getenv("ZOHO_REFRESH_TOKEN")
+		}
+		return zoho.New(zohoCfg, api.NewAPIClient("", append(opts, targetOption(api.TargetZoho))...).HTTPClient())
 	}
+	return nil, fmt.Errorf("invalid --provider %q (use %s)", provider, strings.Join(providers, ", "))
+}
 
-	LogInfo("CSV file read successfully", "leadCount", len(leads))
-	fmt.Printf("Found %d leads to process\n", len(leads))
-
-	// Process each lead
-	createCount := 0
-	updateCount := 0
-	skipCount := 0
-	errorCount := 0
-
-	for i, lead := range leads {
-		LogInfo("Processing lead", "progress", fmt.Sprintf("%d/%d", i+1, len(leads)), "name", lead.Name, "email", lead.Email)
-		fmt.Printf("Processing lead %d/%d: %s (%s)\n", i+1, len(leads), lead.Name, lead.Email)
-
-		result, err := leadProcessor.ProcessLead(lead)
-		if err != nil {
-			LogError("Lead processing failed", err, "name", lead.Name, "email", lead.Email)
-			fmt.Printf("  Error: %v\n", err)
-			errorCount++
-			continue
-		}
+// checkOwnershipProvider refuses --respect-ownership with a provider whose
+// leads always have an owner: Pipedrive and Zoho make the user who added a
+// record its owner, so every lead would look worked by a rep and no update
+// would ever be sent
+func checkOwnershipProvider(cmd *cobra.Command) error {
+	provider, _ := cmd.Flags().GetString("provider")
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider != providerAPI {
+		return fmt.Errorf("--respect-ownership needs --provider %s; every %s record has an owner, so no lead could be updated", providerAPI, provider)
+	}
+	return nil
+}
 
-		switch result.Action {
-		case "CREATE":
-			LogInfo("Lead created successfully", "name", lead.Name, "email", lead.Email)
-			fmt.Printf("  ✓ Created new lead\n")
-			createCount++
-		case "UPDATE":
-			LogInfo("Lead updated successfully", "name", lead.Name, "email", lead.Email)
-			fmt.Printf("  ✓ Updated existing lead\n")
-			updateCount++
-		case "SKIP":
-			LogInfo("Lead skipped (no changes needed)", "name", lead.Name, "email", lead.Email)
-			fmt.Printf("  - Skipped (no changes needed)\n")
-			skipCount++
-		case "VALIDATION_ERROR":
-			LogWarn("Lead validation failed", "name", lead.Name, "email", lead.Email, "error", result.Error.Error())
-			fmt.Printf("  ✗ Validation error: %v\n", result.Error)
-			errorCount++
-		case "API_ERROR":
-			LogError("API error during lead processing", result.Error, "name", lead.Name, "email", lead.Email)
-			fmt.Printf("  ✗ API error: %v\n", result.Error)
-			errorCount++
-		default:
-			LogWarn("Unknown action result", "action", result.Action, "name", lead.Name, "email", lead.Email)
-			fmt.Printf("  ? Unknown action: %s\n", result.Action)
-			errorCount++
-		}
+// newMailchimpSink subscribes written leads to the config's Mailchimp audience
+func newMailchimpSink(cfg *config.Config) (*mailchimp.Client, error) {
+	mailchimpCfg := cfg.Mailchimp
+	if mailchimpCfg.APIKey == "" {
+		mailchimpCfg.APIKey = os.Getenv("MAILCHIMP_API_KEY")
+	}
+	return mailchimp.New(mailchimpCfg, api.NewAPIClient("", targetOption(api.TargetMailchimp)).HTTPClient())
+}
+
+// configClientOptions returns the lead API client options set in the config
+// file
+func configClientOptions(cfg *config.Config) []api.ClientOption {
+	opts := []api.ClientOption{targetOption(api.TargetAPI), api.WithCompression(compression)}
+	if cfg.APIToken != "" {
+		opts = append(opts, api.WithMiddleware(api.BearerAuthMiddleware(cfg.APIToken)))
+	}
+	if cfg.RawSource {
+		opts = append(opts, api.WithRawSource())
+	}
+	return opts
+}
+
+// storageHTTPClient returns the HTTP client for S3 and webhook destinations
+func storageHTTPClient() *http.Client {
+	return api.NewAPIClient("", targetOption(api.TargetStorage)).HTTPClient()
+}
+
+// validationOptions combines --email-validation with the config's max_lengths
+func validationOptions(cfg *config.Config, emailLevel emailcheck.Level) models.ValidationOptions {
+	return models.ValidationOptions{
+		EmailRule:  emailcheck.NewChecker(emailLevel).Rule(),
+		MaxLengths: cfg.MaxLengths,
 	}
+}
 
-	// Log and print summary
-	LogInfo("Processing completed", "totalLeads", len(leads), "created", createCount, "updated", updateCount, "skipped", skipCount, "errors", errorCount)
+// sourceInferrer fills in missing sources using the config's source_rules,
+// or the built-in heuristics if it has none
+func sourceInferrer(cfg *config.Config) *inference.Inferrer {
+	if len(cfg.SourceRules) > 0 {
+		return inference.NewInferrer(cfg.SourceRules)
+	}
+	return inference.NewInferrer(inference.DefaultRules())
+}
 
-	fmt.Println("\n=== Processing Summary ===")
-	fmt.Printf("Total leads: %d\n", len(leads))
-	fmt.Printf("Created: %d\n", createCount)
-	fmt.Printf("Updated: %d\n", updateCount)
-	fmt.Printf("Skipped: %d\n", skipCount)
-	fmt.Printf("Errors: %d\n", errorCount)
+// loadGeoIP reads the table the config's territories look the countries of
+// IP addresses up in, or returns nil when they look none up
+func loadGeoIP(cfg *config.Config) (*territory.GeoIP, error) {
+	if cfg.Territories.GeoIPFile == "" {
+		return nil, nil
+	}
+	geo, err := territory.LoadGeoIP(cleanPath(cfg.Territories.GeoIPFile))
+	if err != nil {
+		return nil, fmt.Errorf("invalid territories: %w", err)
+	}
+	return geo, nil
+}
+
+// printer formats user-facing output in the language chosen by --lang or the locale
+var printer = i18n.NewPrinter(i18n.English)
 
+// initLanguage selects the output language from --lang, LC_ALL, LC_MESSAGES or LANG
+func initLanguage() error {
+	value, _ := rootCmd.PersistentFlags().GetString("lang")
+	lang, err := i18n.Detect(value)
+	if err != nil {
+		return err
+	}
+	printer = i18n.NewPrinter(lang)
 	return nil
 }
 
 func init() {
-	cobra.OnInitialize(func() {
-		// Initialize logging here
-		fmt.Println("Lead Processor CLI initialized")
-	})
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := initLanguage(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; using English\n", err)
+		}
+		initSymbols()
+		debugHTTP, _ = rootCmd.PersistentFlags().GetBool("debug-http")
+		size, _ := rootCmd.PersistentFlags().GetString("max-response-size")
+		var err error
+		if maxResponseSize, err = parseByteSize(size); err != nil {
+			return fmt.Errorf("invalid --max-response-size: %w", err)
+		}
+		compressionValue, _ := rootCmd.PersistentFlags().GetString("compression")
+		if compression, err = api.ParseCompression(compressionValue); err != nil {
+			return fmt.Errorf("invalid --compression: %w", err)
+		}
+		if printsBanner(cmd) {
+			fmt.Println(printer.Text("Lead Processor CLI initialized"))
+		}
+		return nil
+	}
+}
+
+// printsBanner reports whether cmd's output is for people rather than other
+// programs; completion scripts, generated docs, version details, JSON
+// reports and streamed decisions must not be prefixed
+func printsBanner(cmd *cobra.Command) bool {
+	if format, err := cmd.Flags().GetString("format"); err == nil && format == reportJSON {
+		return false
+	}
+	if output, err := cmd.Flags().GetString("output"); err == nil && output != "" && cmd == processCmd {
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "completion", "docs", "version", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+	}
+	return true
 }
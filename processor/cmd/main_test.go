@@ -1,6 +1,16 @@
 package cmd
 
 import (
+	"code/internal/anomaly"
+	"code/internal/api"
+	"code/internal/errcode"
+	"code/internal/mirror"
+	"code/internal/models"
+	"code/internal/processor"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,3 +40,52 @@ func TestRootCommand_Initialization(t *testing.T) {
 		assert.Equal(t, "http://localhost:3030", apiURLFlag.DefValue)
 	})
 }
+
+func TestExitCode(t *testing.T) {
+	t.Run("distinguishes budget and window stops from failures", func(t *testing.T) {
+		// Arrange
+		budgetErr := fmt.Errorf("%w: 3 lead(s) deferred", processor.ErrBudgetExhausted)
+
+		// Assert
+		assert.Equal(t, ExitOK, ExitCode(nil))
+		assert.Equal(t, ExitBudgetExhausted, ExitCode(budgetErr))
+		assert.Equal(t, ExitPaused, ExitCode(fmt.Errorf("%w: progress saved", processor.ErrRunPaused)))
+		assert.Equal(t, ExitRetryBudget, ExitCode(fmt.Errorf("%w: stopped after 12 lead(s)", processor.ErrRetryBudgetExhausted)))
+		assert.Equal(t, ExitAnomaly, ExitCode(fmt.Errorf("%w: 1 metric(s) of feed acme strayed from earlier runs", anomaly.ErrAnomaly)))
+		assert.Equal(t, ExitPreflight, ExitCode(fmt.Errorf("%w: 12.0%% of 100 sampled lead(s) are invalid", processor.ErrPreflightFailed)))
+		assert.Equal(t, ExitDrift, ExitCode(fmt.Errorf("%w: 3 lead(s) differ between leads-mirror.json and the CRM", mirror.ErrDrift)))
+		assert.Equal(t, ExitFailure, ExitCode(errors.New("boom")))
+	})
+
+	t.Run("maps auth, network and rate limit failures to their own codes", func(t *testing.T) {
+		// Arrange
+		authErr := fmt.Errorf("lookup failed: %w", &errcode.StatusError{Status: 401})
+		networkErr := fmt.Errorf("%w: 2 lead(s) failed", errcode.ErrNetwork)
+		rateErr := fmt.Errorf("lookup failed: %w", &errcode.StatusError{Status: 429})
+
+		// Assert
+		assert.Equal(t, ExitAuth, ExitCode(authErr))
+		assert.Equal(t, ExitNetwork, ExitCode(networkErr))
+		assert.Equal(t, ExitRateLimited, ExitCode(rateErr))
+		assert.Equal(t, ExitFailure, ExitCode(&errcode.StatusError{Status: 500}))
+	})
+}
+
+func TestAPIClientAdapter_LookupLead(t *testing.T) {
+	t.Run("skips a lead the CRM holds with the same territory and language", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"found":true,"lead":{"id":"lead-1","name":"Jane Doe","email":"jane@example.com","company":"Acme","source":"Website","territory":"EMEA","language":"de","createdAt":"2026-01-01T00:00:00Z"}}`))
+		}))
+		defer server.Close()
+		leadProcessor := processor.NewLeadProcessor(&APIClientAdapter{client: api.NewAPIClient(server.URL)})
+		lead := &models.Lead{Name: "Jane Doe", Email: "jane@example.com", Company: "Acme", Source: "Website", Territory: "EMEA", Language: "de"}
+
+		// Act
+		result, err := leadProcessor.ProcessLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "SKIP", result.Action)
+	})
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"code/internal/merge"
+	"code/internal/models"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <primary-email> <duplicate-email>",
+	Short: "Merge a duplicate lead into another lead for the same person",
+	Long: `Consolidate two CRM leads for the same person. The duplicate is folded into
+the primary lead, which keeps its ID and email; every other field takes the
+value the field-precedence policy picks, from --prefer or the config's
+merge_precedence, keeping the primary's value by default. Every attempt is
+appended to an audit log, since a merge can't be undone.`,
+	Example: `  # Preview the merged lead without changing anything
+  lead-processor merge jane@techfirm.com jsmith@techfirm.com --dry-run
+
+  # Keep the duplicate's company and the most recently updated name
+  lead-processor merge jane@techfirm.com jsmith@techfirm.com --prefer company=duplicate,name=newest`,
+	GroupID: groupLeads,
+	Args:    cobra.ExactArgs(2),
+	RunE:    runMergeCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+	mergeCmd.Flags().StringToString("prefer", nil, "Which lead each field is taken from: field=primary, duplicate or newest (overrides merge_precedence)")
+	mergeCmd.Flags().Bool("dry-run", false, "Show the merged lead without merging")
+	mergeCmd.Flags().String("audit-file", "merge-audit.ndjson", "Append-only audit log of merges")
+	mergeCmd.Flags().String("operator", os.Getenv("USER"), "Who requested the merge, recorded in the audit log")
+
+	setFlagGroup(mergeCmd, "Audit Flags", "audit-file", "operator")
+	_ = mergeCmd.MarkFlagFilename("audit-file", "ndjson")
+}
+
+func runMergeCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	prefer, _ := cmd.Flags().GetStringToString("prefer")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	auditFile, _ := cmd.Flags().GetString("audit-file")
+	auditFile = cleanPath(auditFile)
+	operator, _ := cmd.Flags().GetString("operator")
+	primaryEmail, duplicateEmail := args[0], args[1]
+
+	if strings.EqualFold(strings.TrimSpace(primaryEmail), strings.TrimSpace(duplicateEmail)) {
+		return fmt.Errorf("the primary and duplicate emails are the same")
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// --prefer overrides the config per field
+	precedence := make(map[string]string)
+	for field, rule := range cfg.MergePrecedence {
+		precedence[field] = rule
+	}
+	for field, rule := range prefer {
+		precedence[strings.ToLower(strings.TrimSpace(field))] = rule
+	}
+	policy, err := merge.ParsePolicy(precedence)
+	if err != nil {
+		return fmt.Errorf("invalid --prefer: %w", err)
+	}
+
+	leadClient, err := newLeadClient(cmd, cfg, apiURL)
+	if err != nil {
+		return err
+	}
+	client, ok := leadClient.(merge.Client)
+	if !ok {
+		return fmt.Errorf("merging is not supported by this API client")
+	}
+
+	// Failures from here on are about the leads, not the command line
+	cmd.SilenceUsage = true
+
+	if dryRun {
+		record, merged, err := merge.New(client, nil, policy, operator).Plan(primaryEmail, duplicateEmail)
+		if err != nil {
+			return err
+		}
+		printer.Printf("Would merge lead %s into lead %s:\n", record.DuplicateID, record.PrimaryID)
+		printMergedLead(merged, record)
+		return nil
+	}
+
+	audit, err := merge.OpenAuditLog(auditFile)
+	if err != nil {
+		return err
+	}
+	defer audit.Close()
+
+	LogInfo("Merging leads", "primary", primaryEmail, "duplicate", duplicateEmail, "auditFile", auditFile)
+	record, stored, err := merge.New(client, audit, policy, operator).Merge(primaryEmail, duplicateEmail)
+	if err != nil {
+		LogError("Failed to write merge audit record", err, "primary", primaryEmail, "duplicate", duplicateEmail)
+		return fmt.Errorf("merge audit log is not writable: %w", err)
+	}
+	if record.Outcome != merge.OutcomeMerged {
+		LogWarn("Merge failed", "primary", primaryEmail, "duplicate", duplicateEmail, "error", record.Error)
+		printer.Printf("Audit log: %s\n", auditFile)
+		return fmt.Errorf("%s", record.Error)
+	}
+
+	LogInfo("Leads merged", "primaryId", record.PrimaryID, "duplicateId", record.DuplicateID, "fromDuplicate", strings.Join(record.DuplicateFields(), ","))
+	fmt.Printf("%s %s\n", symbols.ok, printer.Sprintf("Merged lead %s into lead %s", record.DuplicateID, record.PrimaryID))
+	printMergedLead(stored, record)
+	printer.Printf("Audit log: %s\n", auditFile)
+	return nil
+}
+
+// printMergedLead prints the merged values, marking those taken from the duplicate
+func printMergedLead(lead *models.Lead, record merge.Record) {
+	for _, field := range []string{"name", "email", "company", "source", "status"} {
+		value, _ := lead.Field(field)
+		marker := ""
+		if record.Sources[field] == merge.SideDuplicate {
+			marker = printer.Text(" (from duplicate)")
+		}
+		fmt.Printf("  %-8s %s%s\n", field+":", value, marker)
+	}
+}
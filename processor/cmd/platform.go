@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// symbolSet holds the status markers printed in front of per-lead results
+type symbolSet struct {
+	ok      string
+	fail    string
+	skip    string
+	warn    string
+	unknown string
+}
+
+var (
+	unicodeSymbols = symbolSet{ok: "✓", fail: "✗", skip: "-", warn: "!", unknown: "?"}
+	asciiSymbols   = symbolSet{ok: "+", fail: "x", skip: "-", warn: "!", unknown: "?"}
+)
+
+// symbols is the marker set for the current terminal, chosen by initSymbols
+var symbols = unicodeSymbols
+
+// initSymbols picks ASCII markers when --ascii is set or the terminal is
+// unlikely to render unicode
+func initSymbols() {
+	ascii, _ := rootCmd.PersistentFlags().GetBool("ascii")
+	if ascii || !supportsUnicode(runtime.GOOS, os.Getenv) {
+		symbols = asciiSymbols
+		return
+	}
+	symbols = unicodeSymbols
+}
+
+// supportsUnicode guesses whether the terminal renders unicode. Classic
+// Windows consoles (cmd.exe, older PowerShell hosts) use legacy code pages,
+// so on Windows only terminals known to handle UTF-8 qualify.
+func supportsUnicode(goos string, getenv func(string) string) bool {
+	if getenv("TERM") == "dumb" {
+		return false
+	}
+	if goos != "windows" {
+		return true
+	}
+
+	return getenv("WT_SESSION") != "" || // Windows Terminal
+		getenv("TERM_PROGRAM") == "vscode" ||
+		getenv("ConEmuANSI") == "ON" ||
+		getenv("TERM") != "" // mintty, Git Bash, MSYS2
+}
+
+// cleanPath tidies a path typed or pasted by an operator: it drops the quotes
+// Explorer's "Copy as path" adds and converts forward slashes to the OS
+// separator, so //server/share/leads.csv becomes a UNC path on Windows
+func cleanPath(path string) string {
+	path = strings.TrimSpace(path)
+	if len(path) >= 2 && path[0] == '"' && path[len(path)-1] == '"' {
+		path = path[1 : len(path)-1]
+	}
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Clean(filepath.FromSlash(path))
+}
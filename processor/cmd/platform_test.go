@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportsUnicode(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	t.Run("detects unicode support per platform and terminal", func(t *testing.T) {
+		// Arrange
+		cases := []struct {
+			goos     string
+			vars     map[string]string
+			expected bool
+		}{
+			{"linux", nil, true},
+			{"darwin", nil, true},
+			{"linux", map[string]string{"TERM": "dumb"}, false},
+			{"windows", nil, false},
+			{"windows", map[string]string{"WT_SESSION": "1"}, true},
+			{"windows", map[string]string{"TERM": "xterm-256color"}, true},
+		}
+
+		for _, c := range cases {
+			// Act
+			actual := supportsUnicode(c.goos, env(c.vars))
+
+			// Assert
+			assert.Equal(t, c.expected, actual, "%s %v", c.goos, c.vars)
+		}
+	})
+}
+
+func TestCleanPath(t *testing.T) {
+	t.Run("tidies pasted paths", func(t *testing.T) {
+		// Arrange
+		cases := map[string]string{
+			`"data/leads.csv"`:        filepath.Join("data", "leads.csv"),
+			"  ":                      "",
+			"/share/leads//leads.csv": filepath.FromSlash("/share/leads/leads.csv"),
+			"reports/../leads.csv":    "leads.csv",
+		}
+
+		for input, expected := range cases {
+			// Act
+			actual := cleanPath(input)
+
+			// Assert
+			assert.Equal(t, expected, actual, input)
+		}
+	})
+}
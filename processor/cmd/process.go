@@ -0,0 +1,2324 @@
+package cmd
+
+import (
+	"code/internal/anomaly"
+	"code/internal/api"
+	"code/internal/attachments"
+	"code/internal/bloom"
+	"code/internal/checkpoint"
+	"code/internal/config"
+	"code/internal/csv"
+	"code/internal/datalake"
+	"code/internal/deadletter"
+	"code/internal/dedupe"
+	"code/internal/emailcheck"
+	"code/internal/errcode"
+	"code/internal/failures"
+	"code/internal/filter"
+	"code/internal/fixedwidth"
+	"code/internal/history"
+	"code/internal/inference"
+	"code/internal/intentlog"
+	"code/internal/langdetect"
+	"code/internal/metaleads"
+	"code/internal/mirror"
+	"code/internal/models"
+	"code/internal/notes"
+	"code/internal/notfound"
+	"code/internal/ordering"
+	"code/internal/pii"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"code/internal/report"
+	"code/internal/schedule"
+	"code/internal/sheets"
+	"code/internal/stats"
+	"code/internal/territory"
+	"code/internal/trace"
+	"code/internal/usage"
+	"code/internal/xmlfeed"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var processCmd = &cobra.Command{
+	Use:   "process <file|sheet-url|meta:form-ids>",
+	Short: "Process leads from a CSV, Parquet or Avro file, Google Sheet or Meta Lead Ads forms",
+	Long: `Process leads from a CSV file, or a Google Sheet laid out the same way, and
+manage them via external APIs. Parquet, Avro and XML files are read directly,
+as are fixed-width files laid out in the config file; their columns are
+mapped like a CSV header.
+
+An input of meta:<form-id>[,<form-id>...] pulls the leads submitted to those
+Meta Lead Ads forms since the previous run, whose position is kept in a cursor
+file; schedule it with cron to ingest Lead Ads continuously.`,
+	Example: `  # Create or update every lead in a file
+  lead-processor process leads.csv
+
+  # Use four workers and skip leads already sent in the last week
+  lead-processor process leads.csv --workers 4 --skip-seen 7
+
+  # Try a sample of 200 leads first, aborting if over 2% would fail validation
+  lead-processor process partner.csv --preflight 200 --preflight-max-invalid 2
+
+  # Only accept leads that gave marketing consent
+  lead-processor process leads.csv --require-consent
+
+  # Only call the API overnight, waiting through the day until the file is done
+  lead-processor process leads.csv --run-window 22:00-06:00 --daemon
+
+  # Read a data engineering extract without converting it to CSV
+  lead-processor process leads.parquet
+
+  # Read a partner's mainframe extract, cut into columns by the config's fixed_width
+  lead-processor process partner.dat --input-format fixed-width --config config.yaml
+
+  # Read marketing's spreadsheet directly, authenticating as a service account
+  lead-processor process https://docs.google.com/spreadsheets/d/<id>/edit \
+    --google-credentials key.json --sheet-range 'Leads!A:H'
+
+  # Pull new Lead Ads leads, e.g. from cron every 15 minutes
+  lead-processor process meta:1234567890,9876543210 --meta-page-token "$META_PAGE_TOKEN" --meta-source LinkedIn`,
+	GroupID:           groupLeads,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeLeadFiles,
+	RunE:              runProcessCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(processCmd)
+	processCmd.Flags().String("cache-file", "", "Persist lookup responses here and revalidate them with ETags on later runs")
+	processCmd.Flags().String("mirror", "", "Answer lookups from this lead mirror, kept by sync snapshot and refreshed before the run")
+	processCmd.Flags().Duration("not-found-ttl", 0, "Reuse the API's answer that a lead doesn't exist for this long, e.g. 30s, forgetting it once the lead is created (0 = off)")
+	processCmd.Flags().String("bloom", "", "Skip lookups of emails this bloom filter, kept by sync bloom and refreshed before the run, rules out")
+	processCmd.Flags().Int("workers", 1, "Number of leads processed concurrently")
+	processCmd.Flags().Int("queue-size", pipeline.DefaultQueueSize, "Maximum leads buffered between pipeline stages")
+	processCmd.Flags().BoolP("verbose", "v", false, "Print pipeline queue depths while processing")
+	processCmd.Flags().String("output", "", "Stream one line per lead (email, action, lead ID, error) to stdout as it is decided: tsv or csv; everything else goes to stderr")
+	processCmd.Flags().String("history-dir", "", "Directory of the local run-history store, which keeps the email of every lead processed (off unless set)")
+	processCmd.Flags().Int("skip-seen", 0, "Skip leads processed with identical content in a previous run within this many days")
+	processCmd.Flags().Bool("no-reprocess", false, "Refuse to process a file identical to one already processed")
+	processCmd.Flags().String("intent-log", "", "Write-ahead log of creates and updates, reconciled after a crash (default intents.ndjson in --history-dir)")
+	processCmd.Flags().StringToString("label", nil, "Label the run, e.g. campaign=q3-webinar (repeatable); kept in run history and exports and sent to the API in the "+api.RunLabelsHeader+" header")
+	processCmd.Flags().String("events", "", "Write an NDJSON trace here: one event per pipeline stage and API call of each lead, with timings")
+	processCmd.Flags().Bool("require-consent", false, "Reject leads that have not given marketing consent")
+	processCmd.Flags().String("email-validation", string(emailcheck.LevelSyntax), emailValidationUsage)
+	processCmd.Flags().Bool("infer-source", false, "Fill in a missing source from referrer and event columns, or the config's source_rules")
+	processCmd.Flags().String("order-by", "", "Process high-value leads first: score, source or column:<name> (reads the whole file first)")
+	processCmd.Flags().Int("max-api-calls", 0, "Stop sending API requests after this many, deferring the remaining leads (0 = no limit)")
+	processCmd.Flags().Int("max-creates", 0, "Create at most this many leads, deferring the rest (0 = no limit)")
+	processCmd.Flags().String("dead-letter", "", deadLetterUsage)
+	processCmd.Flags().String("deferred-file", "", "Where deferred leads are written (default <file>.deferred.csv)")
+	processCmd.Flags().String("rejects-file", "", "Write leads failing validation or conflicting with the CRM here, in their original layout with the reason, for the sender to fix (default <file>.rejects.csv with --split-rejects-by)")
+	processCmd.Flags().String("split-rejects-by", "", "Split rejected leads into a file per value of this column, e.g. Partner, to send each partner of a merged feed their own fix list")
+	processCmd.Flags().Int("max-retries", 0, "Fail the run once this many rate-limit retries were made across all leads (0 = no limit)")
+	processCmd.Flags().Duration("max-retry-time", 0, "Fail the run once this much time was spent retrying across all leads, e.g. 10m (0 = no limit)")
+	processCmd.Flags().Int("atomic-batch", 0, "Apply leads in batches of this many, all or nothing: if one fails, the batch's applied leads are rolled back (0 = off)")
+	processCmd.Flags().String("deletion-mode", string(processor.DeletionArchive), deletionModeUsage)
+	processCmd.Flags().String("run-window", "", "Only call the API between these local times, e.g. 22:00-06:00; the run pauses with a checkpoint when the window closes")
+	processCmd.Flags().Bool("daemon", false, "With --run-window, wait for the window to reopen and resume instead of exiting")
+	processCmd.Flags().String("checkpoint-file", "", "Where a paused run's progress is kept (default <file>.checkpoint.json)")
+	processCmd.Flags().String("input-format", "", "Format of the input file: csv, parquet, avro, xml or fixed-width (default from its extension)")
+	processCmd.Flags().String("delimiter", "", "Character separating the columns of a CSV file, e.g. ; or tab (default guessed from the file)")
+	processCmd.Flags().String("quote", "", "Character quoting the cells of a CSV file, \" or ' (default guessed from the file)")
+	processCmd.Flags().String("header", headerAuto, "Whether the first row of a CSV file names the columns: yes, no, or auto to guess")
+	processCmd.Flags().String("sheet-range", sheets.DefaultRange, "Cells of a Google Sheet to read, header row first, e.g. Leads!A:H")
+	processCmd.Flags().String("google-credentials", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "Service-account key file for reading Google Sheets")
+	processCmd.Flags().String("meta-page-token", os.Getenv("META_PAGE_TOKEN"), "Page access token with leads_retrieval permission, for meta: inputs")
+	processCmd.Flags().String("meta-source", "", "Source given to Meta Lead Ads leads, whose forms have no source question")
+	processCmd.Flags().String("meta-cursor-file", "", "Where the newest processed Lead Ads lead of each form is kept (default meta-<form-ids>.cursor.json)")
+	processCmd.Flags().Bool("mailchimp", false, "Also subscribe created and updated leads to the config's Mailchimp audience")
+	processCmd.Flags().String("note", "", "Also add this note to the activity of created and updated leads; {file}, {run} and {date} are replaced with the input file, run ID and date")
+	processCmd.Flags().Bool("attachments", false, "Also upload the photos and documents in the config's attachments columns to created and updated leads")
+	processCmd.Flags().String("export", "", "After the run, write every lead's outcome to s3://bucket/prefix or a directory, partitioned by run date")
+	processCmd.Flags().String("export-format", datalake.FormatParquet, "Format of --export files: parquet or csv")
+	processCmd.Flags().String("filter", "", `Only process the leads this expression matches, e.g. 'source == "Webinar" && company != ""' (see Filters in the README)`)
+	processCmd.Flags().String("profile", "", "Import with the settings of this profile from the config's profiles, e.g. a partner's delimiter, mapping and target")
+	processCmd.Flags().String("max-memory", "", "Approximate memory budget (e.g. 512MB, 2GB); bounds buffered rows and spills results to disk")
+	processCmd.Flags().Bool("project-columns", false, "Keep only the columns this run reads of each row, saving memory on very wide files; deferred and review files get those columns only")
+	processCmd.Flags().StringSlice("keep-columns", nil, "With --project-columns, also keep these columns, e.g. for raw_source or a later run of the deferred file")
+	processCmd.Flags().Int("preflight", 0, "First look up a random sample of this many leads without writing, and abort if too many would fail (0 = off)")
+	processCmd.Flags().Float64("preflight-max-invalid", 5, "Abort when more than this percentage of the --preflight sample fails validation")
+	processCmd.Flags().Float64("preflight-max-conflicts", 5, "Abort when more than this percentage of the --preflight sample conflicts with the CRM")
+	processCmd.Flags().Int("reconcile", 0, "After the run, look up a random sample of this many created and updated leads again and report those the CRM doesn't hold as written (0 = off)")
+	processCmd.Flags().Duration("reconcile-delay", 0, "With --reconcile, wait this long before the lookups, e.g. 30s for an eventually-consistent CRM")
+	processCmd.Flags().String("policy", processor.PolicyUpsert, "How leads are decided: upsert, create-only, update-only, fields or review")
+	processCmd.Flags().StringSlice("policy-fields", nil, "Fields the fields policy updates on existing leads, e.g. company,status")
+	processCmd.Flags().Bool("respect-ownership", false, "Skip updates to leads the CRM has assigned to an owner, as a rep is working them; with --note they still get the note")
+	processCmd.Flags().String("review-file", "", "Where leads the review policy holds back are written (default <file>.review.csv)")
+
+	setFlagGroup(processCmd, "Performance Flags", "workers", "queue-size", "max-memory", "project-columns", "keep-columns", "cache-file", "not-found-ttl", "mirror", "bloom", "atomic-batch", "deletion-mode")
+	setFlagGroup(processCmd, "Policy Flags", "policy", "policy-fields", "respect-ownership", "review-file")
+	setFlagGroup(processCmd, "Pre-flight Flags", "preflight", "preflight-max-invalid", "preflight-max-conflicts", "reconcile", "reconcile-delay")
+	setFlagGroup(processCmd, "History Flags", "history-dir", "label", "skip-seen", "no-reprocess", "intent-log", "events")
+	setFlagGroup(processCmd, "Quota Flags", "order-by", "max-api-calls", "max-creates", "deferred-file", "max-retries", "max-retry-time")
+	setFlagGroup(processCmd, "Schedule Flags", "run-window", "daemon", "checkpoint-file")
+	setFlagGroup(processCmd, "Google Sheets Flags", "sheet-range", "google-credentials")
+	setFlagGroup(processCmd, "Meta Lead Ads Flags", "meta-page-token", "meta-source", "meta-cursor-file")
+	setFlagGroup(processCmd, "Sync Flags", "mailchimp", "attachments", "note", "export", "export-format")
+	_ = processCmd.RegisterFlagCompletionFunc("input-format", cobra.FixedCompletions(inputFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = processCmd.RegisterFlagCompletionFunc("header", cobra.FixedCompletions(headerModes, cobra.ShellCompDirectiveNoFileComp))
+	_ = processCmd.RegisterFlagCompletionFunc("deletion-mode", cobra.FixedCompletions(processor.DeletionModes(), cobra.ShellCompDirectiveNoFileComp))
+	_ = processCmd.RegisterFlagCompletionFunc("policy", cobra.FixedCompletions(processor.Policies(), cobra.ShellCompDirectiveNoFileComp))
+	_ = processCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(report.StreamFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = processCmd.RegisterFlagCompletionFunc("export-format", cobra.FixedCompletions(datalake.Formats, cobra.ShellCompDirectiveNoFileComp))
+	_ = processCmd.MarkFlagFilename("google-credentials", "json")
+	_ = processCmd.RegisterFlagCompletionFunc("order-by", cobra.FixedCompletions(
+		[]string{ordering.ByScore, ordering.BySource, ordering.ByColumn + ":"}, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace))
+	_ = processCmd.RegisterFlagCompletionFunc("email-validation", completeEmailLevels)
+	_ = processCmd.MarkFlagDirname("history-dir")
+	_ = processCmd.MarkFlagFilename("mirror", "json")
+	_ = processCmd.MarkFlagFilename("bloom", "json")
+	_ = processCmd.MarkFlagFilename("events", "ndjson")
+}
+
+// emailValidationUsage describes --email-validation for process and serve
+const emailValidationUsage = "How strictly emails are checked: syntax, rfc5322, dns (domain accepts mail) or smtp (asks the mail server; slow, opt-in)"
+
+// deadLetterUsage describes --dead-letter for process and serve
+const deadLetterUsage = "Write leads that fail with API or processing errors, with the error and source row, to this NDJSON file or s3://bucket/prefix"
+
+// deletionModeUsage describes --deletion-mode for process and erase
+const deletionModeUsage = "How leads are removed, e.g. the creates of a rolled-back atomic batch: archive (restorable; deletes instead where the server cannot, unless given explicitly) or delete (permanent)"
+
+// deletionHint points to --deletion-mode delete when archiving isn't possible
+func deletionHint(mode processor.DeletionMode) string {
+	if mode != processor.DeletionArchive {
+		return ""
+	}
+	return "; use --deletion-mode delete to delete leads permanently instead"
+}
+
+// resolveDeletionMode falls back from archiving, the default, to deleting
+// when the server can't archive or its features are unknown, warning that
+// leads will be gone for good. Archiving asked for with --deletion-mode is
+// kept, to fail where it isn't supported.
+func resolveDeletionMode(cmd *cobra.Command, client interface{}, mode processor.DeletionMode, features processor.ServerFeatures) processor.DeletionMode {
+	if mode != processor.DeletionArchive || cmd.Flags().Changed("deletion-mode") || processor.CanArchive(client, features) {
+		return mode
+	}
+	if _, ok := processor.Unwrap(client).(processor.DeleteClient); !ok {
+		return mode
+	}
+	LogWarn("The server cannot archive leads, deleting them permanently instead")
+	printer.Printf("Warning: the server cannot archive leads, so they will be deleted permanently instead\n")
+	return processor.DeletionDelete
+}
+
+// attachClient returns client as an AttachClient when it can attach files
+// to leads. Wrapping clients, such as the mirror's, have the method either
+// way, so the client they wrap is asked.
+func attachClient(client processor.APIClient) (processor.AttachClient, bool) {
+	if _, ok := processor.Unwrap(client).(processor.AttachClient); !ok {
+		return nil, false
+	}
+	attacher, ok := client.(processor.AttachClient)
+	return attacher, ok
+}
+
+// noteClient returns client as a NoteClient when it can add notes to
+// leads, asking the client any wrapping ones wrap like attachClient
+func noteClient(client processor.APIClient) (processor.NoteClient, bool) {
+	if _, ok := processor.Unwrap(client).(processor.NoteClient); !ok {
+		return nil, false
+	}
+	noter, ok := client.(processor.NoteClient)
+	return noter, ok
+}
+
+// leadClientWrappers are the clients process wraps around the API client
+// to answer lookups without it
+type leadClientWrappers struct {
+	bloom    *bloom.Client
+	notFound *notfound.Client
+	saves    []func()
+}
+
+// Save writes the mirror and the bloom filter back, with what the run
+// learned
+func (w *leadClientWrappers) Save() {
+	for _, save := range w.saves {
+		save()
+	}
+}
+
+// wrapLeadClient wraps client in the lead mirror, bloom filter and
+// not-found cache the flags ask for, bringing the mirror and filter up to
+// date first
+func wrapLeadClient(cmd *cobra.Command, cfg *config.Config, apiURL string, meter *usage.Meter, client processor.APIClient, mirrorFile, bloomFile string, notFoundTTL time.Duration) (processor.APIClient, *leadClientWrappers, error) {
+	wrappers := &leadClientWrappers{}
+
+	// Lookups are answered from the mirror, refreshed first; writes still
+	// go to the API and update it
+	if mirrorFile != "" {
+		export, err := mirrorExport(cmd, cfg, apiURL, api.PageOptions{}, api.WithMiddleware(meter.Middleware))
+		if err != nil {
+			return nil, wrappers, fmt.Errorf("invalid --mirror: %w", err)
+		}
+		leadMirror, err := mirror.Open(mirrorFile)
+		if err != nil {
+			return nil, wrappers, err
+		}
+		if leadMirror.SyncedAt().IsZero() {
+			return nil, wrappers, fmt.Errorf("invalid --mirror: %s has not been synced yet, run sync snapshot --mirror %s first", mirrorFile, mirrorFile)
+		}
+		if _, err := syncMirror(leadMirror, export, false, mirrorFile); err != nil {
+			return nil, wrappers, err
+		}
+		wrappers.saves = append(wrappers.saves, func() {
+			if err := leadMirror.Save(); err != nil {
+				LogError("Failed to save lead mirror", err, "mirror", mirrorFile)
+			}
+		})
+		client = mirror.NewClient(client, leadMirror)
+		LogInfo("Answering lookups from the lead mirror", "mirror", mirrorFile, "leads", leadMirror.Len())
+	}
+
+	// Lookups of emails the filter rules out are skipped; the rest still
+	// call the API
+	if bloomFile != "" {
+		export, err := mirrorExport(cmd, cfg, apiURL, api.PageOptions{}, api.WithMiddleware(meter.Middleware))
+		if err != nil {
+			return nil, wrappers, fmt.Errorf("invalid --bloom: %w", err)
+		}
+		filter, err := bloom.Open(bloomFile)
+		if err != nil {
+			return nil, wrappers, err
+		}
+		if filter.SyncedAt().IsZero() {
+			return nil, wrappers, fmt.Errorf("invalid --bloom: %s has not been built yet, run sync bloom --bloom %s first", bloomFile, bloomFile)
+		}
+		if _, err := syncBloom(filter, export, false, bloom.DefaultFalsePositiveRate, bloomFile); err != nil {
+			return nil, wrappers, err
+		}
+		wrappers.saves = append(wrappers.saves, func() {
+			if err := filter.Save(); err != nil {
+				LogError("Failed to save bloom filter", err, "bloom", bloomFile)
+			}
+		})
+		wrappers.bloom = bloom.NewClient(client, filter)
+		client = wrappers.bloom
+		LogInfo("Checking lookups against the bloom filter", "bloom", bloomFile, "emails", filter.Count())
+	}
+
+	// Duplicates of a lead the CRM didn't have aren't looked up again
+	// until it is created or the TTL passes
+	if notFoundTTL > 0 {
+		wrappers.notFound = notfound.NewClient(client, notFoundTTL)
+		client = wrappers.notFound
+	}
+
+	return client, wrappers, nil
+}
+
+// checkpointInterval is how often a windowed run saves its progress
+const checkpointInterval = 5 * time.Second
+
+// estimatedLeadBytes is a rough per-row footprint used to turn --max-memory into row limits
+const estimatedLeadBytes = 1024
+
+// memoryPlan splits a memory budget between pipeline queues and the in-memory report buffer
+type memoryPlan struct {
+	queueSize     int
+	reportRecords int
+}
+
+// planMemory gives a quarter of the budget to the four stage queues and a
+// quarter to buffered report records, leaving the rest for the runtime
+func planMemory(budget int64, queueSize int) memoryPlan {
+	if budget <= 0 {
+		return memoryPlan{queueSize: queueSize}
+	}
+
+	perQueue := int(budget / 4 / 4 / estimatedLeadBytes)
+	if perQueue < 1 {
+		perQueue = 1
+	}
+	if perQueue < queueSize {
+		queueSize = perQueue
+	}
+
+	reportRecords := int(budget / 4 / estimatedLeadBytes)
+	if reportRecords < 1 {
+		reportRecords = 1
+	}
+
+	return memoryPlan{queueSize: queueSize, reportRecords: reportRecords}
+}
+
+// formatByteSize renders a byte count with a binary unit, e.g. "1.5 MiB"
+func formatByteSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes) / unit
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
+
+// parseByteSize parses sizes such as "512MB", "2GiB" or "1048576"
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	factor := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			factor = unit.factor
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	return n * factor, nil
+}
+
+func runProcessCommand(cmd *cobra.Command, args []string) error {
+	// Initialize structured logging with default level
+	initLogger("info")
+
+	// Decisions streamed to stdout get it to themselves; everything else
+	// printed goes to stderr
+	var decisions *report.Stream
+	if output, _ := cmd.Flags().GetString("output"); output != "" {
+		var err error
+		if decisions, err = report.NewStream(os.Stdout, output); err != nil {
+			return fmt.Errorf("invalid --output: %w", err)
+		}
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// The profile's settings fill in the flags not given, so it goes first
+	profile, err := applyProfile(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	cacheFile, _ := cmd.Flags().GetString("cache-file")
+	cacheFile = cleanPath(cacheFile)
+	workers, _ := cmd.Flags().GetInt("workers")
+	queueSize, _ := cmd.Flags().GetInt("queue-size")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	maxMemory, _ := cmd.Flags().GetString("max-memory")
+	historyDir, _ := cmd.Flags().GetString("history-dir")
+	historyDir = cleanPath(historyDir)
+	skipSeenDays, _ := cmd.Flags().GetInt("skip-seen")
+	noReprocess, _ := cmd.Flags().GetBool("no-reprocess")
+	intentLogFile, _ := cmd.Flags().GetString("intent-log")
+	eventsFile, _ := cmd.Flags().GetString("events")
+	labelPairs, _ := cmd.Flags().GetStringToString("label")
+	eventsFile = cleanPath(eventsFile)
+	requireConsent, _ := cmd.Flags().GetBool("require-consent")
+	inferSource, _ := cmd.Flags().GetBool("infer-source")
+	emailValidation, _ := cmd.Flags().GetString("email-validation")
+	orderBy, _ := cmd.Flags().GetString("order-by")
+	filterExpr, _ := cmd.Flags().GetString("filter")
+	maxAPICalls, _ := cmd.Flags().GetInt("max-api-calls")
+	maxCreates, _ := cmd.Flags().GetInt("max-creates")
+	deferredFile, _ := cmd.Flags().GetString("deferred-file")
+	rejectsFile, _ := cmd.Flags().GetString("rejects-file")
+	splitRejectsBy, _ := cmd.Flags().GetString("split-rejects-by")
+	maxRetries, _ := cmd.Flags().GetInt("max-retries")
+	maxRetryTime, _ := cmd.Flags().GetDuration("max-retry-time")
+	atomicBatch, _ := cmd.Flags().GetInt("atomic-batch")
+	deletionModeValue, _ := cmd.Flags().GetString("deletion-mode")
+	syncMailchimp, _ := cmd.Flags().GetBool("mailchimp")
+	syncAttachments, _ := cmd.Flags().GetBool("attachments")
+	projectColumns, _ := cmd.Flags().GetBool("project-columns")
+	keepColumns, _ := cmd.Flags().GetStringSlice("keep-columns")
+	noteTemplate, _ := cmd.Flags().GetString("note")
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	deadLetterDest, _ := cmd.Flags().GetString("dead-letter")
+	exportDest, _ := cmd.Flags().GetString("export")
+	exportFormat, _ := cmd.Flags().GetString("export-format")
+	deferredFile = cleanPath(deferredFile)
+	rejectsFile = cleanPath(rejectsFile)
+	runWindow, _ := cmd.Flags().GetString("run-window")
+	daemon, _ := cmd.Flags().GetBool("daemon")
+	checkpointFile, _ := cmd.Flags().GetString("checkpoint-file")
+	checkpointFile = cleanPath(checkpointFile)
+	mirrorFile, _ := cmd.Flags().GetString("mirror")
+	mirrorFile = cleanPath(mirrorFile)
+	notFoundTTL, _ := cmd.Flags().GetDuration("not-found-ttl")
+	bloomFile, _ := cmd.Flags().GetString("bloom")
+	bloomFile = cleanPath(bloomFile)
+	preflightSize, _ := cmd.Flags().GetInt("preflight")
+	preflightMaxInvalid, _ := cmd.Flags().GetFloat64("preflight-max-invalid")
+	preflightMaxConflicts, _ := cmd.Flags().GetFloat64("preflight-max-conflicts")
+	reconcileSize, _ := cmd.Flags().GetInt("reconcile")
+	reconcileDelay, _ := cmd.Flags().GetDuration("reconcile-delay")
+	policyName, _ := cmd.Flags().GetString("policy")
+	policyFields, _ := cmd.Flags().GetStringSlice("policy-fields")
+	respectOwnership, _ := cmd.Flags().GetBool("respect-ownership")
+	reviewFile, _ := cmd.Flags().GetString("review-file")
+	reviewFile = cleanPath(reviewFile)
+
+	if skipSeenDays > 0 && historyDir == "" {
+		return fmt.Errorf("--skip-seen requires --history-dir")
+	}
+
+	// The mirror already answers every lookup without the API
+	if mirrorFile != "" && bloomFile != "" {
+		return fmt.Errorf("--bloom cannot be combined with --mirror")
+	}
+
+	labels, err := history.ParseLabels(labelPairs)
+	if err != nil {
+		return fmt.Errorf("invalid --label: %w", err)
+	}
+
+	deletionMode, err := processor.ParseDeletionMode(deletionModeValue)
+	if err != nil {
+		return fmt.Errorf("invalid --deletion-mode: %w", err)
+	}
+
+	// Deferring or pausing part of a batch would leave it half applied
+	if atomicBatch > 0 {
+		if runWindow != "" || maxAPICalls > 0 || maxCreates > 0 || maxRetries > 0 || maxRetryTime > 0 {
+			return fmt.Errorf("--atomic-batch cannot be combined with --run-window, --max-api-calls, --max-creates, --max-retries or --max-retry-time")
+		}
+		// A rolled-back lead would stay subscribed
+		if syncMailchimp {
+			return fmt.Errorf("--atomic-batch cannot be combined with --mailchimp")
+		}
+		if syncAttachments {
+			return fmt.Errorf("--atomic-batch cannot be combined with --attachments")
+		}
+		if noteTemplate != "" {
+			return fmt.Errorf("--atomic-batch cannot be combined with --note")
+		}
+	}
+
+	// Check the export destination before any lead is sent
+	var exporter *datalake.Exporter
+	if exportDest != "" {
+		if !strings.HasPrefix(exportDest, "s3://") {
+			exportDest = cleanPath(exportDest)
+		}
+		var err error
+		exporter, err = datalake.New(exportDest, exportFormat, storageHTTPClient())
+		if err != nil {
+			return fmt.Errorf("invalid --export: %w", err)
+		}
+		exporter.SetLabels(labels.String())
+		if !cfg.Export.PII.IsZero() {
+			hasher, err := pii.New(cfg.Export.PII)
+			if err != nil {
+				return fmt.Errorf("invalid export.pii: %w", err)
+			}
+			exporter.SetPII(hasher)
+			LogInfo("Pseudonymizing exported columns", "fields", cfg.Export.PII.Fields)
+		}
+	}
+
+	var window *schedule.Window
+	if runWindow != "" {
+		w, err := schedule.ParseWindow(runWindow)
+		if err != nil {
+			return fmt.Errorf("invalid --run-window: %w", err)
+		}
+		window = &w
+	} else if daemon {
+		return fmt.Errorf("--daemon requires --run-window")
+	}
+
+	emailLevel, err := emailcheck.ParseLevel(emailValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --email-validation: %w", err)
+	}
+
+	var orderKey *ordering.Key
+	if orderBy != "" {
+		key, err := ordering.ParseKey(orderBy)
+		if err != nil {
+			return fmt.Errorf("invalid --order-by: %w", err)
+		}
+		orderKey = &key
+	}
+
+	dialect, err := parseDialectFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	var leadFilter *filter.Filter
+	if strings.TrimSpace(filterExpr) != "" {
+		leadFilter, err = filter.Parse(filterExpr)
+		if err != nil {
+			return fmt.Errorf("invalid --filter: %w", err)
+		}
+	}
+
+	geo, err := loadGeoIP(cfg)
+	if err != nil {
+		return err
+	}
+
+	policy, err := processor.ParsePolicy(policyName, policyFields)
+	if err != nil {
+		return fmt.Errorf("invalid --policy: %w", err)
+	}
+	if respectOwnership {
+		if err := checkOwnershipProvider(cmd); err != nil {
+			return err
+		}
+	}
+
+	memoryBudget, err := parseByteSize(maxMemory)
+	if err != nil {
+		return fmt.Errorf("invalid --max-memory: %w", err)
+	}
+	plan := planMemory(memoryBudget, queueSize)
+	if memoryBudget > 0 {
+		debug.SetMemoryLimit(memoryBudget)
+	}
+
+	// Get the CSV file path, Google Sheet URL or Lead Ads forms
+	input := args[0]
+	if !sheets.IsURL(input) && !metaleads.IsInput(input) {
+		input = cleanPath(input)
+	}
+
+	if inputFormat != "" && (sheets.IsURL(input) || metaleads.IsInput(input)) {
+		return fmt.Errorf("--input-format only applies to files")
+	}
+	if _, err := detectInputFormat(input, inputFormat); err != nil {
+		return err
+	}
+
+	// Each pull of Lead Ads is new leads, never a file seen before
+	if metaleads.IsInput(input) && noReprocess {
+		return fmt.Errorf("--no-reprocess cannot be used with Meta Lead Ads inputs")
+	}
+
+	if preflightSize < 0 {
+		return fmt.Errorf("invalid --preflight: cannot be negative")
+	}
+	if preflightMaxInvalid < 0 || preflightMaxInvalid > 100 {
+		return fmt.Errorf("invalid --preflight-max-invalid: must be between 0 and 100")
+	}
+	if preflightMaxConflicts < 0 || preflightMaxConflicts > 100 {
+		return fmt.Errorf("invalid --preflight-max-conflicts: must be between 0 and 100")
+	}
+	if reconcileSize < 0 {
+		return fmt.Errorf("invalid --reconcile: cannot be negative")
+	}
+	if reconcileDelay < 0 {
+		return fmt.Errorf("invalid --reconcile-delay: cannot be negative")
+	}
+	// The sample would be a pull of its own, moving past leads never processed
+	if metaleads.IsInput(input) && preflightSize > 0 {
+		return fmt.Errorf("--preflight cannot be used with Meta Lead Ads inputs")
+	}
+
+	startedAt := time.Now()
+	LogInfo("Starting lead processing", "input", input, "apiURL", apiURL, "workers", workers)
+
+	printer.Printf("Processing leads from: %s\n", input)
+	printer.Printf("API URL: %s\n", apiURL)
+	if name, _ := cmd.Flags().GetString("profile"); name != "" {
+		printer.Printf("Profile: %s\n", name)
+	}
+	if len(labels) > 0 {
+		printer.Printf("Labels: %s\n", labels)
+	}
+
+	// Initialize components
+	var clientOpts []api.ClientOption
+	if len(labels) > 0 {
+		clientOpts = append(clientOpts, api.WithMiddleware(api.HeaderMiddleware(api.RunLabelsHeader, labels.String())))
+	}
+
+	// Outside the run window requests are held back, before the meter sees them
+	var gate *schedule.Gate
+	if window != nil {
+		gate = schedule.NewGate(*window, daemon, processor.ErrRunPaused)
+		gate.OnPause(func(resumeAt time.Time) {
+			LogInfo("Outside run window, waiting", "window", window.String(), "resumeAt", resumeAt.Format(time.RFC3339))
+			printer.Printf("Outside the run window (%s), waiting until %s\n", window, resumeAt.Format("2006-01-02 15:04"))
+		})
+		clientOpts = append(clientOpts, api.WithMiddleware(gate.Middleware))
+	}
+
+	// Retries beyond the run's budget fail the run instead of every row in turn
+	retryBudget := api.NewRetryBudget(maxRetries, maxRetryTime, processor.ErrRetryBudgetExhausted)
+	clientOpts = append(clientOpts, api.WithMiddleware(retryBudget.Middleware))
+
+	meter := usage.NewMeter()
+	meter.SetMaxCalls(maxAPICalls, processor.ErrBudgetExhausted)
+	clientOpts = append(clientOpts, api.WithMiddleware(meter.Middleware))
+	if cacheFile != "" {
+		lookupCache, err := api.NewFileLookupCache(cacheFile)
+		if err != nil {
+			LogError("Failed to load lookup cache", err, "cacheFile", cacheFile)
+			return fmt.Errorf("failed to load lookup cache: %w", err)
+		}
+		defer func() {
+			if err := lookupCache.Save(); err != nil {
+				LogError("Failed to save lookup cache", err, "cacheFile", cacheFile)
+			}
+		}()
+		clientOpts = append(clientOpts, api.WithLookupCache(lookupCache))
+	}
+
+	apiAdapter, err := newLeadClient(cmd, cfg, apiURL, clientOpts...)
+	if err != nil {
+		return err
+	}
+
+	apiAdapter, wrappers, err := wrapLeadClient(cmd, cfg, apiURL, meter, apiAdapter, mirrorFile, bloomFile, notFoundTTL)
+	defer wrappers.Save()
+	if err != nil {
+		return err
+	}
+
+	csvReader := csv.NewCSVReader()
+	csvReader.SetMapping(cfg.Mapping)
+	csvReader.SetIDGenerator(cfg.IDGenerator())
+	csvReader.SetDelimiter(dialect.comma)
+	csvReader.SetQuote(dialect.quote)
+
+	// Checkpoints and deferred rows are kept next to a file, or in the
+	// working directory for a sheet
+	source, err := newFileSource(input, inputFormat, cfg, csvReader)
+	if err != nil {
+		return err
+	}
+	sidecarBase := strings.TrimSuffix(input, filepath.Ext(input))
+	if sheets.IsURL(input) {
+		sheet, err := newSheetSource(cmd, input, csvReader)
+		if err != nil {
+			return err
+		}
+		source = sheet
+		sidecarBase = "sheet-" + sheet.SpreadsheetID()
+	}
+	var metaSource *metaleads.Reader
+	var metaCursorFile string
+	if metaleads.IsInput(input) {
+		metaSource, metaCursorFile, err = newMetaSource(cmd, input, csvReader)
+		if err != nil {
+			return err
+		}
+		source = metaSource
+		sidecarBase = strings.TrimSuffix(metaCursorFile, ".cursor.json")
+	}
+
+	if err := dialect.apply(source, csvReader, verbose); err != nil {
+		return err
+	}
+
+	// A partner's format change stops here rather than in the CRM
+	if len(profile.Schema.Header) > 0 {
+		profileName, _ := cmd.Flags().GetString("profile")
+		if err := checkSchema(source, profileName, profile.Schema); err != nil {
+			return err
+		}
+	}
+
+	// Very wide files take far less memory without the columns no part of
+	// the run reads
+	if projectColumns {
+		if splitRejectsBy != "" {
+			keepColumns = append(keepColumns, splitRejectsBy)
+		}
+		csvReader.SetProjection(projectedColumns(cfg, leadFilter, orderKey, inferSource, syncAttachments, keepColumns))
+		header, err := source.ReadHeader()
+		if err != nil {
+			LogError("Failed to read header", err, "source", source.Name())
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+		_, dropped, err := csvReader.Projection(header)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+		if len(dropped) > 0 {
+			LogWarn("Ignoring columns the run doesn't read", "source", source.Name(), "columns", strings.Join(dropped, ", "))
+			printer.Printf("Warning: ignoring %d of %d columns: %s\n", len(dropped), len(header), columnList(dropped))
+		}
+	} else if len(keepColumns) > 0 {
+		return fmt.Errorf("--keep-columns needs --project-columns")
+	} else if header, err := source.ReadHeader(); err == nil && len(header) >= wideColumns {
+		LogWarn("Reading a very wide file", "source", source.Name(), "columns", len(header))
+		printer.Printf("Warning: %s has %d columns; --project-columns keeps only the ones this run reads\n", source.Name(), len(header))
+	}
+
+	leadProcessor := processor.NewLeadProcessor(apiAdapter)
+	if err := leadProcessor.SetStages(cfg.Stages); err != nil {
+		return fmt.Errorf("invalid stages: %w", err)
+	}
+	leadProcessor.SetProtectedFields(cfg.ProtectedFields)
+	leadProcessor.SetEquality(cfg.Equality)
+	leadProcessor.SetRequireConsent(requireConsent)
+	leadProcessor.SetRespectOwnership(respectOwnership)
+	leadProcessor.SetMaxCreates(maxCreates)
+	leadProcessor.SetPolicy(policy)
+	validation := validationOptions(cfg, emailLevel)
+	leadProcessor.SetValidation(validation)
+
+	// A windowed run resumes from the checkpoint a paused run left behind
+	var fileHash string
+	skip := 0
+	if window != nil || historyDir != "" {
+		fileHash, err = source.Hash()
+		if err != nil {
+			LogError("Failed to fingerprint lead source", err, "source", source.Name())
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+	}
+	if window != nil {
+		if checkpointFile == "" {
+			checkpointFile = sidecarBase + ".checkpoint.json"
+		}
+		previous, err := checkpoint.Load(checkpointFile)
+		if err != nil {
+			LogError("Failed to load checkpoint", err, "checkpointFile", checkpointFile)
+			return err
+		}
+		switch {
+		case previous == nil:
+		case previous.Matches(fileHash, orderBy):
+			skip = previous.Completed
+			LogInfo("Resuming from checkpoint", "checkpointFile", checkpointFile, "completed", skip)
+			printer.Printf("Resuming from checkpoint: %d lead(s) already processed\n", skip)
+		default:
+			LogWarn("Ignoring checkpoint for a different file or ordering", "checkpointFile", checkpointFile)
+			printer.Printf("Warning: ignoring %s, it was saved for a different file or --order-by\n", checkpointFile)
+		}
+
+		// Don't start, not even to detect server features, outside the window
+		if err := gate.Wait(context.Background()); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+	}
+
+	// Detect optional server features; unknown servers get legacy behaviour
+	var features processor.ServerFeatures
+	if detector, ok := apiAdapter.(featureDetector); ok {
+		features, err = detector.DetectFeatures()
+		if err != nil {
+			LogWarn("Failed to detect server capabilities, using legacy behaviour", "error", err.Error())
+		} else {
+			LogInfo("Detected server capabilities", "patch", features.Patch, "domainLookup", features.DomainLookup, "archive", features.Archive)
+			leadProcessor.SetFeatures(features)
+		}
+	}
+
+	// Open the run-history store
+	var historyStore *history.Store
+	var dedupeIndex *dedupe.Index
+	var run *history.Run
+	var leadHandler pipeline.LeadProcessor = leadProcessor
+	if historyDir != "" {
+		historyStore, err = history.Open(historyDir)
+		if err != nil {
+			LogError("Failed to open run history", err, "historyDir", historyDir)
+			return fmt.Errorf("failed to open run history: %w", err)
+		}
+		defer historyStore.Close()
+		run = historyStore.StartRun(source.Name())
+
+		// The dedupe index is saved even when the run fails part way
+		dedupeIndex, err = openDedupeIndex(historyDir, historyStore)
+		if err != nil {
+			LogError("Failed to open dedupe index", err, "historyDir", historyDir)
+			return err
+		}
+		defer func() {
+			if err := dedupeIndex.Save(); err != nil {
+				LogError("Failed to save dedupe index", err, "historyDir", historyDir)
+			}
+		}()
+		run.Labels = labels
+
+		// Guard against accidentally importing the same file twice
+		run.FileHash = fileHash
+
+		// A profile names the partner's feed; otherwise its dated file names do
+		run.Feed = history.FeedOf(source.Name())
+		if profileName, _ := cmd.Flags().GetString("profile"); profileName != "" {
+			run.Feed = profileName
+		}
+		if info, err := os.Stat(source.Name()); err == nil && info.Mode().IsRegular() {
+			run.Bytes = info.Size()
+		}
+
+		if previous := historyStore.FindRunByFileHash(fileHash); previous != nil && skip == 0 {
+			msg := fmt.Sprintf("this file was already processed in run %s on %s", previous.ID, previous.StartedAt.Format("2006-01-02 15:04:05 MST"))
+			if noReprocess {
+				LogError("Refusing to reprocess file", fmt.Errorf("%s", msg), "source", source.Name())
+				return fmt.Errorf("refusing to reprocess %s: %s", source.Name(), msg)
+			}
+			LogWarn("Reprocessing a file that was already processed", "source", source.Name(), "previousRun", previous.ID, "previousDate", previous.StartedAt.Format(time.RFC3339))
+			printer.Printf("Warning: this file was already processed in run %s on %s\n", previous.ID, previous.StartedAt.Format("2006-01-02 15:04:05 MST"))
+		}
+
+		if skipSeenDays > 0 {
+			window := time.Duration(skipSeenDays) * 24 * time.Hour
+			leadProcessor.SetSkipSeen(dedupeIndex, window)
+			LogInfo("Skipping leads seen in recent runs", "days", skipSeenDays)
+		}
+
+		if intentLogFile == "" {
+			intentLogFile = filepath.Join(historyDir, "intents.ndjson")
+		}
+	}
+
+	// Creates and updates are written ahead, so a crash mid-request can be
+	// reconciled instead of leaving the lead in an unknown state
+	if intentLogFile != "" {
+		runID := ""
+		if run != nil {
+			runID = run.ID
+		}
+		intents, err := intentlog.Open(cleanPath(intentLogFile), runID)
+		if err != nil {
+			LogError("Failed to open intent log", err, "intentLog", intentLogFile)
+			return err
+		}
+		defer intents.Close()
+		reconcileIntents(intents, apiAdapter, historyStore, dedupeIndex)
+		leadProcessor.SetIntentLog(intents)
+	}
+
+	// Each lead's stages and API calls are traced with their timings
+	var events *trace.Log
+	if eventsFile != "" {
+		runID := ""
+		if run != nil {
+			runID = run.ID
+		}
+		events, err = trace.Open(eventsFile, runID)
+		if err != nil {
+			return fmt.Errorf("invalid --events: %w", err)
+		}
+		defer events.Close()
+		leadProcessor.SetTracer(events)
+	}
+
+	// Leads that fail for good are kept, with their error, for inspection and replay
+	var deadLetters *deadletter.Writer
+	if deadLetterDest != "" {
+		if !strings.HasPrefix(deadLetterDest, "s3://") {
+			deadLetterDest = cleanPath(deadLetterDest)
+		}
+		runID := uuid.NewString()
+		if run != nil {
+			runID = run.ID
+		}
+		deadLetters, err = deadletter.Open(deadLetterDest, runID, storageHTTPClient())
+		if err != nil {
+			return fmt.Errorf("invalid --dead-letter: %w", err)
+		}
+		defer deadLetters.Close()
+	}
+
+	// Written leads are also subscribed to the marketing audience
+	if syncMailchimp {
+		sink, err := newMailchimpSink(cfg)
+		if err != nil {
+			return err
+		}
+		leadHandler = processor.NewSinkProcessor(leadHandler, sink)
+		LogInfo("Subscribing written leads to Mailchimp", "listID", cfg.Mailchimp.ListID)
+	}
+
+	// Written leads get the photos and documents their rows name; relative
+	// paths are read next to the input file
+	if syncAttachments {
+		attacher, ok := attachClient(apiAdapter)
+		if !ok {
+			return fmt.Errorf("--attachments: the API client cannot attach files to leads")
+		}
+		baseDir := "."
+		if !sheets.IsURL(input) && !metaleads.IsInput(input) {
+			baseDir = filepath.Dir(input)
+		}
+		uploader, err := attachments.NewUploader(cfg.Attachments, attacher, storageHTTPClient(), baseDir)
+		if err != nil {
+			return fmt.Errorf("--attachments: %w", err)
+		}
+		leadHandler = processor.NewSinkProcessor(leadHandler, uploader)
+		LogInfo("Uploading attachments to written leads", "columns", strings.Join(cfg.Attachments.Columns, ", "))
+	}
+
+	// Written leads get a note saying where they were imported from, so
+	// sales reps can see it in the CRM
+	if noteTemplate != "" {
+		noter, ok := noteClient(apiAdapter)
+		if !ok {
+			return fmt.Errorf("--note: the API client cannot add notes to leads")
+		}
+		noteRun := notes.Run{File: source.Name(), ID: uuid.NewString(), Date: startedAt}
+		if !sheets.IsURL(input) && !metaleads.IsInput(input) {
+			noteRun.File = filepath.Base(input)
+		}
+		if run != nil {
+			noteRun.ID, noteRun.Date = run.ID, run.StartedAt
+		}
+		text, err := notes.Expand(noteTemplate, noteRun)
+		if err != nil {
+			return fmt.Errorf("invalid --note: %w", err)
+		}
+		noteProcessor := processor.NewSinkProcessor(leadHandler, notes.NewSink(noter, text))
+		// Owned leads aren't updated, but their owner still learns of the import
+		noteProcessor.SetSendOwned(true)
+		leadHandler = noteProcessor
+		LogInfo("Adding a note to written leads", "note", text)
+	}
+
+	// Batches are applied all or nothing, compensating through the API
+	if atomicBatch > 0 {
+		deletionMode = resolveDeletionMode(cmd, apiAdapter, deletionMode, features)
+		leadProcessor.SetDeletionMode(deletionMode)
+		batches, err := processor.NewAtomicBatchProcessor(leadHandler, leadProcessor)
+		if err != nil {
+			return fmt.Errorf("--atomic-batch: %w%s", err, deletionHint(deletionMode))
+		}
+		leadHandler = batches
+		LogInfo("Applying leads in atomic batches", "batchSize", atomicBatch)
+	}
+
+	// Leads the run budget can't cover are kept, in their original layout, for a later run
+	var deferredLeads *csv.RowWriter
+	if maxAPICalls > 0 || maxCreates > 0 {
+		if deferredFile == "" {
+			deferredFile = sidecarBase + ".deferred.csv"
+		}
+		header, err := rowLayout(source, csvReader)
+		if err != nil {
+			LogError("Failed to read header", err, "source", source.Name())
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+		deferredLeads = csv.NewRowWriter(deferredFile, header)
+		// A resumed run keeps what earlier sessions deferred
+		deferredLeads.SetAppend(skip > 0)
+		defer deferredLeads.Close()
+	}
+
+	// Leads the review policy holds back are kept, in their original layout, for someone to check
+	var reviewLeads *csv.RowWriter
+	if strings.EqualFold(policyName, processor.PolicyReview) {
+		if reviewFile == "" {
+			reviewFile = sidecarBase + ".review.csv"
+		}
+		header, err := rowLayout(source, csvReader)
+		if err != nil {
+			LogError("Failed to read header", err, "source", source.Name())
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+		reviewLeads = csv.NewRowWriter(reviewFile, header)
+		reviewLeads.SetAppend(skip > 0)
+		defer reviewLeads.Close()
+	}
+
+	// Leads the sender has to fix are kept, in their original layout, to send back
+	var rejectedLeads *csv.RejectWriter
+	if rejectsFile != "" || splitRejectsBy != "" {
+		if rejectsFile == "" {
+			rejectsFile = sidecarBase + ".rejects.csv"
+		}
+		header, err := rowLayout(source, csvReader)
+		if err != nil {
+			LogError("Failed to read header", err, "source", source.Name())
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+		rejectedLeads = csv.NewRejectWriter(rejectsFile, header)
+		if splitRejectsBy != "" {
+			if err := rejectedLeads.SetSplitBy(splitRejectsBy); err != nil {
+				return fmt.Errorf("--split-rejects-by: %w in %s", err, source.Name())
+			}
+		}
+		rejectedLeads.SetAppend(skip > 0)
+		defer rejectedLeads.Close()
+	}
+
+	// Stream leads from CSV through the pipeline
+	LogInfo("Reading leads from CSV file")
+	printer.Printf("Reading leads from CSV file...\n")
+
+	rewrites := leadTransforms(cfg, inferSource, profile.Transforms.Defaults, geo)
+
+	// A sample is looked up first, so a bad mapping aborts the run before a
+	// single lead is written. A resumed run already passed it.
+	if preflightSize > 0 && skip == 0 {
+		sample := leadTransforms(cfg, inferSource, profile.Transforms.Defaults, geo)
+		if err := preflight(leadProcessor, source, sample.transforms, leadFilter, preflightSize, preflightMaxInvalid, preflightMaxConflicts); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+	}
+
+	pipelineCfg := pipeline.Config{
+		QueueSize:  plan.queueSize,
+		Workers:    workers,
+		Transforms: rewrites.transforms,
+		Validation: validation,
+		Skip:       skip,
+		BatchSize:  atomicBatch,
+	}
+	if events != nil {
+		pipelineCfg.Observe = events.Stage
+	}
+	if leadFilter != nil {
+		pipelineCfg.Filter = leadFilter.Match
+		LogInfo("Filtering leads", "filter", leadFilter.String())
+	}
+	leadPipeline := pipeline.New(leadHandler, pipelineCfg)
+	results := report.NewStore(plan.reportRecords)
+	defer results.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	items := leadPipeline.Run(ctx, func(emit func(*models.Lead) error) error {
+		if orderKey == nil {
+			return source.StreamLeads(emit)
+		}
+		return emitOrdered(source, *orderKey, cfg.SourcePriority, emit)
+	})
+
+	progress := checkpoint.NewTracker(skip)
+	cp := &checkpoint.Checkpoint{File: source.Name(), FileHash: fileHash, OrderBy: orderBy}
+	lastSaved := time.Now()
+	paused := false
+	aborted := false
+
+	// Process each lead
+	counts := stats.New()
+	failed := failures.NewTally()
+	// Written leads are sampled as they come, to be looked up again after the run
+	var reconcileSample *processor.ReconcileSample
+	if reconcileSize > 0 {
+		reconcileSample = processor.NewReconcileSample(reconcileSize, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	}
+	filtered := 0
+
+	for item := range items {
+		lead := item.Lead
+
+		// Leads the filter left out are done without being processed
+		if item.Filtered {
+			filtered++
+			progress.Done(item.Index)
+			continue
+		}
+
+		// Leads the closed window held back are left for the resumed run
+		if item.Err == nil && item.Result.Action == "PAUSED" {
+			if !paused {
+				paused = true
+				LogInfo("Run window closed, pausing", "window", window.String())
+				cancel()
+			}
+			continue
+		}
+
+		// A spent retry budget means the API is failing; stop reading rows
+		if item.Err == nil && item.Result.Action == "ABORTED" {
+			if !aborted {
+				aborted = true
+				LogError("Retry budget exhausted, stopping the run", item.Result.Error)
+				cancel()
+			}
+			continue
+		}
+
+		counts.Add(item)
+		progress.Done(item.Index)
+		if window != nil && time.Since(lastSaved) >= checkpointInterval {
+			cp.Completed = progress.Completed()
+			if err := cp.Save(checkpointFile); err != nil {
+				LogWarn("Failed to save checkpoint", "checkpointFile", checkpointFile, "error", err.Error())
+			}
+			lastSaved = time.Now()
+		}
+
+		record := newReportRecord(item)
+		if err := results.Add(record); err != nil {
+			LogError("Failed to record lead result", err, "email", lead.Email)
+			return fmt.Errorf("failed to record lead result: %w", err)
+		}
+		if decisions != nil {
+			if err := decisions.Write(record); err != nil {
+				LogError("Failed to write decision", err, "email", lead.Email)
+				return fmt.Errorf("failed to write decision: %w", err)
+			}
+		}
+
+		LogInfo("Processed lead", "index", item.Index, "name", lead.Name, "email", lead.Email)
+		printer.Printf("Processed lead %d: %s (%s)\n", item.Index, lead.Name, lead.Email)
+		if verbose {
+			fmt.Printf("  %s\n", printer.Sprintf("queues: %s", formatQueueDepths(leadPipeline.Depths())))
+		}
+
+		if deadLetters != nil {
+			if err := writeDeadLetter(deadLetters, source.Name(), item); err != nil {
+				LogError("Failed to write dead-letter entry", err, "deadLetter", deadLetterDest)
+				return err
+			}
+		}
+
+		if item.Err != nil {
+			LogError("Lead processing failed", item.Err, "name", lead.Name, "email", lead.Email)
+			fmt.Printf("  %s\n", printer.Sprintf("Error: %v", item.Err))
+			failed.Add(lead.Email, item.Err)
+			continue
+		}
+
+		result := item.Result
+		if reconcileSample != nil {
+			reconcileSample.Add(result)
+		}
+		if historyStore != nil && result.Error == nil && result.Reason == "" {
+			if err := historyStore.RecordLead(run.ID, lead.Email, lead.ContentHash(), result.Action); err != nil {
+				LogWarn("Failed to record lead in run history", "email", lead.Email, "error", err.Error())
+			}
+			dedupeIndex.Record(dedupe.Entry{Email: lead.Email, LeadID: resultLeadID(result), ContentHash: lead.ContentHash(), LastAction: result.Action, RunID: run.ID})
+		}
+
+		if result.SyncError != nil {
+			LogWarn("Failed to sync lead", "name", lead.Name, "email", lead.Email, "error", result.SyncError.Error())
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("Sync failed: %v", result.SyncError))
+		}
+
+		switch result.Action {
+		case "CREATE":
+			LogInfo("Lead created successfully", "name", lead.Name, "email", lead.Email)
+			fmt.Printf("  %s %s\n", symbols.ok, printer.Text("Created new lead"))
+		case "UPDATE":
+			LogInfo("Lead updated successfully", "name", lead.Name, "email", lead.Email)
+			fmt.Printf("  %s %s\n", symbols.ok, printer.Text("Updated existing lead"))
+		case "SKIP":
+			if result.Reason != "" {
+				LogInfo("Lead skipped", "name", lead.Name, "email", lead.Email, "reason", result.Reason)
+				fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("Skipped (%s)", printer.Text(result.Reason)))
+			} else {
+				LogInfo("Lead skipped (no changes needed)", "name", lead.Name, "email", lead.Email)
+				fmt.Printf("  %s %s\n", symbols.skip, printer.Text("Skipped (no changes needed)"))
+			}
+		case "DEFERRED":
+			LogWarn("Lead deferred", "name", lead.Name, "email", lead.Email, "reason", result.Error.Error())
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Text("Deferred (run budget exhausted)"))
+			if err := deferredLeads.Write(lead); err != nil {
+				LogError("Failed to write deferred lead", err, "deferredFile", deferredFile)
+				return fmt.Errorf("failed to write deferred lead: %w", err)
+			}
+		case "REVIEW":
+			LogInfo("Lead held for review", "name", lead.Name, "email", lead.Email, "reason", result.Reason)
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("Held for review (%s)", result.Reason))
+			if err := reviewLeads.Write(lead); err != nil {
+				LogError("Failed to write lead held for review", err, "reviewFile", reviewFile)
+				return fmt.Errorf("failed to write lead held for review: %w", err)
+			}
+		case "ROLLED_BACK":
+			LogWarn("Lead rolled back", "name", lead.Name, "email", lead.Email, "reason", result.Error.Error())
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("Rolled back: %v", result.Error))
+		case "ROLLBACK_FAILED":
+			LogError("Failed to roll back lead", result.Error, "name", lead.Name, "email", lead.Email)
+			fmt.Printf("  %s %s\n", symbols.fail, printer.Sprintf("Rollback failed: %v", result.Error))
+			failed.Add(lead.Email, result.Error)
+		case "VALIDATION_ERROR":
+			LogWarn("Lead validation failed", "name", lead.Name, "email", lead.Email, "error", result.Error.Error())
+			fmt.Printf("  %s %s\n", symbols.fail, printer.Sprintf("Validation error: %s", printer.Error(result.Error)))
+			failed.Add(lead.Email, result.Error)
+			if err := writeReject(rejectedLeads, lead, result.Error); err != nil {
+				LogError("Failed to write rejected lead", err, "rejectsFile", rejectsFile)
+				return fmt.Errorf("failed to write rejected lead: %w", err)
+			}
+		case "STATUS_CONFLICT":
+			LogWarn("Lead status change refused", "name", lead.Name, "email", lead.Email, "error", result.Error.Error())
+			fmt.Printf("  %s %s\n", symbols.fail, printer.Sprintf("Status conflict: %v", result.Error))
+			failed.Add(lead.Email, result.Error)
+			if err := writeReject(rejectedLeads, lead, result.Error); err != nil {
+				LogError("Failed to write rejected lead", err, "rejectsFile", rejectsFile)
+				return fmt.Errorf("failed to write rejected lead: %w", err)
+			}
+		case "API_ERROR", "CREATE_ERROR", "UPDATE_ERROR":
+			LogError("API error during lead processing", result.Error, "name", lead.Name, "email", lead.Email, "code", string(errcode.Of(result.Error)))
+			fmt.Printf("  %s %s\n", symbols.fail, printer.Sprintf("API error: %v", result.Error))
+			failed.Add(lead.Email, result.Error)
+		default:
+			LogWarn("Unknown action result", "action", result.Action, "name", lead.Name, "email", lead.Email)
+			fmt.Printf("  %s %s\n", symbols.unknown, printer.Sprintf("Unknown action: %s", result.Action))
+			failed.Add(lead.Email, result.Error)
+		}
+	}
+
+	if err := leadPipeline.Err(); err != nil {
+		LogError("Failed to read leads", err, "source", source.Name())
+		return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+	}
+
+	if reconcileSample != nil && len(reconcileSample.Results()) > 0 {
+		client, err := newLeadClient(cmd, cfg, apiURL, reconcileClientOptions(labels)...)
+		if err != nil {
+			return err
+		}
+		reconcile(leadProcessor, client, reconcileSample.Results(), reconcileDelay)
+	}
+
+	apiUsage := meter.Summary()
+	cost := apiUsage.Cost(cfg.Costs)
+
+	var anomalies []anomaly.Anomaly
+	if historyStore != nil {
+		run.APICalls = apiUsage.Calls
+		run.BytesSent = apiUsage.BytesSent
+		run.BytesReceived = apiUsage.BytesReceived
+		run.EstimatedCost = cost
+		run.Total = counts.Total()
+		run.Created = counts.Count("CREATE")
+		run.Updated = counts.Count("UPDATE")
+		run.Skipped = counts.Count("SKIP")
+		run.Deferred = counts.Count("DEFERRED")
+		run.Errors = counts.Errors()
+		run.Paused = paused
+		// A partial run says nothing about its feed
+		if cfg.Anomalies.Enabled() && !paused && !aborted {
+			anomalies = detectAnomalies(cfg.Anomalies, historyStore, run)
+		}
+		if err := historyStore.FinishRun(run); err != nil {
+			LogWarn("Failed to record run in history", "error", err.Error())
+		}
+	}
+
+	// Log and print summary
+	peakDepths := leadPipeline.PeakDepths()
+	LogInfo("Processing completed", "totalLeads", counts.Total(), "created", counts.Count("CREATE"), "updated", counts.Count("UPDATE"), "skipped", counts.Count("SKIP"), "deferred", counts.Count("DEFERRED"), "rolledBack", counts.Count("ROLLED_BACK"), "heldForReview", counts.Count("REVIEW"), "syncErrors", counts.SyncErrors(), "errors", counts.Errors(), "filtered", filtered, "peakQueueDepths", formatQueueDepths(peakDepths))
+	LogInfo("Lead timings", "fromReadToDone", formatTimings(counts.Summary().Timings))
+	if wrappers.bloom != nil {
+		LogInfo("Bloom filter", "lookupsSkipped", wrappers.bloom.Skipped())
+	}
+	if wrappers.notFound != nil {
+		LogInfo("Not-found cache", "lookupsSkipped", wrappers.notFound.Hits())
+	}
+	LogInfo("API usage", "calls", apiUsage.FormatCalls(), "bytesSent", apiUsage.BytesSent, "bytesReceived", apiUsage.BytesReceived, "estimatedCost", cost)
+
+	printer.Printf("\n=== Processing Summary ===\n")
+	printer.Printf("Total leads: %d\n", counts.Total())
+	if leadFilter != nil {
+		printer.Printf("Filtered out: %d\n", filtered)
+	}
+	printer.Printf("Created: %d\n", counts.Count("CREATE"))
+	printer.Printf("Updated: %d\n", counts.Count("UPDATE"))
+	printer.Printf("Skipped: %d\n", counts.Count("SKIP"))
+	if counts.Count("DEFERRED") > 0 {
+		printer.Printf("Deferred: %d (written to %s)\n", counts.Count("DEFERRED"), deferredFile)
+	}
+	if counts.Count("REVIEW") > 0 {
+		printer.Printf("Held for review: %d (written to %s)\n", counts.Count("REVIEW"), reviewFile)
+	}
+	if rejectedLeads != nil && rejectedLeads.Count() > 0 {
+		printRejectFiles(rejectedLeads, splitRejectsBy)
+	}
+	if counts.Count("ROLLED_BACK") > 0 {
+		printer.Printf("Rolled back: %d\n", counts.Count("ROLLED_BACK"))
+	}
+	if counts.SyncErrors() > 0 {
+		printer.Printf("Sync failures: %d\n", counts.SyncErrors())
+	}
+	printer.Printf("Errors: %d\n", counts.Errors())
+	if counts.Errors() > 0 {
+		printer.Printf("Errors by class: %s\n", formatErrorCodes(counts.Codes()))
+		printErrorGroups(failed, verbose)
+	}
+	if deadLetters != nil && deadLetters.Count() > 0 {
+		if err := deadLetters.Close(); err != nil {
+			LogError("Failed to write dead-letter entries", err, "deadLetter", deadLetterDest)
+			printer.Printf("Warning: failed to write dead-letter entries: %v\n", err)
+		} else {
+			printer.Printf("Dead-lettered: %d (written to %s)\n", deadLetters.Count(), deadLetterDest)
+		}
+	}
+	if events != nil {
+		if err := events.Close(); err != nil {
+			LogError("Failed to write trace events", err, "events", eventsFile)
+			printer.Printf("Warning: failed to write trace events: %v\n", err)
+		} else {
+			printer.Printf("Events written to: %s (%d)\n", eventsFile, events.Count())
+		}
+	}
+	if rewrites.inferrer != nil {
+		printer.Printf("Sources inferred: %d\n", rewrites.inferrer.Count())
+	}
+	if rewrites.territories != nil {
+		LogInfo("Territories assigned", "leads", rewrites.territories.Count(), "countriesFromIP", rewrites.territories.GeoHits())
+		printer.Printf("Territories assigned: %d\n", rewrites.territories.Count())
+	}
+	if rewrites.languages != nil {
+		LogInfo("Languages detected", "leads", rewrites.languages.Count())
+		printer.Printf("Languages detected: %d\n", rewrites.languages.Count())
+	}
+	if wrappers.bloom != nil {
+		printer.Printf("Lookups skipped by the bloom filter: %d\n", wrappers.bloom.Skipped())
+	}
+	if wrappers.notFound != nil {
+		printer.Printf("Lookups answered by the not-found cache: %d\n", wrappers.notFound.Hits())
+	}
+	printer.Printf("API calls: %d (%s)\n", apiUsage.TotalCalls(), apiUsage.FormatCalls())
+	printer.Printf("Data transferred: %s sent, %s received\n", formatByteSize(apiUsage.BytesSent), formatByteSize(apiUsage.BytesReceived))
+	if !cfg.Costs.IsZero() {
+		printer.Printf("Estimated cost: %.4f %s\n", cost, cfg.Costs.Currency)
+	}
+	if verbose {
+		printer.Printf("Peak queue depths: %s\n", formatQueueDepths(peakDepths))
+		if results.Spilled() {
+			printer.Printf("Results were spilled to disk to stay within --max-memory\n")
+		}
+	}
+
+	if len(anomalies) > 0 {
+		printAnomalies(anomalies, run.Feed)
+	}
+
+	if counts.Errors() > 0 {
+		printer.Printf("\n=== Failed Leads ===\n")
+		err := results.Each(func(record report.Record) error {
+			if record.Error != "" && record.Action != "DEFERRED" {
+				fmt.Printf("%d: %s (%s) %s [%s]: %s\n", record.Index, record.Name, record.Email, record.Action, record.Code, record.Error)
+			}
+			return nil
+		})
+		if err != nil {
+			LogError("Failed to read lead results", err)
+		}
+	}
+
+	// The export is for analytics, so a failed upload doesn't undo the run
+	var exportErr error
+	if exporter != nil {
+		runID, runDate := uuid.NewString(), startedAt
+		if run != nil {
+			runID, runDate = run.ID, run.StartedAt
+		}
+		location, err := exporter.Export(runID, runDate, results.Each)
+		if err != nil {
+			LogError("Failed to export results", err, "export", exportDest)
+			printer.Printf("Warning: failed to export results: %v\n", err)
+			exportErr = fmt.Errorf("failed to export results: %w", err)
+		} else {
+			LogInfo("Exported results", "location", location)
+			printer.Printf("Results exported to %s\n", location)
+		}
+	}
+
+	if counts.Count("DEFERRED") > 0 {
+		if err := deferredLeads.Close(); err != nil {
+			LogError("Failed to write deferred leads", err, "deferredFile", deferredFile)
+			return fmt.Errorf("failed to write deferred leads: %w", err)
+		}
+	}
+	if counts.Count("REVIEW") > 0 {
+		if err := reviewLeads.Close(); err != nil {
+			LogError("Failed to write leads held for review", err, "reviewFile", reviewFile)
+			return fmt.Errorf("failed to write leads held for review: %w", err)
+		}
+	}
+	if rejectedLeads != nil {
+		if err := rejectedLeads.Close(); err != nil {
+			LogError("Failed to write rejected leads", err, "rejectsFile", rejectsFile)
+			return fmt.Errorf("failed to write rejected leads: %w", err)
+		}
+	}
+
+	if aborted {
+		if window != nil {
+			cp.Completed = progress.Completed()
+			if err := cp.Save(checkpointFile); err != nil {
+				LogWarn("Failed to save checkpoint", "checkpointFile", checkpointFile, "error", err.Error())
+			}
+		}
+		printer.Printf("Stopped early: %d retries and %s spent retrying; the API looks degraded, try again later\n", retryBudget.Retries(), retryBudget.Spent().Round(time.Second))
+		// A degraded API is expected, not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%w: stopped after %d lead(s)", processor.ErrRetryBudgetExhausted, counts.Total())
+	}
+
+	if window != nil {
+		if !paused {
+			if err := checkpoint.Remove(checkpointFile); err != nil {
+				LogWarn("Failed to remove checkpoint", "checkpointFile", checkpointFile, "error", err.Error())
+			}
+		} else {
+			cp.Completed = progress.Completed()
+			if err := cp.Save(checkpointFile); err != nil {
+				LogError("Failed to save checkpoint", err, "checkpointFile", checkpointFile)
+				return err
+			}
+			printer.Printf("Paused: %d lead(s) of the file are done; run the same command again to resume\n", cp.Completed)
+			// A closed window is expected, not a usage mistake
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%w: progress saved to %s", processor.ErrRunPaused, checkpointFile)
+		}
+	}
+
+	if err := runFailure(counts.Codes()); err != nil {
+		// Leads failed for reasons outside the file; exit with the class
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	// Lead Ads are pulled from the same cursor until a run gets through them
+	if metaSource != nil {
+		cursor, err := metaSource.Cursor()
+		if err == nil {
+			err = cursor.Save(metaCursorFile)
+		}
+		if err != nil {
+			LogError("Failed to save Meta cursor", err, "cursorFile", metaCursorFile)
+			return err
+		}
+		LogInfo("Saved Meta cursor", "cursorFile", metaCursorFile)
+	}
+
+	if len(anomalies) > 0 && cfg.Anomalies.Fails() {
+		// A broken feed is a finding, not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%w: %d metric(s) of feed %s strayed from earlier runs", anomaly.ErrAnomaly, len(anomalies), run.Feed)
+	}
+
+	if counts.Count("DEFERRED") > 0 {
+		// Running out of budget is expected, not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%w: %d lead(s) deferred to %s", processor.ErrBudgetExhausted, counts.Count("DEFERRED"), deferredFile)
+	}
+
+	return exportErr
+}
+
+// newSheetSource opens the Google Sheet at sheetURL as a lead source,
+// authenticating with the service-account key from --google-credentials
+func newSheetSource(cmd *cobra.Command, sheetURL string, reader *csv.CSVReader) (*sheets.Reader, error) {
+	sheetRange, _ := cmd.Flags().GetString("sheet-range")
+	credentialsFile, _ := cmd.Flags().GetString("google-credentials")
+	if credentialsFile == "" {
+		return nil, fmt.Errorf("reading a Google Sheet requires --google-credentials or GOOGLE_APPLICATION_CREDENTIALS")
+	}
+
+	creds, err := sheets.LoadCredentials(cleanPath(credentialsFile))
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := sheets.NewReader(sheetURL, sheetRange, creds, reader)
+	if err != nil {
+		return nil, err
+	}
+	LogInfo("Reading leads from Google Sheet", "spreadsheetId", sheet.SpreadsheetID(), "range", sheetRange, "serviceAccount", creds.ClientEmail)
+	return sheet, nil
+}
+
+// newMetaSource opens Meta Lead Ads forms as a lead source, pulling the
+// leads newer than the cursor file, and returns the cursor file's path
+func newMetaSource(cmd *cobra.Command, input string, reader *csv.CSVReader) (*metaleads.Reader, string, error) {
+	pageToken, _ := cmd.Flags().GetString("meta-page-token")
+	metaSource, _ := cmd.Flags().GetString("meta-source")
+	cursorFile, _ := cmd.Flags().GetString("meta-cursor-file")
+	if pageToken == "" {
+		return nil, "", fmt.Errorf("reading Meta Lead Ads requires --meta-page-token or META_PAGE_TOKEN")
+	}
+
+	forms, err := metaleads.ParseInput(input)
+	if err != nil {
+		return nil, "", err
+	}
+	if cursorFile == "" {
+		cursorFile = "meta-" + strings.Join(forms, "-") + ".cursor.json"
+	}
+	cursorFile = cleanPath(cursorFile)
+
+	cursor, err := metaleads.LoadCursor(cursorFile)
+	if err != nil {
+		return nil, "", err
+	}
+	leads, err := metaleads.NewReader(forms, pageToken, cursor, reader, metaleads.WithSource(metaSource))
+	if err != nil {
+		return nil, "", err
+	}
+	LogInfo("Reading leads from Meta Lead Ads", "forms", strings.Join(forms, ","), "cursorFile", cursorFile)
+	return leads, cursorFile, nil
+}
+
+// Formats --input-format accepts
+const (
+	inputCSV        = "csv"
+	inputParquet    = "parquet"
+	inputAvro       = "avro"
+	inputXML        = "xml"
+	inputFixedWidth = "fixed-width"
+)
+
+var inputFormats = []string{inputCSV, inputParquet, inputAvro, inputXML, inputFixedWidth}
+
+// Values --header accepts
+const (
+	headerAuto = "auto"
+	headerYes  = "yes"
+	headerNo   = "no"
+)
+
+var headerModes = []string{headerAuto, headerYes, headerNo}
+
+// dialectFlags are --delimiter, --quote and --header. What they leave unset
+// is guessed from the start of a CSV file.
+type dialectFlags struct {
+	comma  rune
+	quote  rune
+	header string
+}
+
+// parseDialectFlags reads and checks the dialect flags
+func parseDialectFlags(cmd *cobra.Command) (dialectFlags, error) {
+	delimiter, _ := cmd.Flags().GetString("delimiter")
+	quote, _ := cmd.Flags().GetString("quote")
+	header, _ := cmd.Flags().GetString("header")
+
+	var flags dialectFlags
+	var err error
+	if flags.comma, err = csv.ParseDelimiter(delimiter); err != nil {
+		return flags, fmt.Errorf("invalid --delimiter: %w", err)
+	}
+	if flags.quote, err = csv.ParseQuote(quote); err != nil {
+		return flags, fmt.Errorf("invalid --quote: %w", err)
+	}
+	if flags.comma != 0 && flags.comma == flags.quote {
+		return flags, fmt.Errorf("invalid --quote: %q is the delimiter", quote)
+	}
+	flags.header = strings.ToLower(strings.TrimSpace(header))
+	if !slices.Contains(headerModes, flags.header) {
+		return flags, fmt.Errorf("invalid --header %q (use %s)", header, strings.Join(headerModes, ", "))
+	}
+	return flags, nil
+}
+
+// apply sets the reader's dialect for a CSV file, guessing from the file
+// what the flags leave unset. Other sources keep the flags' dialect.
+func (f dialectFlags) apply(source csv.LeadSource, reader *csv.CSVReader, verbose bool) error {
+	file, ok := source.(*csv.FileSource)
+	if !ok {
+		return nil
+	}
+	guessed := csv.Dialect{Comma: f.comma, Quote: f.quote, Header: f.header != headerNo}
+	if f.comma == 0 || f.quote == 0 || f.header == headerAuto {
+		sniffed, err := file.Sniff(f.comma)
+		if err != nil {
+			LogError("Failed to read file", err, "source", source.Name())
+			return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+		}
+		guessed.Comma = sniffed.Comma
+		if f.quote == 0 {
+			guessed.Quote = sniffed.Quote
+		}
+		if f.header == headerAuto {
+			guessed.Header = sniffed.Header
+		}
+		LogInfo("Guessed the CSV dialect", "source", source.Name(), "delimiter", csv.DelimiterName(guessed.Comma), "quote", string(guessed.Quote), "header", guessed.Header)
+		if verbose {
+			if guessed.Header {
+				printer.Printf("CSV dialect: delimiter %s, quote %c, first row is the header\n", csv.DelimiterName(guessed.Comma), guessed.Quote)
+			} else {
+				printer.Printf("CSV dialect: delimiter %s, quote %c, no header row\n", csv.DelimiterName(guessed.Comma), guessed.Quote)
+			}
+		}
+	}
+	reader.SetDelimiter(guessed.Comma)
+	reader.SetQuote(guessed.Quote)
+	reader.SetHeaderless(!guessed.Header)
+	return nil
+}
+
+// detectInputFormat returns the format named by --input-format, or else the
+// one the file's extension implies, CSV by default
+func detectInputFormat(input, format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(input)) {
+		case ".parquet":
+			return inputParquet, nil
+		case ".avro":
+			return inputAvro, nil
+		case ".xml":
+			return inputXML, nil
+		}
+		return inputCSV, nil
+	}
+	for _, valid := range inputFormats {
+		if format == valid {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --input-format %q (use %s)", format, strings.Join(inputFormats, ", "))
+}
+
+// newFileSource opens a lead file as a source of its format
+func newFileSource(input, format string, cfg *config.Config, reader *csv.CSVReader) (csv.LeadSource, error) {
+	format, err := detectInputFormat(input, format)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case inputParquet, inputAvro:
+		return datalake.NewFileSource(input, reader), nil
+	case inputXML:
+		return xmlfeed.NewSource(input, cfg.XML, reader), nil
+	case inputFixedWidth:
+		if !cfg.FixedWidth.Configured() {
+			return nil, fmt.Errorf("--input-format fixed-width needs the column layout in the config's fixed_width section")
+		}
+		return fixedwidth.NewSource(input, cfg.FixedWidth, reader), nil
+	}
+	return reader.File(input), nil
+}
+
+// applyProfile looks up the --profile in the config. Its settings stand in
+// for the flags they cover unless those were given, and its mapping is added
+// to the config's.
+func applyProfile(cmd *cobra.Command, cfg *config.Config) (config.Profile, error) {
+	name, _ := cmd.Flags().GetString("profile")
+	if name == "" {
+		return config.Profile{}, nil
+	}
+	profile, err := cfg.Profile(name)
+	if err != nil {
+		return config.Profile{}, fmt.Errorf("invalid --profile: %w", err)
+	}
+
+	flags := make(map[string]string)
+	if profile.Transforms.InferSource {
+		flags["infer-source"] = "true"
+	}
+	if profile.Dedup.SkipSeen > 0 {
+		flags["skip-seen"] = strconv.Itoa(profile.Dedup.SkipSeen)
+	}
+	if profile.Dedup.NoReprocess {
+		flags["no-reprocess"] = "true"
+	}
+	if profile.Target.Provider != "" {
+		flags["provider"] = profile.Target.Provider
+	}
+	if profile.Target.APIURL != "" {
+		flags["api-url"] = profile.Target.APIURL
+	}
+	if profile.Delimiter != "" {
+		flags["delimiter"] = profile.Delimiter
+	}
+	if profile.Policy.Name != "" {
+		flags["policy"] = profile.Policy.Name
+	}
+	if len(profile.Policy.Fields) > 0 {
+		flags["policy-fields"] = strings.Join(profile.Policy.Fields, ",")
+	}
+	for flag, value := range flags {
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(flag, value); err != nil {
+			return config.Profile{}, fmt.Errorf("invalid --profile %s: --%s: %w", name, flag, err)
+		}
+	}
+
+	cfg.Mapping = profileMapping(cfg, profile)
+	LogInfo("Using import profile", "profile", name)
+	return profile, nil
+}
+
+// profileMapping is the config's mapping with the profile's on top
+func profileMapping(cfg *config.Config, profile config.Profile) map[string]string {
+	if len(profile.Mapping) == 0 {
+		return cfg.Mapping
+	}
+	mapping := make(map[string]string, len(cfg.Mapping)+len(profile.Mapping))
+	for field, column := range cfg.Mapping {
+		mapping[field] = column
+	}
+	for field, column := range profile.Mapping {
+		mapping[field] = column
+	}
+	return mapping
+}
+
+// checkSchema compares the source's header with the profile's manifest,
+// printing the differences, and fails a strict schema on any
+func checkSchema(source csv.LeadSource, profileName string, schema config.ProfileSchema) error {
+	header, err := source.ReadHeader()
+	if err != nil {
+		LogError("Failed to read header", err, "source", source.Name())
+		return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+	}
+	diff := csv.DiffHeader(schema.Header, header)
+	if diff == nil {
+		return nil
+	}
+
+	LogWarn("Header differs from the profile's schema", "source", source.Name(), "profile", profileName, "changes", diff.String())
+	printer.Printf("Header of %s differs from the schema of profile %s:\n", source.Name(), profileName)
+	for _, change := range diff.Changes() {
+		printer.Printf("  - %s\n", change)
+	}
+	printer.Printf("  expected: %s\n", strings.Join(schema.Header, ", "))
+	printer.Printf("  actual:   %s\n", strings.Join(header, ", "))
+	if schema.Strict {
+		return fmt.Errorf("%s does not match the strict schema of profile %s: %s", source.Name(), profileName, diff)
+	}
+	return nil
+}
+
+// wideColumns is the width from which a file read whole is worth projecting
+const wideColumns = 200
+
+// maxListedColumns bounds the columns named in the projection warning
+const maxListedColumns = 20
+
+// projectedColumns returns the source columns the run reads besides the lead
+// fields' own: those of the filter, ordering, source inference, territories,
+// language detection and attachments, and the ones to keep anyway
+func projectedColumns(cfg *config.Config, leadFilter *filter.Filter, orderKey *ordering.Key, inferSource, attachments bool, keep []string) []string {
+	columns := slices.Clone(keep)
+	if leadFilter != nil {
+		columns = append(columns, leadFilter.Columns()...)
+	}
+	if orderKey != nil {
+		columns = append(columns, orderKey.Columns()...)
+	}
+	if inferSource {
+		columns = append(columns, sourceInferrer(cfg).Columns()...)
+	}
+	if !cfg.Territories.IsZero() {
+		columns = append(columns, cfg.Territories.Columns()...)
+	}
+	columns = append(columns, cfg.LanguageDetection.Columns...)
+	if attachments {
+		columns = append(columns, cfg.Attachments.Columns...)
+	}
+	// An empty projection still means "project", unlike nil
+	if columns == nil {
+		columns = []string{}
+	}
+	return columns
+}
+
+// columnList names columns for a warning, up to maxListedColumns of them
+func columnList(columns []string) string {
+	if len(columns) <= maxListedColumns {
+		return strings.Join(columns, ", ")
+	}
+	return printer.Sprintf("%s and %d more", strings.Join(columns[:maxListedColumns], ", "), len(columns)-maxListedColumns)
+}
+
+// writeReject writes a lead the sender has to fix to w, if rejects are kept
+func writeReject(w *csv.RejectWriter, lead *models.Lead, reason error) error {
+	if w == nil {
+		return nil
+	}
+	return w.Write(lead, reason)
+}
+
+// printRejectFiles lists the files rejected leads were written to, one per
+// partner when split
+func printRejectFiles(w *csv.RejectWriter, splitBy string) {
+	files := w.Files()
+	if splitBy == "" {
+		printer.Printf("Rejected: %d (written to %s)\n", w.Count(), files[0].Path)
+		return
+	}
+	printer.Printf("Rejected: %d (split by %s into %d files)\n", w.Count(), splitBy, len(files))
+	for _, file := range files {
+		printer.Printf("  %s: %d\n", file.Path, file.Count)
+	}
+}
+
+// rowLayout returns the columns deferred and review files are written with:
+// the source's header, less the columns --project-columns drops
+func rowLayout(source csv.LeadSource, reader *csv.CSVReader) ([]string, error) {
+	header, err := source.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	kept, _, err := reader.Projection(header)
+	return kept, err
+}
+
+// leadRewrites are the rewrites every lead goes through before validation,
+// with those whose counts the summary reports, nil when not configured
+type leadRewrites struct {
+	transforms  []pipeline.Transform
+	inferrer    *inference.Inferrer
+	territories *territory.Assigner
+	languages   *langdetect.Detector
+}
+
+// leadTransforms returns the rewrites every lead goes through before
+// validation: the source inferrer if inferSource is set, then the config's
+// territories and language detection
+func leadTransforms(cfg *config.Config, inferSource bool, defaults map[string]string, geo *territory.GeoIP) leadRewrites {
+	// Clean up text before anything else looks at it
+	rewrites := leadRewrites{transforms: []pipeline.Transform{models.Sanitize}}
+	if inferSource {
+		rewrites.inferrer = sourceInferrer(cfg)
+		rewrites.transforms = append(rewrites.transforms, rewrites.inferrer.Infer)
+	}
+	// Defaults go last, so a source inferred from the row wins over them
+	if len(defaults) > 0 {
+		rewrites.transforms = append(rewrites.transforms, fillDefaults(defaults))
+	}
+	if !cfg.Territories.IsZero() {
+		rewrites.territories = territory.New(cfg.Territories, geo)
+		rewrites.transforms = append(rewrites.transforms, rewrites.territories.Assign)
+	}
+	if !cfg.LanguageDetection.IsZero() {
+		rewrites.languages = langdetect.New(cfg.LanguageDetection)
+		rewrites.transforms = append(rewrites.transforms, rewrites.languages.Detect)
+	}
+	return rewrites
+}
+
+// preflight looks up a random sample of the source's leads without writing
+// anything, failing with processor.ErrPreflightFailed when too many of them
+// would fail validation or conflict with the CRM
+func preflight(leadProcessor *processor.LeadProcessor, source csv.LeadSource, transforms []pipeline.Transform, leadFilter *filter.Filter, size int, maxInvalid, maxConflicts float64) error {
+	LogInfo("Running pre-flight", "sample", size)
+	printer.Printf("Pre-flight: looking up a sample of %d lead(s)...\n", size)
+
+	sample, err := processor.Sample(source.StreamLeads, size, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source.Name(), err)
+	}
+	kept := sample[:0]
+	for _, lead := range sample {
+		for _, transform := range transforms {
+			lead = transform(lead)
+		}
+		// Leads the run would filter out say nothing about it
+		if leadFilter == nil || leadFilter.Match(lead) {
+			kept = append(kept, lead)
+		}
+	}
+	report, err := leadProcessor.Preflight(kept)
+	if err != nil {
+		return err
+	}
+
+	invalid := report.Rate("VALIDATION_ERROR") * 100
+	conflicts := report.Rate("STATUS_CONFLICT") * 100
+	LogInfo("Pre-flight finished", "sampled", report.Sampled, "actions", report.Actions)
+	printer.Printf("Pre-flight: %d create(s), %d update(s), %d skip(s), %d invalid (%.1f%%), %d conflict(s) (%.1f%%), %d API error(s)\n",
+		report.Actions["CREATE"], report.Actions["UPDATE"], report.Actions["SKIP"], report.Actions["VALIDATION_ERROR"], invalid, report.Actions["STATUS_CONFLICT"], conflicts, report.Actions["API_ERROR"])
+	if invalid <= maxInvalid && conflicts <= maxConflicts {
+		return nil
+	}
+
+	for _, result := range report.Failures {
+		fmt.Printf("  %s (%s) %s: %v\n", result.Lead.Name, result.Lead.Email, result.Action, result.Error)
+	}
+	if invalid > maxInvalid {
+		return fmt.Errorf("%w: %.1f%% of %d sampled lead(s) are invalid, more than --preflight-max-invalid %g%%", processor.ErrPreflightFailed, invalid, report.Sampled, maxInvalid)
+	}
+	return fmt.Errorf("%w: %.1f%% of %d sampled lead(s) conflict with the CRM, more than --preflight-max-conflicts %g%%", processor.ErrPreflightFailed, conflicts, report.Sampled, maxConflicts)
+}
+
+// reconcileClientOptions are those of the client reconciliation looks leads
+// up with: the run's labels, but neither its budgets nor the lookup cache,
+// so every lookup reaches the CRM
+func reconcileClientOptions(labels history.Labels) []api.ClientOption {
+	if len(labels) == 0 {
+		return nil
+	}
+	return []api.ClientOption{api.WithMiddleware(api.HeaderMiddleware(api.RunLabelsHeader, labels.String()))}
+}
+
+// reconcile looks the sampled leads the run wrote up again after delay,
+// reporting those the CRM doesn't hold as they were sent
+func reconcile(leadProcessor *processor.LeadProcessor, client processor.APIClient, sample []*processor.ProcessResult, delay time.Duration) {
+	if delay > 0 {
+		printer.Printf("Reconciliation: waiting %s for the CRM to settle...\n", delay)
+		time.Sleep(delay)
+	}
+	LogInfo("Reconciling written leads", "sample", len(sample))
+	printer.Printf("Reconciliation: looking up %d written lead(s) again...\n", len(sample))
+
+	report := leadProcessor.Reconcile(client, sample)
+	LogInfo("Reconciliation finished", "checked", report.Checked, "mismatches", len(report.Mismatches), "failed", report.Failed)
+	printer.Printf("Reconciliation: %d checked, %d mismatch(es), %d lookup(s) failed\n", report.Checked, len(report.Mismatches), report.Failed)
+	for _, mismatch := range report.Mismatches {
+		LogWarn("Reconciliation mismatch", "email", mismatch.Email, "action", mismatch.Action, "problem", mismatch.Problem)
+		fmt.Printf("  %s %s\n", symbols.fail, printer.Sprintf("%s (%s): %s", mismatch.Email, mismatch.Action, mismatch.Problem))
+	}
+}
+
+// fillDefaults sets the fields a lead leaves empty to a profile's defaults
+func fillDefaults(defaults map[string]string) pipeline.Transform {
+	return func(lead *models.Lead) *models.Lead {
+		for field, value := range defaults {
+			if current, _ := lead.Field(field); strings.TrimSpace(current) == "" {
+				lead.SetField(field, value)
+			}
+		}
+		return lead
+	}
+}
+
+// emitOrdered reads the whole source and emits its leads highest priority first
+func emitOrdered(source csv.LeadSource, key ordering.Key, sourcePriority []string, emit func(*models.Lead) error) error {
+	var leads []*models.Lead
+	err := source.StreamLeads(func(lead *models.Lead) error {
+		leads = append(leads, lead)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(sourcePriority) == 0 {
+		sourcePriority = ordering.DefaultSourcePriority()
+	}
+	ordering.Sort(leads, key, sourcePriority)
+	LogInfo("Ordered leads", "orderBy", key.Kind, "column", key.Column, "count", len(leads))
+
+	for _, lead := range leads {
+		if err := emit(lead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDeadLetter keeps the item's lead if it failed for good
+func writeDeadLetter(w *deadletter.Writer, input string, item *pipeline.Item) error {
+	action, err := "ERROR", item.Err
+	if err == nil {
+		action, err = item.Result.Action, item.Result.Error
+	}
+	if !deadletter.Terminal(action, err) {
+		return nil
+	}
+	if err := w.Write(deadletter.NewEntry(input, item.Index, item.Lead, action, err)); err != nil {
+		return fmt.Errorf("failed to write dead-letter entry: %w", err)
+	}
+	return nil
+}
+
+// newReportRecord converts a pipeline item into a report record. Errors stay
+// in English next to their code, so reports read the same whatever --lang is.
+func newReportRecord(item *pipeline.Item) report.Record {
+	record := report.Record{
+		Index:   item.Index,
+		Name:    item.Lead.Name,
+		Email:   item.Lead.Email,
+		Company: item.Lead.Company,
+		Source:  item.Lead.Source,
+		Status:  item.Lead.Status,
+	}
+
+	if item.Err != nil {
+		record.Action = "ERROR"
+		record.Error = item.Err.Error()
+		record.Code = string(errcode.Of(item.Err))
+		return record
+	}
+
+	record.Action = item.Result.Action
+	if item.Result.Error != nil {
+		record.Error = item.Result.Error.Error()
+		record.Code = string(errcode.Of(item.Result.Error))
+	}
+	switch {
+	case item.Result.CreatedLead != nil:
+		record.LeadID = item.Result.CreatedLead.ID
+	case item.Result.UpdatedLead != nil:
+		record.LeadID = item.Result.UpdatedLead.ID
+	}
+
+	return record
+}
+
+// leadTally counts the outcomes of leads sent by sendLeads
+type leadTally struct {
+	created    int
+	updated    int
+	unchanged  int
+	failed     int
+	errorCodes map[errcode.Code]int
+}
+
+// print prints the counts for a command's summary
+func (t *leadTally) print() {
+	printer.Printf("Created: %d\n", t.created)
+	printer.Printf("Updated: %d\n", t.updated)
+	printer.Printf("Skipped: %d\n", t.unchanged)
+	printer.Printf("Failed: %d\n", t.failed)
+	if t.failed > 0 {
+		printer.Printf("Errors by class: %s\n", formatErrorCodes(t.errorCodes))
+	}
+}
+
+// sendLeads sends leads through validation and processing, as a command
+// other than process does, printing a line per lead. failed is called with
+// each lead that failed and its position in leads.
+func sendLeads(cmd *cobra.Command, cfg *config.Config, workers int, emailLevel emailcheck.Level, leads []*models.Lead, failed func(i int, lead *models.Lead, action string, err error) error) (*leadTally, error) {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	client, err := newLeadClient(cmd, cfg, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	validation := validationOptions(cfg, emailLevel)
+	leadProcessor := processor.NewLeadProcessor(client)
+	if err := leadProcessor.SetStages(cfg.Stages); err != nil {
+		return nil, fmt.Errorf("invalid stages: %w", err)
+	}
+	leadProcessor.SetProtectedFields(cfg.ProtectedFields)
+	leadProcessor.SetEquality(cfg.Equality)
+	leadProcessor.SetValidation(validation)
+
+	leadPipeline := pipeline.New(leadProcessor, pipeline.Config{
+		Workers:    workers,
+		Transforms: []pipeline.Transform{models.Sanitize},
+		Validation: validation,
+	})
+	items := leadPipeline.Run(context.Background(), func(emit func(*models.Lead) error) error {
+		for _, lead := range leads {
+			if err := emit(lead); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	tally := &leadTally{errorCodes: make(map[errcode.Code]int)}
+	for item := range items {
+		lead := item.Lead
+		action, failure := "ERROR", item.Err
+		if failure == nil {
+			action, failure = item.Result.Action, item.Result.Error
+		}
+
+		switch {
+		case failure == nil && action == "CREATE":
+			fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: created", lead.Email))
+			tally.created++
+		case failure == nil && action == "UPDATE":
+			fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: updated", lead.Email))
+			tally.updated++
+		case failure == nil:
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("%s: no changes needed", lead.Email))
+			tally.unchanged++
+		default:
+			fmt.Printf("  %s %s: %s\n", symbols.fail, lead.Email, printer.Error(failure))
+			tally.errorCodes[errcode.Of(failure)]++
+			tally.failed++
+			if err := failed(item.Index-1, lead, action, failure); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := leadPipeline.Err(); err != nil {
+		return nil, err
+	}
+	return tally, nil
+}
+
+// runFailure returns an error classed like the most severe run-level failure
+// among the leads: auth, then network, then rate limiting. Validation and
+// conflict failures are about single leads and don't fail the run.
+func runFailure(errorCodes map[errcode.Code]int) error {
+	for _, class := range []struct {
+		code errcode.Code
+		err  error
+	}{
+		{errcode.CodeAuth, errcode.ErrAuth},
+		{errcode.CodeNetwork, errcode.ErrNetwork},
+		{errcode.CodeRateLimited, errcode.ErrRateLimited},
+	} {
+		if count := errorCodes[class.code]; count > 0 {
+			return fmt.Errorf("%w: %d lead(s) failed", class.err, count)
+		}
+	}
+	return nil
+}
+
+// detectAnomalies compares the run with the latest earlier runs of its feed
+func detectAnomalies(cfg anomaly.Config, historyStore *history.Store, run *history.Run) []anomaly.Anomaly {
+	earlier := historyStore.RecentRuns(run.Feed, cfg.Runs())
+	baseline := make([]anomaly.Metrics, len(earlier))
+	for i, previous := range earlier {
+		baseline[i] = runMetrics(previous)
+	}
+	anomalies := anomaly.Detect(cfg, runMetrics(run), baseline)
+	for _, a := range anomalies {
+		LogWarn("Run strays from its baseline", "feed", run.Feed, "metric", a.Metric, "value", a.Value, "baseline", a.Baseline, "runs", len(earlier))
+	}
+	return anomalies
+}
+
+func runMetrics(run *history.Run) anomaly.Metrics {
+	return anomaly.Metrics{Rows: run.Total, Created: run.Created, Errors: run.Errors, Bytes: run.Bytes}
+}
+
+// printAnomalies lists the metrics that strayed from the feed's baseline
+func printAnomalies(anomalies []anomaly.Anomaly, feed string) {
+	printer.Printf("\n=== Anomalies (feed %s) ===\n", feed)
+	for _, a := range anomalies {
+		switch a.Metric {
+		case anomaly.MetricRows:
+			printer.Printf("Rows: %.0f, usually %.0f\n", a.Value, a.Baseline)
+		case anomaly.MetricCreated:
+			printer.Printf("Created: %.0f, usually %.0f\n", a.Value, a.Baseline)
+		case anomaly.MetricBytes:
+			printer.Printf("File size: %s, usually %s\n", formatByteSize(int64(a.Value)), formatByteSize(int64(a.Baseline)))
+		case anomaly.MetricErrorRate:
+			printer.Printf("Error rate: %.1f%%, usually %.1f%%\n", a.Value*100, a.Baseline*100)
+		}
+	}
+}
+
+// reconcileIntents settles the creates and updates an earlier run sent but
+// never saw answered, recording applied ones in the run history and the dedupe
+// index. Failures are only logged; the intents stay pending for the next run.
+func reconcileIntents(intents *intentlog.Log, client processor.APIClient, historyStore *history.Store, dedupeIndex *dedupe.Index) {
+	if len(intents.Pending()) == 0 {
+		return
+	}
+
+	resolutions, err := intents.Reconcile(func(email string) (*models.Lead, bool, error) {
+		resp, err := client.LookupLead(email)
+		if err != nil {
+			return nil, false, err
+		}
+		return resp.Lead, resp.Found, nil
+	})
+
+	applied := 0
+	for _, resolution := range resolutions {
+		intent := resolution.Intent
+		LogInfo("Reconciled interrupted request", "action", intent.Action, "email", intent.Email, "runId", intent.RunID, "outcome", resolution.Outcome)
+		if resolution.Outcome != intentlog.OutcomeApplied {
+			continue
+		}
+		applied++
+		if historyStore != nil {
+			if err := historyStore.RecordLead(intent.RunID, intent.Email, intent.ContentHash, intent.Action); err != nil {
+				LogWarn("Failed to record lead in run history", "email", intent.Email, "error", err.Error())
+			}
+		}
+		if dedupeIndex != nil {
+			dedupeIndex.Record(dedupe.Entry{Email: intent.Email, ContentHash: intent.ContentHash, LastAction: intent.Action, RunID: intent.RunID})
+		}
+	}
+	if len(resolutions) > 0 {
+		printer.Printf("Reconciled %d interrupted request(s) from an earlier run: %d applied, %d not applied\n", len(resolutions), applied, len(resolutions)-applied)
+	}
+	if err != nil {
+		LogWarn("Failed to reconcile the intent log, will retry next run", "error", err.Error())
+	}
+}
+
+// formatTimings lists each action's mean and 95th percentile time, e.g.
+// CREATE mean=120ms p95=250ms, SKIP mean=8ms p95=10ms
+func formatTimings(timings map[string]stats.Histogram) string {
+	actions := make([]string, 0, len(timings))
+	for action := range timings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	parts := make([]string, len(actions))
+	for i, action := range actions {
+		timing := timings[action]
+		parts[i] = fmt.Sprintf("%s mean=%s p95=%s", action, timing.Mean().Round(time.Millisecond), timing.Quantile(0.95))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatErrorCodes renders error counts as CODE=count pairs in a stable order
+func formatErrorCodes(errorCodes map[errcode.Code]int) string {
+	codes := make([]string, 0, len(errorCodes))
+	for code := range errorCodes {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%s=%d", code, errorCodes[errcode.Code(code)]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// topErrors is how many causes and domains the summary lists without --verbose
+const topErrors = 5
+
+// printErrorGroups lists the most common causes of failed leads and the email
+// domains with the most failures, so a partner sending rows without a company
+// stands out
+func printErrorGroups(failed *failures.Tally, verbose bool) {
+	n := topErrors
+	if verbose {
+		n = 0
+	}
+	printer.Printf("Top error causes:\n")
+	for _, group := range failed.Causes(n) {
+		fmt.Printf("  %s: %d\n", group.Name, group.Count)
+	}
+	printer.Printf("Top failing domains:\n")
+	for _, group := range failed.Domains(n) {
+		fmt.Printf("  %s\n", printer.Sprintf("%s: %d (mostly %s: %d)", group.Name, group.Count, group.TopCause, group.TopCauseCount))
+	}
+}
+
+// formatQueueDepths renders queue depths as stage=depth pairs in a stable order
+func formatQueueDepths(depths pipeline.QueueDepths) string {
+	stages := make([]string, 0, len(depths))
+	for stage := range depths {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+
+	parts := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		parts = append(parts, fmt.Sprintf("%s=%d", stage, depths[stage]))
+	}
+	return strings.Join(parts, ",")
+}
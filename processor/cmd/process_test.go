@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"code/internal/config"
+	"code/internal/csv"
+	"code/internal/errcode"
+	"code/internal/i18n"
+	"code/internal/models"
+	"code/internal/notfound"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseByteSize(t *testing.T) {
+	t.Run("parses sizes with and without units", func(t *testing.T) {
+		// Arrange
+		cases := map[string]int64{
+			"":      0,
+			"1024":  1024,
+			"512MB": 512 << 20,
+			"2GiB":  2 << 30,
+			"64 kb": 64 << 10,
+			"100b":  100,
+		}
+
+		for input, expected := range cases {
+			// Act
+			actual, err := parseByteSize(input)
+
+			// Assert
+			assert.NoError(t, err, input)
+			assert.Equal(t, expected, actual, input)
+		}
+	})
+
+	t.Run("rejects malformed sizes", func(t *testing.T) {
+		// Act
+		_, err := parseByteSize("lots")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestFormatByteSize(t *testing.T) {
+	t.Run("uses the largest fitting unit", func(t *testing.T) {
+		// Arrange
+		cases := map[int64]string{
+			0:       "0 B",
+			1023:    "1023 B",
+			1536:    "1.5 KiB",
+			5 << 20: "5.0 MiB",
+			3 << 30: "3.0 GiB",
+		}
+
+		for input, expected := range cases {
+			// Act
+			actual := formatByteSize(input)
+
+			// Assert
+			assert.Equal(t, expected, actual, input)
+		}
+	})
+}
+
+func TestPlanMemory(t *testing.T) {
+	t.Run("keeps defaults without a budget", func(t *testing.T) {
+		// Act
+		plan := planMemory(0, 100)
+
+		// Assert
+		assert.Equal(t, 100, plan.queueSize)
+		assert.Equal(t, 0, plan.reportRecords)
+	})
+
+	t.Run("shrinks queues and report buffer to fit the budget", func(t *testing.T) {
+		// Act
+		plan := planMemory(64<<10, 100)
+
+		// Assert
+		assert.Equal(t, 4, plan.queueSize)
+		assert.Equal(t, 16, plan.reportRecords)
+	})
+}
+
+func TestDetectInputFormat(t *testing.T) {
+	t.Run("picks the format from the extension", func(t *testing.T) {
+		// Arrange
+		cases := map[string]string{
+			"leads.csv":          inputCSV,
+			"export.PARQUET":     inputParquet,
+			"extract.avro":       inputAvro,
+			"partner-feed.xml":   inputXML,
+			"mainframe.dat":      inputCSV,
+			"leads.without.ext.": inputCSV,
+		}
+
+		for input, expected := range cases {
+			// Act
+			actual, err := detectInputFormat(input, "")
+
+			// Assert
+			assert.NoError(t, err, input)
+			assert.Equal(t, expected, actual, input)
+		}
+	})
+
+	t.Run("prefers --input-format", func(t *testing.T) {
+		// Act
+		format, err := detectInputFormat("mainframe.dat", "Fixed-Width")
+		_, invalid := detectInputFormat("leads.csv", "json")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, inputFixedWidth, format)
+		assert.ErrorContains(t, invalid, "invalid --input-format")
+	})
+}
+
+func TestDialectFlags(t *testing.T) {
+	newCommand := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("delimiter", "", "")
+		cmd.Flags().String("quote", "", "")
+		cmd.Flags().String("header", headerAuto, "")
+		cmd.Flags().Parse(args)
+		return cmd
+	}
+	path := filepath.Join(t.TempDir(), "partner.csv")
+	os.WriteFile(path, []byte("Jane Roe;jane@example.com;'Acme; Labs';Website\n"), 0o644)
+
+	t.Run("guesses what the flags leave unset", func(t *testing.T) {
+		// Arrange
+		flags, err := parseDialectFlags(newCommand())
+		assert.NoError(t, err)
+		reader := csv.NewCSVReader()
+
+		// Act
+		err = flags.apply(reader.File(path), reader, false)
+
+		// Assert
+		assert.NoError(t, err)
+		leads, _ := reader.ReadLeads(path)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "Acme; Labs", leads[0].Company)
+	})
+
+	t.Run("prefers the flags", func(t *testing.T) {
+		// Arrange
+		flags, err := parseDialectFlags(newCommand("--delimiter", "tab", "--header", "yes"))
+		assert.NoError(t, err)
+		reader := csv.NewCSVReader()
+
+		// Act
+		err = flags.apply(reader.File(path), reader, false)
+
+		// Assert
+		assert.NoError(t, err)
+		header, _ := reader.ReadHeader(path)
+		assert.Equal(t, []string{"Jane Roe;jane@example.com;'Acme; Labs';Website"}, header)
+	})
+
+	t.Run("rejects invalid flags", func(t *testing.T) {
+		// Act
+		_, delimiter := parseDialectFlags(newCommand("--delimiter", ";;"))
+		_, quote := parseDialectFlags(newCommand("--quote", "`"))
+		_, header := parseDialectFlags(newCommand("--header", "maybe"))
+
+		// Assert
+		assert.ErrorContains(t, delimiter, "invalid --delimiter")
+		assert.ErrorContains(t, quote, "invalid --quote")
+		assert.ErrorContains(t, header, "invalid --header")
+	})
+}
+
+// removingClient can archive and delete leads
+type removingClient struct{}
+
+func (removingClient) ArchiveLead(id string) error { return nil }
+func (removingClient) DeleteLead(id string) error  { return nil }
+
+// lookupClient only looks up, creates and updates leads, as the Pipedrive
+// and Zoho clients do
+type lookupClient struct{}
+
+func (lookupClient) LookupLead(email string) (*processor.LookupResponse, error) {
+	return &processor.LookupResponse{}, nil
+}
+func (lookupClient) CreateLead(lead *models.Lead) (*models.Lead, error) { return lead, nil }
+func (lookupClient) UpdateLead(lead *models.Lead) (*models.Lead, error) { return lead, nil }
+
+// featureClient can also attach files and add notes
+type featureClient struct {
+	lookupClient
+}
+
+func (featureClient) AttachToLead(id string, attachment *models.Attachment) error { return nil }
+func (featureClient) AddNote(id string, text string) error                        { return nil }
+
+func TestAttachClient(t *testing.T) {
+	t.Run("asks the client a not-found cache wraps", func(t *testing.T) {
+		// Act
+		_, wrappedLacking := attachClient(notfound.NewClient(lookupClient{}, time.Minute))
+		attacher, wrappedCapable := attachClient(notfound.NewClient(featureClient{}, time.Minute))
+
+		// Assert
+		assert.False(t, wrappedLacking)
+		assert.True(t, wrappedCapable)
+		assert.IsType(t, &notfound.Client{}, attacher)
+	})
+}
+
+func TestNoteClient(t *testing.T) {
+	t.Run("asks the client a not-found cache wraps", func(t *testing.T) {
+		// Act
+		_, wrappedLacking := noteClient(notfound.NewClient(lookupClient{}, time.Minute))
+		noter, wrappedCapable := noteClient(notfound.NewClient(featureClient{}, time.Minute))
+
+		// Assert
+		assert.False(t, wrappedLacking)
+		assert.True(t, wrappedCapable)
+		assert.IsType(t, &notfound.Client{}, noter)
+	})
+}
+
+func TestResolveDeletionMode(t *testing.T) {
+	newCommand := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("deletion-mode", string(processor.DeletionArchive), "")
+		cmd.Flags().Parse(args)
+		return cmd
+	}
+
+	t.Run("archives where the server can", func(t *testing.T) {
+		// Act
+		mode := resolveDeletionMode(newCommand(), removingClient{}, processor.DeletionArchive, processor.ServerFeatures{Archive: true})
+
+		// Assert
+		assert.Equal(t, processor.DeletionArchive, mode)
+	})
+
+	t.Run("deletes by default where the server can't archive", func(t *testing.T) {
+		// Act
+		mode := resolveDeletionMode(newCommand(), removingClient{}, processor.DeletionArchive, processor.ServerFeatures{})
+
+		// Assert
+		assert.Equal(t, processor.DeletionDelete, mode)
+	})
+
+	t.Run("keeps archiving asked for explicitly", func(t *testing.T) {
+		// Act
+		mode := resolveDeletionMode(newCommand("--deletion-mode", "archive"), removingClient{}, processor.DeletionArchive, processor.ServerFeatures{})
+
+		// Assert
+		assert.Equal(t, processor.DeletionArchive, mode)
+	})
+}
+
+func TestCheckOwnershipProvider(t *testing.T) {
+	newCommand := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("provider", providerAPI, "")
+		cmd.Flags().Parse(args)
+		return cmd
+	}
+
+	t.Run("allows the lead API", func(t *testing.T) {
+		// Act
+		err := checkOwnershipProvider(newCommand())
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses CRMs that give every record an owner", func(t *testing.T) {
+		for _, provider := range []string{"pipedrive", "Zoho"} {
+			// Act
+			err := checkOwnershipProvider(newCommand("--provider", provider))
+
+			// Assert
+			assert.ErrorContains(t, err, "--respect-ownership needs --provider api", provider)
+		}
+	})
+}
+
+func TestNewReportRecord(t *testing.T) {
+	t.Run("keeps errors in English whatever the console language", func(t *testing.T) {
+		// Arrange
+		previous := printer
+		printer = i18n.NewPrinter(i18n.German)
+		defer func() { printer = previous }()
+		lead := &models.Lead{Email: "jane@example.com"}
+		item := &pipeline.Item{Index: 3, Lead: lead, Err: lead.Validate()}
+
+		// Act
+		record := newReportRecord(item)
+
+		// Assert
+		assert.Equal(t, "ERROR", record.Action)
+		assert.Contains(t, record.Error, "name is required")
+		assert.Equal(t, string(errcode.CodeValidation), record.Code)
+	})
+}
+
+func TestApplyProfile(t *testing.T) {
+	newCommand := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("profile", "", "")
+		cmd.Flags().Bool("infer-source", false, "")
+		cmd.Flags().String("delimiter", "", "")
+		cmd.Flags().Int("skip-seen", 0, "")
+		cmd.Flags().Bool("no-reprocess", false, "")
+		cmd.Flags().String("provider", "api", "")
+		cmd.Flags().String("api-url", "http://localhost:3030", "")
+		cmd.Flags().String("policy", "upsert", "")
+		cmd.Flags().StringSlice("policy-fields", nil, "")
+		return cmd
+	}
+	cfg := func() *config.Config {
+		cfg, _ := config.Parse([]byte(`
+mapping:
+  email: Email Address
+  company: Organisation
+profiles:
+  acme:
+    delimiter: ";"
+    mapping:
+      company: Firma
+    transforms:
+      infer_source: true
+    dedup:
+      skip_seen: 30
+    target:
+      provider: pipedrive
+      api_url: https://crm.example.com
+    policy:
+      name: fields
+      fields: [company, status]
+`))
+		return cfg
+	}
+
+	t.Run("fills in the flags not given and merges the mapping", func(t *testing.T) {
+		// Arrange
+		cmd := newCommand()
+		cmd.Flags().Set("profile", "acme")
+		cmd.Flags().Set("api-url", "http://explicit:3030")
+		cfg := cfg()
+
+		// Act
+		profile, err := applyProfile(cmd, cfg)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, ';', profile.Comma())
+		delimiter, _ := cmd.Flags().GetString("delimiter")
+		assert.Equal(t, ";", delimiter)
+		inferSource, _ := cmd.Flags().GetBool("infer-source")
+		skipSeen, _ := cmd.Flags().GetInt("skip-seen")
+		provider, _ := cmd.Flags().GetString("provider")
+		apiURL, _ := cmd.Flags().GetString("api-url")
+		assert.True(t, inferSource)
+		assert.Equal(t, 30, skipSeen)
+		assert.Equal(t, "pipedrive", provider)
+		assert.Equal(t, "http://explicit:3030", apiURL)
+		policy, _ := cmd.Flags().GetString("policy")
+		policyFields, _ := cmd.Flags().GetStringSlice("policy-fields")
+		assert.Equal(t, "fields", policy)
+		assert.Equal(t, []string{"company", "status"}, policyFields)
+		assert.Equal(t, map[string]string{"email": "Email Address", "company": "Firma"}, cfg.Mapping)
+	})
+
+	t.Run("changes nothing without --profile", func(t *testing.T) {
+		// Arrange
+		cmd := newCommand()
+		cfg := cfg()
+
+		// Act
+		profile, err := applyProfile(cmd, cfg)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, rune(0), profile.Comma())
+		provider, _ := cmd.Flags().GetString("provider")
+		assert.Equal(t, "api", provider)
+		assert.Equal(t, "Organisation", cfg.Mapping["company"])
+	})
+
+	t.Run("rejects unknown profiles", func(t *testing.T) {
+		// Arrange
+		cmd := newCommand()
+		cmd.Flags().Set("profile", "globex")
+
+		// Act
+		_, err := applyProfile(cmd, cfg())
+
+		// Assert
+		assert.ErrorContains(t, err, `unknown profile "globex" (available: acme)`)
+	})
+}
+
+func TestFillDefaults(t *testing.T) {
+	t.Run("fills only empty fields", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("Jane Roe", "jane@example.com", "", "Website")
+		fill := fillDefaults(map[string]string{"company": "Acme", "source": "Referral"})
+
+		// Act
+		lead = fill(lead)
+
+		// Assert
+		assert.Equal(t, "Acme", lead.Company)
+		assert.Equal(t, "Website", lead.Source)
+	})
+}
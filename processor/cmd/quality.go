@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"code/internal/csv"
+	"code/internal/emailcheck"
+	"code/internal/models"
+	"code/internal/quality"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats of the profile command's report
+const (
+	reportText = "text"
+	reportJSON = "json"
+)
+
+var qualityCmd = &cobra.Command{
+	Use:   "profile <file>",
+	Short: "Report per-column statistics and data-quality problems of a lead file",
+	Long: `Read a lead file without sending anything and report, per column, how often
+it is filled, how many distinct values it has and its most frequent values,
+along with the share of invalid emails, duplicate emails and how the leads'
+sources are spread. Run it on a partner's feed before importing it, to turn it
+down early and show the partner what to fix.
+
+With --max-invalid-emails, the command fails when more than that percentage of
+the leads have an invalid email, so it can gate scheduled imports.`,
+	Example: `  # Profile a partner's file
+  lead-processor profile partner-export.csv
+
+  # Read it with the partner's import profile, and fail on over 5% bad emails
+  lead-processor profile acme-leads.csv --config config.yaml --profile acme --max-invalid-emails 5
+
+  # Keep the statistics as JSON, e.g. to attach to a ticket
+  lead-processor profile partner-export.csv --format json > partner-profile.json`,
+	GroupID: groupLeads,
+	Args:    cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"csv", "parquet", "avro", "xml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runQualityCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(qualityCmd)
+	qualityCmd.Flags().Int("top", 5, "Number of most frequent values shown per column")
+	qualityCmd.Flags().String("format", reportText, "Report format: text or json")
+	qualityCmd.Flags().Float64("max-invalid-emails", 0, "Fail when more than this percentage of leads have an invalid email (0 = never)")
+	qualityCmd.Flags().String("profile", "", "Read the file with the delimiter and mapping of this profile from the config")
+	qualityCmd.Flags().String("input-format", "", "Format of the file: csv, parquet, avro, xml or fixed-width (default from its extension)")
+	qualityCmd.Flags().String("email-validation", string(emailcheck.LevelSyntax), emailValidationUsage)
+
+	_ = qualityCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{reportText, reportJSON}, cobra.ShellCompDirectiveNoFileComp))
+	_ = qualityCmd.RegisterFlagCompletionFunc("input-format", cobra.FixedCompletions(inputFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = qualityCmd.RegisterFlagCompletionFunc("email-validation", completeEmailLevels)
+}
+
+func runQualityCommand(cmd *cobra.Command, args []string) error {
+	top, _ := cmd.Flags().GetInt("top")
+	format, _ := cmd.Flags().GetString("format")
+	maxInvalidEmails, _ := cmd.Flags().GetFloat64("max-invalid-emails")
+	profileName, _ := cmd.Flags().GetString("profile")
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	emailValidation, _ := cmd.Flags().GetString("email-validation")
+	input := cleanPath(args[0])
+
+	if format != reportText && format != reportJSON {
+		return fmt.Errorf("invalid --format %q (use text or json)", format)
+	}
+	if maxInvalidEmails < 0 || maxInvalidEmails > 100 {
+		return fmt.Errorf("invalid --max-invalid-emails: must be between 0 and 100")
+	}
+	emailLevel, err := emailcheck.ParseLevel(emailValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --email-validation: %w", err)
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	csvReader := csv.NewCSVReader()
+	csvReader.SetMapping(cfg.Mapping)
+	if profileName != "" {
+		profile, err := cfg.Profile(profileName)
+		if err != nil {
+			return fmt.Errorf("invalid --profile: %w", err)
+		}
+		csvReader.SetMapping(profileMapping(cfg, profile))
+		csvReader.SetDelimiter(profile.Comma())
+	}
+	source, err := newFileSource(input, inputFormat, cfg, csvReader)
+	if err != nil {
+		return err
+	}
+	header, err := source.ReadHeader()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	LogInfo("Profiling lead file", "input", input)
+	profiler := quality.New(header, top, emailcheck.NewChecker(emailLevel).Rule())
+	if err := source.StreamLeads(func(lead *models.Lead) error {
+		profiler.Add(lead)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+	report := profiler.Report()
+
+	if format == reportJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printQualityReport(input, report)
+	}
+
+	if maxInvalidEmails > 0 && report.InvalidEmailRate*100 > maxInvalidEmails {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%.1f%% of leads have an invalid email, more than --max-invalid-emails %g%%", report.InvalidEmailRate*100, maxInvalidEmails)
+	}
+	return nil
+}
+
+// printQualityReport prints the report as text, columns as a table
+func printQualityReport(input string, report quality.Report) {
+	printer.Printf("Profile of %s\n", input)
+	printer.Printf("Rows: %d\n", report.Rows)
+	printer.Printf("Invalid emails: %d (%.1f%%)\n", report.InvalidEmails, report.InvalidEmailRate*100)
+	printer.Printf("Duplicate emails: %d\n", report.DuplicateEmails)
+	printer.Printf("Invalid sources: %d\n", report.InvalidSources)
+
+	printer.Printf("\n=== Sources ===\n")
+	for _, source := range report.Sources {
+		fmt.Printf("  %-12s %6d  %5.1f%%\n", displayValue(source.Value), source.Count, percent(source.Count, report.Rows))
+	}
+
+	printer.Printf("\n=== Columns ===\n")
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join([]string{printer.Text("COLUMN"), printer.Text("FILLED"), printer.Text("DISTINCT"), printer.Text("TOP VALUES")}, "\t"))
+	for _, column := range report.Columns {
+		distinct := fmt.Sprint(column.Distinct)
+		if column.DistinctCapped {
+			distinct = ">=" + distinct
+		}
+		top := make([]string, len(column.Top))
+		for i, value := range column.Top {
+			top[i] = fmt.Sprintf("%s (%d)", displayValue(value.Value), value.Count)
+		}
+		fmt.Fprintf(table, "%s\t%.1f%%\t%s\t%s\n", column.Name, column.FillRate*100, distinct, strings.Join(top, ", "))
+	}
+	table.Flush()
+}
+
+// displayValue shows an empty value so it can't be missed
+func displayValue(value string) string {
+	if value == "" {
+		return printer.Text("(empty)")
+	}
+	if runes := []rune(value); len(runes) > 40 {
+		return string(runes[:37]) + "..."
+	}
+	return value
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(total)
+}
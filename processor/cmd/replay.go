@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"code/internal/deadletter"
+	"code/internal/emailcheck"
+	"code/internal/errcode"
+	"code/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <dead-letter-file|s3://bucket/prefix>",
+	Short: "Send dead-lettered leads through processing again",
+	Long: `Send the leads a --dead-letter destination collected through validation and
+processing again, once the cause of their failure is fixed. Each lead keeps its
+source row, and the input, row and run it failed in.
+
+A lead dead-lettered more than once is replayed as it was last seen. Leads that
+fail again can be written to a new destination with --dead-letter.`,
+	Example: `  # Replay the leads that were rate limited yesterday
+  lead-processor replay failed-leads.ndjson --code RATE_LIMITED --since 2026-10-15 --until 2026-10-16
+
+  # List what a replay would send from S3 without sending it
+  lead-processor replay s3://ops/lead-dead-letters --dry-run
+
+  # Keep leads that fail again apart from those already replayed
+  lead-processor replay failed-leads.ndjson --dead-letter failed-again.ndjson`,
+	GroupID: groupLeads,
+	Args:    cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"ndjson"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runReplayCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringSlice("code", nil, "Only replay leads that failed with these error codes, e.g. RATE_LIMITED,NETWORK")
+	replayCmd.Flags().String("since", "", "Only replay leads that failed at or after this date or time (YYYY-MM-DD or RFC 3339)")
+	replayCmd.Flags().String("until", "", "Only replay leads that failed before this date or time (YYYY-MM-DD or RFC 3339)")
+	replayCmd.Flags().Bool("dry-run", false, "List the leads that would be replayed without sending them")
+	replayCmd.Flags().Int("workers", 1, "Number of leads processed concurrently")
+	replayCmd.Flags().String("email-validation", string(emailcheck.LevelSyntax), emailValidationUsage)
+	replayCmd.Flags().String("dead-letter", "", deadLetterUsage)
+
+	setFlagGroup(replayCmd, "Filter Flags", "code", "since", "until")
+	_ = replayCmd.RegisterFlagCompletionFunc("code", cobra.FixedCompletions([]string{
+		string(errcode.CodeRateLimited), string(errcode.CodeNetwork), string(errcode.CodeAuth), string(errcode.CodeAPI),
+	}, cobra.ShellCompDirectiveNoFileComp))
+	_ = replayCmd.RegisterFlagCompletionFunc("email-validation", completeEmailLevels)
+}
+
+func runReplayCommand(cmd *cobra.Command, args []string) error {
+	codes, _ := cmd.Flags().GetStringSlice("code")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	workers, _ := cmd.Flags().GetInt("workers")
+	emailValidation, _ := cmd.Flags().GetString("email-validation")
+	deadLetterDest, _ := cmd.Flags().GetString("dead-letter")
+	location := args[0]
+	if !strings.HasPrefix(location, "s3://") {
+		location = cleanPath(location)
+	}
+
+	var filter deadletter.Filter
+	var err error
+	if filter.Codes, err = deadletter.ParseCodes(codes); err != nil {
+		return fmt.Errorf("invalid --code: %w", err)
+	}
+	if filter.Since, err = parseReplayTime(since); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseReplayTime(until); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	emailLevel, err := emailcheck.ParseLevel(emailValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --email-validation: %w", err)
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// The latest entry for each email wins, so a lead is sent once
+	httpClient := storageHTTPClient()
+	var entries []deadletter.Entry
+	latest := make(map[string]int)
+	total := 0
+	skipped, err := deadletter.Load(location, httpClient, func(entry deadletter.Entry) error {
+		total++
+		if !filter.Match(entry) {
+			return nil
+		}
+		key := strings.ToLower(strings.TrimSpace(entry.Lead.Email))
+		if i, ok := latest[key]; ok && key != "" {
+			if entry.At.Before(entries[i].At) {
+				return nil
+			}
+			entries[i] = entry
+			return nil
+		}
+		latest[key] = len(entries)
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read dead letters from %s: %w", location, err)
+	}
+	if skipped > 0 {
+		LogWarn("Skipped unreadable dead-letter lines", "location", location, "lines", skipped)
+	}
+	LogInfo("Read dead letters", "location", location, "entries", total, "matching", len(entries))
+	printer.Printf("Replaying %d of %d dead-lettered lead(s) from %s\n", len(entries), total, location)
+
+	if dryRun {
+		for _, entry := range entries {
+			fmt.Printf("%s %s:%d %s (%s) %s [%s]: %s\n", entry.At.Format(time.RFC3339), entry.Input, entry.Index, entry.Lead.Name, entry.Lead.Email, entry.Action, entry.Code, entry.Error)
+		}
+		return nil
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var deadLetters *deadletter.Writer
+	if deadLetterDest != "" {
+		if !strings.HasPrefix(deadLetterDest, "s3://") {
+			deadLetterDest = cleanPath(deadLetterDest)
+		}
+		deadLetters, err = deadletter.Open(deadLetterDest, uuid.NewString(), httpClient)
+		if err != nil {
+			return fmt.Errorf("invalid --dead-letter: %w", err)
+		}
+		defer deadLetters.Close()
+	}
+
+	leads := make([]*models.Lead, len(entries))
+	for i, entry := range entries {
+		leads[i] = entry.Lead
+	}
+	tally, err := sendLeads(cmd, cfg, workers, emailLevel, leads, func(i int, lead *models.Lead, action string, failure error) error {
+		origin := entries[i]
+		LogWarn("Replayed lead failed again", "email", lead.Email, "input", origin.Input, "index", origin.Index, "action", action, "error", failure.Error())
+		if deadLetters == nil || !deadletter.Terminal(action, failure) {
+			return nil
+		}
+		// The entry keeps the input and row the lead first failed in
+		entry := deadletter.NewEntry(origin.Input, origin.Index, lead, action, failure)
+		entry.RunID = origin.RunID
+		return deadLetters.Write(entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	printer.Printf("\n=== Replay Summary ===\n")
+	tally.print()
+	if deadLetters != nil && deadLetters.Count() > 0 {
+		if err := deadLetters.Close(); err != nil {
+			return fmt.Errorf("failed to write dead-letter entries: %w", err)
+		}
+		printer.Printf("Dead-lettered: %d (written to %s)\n", deadLetters.Count(), deadLetterDest)
+	}
+
+	if err := runFailure(tally.errorCodes); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return nil
+}
+
+// parseReplayTime reads a date, taken as midnight UTC, or an RFC 3339 time
+func parseReplayTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (YYYY-MM-DD) or RFC 3339 time", value)
+	}
+	return t, nil
+}
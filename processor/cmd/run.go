@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"code/internal/config"
+	"code/internal/history"
+	"code/internal/notify"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// pipelineLabel labels every run of a pipeline with the pipeline's name
+const pipelineLabel = "pipeline"
+
+var runCmd = &cobra.Command{
+	Use:   "run [pipeline]",
+	Short: "Run a pipeline declared in the config, from source to notifications",
+	Long: `Run one of the config's pipelines: a whole import declared in YAML, from the
+source it reads, through its transforms, validation and dedup, to the CRM it
+writes to, the files the leftovers go to and the webhooks told how it went.
+Each setting stands in for the process flag of the same name, so a pipeline
+runs exactly as process would with those flags; --print shows that command.
+
+A pipeline's profile is applied first, and the pipeline's own settings take
+precedence over it. Global flags given on the command line, such as --api-url,
+take precedence over both. Every run is labelled pipeline=<name> in the run
+history, and its exit status is the one process would have had.
+
+Without a pipeline, the config's pipelines are listed.`,
+	Example: `  # List the config's pipelines
+  lead-processor run --config pipelines.yaml
+
+  # Run one, e.g. from cron
+  lead-processor run acme-daily --config pipelines.yaml
+
+  # Show the process command a pipeline stands for, without running it
+  lead-processor run acme-daily --config pipelines.yaml --print`,
+	GroupID: groupLeads,
+	Args:    cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return cfg.PipelineNames(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: runRunCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().Bool("print", false, "Print the process command the pipeline stands for instead of running it")
+}
+
+func runRunCommand(cmd *cobra.Command, args []string) error {
+	printOnly, _ := cmd.Flags().GetBool("print")
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return listPipelines(cfg)
+	}
+
+	name := args[0]
+	pipeline, err := cfg.Pipeline(name)
+	if err != nil {
+		return err
+	}
+	flags := pipelineFlags(name, pipeline)
+	if printOnly {
+		if path, _ := cmd.Flags().GetString("config"); path != "" {
+			flags = append(flags, pipelineFlag{"config", path})
+		}
+		fmt.Println(processCommandLine(pipeline.Source.Input, flags))
+		return nil
+	}
+
+	// process reads the global flags, such as --config, from its own set
+	if err := processCmd.ParseFlags(nil); err != nil {
+		return err
+	}
+	for _, flag := range flags {
+		// Global flags given on the command line win
+		if cmd.Flags().Changed(flag.name) {
+			continue
+		}
+		if err := processCmd.Flags().Set(flag.name, flag.value); err != nil {
+			return fmt.Errorf("invalid pipeline %s: --%s: %w", name, flag.name, err)
+		}
+	}
+
+	// The pipeline is valid; what fails from here on is its run
+	cmd.SilenceUsage = true
+	LogInfo("Running pipeline", "pipeline", name, "input", pipeline.Source.Input)
+	printer.Printf("Running pipeline %s\n", name)
+	startedAt := time.Now()
+	runErr := runProcessCommand(processCmd, []string{pipeline.Source.Input})
+	if runErr != nil {
+		LogError("Pipeline failed", runErr, "pipeline", name)
+	} else {
+		LogInfo("Pipeline finished", "pipeline", name)
+	}
+
+	notifyPipeline(name, pipeline.Notifications, startedAt, runErr)
+	return runErr
+}
+
+// listPipelines prints the config's pipelines with their inputs
+func listPipelines(cfg *config.Config) error {
+	names := cfg.PipelineNames()
+	if len(names) == 0 {
+		printer.Printf("The config has no pipelines\n")
+		return nil
+	}
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join([]string{printer.Text("PIPELINE"), printer.Text("INPUT"), printer.Text("DESCRIPTION")}, "\t"))
+	for _, name := range names {
+		pipeline := cfg.Pipelines[name]
+		fmt.Fprintf(table, "%s\t%s\t%s\n", name, pipeline.Source.Input, pipeline.Description)
+	}
+	return table.Flush()
+}
+
+// pipelineFlag is a process flag a pipeline's setting stands in for
+type pipelineFlag struct {
+	name  string
+	value string
+}
+
+// pipelineFlags returns the process flags a pipeline stands for, in the
+// order of its YAML sections. Settings left out are left to process's
+// defaults, or the pipeline's profile.
+func pipelineFlags(name string, p config.Pipeline) []pipelineFlag {
+	var flags []pipelineFlag
+	add := func(flag, value string) {
+		if value != "" {
+			flags = append(flags, pipelineFlag{flag, value})
+		}
+	}
+	addBool := func(flag string, value bool) {
+		if value {
+			add(flag, "true")
+		}
+	}
+	addInt := func(flag string, value int) {
+		if value > 0 {
+			add(flag, strconv.Itoa(value))
+		}
+	}
+
+	add("profile", p.Profile)
+	labels := map[string]string{pipelineLabel: name}
+	for key, value := range p.Labels {
+		labels[key] = value
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	add("label", strings.Join(pairs, ","))
+
+	add("input-format", p.Source.Format)
+	add("delimiter", p.Source.Delimiter)
+	add("quote", p.Source.Quote)
+	add("header", p.Source.Header)
+	add("sheet-range", p.Source.SheetRange)
+	add("meta-source", p.Source.MetaSource)
+
+	addBool("infer-source", p.Transforms.InferSource)
+	add("filter", p.Transforms.Filter)
+	add("order-by", p.Transforms.OrderBy)
+
+	add("email-validation", p.Validation.Email)
+	addBool("require-consent", p.Validation.RequireConsent)
+	addInt("preflight", p.Validation.Preflight)
+	if p.Validation.PreflightMaxInvalid != nil {
+		add("preflight-max-invalid", strconv.FormatFloat(*p.Validation.PreflightMaxInvalid, 'g', -1, 64))
+	}
+	if p.Validation.PreflightMaxConflicts != nil {
+		add("preflight-max-conflicts", strconv.FormatFloat(*p.Validation.PreflightMaxConflicts, 'g', -1, 64))
+	}
+	addInt("reconcile", p.Validation.Reconcile)
+	if p.Validation.ReconcileDelay > 0 {
+		add("reconcile-delay", p.Validation.ReconcileDelay.String())
+	}
+
+	add("history-dir", p.Dedup.HistoryDir)
+	addInt("skip-seen", p.Dedup.SkipSeen)
+	addBool("no-reprocess", p.Dedup.NoReprocess)
+	add("mirror", p.Dedup.Mirror)
+	add("bloom", p.Dedup.Bloom)
+
+	add("provider", p.Target.Provider)
+	add("api-url", p.Target.APIURL)
+	add("policy", p.Target.Policy.Name)
+	add("policy-fields", strings.Join(p.Target.Policy.Fields, ","))
+	addBool("respect-ownership", p.Target.RespectOwnership)
+	addInt("workers", p.Target.Workers)
+	addInt("max-api-calls", p.Target.MaxAPICalls)
+	addInt("max-creates", p.Target.MaxCreates)
+	addBool("mailchimp", p.Target.Mailchimp)
+	addBool("attachments", p.Target.Attachments)
+	add("note", p.Target.Note)
+
+	add("rejects-file", p.Sinks.Rejects)
+	add("split-rejects-by", p.Sinks.SplitRejectsBy)
+	add("dead-letter", p.Sinks.DeadLetter)
+	add("deferred-file", p.Sinks.Deferred)
+	add("review-file", p.Sinks.Review)
+	add("export", p.Sinks.Export)
+	add("export-format", p.Sinks.ExportFormat)
+	add("events", p.Sinks.Events)
+	return flags
+}
+
+// plainArgument matches arguments a shell takes as they are
+var plainArgument = regexp.MustCompile(`^[A-Za-z0-9_./:=,@%+-]+$`)
+
+// processCommandLine spells out the process command a pipeline stands for,
+// quoted for a POSIX shell
+func processCommandLine(input string, flags []pipelineFlag) string {
+	quote := func(s string) string {
+		if plainArgument.MatchString(s) {
+			return s
+		}
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	parts := []string{"lead-processor", "process", quote(input)}
+	for _, flag := range flags {
+		if flag.value == "true" {
+			parts = append(parts, "--"+flag.name)
+			continue
+		}
+		parts = append(parts, "--"+flag.name, quote(flag.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// notifyPipeline tells the pipeline's webhooks how its run went, with its
+// figures from the run history when it was recorded. Failing to notify is
+// only logged, so it doesn't mask the run's own outcome.
+func notifyPipeline(name string, webhooks []notify.Webhook, startedAt time.Time, runErr error) {
+	failed := runErr != nil
+	var wanted []notify.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Wants(failed) {
+			wanted = append(wanted, webhook)
+		}
+	}
+	if len(wanted) == 0 {
+		return
+	}
+
+	message := notify.NewMessage(name, startedAt, pipelineRun(startedAt), runErr, ExitCode(runErr))
+	client := storageHTTPClient()
+	for i, webhook := range wanted {
+		if err := notify.Send(client, webhook, message); err != nil {
+			// The URL may hold the webhook's secret, so it isn't logged
+			LogWarn("Failed to notify webhook", "pipeline", name, "webhook", i, "error", err.Error())
+			printer.Printf("Warning: failed to notify a webhook of pipeline %s: %v\n", name, err)
+			continue
+		}
+		LogInfo("Notified webhook", "pipeline", name, "webhook", i, "status", message.Status)
+	}
+}
+
+// pipelineRun returns the run process recorded in its history since
+// startedAt, or nil if history is off or the run failed before recording one
+func pipelineRun(startedAt time.Time) *history.Run {
+	historyDir, _ := processCmd.Flags().GetString("history-dir")
+	historyDir = cleanPath(historyDir)
+	if historyDir == "" {
+		return nil
+	}
+	store, err := history.Open(historyDir)
+	if err != nil {
+		LogWarn("Failed to open run history", "historyDir", historyDir, "error", err.Error())
+		return nil
+	}
+	defer store.Close()
+
+	runs := store.Runs()
+	for i := len(runs) - 1; i >= 0; i-- {
+		if !runs[i].StartedAt.Before(startedAt) {
+			return runs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"code/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineFlags(t *testing.T) {
+	t.Run("spells a pipeline out as a process command", func(t *testing.T) {
+		// Arrange
+		maxInvalid := 2.5
+		pipeline := config.Pipeline{
+			Profile:    "acme",
+			Labels:     map[string]string{"campaign": "q3 webinar"},
+			Source:     config.PipelineSource{Input: "/data/acme leads.csv", Delimiter: ";"},
+			Validation: config.PipelineValidation{Email: "dns", PreflightMaxInvalid: &maxInvalid, ReconcileDelay: 30 * time.Second},
+			Dedup:      config.PipelineDedup{SkipSeen: 7, NoReprocess: true},
+			Target:     config.PipelineTarget{Policy: config.ProfilePolicy{Name: "fields", Fields: []string{"company", "status"}}, Workers: 4},
+			Sinks:      config.PipelineSinks{Rejects: "rejects.csv"},
+		}
+
+		// Act
+		line := processCommandLine(pipeline.Source.Input, pipelineFlags("acme-daily", pipeline))
+
+		// Assert
+		assert.Equal(t, "lead-processor process '/data/acme leads.csv' --profile acme --label 'campaign=q3 webinar,pipeline=acme-daily' "+
+			"--delimiter ';' --email-validation dns --preflight-max-invalid 2.5 --reconcile-delay 30s --skip-seen 7 --no-reprocess "+
+			"--policy fields --policy-fields company,status --workers 4 --rejects-file rejects.csv", line)
+	})
+
+	t.Run("only names flags process has", func(t *testing.T) {
+		// Arrange
+		limit := 1.0
+		pipeline := config.Pipeline{
+			Profile:    "acme",
+			Source:     config.PipelineSource{Input: "a.csv", Format: "csv", Delimiter: ";", Quote: "'", Header: "yes", SheetRange: "A:H", MetaSource: "LinkedIn"},
+			Transforms: config.PipelineTransforms{InferSource: true, Filter: "true", OrderBy: "score"},
+			Validation: config.PipelineValidation{Email: "dns", RequireConsent: true, Preflight: 1, PreflightMaxInvalid: &limit, PreflightMaxConflicts: &limit, Reconcile: 1, ReconcileDelay: time.Second},
+			Dedup:      config.PipelineDedup{HistoryDir: "h", SkipSeen: 1, NoReprocess: true, Mirror: "m.json", Bloom: "b.json"},
+			Target:     config.PipelineTarget{Provider: "api", APIURL: "http://x", Policy: config.ProfilePolicy{Name: "fields", Fields: []string{"company"}}, RespectOwnership: true, Workers: 1, MaxAPICalls: 1, MaxCreates: 1, Mailchimp: true, Attachments: true, Note: "n"},
+			Sinks:      config.PipelineSinks{Rejects: "r", SplitRejectsBy: "Partner", DeadLetter: "d", Deferred: "f", Review: "v", Export: "e", ExportFormat: "csv", Events: "t"},
+		}
+		assert.NoError(t, processCmd.ParseFlags(nil))
+
+		for _, flag := range pipelineFlags("daily", pipeline) {
+			// Assert
+			assert.NotNil(t, processCmd.Flags().Lookup(flag.name), flag.name)
+		}
+	})
+}
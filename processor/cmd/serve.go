@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/auth"
+	"code/internal/config"
+	"code/internal/deadletter"
+	"code/internal/emailcheck"
+	"code/internal/jobs"
+	"code/internal/langdetect"
+	"code/internal/mailchimp"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"code/internal/server"
+	"code/internal/territory"
+	"code/internal/upload"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP server that processes uploaded lead files",
+	Long: `Run an HTTP server that accepts CSV uploads on POST /imports and processes them via external APIs.
+
+With --queue-dir, uploads are stored and queued instead: POST /imports answers
+202 with the job, which GET /imports/{id} reports on, and GET /imports lists jobs
+filtered by state, since, until and limit. Jobs interrupted by a restart run
+again when the server starts.
+
+Large files can also be sent in resumable chunks with the tus protocol on
+/uploads; each is queued as an import once every byte has arrived.
+
+POST /imports/from-url takes {"url": "...", "name": "...", "priority": 0}
+instead of the file: the server fetches it itself, e.g. from a pre-signed S3 or
+GCS URL, so large files never pass through the caller's connection. Only https
+URLs on hosts in --url-hosts are fetched from, redirects included, and never
+from private, loopback or link-local addresses. The URL's query, which holds
+the signature, is left out of job listings and logs.
+
+With --detect-profile, each import is read with the config profile whose
+match rules fit it: match.filename is a pattern for the file's name (?name=, the
+upload's filename or the URL's), match.header columns its header must have.
+Files no profile matches fail with UNMATCHED_PROFILE, to be routed by hand.
+
+With serve.tokens in the config, requests must carry a token whose role allows
+them: viewer (list imports, dashboard), uploader (also submit imports), reviewer
+(also approve or reject imports) or admin (also pprof). --audit-log records who
+submitted and who reviewed which import. A token's quota caps its imports
+running at once, the leads they process per day (the rest fail with
+QUOTA_EXCEEDED) and the rate of their CRM requests.
+
+With --require-approval, queued imports write nothing until reviewed: each is
+first planned by looking its leads up without writing, then waits in
+pending_review with the plan, which GET /imports/{id} and the dashboard show. A
+reviewer other than its submitter runs it with POST /imports/{id}/approve or
+drops it with POST /imports/{id}/reject, optionally with {"comment": "..."}.
+
+Form submissions are processed as they arrive on POST /webhooks/typeform and
+POST /webhooks/google-forms. Their questions become columns, named after the
+question titles (and Typeform field refs and hidden fields), and are mapped to
+lead fields with the config's mapping like CSV columns.`,
+	Example: `  # Accept uploads on port 9000
+  lead-processor serve --addr :9000
+
+  # Upload a file to a running server
+  curl --data-binary @leads.csv -H 'Content-Type: text/csv' http://localhost:9000/imports
+
+  # Queue uploads on a persistent volume, so they survive restarts
+  lead-processor serve --queue-dir /data/imports --queue-workers 2
+  curl --data-binary @leads.csv 'http://localhost:9000/imports?priority=10&name=leads.csv'
+  curl 'http://localhost:9000/imports?state=failed&since=2026-10-01'
+
+  # Import a file straight from S3 with a pre-signed URL
+  curl -H 'Content-Type: application/json' \
+    -d "{\"url\": \"$(aws s3 presign s3://leads/2026-10/leads.csv)\"}" \
+    http://localhost:9000/imports/from-url
+
+  # Let marketing ops follow imports in a browser at http://localhost:9000/dashboard
+  lead-processor serve --addr :9000 --queue-dir /data/imports --dashboard
+
+  # Hold uploads until a reviewer approves their plan
+  lead-processor serve --queue-dir /data/imports --require-approval --audit-log audit.ndjson
+  curl -X POST -H "Authorization: Bearer $REVIEWER_TOKEN" http://localhost:9000/imports/$ID/approve
+
+  # Verify Typeform signatures and Google Forms tokens
+  lead-processor serve --typeform-secret "$TYPEFORM_SECRET" --forms-token "$FORMS_TOKEN"`,
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+	RunE:    runServeCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().String("addr", ":8080", "Address to listen on")
+	serveCmd.Flags().Int("workers", 1, "Number of leads processed concurrently per import")
+	serveCmd.Flags().Bool("require-consent", false, "Reject leads that have not given marketing consent")
+	serveCmd.Flags().Bool("respect-ownership", false, "Skip updates to leads the CRM has assigned to an owner, as a rep is working them")
+	serveCmd.Flags().String("email-validation", string(emailcheck.LevelSyntax), emailValidationUsage)
+	serveCmd.Flags().Bool("infer-source", false, "Fill in a missing source from referrer and event columns, or the config's source_rules")
+	serveCmd.Flags().Bool("pprof", false, "Expose net/http/pprof handlers under /debug/pprof/")
+	serveCmd.Flags().Bool("dashboard", false, "Serve a web dashboard of running, queued and recent imports on /dashboard")
+	serveCmd.Flags().Bool("detect-profile", false, "Read each import with the config profile whose match rules fit its file name or header; others fail with UNMATCHED_PROFILE")
+	serveCmd.Flags().String("typeform-secret", os.Getenv("TYPEFORM_SECRET"), "Secret Typeform signs webhook payloads with, or a vault:// or aws-sm:// reference to it; unsigned payloads are rejected when set (default: serve.typeform_secret in the config)")
+	serveCmd.Flags().String("forms-token", os.Getenv("FORMS_TOKEN"), "Bearer token Google Forms submissions must carry when set, or a vault:// or aws-sm:// reference to it (default: serve.forms_token in the config)")
+	serveCmd.Flags().Bool("mailchimp", false, "Also subscribe created and updated leads to the config's Mailchimp audience")
+	serveCmd.Flags().String("dead-letter", "", deadLetterUsage)
+	serveCmd.Flags().String("audit-log", "", "Append uploads and refused requests, with the token that made them, to this NDJSON file")
+	serveCmd.Flags().String("queue-dir", "", "Queue uploads in this directory and process them in the background, by priority")
+	serveCmd.Flags().Int("queue-workers", 1, "Number of queued imports processed at once")
+	serveCmd.Flags().Bool("require-approval", false, "Hold queued imports with a plan of what they would do until a reviewer approves them")
+	serveCmd.Flags().String("max-upload-size", "10GB", "Largest file POST /imports or a resumable upload to /uploads may send (e.g. 500MB, 20GB); 0 for no limit")
+	serveCmd.Flags().Duration("upload-expiry", 24*time.Hour, "Drop resumable uploads not finished within this time; 0 keeps them")
+	serveCmd.Flags().StringSlice("url-hosts", server.DefaultURLHosts, "Hosts, and their subdomains, POST /imports/from-url may fetch files from; a * label matches any one label")
+
+	setFlagGroup(serveCmd, "Webhook Flags", "typeform-secret", "forms-token")
+	setFlagGroup(serveCmd, "Access Flags", "audit-log")
+	setFlagGroup(serveCmd, "Sync Flags", "mailchimp")
+	setFlagGroup(serveCmd, "Queue Flags", "queue-dir", "queue-workers", "require-approval", "max-upload-size", "upload-expiry", "url-hosts")
+	_ = serveCmd.MarkFlagDirname("queue-dir")
+	_ = serveCmd.MarkFlagFilename("audit-log", "ndjson")
+	_ = serveCmd.RegisterFlagCompletionFunc("email-validation", completeEmailLevels)
+}
+
+func runServeCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	addr, _ := cmd.Flags().GetString("addr")
+	workers, _ := cmd.Flags().GetInt("workers")
+	enablePprof, _ := cmd.Flags().GetBool("pprof")
+	enableDashboard, _ := cmd.Flags().GetBool("dashboard")
+	requireConsent, _ := cmd.Flags().GetBool("require-consent")
+	respectOwnership, _ := cmd.Flags().GetBool("respect-ownership")
+	inferSource, _ := cmd.Flags().GetBool("infer-source")
+	detectProfile, _ := cmd.Flags().GetBool("detect-profile")
+	emailValidation, _ := cmd.Flags().GetString("email-validation")
+	typeformSecret, _ := cmd.Flags().GetString("typeform-secret")
+	formsToken, _ := cmd.Flags().GetString("forms-token")
+	syncMailchimp, _ := cmd.Flags().GetBool("mailchimp")
+	deadLetterDest, _ := cmd.Flags().GetString("dead-letter")
+	queueDir, _ := cmd.Flags().GetString("queue-dir")
+	queueWorkers, _ := cmd.Flags().GetInt("queue-workers")
+	requireApproval, _ := cmd.Flags().GetBool("require-approval")
+	maxUploadSize, _ := cmd.Flags().GetString("max-upload-size")
+	uploadExpiry, _ := cmd.Flags().GetDuration("upload-expiry")
+	auditLogFile, _ := cmd.Flags().GetString("audit-log")
+	urlHosts, _ := cmd.Flags().GetStringSlice("url-hosts")
+
+	emailLevel, err := emailcheck.ParseLevel(emailValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --email-validation: %w", err)
+	}
+	maxUploadBytes, err := parseByteSize(maxUploadSize)
+	if err != nil {
+		return fmt.Errorf("invalid --max-upload-size: %w", err)
+	}
+	if requireApproval && queueDir == "" {
+		return fmt.Errorf("--require-approval needs --queue-dir, as imports wait for review in the queue")
+	}
+	if respectOwnership {
+		if err := checkOwnershipProvider(cmd); err != nil {
+			return err
+		}
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if typeformSecret, err = resolveSecret(typeformSecret, cfg.Serve.TypeformSecret); err != nil {
+		return fmt.Errorf("invalid --typeform-secret: %w", err)
+	}
+	if formsToken, err = resolveSecret(formsToken, cfg.Serve.FormsToken); err != nil {
+		return fmt.Errorf("invalid --forms-token: %w", err)
+	}
+
+	validation := validationOptions(cfg, emailLevel)
+	serverCfg := server.Config{
+		Addr:        addr,
+		EnablePprof: enablePprof,
+		Workers:     workers,
+		Mapping:     cfg.Mapping,
+		NewID:       cfg.IDGenerator(),
+		Transforms:  []pipeline.Transform{models.Sanitize},
+		Validation:  validation,
+
+		EnableDashboard: enableDashboard,
+		MaxUploadSize:   maxUploadBytes,
+
+		URLHosts: urlHosts,
+		// Fetching a large file outlasts the client's request timeout, so
+		// only its transport is shared
+		FetchClient: &http.Client{Transport: api.NewAPIClient("", targetOption(api.TargetStorage), api.WithDialContext(server.DialPublic), api.WithRequestTimeout(0)).HTTPClient().Transport},
+
+		TypeformSecret: typeformSecret,
+		FormsToken:     formsToken,
+	}
+	if inferSource {
+		serverCfg.Transforms = append(serverCfg.Transforms, sourceInferrer(cfg).Infer)
+	}
+	if !cfg.Territories.IsZero() {
+		geo, err := loadGeoIP(cfg)
+		if err != nil {
+			return err
+		}
+		serverCfg.Transforms = append(serverCfg.Transforms, territory.New(cfg.Territories, geo).Assign)
+	}
+	if !cfg.LanguageDetection.IsZero() {
+		serverCfg.Transforms = append(serverCfg.Transforms, langdetect.New(cfg.LanguageDetection).Detect)
+	}
+	if detectProfile {
+		if len(cfg.Profiles) == 0 {
+			return fmt.Errorf("--detect-profile needs profiles with match rules in the config")
+		}
+		serverCfg.DetectProfile = profileDetector(cfg, serverCfg.Transforms, inferSource)
+	}
+	if deadLetterDest != "" {
+		if !strings.HasPrefix(deadLetterDest, "s3://") {
+			deadLetterDest = cleanPath(deadLetterDest)
+		}
+		// Each request's failures are flushed as they happen, so S3 gets
+		// one object per request
+		serverCfg.DeadLetters, err = deadletter.Open(deadLetterDest, uuid.NewString(), storageHTTPClient())
+		if err != nil {
+			return fmt.Errorf("invalid --dead-letter: %w", err)
+		}
+		defer serverCfg.DeadLetters.Close()
+	}
+
+	var audit *auth.AuditLog
+	if auditLogFile != "" {
+		if audit, err = auth.OpenAuditLog(cleanPath(auditLogFile)); err != nil {
+			return fmt.Errorf("invalid --audit-log: %w", err)
+		}
+		defer audit.Close()
+	}
+	if serverCfg.Auth, err = auth.New(cfg.Serve, audit); err != nil {
+		return fmt.Errorf("invalid serve tokens: %w", err)
+	}
+	if requireApproval && !serverCfg.Auth.Enabled() {
+		return fmt.Errorf("--require-approval needs serve tokens in the config, so reviews name their reviewer")
+	}
+	if !serverCfg.Auth.Enabled() {
+		LogWarn("No serve tokens configured; imports, the dashboard and pprof are open to anyone who can reach the server")
+	}
+
+	if queueDir != "" {
+		store, err := jobs.NewFileStore(cleanPath(queueDir))
+		if err != nil {
+			return fmt.Errorf("invalid --queue-dir: %w", err)
+		}
+		if serverCfg.Jobs, err = jobs.Open(store); err != nil {
+			return fmt.Errorf("invalid --queue-dir: %w", err)
+		}
+		serverCfg.JobWorkers = queueWorkers
+		serverCfg.RequireApproval = requireApproval
+		// Uploads sit next to the queue, so a finished one moves into it
+		// without being copied
+		if serverCfg.Uploads, err = upload.Open(filepath.Join(cleanPath(queueDir), "uploads")); err != nil {
+			return fmt.Errorf("invalid --queue-dir: %w", err)
+		}
+		serverCfg.UploadExpiry = uploadExpiry
+		LogInfo("Import queue opened", "dir", queueDir, "queued", len(serverCfg.Jobs.List(jobs.Filter{State: jobs.StateQueued})), "pendingReview", len(serverCfg.Jobs.List(jobs.Filter{State: jobs.StatePendingReview})))
+	}
+
+	// Imports get their own processor but share the client, or their
+	// token's client when its quota paces CRM requests
+	client, err := newLeadClient(cmd, cfg, apiURL)
+	if err != nil {
+		return err
+	}
+	serverCfg.Quotas = cfg.Serve.Quotas()
+	tenantClients := make(map[string]processor.APIClient)
+	for tenant, quota := range serverCfg.Quotas {
+		if quota.CRMRequestsPerSecond <= 0 {
+			continue
+		}
+		limiter := api.NewLimiter(api.Limit{RequestsPerSecond: quota.CRMRequestsPerSecond})
+		if tenantClients[tenant], err = newLeadClient(cmd, cfg, apiURL, api.WithMiddleware(limiter.Middleware)); err != nil {
+			return err
+		}
+	}
+	var sink *mailchimp.Client
+	if syncMailchimp {
+		if sink, err = newMailchimpSink(cfg); err != nil {
+			return err
+		}
+	}
+	srv := server.New(serverCfg, func(tenant string) pipeline.LeadProcessor {
+		leadClient := client
+		if tenantClient, ok := tenantClients[tenant]; ok {
+			leadClient = tenantClient
+		}
+		leadProcessor := processor.NewLeadProcessor(leadClient)
+		// The stages were validated with the config
+		_ = leadProcessor.SetStages(cfg.Stages)
+		leadProcessor.SetProtectedFields(cfg.ProtectedFields)
+		leadProcessor.SetEquality(cfg.Equality)
+		leadProcessor.SetRequireConsent(requireConsent)
+		leadProcessor.SetRespectOwnership(respectOwnership)
+		leadProcessor.SetValidation(validation)
+		if sink != nil {
+			return processor.NewSinkProcessor(leadProcessor, sink)
+		}
+		return leadProcessor
+	})
+
+	if typeformSecret == "" || formsToken == "" {
+		LogWarn("Form webhooks accept unauthenticated submissions", "typeformSigned", typeformSecret != "", "formsToken", formsToken != "")
+	}
+	LogInfo("Starting server", "addr", addr, "apiURL", apiURL, "pprof", enablePprof, "dashboard", enableDashboard)
+	printer.Printf("Listening on %s\n", addr)
+
+	return srv.ListenAndServe()
+}
+
+// profileDetector reads each import with the config profile matching its
+// file. A profile's dedup, target and policy settings don't apply: the server
+// keeps no run history, writes to one CRM and always upserts.
+func profileDetector(cfg *config.Config, transforms []pipeline.Transform, inferSource bool) server.ProfileDetector {
+	profiles := make(map[string]server.Profile, len(cfg.Profiles))
+	for name, profile := range cfg.Profiles {
+		if len(profile.Match.Header) == 0 && profile.Match.Filename == "" {
+			LogWarn("Profile has no match rules and is never picked", "profile", name)
+		}
+		profileTransforms := append([]pipeline.Transform(nil), transforms...)
+		if profile.Transforms.InferSource && !inferSource {
+			profileTransforms = append(profileTransforms, sourceInferrer(cfg).Infer)
+		}
+		if len(profile.Transforms.Defaults) > 0 {
+			profileTransforms = append(profileTransforms, fillDefaults(profile.Transforms.Defaults))
+		}
+		profiles[name] = server.Profile{
+			Name:         name,
+			Comma:        profile.Comma(),
+			Mapping:      profileMapping(cfg, profile),
+			Transforms:   profileTransforms,
+			Schema:       profile.Schema.Header,
+			StrictSchema: profile.Schema.Strict,
+		}
+	}
+	return func(filename, headerLine string) (server.Profile, bool) {
+		name, ok := cfg.DetectProfile(filename, headerLine)
+		return profiles[name], ok
+	}
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/bloom"
+	"code/internal/config"
+	"code/internal/mirror"
+	"code/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultMirrorFile is where sync snapshot keeps the lead mirror
+const defaultMirrorFile = "leads-mirror.json"
+
+// defaultBloomFile is where sync bloom keeps the bloom filter
+const defaultBloomFile = "leads-bloom.json"
+
+var syncCmd = &cobra.Command{
+	Use:     "sync",
+	Short:   "Keep local copies of the CRM's leads",
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+}
+
+var syncSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Mirror the CRM's leads locally, so process can look leads up without the API",
+	Long: `Copy every lead from the API's export into a local mirror file. Later runs
+only fetch the leads created or updated since the previous one, so the mirror
+can be refreshed often; --full fetches everything again and drops leads the
+CRM no longer has.
+
+process --mirror answers its lookups from the mirror, after refreshing it the
+same way, and only its creates and updates call the API.`,
+	Example: `  # Mirror the CRM's leads, then refresh the mirror every hour from cron
+  lead-processor sync snapshot --mirror leads-mirror.json
+
+  # Rebuild the mirror from scratch, e.g. weekly, to drop deleted leads
+  lead-processor sync snapshot --mirror leads-mirror.json --full
+
+  # Process a file with lookups answered from the mirror
+  lead-processor process leads.csv --mirror leads-mirror.json`,
+	Args: cobra.NoArgs,
+	RunE: runSyncSnapshotCommand,
+}
+
+var syncBloomCmd = &cobra.Command{
+	Use:   "bloom",
+	Short: "Keep a bloom filter of the CRM's emails, so process can skip lookups of new leads",
+	Long: `Build a bloom filter of every email the CRM has from the API's export. An
+email the filter rules out is certainly not in the CRM, so process --bloom
+skips its lookup and creates the lead straight away; an email the filter
+might have is looked up as usual.
+
+Later runs only add the emails of leads created or updated since the previous
+one. --full rebuilds the filter, sized for twice the CRM's leads, which drops
+deleted leads and makes room to grow. A filter holding more emails than it
+was sized for still works but rules out fewer, so rebuild it when sync bloom
+warns.`,
+	Example: `  # Build the filter, then refresh it before each import
+  lead-processor sync bloom --bloom leads-bloom.json
+
+  # Rebuild it from scratch, e.g. weekly
+  lead-processor sync bloom --bloom leads-bloom.json --full
+
+  # Process a feed of mostly new leads, skipping their lookups
+  lead-processor process fresh-leads.csv --bloom leads-bloom.json`,
+	Args: cobra.NoArgs,
+	RunE: runSyncBloomCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncSnapshotCmd)
+	syncCmd.AddCommand(syncBloomCmd)
+	syncSnapshotCmd.Flags().String("mirror", defaultMirrorFile, "Local mirror file of the CRM's leads")
+	syncSnapshotCmd.Flags().Bool("full", false, "Fetch every lead instead of those updated since the last sync")
+	syncSnapshotCmd.Flags().Int("page-size", api.DefaultPageSize, "Leads fetched per export request")
+	_ = syncSnapshotCmd.MarkFlagFilename("mirror", "json")
+
+	syncBloomCmd.Flags().String("bloom", defaultBloomFile, "Bloom filter file of the CRM's emails")
+	syncBloomCmd.Flags().Bool("full", false, "Rebuild the filter from every lead instead of adding those updated since the last sync")
+	syncBloomCmd.Flags().Float64("false-positive-rate", bloom.DefaultFalsePositiveRate, "Share of new emails a rebuilt filter still sends to lookup; lower rates make a bigger file")
+	syncBloomCmd.Flags().Int("page-size", api.DefaultPageSize, "Leads fetched per export request")
+	_ = syncBloomCmd.MarkFlagFilename("bloom", "json")
+}
+
+func runSyncSnapshotCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	mirrorFile, _ := cmd.Flags().GetString("mirror")
+	mirrorFile = cleanPath(mirrorFile)
+	full, _ := cmd.Flags().GetBool("full")
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	export, err := mirrorExport(cmd, cfg, apiURL, api.PageOptions{PageSize: pageSize})
+	if err != nil {
+		return err
+	}
+	leadMirror, err := mirror.Open(mirrorFile)
+	if err != nil {
+		return err
+	}
+
+	// Failures from here on are about the API, not the command line
+	cmd.SilenceUsage = true
+	stats, err := syncMirror(leadMirror, export, full, mirrorFile)
+	if err != nil {
+		return err
+	}
+	if stats.Full {
+		printer.Printf("Mirrored %d lead(s) to %s (%d removed)\n", leadMirror.Len(), mirrorFile, stats.Removed)
+	} else {
+		printer.Printf("Refreshed %d lead(s) in %s, which now holds %d\n", stats.Fetched, mirrorFile, leadMirror.Len())
+	}
+	return nil
+}
+
+func runSyncBloomCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	bloomFile, _ := cmd.Flags().GetString("bloom")
+	bloomFile = cleanPath(bloomFile)
+	full, _ := cmd.Flags().GetBool("full")
+	rate, _ := cmd.Flags().GetFloat64("false-positive-rate")
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+
+	if rate <= 0 || rate >= 1 {
+		return fmt.Errorf("invalid --false-positive-rate %v (use a value between 0 and 1, e.g. 0.01)", rate)
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	export, err := mirrorExport(cmd, cfg, apiURL, api.PageOptions{PageSize: pageSize})
+	if err != nil {
+		return err
+	}
+	filter, err := bloom.Open(bloomFile)
+	if err != nil {
+		return err
+	}
+
+	// Failures from here on are about the API, not the command line
+	cmd.SilenceUsage = true
+	stats, err := syncBloom(filter, export, full, rate, bloomFile)
+	if err != nil {
+		return err
+	}
+	if stats.Full {
+		printer.Printf("Built a bloom filter of %d email(s) in %s (%s)\n", stats.Added, bloomFile, formatByteSize(int64(filter.Size())))
+	} else {
+		printer.Printf("Added %d email(s) to %s, which now holds %d\n", stats.Added, bloomFile, filter.Count())
+	}
+	if filter.Count() > filter.Capacity() {
+		printer.Printf("Warning: %s holds more emails than it was sized for; rebuild it with --full\n", bloomFile)
+	}
+	return nil
+}
+
+// syncBloom refreshes the bloom filter and saves it
+func syncBloom(filter *bloom.Filter, export mirror.Export, full bool, rate float64, bloomFile string) (bloom.SyncStats, error) {
+	LogInfo("Syncing bloom filter", "bloom", bloomFile, "full", full, "syncedAt", filter.SyncedAt().Format(time.RFC3339))
+	stats, err := filter.Sync(bloom.Export(export), full, rate)
+	if err != nil {
+		LogError("Failed to sync bloom filter", err, "bloom", bloomFile)
+		return stats, fmt.Errorf("failed to sync the bloom filter: %w", err)
+	}
+	if err := filter.Save(); err != nil {
+		return stats, err
+	}
+	LogInfo("Synced bloom filter", "bloom", bloomFile, "full", stats.Full, "added", stats.Added, "count", filter.Count(), "capacity", filter.Capacity())
+	return stats, nil
+}
+
+// mirrorExport reads the API's lead export for a mirror or bloom filter,
+// with the run's client options, such as its call budget
+func mirrorExport(cmd *cobra.Command, cfg *config.Config, apiURL string, opts api.PageOptions, clientOpts ...api.ClientOption) (mirror.Export, error) {
+	provider, _ := cmd.Flags().GetString("provider")
+	if strings.ToLower(strings.TrimSpace(provider)) != providerAPI {
+		return nil, fmt.Errorf("the lead mirror and bloom filter need the lead API's export and only work with --provider %s", providerAPI)
+	}
+	client := api.NewAPIClient(apiURL, append(configClientOptions(cfg), clientOpts...)...)
+	return func(since time.Time, fn func(*models.Lead) error) error {
+		it := client.ExportLeads(opts)
+		if !since.IsZero() {
+			it = client.ExportLeadsUpdatedSince(since, opts)
+		}
+		defer it.Close()
+		for it.Next() {
+			if err := fn(convertAPIToProcessorLead(it.Lead())); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	}, nil
+}
+
+// syncMirror refreshes the mirror and saves it
+func syncMirror(leadMirror *mirror.Mirror, export mirror.Export, full bool, mirrorFile string) (mirror.SyncStats, error) {
+	LogInfo("Syncing lead mirror", "mirror", mirrorFile, "full", full, "syncedAt", leadMirror.SyncedAt().Format(time.RFC3339))
+	stats, err := leadMirror.Sync(export, full)
+	if err != nil {
+		LogError("Failed to sync lead mirror", err, "mirror", mirrorFile)
+		return stats, fmt.Errorf("failed to sync the lead mirror: %w", err)
+	}
+	if err := leadMirror.Save(); err != nil {
+		return stats, err
+	}
+	LogInfo("Synced lead mirror", "mirror", mirrorFile, "full", stats.Full, "fetched", stats.Fetched, "removed", stats.Removed, "leads", leadMirror.Len())
+	return stats, nil
+}
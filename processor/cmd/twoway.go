@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/bisync"
+	"code/internal/csv"
+	"code/internal/emailcheck"
+	"code/internal/errcode"
+	"code/internal/history"
+	"code/internal/mirror"
+	"code/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultSyncStateFile is where sync two-way remembers what each side had
+const defaultSyncStateFile = "sync-state.json"
+
+var syncTwoWayCmd = &cobra.Command{
+	Use:   "two-way <file>",
+	Short: "Sync a partner's lead file with the CRM in both directions",
+	Long: `Push the changes made in a lead file since the last sync to the CRM, and
+export the changes made in the CRM since then to a CSV file or webhook, so the
+partner can apply them.
+
+Each side's changes are found against what it had at the last sync, kept in
+the --state file, with the CRM read from the lead mirror (see sync snapshot),
+which is refreshed first. A field changed on one side takes that side's value.
+A field both sides changed to different values is a conflict: the CRM's value
+is kept unless --prefer or the config's sync_precedence says the file wins.
+Protected fields always keep the CRM's value.
+
+The first sync has no state, so every field that differs is a conflict and
+every CRM lead the file doesn't match is exported.`,
+	Example: `  # Sync a partner's file, exporting the CRM's changes to a file
+  lead-processor sync two-way partner.csv --changes partner-changes.csv
+
+  # Let the partner win conflicts on status, and post the CRM's changes
+  lead-processor sync two-way partner.csv --prefer status=file --changes https://partner.example.com/hooks/leads
+
+  # See what a sync would do without writing anything
+  lead-processor sync two-way partner.csv --changes partner-changes.csv --dry-run`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"csv", "parquet", "avro", "xml"}, cobra.ShellCompDirectiveFilterFileExt
+	},
+	RunE: runSyncTwoWayCommand,
+}
+
+func init() {
+	syncCmd.AddCommand(syncTwoWayCmd)
+	syncTwoWayCmd.Flags().String("changes", "", "CSV file or http(s) webhook the CRM's changes are exported to (required)")
+	syncTwoWayCmd.Flags().String("mirror", defaultMirrorFile, "Local mirror file of the CRM's leads")
+	syncTwoWayCmd.Flags().String("state", defaultSyncStateFile, "File keeping what each side had at the last sync")
+	syncTwoWayCmd.Flags().StringToString("prefer", nil, "Which side wins a conflict on each field: field=crm or file (overrides sync_precedence)")
+	syncTwoWayCmd.Flags().String("input-format", "", "Format of the file: csv, parquet, avro, xml or fixed-width (default from its extension)")
+	syncTwoWayCmd.Flags().String("email-validation", string(emailcheck.LevelSyntax), emailValidationUsage)
+	syncTwoWayCmd.Flags().StringToString("label", nil, "Label the sync, e.g. campaign=q3-webinar (repeatable); sent with webhook changes and to the API in the "+api.RunLabelsHeader+" header")
+	syncTwoWayCmd.Flags().Bool("dry-run", false, "Show what would be pushed and exported without writing anything")
+
+	_ = syncTwoWayCmd.MarkFlagRequired("changes")
+	_ = syncTwoWayCmd.MarkFlagFilename("mirror", "json")
+	_ = syncTwoWayCmd.MarkFlagFilename("state", "json")
+	_ = syncTwoWayCmd.RegisterFlagCompletionFunc("input-format", cobra.FixedCompletions(inputFormats, cobra.ShellCompDirectiveNoFileComp))
+	_ = syncTwoWayCmd.RegisterFlagCompletionFunc("email-validation", completeEmailLevels)
+}
+
+func runSyncTwoWayCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	changes, _ := cmd.Flags().GetString("changes")
+	if !bisync.IsWebhook(changes) {
+		changes = cleanPath(changes)
+	}
+	mirrorFile, _ := cmd.Flags().GetString("mirror")
+	mirrorFile = cleanPath(mirrorFile)
+	stateFile, _ := cmd.Flags().GetString("state")
+	stateFile = cleanPath(stateFile)
+	prefer, _ := cmd.Flags().GetStringToString("prefer")
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	emailValidation, _ := cmd.Flags().GetString("email-validation")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	labelPairs, _ := cmd.Flags().GetStringToString("label")
+	input := cleanPath(args[0])
+
+	labels, err := history.ParseLabels(labelPairs)
+	if err != nil {
+		return fmt.Errorf("invalid --label: %w", err)
+	}
+
+	emailLevel, err := emailcheck.ParseLevel(emailValidation)
+	if err != nil {
+		return fmt.Errorf("invalid --email-validation: %w", err)
+	}
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// --prefer overrides the config per field
+	precedence := make(map[string]string)
+	for field, rule := range cfg.SyncPrecedence {
+		precedence[field] = rule
+	}
+	for field, rule := range prefer {
+		precedence[strings.ToLower(strings.TrimSpace(field))] = rule
+	}
+	policy, err := bisync.ParsePolicy(precedence)
+	if err != nil {
+		return fmt.Errorf("invalid --prefer: %w", err)
+	}
+
+	export, err := mirrorExport(cmd, cfg, apiURL, api.PageOptions{})
+	if err != nil {
+		return err
+	}
+	leadMirror, err := mirror.Open(mirrorFile)
+	if err != nil {
+		return err
+	}
+	if leadMirror.SyncedAt().IsZero() {
+		return fmt.Errorf("lead mirror %s was never synced; run sync snapshot first", mirrorFile)
+	}
+	state, err := bisync.OpenState(stateFile)
+	if err != nil {
+		return err
+	}
+
+	csvReader := csv.NewCSVReader()
+	csvReader.SetMapping(cfg.Mapping)
+	csvReader.SetIDGenerator(cfg.IDGenerator())
+	source, err := newFileSource(input, inputFormat, cfg, csvReader)
+	if err != nil {
+		return err
+	}
+	// A lead listed twice is synced as its last row
+	var fileLeads []*models.Lead
+	positions := make(map[string]int)
+	err = source.StreamLeads(func(lead *models.Lead) error {
+		lead = models.Sanitize(lead)
+		k := bisync.Key(lead.Email)
+		if k == "" {
+			return nil
+		}
+		if i, ok := positions[k]; ok {
+			fileLeads[i] = lead
+			return nil
+		}
+		positions[k] = len(fileLeads)
+		fileLeads = append(fileLeads, lead)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	// Failures from here on are about the API, not the command line
+	cmd.SilenceUsage = true
+	if _, err := syncMirror(leadMirror, export, false, mirrorFile); err != nil {
+		return err
+	}
+
+	var clientOpts []api.ClientOption
+	if len(labels) > 0 {
+		clientOpts = append(clientOpts, api.WithMiddleware(api.HeaderMiddleware(api.RunLabelsHeader, labels.String())))
+	}
+	leadClient, err := newLeadClient(cmd, cfg, apiURL, clientOpts...)
+	if err != nil {
+		return err
+	}
+	client := mirror.NewClient(leadClient, leadMirror)
+	validation := validationOptions(cfg, emailLevel)
+	exporter := bisync.NewExporter(changes, storageHTTPClient())
+	exporter.SetLabels(labels)
+	syncedAt := time.Now().UTC()
+
+	LogInfo("Syncing lead file with the CRM", "input", input, "changes", changes, "state", stateFile, "dryRun", dryRun)
+	printer.Printf("Syncing %s with the CRM\n", input)
+
+	tally := &leadTally{errorCodes: make(map[errcode.Code]int)}
+	conflicts := 0
+	for _, fileLead := range fileLeads {
+		base := state.Get(fileLead.Email)
+		crm, _ := leadMirror.Get(fileLead.Email)
+		resolution := bisync.Reconcile(base, fileLead, crm, policy)
+		resolution.Protect(crm, cfg.ProtectedFields)
+		for _, conflict := range resolution.Conflicts {
+			conflicts++
+			if conflict.Winner == bisync.SideFile {
+				fmt.Printf("  ! %s\n", printer.Sprintf("%s: %s changed on both sides, kept the file's %q", fileLead.Email, conflict.Field, conflict.File))
+			} else {
+				fmt.Printf("  ! %s\n", printer.Sprintf("%s: %s changed on both sides, kept the CRM's %q", fileLead.Email, conflict.Field, conflict.CRM))
+			}
+		}
+
+		final := crm
+		switch {
+		case resolution.Lead == nil:
+			fmt.Printf("  %s %s\n", symbols.skip, printer.Sprintf("%s: no changes needed", fileLead.Email))
+			tally.unchanged++
+			if !dryRun {
+				state.Set(fileLead.Email, fileLead, nil)
+			}
+		case dryRun:
+			if crm == nil {
+				fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: would create", fileLead.Email))
+				tally.created++
+			} else {
+				fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: would update", fileLead.Email))
+				tally.updated++
+			}
+			final = resolution.Lead
+		default:
+			written, err := pushLead(client, resolution.Lead, crm == nil, validation)
+			if err != nil {
+				fmt.Printf("  %s %s: %s\n", symbols.fail, fileLead.Email, printer.Error(err))
+				LogWarn("Lead failed", "email", fileLead.Email, "error", err.Error())
+				tally.errorCodes[errcode.Of(err)]++
+				tally.failed++
+				// The CRM's changes are still exported, and the file's are
+				// tried again next sync
+				if crm != nil && bisync.CRMChanged(base, crm) && !bisync.Agree(fileLead, crm) {
+					exporter.Add(crm)
+				}
+				continue
+			}
+			if crm == nil {
+				fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: created", fileLead.Email))
+				tally.created++
+			} else {
+				fmt.Printf("  %s %s\n", symbols.ok, printer.Sprintf("%s: updated", fileLead.Email))
+				tally.updated++
+			}
+			final = written
+			state.Set(fileLead.Email, fileLead, nil)
+		}
+
+		// The partner needs the CRM's copy when the CRM changed and the file
+		// doesn't already have what the CRM now holds
+		if crm != nil && bisync.CRMChanged(base, crm) && !bisync.Agree(fileLead, final) {
+			exporter.Add(final)
+		}
+		if !dryRun && final != nil {
+			state.Set(fileLead.Email, nil, final)
+		}
+	}
+
+	// Leads only the CRM has are exported when they changed
+	leadMirror.Each(func(crm *models.Lead) {
+		if _, ok := positions[bisync.Key(crm.Email)]; ok {
+			return
+		}
+		if bisync.CRMChanged(state.Get(crm.Email), crm) {
+			exporter.Add(crm)
+			if !dryRun {
+				state.Set(crm.Email, nil, crm)
+			}
+		}
+	})
+
+	if !dryRun {
+		if err := exporter.Write(syncedAt); err != nil {
+			LogError("Failed to export CRM changes", err, "changes", changes)
+			return err
+		}
+		if err := leadMirror.Save(); err != nil {
+			return err
+		}
+		state.SyncedAt = syncedAt
+		if err := state.Save(); err != nil {
+			return err
+		}
+	}
+	LogInfo("Synced lead file with the CRM", "input", input, "created", tally.created, "updated", tally.updated, "conflicts", conflicts, "exported", exporter.Count(), "failed", tally.failed)
+
+	printer.Printf("\n=== Sync Summary ===\n")
+	tally.print()
+	printer.Printf("Conflicts: %d\n", conflicts)
+	if dryRun {
+		printer.Printf("CRM changes to export: %d\n", exporter.Count())
+	} else {
+		printer.Printf("CRM changes exported: %d (to %s)\n", exporter.Count(), changes)
+	}
+	return runFailure(tally.errorCodes)
+}
+
+// pushLead validates a reconciled lead and writes it to the CRM
+func pushLead(client *mirror.Client, lead *models.Lead, create bool, validation models.ValidationOptions) (*models.Lead, error) {
+	if err := lead.ValidateWith(validation); err != nil {
+		return nil, err
+	}
+	var written *models.Lead
+	var err error
+	if create {
+		written, err = client.CreateLead(lead)
+	} else {
+		written, err = client.UpdateLead(lead)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if written == nil || written.Email == "" {
+		return lead, nil
+	}
+	return written, nil
+}
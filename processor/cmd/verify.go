@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"code/internal/api"
+	"code/internal/mirror"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare the lead mirror with the CRM and report leads changed outside this tool",
+	Long: `Read every lead from the API's export and compare it with the local mirror
+kept by sync snapshot, matching leads by email. Leads this tool writes with
+process --mirror are kept up to date in the mirror, so a difference is drift:
+a lead changed, created or deleted in the CRM by someone or something else,
+or a sign of a sync bug.
+
+verify only reads: the mirror is neither refreshed nor saved, so the drift can
+be inspected before the next sync snapshot takes it in. Leads changed since
+the last sync drift until the next one, so verify right after a sync for the
+clearest picture.
+
+A run that finds drift exits with status 11.`,
+	Example: `  # Check the mirror against the CRM
+  lead-processor verify --mirror leads-mirror.json
+
+  # Only print the counts, e.g. from a nightly job
+  lead-processor verify --mirror leads-mirror.json --quiet`,
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+	RunE:    runVerifyCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().String("mirror", defaultMirrorFile, "Local mirror file of the CRM's leads, kept by sync snapshot")
+	verifyCmd.Flags().Int("page-size", api.DefaultPageSize, "Leads fetched per export request")
+	verifyCmd.Flags().Bool("quiet", false, "Only print the summary, not each lead")
+	_ = verifyCmd.MarkFlagFilename("mirror", "json")
+}
+
+func runVerifyCommand(cmd *cobra.Command, args []string) error {
+	apiURL, _ := cmd.Flags().GetString("api-url")
+	mirrorFile, _ := cmd.Flags().GetString("mirror")
+	mirrorFile = cleanPath(mirrorFile)
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+
+	initLogger("info")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	export, err := mirrorExport(cmd, cfg, apiURL, api.PageOptions{PageSize: pageSize})
+	if err != nil {
+		return err
+	}
+	leadMirror, err := mirror.Open(mirrorFile)
+	if err != nil {
+		return err
+	}
+	if leadMirror.SyncedAt().IsZero() {
+		return fmt.Errorf("invalid --mirror: %s has not been synced yet, run sync snapshot --mirror %s first", mirrorFile, mirrorFile)
+	}
+
+	// Failures from here on are about the API, not the command line
+	cmd.SilenceUsage = true
+	LogInfo("Verifying lead mirror", "mirror", mirrorFile, "syncedAt", leadMirror.SyncedAt().Format(time.RFC3339), "leads", leadMirror.Len())
+	printer.Printf("Comparing %s (synced %s) with the CRM\n", mirrorFile, leadMirror.SyncedAt().Local().Format("2006-01-02 15:04"))
+
+	stats, err := leadMirror.Verify(export, func(drift mirror.Drift) error {
+		if !quiet {
+			printDrift(drift)
+		}
+		return nil
+	})
+	if err != nil {
+		LogError("Failed to verify lead mirror", err, "mirror", mirrorFile)
+		return fmt.Errorf("failed to verify the lead mirror: %w", err)
+	}
+	LogInfo("Verified lead mirror", "mirror", mirrorFile, "changed", stats.Changed, "unknown", stats.Unknown, "missing", stats.Missing, "unchanged", stats.Unchanged)
+
+	printer.Printf("\n=== Drift Summary ===\n")
+	printer.Printf("Changed: %d\n", stats.Changed)
+	printer.Printf("Not in the mirror: %d\n", stats.Unknown)
+	printer.Printf("No longer in the CRM: %d\n", stats.Missing)
+	printer.Printf("Unchanged: %d\n", stats.Unchanged)
+	if stats.Total() > 0 {
+		return fmt.Errorf("%w: %d lead(s) differ between %s and the CRM", mirror.ErrDrift, stats.Total(), mirrorFile)
+	}
+	return nil
+}
+
+// printDrift prints a line for a lead that drifted
+func printDrift(drift mirror.Drift) {
+	switch drift.Kind {
+	case mirror.DriftChanged:
+		fmt.Printf("  ~ %s\n", printer.Sprintf("%s: changed %s", drift.Lead.Email, strings.Join(drift.Fields, ", ")))
+	case mirror.DriftUnknown:
+		fmt.Printf("  + %s\n", printer.Sprintf("%s: not in the mirror", drift.Lead.Email))
+	case mirror.DriftMissing:
+		fmt.Printf("  - %s\n", printer.Sprintf("%s: no longer in the CRM", drift.Lead.Email))
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"code/internal/version"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the build version, commit and date",
+	Long: `Print the version, git commit and build date of this binary. With
+--check-update, also ask GitHub whether a newer release has been published.`,
+	Example: `  lead-processor version
+  lead-processor version --check-update`,
+	GroupID: groupOperations,
+	Args:    cobra.NoArgs,
+	RunE:    runVersionCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().Bool("check-update", false, "Check GitHub for a newer release")
+}
+
+func runVersionCommand(cmd *cobra.Command, args []string) error {
+	checkUpdate, _ := cmd.Flags().GetBool("check-update")
+
+	info := version.Get()
+	fmt.Printf("lead-processor %s\n", info.Version)
+	fmt.Printf("  commit: %s\n", info.Commit)
+	fmt.Printf("  built:  %s\n", info.Date)
+	fmt.Printf("  go:     %s %s\n", info.GoVersion, info.Platform)
+
+	if !checkUpdate {
+		return nil
+	}
+
+	release, err := version.LatestRelease(nil, version.ReleasesURL)
+	if err != nil {
+		return fmt.Errorf("update check failed: %w", err)
+	}
+
+	if version.IsNewer(info.Version, release.TagName) {
+		printer.Printf("A newer release is available: %s (%s)\n", release.TagName, release.URL)
+	} else {
+		printer.Printf("No newer release found (latest is %s)\n", release.TagName)
+	}
+
+	return nil
+}
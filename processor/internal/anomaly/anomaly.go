@@ -0,0 +1,192 @@
+package anomaly
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrAnomaly means a run's figures deviate sharply from the earlier runs of
+// the same feed, hinting at a broken export rather than a quiet day
+var ErrAnomaly = errors.New("run deviates from its baseline")
+
+// Actions taken on an anomalous run
+const (
+	ActionWarn = "warn"
+	ActionFail = "fail"
+)
+
+// Metrics compared against the baseline
+const (
+	MetricRows      = "rows"
+	MetricCreated   = "created"
+	MetricBytes     = "bytes"
+	MetricErrorRate = "error_rate"
+)
+
+const (
+	defaultBaselineRuns = 10
+	defaultMinRuns      = 3
+
+	// minErrorRate stands in for a baseline error rate below it, so a feed
+	// that usually has no errors isn't flagged for a single one
+	minErrorRate = 0.01
+)
+
+// Config sets how far a run may stray from the baseline of its feed's
+// earlier runs. Drops are percentages below the baseline; a threshold of 0
+// leaves its metric unchecked.
+type Config struct {
+	// Action is warn (default) or fail
+	Action string `yaml:"action"`
+	// BaselineRuns is how many of the feed's latest runs make up the
+	// baseline (default 10)
+	BaselineRuns int `yaml:"baseline_runs"`
+	// MinRuns is how many earlier runs a feed needs before it is checked
+	// (default 3)
+	MinRuns int `yaml:"min_runs"`
+
+	RowsDrop    float64 `yaml:"rows_drop"`
+	CreatedDrop float64 `yaml:"created_drop"`
+	BytesDrop   float64 `yaml:"bytes_drop"`
+	// ErrorRateRise flags error rates this many times the baseline's
+	ErrorRateRise float64 `yaml:"error_rate_rise"`
+}
+
+// Validate rejects unknown actions and out-of-range thresholds
+func (c Config) Validate() error {
+	if c.Action != "" && c.Action != ActionWarn && c.Action != ActionFail {
+		return fmt.Errorf("action: unknown action %q (allowed: %s, %s)", c.Action, ActionWarn, ActionFail)
+	}
+	if c.BaselineRuns < 0 {
+		return fmt.Errorf("baseline_runs cannot be negative")
+	}
+	if c.MinRuns < 0 {
+		return fmt.Errorf("min_runs cannot be negative")
+	}
+	if c.MinRuns > c.Runs() {
+		return fmt.Errorf("min_runs cannot exceed baseline_runs (%d)", c.Runs())
+	}
+	for _, drop := range []struct {
+		name  string
+		value float64
+	}{{"rows_drop", c.RowsDrop}, {"created_drop", c.CreatedDrop}, {"bytes_drop", c.BytesDrop}} {
+		if drop.value < 0 || drop.value > 100 {
+			return fmt.Errorf("%s must be between 0 and 100", drop.name)
+		}
+	}
+	if c.ErrorRateRise != 0 && c.ErrorRateRise <= 1 {
+		return fmt.Errorf("error_rate_rise must be greater than 1")
+	}
+	return nil
+}
+
+// Enabled reports whether any metric is checked
+func (c Config) Enabled() bool {
+	return c.RowsDrop > 0 || c.CreatedDrop > 0 || c.BytesDrop > 0 || c.ErrorRateRise > 0
+}
+
+// Fails reports whether an anomalous run fails
+func (c Config) Fails() bool {
+	return c.Action == ActionFail
+}
+
+// Runs returns how many earlier runs make up the baseline
+func (c Config) Runs() int {
+	if c.BaselineRuns == 0 {
+		return defaultBaselineRuns
+	}
+	return c.BaselineRuns
+}
+
+func (c Config) minRuns() int {
+	if c.MinRuns == 0 {
+		return min(defaultMinRuns, c.Runs())
+	}
+	return c.MinRuns
+}
+
+// Metrics are the figures of one run
+type Metrics struct {
+	Rows    int
+	Created int
+	Errors  int
+	// Bytes is the input file's size, 0 when it isn't a file
+	Bytes int64
+}
+
+// ErrorRate returns the share of rows that failed
+func (m Metrics) ErrorRate() float64 {
+	if m.Rows == 0 {
+		return 0
+	}
+	return float64(m.Errors) / float64(m.Rows)
+}
+
+// Anomaly is a metric that strayed from its baseline, the median of the
+// earlier runs
+type Anomaly struct {
+	Metric   string
+	Value    float64
+	Baseline float64
+}
+
+// Detect compares a run against the earlier runs of its feed and returns
+// the metrics that strayed past their thresholds. Too few earlier runs make
+// no baseline, so nothing is flagged.
+func Detect(cfg Config, run Metrics, earlier []Metrics) []Anomaly {
+	if !cfg.Enabled() || len(earlier) < cfg.minRuns() {
+		return nil
+	}
+
+	var anomalies []Anomaly
+	dropped := func(metric string, threshold, value float64, values []float64) {
+		baseline := median(values)
+		if threshold > 0 && baseline > 0 && (baseline-value)/baseline*100 >= threshold {
+			anomalies = append(anomalies, Anomaly{Metric: metric, Value: value, Baseline: baseline})
+		}
+	}
+	dropped(MetricRows, cfg.RowsDrop, float64(run.Rows), collect(earlier, func(m Metrics) float64 { return float64(m.Rows) }))
+	dropped(MetricCreated, cfg.CreatedDrop, float64(run.Created), collect(earlier, func(m Metrics) float64 { return float64(m.Created) }))
+	if run.Bytes > 0 {
+		// Runs of sources other than files have no size to compare
+		sized := make([]Metrics, 0, len(earlier))
+		for _, m := range earlier {
+			if m.Bytes > 0 {
+				sized = append(sized, m)
+			}
+		}
+		if len(sized) >= cfg.minRuns() {
+			dropped(MetricBytes, cfg.BytesDrop, float64(run.Bytes), collect(sized, func(m Metrics) float64 { return float64(m.Bytes) }))
+		}
+	}
+
+	if cfg.ErrorRateRise > 0 && run.Rows > 0 {
+		baseline := median(collect(earlier, Metrics.ErrorRate))
+		if rate := run.ErrorRate(); rate > max(baseline, minErrorRate)*cfg.ErrorRateRise {
+			anomalies = append(anomalies, Anomaly{Metric: MetricErrorRate, Value: rate, Baseline: baseline})
+		}
+	}
+	return anomalies
+}
+
+func collect(runs []Metrics, value func(Metrics) float64) []float64 {
+	values := make([]float64, len(runs))
+	for i, m := range runs {
+		values[i] = value(m)
+	}
+	return values
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
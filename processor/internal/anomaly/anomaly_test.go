@@ -0,0 +1,94 @@
+package anomaly
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func baseline() []Metrics {
+	return []Metrics{
+		{Rows: 1000, Created: 100, Errors: 10, Bytes: 50000},
+		{Rows: 1100, Created: 120, Errors: 12, Bytes: 55000},
+		{Rows: 900, Created: 90, Errors: 8, Bytes: 45000},
+	}
+}
+
+func TestDetect(t *testing.T) {
+	t.Run("flags metrics that strayed past their thresholds", func(t *testing.T) {
+		// Arrange
+		cfg := Config{RowsDrop: 50, CreatedDrop: 80, BytesDrop: 50, ErrorRateRise: 3}
+		run := Metrics{Rows: 950, Created: 10, Errors: 40, Bytes: 20000}
+
+		// Act
+		anomalies := Detect(cfg, run, baseline())
+
+		// Assert
+		assert.Equal(t, []Anomaly{
+			{Metric: MetricCreated, Value: 10, Baseline: 100},
+			{Metric: MetricBytes, Value: 20000, Baseline: 50000},
+			{Metric: MetricErrorRate, Value: 40.0 / 950, Baseline: 0.01},
+		}, anomalies)
+	})
+
+	t.Run("leaves a normal run alone", func(t *testing.T) {
+		// Arrange
+		cfg := Config{RowsDrop: 50, CreatedDrop: 80, BytesDrop: 50, ErrorRateRise: 3}
+		run := Metrics{Rows: 800, Created: 70, Errors: 15, Bytes: 40000}
+
+		// Act
+		anomalies := Detect(cfg, run, baseline())
+
+		// Assert
+		assert.Empty(t, anomalies)
+	})
+
+	t.Run("needs enough earlier runs for a baseline", func(t *testing.T) {
+		// Arrange
+		cfg := Config{CreatedDrop: 80}
+		run := Metrics{Rows: 1000, Created: 0}
+
+		// Act
+		anomalies := Detect(cfg, run, baseline()[:2])
+
+		// Assert
+		assert.Empty(t, anomalies)
+	})
+
+	t.Run("floors an error-free baseline", func(t *testing.T) {
+		// Arrange
+		cfg := Config{ErrorRateRise: 3}
+		clean := []Metrics{{Rows: 1000}, {Rows: 1000}, {Rows: 1000}}
+
+		// Act
+		one := Detect(cfg, Metrics{Rows: 1000, Errors: 1}, clean)
+		many := Detect(cfg, Metrics{Rows: 1000, Errors: 50}, clean)
+
+		// Assert
+		assert.Empty(t, one)
+		assert.Len(t, many, 1)
+	})
+
+	t.Run("skips the file size when earlier runs weren't files", func(t *testing.T) {
+		// Arrange
+		cfg := Config{BytesDrop: 50}
+		earlier := []Metrics{{Rows: 1000}, {Rows: 1000}, {Rows: 1000, Bytes: 50000}}
+
+		// Act
+		anomalies := Detect(cfg, Metrics{Rows: 1000, Bytes: 100}, earlier)
+
+		// Assert
+		assert.Empty(t, anomalies)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects unknown actions and out-of-range thresholds", func(t *testing.T) {
+		// Assert
+		assert.NoError(t, Config{Action: ActionFail, CreatedDrop: 80, ErrorRateRise: 3}.Validate())
+		assert.ErrorContains(t, Config{Action: "page"}.Validate(), "unknown action")
+		assert.ErrorContains(t, Config{RowsDrop: 120}.Validate(), "rows_drop")
+		assert.ErrorContains(t, Config{ErrorRateRise: 0.5}.Validate(), "error_rate_rise")
+		assert.ErrorContains(t, Config{BaselineRuns: 2, MinRuns: 5}.Validate(), "min_runs")
+	})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CachedLookup is a lookup response together with the ETag the server sent for it
+type CachedLookup struct {
+	ETag     string          `json:"etag"`
+	Response *LookupResponse `json:"response"`
+}
+
+// LookupCache stores the last-seen lookup response per email
+type LookupCache interface {
+	Get(email string) (*CachedLookup, bool)
+	Set(email string, entry *CachedLookup)
+}
+
+// FileLookupCache is a LookupCache persisted as a JSON file between runs
+type FileLookupCache struct {
+	path    string
+	mu      sync.RWMutex
+	entries map[string]*CachedLookup
+}
+
+// NewFileLookupCache loads the cache at path, starting empty if the file does not exist
+func NewFileLookupCache(path string) (*FileLookupCache, error) {
+	cache := &FileLookupCache{
+		path:    path,
+		entries: make(map[string]*CachedLookup),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read lookup cache: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cache.entries); err != nil {
+			return nil, fmt.Errorf("failed to decode lookup cache: %w", err)
+		}
+	}
+
+	return cache, nil
+}
+
+// Get returns the cached lookup for email, if any
+func (c *FileLookupCache) Get(email string) (*CachedLookup, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[cacheKey(email)]
+	return entry, ok
+}
+
+// Set stores the lookup for email
+func (c *FileLookupCache) Set(email string, entry *CachedLookup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cacheKey(email)] = entry
+}
+
+// Save writes the cache back to disk
+func (c *FileLookupCache) Save() error {
+	c.mu.RLock()
+	data, err := json.Marshal(c.entries)
+	c.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode lookup cache: %w", err)
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write lookup cache: %w", err)
+	}
+
+	return os.Rename(tmpPath, c.path)
+}
+
+// cacheKey normalizes an email for cache lookups; the API matches emails case-insensitively
+func cacheKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
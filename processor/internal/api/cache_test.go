@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIClient_LookupLeadWithCache(t *testing.T) {
+	t.Run("revalidates cached lookups with If-None-Match", func(t *testing.T) {
+		// Arrange
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			json.NewEncoder(w).Encode(LookupResponse{
+				Found: true,
+				Lead:  &Lead{ID: "1", Email: "alice@example.com", Name: "Alice Johnson"},
+			})
+		}))
+		defer server.Close()
+
+		cache, err := NewFileLookupCache(filepath.Join(t.TempDir(), "cache.json"))
+		assert.NoError(t, err)
+		client := NewAPIClient(server.URL, WithLookupCache(cache))
+
+		// Act
+		first, err1 := client.LookupLead("alice@example.com")
+		second, err2 := client.LookupLead("Alice@Example.com")
+
+		// Assert
+		assert.NoError(t, err1)
+		assert.NoError(t, err2)
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, first, second)
+		assert.Equal(t, "Alice Johnson", second.Lead.Name)
+	})
+
+	t.Run("persists entries across cache instances", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "cache.json")
+		cache, err := NewFileLookupCache(path)
+		assert.NoError(t, err)
+		cache.Set("bob@startup.com", &CachedLookup{ETag: `"abc"`, Response: &LookupResponse{Found: false}})
+
+		// Act
+		saveErr := cache.Save()
+		reloaded, loadErr := NewFileLookupCache(path)
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, loadErr)
+		entry, ok := reloaded.Get("bob@startup.com")
+		assert.True(t, ok)
+		assert.Equal(t, `"abc"`, entry.ETag)
+		assert.False(t, entry.Response.Found)
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"code/internal/errcode"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrUnsupportedFeature is returned when the server does not advertise a feature
+var ErrUnsupportedFeature = errors.New("feature not supported by server")
+
+// Capability names advertised by the /api/version endpoint
+const (
+	CapabilityPatch        = "patch"
+	CapabilityDomainLookup = "domain-lookup"
+	CapabilityArchive      = "archive"
+	CapabilityGzip         = "gzip"
+)
+
+// Capabilities describes the optional features a server supports
+type Capabilities struct {
+	Version      string
+	Patch        bool
+	DomainLookup bool
+	Archive      bool
+	Gzip         bool
+}
+
+// versionResponse is the body of GET /api/version
+type versionResponse struct {
+	Version      string   `json:"version"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// LegacyCapabilities describes servers that predate the version endpoint:
+// lookup, create and update only
+func LegacyCapabilities() *Capabilities {
+	return &Capabilities{Version: "legacy"}
+}
+
+// DetectCapabilities asks the server which features it supports and remembers
+// the answer. Servers without a version endpoint are treated as legacy.
+func (c *APIClient) DetectCapabilities() (*Capabilities, error) {
+	resp, err := c.httpClient.Get(c.baseURL + "/api/version")
+	if err != nil {
+		if isTimeoutError(err) {
+			return nil, fmt.Errorf("request timeout: %w", errcode.Network(err))
+		}
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		c.capabilities = LegacyCapabilities()
+		return c.capabilities, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &errcode.StatusError{Status: resp.StatusCode}
+	}
+
+	var version versionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	caps := &Capabilities{Version: version.Version}
+	for _, name := range version.Capabilities {
+		switch name {
+		case CapabilityPatch:
+			caps.Patch = true
+		case CapabilityDomainLookup:
+			caps.DomainLookup = true
+		case CapabilityArchive:
+			caps.Archive = true
+		case CapabilityGzip:
+			caps.Gzip = true
+		}
+	}
+	c.capabilities = caps
+
+	return caps, nil
+}
+
+// Capabilities returns the detected capabilities, or nil if detection has not run
+func (c *APIClient) Capabilities() *Capabilities {
+	return c.capabilities
+}
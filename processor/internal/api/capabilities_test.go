@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIClient_DetectCapabilities(t *testing.T) {
+	t.Run("reads capabilities from the version endpoint", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/version", r.URL.Path)
+			w.Write([]byte(`{"version":"2.1.0","capabilities":["batch","patch","archive","gzip"]}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		caps, err := client.DetectCapabilities()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "2.1.0", caps.Version)
+		assert.True(t, caps.Patch)
+		assert.False(t, caps.DomainLookup)
+		assert.True(t, caps.Archive)
+		assert.True(t, caps.Gzip)
+		assert.Equal(t, caps, client.Capabilities())
+	})
+
+	t.Run("treats servers without a version endpoint as legacy", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		caps, err := client.DetectCapabilities()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, LegacyCapabilities(), caps)
+	})
+
+	t.Run("refuses features the server does not support", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+		_, err := client.DetectCapabilities()
+		assert.NoError(t, err)
+
+		// Act
+		_, patchErr := client.PatchLead("1", map[string]string{"name": "Alice"})
+		it := client.LookupLeadsByDomain("example.com", PageOptions{})
+
+		// Assert
+		assert.True(t, errors.Is(patchErr, ErrUnsupportedFeature))
+		assert.False(t, it.Next())
+		assert.True(t, errors.Is(it.Err(), ErrUnsupportedFeature))
+	})
+}
@@ -1,21 +1,65 @@
 package api
 
 import (
+	"bytes"
+	"code/internal/errcode"
 	"code/internal/models"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// ErrLeadNotFound is returned when the server has no lead with the requested ID
+var ErrLeadNotFound = errors.New("lead not found")
+
 // APIClient handles communication with the external API
 type APIClient struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL        string
+	httpClient     *http.Client
+	network        http.RoundTripper
+	transport      http.RoundTripper
+	dialContext    DialContextFunc
+	debug          Middleware
+	compression    Compression
+	gzipRefused    atomic.Bool
+	middlewares    []Middleware
+	lookupCache    LookupCache
+	capabilities   *Capabilities
+	rawSource      bool
+	requestTimeout time.Duration
+}
+
+// ClientOption configures optional APIClient behaviour
+type ClientOption func(*APIClient)
+
+// WithLookupCache enables conditional lookups (If-None-Match/ETag) backed by cache
+func WithLookupCache(cache LookupCache) ClientOption {
+	return func(c *APIClient) {
+		c.lookupCache = cache
+	}
+}
+
+// WithRawSource sends the source row of each created lead, by header, as
+// rawSource, so CRM admins can see exactly what a partner sent
+func WithRawSource() ClientOption {
+	return func(c *APIClient) {
+		c.rawSource = true
+	}
+}
+
+// RawSource reports whether created leads carry their source row
+func (c *APIClient) RawSource() bool {
+	return c.rawSource
 }
 
 // LookupResponse represents the response from the lookup API
@@ -26,22 +70,49 @@ type LookupResponse struct {
 
 // Lead represents a lead from the API response
 type Lead struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Company   string    `json:"company"`
-	Source    string    `json:"source"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Company   string     `json:"company"`
+	Source    string     `json:"source"`
+	Status    string     `json:"status,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Owner     string     `json:"owner,omitempty"`
+	Territory string     `json:"territory,omitempty"`
+	Language  string     `json:"language,omitempty"`
+
+	ConsentGiven     bool       `json:"consentGiven,omitempty"`
+	ConsentTimestamp *time.Time `json:"consentTimestamp,omitempty"`
+	ConsentSource    string     `json:"consentSource,omitempty"`
 }
 
 // NewAPIClient creates a new API client
-func NewAPIClient(baseURL string) *APIClient {
-	return &APIClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 5 * time.Second, // Shorter timeout for testing
-		},
+func NewAPIClient(baseURL string, opts ...ClientOption) *APIClient {
+	client := &APIClient{
+		baseURL:        baseURL,
+		httpClient:     &http.Client{},
+		requestTimeout: DefaultRequestTimeout,
+		middlewares:    DefaultMiddlewares(),
+		compression:    CompressionAuto,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.network, client.baseURL = BaseTransport(baseURL)
+	if client.dialContext != nil {
+		client.network = dialTransport(client.dialContext)
 	}
+	client.rebuildTransport()
+
+	return client
+}
+
+// BaseURL returns the HTTP URL requests are addressed to, which for a unix://
+// socket is a placeholder host
+func (c *APIClient) BaseURL() string {
+	return c.baseURL
 }
 
 // LookupLead looks up a lead by email
@@ -50,25 +121,49 @@ func (c *APIClient) LookupLead(email string) (*LookupResponse, error) {
 	apiURL := fmt.Sprintf("%s/api/leads/lookup?email=%s", c.baseURL, url.QueryEscape(email))
 
 	// Make HTTP GET request
-	resp, err := c.httpClient.Get(apiURL)
+	resp, err := c.doLookupRequest(apiURL, email)
 	if err != nil {
 		// Check if it's a timeout error
 		if isTimeoutError(err) {
-			return nil, fmt.Errorf("request timeout: %w", err)
+			return nil, fmt.Errorf("request timeout: %w", errcode.Network(err))
 		}
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode == http.StatusTooManyRequests {
-		log.Printf("Rate limit detected for email: %s, status: %d", email, resp.StatusCode)
-		// Handle rate limiting with retry
-		return c.handleRateLimit(apiURL, email)
+	// Check status code; rate limiting is retried by the middleware chain
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified {
+		return nil, &errcode.StatusError{Status: resp.StatusCode}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	return c.readLookupResponse(resp, email)
+}
+
+// doLookupRequest issues a lookup GET, sending the cached ETag when one is known
+func (c *APIClient) doLookupRequest(apiURL, email string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.lookupCache != nil {
+		if cached, ok := c.lookupCache.Get(email); ok && cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// readLookupResponse decodes a 200 lookup response or serves a 304 from the cache
+func (c *APIClient) readLookupResponse(resp *http.Response, email string) (*LookupResponse, error) {
+	if resp.StatusCode == http.StatusNotModified {
+		if c.lookupCache != nil {
+			if cached, ok := c.lookupCache.Get(email); ok && cached.Response != nil {
+				return cached.Response, nil
+			}
+		}
+		return nil, fmt.Errorf("API returned 304 for email without cached response")
 	}
 
 	// Decode JSON response
@@ -77,119 +172,300 @@ func (c *APIClient) LookupLead(email string) (*LookupResponse, error) {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	if c.lookupCache != nil {
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			c.lookupCache.Set(email, &CachedLookup{ETag: etag, Response: &lookupResp})
+		}
+	}
+
 	return &lookupResp, nil
 }
 
+// LeadRequest is the payload sent to the create and update endpoints
+type LeadRequest struct {
+	Name    string `json:"name,omitempty"`
+	Email   string `json:"email"`
+	Company string `json:"company,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Status  string `json:"status,omitempty"`
+	// Territory is sent when the config's territories assigned one
+	Territory string `json:"territory,omitempty"`
+	// Language is sent when it was detected
+	Language string `json:"language,omitempty"`
+
+	ConsentGiven     bool       `json:"consentGiven,omitempty"`
+	ConsentTimestamp *time.Time `json:"consentTimestamp,omitempty"`
+	ConsentSource    string     `json:"consentSource,omitempty"`
+
+	// ClientID is the lead's client-side ID, sent on create so the server
+	// can recognise a re-import of the same lead
+	ClientID string `json:"clientId,omitempty"`
+	// RawSource is the row the lead was read from, by header, sent on
+	// create with WithRawSource
+	RawSource map[string]string `json:"rawSource,omitempty"`
+}
+
+// MergeRequest asks the server to fold a duplicate lead into a primary one
+type MergeRequest struct {
+	PrimaryID   string       `json:"primaryId"`
+	DuplicateID string       `json:"duplicateId"`
+	Lead        *LeadRequest `json:"lead"`
+}
+
+// leadEnvelope is the response body of the write endpoints
+type leadEnvelope struct {
+	Success bool  `json:"success"`
+	Lead    *Lead `json:"lead"`
+}
+
 // CreateLead creates a new lead
 func (c *APIClient) CreateLead(lead *models.Lead) (*models.Lead, error) {
-	// TODO: Implement actual HTTP POST request
-	// For now, return the lead with a generated ID
-	createdLead := &Lead{
-		ID:        "generated-id",
-		Name:      lead.Name,
-		Email:     lead.Email,
-		Company:   lead.Company,
-		Source:    lead.Source,
-		CreatedAt: time.Now(),
+	apiURL := fmt.Sprintf("%s/api/leads/create", c.baseURL)
+	payload := newLeadRequest(lead)
+	payload.ClientID = lead.ID
+	if c.rawSource {
+		payload.RawSource = lead.Raw
 	}
-
-	return &models.Lead{
-		ID:        createdLead.ID,
-		Name:      createdLead.Name,
-		Email:     createdLead.Email,
-		Company:   createdLead.Company,
-		Source:    createdLead.Source,
-		CreatedAt: createdLead.CreatedAt,
-	}, nil
+	return c.writeLead(http.MethodPost, apiURL, payload, http.StatusCreated)
 }
 
-// UpdateLead updates an existing lead
+// UpdateLead updates an existing lead, identified by email
 func (c *APIClient) UpdateLead(lead *models.Lead) (*models.Lead, error) {
-	// TODO: Implement actual HTTP PUT request
-	// For now, return the lead with updated timestamp
-	now := time.Now()
-	updatedLead := &models.Lead{
-		ID:        lead.ID,
-		Name:      lead.Name,
-		Email:     lead.Email,
-		Company:   lead.Company,
-		Source:    lead.Source,
-		CreatedAt: lead.CreatedAt,
-		UpdatedAt: &now,
+	apiURL := fmt.Sprintf("%s/api/leads/update", c.baseURL)
+	return c.writeLead(http.MethodPost, apiURL, newLeadRequest(lead), http.StatusOK)
+}
+
+// PatchLead sends only the changed fields of an existing lead. It requires a
+// server advertising the patch capability.
+func (c *APIClient) PatchLead(id string, changes map[string]string) (*models.Lead, error) {
+	if c.capabilities != nil && !c.capabilities.Patch {
+		return nil, fmt.Errorf("%w: patch", ErrUnsupportedFeature)
 	}
 
-	return updatedLead, nil
+	apiURL := fmt.Sprintf("%s/api/leads/%s", c.baseURL, url.PathEscape(id))
+	return c.writeLead(http.MethodPatch, apiURL, changes, http.StatusOK)
 }
 
-// isTimeoutError checks if the error is a timeout error
-func isTimeoutError(err error) bool {
-	if err == nil {
-		return false
-	}
+// MergeLeads folds the duplicate lead into the primary one, which takes the
+// merged field values; the server removes the duplicate and repoints its
+// history to the primary
+func (c *APIClient) MergeLeads(primaryID, duplicateID string, merged *models.Lead) (*models.Lead, error) {
+	apiURL := fmt.Sprintf("%s/api/leads/merge", c.baseURL)
+	payload := &MergeRequest{PrimaryID: primaryID, DuplicateID: duplicateID, Lead: newLeadRequest(merged)}
+	return c.writeLead(http.MethodPost, apiURL, payload, http.StatusOK)
+}
 
-	// Check for net.Error timeout
-	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-		return true
+// AnonymizeLead asks the server to replace a lead's personal data with
+// placeholders, keeping the record itself for reporting
+func (c *APIClient) AnonymizeLead(id string) error {
+	apiURL := fmt.Sprintf("%s/api/leads/%s/anonymize", c.baseURL, url.PathEscape(id))
+	return c.sendErasure(http.MethodPost, apiURL, http.StatusOK)
+}
+
+// ArchiveLead asks the server to archive a lead: it no longer turns up in
+// lookups or exports, but can be restored
+func (c *APIClient) ArchiveLead(id string) error {
+	apiURL := fmt.Sprintf("%s/api/leads/%s/archive", c.baseURL, url.PathEscape(id))
+	return c.sendErasure(http.MethodPost, apiURL, http.StatusOK)
+}
+
+// DeleteLead permanently deletes a lead
+func (c *APIClient) DeleteLead(id string) error {
+	apiURL := fmt.Sprintf("%s/api/leads/%s", c.baseURL, url.PathEscape(id))
+	return c.sendErasure(http.MethodDelete, apiURL, http.StatusNoContent)
+}
+
+// AddNote adds a note to a lead's activity
+func (c *APIClient) AddNote(id string, text string) error {
+	apiURL := fmt.Sprintf("%s/api/leads/%s/notes", c.baseURL, url.PathEscape(id))
+	body, err := json.Marshal(noteRequest{Text: text})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	// Check for context timeout
-	if strings.Contains(err.Error(), "timeout") {
-		return true
+	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req, http.StatusCreated)
+}
 
-	return false
+// noteRequest is the body of a note added to a lead
+type noteRequest struct {
+	Text string `json:"text"`
 }
 
-// handleRateLimit handles 429 responses with exponential backoff retry
-func (c *APIClient) handleRateLimit(apiURL, email string) (*LookupResponse, error) {
-	maxRetries := 3
-	baseDelay := 100 * time.Millisecond
+// AttachToLead attaches a file to a lead, uploading it as multipart form
+// data. An attachment without data sends its URL for the server to fetch.
+func (c *APIClient) AttachToLead(id string, attachment *models.Attachment) error {
+	apiURL := fmt.Sprintf("%s/api/leads/%s/attachments", c.baseURL, url.PathEscape(id))
 
-	log.Printf("Starting retry with exponential backoff for email: %s, maxRetries: %d, baseDelay: %v", email, maxRetries, baseDelay)
+	var body bytes.Buffer
+	contentType := "application/json"
+	if attachment.Data == nil {
+		payload := attachmentURLRequest{Name: attachment.Name, URL: attachment.URL, ContentType: attachment.ContentType}
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	} else {
+		writer := multipart.NewWriter(&body)
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(attachment.Name)))
+		header.Set("Content-Type", attachment.ContentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		part.Write(attachment.Data)
+		writer.Close()
+		contentType = writer.FormDataContentType()
+	}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Calculate exponential backoff delay
-		delay := baseDelay * time.Duration(1<<uint(attempt)) // 100ms, 200ms, 400ms
+	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.send(req, http.StatusCreated)
+}
 
-		log.Printf("Retry attempt %d/%d for email: %s, delay: %v", attempt+1, maxRetries, email, delay)
+// attachmentURLRequest asks the server to fetch an attachment from its URL
+type attachmentURLRequest struct {
+	Name        string `json:"name,omitempty"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType,omitempty"`
+}
 
-		// Wait before retry
-		time.Sleep(delay)
+// quoteEscaper escapes a file name for a Content-Disposition header
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
 
-		// Make retry request
-		resp, err := c.httpClient.Get(apiURL)
-		if err != nil {
-			log.Printf("Retry attempt %d failed for email: %s, error: %v", attempt+1, email, err)
-			// If it's the last attempt, return the error
-			if attempt == maxRetries-1 {
-				log.Printf("Max retries exceeded for email: %s, error: %v", email, err)
-				return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, err)
-			}
-			continue
-		}
-		defer resp.Body.Close()
-
-		// Check if we got a successful response
-		if resp.StatusCode == http.StatusOK {
-			log.Printf("Retry attempt %d succeeded for email: %s", attempt+1, email)
-			var lookupResp LookupResponse
-			if err := json.NewDecoder(resp.Body).Decode(&lookupResp); err != nil {
-				return nil, fmt.Errorf("failed to decode response: %w", err)
-			}
-			return &lookupResp, nil
+// sendErasure sends a body-less erasure or archive request, with an
+// idempotency key so it can be retried, and checks the response status
+func (c *APIClient) sendErasure(method, apiURL string, expectedStatus int) error {
+	req, err := http.NewRequest(method, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(IdempotencyKeyHeader, uuid.NewString())
+	return c.send(req, expectedStatus)
+}
+
+// send sends a request whose response has no body of interest and checks the
+// response status
+func (c *APIClient) send(req *http.Request, expectedStatus int) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if isTimeoutError(err) {
+			return fmt.Errorf("request timeout: %w", errcode.Network(err))
 		}
+		return fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	defer drainAndClose(resp)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrLeadNotFound
+	}
+	if resp.StatusCode != expectedStatus {
+		return &errcode.StatusError{Status: resp.StatusCode}
+	}
 
-		// If still rate limited and not the last attempt, continue retrying
-		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries-1 {
-			log.Printf("Still rate limited on attempt %d for email: %s, status: %d", attempt+1, email, resp.StatusCode)
-			continue
+	return nil
+}
+
+// writeLead sends a JSON write request and decodes the lead from the response
+// envelope. The request carries an idempotency key, so a retry after a
+// dropped connection or gateway error can't create or change the lead twice.
+func (c *APIClient) writeLead(method, apiURL string, payload interface{}, expectedStatus int) (*models.Lead, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequest(method, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, uuid.NewString())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if isTimeoutError(err) {
+			return nil, fmt.Errorf("request timeout: %w", errcode.Network(err))
 		}
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
 
-		// If we get here, it's either the last attempt or a different error
-		log.Printf("API returned error after %d retries for email: %s, status: %d", attempt+1, email, resp.StatusCode)
-		return nil, fmt.Errorf("API returned status %d after %d retries", resp.StatusCode, attempt+1)
+	if resp.StatusCode != expectedStatus {
+		return nil, &errcode.StatusError{Status: resp.StatusCode}
 	}
 
-	log.Printf("Max retries exceeded for rate limiting for email: %s", email)
-	return nil, fmt.Errorf("max retries exceeded for rate limiting")
+	var envelope leadEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if envelope.Lead == nil {
+		return nil, fmt.Errorf("API response did not include a lead")
+	}
+
+	return envelope.Lead.toModel(), nil
+}
+
+// newLeadRequest builds the write payload for a lead
+func newLeadRequest(lead *models.Lead) *LeadRequest {
+	return &LeadRequest{
+		Name:    lead.Name,
+		Email:   lead.Email,
+		Company: lead.Company,
+		Source:  lead.Source,
+		Status:  lead.Status,
+
+		Territory: lead.Territory,
+		Language:  lead.Language,
+
+		ConsentGiven:     lead.ConsentGiven,
+		ConsentTimestamp: lead.ConsentTimestamp,
+		ConsentSource:    lead.ConsentSource,
+	}
+}
+
+// toModel converts an API lead to the domain model
+func (l *Lead) toModel() *models.Lead {
+	return &models.Lead{
+		ID:        l.ID,
+		Name:      l.Name,
+		Email:     l.Email,
+		Company:   l.Company,
+		Source:    l.Source,
+		Status:    l.Status,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+		Territory: l.Territory,
+		Language:  l.Language,
+
+		ConsentGiven:     l.ConsentGiven,
+		ConsentTimestamp: l.ConsentTimestamp,
+		ConsentSource:    l.ConsentSource,
+	}
+}
+
+// isTimeoutError checks if the error is a timeout error
+func isTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Check for net.Error timeout
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+
+	// Check for context timeout
+	if strings.Contains(err.Error(), "timeout") {
+		return true
+	}
+
+	return false
 }
@@ -1,16 +1,44 @@
 package api
 
 import (
+	"code/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// newRecordedClient returns a client backed by the named fixture. Set
+// API_RECORD=1 to re-record fixtures against a live mock server on localhost:3030.
+func newRecordedClient(t *testing.T, fixture string) *APIClient {
+	t.Helper()
+
+	mode := ModeReplay
+	if os.Getenv("API_RECORD") == "1" {
+		mode = ModeRecord
+	}
+
+	recorder, err := NewRecorder("../../testdata/fixtures/"+fixture+".json", mode, nil)
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := recorder.Stop(); err != nil {
+			t.Errorf("failed to save fixture: %v", err)
+		}
+	})
+
+	return NewAPIClient("http://localhost:3030", WithTransport(recorder))
+}
+
 func TestAPIClient_LookupLead(t *testing.T) {
 	t.Run("successfully looks up existing lead", func(t *testing.T) {
 		// Arrange
-		client := NewAPIClient("http://localhost:3030")
+		client := newRecordedClient(t, "lookup_existing_lead")
 		email := "alice@example.com"
 
 		// Act
@@ -49,7 +77,7 @@ func TestAPIClient_LookupLead(t *testing.T) {
 
 	t.Run("handles API rate limiting (429) with retry", func(t *testing.T) {
 		// Arrange
-		client := NewAPIClient("http://localhost:3030")
+		client := newRecordedClient(t, "lookup_rate_limited")
 		email := "test@example.com"
 
 		// Act
@@ -70,3 +98,262 @@ func TestAPIClient_LookupLead(t *testing.T) {
 		}
 	})
 }
+
+func TestAPIClient_Erasure(t *testing.T) {
+	t.Run("anonymizes a lead by ID", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/leads/42/anonymize", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.AnonymizeLead("42")
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("archives a lead by ID", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/leads/42/archive", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.ArchiveLead("42")
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("deletes a lead by ID", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			assert.Equal(t, "/api/leads/42", r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.DeleteLead("42")
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports unknown leads", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.DeleteLead("42")
+
+		// Assert
+		assert.ErrorIs(t, err, ErrLeadNotFound)
+	})
+}
+
+func TestAPIClient_AddNote(t *testing.T) {
+	t.Run("posts the note to the lead's notes", func(t *testing.T) {
+		// Arrange
+		var body map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/leads/42/notes", r.URL.Path)
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.AddNote("42", "Imported from conference-2024.csv run abc123")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"text": "Imported from conference-2024.csv run abc123"}, body)
+	})
+}
+
+func TestAPIClient_AttachToLead(t *testing.T) {
+	t.Run("uploads the file as multipart form data", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/leads/42/attachments", r.URL.Path)
+			file, header, err := r.FormFile("file")
+			assert.NoError(t, err)
+			defer file.Close()
+			assert.Equal(t, "jane.png", header.Filename)
+			assert.Equal(t, "image/png", header.Header.Get("Content-Type"))
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.AttachToLead("42", &models.Attachment{Name: "jane.png", ContentType: "image/png", Data: []byte("\x89PNG")})
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("sends the URL of an attachment without data", func(t *testing.T) {
+		// Arrange
+		var body map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.AttachToLead("42", &models.Attachment{Name: "jane.jpg", URL: "https://media.example.com/jane.jpg"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"name": "jane.jpg", "url": "https://media.example.com/jane.jpg"}, body)
+	})
+}
+
+func TestAPIClient_WriteLead(t *testing.T) {
+	t.Run("creates lead via POST and returns the stored lead", func(t *testing.T) {
+		// Arrange
+		var received LeadRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/leads/create", r.URL.Path)
+			json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"success":true,"lead":{"id":"42","name":"Jane Smith","email":"jane@techfirm.com","company":"Tech Firm","source":"LinkedIn"}}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+		lead := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+
+		// Act
+		created, err := client.CreateLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@techfirm.com", received.Email)
+		assert.Equal(t, lead.ID, received.ClientID)
+		assert.Equal(t, "42", created.ID)
+		assert.Equal(t, "Tech Firm", created.Company)
+	})
+
+	t.Run("sends the source row on create with WithRawSource", func(t *testing.T) {
+		// Arrange
+		var received []map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			received = append(received, body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"success":true,"lead":{"id":"42","email":"jane@techfirm.com"}}`))
+		}))
+		defer server.Close()
+
+		lead := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+		lead.Raw = map[string]string{"Full Name": "Jane Smith", "E-Mail": "jane@techfirm.com", "Notes": "met at booth 4"}
+
+		// Act
+		_, errWith := NewAPIClient(server.URL, WithRawSource()).CreateLead(lead)
+		_, errWithout := NewAPIClient(server.URL).CreateLead(lead)
+
+		// Assert
+		assert.NoError(t, errWith)
+		assert.NoError(t, errWithout)
+		assert.Equal(t, map[string]interface{}{"Full Name": "Jane Smith", "E-Mail": "jane@techfirm.com", "Notes": "met at booth 4"}, received[0]["rawSource"])
+		assert.NotContains(t, received[1], "rawSource")
+	})
+
+	t.Run("returns error when update is rejected", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/leads/update", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+		lead := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+
+		// Act
+		updated, err := client.UpdateLead(lead)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, updated)
+		assert.Contains(t, err.Error(), "status 404")
+	})
+
+	t.Run("merges a duplicate into the primary lead", func(t *testing.T) {
+		// Arrange
+		var received MergeRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/leads/merge", r.URL.Path)
+			json.NewDecoder(r.Body).Decode(&received)
+			w.Write([]byte(`{"success":true,"lead":{"id":"7","name":"Jane Smith","email":"jane@techfirm.com","company":"Tech Firm"}}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+		merged := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+
+		// Act
+		lead, err := client.MergeLeads("7", "9", merged)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "7", received.PrimaryID)
+		assert.Equal(t, "9", received.DuplicateID)
+		assert.Equal(t, "Tech Firm", received.Lead.Company)
+		assert.Equal(t, "7", lead.ID)
+	})
+
+	t.Run("patches only changed fields by lead ID", func(t *testing.T) {
+		// Arrange
+		var received map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.Equal(t, "/api/leads/7", r.URL.Path)
+			json.NewDecoder(r.Body).Decode(&received)
+			w.Write([]byte(`{"success":true,"lead":{"id":"7","name":"New Name","email":"a@example.com"}}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		updated, err := client.PatchLead("7", map[string]string{"name": "New Name"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"name": "New Name"}, received)
+		assert.Equal(t, "New Name", updated.Name)
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Compression is when request bodies are sent gzip-compressed
+type Compression string
+
+// Compression modes
+const (
+	// CompressionAuto compresses large request bodies when the server
+	// advertises the gzip capability; the default
+	CompressionAuto Compression = "auto"
+	// CompressionAlways compresses large request bodies whatever the server
+	// advertises
+	CompressionAlways Compression = "always"
+	// CompressionOff sends and asks for uncompressed bodies
+	CompressionOff Compression = "off"
+)
+
+// minCompressSize is the smallest request body worth compressing
+const minCompressSize = 1 << 10
+
+// CompressionModes lists the modes ParseCompression accepts
+func CompressionModes() []string {
+	return []string{string(CompressionAuto), string(CompressionAlways), string(CompressionOff)}
+}
+
+// ParseCompression parses a compression mode; an empty value means
+// CompressionAuto
+func ParseCompression(value string) (Compression, error) {
+	switch mode := Compression(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return CompressionAuto, nil
+	case CompressionAuto, CompressionAlways, CompressionOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown compression %q (allowed: %s)", value, strings.Join(CompressionModes(), ", "))
+}
+
+// WithCompression sets when the client compresses request bodies. Responses
+// are asked for gzip-compressed unless compression is off.
+func WithCompression(mode Compression) ClientOption {
+	return func(c *APIClient) {
+		c.compression = mode
+	}
+}
+
+// compress gzips JSON request bodies of minCompressSize or more when the
+// compression mode allows it. A server that answers a compressed body with
+// 415 gets it again uncompressed, and no compressed bodies after that unless
+// compression is always on. Compressed responses are negotiated and
+// decompressed by the transport.
+func (c *APIClient) compress(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if c.compression == CompressionOff {
+			req = req.Clone(req.Context())
+			req.Header.Set("Accept-Encoding", "identity")
+			return next.RoundTrip(req)
+		}
+		if !c.compressesRequests() || !compressible(req) {
+			return next.RoundTrip(req)
+		}
+
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next.RoundTrip(gzipRequest(req, body))
+		if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType || c.compression == CompressionAlways {
+			return resp, err
+		}
+
+		drainAndClose(resp)
+		c.gzipRefused.Store(true)
+		plain := req.Clone(req.Context())
+		plain.Body = io.NopCloser(bytes.NewReader(body))
+		return next.RoundTrip(plain)
+	})
+}
+
+// compressesRequests reports whether large request bodies are compressed
+func (c *APIClient) compressesRequests() bool {
+	switch c.compression {
+	case CompressionAlways:
+		return true
+	case CompressionAuto:
+		return c.capabilities != nil && c.capabilities.Gzip && !c.gzipRefused.Load()
+	}
+	return false
+}
+
+// compressible reports whether req has a JSON body big enough to be worth
+// compressing; uploads such as photos are compressed already
+func compressible(req *http.Request) bool {
+	return req.ContentLength >= minCompressSize && req.Header.Get("Content-Encoding") == "" &&
+		strings.HasPrefix(req.Header.Get("Content-Type"), "application/json")
+}
+
+// gzipRequest returns a copy of req sending body gzip-compressed
+func gzipRequest(req *http.Request, body []byte) *http.Request {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(body)
+	writer.Close()
+
+	gzipped := req.Clone(req.Context())
+	gzipped.Header.Set("Content-Encoding", "gzip")
+	gzipped.ContentLength = int64(compressed.Len())
+	gzipped.Body = io.NopCloser(bytes.NewReader(compressed.Bytes()))
+	gzipped.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed.Bytes())), nil
+	}
+	return gzipped
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"code/internal/models"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCompressionServer advertises capabilities, accepts gzip request bodies
+// unless refuseGzip, and records the encoding of each create it received
+func newCompressionServer(t *testing.T, capabilities string, refuseGzip bool) (*httptest.Server, *[]string) {
+	var encodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/version" {
+			w.Write([]byte(`{"version":"2.1.0","capabilities":[` + capabilities + `]}`))
+			return
+		}
+
+		encoding := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		if encoding == "gzip" && refuseGzip {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		var body io.Reader = r.Body
+		if encoding == "gzip" {
+			reader, err := gzip.NewReader(r.Body)
+			assert.NoError(t, err)
+			body = reader
+		}
+		data, _ := io.ReadAll(body)
+		assert.Contains(t, string(data), `"email":"jane@example.com"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"lead":{"id":"lead-1","email":"jane@example.com"}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &encodings
+}
+
+// largeLead has a body worth compressing
+func largeLead() *models.Lead {
+	return models.NewLead("Jane Roe", "jane@example.com", strings.Repeat("Acme ", 400), "Website")
+}
+
+func TestWithCompression(t *testing.T) {
+	t.Run("compresses large bodies for servers advertising gzip", func(t *testing.T) {
+		// Arrange
+		server, encodings := newCompressionServer(t, `"gzip"`, false)
+		client := NewAPIClient(server.URL)
+		client.DetectCapabilities()
+
+		// Act
+		_, err := client.CreateLead(largeLead())
+		_, smallErr := client.CreateLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, smallErr)
+		assert.Equal(t, []string{"gzip", ""}, *encodings)
+	})
+
+	t.Run("sends plain bodies to servers that don't advertise gzip", func(t *testing.T) {
+		// Arrange
+		server, encodings := newCompressionServer(t, `"patch"`, false)
+		client := NewAPIClient(server.URL)
+		client.DetectCapabilities()
+
+		// Act
+		_, err := client.CreateLead(largeLead())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{""}, *encodings)
+	})
+
+	t.Run("falls back to plain bodies when the server refuses gzip", func(t *testing.T) {
+		// Arrange
+		server, encodings := newCompressionServer(t, `"gzip"`, true)
+		client := NewAPIClient(server.URL)
+		client.DetectCapabilities()
+
+		// Act
+		_, err := client.CreateLead(largeLead())
+		_, againErr := client.CreateLead(largeLead())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, againErr)
+		assert.Equal(t, []string{"gzip", "", ""}, *encodings)
+	})
+
+	t.Run("compresses whatever the server advertises when always on", func(t *testing.T) {
+		// Arrange
+		server, encodings := newCompressionServer(t, ``, false)
+		client := NewAPIClient(server.URL, WithCompression(CompressionAlways))
+
+		// Act
+		_, err := client.CreateLead(largeLead())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"gzip"}, *encodings)
+	})
+}
+
+func TestParseCompression(t *testing.T) {
+	t.Run("defaults to auto", func(t *testing.T) {
+		// Act
+		mode, err := ParseCompression("")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, CompressionAuto, mode)
+	})
+
+	t.Run("rejects unknown modes", func(t *testing.T) {
+		// Act
+		_, err := ParseCompression("brotli")
+
+		// Assert
+		assert.ErrorContains(t, err, `unknown compression "brotli"`)
+	})
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted replaces secrets and personal data in debug traces
+const Redacted = "[REDACTED]"
+
+// maxTraceBody caps how much of a body a debug trace shows
+const maxTraceBody = 64 << 10
+
+// secretHeaders are always redacted; so is any header whose name contains
+// one of secretWords
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// secretWords mark header, parameter and field names holding credentials
+var secretWords = []string{"token", "secret", "password", "apikey", "api-key", "api_key", "signature", "credential", "auth"}
+
+// piiFields are parameter and field names holding personal data, compared
+// in lower case without separators
+var piiFields = map[string]bool{
+	"email": true, "emails": true, "name": true, "firstname": true, "lastname": true, "fullname": true,
+	"phone": true, "phones": true, "mobile": true, "address": true, "street": true, "postcode": true,
+	"zip": true, "ip": true, "ipaddress": true, "birthdate": true, "dateofbirth": true,
+	"emailaddress": true, "fname": true, "lname": true,
+}
+
+// piiObjects are field names, compared like piiFields, of objects all of
+// whose values are personal data, such as Mailchimp's merge_fields, which are
+// named by each audience
+var piiObjects = map[string]bool{"mergefields": true}
+
+// emailPattern finds email addresses wherever they appear, e.g. in a search
+// term or an error message
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// WithDebug writes a trace of every request and response to w, with
+// credentials and personal data redacted. The trace is taken below the
+// middleware chain, so it shows the headers middleware added and every retry;
+// bodies are shown as they are before compression.
+func WithDebug(w io.Writer) ClientOption {
+	return func(c *APIClient) {
+		c.debug = DebugMiddleware(w)
+	}
+}
+
+// DebugMiddleware writes a redacted trace of each round trip to w. Traces of
+// concurrent requests are written whole, one after another.
+func DebugMiddleware(w io.Writer) Middleware {
+	var mu sync.Mutex
+	return func(next http.RoundTripper) http.RoundTripper {
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var trace bytes.Buffer
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&trace, "--> %s %s\n", req.Method, RedactURL(req.URL))
+			writeTraceHeaders(&trace, req.Header)
+			writeTraceBody(&trace, req.Header.Get("Content-Type"), body)
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				fmt.Fprintf(&trace, "<-- %s %s failed after %v: %s\n\n", req.Method, RedactURL(req.URL), time.Since(start).Round(time.Millisecond), RedactText(err.Error()))
+			} else {
+				// Only what the trace shows is read ahead; the caller reads the rest
+				data, readErr := io.ReadAll(io.LimitReader(resp.Body, maxTraceBody+1))
+				var rest io.Reader = resp.Body
+				if readErr != nil {
+					rest = errorReader{readErr}
+				}
+				resp.Body = &replayedBody{Reader: io.MultiReader(bytes.NewReader(data), rest), body: resp.Body}
+				fmt.Fprintf(&trace, "<-- %s (%v)\n", resp.Status, time.Since(start).Round(time.Millisecond))
+				writeTraceHeaders(&trace, resp.Header)
+				writeTraceBody(&trace, resp.Header.Get("Content-Type"), string(data))
+				if readErr != nil {
+					fmt.Fprintf(&trace, "(body cut short: %s)\n\n", RedactText(readErr.Error()))
+				}
+			}
+
+			mu.Lock()
+			w.Write(trace.Bytes())
+			mu.Unlock()
+			return resp, err
+		})
+	}
+}
+
+// RedactURL returns u as text with credentials and personal data in its
+// query redacted, as well as any user info and email addresses in its path
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	if redacted.RawQuery != "" {
+		redacted.RawQuery = redactQuery(redacted.Query())
+	}
+	text := RedactText(redacted.String())
+	if u.User != nil {
+		text = strings.Replace(text, "//", "//"+Redacted+"@", 1)
+	}
+	return text
+}
+
+// RedactHeader returns the value to show for a header
+func RedactHeader(name, value string) string {
+	if secretHeaders[http.CanonicalHeaderKey(name)] || isSecretName(name) {
+		return Redacted
+	}
+	return RedactText(value)
+}
+
+// RedactBody returns body with credentials and personal data redacted:
+// fields by name in JSON and form bodies, and email addresses everywhere
+func RedactBody(contentType, body string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		var value interface{}
+		if err := json.Unmarshal([]byte(body), &value); err == nil {
+			if data, err := json.Marshal(redactJSON(value)); err == nil {
+				return string(data)
+			}
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(body); err == nil {
+			return redactQuery(values)
+		}
+	}
+	return RedactText(body)
+}
+
+// RedactText redacts the email addresses in text
+func RedactText(text string) string {
+	return emailPattern.ReplaceAllString(text, Redacted)
+}
+
+func writeTraceHeaders(w io.Writer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, RedactHeader(name, value))
+		}
+	}
+}
+
+func writeTraceBody(w io.Writer, contentType, body string) {
+	if body == "" {
+		fmt.Fprintln(w)
+		return
+	}
+	if len(body) > maxTraceBody {
+		// Part of a JSON or form body can't be redacted by field, so it isn't
+		// shown at all
+		if mediaType, _, _ := mime.ParseMediaType(contentType); strings.HasSuffix(mediaType, "json") || mediaType == "application/x-www-form-urlencoded" {
+			fmt.Fprintf(w, "\n(%s body over %d bytes not shown)\n\n", mediaType, maxTraceBody)
+			return
+		}
+		fmt.Fprintf(w, "\n%s\n(body cut at %d bytes)\n\n", RedactText(body[:maxTraceBody]), maxTraceBody)
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n\n", RedactBody(contentType, body))
+}
+
+// replayedBody is a response body whose start the trace read ahead
+type replayedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *replayedBody) Close() error {
+	return b.body.Close()
+}
+
+// errorReader fails every read with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// redactQuery encodes values with credentials and personal data redacted,
+// leaving the redaction marker readable
+func redactQuery(values url.Values) string {
+	redacted := make(url.Values, len(values))
+	for name, list := range values {
+		for _, value := range list {
+			if isSecretName(name) || isPIIName(name) {
+				value = Redacted
+			}
+			redacted.Add(name, RedactText(value))
+		}
+	}
+	return strings.ReplaceAll(redacted.Encode(), url.QueryEscape(Redacted), Redacted)
+}
+
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			object, isObject := field.(map[string]interface{})
+			switch {
+			case isSecretName(key) || isPIIName(key):
+				v[key] = Redacted
+			case isObject && piiObjects[normalizeName(key)]:
+				for name := range object {
+					object[name] = Redacted
+				}
+			default:
+				v[key] = redactJSON(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	case string:
+		return RedactText(v)
+	}
+	return value
+}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range secretWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPIIName(name string) bool {
+	return piiFields[normalizeName(name)]
+}
+
+// normalizeName lowercases a field name and drops its separators
+func normalizeName(name string) string {
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(name))
+}
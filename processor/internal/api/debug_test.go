@@ -0,0 +1,90 @@
+package api
+
+import (
+	"code/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDebug(t *testing.T) {
+	t.Run("traces requests and responses without secrets or personal data", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"lead":{"id":"lead-1","name":"Jane Roe","email":"jane@example.com","company":"Acme"}}`))
+		}))
+		defer server.Close()
+		var trace strings.Builder
+		client := NewAPIClient(server.URL, WithMiddleware(BearerAuthMiddleware("s3cret")), WithDebug(&trace))
+
+		// Act
+		created, err := client.CreateLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane Roe", created.Name)
+		assert.Contains(t, trace.String(), "--> POST "+server.URL+"/api/leads")
+		assert.Contains(t, trace.String(), "Authorization: [REDACTED]")
+		assert.Contains(t, trace.String(), "<-- 201 Created")
+		assert.Contains(t, trace.String(), `"company":"Acme"`)
+		assert.NotContains(t, trace.String(), "s3cret")
+		assert.NotContains(t, trace.String(), "Jane")
+		assert.NotContains(t, trace.String(), "jane@example.com")
+	})
+}
+
+func TestRedactURL(t *testing.T) {
+	t.Run("redacts keys, personal data and emails in the query", func(t *testing.T) {
+		// Arrange
+		u, _ := url.Parse("https://user:pw@crm.example.com/v1/persons/search?api_token=abc&email=jane%40example.com&term=john%40example.com&limit=5")
+
+		// Act
+		redacted := RedactURL(u)
+
+		// Assert
+		assert.Equal(t, "https://[REDACTED]@crm.example.com/v1/persons/search?api_token=[REDACTED]&email=[REDACTED]&limit=5&term=[REDACTED]", redacted)
+	})
+}
+
+func TestRedactBody(t *testing.T) {
+	t.Run("redacts form credentials", func(t *testing.T) {
+		// Act
+		redacted := RedactBody("application/x-www-form-urlencoded", "grant_type=refresh_token&refresh_token=abc&client_secret=xyz")
+
+		// Assert
+		assert.Equal(t, "client_secret=[REDACTED]&grant_type=refresh_token&refresh_token=[REDACTED]", redacted)
+	})
+
+	t.Run("redacts nested JSON fields by name", func(t *testing.T) {
+		// Act
+		redacted := RedactBody("application/json; charset=utf-8", `{"data":[{"First_Name":"Jane","Company":"Acme","Phone":"555"}]}`)
+
+		// Assert
+		assert.Equal(t, `{"data":[{"Company":"Acme","First_Name":"[REDACTED]","Phone":"[REDACTED]"}]}`, redacted)
+	})
+
+	t.Run("redacts a Mailchimp member's merge fields", func(t *testing.T) {
+		// Act
+		redacted := RedactBody("application/json",
+			`{"email_address":"jane@example.com","status_if_new":"pending","merge_fields":{"FNAME":"Jane","LNAME":"Roe","PHONE":"555","COMPANY":"Acme"},"tags":["Website"]}`)
+
+		// Assert
+		assert.Equal(t, `{"email_address":"[REDACTED]","merge_fields":{"COMPANY":"[REDACTED]","FNAME":"[REDACTED]","LNAME":"[REDACTED]","PHONE":"[REDACTED]"},"status_if_new":"pending","tags":["Website"]}`, redacted)
+		assert.NotContains(t, redacted, "Jane")
+		assert.NotContains(t, redacted, "Roe")
+	})
+
+	t.Run("redacts emails in other bodies", func(t *testing.T) {
+		// Act
+		redacted := RedactBody("text/plain", "duplicate lead jane@example.com")
+
+		// Assert
+		assert.Equal(t, "duplicate lead [REDACTED]", redacted)
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// unixScheme marks an API URL that is a unix domain socket, e.g.
+// unix:///run/leads/api.sock
+const unixScheme = "unix://"
+
+// socketBaseURL addresses requests sent over a unix domain socket; the host
+// only fills the Host header
+const socketBaseURL = "http://localhost"
+
+// DialContextFunc opens the connections a client's requests go over, like
+// net.Dialer.DialContext
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialContext opens the client's connections with dial instead of
+// dialling the API's host, e.g. to go through a service mesh sidecar
+func WithDialContext(dial DialContextFunc) ClientOption {
+	return func(c *APIClient) {
+		c.dialContext = dial
+	}
+}
+
+// BaseTransport returns the transport requests to apiURL go over and the HTTP
+// base URL to address them with. A unix:// URL dials its socket for every
+// request; other URLs are returned as they are with http.DefaultTransport.
+func BaseTransport(apiURL string) (http.RoundTripper, string) {
+	socket, ok := strings.CutPrefix(apiURL, unixScheme)
+	if !ok {
+		return http.DefaultTransport, apiURL
+	}
+	transport := dialTransport(DialUnix(socket))
+	// A proxy would be dialled over the socket instead of the API
+	transport.Proxy = nil
+	return transport, socketBaseURL
+}
+
+// DialUnix returns a dialer connecting to the unix domain socket at path,
+// whatever address it is asked for
+func DialUnix(path string) DialContextFunc {
+	var dialer net.Dialer
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+}
+
+// dialTransport returns a transport like http.DefaultTransport that opens
+// its connections with dial
+func dialTransport(dial DialContextFunc) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dial
+	return transport
+}
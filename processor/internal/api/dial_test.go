@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSocketServer serves handler on a unix domain socket and returns its path
+func newSocketServer(t *testing.T, handler http.Handler) string {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return path
+}
+
+func TestNewAPIClient_UnixSocket(t *testing.T) {
+	t.Run("sends requests over a unix:// socket", func(t *testing.T) {
+		// Arrange
+		path := newSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/leads/lookup", r.URL.Path)
+			w.Write([]byte(`{"found":true,"lead":{"id":"lead-1","email":"jane@example.com"}}`))
+		}))
+		client := NewAPIClient("unix://" + path)
+
+		// Act
+		resp, err := client.LookupLead("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, resp.Found)
+		assert.Equal(t, "http://localhost", client.BaseURL())
+	})
+}
+
+func TestWithDialContext(t *testing.T) {
+	t.Run("opens connections with the given dialer", func(t *testing.T) {
+		// Arrange
+		path := newSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"found":false}`))
+		}))
+		var dialed []string
+		dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return DialUnix(path)(ctx, network, addr)
+		}
+		client := NewAPIClient("http://leads.mesh:8080", WithDialContext(dial))
+
+		// Act
+		resp, err := client.LookupLead("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, resp.Found)
+		assert.Equal(t, []string{"leads.mesh:8080"}, dialed)
+	})
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware wraps an http.RoundTripper with additional behaviour such as
+// auth, retries, logging or metrics. Middlewares are applied in order, so the
+// first middleware in a chain sees the request first and the response last.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain composes middlewares around base, with the first middleware outermost
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	rt := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+
+	return rt
+}
+
+// DefaultMiddlewares returns the chain every client starts with
+func DefaultMiddlewares() []Middleware {
+	return []Middleware{
+		LoggingMiddleware(nil),
+		RetryRateLimitedMiddleware(3, 100*time.Millisecond),
+		RetryTransientMiddleware(3, 100*time.Millisecond),
+	}
+}
+
+// WithTransport sets the base transport the middleware chain wraps, in place
+// of the one that dials the API
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *APIClient) {
+		c.transport = rt
+	}
+}
+
+// WithMiddleware appends middlewares to the client's chain
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(c *APIClient) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// Use appends middlewares to the client's chain after construction
+func (c *APIClient) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+	c.rebuildTransport()
+}
+
+// Middlewares returns a copy of the client's current middleware chain
+func (c *APIClient) Middlewares() []Middleware {
+	return append([]Middleware(nil), c.middlewares...)
+}
+
+// HTTPClient returns the underlying http.Client, including the middleware chain,
+// so other client implementations can share the same transport behaviour
+func (c *APIClient) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// rebuildTransport reassembles the http.Client transport from the chain
+func (c *APIClient) rebuildTransport() {
+	base := c.transport
+	if base == nil {
+		base = c.network
+	}
+	base = c.compress(base)
+	if c.debug != nil {
+		base = c.debug(base)
+	}
+	if c.requestTimeout > 0 {
+		base = timeoutTransport{next: base, timeout: c.requestTimeout}
+	}
+	c.httpClient.Transport = Chain(base, c.middlewares...)
+}
+
+// BearerAuthMiddleware adds an Authorization header to every request
+func BearerAuthMiddleware(token string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+token)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RunLabelsHeader carries a run's labels on its API requests, e.g.
+// campaign=q3-webinar,team=emea
+const RunLabelsHeader = "X-Run-Labels"
+
+// HeaderMiddleware sets a header on every request
+func HeaderMiddleware(name, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set(name, value)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// LoggingMiddleware logs each request with its status and duration.
+// A nil logger uses the standard library logger.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	logf := log.Printf
+	if logger != nil {
+		logf = logger.Printf
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				logf("HTTP %s %s failed after %v: %v", req.Method, req.URL.Path, time.Since(start), err)
+				return nil, err
+			}
+			if resp.StatusCode >= 400 {
+				logf("HTTP %s %s returned %d in %v", req.Method, req.URL.Path, resp.StatusCode, time.Since(start))
+			}
+			return resp, nil
+		})
+	}
+}
+
+// maxRetryAfter caps how long a server's Retry-After can hold a request back
+const maxRetryAfter = time.Minute
+
+// RetryRateLimitedMiddleware retries 429 responses with exponential backoff,
+// waiting longer when the response's Retry-After asks for it. Requests with a
+// body are only retried when the body can be replayed.
+func RetryRateLimitedMiddleware(maxRetries int, baseDelay time.Duration) Middleware {
+	return retryMiddleware("Rate limit", maxRetries, baseDelay, func(req *http.Request, resp *http.Response, err error) bool {
+		return err == nil && resp.StatusCode == http.StatusTooManyRequests
+	})
+}
+
+// retryMiddleware is the retry policy the retry middlewares share: while
+// shouldRetry holds for a request's outcome it is sent again, up to
+// maxRetries times, after an exponential backoff from baseDelay or the wait
+// the response's Retry-After asks for, if longer. Requests with a body are
+// only retried when the body can be replayed. Each retry is marked so the
+// retry budget can charge it.
+func retryMiddleware(reason string, maxRetries int, baseDelay time.Duration, shouldRetry func(req *http.Request, resp *http.Response, err error) bool) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if !shouldRetry(req, resp, err) {
+				return resp, err
+			}
+
+			log.Printf("%s detected for %s %s: %s", reason, req.Method, req.URL.Path, outcome(resp, err))
+			log.Printf("Starting retry with exponential backoff, maxRetries: %d, baseDelay: %v", maxRetries, baseDelay)
+
+			for attempt := 0; attempt < maxRetries; attempt++ {
+				retryReq, rewindErr := rewindRequest(req)
+				if rewindErr != nil {
+					return resp, err
+				}
+
+				// Calculate exponential backoff delay
+				delay := baseDelay * time.Duration(1<<uint(attempt)) // 100ms, 200ms, 400ms
+				if resp != nil {
+					if wait := retryAfter(resp); wait > delay {
+						delay = wait
+					}
+				}
+
+				log.Printf("Retry attempt %d/%d for %s, delay: %v", attempt+1, maxRetries, req.URL.Path, delay)
+
+				drainAndClose(resp)
+				if err := sleepContext(req, delay); err != nil {
+					return nil, err
+				}
+
+				resp, err = next.RoundTrip(markRetry(retryReq, delay))
+				if !shouldRetry(req, resp, err) {
+					if err != nil {
+						log.Printf("Retry attempt %d failed for %s, error: %v", attempt+1, req.URL.Path, err)
+						return nil, fmt.Errorf("failed after %d retries: %w", attempt+1, err)
+					}
+					log.Printf("Retry attempt %d finished for %s, status: %d", attempt+1, req.URL.Path, resp.StatusCode)
+					return resp, nil
+				}
+
+				log.Printf("Still failing on attempt %d for %s: %s", attempt+1, req.URL.Path, outcome(resp, err))
+			}
+
+			log.Printf("Max retries exceeded for %s", req.URL.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, err)
+			}
+			return resp, nil
+		})
+	}
+}
+
+// outcome describes a round trip's result for the retry log
+func outcome(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+// retryAfter returns the wait a response's Retry-After header asks for, in
+// seconds or as an HTTP date, up to maxRetryAfter
+func retryAfter(resp *http.Response) time.Duration {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = time.Until(at)
+	}
+	return min(max(wait, 0), maxRetryAfter)
+}
+
+// MetricsRecorder receives one observation per HTTP round trip
+type MetricsRecorder interface {
+	ObserveRequest(method, path string, status int, duration time.Duration, err error)
+}
+
+// MetricsMiddleware reports every round trip to recorder
+func MetricsMiddleware(recorder MetricsRecorder) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			recorder.ObserveRequest(req.Method, req.URL.Path, status, time.Since(start), err)
+
+			return resp, err
+		})
+	}
+}
+
+// FaultInjectionMiddleware answers a fraction of requests with the given
+// status code without reaching the server, for exercising error handling
+func FaultInjectionMiddleware(rate float64, status int) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if rand.Float64() >= rate {
+				return next.RoundTrip(req)
+			}
+
+			body := fmt.Sprintf(`{"error":"injected fault","status":%d}`, status)
+			return &http.Response{
+				StatusCode: status,
+				Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		})
+	}
+}
+
+// rewindRequest returns a copy of req whose body can be sent again
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retryReq := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return retryReq, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body cannot be replayed")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	retryReq.Body = body
+
+	return retryReq, nil
+}
+
+// sleepContext waits for delay unless the request's context is cancelled first
+func sleepContext(req *http.Request, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// drainAndClose discards the rest of a response body so the connection can be reused
+func drainAndClose(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
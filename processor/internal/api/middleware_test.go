@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMetrics struct {
+	statuses []int
+}
+
+func (m *recordingMetrics) ObserveRequest(method, path string, status int, duration time.Duration, err error) {
+	m.statuses = append(m.statuses, status)
+}
+
+func TestChain(t *testing.T) {
+	t.Run("applies middlewares with the first one outermost", func(t *testing.T) {
+		// Arrange
+		var order []string
+		tag := func(name string) Middleware {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					order = append(order, name)
+					return next.RoundTrip(req)
+				})
+			}
+		}
+		base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			order = append(order, "base")
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+
+		// Act
+		rt := Chain(base, tag("first"), tag("second"))
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		_, err := rt.RoundTrip(req)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second", "base"}, order)
+	})
+}
+
+func TestRetryRateLimitedMiddleware(t *testing.T) {
+	t.Run("retries 429 responses until success", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		result, err := client.LookupLead("test@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, result.Found)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("waits as long as Retry-After asks", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		start := time.Now()
+		_, err := client.LookupLead("test@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("waits out a Retry-After longer than the request timeout", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "6")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		start := time.Now()
+		_, err := client.LookupLead("test@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.GreaterOrEqual(t, time.Since(start), 6*time.Second)
+	})
+
+	t.Run("returns the last 429 once retries are exhausted", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		result, err := client.LookupLead("test@example.com")
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "status 429")
+	})
+}
+
+func TestAPIClient_Use(t *testing.T) {
+	t.Run("user middlewares see auth headers and metrics", func(t *testing.T) {
+		// Arrange
+		var authHeader string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader = r.Header.Get("Authorization")
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		metrics := &recordingMetrics{}
+		client := NewAPIClient(server.URL, WithMiddleware(BearerAuthMiddleware("secret")))
+		client.Use(MetricsMiddleware(metrics))
+
+		// Act
+		_, err := client.LookupLead("test@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer secret", authHeader)
+		assert.Equal(t, []int{http.StatusOK}, metrics.statuses)
+		assert.Len(t, client.Middlewares(), len(DefaultMiddlewares())+2)
+	})
+
+	t.Run("fault injection short-circuits the request", func(t *testing.T) {
+		// Arrange
+		reached := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, WithMiddleware(FaultInjectionMiddleware(1, http.StatusInternalServerError)))
+
+		// Act
+		_, err := client.LookupLead("test@example.com")
+
+		// Assert
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "status 500")
+		assert.False(t, reached)
+	})
+}
@@ -0,0 +1,62 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientWithResponses(t *testing.T) {
+	t.Run("parses lookup responses", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/leads/lookup", r.URL.Path)
+			assert.Equal(t, "alice@example.com", r.URL.Query().Get("email"))
+			w.Write([]byte(`{"found":true,"lead":{"id":"1","name":"Alice Johnson","email":"alice@example.com","company":"Acme Inc","source":"LinkedIn","createdAt":"2024-01-01T00:00:00Z"}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewClientWithResponses(server.URL)
+		assert.NoError(t, err)
+
+		// Act
+		resp, err := client.LookupLeadWithResponse(context.Background(), &LookupLeadParams{Email: "alice@example.com"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.True(t, resp.JSON200.Found)
+		assert.Equal(t, LinkedIn, resp.JSON200.Lead.Source)
+	})
+
+	t.Run("sends create body and parses error responses", func(t *testing.T) {
+		// Arrange
+		var received LeadInput
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/api/leads/create", r.URL.Path)
+			json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"Lead already exists"}`))
+		}))
+		defer server.Close()
+
+		client, err := NewClientWithResponses(server.URL)
+		assert.NoError(t, err)
+		name := "Alice Johnson"
+
+		// Act
+		resp, err := client.CreateLeadWithResponse(context.Background(), LeadInput{Email: "alice@example.com", Name: &name})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "alice@example.com", received.Email)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode())
+		assert.Nil(t, resp.JSON201)
+		assert.Equal(t, "Lead already exists", *resp.JSON409.Error)
+	})
+}
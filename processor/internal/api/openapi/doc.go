@@ -0,0 +1,7 @@
+// Package openapi is the Lead API client generated from api/openapi.yaml by
+// the generator in gen/. It has a type for each of the spec's schemas, and a
+// Client and ClientWithResponses method for each operation; run go generate
+// after changing the spec, as the tests fail while client.gen.go is stale.
+package openapi
+
+//go:generate go run ./gen -spec ../../../api/openapi.yaml -out client.gen.go
@@ -0,0 +1,703 @@
+// Command gen writes the Lead API client from api/openapi.yaml. It is run by
+// go generate in the openapi package and supports the parts of OpenAPI 3 the
+// spec uses: string enums, objects, arrays and string maps as schemas, path
+// and query parameters, JSON request bodies and JSON responses by status.
+// Anything else fails generation rather than being skipped.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+type document struct {
+	Paths      map[string]map[string]*operation `yaml:"paths"`
+	Components struct {
+		Parameters map[string]*parameter `yaml:"parameters"`
+		Responses  map[string]*response  `yaml:"responses"`
+		Schemas    map[string]*schema    `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Description          string             `yaml:"description"`
+	Enum                 []string           `yaml:"enum"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*schema `yaml:"properties"`
+	Items                *schema            `yaml:"items"`
+	AdditionalProperties *schema            `yaml:"additionalProperties"`
+}
+
+type parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Description string  `yaml:"description"`
+	Required    bool    `yaml:"required"`
+	Schema      *schema `yaml:"schema"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+type response struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]mediaType `yaml:"content"`
+}
+
+type operation struct {
+	OperationID string      `yaml:"operationId"`
+	Summary     string      `yaml:"summary"`
+	Parameters  []parameter `yaml:"parameters"`
+	RequestBody *struct {
+		Content map[string]mediaType `yaml:"content"`
+	} `yaml:"requestBody"`
+	Responses map[string]*response `yaml:"responses"`
+
+	method, path string
+}
+
+const jsonContent = "application/json"
+
+func main() {
+	specPath := flag.String("spec", "api/openapi.yaml", "OpenAPI spec to generate the client from")
+	out := flag.String("out", "client.gen.go", "file to write the client to")
+	pkg := flag.String("package", "openapi", "package of the client")
+	flag.Parse()
+
+	spec, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := generate(spec, *pkg)
+	if err != nil {
+		log.Fatalf("%s: %v", *specPath, err)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generator writes the client source, noting the imports it uses
+type generator struct {
+	doc     document
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+// generate returns the gofmt'ed client for spec, in package pkg
+func generate(spec []byte, pkg string) ([]byte, error) {
+	g := &generator{imports: map[string]bool{"context": true, "net/http": true, "net/url": true, "strings": true}}
+	if err := yaml.Unmarshal(spec, &g.doc); err != nil {
+		return nil, err
+	}
+	if err := g.schemas(); err != nil {
+		return nil, err
+	}
+	operations, err := g.operations()
+	if err != nil {
+		return nil, err
+	}
+	g.buf.WriteString(runtime)
+	for _, op := range operations {
+		if err := g.operation(op); err != nil {
+			return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+		}
+	}
+
+	var imports []string
+	for path := range g.imports {
+		imports = append(imports, strconv.Quote(path))
+	}
+	sort.Strings(imports)
+	source := fmt.Sprintf("// Code generated by gen from api/openapi.yaml. DO NOT EDIT.\n\npackage %s\n\nimport (\n%s\n)\n%s",
+		pkg, strings.Join(imports, "\n"), g.buf.String())
+	formatted, err := format.Source([]byte(source))
+	if err != nil {
+		return nil, fmt.Errorf("generated source doesn't parse: %w", err)
+	}
+	return formatted, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// schemas writes a type for each schema, by name
+func (g *generator) schemas() error {
+	enumValues := map[string]string{}
+	for _, name := range sortedKeys(g.doc.Components.Schemas) {
+		s := g.doc.Components.Schemas[name]
+		g.printf("\n")
+		g.comment(fmt.Sprintf("%s is the %s schema", name, name), s.Description)
+		switch {
+		case s.Type == "string" && len(s.Enum) > 0:
+			g.printf("type %s string\n\n// Values of %s\nconst (\n", name, name)
+			values := append([]string(nil), s.Enum...)
+			sort.Strings(values)
+			for _, value := range values {
+				constant := goName(value)
+				if other, ok := enumValues[constant]; ok {
+					return fmt.Errorf("value %s of %s clashes with one of %s", value, name, other)
+				}
+				enumValues[constant] = name
+				g.printf("%s %s = %q\n", constant, name, value)
+			}
+			g.printf(")\n")
+		case s.Type == "object" && s.AdditionalProperties == nil:
+			g.printf("type %s struct {\n", name)
+			if err := g.fields(s); err != nil {
+				return fmt.Errorf("schema %s: %w", name, err)
+			}
+			g.printf("}\n")
+		default:
+			typ, err := g.goType(s, true)
+			if err != nil {
+				return fmt.Errorf("schema %s: %w", name, err)
+			}
+			g.printf("type %s %s\n", name, typ)
+		}
+	}
+	return nil
+}
+
+// fields writes the fields of an object schema, by JSON name; optional ones
+// are pointers, omitted when nil
+func (g *generator) fields(s *schema) error {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		property := s.Properties[name]
+		typ, err := g.goType(property, required[name])
+		if err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+		if property.Description != "" {
+			g.comment("", property.Description)
+		}
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`\n", goName(name), typ, tag)
+	}
+	return nil
+}
+
+// goType returns the Go type of a schema; an optional one is a pointer
+func (g *generator) goType(s *schema, required bool) (string, error) {
+	var typ string
+	switch {
+	case s.Ref != "":
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			return "", err
+		}
+		if _, ok := g.doc.Components.Schemas[name]; !ok {
+			return "", fmt.Errorf("no schema %s", name)
+		}
+		typ = name
+	case len(s.Enum) > 0:
+		return "", fmt.Errorf("inline enums aren't supported; add a schema for it")
+	case s.Type == "string" && s.Format == "date-time":
+		g.imports["time"] = true
+		typ = "time.Time"
+	case s.Type == "string":
+		typ = "string"
+	case s.Type == "integer":
+		typ = "int"
+	case s.Type == "number":
+		typ = "float64"
+	case s.Type == "boolean":
+		typ = "bool"
+	case s.Type == "array" && s.Items != nil:
+		items, err := g.goType(s.Items, true)
+		if err != nil {
+			return "", err
+		}
+		typ = "[]" + items
+	case s.Type == "object" && s.AdditionalProperties != nil && len(s.Properties) == 0:
+		values, err := g.goType(s.AdditionalProperties, true)
+		if err != nil {
+			return "", err
+		}
+		typ = "map[string]" + values
+	default:
+		return "", fmt.Errorf("unsupported schema of type %q; inline objects need a schema of their own", s.Type)
+	}
+	if !required {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+// operations returns the spec's operations by ID
+func (g *generator) operations() ([]*operation, error) {
+	var operations []*operation
+	for path, item := range g.doc.Paths {
+		for method, op := range item {
+			switch method {
+			case "get", "put", "post", "delete", "patch", "head", "options":
+			default:
+				return nil, fmt.Errorf("%s: unsupported path item field %s", path, method)
+			}
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s has no operationId", strings.ToUpper(method), path)
+			}
+			op.method, op.path = strings.ToUpper(method), path
+			for i, param := range op.Parameters {
+				if param.Ref == "" {
+					continue
+				}
+				name, err := refName(param.Ref, "parameters")
+				if err != nil {
+					return nil, err
+				}
+				ref, ok := g.doc.Components.Parameters[name]
+				if !ok {
+					return nil, fmt.Errorf("%s: no parameter %s", op.OperationID, name)
+				}
+				op.Parameters[i] = *ref
+			}
+			operations = append(operations, op)
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].OperationID < operations[j].OperationID })
+	return operations, nil
+}
+
+// operation writes the request builder, the Client and ClientWithResponses
+// methods, and the response type and parser of an operation
+func (g *generator) operation(op *operation) error {
+	name := goName(op.OperationID)
+	var pathParams, queryParams []parameter
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "path":
+			pathParams = append(pathParams, param)
+		case "query":
+			queryParams = append(queryParams, param)
+		default:
+			return fmt.Errorf("parameter %s: %s parameters aren't supported", param.Name, param.In)
+		}
+	}
+	var bodyType string
+	if op.RequestBody != nil {
+		media, ok := op.RequestBody.Content[jsonContent]
+		if !ok || len(op.RequestBody.Content) > 1 {
+			return fmt.Errorf("only %s request bodies are supported", jsonContent)
+		}
+		var err error
+		if bodyType, err = g.goType(media.Schema, true); err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+	}
+
+	// The arguments every function of the operation takes, after the context
+	var params, args []string
+	for _, param := range pathParams {
+		if param.Schema == nil || param.Schema.Type != "string" {
+			return fmt.Errorf("parameter %s: only string path parameters are supported", param.Name)
+		}
+		params = append(params, lowerFirst(goName(param.Name))+" string")
+		args = append(args, lowerFirst(goName(param.Name)))
+	}
+	if len(queryParams) > 0 {
+		if err := g.paramsType(name, queryParams); err != nil {
+			return err
+		}
+		params = append(params, "params *"+name+"Params")
+		args = append(args, "params")
+	}
+	if bodyType != "" {
+		params = append(params, "body "+bodyType)
+		args = append(args, "body")
+	}
+	signature := strings.Join(append(params, "reqEditors ...RequestEditorFn"), ", ")
+	call := strings.Join(append(args, "reqEditors..."), ", ")
+
+	g.printf("\n")
+	g.comment(fmt.Sprintf("%s sends %s %s", name, op.method, op.path), op.Summary)
+	g.printf("func (c *Client) %s(ctx context.Context, %s) (*http.Response, error) {\n", name, signature)
+	g.printf("req, err := New%sRequest(%s)\n", name, strings.Join(append([]string{"c.Server"}, args...), ", "))
+	g.printf("if err != nil {\nreturn nil, err\n}\nreturn c.do(ctx, req, reqEditors)\n}\n")
+
+	if err := g.request(name, op, pathParams, queryParams, params, bodyType != ""); err != nil {
+		return err
+	}
+	return g.response(name, op, signature, call)
+}
+
+// paramsType writes the struct of an operation's query parameters
+func (g *generator) paramsType(name string, queryParams []parameter) error {
+	g.printf("\n// %sParams are the query parameters of %s\ntype %sParams struct {\n", name, name, name)
+	for _, param := range queryParams {
+		if param.Schema == nil {
+			return fmt.Errorf("parameter %s has no schema", param.Name)
+		}
+		typ, err := g.goType(param.Schema, param.Required)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		if strings.TrimPrefix(typ, "*") != queryType(param.Schema) {
+			return fmt.Errorf("parameter %s: only string, integer, boolean and date-time query parameters are supported", param.Name)
+		}
+		if param.Description != "" {
+			g.comment("", param.Description)
+		}
+		tag := param.Name
+		if !param.Required {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`\n", goName(param.Name), typ, tag)
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// queryType returns the Go type of a query parameter the request builder
+// can format
+func queryType(s *schema) string {
+	switch {
+	case s.Type == "string" && s.Format == "date-time":
+		return "time.Time"
+	case s.Type == "string" && s.Ref == "" && len(s.Enum) == 0:
+		return "string"
+	case s.Type == "integer":
+		return "int"
+	case s.Type == "boolean":
+		return "bool"
+	}
+	return ""
+}
+
+// request writes the function building an operation's request
+func (g *generator) request(name string, op *operation, pathParams, queryParams []parameter, params []string, hasBody bool) error {
+	path := strings.TrimPrefix(op.path, "/")
+	var pathExpr []string
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			pathExpr = append(pathExpr, strconv.Quote(path))
+			break
+		}
+		end := strings.Index(path, "}")
+		if end < start {
+			return fmt.Errorf("malformed path %s", op.path)
+		}
+		if start > 0 {
+			pathExpr = append(pathExpr, strconv.Quote(path[:start]))
+		}
+		param := path[start+1 : end]
+		found := false
+		for _, p := range pathParams {
+			found = found || p.Name == param
+		}
+		if !found {
+			return fmt.Errorf("path parameter %s isn't declared", param)
+		}
+		pathExpr = append(pathExpr, "url.PathEscape("+lowerFirst(goName(param))+")")
+		path = path[end+1:]
+	}
+
+	g.printf("\n// New%sRequest builds the request of %s: %s %s\n", name, name, op.method, op.path)
+	g.printf("func New%sRequest(%s) (*http.Request, error) {\n", name, strings.Join(append([]string{"server string"}, params...), ", "))
+	g.printf("queryURL, err := operationURL(server, %s)\nif err != nil {\nreturn nil, err\n}\n", strings.Join(pathExpr, " + "))
+	if len(queryParams) > 0 {
+		g.printf("if params != nil {\nquery := queryURL.Query()\n")
+		for _, param := range queryParams {
+			field := "params." + goName(param.Name)
+			value := field
+			if !param.Required {
+				g.printf("if %s != nil {\n", field)
+				value = "*" + field
+			}
+			switch queryType(param.Schema) {
+			case "time.Time":
+				value = field + ".Format(time.RFC3339)"
+			case "int":
+				g.imports["strconv"] = true
+				value = "strconv.Itoa(" + value + ")"
+			case "bool":
+				g.imports["strconv"] = true
+				value = "strconv.FormatBool(" + value + ")"
+			}
+			g.printf("query.Set(%q, %s)\n", param.Name, value)
+			if !param.Required {
+				g.printf("}\n")
+			}
+		}
+		g.printf("queryURL.RawQuery = query.Encode()\n}\n")
+	}
+	if !hasBody {
+		g.printf("return http.NewRequest(http.Method%s, queryURL.String(), nil)\n}\n", methodName(op.method))
+		return nil
+	}
+	g.imports["bytes"] = true
+	g.imports["encoding/json"] = true
+	g.printf("buf, err := json.Marshal(body)\nif err != nil {\nreturn nil, err\n}\n")
+	g.printf("req, err := http.NewRequest(http.Method%s, queryURL.String(), bytes.NewReader(buf))\n", methodName(op.method))
+	g.printf("if err != nil {\nreturn nil, err\n}\nreq.Header.Set(\"Content-Type\", %q)\nreturn req, nil\n}\n", jsonContent)
+	return nil
+}
+
+// response writes the response type of an operation, with a JSON<status>
+// field for each status answered with JSON, its parser and the
+// ClientWithResponses method
+func (g *generator) response(name string, op *operation, signature, call string) error {
+	type jsonResponse struct {
+		status int
+		typ    string
+	}
+	var responses []jsonResponse
+	for code, resp := range op.Responses {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 100 || status > 599 {
+			return fmt.Errorf("response %s: only numeric status codes are supported", code)
+		}
+		if resp.Ref != "" {
+			ref, err := refName(resp.Ref, "responses")
+			if err != nil {
+				return err
+			}
+			if resp = g.doc.Components.Responses[ref]; resp == nil {
+				return fmt.Errorf("no response %s", ref)
+			}
+		}
+		if len(resp.Content) == 0 {
+			continue
+		}
+		media, ok := resp.Content[jsonContent]
+		if !ok || len(resp.Content) > 1 {
+			return fmt.Errorf("response %s: only %s responses are supported", code, jsonContent)
+		}
+		typ, err := g.goType(media.Schema, true)
+		if err != nil {
+			return fmt.Errorf("response %s: %w", code, err)
+		}
+		responses = append(responses, jsonResponse{status, typ})
+	}
+	sort.Slice(responses, func(i, j int) bool { return responses[i].status < responses[j].status })
+
+	g.printf("\n// %sResponse is the response of %s, with its body decoded by status\n", name, name)
+	g.printf("type %sResponse struct {\nBody []byte\nHTTPResponse *http.Response\n", name)
+	for _, resp := range responses {
+		g.printf("JSON%d *%s\n", resp.status, resp.typ)
+	}
+	g.printf("}\n")
+	g.printf("\n// StatusCode returns HTTPResponse.StatusCode\nfunc (r %sResponse) StatusCode() int {\n", name)
+	g.printf("if r.HTTPResponse != nil {\nreturn r.HTTPResponse.StatusCode\n}\nreturn 0\n}\n")
+
+	g.printf("\n// %sWithResponse calls %s and parses its response\n", name, name)
+	g.printf("func (c *ClientWithResponses) %sWithResponse(ctx context.Context, %s) (*%sResponse, error) {\n", name, signature, name)
+	g.printf("rsp, err := c.ClientInterface.%s(ctx, %s)\nif err != nil {\nreturn nil, err\n}\nreturn Parse%sResponse(rsp)\n}\n", name, call, name)
+
+	g.imports["io"] = true
+	g.printf("\n// Parse%sResponse reads and closes the body of %s's response. A body\n", name, name)
+	g.printf("// that isn't JSON is left undecoded on an error status, as a proxy's may be.\n")
+	g.printf("func Parse%sResponse(rsp *http.Response) (*%sResponse, error) {\n", name, name)
+	g.printf("bodyBytes, err := io.ReadAll(rsp.Body)\ndefer func() { _ = rsp.Body.Close() }()\nif err != nil {\nreturn nil, err\n}\n")
+	g.printf("response := &%sResponse{\nBody: bodyBytes,\nHTTPResponse: rsp,\n}\n", name)
+	if len(responses) > 0 {
+		g.imports["encoding/json"] = true
+		g.printf("switch rsp.StatusCode {\n")
+		for _, resp := range responses {
+			g.printf("case %d:\nvar dest %s\n", resp.status, resp.typ)
+			if resp.status < 400 {
+				g.printf("if err := json.Unmarshal(bodyBytes, &dest); err != nil {\nreturn nil, err\n}\n")
+				g.printf("response.JSON%d = &dest\n", resp.status)
+			} else {
+				g.printf("if json.Unmarshal(bodyBytes, &dest) == nil {\nresponse.JSON%d = &dest\n}\n", resp.status)
+			}
+		}
+		g.printf("}\n")
+	}
+	g.printf("return response, nil\n}\n")
+	return nil
+}
+
+// comment writes a doc comment of lead followed by the spec's description,
+// either of which may be empty
+func (g *generator) comment(lead, description string) {
+	text := oneLine(description)
+	if lead != "" && text != "" {
+		text = lead + ": " + text
+	} else if lead != "" {
+		text = lead
+	}
+	if text != "" {
+		g.printf("// %s\n", text)
+	}
+}
+
+// refName returns the name of a component a $ref points to
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported $ref %s; only %s... is", ref, prefix)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// goName makes an exported Go name of a spec name: consentGiven becomes
+// ConsentGiven and page-size PageSize
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// methodName returns the suffix of an http.Method constant
+func methodName(method string) string {
+	return method[:1] + strings.ToLower(method[1:])
+}
+
+// oneLine folds text onto one line and drops a trailing period
+func oneLine(text string) string {
+	return strings.TrimSuffix(strings.Join(strings.Fields(text), " "), ".")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runtime is the part of the client that doesn't depend on the spec
+const runtime = `
+// HttpRequestDoer sends HTTP requests, as *http.Client does
+type HttpRequestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RequestEditorFn changes a request before it is sent, e.g. to add headers
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+// Client sends the spec's operations to a server
+type Client struct {
+	// Server is the base URL the operations' paths are relative to, such as
+	// http://localhost:3030 or, under a prefix, http://localhost:3030/v1
+	Server string
+
+	// Client sends the requests; NewClient defaults it to an http.Client
+	Client HttpRequestDoer
+
+	// RequestEditors change every request before it is sent
+	RequestEditors []RequestEditorFn
+}
+
+// ClientOption configures a Client in NewClient
+type ClientOption func(*Client) error
+
+// NewClient returns a Client of the server at the base URL server
+func NewClient(server string, opts ...ClientOption) (*Client, error) {
+	client := Client{
+		Server: server,
+	}
+	for _, o := range opts {
+		if err := o(&client); err != nil {
+			return nil, err
+		}
+	}
+	// Paths are resolved relative to the server URL, so it ends in a slash
+	if !strings.HasSuffix(client.Server, "/") {
+		client.Server += "/"
+	}
+	if client.Client == nil {
+		client.Client = &http.Client{}
+	}
+	return &client, nil
+}
+
+// WithHTTPClient sends requests with doer in place of a plain http.Client
+func WithHTTPClient(doer HttpRequestDoer) ClientOption {
+	return func(c *Client) error {
+		c.Client = doer
+		return nil
+	}
+}
+
+// WithRequestEditorFn adds fn to the editors every request goes through
+func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
+	return func(c *Client) error {
+		c.RequestEditors = append(c.RequestEditors, fn)
+		return nil
+	}
+}
+
+func operationURL(server, path string) (*url.URL, error) {
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	return serverURL.Parse(path)
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, reqEditors []RequestEditorFn) (*http.Response, error) {
+	req = req.WithContext(ctx)
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range reqEditors {
+		if err := r(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return c.Client.Do(req)
+}
+
+// ClientWithResponses sends operations with a Client and decodes their
+// responses
+type ClientWithResponses struct {
+	ClientInterface *Client
+}
+
+// NewClientWithResponses returns a ClientWithResponses of the server at the
+// base URL server
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+`
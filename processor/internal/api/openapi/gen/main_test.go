@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Run("the committed client is generated from the spec", func(t *testing.T) {
+		// Arrange
+		spec, err := os.ReadFile("../../../../api/openapi.yaml")
+		assert.NoError(t, err)
+		committed, err := os.ReadFile("../client.gen.go")
+		assert.NoError(t, err)
+
+		// Act
+		generated, err := generate(spec, "openapi")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, string(committed), string(generated), "client.gen.go is stale; run go generate ./internal/api/openapi")
+	})
+
+	t.Run("builds path parameters into the path", func(t *testing.T) {
+		// Arrange
+		spec := []byte(`
+paths:
+  /leads/{id}/notes:
+    post:
+      operationId: addNote
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "204": {description: Note added}
+`)
+
+		// Act
+		generated, err := generate(spec, "openapi")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Contains(t, string(generated), `operationURL(server, "leads/"+url.PathEscape(id)+"/notes")`)
+		assert.Contains(t, string(generated), "func (c *Client) AddNote(ctx context.Context, id string, reqEditors ...RequestEditorFn)")
+	})
+
+	t.Run("refuses what it can't generate", func(t *testing.T) {
+		for name, spec := range map[string]string{
+			"inline object": `
+components:
+  schemas:
+    Lead:
+      type: object
+      properties:
+        address: {type: object, properties: {city: {type: string}}}
+`,
+			"header parameter": `
+paths:
+  /leads:
+    get:
+      operationId: listLeads
+      parameters:
+        - {name: X-Tenant, in: header, schema: {type: string}}
+      responses:
+        "200": {description: Leads}
+`,
+			"non-JSON response": `
+paths:
+  /leads:
+    get:
+      operationId: listLeads
+      responses:
+        "200":
+          description: Leads
+          content:
+            text/csv: {schema: {type: string}}
+`,
+		} {
+			// Act
+			_, err := generate([]byte(spec), "openapi")
+
+			// Assert
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// spec is the part of api/openapi.yaml the client is checked against
+type spec struct {
+	Paths map[string]map[string]struct {
+		OperationID string `yaml:"operationId"`
+	} `yaml:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Required   []string               `yaml:"required"`
+			Properties map[string]interface{} `yaml:"properties"`
+			Enum       []string               `yaml:"enum"`
+		} `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+	data, err := os.ReadFile("../../../api/openapi.yaml")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var s spec
+	if !assert.NoError(t, yaml.Unmarshal(data, &s)) {
+		t.FailNow()
+	}
+	return s
+}
+
+// jsonFields returns the JSON names of a struct's fields and which of them
+// are required, i.e. not omitempty
+func jsonFields(v interface{}) (names, required []string) {
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		name, options, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		names = append(names, name)
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(required)
+	return names, required
+}
+
+func TestClientMatchesSpec(t *testing.T) {
+	s := loadSpec(t)
+
+	t.Run("models have the properties of their schemas", func(t *testing.T) {
+		for name, model := range map[string]interface{}{
+			"Lead":           Lead{},
+			"LeadInput":      LeadInput{},
+			"LookupResponse": LookupResponse{},
+			"LeadEnvelope":   LeadEnvelope{},
+			"Error":          Error{},
+		} {
+			// Arrange
+			schema, ok := s.Components.Schemas[name]
+			assert.True(t, ok, "schema %s", name)
+			var properties []string
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+			required := append([]string(nil), schema.Required...)
+			sort.Strings(required)
+
+			// Act
+			fields, requiredFields := jsonFields(model)
+
+			// Assert
+			assert.Equal(t, properties, fields, "properties of %s", name)
+			assert.Equal(t, required, requiredFields, "required properties of %s", name)
+		}
+	})
+
+	t.Run("enums have the values of their schemas", func(t *testing.T) {
+		// Arrange
+		sources := []string{string(Conference), string(LinkedIn), string(Referral), string(Twitter), string(Webinar), string(Website)}
+		statuses := []string{string(Contacted), string(Disqualified), string(New), string(Qualified)}
+
+		// Assert
+		assert.ElementsMatch(t, s.Components.Schemas["Source"].Enum, sources)
+		assert.ElementsMatch(t, s.Components.Schemas["Status"].Enum, statuses)
+	})
+
+	t.Run("operations use the method and path of their operationId", func(t *testing.T) {
+		// Arrange
+		var method, path string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		client, err := NewClient(server.URL)
+		assert.NoError(t, err)
+
+		for operationID, call := range map[string]func() (*http.Response, error){
+			"lookupLead": func() (*http.Response, error) {
+				return client.LookupLead(context.Background(), &LookupLeadParams{Email: "alice@example.com"})
+			},
+			"createLead": func() (*http.Response, error) {
+				return client.CreateLead(context.Background(), LeadInput{Email: "alice@example.com"})
+			},
+			"updateLead": func() (*http.Response, error) {
+				return client.UpdateLead(context.Background(), LeadInput{Email: "alice@example.com"})
+			},
+		} {
+			// Act
+			resp, err := call()
+
+			// Assert
+			assert.NoError(t, err)
+			resp.Body.Close()
+			operation, ok := s.Paths[path][strings.ToLower(method)]
+			assert.True(t, ok, "%s %s is not in the spec", method, path)
+			assert.Equal(t, operationID, operation.OperationID)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"code/internal/errcode"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultPageSize is the page size used when PageOptions.PageSize is not set
+const DefaultPageSize = 100
+
+// PageOptions controls how paginated endpoints are requested
+type PageOptions struct {
+	PageSize int
+}
+
+// LeadPage represents a single page returned by a paginated endpoint.
+// Servers may paginate by cursor (NextCursor), by link (Next) or by page
+// number (Page/TotalPages); the iterator follows whichever is present.
+type LeadPage struct {
+	Leads      []*Lead `json:"leads"`
+	Next       string  `json:"next,omitempty"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+	Page       int     `json:"page,omitempty"`
+	TotalPages int     `json:"totalPages,omitempty"`
+	TotalCount *int    `json:"totalCount,omitempty"`
+}
+
+// LeadIterator streams leads from a paginated endpoint, decoding each lead
+// as it is read from the response, so callers never hold more than one lead
+// in memory however large the pages are
+type LeadIterator struct {
+	client  *APIClient
+	nextURL string
+	pageURL string
+	body    io.ReadCloser
+	decoder *json.Decoder
+	inLeads bool
+	page    LeadPage
+	read    int
+	current *Lead
+	total   int
+	err     error
+	done    bool
+}
+
+// ListLeads returns an iterator over all leads
+func (c *APIClient) ListLeads(opts PageOptions) *LeadIterator {
+	return c.newLeadIterator("/api/leads", nil, opts)
+}
+
+// ExportLeads returns an iterator over the full lead export
+func (c *APIClient) ExportLeads(opts PageOptions) *LeadIterator {
+	return c.newLeadIterator("/api/leads/export", nil, opts)
+}
+
+// ExportLeadsUpdatedSince returns an iterator over the leads created or updated
+// since the given time. A server that ignores updatedSince returns the full
+// export, which callers merging the leads into a copy can't tell apart.
+func (c *APIClient) ExportLeadsUpdatedSince(since time.Time, opts PageOptions) *LeadIterator {
+	return c.newLeadIterator("/api/leads/export", url.Values{"updatedSince": {since.UTC().Format(time.RFC3339)}}, opts)
+}
+
+// LookupLeadsByDomain returns an iterator over leads whose email belongs to the given domain
+func (c *APIClient) LookupLeadsByDomain(domain string, opts PageOptions) *LeadIterator {
+	if c.capabilities != nil && !c.capabilities.DomainLookup {
+		return &LeadIterator{err: fmt.Errorf("%w: domain lookup", ErrUnsupportedFeature), total: -1}
+	}
+	return c.newLeadIterator("/api/leads/lookup-by-domain", url.Values{"domain": {domain}}, opts)
+}
+
+func (c *APIClient) newLeadIterator(path string, query url.Values, opts PageOptions) *LeadIterator {
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("pageSize", strconv.Itoa(pageSize))
+
+	return &LeadIterator{
+		client:  c,
+		nextURL: fmt.Sprintf("%s%s?%s", c.baseURL, path, query.Encode()),
+		total:   -1,
+	}
+}
+
+// Next advances the iterator and reports whether a lead is available
+func (it *LeadIterator) Next() bool {
+	for {
+		switch {
+		case it.err != nil:
+			it.current = nil
+			return false
+		case it.inLeads:
+			if it.decoder.More() {
+				var lead Lead
+				if err := it.decoder.Decode(&lead); err != nil {
+					it.fail(fmt.Errorf("failed to decode response: %w", err))
+					continue
+				}
+				it.read++
+				it.current = &lead
+				return true
+			}
+			if _, err := it.decoder.Token(); err != nil {
+				it.fail(fmt.Errorf("failed to decode response: %w", err))
+				continue
+			}
+			it.inLeads = false
+		case it.decoder != nil:
+			inLeads, err := it.readFields()
+			if err != nil {
+				it.fail(err)
+				continue
+			}
+			it.inLeads = inLeads
+			if !inLeads {
+				it.finishPage()
+			}
+		case it.done:
+			it.current = nil
+			return false
+		default:
+			if err := it.fetch(); err != nil {
+				it.fail(err)
+			}
+		}
+	}
+}
+
+// Lead returns the lead at the current iterator position
+func (it *LeadIterator) Lead() *Lead {
+	return it.current
+}
+
+// Err returns the first error encountered while iterating
+func (it *LeadIterator) Err() error {
+	return it.err
+}
+
+// Total returns the total number of leads reported by the server, or -1 if unknown
+func (it *LeadIterator) Total() int {
+	return it.total
+}
+
+// Close releases the page being read; call it when stopping before Next
+// returns false
+func (it *LeadIterator) Close() {
+	if it.body != nil {
+		it.body.Close()
+		it.body = nil
+	}
+	it.decoder = nil
+	it.inLeads = false
+	it.done = true
+}
+
+// fail stops the iterator with err
+func (it *LeadIterator) fail(err error) {
+	it.Close()
+	it.err = err
+}
+
+// fetch requests the next page and starts reading it
+func (it *LeadIterator) fetch() error {
+	requestURL := it.nextURL
+
+	resp, err := it.client.httpClient.Get(requestURL)
+	if err != nil {
+		if isTimeoutError(err) {
+			return fmt.Errorf("request timeout: %w", errcode.Network(err))
+		}
+		return fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return &errcode.StatusError{Status: resp.StatusCode}
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	if token, err := decoder.Token(); err != nil {
+		resp.Body.Close()
+		return fmt.Errorf("failed to decode response: %w", err)
+	} else if token != json.Delim('{') {
+		resp.Body.Close()
+		return fmt.Errorf("failed to decode response: a page must be a JSON object")
+	}
+
+	it.pageURL = requestURL
+	it.body = resp.Body
+	it.decoder = decoder
+	it.page = LeadPage{}
+	it.read = 0
+	return nil
+}
+
+// readFields reads the page's fields until it reaches the leads, reporting
+// true, or the end of the page, reporting false
+func (it *LeadIterator) readFields() (bool, error) {
+	for it.decoder.More() {
+		token, err := it.decoder.Token()
+		if err != nil {
+			return false, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		var value interface{}
+		switch token {
+		case "leads":
+			if token, err = it.decoder.Token(); err != nil {
+				return false, fmt.Errorf("failed to decode response: %w", err)
+			}
+			if token == json.Delim('[') {
+				return true, nil
+			}
+			if token != nil {
+				return false, fmt.Errorf("failed to decode response: leads must be a list")
+			}
+			continue
+		case "next":
+			value = &it.page.Next
+		case "nextCursor":
+			value = &it.page.NextCursor
+		case "page":
+			value = &it.page.Page
+		case "totalPages":
+			value = &it.page.TotalPages
+		case "totalCount":
+			value = &it.page.TotalCount
+		default:
+			value = new(json.RawMessage)
+		}
+		if err := it.decoder.Decode(value); err != nil {
+			return false, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	if _, err := it.decoder.Token(); err != nil {
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return false, nil
+}
+
+// finishPage closes the page that was read and works out where the next
+// one lives
+func (it *LeadIterator) finishPage() {
+	it.body.Close()
+	it.body = nil
+	it.decoder = nil
+	if it.page.TotalCount != nil {
+		it.total = *it.page.TotalCount
+	}
+
+	next, err := it.nextPageURL(it.pageURL, &it.page)
+	if err != nil {
+		it.fail(err)
+		return
+	}
+	if next == "" || it.read == 0 {
+		it.done = true
+	}
+	it.nextURL = next
+}
+
+// nextPageURL resolves the URL of the following page from link, cursor or
+// page number. A link to another scheme or host is an error.
+func (it *LeadIterator) nextPageURL(current string, page *LeadPage) (string, error) {
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	switch {
+	case page.Next != "":
+		ref, err := url.Parse(page.Next)
+		if err != nil {
+			return "", fmt.Errorf("invalid next link: %w", err)
+		}
+		next := base.ResolveReference(ref)
+		// The API token goes with every page, so pages must stay on the API
+		if next.Scheme != base.Scheme || next.Host != base.Host {
+			return "", fmt.Errorf("next link %s leaves %s://%s", next.Redacted(), base.Scheme, base.Host)
+		}
+		return next.String(), nil
+	case page.NextCursor != "":
+		query := base.Query()
+		query.Del("page")
+		query.Set("cursor", page.NextCursor)
+		base.RawQuery = query.Encode()
+		return base.String(), nil
+	case page.Page > 0 && page.TotalPages > page.Page:
+		query := base.Query()
+		query.Del("cursor")
+		query.Set("page", strconv.Itoa(page.Page+1))
+		base.RawQuery = query.Encode()
+		return base.String(), nil
+	}
+
+	return "", nil
+}
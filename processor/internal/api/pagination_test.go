@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIClient_ListLeads(t *testing.T) {
+	t.Run("follows cursors until the last page", func(t *testing.T) {
+		// Arrange
+		total := 3
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/leads", r.URL.Path)
+			assert.Equal(t, "2", r.URL.Query().Get("pageSize"))
+
+			page := LeadPage{TotalCount: &total}
+			switch r.URL.Query().Get("cursor") {
+			case "":
+				page.Leads = []*Lead{{Email: "a@example.com"}, {Email: "b@example.com"}}
+				page.NextCursor = "abc"
+			case "abc":
+				page.Leads = []*Lead{{Email: "c@example.com"}}
+			}
+			json.NewEncoder(w).Encode(page)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		it := client.ListLeads(PageOptions{PageSize: 2})
+		var emails []string
+		for it.Next() {
+			emails = append(emails, it.Lead().Email)
+		}
+
+		// Assert
+		assert.NoError(t, it.Err())
+		assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com"}, emails)
+		assert.Equal(t, 3, it.Total())
+	})
+
+	t.Run("follows next links and page numbers", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var page LeadPage
+			switch {
+			case r.URL.Path == "/api/leads/export" && r.URL.Query().Get("page") == "":
+				page.Leads = []*Lead{{Email: "a@example.com"}}
+				page.Next = "/api/leads/export?page=2&pageSize=1"
+			case r.URL.Query().Get("page") == "2":
+				page.Leads = []*Lead{{Email: "b@example.com"}}
+				page.Page = 2
+				page.TotalPages = 3
+			case r.URL.Query().Get("page") == "3":
+				page.Leads = []*Lead{{Email: "c@example.com"}}
+				page.Page = 3
+				page.TotalPages = 3
+			}
+			json.NewEncoder(w).Encode(page)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		it := client.ExportLeads(PageOptions{PageSize: 1})
+		count := 0
+		for it.Next() {
+			count++
+		}
+
+		// Assert
+		assert.NoError(t, it.Err())
+		assert.Equal(t, 3, count)
+		assert.Equal(t, -1, it.Total())
+	})
+
+	t.Run("refuses next links to another host", func(t *testing.T) {
+		// Arrange
+		var leaked bool
+		elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			leaked = r.Header.Get("Authorization") != ""
+			fmt.Fprint(w, `{"leads":[]}`)
+		}))
+		defer elsewhere.Close()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"leads":[{"email":"a@example.com"}],"next":%q}`, elsewhere.URL+"/api/leads/export?page=2")
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL, WithMiddleware(BearerAuthMiddleware("secret")))
+
+		// Act
+		it := client.ExportLeads(PageOptions{})
+		count := 0
+		for it.Next() {
+			count++
+		}
+
+		// Assert
+		assert.Equal(t, 1, count)
+		assert.ErrorContains(t, it.Err(), "leaves")
+		assert.False(t, leaked, "the token was sent to another host")
+	})
+
+	t.Run("stops and reports error on non-200 page", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("cursor") == "" {
+				fmt.Fprint(w, `{"leads":[{"email":"a@example.com"}],"nextCursor":"x"}`)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		it := client.LookupLeadsByDomain("example.com", PageOptions{})
+		count := 0
+		for it.Next() {
+			count++
+		}
+
+		// Assert
+		assert.Equal(t, 1, count)
+		assert.Error(t, it.Err())
+		assert.Contains(t, it.Err().Error(), "status 500")
+	})
+
+	t.Run("keeps the updatedSince filter across pages", func(t *testing.T) {
+		// Arrange
+		var filters []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/leads/export", r.URL.Path)
+			filters = append(filters, r.URL.Query().Get("updatedSince"))
+			if r.URL.Query().Get("cursor") == "" {
+				fmt.Fprint(w, `{"leads":[{"email":"a@example.com"}],"nextCursor":"x"}`)
+				return
+			}
+			fmt.Fprint(w, `{"leads":[{"email":"b@example.com"}]}`)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+		since := time.Date(2026, 10, 15, 9, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+		// Act
+		it := client.ExportLeadsUpdatedSince(since, PageOptions{})
+		count := 0
+		for it.Next() {
+			count++
+		}
+
+		// Assert
+		assert.NoError(t, it.Err())
+		assert.Equal(t, 2, count)
+		assert.Equal(t, []string{"2026-10-15T07:30:00Z", "2026-10-15T07:30:00Z"}, filters)
+	})
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Targets the client layer limits separately
+const (
+	// TargetAPI is the lead API at --api-url
+	TargetAPI = "api"
+	// TargetPipedrive is Pipedrive's API, for --provider pipedrive
+	TargetPipedrive = "pipedrive"
+	// TargetZoho is Zoho CRM's API, for --provider zoho
+	TargetZoho = "zoho"
+	// TargetMailchimp is Mailchimp's API, for --mailchimp
+	TargetMailchimp = "mailchimp"
+	// TargetStorage is S3 and the webhooks exports and dead letters go to
+	TargetStorage = "storage"
+)
+
+// Targets returns the targets limits can be set for
+func Targets() []string {
+	return []string{TargetAPI, TargetPipedrive, TargetZoho, TargetMailchimp, TargetStorage}
+}
+
+// Limit caps the requests sent to a target
+type Limit struct {
+	// RequestsPerSecond paces the requests to each host of the target; 0
+	// leaves them unpaced
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst is how many requests to a host may go at once before pacing
+	// starts; 0 means 1
+	Burst int `yaml:"burst"`
+	// MaxConcurrent caps the target's requests in flight across all
+	// workers; 0 leaves them uncapped
+	MaxConcurrent int `yaml:"max_concurrent"`
+}
+
+// IsZero reports whether the limit caps nothing
+func (l Limit) IsZero() bool {
+	return l.RequestsPerSecond == 0 && l.MaxConcurrent == 0
+}
+
+// Limits maps targets to their limits
+type Limits map[string]Limit
+
+// Validate rejects unknown targets and negative limits
+func (l Limits) Validate() error {
+	targets := make([]string, 0, len(l))
+	for target := range l {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		if !isTarget(target) {
+			return fmt.Errorf("unknown target %q (allowed: %s)", target, strings.Join(Targets(), ", "))
+		}
+		limit := l[target]
+		if limit.RequestsPerSecond < 0 || limit.Burst < 0 || limit.MaxConcurrent < 0 {
+			return fmt.Errorf("%s: limits cannot be negative", target)
+		}
+	}
+	return nil
+}
+
+func isTarget(target string) bool {
+	for _, name := range Targets() {
+		if target == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Limiter holds back the requests to a target: each host it talks to gets
+// its own pacing, so a slow or strict host doesn't hold up the others, and
+// the target as a whole gets a cap on requests in flight, so one provider
+// can't take up every worker's connection.
+type Limiter struct {
+	limit Limit
+	slots chan struct{}
+
+	mu    sync.Mutex
+	hosts map[string]*bucket
+}
+
+// NewLimiter enforces limit
+func NewLimiter(limit Limit) *Limiter {
+	l := &Limiter{limit: limit, hosts: make(map[string]*bucket)}
+	if limit.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return l
+}
+
+// Middleware holds each request until the target has a free slot and its
+// host's pacing allows it; it has the shape of Middleware. Add it after the
+// retry middleware so retries are paced too.
+func (l *Limiter) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if l.slots != nil {
+			select {
+			case l.slots <- struct{}{}:
+				defer func() { <-l.slots }()
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+		if err := l.pace(req.Context(), req.URL.Host); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// pace waits until the host may be sent another request
+func (l *Limiter) pace(ctx context.Context, host string) error {
+	if l.limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	b, ok := l.hosts[host]
+	if !ok {
+		b = newBucket(l.limit)
+		l.hosts[host] = b
+	}
+	delay := b.reserve(time.Now())
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bucket is a token bucket refilled at the limit's rate
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit Limit) *bucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: limit.RequestsPerSecond, burst: burst, tokens: burst}
+}
+
+// reserve takes a token and returns how long to wait before using it. The
+// tokens go negative while requests queue, so each waits its turn.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Limiters keeps one limiter per target, shared by every client of a run
+// that talks to it
+type Limiters struct {
+	limits Limits
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewLimiters enforces limits; targets without a limit are left alone
+func NewLimiters(limits Limits) *Limiters {
+	return &Limiters{limits: limits, limiters: make(map[string]*Limiter)}
+}
+
+// Option returns the client option adding the target's limiter, or one that
+// does nothing when the target has no limit
+func (l *Limiters) Option(target string) ClientOption {
+	limit := l.limits[target]
+	if limit.IsZero() {
+		return func(*APIClient) {}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.limiters[target]
+	if !ok {
+		limiter = NewLimiter(limit)
+		l.limiters[target] = limiter
+	}
+	return WithMiddleware(limiter.Middleware)
+}
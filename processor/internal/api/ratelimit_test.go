@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits(t *testing.T) {
+	t.Run("accepts known targets", func(t *testing.T) {
+		// Act
+		err := Limits{TargetAPI: {RequestsPerSecond: 10, Burst: 5}, TargetMailchimp: {MaxConcurrent: 2}}.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects unknown targets and negative limits", func(t *testing.T) {
+		// Act
+		targetErr := Limits{"hubspot": {RequestsPerSecond: 1}}.Validate()
+		negativeErr := Limits{TargetZoho: {MaxConcurrent: -1}}.Validate()
+
+		// Assert
+		assert.ErrorContains(t, targetErr, `unknown target "hubspot"`)
+		assert.EqualError(t, negativeErr, "zoho: limits cannot be negative")
+	})
+}
+
+func TestBucket(t *testing.T) {
+	t.Run("lets a burst through, then paces requests in turn", func(t *testing.T) {
+		// Arrange
+		b := newBucket(Limit{RequestsPerSecond: 2, Burst: 2})
+		now := time.Now()
+
+		// Act
+		delays := []time.Duration{b.reserve(now), b.reserve(now), b.reserve(now), b.reserve(now)}
+		later := b.reserve(now.Add(5 * time.Second))
+
+		// Assert
+		assert.Equal(t, []time.Duration{0, 0, 500 * time.Millisecond, time.Second}, delays)
+		assert.Equal(t, time.Duration(0), later)
+	})
+}
+
+// countingTransport counts the requests in flight and per host
+type countingTransport struct {
+	inFlight    atomic.Int64
+	maxInFlight atomic.Int64
+	delay       time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	current := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.maxInFlight.Load()
+		if current <= peak || c.maxInFlight.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	c.mu.Lock()
+	c.hosts[req.URL.Host]++
+	c.mu.Unlock()
+	time.Sleep(c.delay)
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("caps the requests in flight", func(t *testing.T) {
+		// Arrange
+		transport := &countingTransport{delay: 20 * time.Millisecond, hosts: map[string]int{}}
+		rt := NewLimiter(Limit{MaxConcurrent: 2}).Middleware(transport)
+
+		// Act
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, _ := http.NewRequest(http.MethodGet, "http://crm.example.com/api/leads", nil)
+				rt.RoundTrip(req)
+			}()
+		}
+		wg.Wait()
+
+		// Assert
+		assert.Equal(t, int64(2), transport.maxInFlight.Load())
+		assert.Equal(t, 8, transport.hosts["crm.example.com"])
+	})
+
+	t.Run("paces each host on its own", func(t *testing.T) {
+		// Arrange
+		transport := &countingTransport{hosts: map[string]int{}}
+		rt := NewLimiter(Limit{RequestsPerSecond: 1}).Middleware(transport)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// Act
+		var errs []error
+		for _, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/", nil)
+			_, err := rt.RoundTrip(req)
+			errs = append(errs, err)
+		}
+
+		// Assert
+		assert.NoError(t, errs[0])
+		assert.NoError(t, errs[1], "another host isn't held up")
+		assert.ErrorIs(t, errs[2], context.DeadlineExceeded, "the same host waits a second")
+	})
+}
+
+func TestLimiters(t *testing.T) {
+	t.Run("shares a target's limiter between clients", func(t *testing.T) {
+		// Arrange
+		limiters := NewLimiters(Limits{TargetAPI: {MaxConcurrent: 1}})
+
+		// Act
+		first := NewAPIClient("", limiters.Option(TargetAPI))
+		second := NewAPIClient("", limiters.Option(TargetAPI))
+		unlimited := NewAPIClient("", limiters.Option(TargetMailchimp))
+
+		// Assert
+		assert.Len(t, limiters.limiters, 1)
+		assert.Len(t, first.Middlewares(), len(DefaultMiddlewares())+1)
+		assert.Len(t, second.Middlewares(), len(DefaultMiddlewares())+1)
+		assert.Len(t, unlimited.Middlewares(), len(DefaultMiddlewares()))
+	})
+}
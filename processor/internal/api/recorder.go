@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RecorderMode selects whether a Recorder replays fixtures or records new ones
+type RecorderMode int
+
+const (
+	// ModeReplay serves responses from the fixture file and never touches the network
+	ModeReplay RecorderMode = iota
+	// ModeRecord sends requests to the real transport and saves the interactions
+	ModeRecord
+)
+
+// RecordedRequest is the part of a request used to match it against a fixture
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response stored in a fixture
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Recorder is a VCR-style http.RoundTripper that records HTTP interactions to
+// a fixture file and replays them in order, so tests don't need a live server
+type Recorder struct {
+	mode         RecorderMode
+	path         string
+	transport    http.RoundTripper
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewRecorder creates a recorder for the fixture at path. In replay mode the
+// fixture must exist; transport is only used in record mode and defaults to
+// http.DefaultTransport.
+func NewRecorder(path string, mode RecorderMode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: transport,
+	}
+
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.interactions); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Body:   body,
+	}
+
+	if r.mode == ModeRecord {
+		return r.record(req, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+// Stop saves recorded interactions; it is a no-op in replay mode
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// record forwards the request to the real transport and stores the exchange
+func (r *Recorder) record(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       string(respBody),
+		},
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.mu.Unlock()
+
+	return interaction.Response.toHTTP(req), nil
+}
+
+// replay serves the first unused interaction matching the request
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || interaction.Request != recorded {
+			continue
+		}
+		r.used[i] = true
+		return interaction.Response.toHTTP(req), nil
+	}
+
+	return nil, errors.New("no recorded interaction for " + recorded.Method + " " + recorded.URL)
+}
+
+// toHTTP rebuilds an *http.Response from a recorded response
+func (rr RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	header := rr.Header
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		StatusCode: rr.StatusCode,
+		Status:     fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		Header:     header.Clone(),
+		Body:       io.NopCloser(bytes.NewBufferString(rr.Body)),
+		Request:    req,
+	}
+}
+
+// readRequestBody reads the request body and restores it for the real transport
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	return string(data), nil
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Run("records interactions and replays them without the server", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"found":false}`))
+		}))
+		fixture := filepath.Join(t.TempDir(), "lookup.json")
+
+		recorder, err := NewRecorder(fixture, ModeRecord, nil)
+		assert.NoError(t, err)
+		_, err = NewAPIClient(server.URL, WithTransport(recorder)).LookupLead("new@example.com")
+		assert.NoError(t, err)
+		assert.NoError(t, recorder.Stop())
+		server.Close()
+
+		// Act
+		replayer, err := NewRecorder(fixture, ModeReplay, nil)
+		assert.NoError(t, err)
+		result, replayErr := NewAPIClient(server.URL, WithTransport(replayer)).LookupLead("new@example.com")
+
+		// Assert
+		assert.NoError(t, replayErr)
+		assert.False(t, result.Found)
+	})
+
+	t.Run("fails requests that have no recorded interaction", func(t *testing.T) {
+		// Arrange
+		replayer, err := NewRecorder("../../testdata/fixtures/lookup_existing_lead.json", ModeReplay, nil)
+		assert.NoError(t, err)
+		client := NewAPIClient("http://localhost:3030", WithTransport(replayer))
+
+		// Act
+		result, err := client.LookupLead("someone-else@example.com")
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "no recorded interaction")
+	})
+}
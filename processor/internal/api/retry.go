@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// IdempotencyKeyHeader carries a key the server remembers a write by, so a
+// write sent again with the same key is applied once and answered like the
+// first time
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryTransientMiddleware retries requests that hit a 502, 503 or 504 or had
+// their connection reset, with the same backoff as RetryRateLimitedMiddleware.
+// Only requests safe to send twice are retried: those with an idempotent
+// method, and writes carrying an Idempotency-Key header.
+func RetryTransientMiddleware(maxRetries int, baseDelay time.Duration) Middleware {
+	return retryMiddleware("Transient failure", maxRetries, baseDelay, func(req *http.Request, resp *http.Response, err error) bool {
+		return retrySafe(req) && isTransient(resp, err)
+	})
+}
+
+// retrySafe reports whether sending req again can't apply it twice
+func retrySafe(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// isTransient reports whether a round trip failed in a way the next attempt
+// may not: a gateway error, an overloaded server or a dropped connection
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, syscall.ECONNRESET)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
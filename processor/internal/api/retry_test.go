@@ -0,0 +1,104 @@
+package api
+
+import (
+	"code/internal/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTransientMiddleware(t *testing.T) {
+	t.Run("retries a create on gateway errors with the same idempotency key", func(t *testing.T) {
+		// Arrange
+		var keys []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+			if len(keys) < 3 {
+				w.WriteHeader([]int{http.StatusBadGateway, http.StatusServiceUnavailable}[len(keys)-1])
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"success":true,"lead":{"id":"lead-1","email":"john@example.com"}}`))
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		lead, err := client.CreateLead(models.NewLead("John Doe", "john@example.com", "Acme", "Website"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "lead-1", lead.ID)
+		assert.Len(t, keys, 3)
+		assert.NotEmpty(t, keys[0])
+		assert.Equal(t, keys[0], keys[1])
+		assert.Equal(t, keys[0], keys[2])
+	})
+
+	t.Run("retries a connection reset", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+		rt := Chain(base, RetryTransientMiddleware(3, time.Millisecond))
+		req, _ := http.NewRequest(http.MethodDelete, "http://example.com/api/leads/lead-1", nil)
+
+		// Act
+		resp, err := rt.RoundTrip(req)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("doesn't retry a write without an idempotency key", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		err := client.AddNote("lead-1", "Called back")
+
+		// Assert
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("doesn't retry other server errors", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := NewAPIClient(server.URL)
+
+		// Act
+		_, err := client.UpdateLead(models.NewLead("John Doe", "john@example.com", "Acme", "Website"))
+
+		// Assert
+		assert.Error(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
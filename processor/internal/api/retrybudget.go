@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retryKey marks a request as a retry in its context
+type retryKey struct{}
+
+// retryInfo describes a retry: how long the retry middleware waited before it
+type retryInfo struct {
+	delay time.Duration
+}
+
+// markRetry returns req marked as a retry sent after waiting delay
+func markRetry(req *http.Request, delay time.Duration) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), retryKey{}, retryInfo{delay: delay}))
+}
+
+// RetryBudget caps the retries made across every request of a run, so a
+// degraded API fails the run fast instead of every row retrying in turn.
+// Add its Middleware after the retry middleware so it sees each retry.
+type RetryBudget struct {
+	maxRetries int
+	maxTime    time.Duration
+	exhausted  error
+
+	mu      sync.Mutex
+	retries int
+	spent   time.Duration
+}
+
+// NewRetryBudget allows at most maxRetries retries and maxTime spent waiting
+// for and sending them; zero means no limit. Once either is used up every
+// further request fails with an error wrapping exhausted.
+func NewRetryBudget(maxRetries int, maxTime time.Duration, exhausted error) *RetryBudget {
+	return &RetryBudget{maxRetries: maxRetries, maxTime: maxTime, exhausted: exhausted}
+}
+
+// Middleware wraps next so retries are charged to the budget; it has the
+// shape of Middleware
+func (b *RetryBudget) Middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := b.check(); err != nil {
+			return nil, err
+		}
+
+		info, isRetry := req.Context().Value(retryKey{}).(retryInfo)
+		if !isRetry {
+			return next.RoundTrip(req)
+		}
+
+		b.mu.Lock()
+		b.retries++
+		b.spent += info.delay
+		b.mu.Unlock()
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		b.mu.Lock()
+		b.spent += time.Since(start)
+		b.mu.Unlock()
+		return resp, err
+	})
+}
+
+// check returns the exhausted error once the budget is used up
+func (b *RetryBudget) check() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if (b.maxRetries > 0 && b.retries >= b.maxRetries) || (b.maxTime > 0 && b.spent >= b.maxTime) {
+		return fmt.Errorf("%w after %d retries and %s spent retrying", b.exhausted, b.retries, b.spent.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// Retries returns how many retries were sent
+func (b *RetryBudget) Retries() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.retries
+}
+
+// Spent returns the time spent waiting for and sending retries
+func (b *RetryBudget) Spent() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errRetriesSpent = errors.New("retry budget exhausted")
+
+func TestRetryBudget(t *testing.T) {
+	t.Run("stops retrying across requests once the retries are spent", func(t *testing.T) {
+		// Arrange
+		var calls int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&calls, 1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		budget := NewRetryBudget(4, 0, errRetriesSpent)
+		client := NewAPIClient(server.URL, WithMiddleware(budget.Middleware))
+
+		// Act
+		_, first := client.LookupLead("one@example.com")
+		_, second := client.LookupLead("two@example.com")
+		_, third := client.LookupLead("three@example.com")
+
+		// Assert
+		assert.NotErrorIs(t, first, errRetriesSpent)
+		assert.ErrorIs(t, second, errRetriesSpent)
+		assert.ErrorIs(t, third, errRetriesSpent)
+		assert.Equal(t, 4, budget.Retries())
+		assert.Equal(t, int64(6), atomic.LoadInt64(&calls), "one request and three retries, then one and one")
+	})
+
+	t.Run("stops once the time spent retrying is used up", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		budget := NewRetryBudget(0, 250*time.Millisecond, errRetriesSpent)
+		client := NewAPIClient(server.URL, WithMiddleware(budget.Middleware))
+
+		// Act
+		_, err := client.LookupLead("one@example.com")
+
+		// Assert
+		assert.ErrorIs(t, err, errRetriesSpent)
+		assert.Equal(t, 2, budget.Retries())
+		assert.GreaterOrEqual(t, budget.Spent(), 250*time.Millisecond)
+	})
+
+	t.Run("leaves requests that need no retries alone", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		budget := NewRetryBudget(1, 0, errRetriesSpent)
+		client := NewAPIClient(server.URL, WithMiddleware(budget.Middleware))
+
+		// Act
+		_, first := client.LookupLead("one@example.com")
+		_, second := client.LookupLead("two@example.com")
+
+		// Assert
+		assert.NoError(t, first)
+		assert.NoError(t, second)
+		assert.Zero(t, budget.Retries())
+	})
+}
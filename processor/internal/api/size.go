@@ -0,0 +1,68 @@
+package api
+
+import (
+	"code/internal/errcode"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxResponseSize is the largest response body clients accept unless
+// configured otherwise
+const DefaultMaxResponseSize = 16 << 20
+
+// MaxResponseSizeMiddleware fails responses whose body is bigger than limit
+// bytes with an *errcode.TooLargeError: at once when the Content-Length says
+// so, otherwise when reading passes the limit. Nothing beyond the limit is
+// read, so a misbehaving server can't make the client hold it in memory. A
+// limit of 0 or less accepts any size.
+func MaxResponseSizeMiddleware(limit int64) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || limit <= 0 {
+				return resp, err
+			}
+			if resp.ContentLength > limit {
+				resp.Body.Close()
+				return nil, &errcode.TooLargeError{Limit: limit}
+			}
+			resp.Body = &limitedBody{body: resp.Body, remaining: limit, limit: limit}
+			return resp, nil
+		})
+	}
+}
+
+// WithMaxResponseSize limits the size of the client's response bodies; see
+// MaxResponseSizeMiddleware
+func WithMaxResponseSize(limit int64) ClientOption {
+	return WithMiddleware(MaxResponseSizeMiddleware(limit))
+}
+
+// limitedBody reads a response body until more than remaining bytes have
+// come, then fails
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, &errcode.TooLargeError{Limit: b.limit}
+	}
+	// Read one byte past the limit, so a body of exactly the limit still ends
+	// with io.EOF
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), &errcode.TooLargeError{Limit: b.limit}
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"code/internal/errcode"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxResponseSizeMiddleware(t *testing.T) {
+	t.Run("fails a response whose Content-Length is over the limit", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"found":false,"padding":"` + strings.Repeat("x", 100) + `"}`))
+		}))
+		defer server.Close()
+		client := NewAPIClient(server.URL, WithMaxResponseSize(64))
+
+		// Act
+		_, err := client.LookupLead("jane@example.com")
+
+		// Assert
+		var tooLarge *errcode.TooLargeError
+		assert.True(t, errors.As(err, &tooLarge))
+		assert.Equal(t, int64(64), tooLarge.Limit)
+		assert.Equal(t, errcode.CodeAPI, errcode.Of(err))
+	})
+
+	t.Run("streams leads until a response passes the limit", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"leads":[`))
+			w.(http.Flusher).Flush()
+			for i := 0; i < 1000; i++ {
+				fmt.Fprintf(w, `{"id":"%d","email":"lead%d@example.com"},`, i, i)
+			}
+		}))
+		defer server.Close()
+		client := NewAPIClient(server.URL, WithMaxResponseSize(1024))
+
+		// Act
+		it := client.ExportLeads(PageOptions{})
+		count := 0
+		for it.Next() {
+			count++
+		}
+
+		// Assert
+		var tooLarge *errcode.TooLargeError
+		assert.True(t, errors.As(it.Err(), &tooLarge))
+		assert.Greater(t, count, 0)
+		assert.Less(t, count, 30)
+	})
+
+	t.Run("accepts a body of exactly the limit", func(t *testing.T) {
+		// Arrange
+		base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, ContentLength: -1, Body: io.NopCloser(strings.NewReader("12345"))}, nil
+		})
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+
+		// Act
+		resp, err := MaxResponseSizeMiddleware(5)(base).RoundTrip(req)
+		body, readErr := io.ReadAll(resp.Body)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, readErr)
+		assert.Equal(t, "12345", string(body))
+	})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestTimeout bounds each attempt of a request, reading its
+// response included
+const DefaultRequestTimeout = 5 * time.Second
+
+// WithRequestTimeout bounds each attempt of a request by timeout in place of
+// DefaultRequestTimeout; 0 leaves attempts unbounded
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(c *APIClient) {
+		c.requestTimeout = timeout
+	}
+}
+
+// timeoutTransport gives every round trip its own deadline. It sits below
+// the middleware chain, so waits between retries, such as a Retry-After,
+// don't count against it the way they would against http.Client.Timeout.
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The deadline stays until the body is closed, so reading it counts
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases a round trip's deadline once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
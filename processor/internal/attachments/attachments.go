@@ -0,0 +1,212 @@
+package attachments
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxSizeMB is the largest attachment uploaded unless max_size_mb says
+// otherwise
+const DefaultMaxSizeMB = 5
+
+// DefaultTypes are the media types attached unless types says otherwise
+var DefaultTypes = []string{"image/jpeg", "image/png", "image/webp", "application/pdf"}
+
+// Config holds the attachments settings of the config file
+type Config struct {
+	// Columns hold a URL or a file path per lead, e.g. "Photo URL" or
+	// "Business Card"
+	Columns []string `yaml:"columns"`
+
+	// MaxSizeMB is the largest file attached, in MiB (default 5)
+	MaxSizeMB int `yaml:"max_size_mb"`
+
+	// Types are the media types attached, e.g. image/png (default JPEG, PNG,
+	// WebP and PDF)
+	Types []string `yaml:"types"`
+
+	// PassThrough sends URLs for the CRM to fetch rather than downloading
+	// and uploading them; files are still uploaded
+	PassThrough bool `yaml:"pass_through"`
+}
+
+// Validate checks the size and types, normalising types
+func (c *Config) Validate() error {
+	if c.MaxSizeMB < 0 {
+		return fmt.Errorf("max_size_mb cannot be negative")
+	}
+	for i, column := range c.Columns {
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("columns: column %d has no name", i+1)
+		}
+	}
+	for i, mediaType := range c.Types {
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if !strings.Contains(mediaType, "/") {
+			return fmt.Errorf("types: %q is not a media type such as image/png", c.Types[i])
+		}
+		c.Types[i] = mediaType
+	}
+	return nil
+}
+
+// Uploader attaches the files named in a lead's attachment columns to the
+// lead once it was written. It is a processor.Sink.
+type Uploader struct {
+	client      processor.AttachClient
+	columns     []string
+	maxSize     int64
+	types       []string
+	passThrough bool
+	httpClient  *http.Client
+	baseDir     string
+}
+
+// NewUploader returns an uploader attaching through client. URLs are
+// downloaded with httpClient; relative file paths are read from baseDir,
+// usually the input file's directory.
+func NewUploader(cfg Config, client processor.AttachClient, httpClient *http.Client, baseDir string) (*Uploader, error) {
+	if len(cfg.Columns) == 0 {
+		return nil, fmt.Errorf("the config lists no attachments columns")
+	}
+	maxSizeMB := cfg.MaxSizeMB
+	if maxSizeMB == 0 {
+		maxSizeMB = DefaultMaxSizeMB
+	}
+	types := cfg.Types
+	if len(types) == 0 {
+		types = DefaultTypes
+	}
+	return &Uploader{
+		client:      client,
+		columns:     cfg.Columns,
+		maxSize:     int64(maxSizeMB) << 20,
+		types:       types,
+		passThrough: cfg.PassThrough,
+		httpClient:  httpClient,
+		baseDir:     baseDir,
+	}, nil
+}
+
+// Name names the uploader in sync errors
+func (u *Uploader) Name() string {
+	return "attachments"
+}
+
+// Send attaches each file the lead's row names. Every column is tried; the
+// failures are returned together.
+func (u *Uploader) Send(lead *models.Lead) error {
+	var errs []error
+	for _, column := range u.columns {
+		location, _ := lead.RawValue(column)
+		location = strings.TrimSpace(location)
+		if location == "" {
+			continue
+		}
+		attachment, err := u.load(location)
+		if err == nil {
+			err = u.client.AttachToLead(lead.ID, attachment)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", column, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// load reads the attachment at location, a URL or a file path, checking its
+// size and type. A URL passed through is only checked to be one.
+func (u *Uploader) load(location string) (*models.Attachment, error) {
+	if isURL(location) {
+		parsed, _ := url.Parse(location)
+		name := path.Base(parsed.Path)
+		if u.passThrough {
+			return &models.Attachment{Name: name, URL: location}, nil
+		}
+		data, err := u.download(location)
+		if err != nil {
+			return nil, err
+		}
+		return u.check(&models.Attachment{Name: name, URL: location, Data: data})
+	}
+
+	file := location
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(u.baseDir, file)
+	}
+	data, err := u.readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return u.check(&models.Attachment{Name: filepath.Base(file), Data: data})
+}
+
+func (u *Uploader) download(location string) ([]byte, error) {
+	resp, err := u.httpClient.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download: status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > u.maxSize {
+		return nil, u.tooLarge()
+	}
+	return u.readAll(resp.Body)
+}
+
+func (u *Uploader) readFile(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return u.readAll(f)
+}
+
+// readAll reads r, failing without reading on once it passes the size limit
+func (u *Uploader) readAll(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > u.maxSize {
+		return nil, u.tooLarge()
+	}
+	return data, nil
+}
+
+func (u *Uploader) tooLarge() error {
+	return fmt.Errorf("larger than %d MiB", u.maxSize>>20)
+}
+
+// check sets the attachment's type from its content, which unlike a file
+// extension or a server's Content-Type can't be wrong, and rejects the
+// types not allowed
+func (u *Uploader) check(attachment *models.Attachment) (*models.Attachment, error) {
+	mediaType := http.DetectContentType(attachment.Data)
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	if !slices.Contains(u.types, mediaType) {
+		return nil, fmt.Errorf("%s is not an allowed type (allowed: %s)", mediaType, strings.Join(u.types, ", "))
+	}
+	attachment.ContentType = mediaType
+	return attachment, nil
+}
+
+func isURL(location string) bool {
+	lower := strings.ToLower(location)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
@@ -0,0 +1,140 @@
+package attachments
+
+import (
+	"code/internal/models"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader is enough of a PNG for its type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// fakeAttacher records the attachments it is given
+type fakeAttacher struct {
+	ids         []string
+	attachments []*models.Attachment
+	err         error
+}
+
+func (f *fakeAttacher) AttachToLead(id string, attachment *models.Attachment) error {
+	f.ids = append(f.ids, id)
+	f.attachments = append(f.attachments, attachment)
+	return f.err
+}
+
+func newLead(raw map[string]string) *models.Lead {
+	lead := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+	lead.ID = "lead-1"
+	lead.Raw = raw
+	return lead
+}
+
+func TestUploader_Send(t *testing.T) {
+	t.Run("downloads a URL and uploads it with its detected type", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pngHeader)
+		}))
+		defer server.Close()
+		attacher := &fakeAttacher{}
+		uploader, _ := NewUploader(Config{Columns: []string{"Photo URL"}}, attacher, server.Client(), t.TempDir())
+
+		// Act
+		err := uploader.Send(newLead(map[string]string{"photo url": server.URL + "/photos/jane.png"}))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"lead-1"}, attacher.ids)
+		assert.Equal(t, "jane.png", attacher.attachments[0].Name)
+		assert.Equal(t, "image/png", attacher.attachments[0].ContentType)
+		assert.Equal(t, pngHeader, attacher.attachments[0].Data)
+	})
+
+	t.Run("passes URLs through without downloading them", func(t *testing.T) {
+		// Arrange
+		attacher := &fakeAttacher{}
+		uploader, _ := NewUploader(Config{Columns: []string{"Photo URL"}, PassThrough: true}, attacher, nil, t.TempDir())
+
+		// Act
+		err := uploader.Send(newLead(map[string]string{"Photo URL": "https://media.example.com/jane.jpg"}))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "https://media.example.com/jane.jpg", attacher.attachments[0].URL)
+		assert.Nil(t, attacher.attachments[0].Data)
+	})
+
+	t.Run("reads relative paths from the base directory", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "card.pdf"), []byte("%PDF-1.7\n"), 0o644)
+		attacher := &fakeAttacher{}
+		uploader, _ := NewUploader(Config{Columns: []string{"Business Card"}}, attacher, nil, dir)
+
+		// Act
+		err := uploader.Send(newLead(map[string]string{"Business Card": "card.pdf"}))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "card.pdf", attacher.attachments[0].Name)
+		assert.Equal(t, "application/pdf", attacher.attachments[0].ContentType)
+	})
+
+	t.Run("rejects files over the size limit or of other types", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "big.png"), append(pngHeader, make([]byte, 1<<20)...), 0o644)
+		os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("call back on Monday"), 0o644)
+		attacher := &fakeAttacher{}
+		uploader, _ := NewUploader(Config{Columns: []string{"Photo", "Card"}, MaxSizeMB: 1}, attacher, nil, dir)
+
+		// Act
+		err := uploader.Send(newLead(map[string]string{"Photo": "big.png", "Card": "notes.txt"}))
+
+		// Assert
+		assert.ErrorContains(t, err, "Photo: larger than 1 MiB")
+		assert.ErrorContains(t, err, "Card: text/plain is not an allowed type")
+		assert.Empty(t, attacher.ids)
+	})
+
+	t.Run("skips empty columns and reports upload failures", func(t *testing.T) {
+		// Arrange
+		attacher := &fakeAttacher{err: errors.New("API error: status 413")}
+		uploader, _ := NewUploader(Config{Columns: []string{"Photo URL", "Card"}, PassThrough: true}, attacher, nil, t.TempDir())
+
+		// Act
+		err := uploader.Send(newLead(map[string]string{"Photo URL": "https://media.example.com/jane.jpg", "Card": " "}))
+
+		// Assert
+		assert.Len(t, attacher.ids, 1)
+		assert.True(t, strings.HasPrefix(err.Error(), "Photo URL: API error"))
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("normalises types", func(t *testing.T) {
+		// Arrange
+		cfg := Config{Columns: []string{"Photo"}, Types: []string{" Image/PNG "}}
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"image/png"}, cfg.Types)
+	})
+
+	t.Run("rejects bad settings", func(t *testing.T) {
+		assert.Error(t, (&Config{MaxSizeMB: -1}).Validate())
+		assert.Error(t, (&Config{Columns: []string{""}}).Validate())
+		assert.Error(t, (&Config{Types: []string{"png"}}).Validate())
+	})
+}
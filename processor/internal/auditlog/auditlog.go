@@ -0,0 +1,50 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Log is an append-only NDJSON file of audit records of type R, one per line
+type Log[R any] struct {
+	mu   sync.Mutex
+	file *os.File
+	sync bool
+}
+
+// Open opens (creating if needed) the audit log at path for appending. With
+// sync, each record is synced to disk before Append returns, for logs of
+// actions that can't be undone.
+func Open[R any](path string, sync bool) (*Log[R], error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Log[R]{file: file, sync: sync}, nil
+}
+
+// Append writes a record
+func (l *Log[R]) Append(record R) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if l.sync {
+		return l.file.Sync()
+	}
+	return nil
+}
+
+// Close closes the audit log
+func (l *Log[R]) Close() error {
+	return l.file.Close()
+}
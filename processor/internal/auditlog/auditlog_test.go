@@ -0,0 +1,79 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	ID    int    `json:"id"`
+	Actor string `json:"actor"`
+}
+
+func TestLog(t *testing.T) {
+	t.Run("appends one record per line across reopens", func(t *testing.T) {
+		for _, synced := range []bool{false, true} {
+			// Arrange
+			path := filepath.Join(t.TempDir(), "audit.ndjson")
+
+			// Act
+			for i := range 2 {
+				log, err := Open[record](path, synced)
+				assert.NoError(t, err)
+				assert.NoError(t, log.Append(record{ID: i, Actor: "dpo"}))
+				assert.NoError(t, log.Close())
+			}
+
+			// Assert
+			assert.Equal(t, []record{{ID: 0, Actor: "dpo"}, {ID: 1, Actor: "dpo"}}, readLog(t, path))
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		}
+	})
+
+	t.Run("keeps records whole when appended concurrently", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "audit.ndjson")
+		log, err := Open[record](path, false)
+		assert.NoError(t, err)
+		var wg sync.WaitGroup
+
+		// Act
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, log.Append(record{ID: i}))
+			}()
+		}
+		wg.Wait()
+		log.Close()
+
+		// Assert
+		assert.Len(t, readLog(t, path), 50)
+	})
+}
+
+func readLog(t *testing.T, path string) []record {
+	t.Helper()
+	file, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+	var records []record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	return records
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"code/internal/auditlog"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Record is one entry in the access audit log: a refused request, or one
+// that changed something, such as an upload
+type Record struct {
+	At time.Time `json:"at"`
+	// Token names the caller's token; it is empty when no valid token was
+	// given
+	Token  string `json:"token,omitempty"`
+	Role   Role   `json:"role,omitempty"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Status int    `json:"status"`
+	// ImportID is the import the request submitted or reviewed
+	ImportID string `json:"importId,omitempty"`
+	// Review is the decision the request made on an import pending review;
+	// Token names the reviewer
+	Review string `json:"review,omitempty"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// AuditLog is an append-only NDJSON file of access records. Unlike erasure
+// and merge records, they aren't synced one by one, as every request that
+// changes something writes one.
+type AuditLog struct {
+	records *auditlog.Log[Record]
+}
+
+// OpenAuditLog opens (creating if needed) the audit log at path for appending
+func OpenAuditLog(path string) (*AuditLog, error) {
+	records, err := auditlog.Open[Record](path, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access audit log: %w", err)
+	}
+	return &AuditLog{records: records}, nil
+}
+
+// Append writes a record. A failure is logged rather than failing the
+// request, which has already been answered.
+func (a *AuditLog) Append(record Record) {
+	if err := a.records.Append(record); err != nil {
+		log.Printf("Failed to write audit record: %v", err)
+	}
+}
+
+// Close closes the audit log
+func (a *AuditLog) Close() error {
+	return a.records.Close()
+}
@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role is what a token may do; each role may also do what the roles below
+// it may
+type Role string
+
+// Roles, from least to most trusted
+const (
+	// RoleViewer may list imports and see the dashboard
+	RoleViewer Role = "viewer"
+	// RoleUploader may also submit imports
+	RoleUploader Role = "uploader"
+	// RoleReviewer may also approve or reject imports pending review
+	RoleReviewer Role = "reviewer"
+	// RoleAdmin may also use the debugging endpoints
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleUploader: 2, RoleReviewer: 3, RoleAdmin: 4}
+
+// Allows reports whether the role includes the required one
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// Token is a bearer token the server accepts
+type Token struct {
+	// Name identifies the token's holder in the audit log
+	Name string `yaml:"name"`
+	// Token is the secret itself, or a reference to it such as
+	// vault://secret/data/lead-processor#admin; prefer TokenEnv or a
+	// reference to keep it out of the config file
+	Token string `yaml:"token"`
+	// TokenEnv names the environment variable holding the secret
+	TokenEnv string `yaml:"token_env"`
+	Role     Role   `yaml:"role"`
+	// RequestsPerSecond caps the token's requests; 0 leaves them uncapped
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Burst is how many requests may go at once before the cap applies; 0
+	// means 1
+	Burst int `yaml:"burst"`
+	// Quota caps what the token's imports may use
+	Quota Quota `yaml:"quota"`
+}
+
+// Quota caps what the imports of one token, or tenant, may use, so one
+// team's large import can't starve the others. Zero leaves a limit off.
+type Quota struct {
+	// MaxConcurrentImports caps the token's imports running at once
+	MaxConcurrentImports int `yaml:"max_concurrent_imports"`
+	// DailyLeads caps the leads the token's imports process per UTC day
+	DailyLeads int `yaml:"daily_leads"`
+	// CRMRequestsPerSecond paces the CRM requests of the token's imports
+	CRMRequestsPerSecond float64 `yaml:"crm_requests_per_second"`
+}
+
+// IsZero reports whether the quota sets no limit
+func (q Quota) IsZero() bool {
+	return q == Quota{}
+}
+
+// Config lists the tokens of serve mode; without any, the server is open
+type Config struct {
+	Tokens []Token `yaml:"tokens"`
+	// TypeformSecret and FormsToken authenticate the form webhooks when
+	// --typeform-secret and --forms-token aren't given
+	TypeformSecret string `yaml:"typeform_secret"`
+	FormsToken     string `yaml:"forms_token"`
+}
+
+// Validate rejects tokens without a name, role or secret, and duplicate names
+func (c Config) Validate() error {
+	names := make(map[string]bool, len(c.Tokens))
+	for i, token := range c.Tokens {
+		if strings.TrimSpace(token.Name) == "" {
+			return fmt.Errorf("tokens[%d]: name is required", i)
+		}
+		if names[token.Name] {
+			return fmt.Errorf("tokens[%d]: duplicate name %q", i, token.Name)
+		}
+		names[token.Name] = true
+		if _, ok := roleRanks[token.Role]; !ok {
+			return fmt.Errorf("tokens[%d]: unknown role %q (allowed: viewer, uploader, reviewer, admin)", i, token.Role)
+		}
+		if (token.Token == "") == (token.TokenEnv == "") {
+			return fmt.Errorf("tokens[%d]: set one of token or token_env", i)
+		}
+		if token.RequestsPerSecond < 0 || token.Burst < 0 {
+			return fmt.Errorf("tokens[%d]: limits cannot be negative", i)
+		}
+		if token.Quota.MaxConcurrentImports < 0 || token.Quota.DailyLeads < 0 || token.Quota.CRMRequestsPerSecond < 0 {
+			return fmt.Errorf("tokens[%d]: quota cannot be negative", i)
+		}
+	}
+	return nil
+}
+
+// Quotas returns the quota of each token that has one, by token name
+func (c Config) Quotas() map[string]Quota {
+	quotas := make(map[string]Quota)
+	for _, token := range c.Tokens {
+		if !token.Quota.IsZero() {
+			quotas[token.Name] = token.Quota
+		}
+	}
+	return quotas
+}
+
+// Caller is the holder of the token a request was authenticated with
+type Caller struct {
+	Name string
+	Role Role
+}
+
+type callerKey struct{}
+
+// CallerFrom returns the caller of an authenticated request
+func CallerFrom(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// credential is a token with its secret resolved and hashed, so secrets of
+// different lengths compare in the same time
+type credential struct {
+	caller  Caller
+	hash    [32]byte
+	limiter *limiter
+}
+
+// Authenticator checks the tokens and roles of requests, limits each token's
+// request rate and audits what each caller did
+type Authenticator struct {
+	credentials []credential
+	audit       *AuditLog
+}
+
+// New resolves the tokens' secrets. audit may be nil.
+func New(cfg Config, audit *AuditLog) (*Authenticator, error) {
+	a := &Authenticator{audit: audit}
+	for _, token := range cfg.Tokens {
+		secret := token.Token
+		if token.TokenEnv != "" {
+			secret = os.Getenv(token.TokenEnv)
+			if secret == "" {
+				return nil, fmt.Errorf("token %s: $%s is not set", token.Name, token.TokenEnv)
+			}
+		}
+		cred := credential{caller: Caller{Name: token.Name, Role: token.Role}, hash: sha256.Sum256([]byte(secret))}
+		if token.RequestsPerSecond > 0 {
+			cred.limiter = newLimiter(token.RequestsPerSecond, token.Burst)
+		}
+		a.credentials = append(a.credentials, cred)
+	}
+	return a, nil
+}
+
+// Enabled reports whether any tokens are configured; without them every
+// request is let through
+func (a *Authenticator) Enabled() bool {
+	return a != nil && len(a.credentials) > 0
+}
+
+// Require wraps a handler so only tokens with the role may call it. The
+// token is read from "Authorization: Bearer", or from the password of basic
+// auth so browsers can open the dashboard. Refused requests and requests
+// that change something are audited.
+func (a *Authenticator) Require(role Role, next http.HandlerFunc) http.HandlerFunc {
+	if !a.Enabled() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		cred, ok := a.find(secret(r))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="lead-processor"`)
+			a.refuse(w, r, Caller{}, http.StatusUnauthorized, "a valid token is required")
+			return
+		}
+		if !cred.caller.Role.Allows(role) {
+			a.refuse(w, r, cred.caller, http.StatusForbidden, fmt.Sprintf("the %s role is required", role))
+			return
+		}
+		if cred.limiter != nil {
+			if wait := cred.limiter.reserve(time.Now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				a.refuse(w, r, cred.caller, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), callerKey{}, cred.caller))
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		a.record(r, cred.caller, recorder.status, recorder.Header().Get(ImportIDHeader), recorder.Header().Get(ReviewHeader))
+	}
+}
+
+// ImportIDHeader carries the ID of the import a request submitted or
+// reviewed, so the audit log can name it
+const ImportIDHeader = "X-Import-Id"
+
+// ReviewHeader carries the decision a request made on an import pending
+// review, approved or rejected, so the audit log can record it
+const ReviewHeader = "X-Import-Review"
+
+func (a *Authenticator) find(given string) (credential, bool) {
+	if given == "" {
+		return credential{}, false
+	}
+	hash := sha256.Sum256([]byte(given))
+	found := -1
+	// Every token is compared, so the time taken doesn't tell which matched
+	for i := range a.credentials {
+		if subtle.ConstantTimeCompare(hash[:], a.credentials[i].hash[:]) == 1 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return credential{}, false
+	}
+	return a.credentials[found], true
+}
+
+func (a *Authenticator) refuse(w http.ResponseWriter, r *http.Request, caller Caller, status int, message string) {
+	a.record(r, caller, status, "", "")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "{\"error\":%q}\n", message)
+}
+
+func (a *Authenticator) record(r *http.Request, caller Caller, status int, importID, review string) {
+	if a.audit == nil {
+		return
+	}
+	a.audit.Append(Record{
+		At:       time.Now().UTC(),
+		Token:    caller.Name,
+		Role:     caller.Role,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Query:    r.URL.RawQuery,
+		Status:   status,
+		ImportID: importID,
+		Review:   review,
+		Remote:   r.RemoteAddr,
+	})
+}
+
+// secret returns the token a request carries
+func secret(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	return ""
+}
+
+// statusRecorder keeps the status a handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// limiter is a token bucket refilled at a token's rate; a request finding it
+// empty is refused rather than held
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	b := float64(burst)
+	if b < 1 {
+		b = 1
+	}
+	return &limiter{rate: rate, burst: b, tokens: b}
+}
+
+// reserve takes a token if there is one, or returns how long until there is
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	l.tokens--
+	return 0
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("accepts named tokens with a role and a secret", func(t *testing.T) {
+		// Act
+		err := Config{Tokens: []Token{
+			{Name: "ops", Token: "s3cret", Role: RoleAdmin},
+			{Name: "marketing", TokenEnv: "MARKETING_TOKEN", Role: RoleUploader, RequestsPerSecond: 1},
+		}}.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects incomplete tokens", func(t *testing.T) {
+		// Act
+		noSecret := Config{Tokens: []Token{{Name: "ops", Role: RoleAdmin}}}.Validate()
+		bothSecrets := Config{Tokens: []Token{{Name: "ops", Token: "a", TokenEnv: "B", Role: RoleAdmin}}}.Validate()
+		duplicate := Config{Tokens: []Token{{Name: "ops", Token: "a", Role: RoleAdmin}, {Name: "ops", Token: "b", Role: RoleViewer}}}.Validate()
+
+		// Assert
+		assert.EqualError(t, noSecret, "tokens[0]: set one of token or token_env")
+		assert.EqualError(t, bothSecrets, "tokens[0]: set one of token or token_env")
+		assert.EqualError(t, duplicate, `tokens[1]: duplicate name "ops"`)
+	})
+
+	t.Run("rejects negative quotas and lists the quotas set", func(t *testing.T) {
+		// Arrange
+		cfg := Config{Tokens: []Token{
+			{Name: "ops", Token: "s3cret", Role: RoleAdmin},
+			{Name: "emea", Token: "e", Role: RoleUploader, Quota: Quota{MaxConcurrentImports: 1, DailyLeads: 50000}},
+		}}
+
+		// Act
+		err := cfg.Validate()
+		negative := Config{Tokens: []Token{{Name: "emea", Token: "e", Role: RoleUploader, Quota: Quota{DailyLeads: -1}}}}.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Quota{"emea": {MaxConcurrentImports: 1, DailyLeads: 50000}}, cfg.Quotas())
+		assert.EqualError(t, negative, "tokens[0]: quota cannot be negative")
+	})
+}
+
+func newTestAuthenticator(t *testing.T, tokens ...Token) (*Authenticator, string) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	audit, err := OpenAuditLog(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { audit.Close() })
+	a, err := New(Config{Tokens: tokens}, audit)
+	assert.NoError(t, err)
+	return a, path
+}
+
+func readAuditLog(t *testing.T, path string) []Record {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func call(handler http.HandlerFunc, method, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/imports", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestAuthenticator_Require(t *testing.T) {
+	upload := func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := CallerFrom(r.Context())
+		w.Header().Set(ImportIDHeader, "import-1")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(caller.Name))
+	}
+
+	t.Run("checks tokens and roles, auditing uploads and refusals", func(t *testing.T) {
+		// Arrange
+		a, path := newTestAuthenticator(t,
+			Token{Name: "marketing", Token: "up", Role: RoleUploader},
+			Token{Name: "analyst", Token: "view", Role: RoleViewer},
+		)
+		handler := a.Require(RoleUploader, upload)
+
+		// Act
+		accepted := call(handler, http.MethodPost, "up")
+		forbidden := call(handler, http.MethodPost, "view")
+		unauthorized := call(handler, http.MethodPost, "wrong")
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, accepted.Code)
+		assert.Equal(t, "marketing", accepted.Body.String())
+		assert.Equal(t, http.StatusForbidden, forbidden.Code)
+		assert.Equal(t, http.StatusUnauthorized, unauthorized.Code)
+		assert.Contains(t, unauthorized.Header().Get("WWW-Authenticate"), "Basic")
+		records := readAuditLog(t, path)
+		assert.Len(t, records, 3)
+		assert.WithinDuration(t, time.Now(), records[0].At, time.Minute)
+		records[0].At = time.Time{}
+		assert.Equal(t, Record{Token: "marketing", Role: RoleUploader, Method: "POST", Path: "/imports", Status: http.StatusAccepted, ImportID: "import-1", Remote: "192.0.2.1:1234"}, records[0])
+		assert.Equal(t, "analyst", records[1].Token)
+		assert.Equal(t, http.StatusForbidden, records[1].Status)
+		assert.Equal(t, "", records[2].Token)
+	})
+
+	t.Run("lets higher roles through and accepts basic auth", func(t *testing.T) {
+		// Arrange
+		a, path := newTestAuthenticator(t, Token{Name: "ops", Token: "adm1n", Role: RoleAdmin})
+		req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+		req.SetBasicAuth("anyone", "adm1n")
+		rec := httptest.NewRecorder()
+
+		// Act
+		a.Require(RoleViewer, upload)(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Empty(t, readAuditLog(t, path), "reads aren't audited")
+	})
+
+	t.Run("limits each token's request rate", func(t *testing.T) {
+		// Arrange
+		a, _ := newTestAuthenticator(t,
+			Token{Name: "marketing", Token: "up", Role: RoleUploader, RequestsPerSecond: 0.5, Burst: 2},
+			Token{Name: "ops", Token: "adm1n", Role: RoleAdmin},
+		)
+		handler := a.Require(RoleUploader, upload)
+
+		// Act
+		first := call(handler, http.MethodPost, "up")
+		second := call(handler, http.MethodPost, "up")
+		limited := call(handler, http.MethodPost, "up")
+		other := call(handler, http.MethodPost, "adm1n")
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, first.Code)
+		assert.Equal(t, http.StatusAccepted, second.Code)
+		assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+		assert.Equal(t, "2", limited.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusAccepted, other.Code)
+	})
+
+	t.Run("lets everything through without tokens", func(t *testing.T) {
+		// Arrange
+		a, _ := New(Config{}, nil)
+
+		// Act
+		rec := call(a.Require(RoleAdmin, upload), http.MethodPost, "")
+
+		// Assert
+		assert.False(t, a.Enabled())
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("requires token_env to be set", func(t *testing.T) {
+		// Arrange
+		t.Setenv("LP_TEST_TOKEN", "")
+
+		// Act
+		_, err := New(Config{Tokens: []Token{{Name: "ci", TokenEnv: "LP_TEST_TOKEN", Role: RoleUploader}}}, nil)
+
+		// Assert
+		assert.EqualError(t, err, "token ci: $LP_TEST_TOKEN is not set")
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("refills at the token's rate", func(t *testing.T) {
+		// Arrange
+		l := newLimiter(2, 1)
+		now := time.Now()
+
+		// Act
+		first := l.reserve(now)
+		refused := l.reserve(now)
+		later := l.reserve(now.Add(time.Second))
+
+		// Assert
+		assert.Equal(t, time.Duration(0), first)
+		assert.Equal(t, 500*time.Millisecond, refused)
+		assert.Equal(t, time.Duration(0), later)
+	})
+}
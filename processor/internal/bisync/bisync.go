@@ -0,0 +1,297 @@
+package bisync
+
+import (
+	"code/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Side is where a synced value comes from
+type Side string
+
+const (
+	// SideCRM keeps the CRM's value
+	SideCRM Side = "crm"
+	// SideFile takes the file's value
+	SideFile Side = "file"
+)
+
+// ParseSide parses a conflict rule
+func ParseSide(value string) (Side, error) {
+	switch Side(strings.ToLower(strings.TrimSpace(value))) {
+	case SideCRM:
+		return SideCRM, nil
+	case SideFile:
+		return SideFile, nil
+	}
+	return "", fmt.Errorf("invalid sync rule %q (use %s or %s)", value, SideCRM, SideFile)
+}
+
+// Policy maps fields, by JSON name, to the side that wins when both changed
+// a field since the last sync. Fields it doesn't mention keep the CRM's value.
+type Policy map[string]Side
+
+// ParsePolicy parses field=side pairs, e.g. from --prefer or the config's
+// sync_precedence
+func ParsePolicy(values map[string]string) (Policy, error) {
+	policy := make(Policy, len(values))
+	for field, value := range values {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if !isSyncedField(name) {
+			return nil, fmt.Errorf("field %q cannot be synced (allowed: %s)", field, strings.Join(models.ProtectableFields(), ", "))
+		}
+		side, err := ParseSide(value)
+		if err != nil {
+			return nil, err
+		}
+		policy[name] = side
+	}
+	return policy, nil
+}
+
+func isSyncedField(field string) bool {
+	for _, name := range models.ProtectableFields() {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Pair is a lead as the file and the CRM last had it, the base changes on
+// either side are found against
+type Pair struct {
+	File *models.Lead `json:"file,omitempty"`
+	CRM  *models.Lead `json:"crm,omitempty"`
+}
+
+// State is what the previous sync saw on each side, kept in a JSON file
+// between syncs
+type State struct {
+	path string
+	// Schema is the models.LeadSchema the leads were stored at
+	Schema   int              `json:"schema"`
+	SyncedAt time.Time        `json:"syncedAt"`
+	Leads    map[string]*Pair `json:"leads"`
+}
+
+// storedState is a state whose leads are decoded once its schema is known
+type storedState struct {
+	Schema   int       `json:"schema"`
+	SyncedAt time.Time `json:"syncedAt"`
+	Leads    map[string]struct {
+		File json.RawMessage `json:"file"`
+		CRM  json.RawMessage `json:"crm"`
+	} `json:"leads"`
+}
+
+// OpenState loads the sync state at path, starting empty if the file does
+// not exist
+func OpenState(path string) (*State, error) {
+	state := &State{path: path, Leads: make(map[string]*Pair)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if len(data) == 0 {
+		return state, nil
+	}
+
+	var stored storedState
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode sync state %s: %w", path, err)
+	}
+	if err := models.CheckSchema(stored.Schema); err != nil {
+		return nil, fmt.Errorf("sync state %s: %w", path, err)
+	}
+	state.SyncedAt = stored.SyncedAt
+	for email, leads := range stored.Leads {
+		file, err := models.DecodeLead(leads.File, stored.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode sync state %s: %w", path, err)
+		}
+		crm, err := models.DecodeLead(leads.CRM, stored.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode sync state %s: %w", path, err)
+		}
+		state.Leads[email] = &Pair{File: file, CRM: crm}
+	}
+	return state, nil
+}
+
+// Get returns the base of the lead with the email, or an empty pair
+func (s *State) Get(email string) Pair {
+	if pair, ok := s.Leads[Key(email)]; ok {
+		return *pair
+	}
+	return Pair{}
+}
+
+// Set records what each side has for a lead after a sync; a nil side keeps
+// its previous base
+func (s *State) Set(email string, file, crm *models.Lead) {
+	k := Key(email)
+	pair, ok := s.Leads[k]
+	if !ok {
+		pair = &Pair{}
+		s.Leads[k] = pair
+	}
+	if file != nil {
+		pair.File = fieldsOnly(file)
+	}
+	if crm != nil {
+		pair.CRM = fieldsOnly(crm)
+	}
+}
+
+// Save writes the state back to disk
+func (s *State) Save() error {
+	s.Schema = models.LeadSchema
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// fieldsOnly copies the synced fields of lead
+func fieldsOnly(lead *models.Lead) *models.Lead {
+	copied := &models.Lead{Email: lead.Email}
+	for _, field := range models.ProtectableFields() {
+		value, _ := lead.Field(field)
+		copied.SetField(field, value)
+	}
+	return copied
+}
+
+// Key identifies a lead on both sides: its email, trimmed and lowercased
+func Key(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Conflict is a field both sides changed to different values since the
+// last sync
+type Conflict struct {
+	Field  string
+	File   string
+	CRM    string
+	Winner Side
+}
+
+// Resolution is what a sync does with a lead of the file
+type Resolution struct {
+	// Lead is the lead to write to the CRM: the CRM's copy with the file's
+	// changes applied, or the file's lead when the CRM doesn't have it. It
+	// is nil when the CRM already has every value.
+	Lead *models.Lead
+	// Conflicts lists the fields both sides changed
+	Conflicts []Conflict
+}
+
+// Reconcile works out how a lead of the file and the CRM's copy, nil if
+// the CRM doesn't have it, are combined. A field changed on one side since
+// base takes that side's value; a field changed on both to different values
+// is a conflict decided by policy. Without a base, as on the first sync,
+// every field that differs is a conflict. A blank status in the file, as
+// in files without a status column, is no change.
+func Reconcile(base Pair, file, crm *models.Lead, policy Policy) Resolution {
+	if crm == nil {
+		created := *file
+		return Resolution{Lead: &created}
+	}
+
+	merged := *crm
+	merged.Raw = file.Raw
+	var resolution Resolution
+	changed := false
+	for _, field := range models.ProtectableFields() {
+		fileValue, _ := file.Field(field)
+		crmValue, _ := crm.Field(field)
+		if fileValue == crmValue || (field == "status" && fileValue == "") {
+			continue
+		}
+
+		fileChanged := base.File == nil || fileValue != fieldValue(base.File, field)
+		crmChanged := base.CRM == nil || crmValue != fieldValue(base.CRM, field)
+		winner := SideCRM
+		switch {
+		case fileChanged && crmChanged:
+			if policy[field] == SideFile {
+				winner = SideFile
+			}
+			resolution.Conflicts = append(resolution.Conflicts, Conflict{Field: field, File: fileValue, CRM: crmValue, Winner: winner})
+		case fileChanged:
+			winner = SideFile
+		}
+		if winner == SideFile {
+			merged.SetField(field, fileValue)
+			changed = true
+		}
+	}
+	if changed {
+		resolution.Lead = &merged
+	}
+	return resolution
+}
+
+// Protect keeps the CRM's values of protected fields, which the file may
+// set when a lead is created but never change
+func (r *Resolution) Protect(crm *models.Lead, fields []string) {
+	if r.Lead == nil || crm == nil {
+		return
+	}
+	for _, field := range fields {
+		r.Lead.SetField(field, fieldValue(crm, field))
+		for i := range r.Conflicts {
+			if r.Conflicts[i].Field == field {
+				r.Conflicts[i].Winner = SideCRM
+			}
+		}
+	}
+	if Agree(r.Lead, crm) {
+		r.Lead = nil
+	}
+}
+
+// CRMChanged reports whether the CRM's copy of a lead differs from base
+func CRMChanged(base Pair, crm *models.Lead) bool {
+	if base.CRM == nil {
+		return true
+	}
+	for _, field := range models.ProtectableFields() {
+		value, _ := crm.Field(field)
+		if value != fieldValue(base.CRM, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Agree reports whether the CRM's copy of a lead has the file's values. A
+// blank status in the file agrees with any.
+func Agree(file, crm *models.Lead) bool {
+	for _, field := range models.ProtectableFields() {
+		fileValue := fieldValue(file, field)
+		if fileValue != fieldValue(crm, field) && !(field == "status" && fileValue == "") {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldValue(lead *models.Lead, field string) string {
+	value, _ := lead.Field(field)
+	return value
+}
@@ -0,0 +1,228 @@
+package bisync
+
+import (
+	"code/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicy(t *testing.T) {
+	t.Run("parses sides per field", func(t *testing.T) {
+		// Act
+		policy, err := ParsePolicy(map[string]string{"Status": "file", "name": " CRM "})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Policy{"status": SideFile, "name": SideCRM}, policy)
+	})
+
+	t.Run("rejects unknown fields and sides", func(t *testing.T) {
+		// Act
+		_, fieldErr := ParsePolicy(map[string]string{"id": "file"})
+		_, sideErr := ParsePolicy(map[string]string{"name": "newest"})
+
+		// Assert
+		assert.ErrorContains(t, fieldErr, `field "id" cannot be synced`)
+		assert.ErrorContains(t, sideErr, `invalid sync rule "newest"`)
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	base := Pair{
+		File: &models.Lead{Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "new"},
+		CRM:  &models.Lead{Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "new"},
+	}
+
+	t.Run("takes each side's own changes", func(t *testing.T) {
+		// Arrange
+		file := &models.Lead{Email: "jane@techfirm.com", Name: "Jane Doe", Company: "TechFirm", Status: "new"}
+		crm := &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm Inc", Status: "new"}
+
+		// Act
+		resolution := Reconcile(base, file, crm, nil)
+
+		// Assert
+		assert.Empty(t, resolution.Conflicts)
+		assert.Equal(t, "1", resolution.Lead.ID)
+		assert.Equal(t, "Jane Doe", resolution.Lead.Name)
+		assert.Equal(t, "TechFirm Inc", resolution.Lead.Company)
+	})
+
+	t.Run("needs no write when only the CRM changed", func(t *testing.T) {
+		// Arrange
+		file := &models.Lead{Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm"}
+		crm := &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "qualified"}
+
+		// Act
+		resolution := Reconcile(base, file, crm, nil)
+
+		// Assert
+		assert.Nil(t, resolution.Lead)
+		assert.Empty(t, resolution.Conflicts)
+	})
+
+	t.Run("decides conflicts by policy", func(t *testing.T) {
+		// Arrange
+		file := &models.Lead{Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "contacted"}
+		crm := &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "qualified"}
+
+		// Act
+		crmWins := Reconcile(base, file, crm, nil)
+		fileWins := Reconcile(base, file, crm, Policy{"status": SideFile})
+
+		// Assert
+		assert.Nil(t, crmWins.Lead)
+		assert.Equal(t, []Conflict{{Field: "status", File: "contacted", CRM: "qualified", Winner: SideCRM}}, crmWins.Conflicts)
+		assert.Equal(t, "contacted", fileWins.Lead.Status)
+		assert.Equal(t, SideFile, fileWins.Conflicts[0].Winner)
+	})
+
+	t.Run("treats every difference as a conflict without a base", func(t *testing.T) {
+		// Arrange
+		file := &models.Lead{Email: "jane@techfirm.com", Name: "Jane Doe", Company: "TechFirm"}
+		crm := &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm"}
+
+		// Act
+		resolution := Reconcile(Pair{}, file, crm, nil)
+
+		// Assert
+		assert.Nil(t, resolution.Lead)
+		assert.Len(t, resolution.Conflicts, 1)
+	})
+
+	t.Run("creates leads the CRM doesn't have", func(t *testing.T) {
+		// Arrange
+		file := &models.Lead{Email: "mia@labs.dev", Name: "Mia Chen"}
+
+		// Act
+		resolution := Reconcile(Pair{}, file, nil, nil)
+
+		// Assert
+		assert.Equal(t, "Mia Chen", resolution.Lead.Name)
+		assert.NotSame(t, file, resolution.Lead)
+	})
+
+	t.Run("keeps protected fields", func(t *testing.T) {
+		// Arrange
+		file := &models.Lead{Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "contacted"}
+		crm := &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "qualified"}
+		resolution := Reconcile(base, file, crm, Policy{"status": SideFile})
+
+		// Act
+		resolution.Protect(crm, []string{"status"})
+
+		// Assert
+		assert.Nil(t, resolution.Lead)
+		assert.Equal(t, SideCRM, resolution.Conflicts[0].Winner)
+	})
+}
+
+func TestState(t *testing.T) {
+	t.Run("saves and reopens the bases", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "state.json")
+		state, _ := OpenState(path)
+		state.Set("Jane@TechFirm.com", &models.Lead{Email: "jane@techfirm.com", Name: "Jane Doe"}, &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith"})
+		state.Set("jane@techfirm.com", nil, &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Doe"})
+
+		// Act
+		saveErr := state.Save()
+		reopened, openErr := OpenState(path)
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, openErr)
+		pair := reopened.Get("jane@techfirm.com")
+		assert.Equal(t, "Jane Doe", pair.File.Name)
+		assert.Equal(t, "Jane Doe", pair.CRM.Name)
+		assert.Empty(t, pair.CRM.ID)
+	})
+
+	t.Run("reads state stored before leads were versioned", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "state.json")
+		os.WriteFile(path, []byte(`{"syncedAt":"2026-10-01T09:00:00Z","leads":{"jane@techfirm.com":{"crm":{"email":"jane@techfirm.com","name":"Jane Smith"}}}}`), 0o600)
+
+		// Act
+		state, err := OpenState(path)
+
+		// Assert
+		assert.NoError(t, err)
+		pair := state.Get("jane@techfirm.com")
+		assert.Nil(t, pair.File)
+		assert.Equal(t, "Jane Smith", pair.CRM.Name)
+	})
+
+	t.Run("reports CRM changes against the base", func(t *testing.T) {
+		// Arrange
+		base := Pair{CRM: &models.Lead{Email: "jane@techfirm.com", Name: "Jane Smith"}}
+
+		// Act & Assert
+		assert.False(t, CRMChanged(base, &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith"}))
+		assert.True(t, CRMChanged(base, &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Doe"}))
+		assert.True(t, CRMChanged(Pair{}, &models.Lead{Email: "jane@techfirm.com"}))
+	})
+}
+
+func TestExporter(t *testing.T) {
+	lead := &models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm", Status: "qualified"}
+
+	t.Run("writes a CSV file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "changes.csv")
+		exporter := NewExporter(path, nil)
+		exporter.Add(lead)
+
+		// Act
+		err := exporter.Write(time.Now())
+
+		// Assert
+		assert.NoError(t, err)
+		data, _ := os.ReadFile(path)
+		assert.Equal(t, "ID,Name,Email,Company,Source,Status,Updated At\n1,Jane Smith,jane@techfirm.com,TechFirm,,qualified,\n", string(data))
+	})
+
+	t.Run("posts to a webhook", func(t *testing.T) {
+		// Arrange
+		var received webhookPayload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+		}))
+		defer server.Close()
+		exporter := NewExporter(server.URL, server.Client())
+		exporter.SetLabels(map[string]string{"campaign": "q3-webinar"})
+		exporter.Add(lead)
+
+		// Act
+		err := exporter.Write(time.Now())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"campaign": "q3-webinar"}, received.Labels)
+		assert.Len(t, received.Leads, 1)
+		assert.Equal(t, "qualified", received.Leads[0].Status)
+	})
+
+	t.Run("reports a refused webhook", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+		exporter := NewExporter(server.URL, server.Client())
+		exporter.Add(lead)
+
+		// Act
+		err := exporter.Write(time.Now())
+
+		// Assert
+		assert.ErrorContains(t, err, "the sync webhook refused the changes")
+	})
+}
@@ -0,0 +1,108 @@
+package bisync
+
+import (
+	"bytes"
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exporter collects the leads changed in the CRM and writes them out after
+// a sync: to a CSV file, replaced each sync, or POSTed as JSON to a webhook
+type Exporter struct {
+	dest       string
+	httpClient *http.Client
+	leads      []*models.Lead
+	labels     map[string]string
+}
+
+// NewExporter exports to dest, a CSV file or an http(s) URL
+func NewExporter(dest string, httpClient *http.Client) *Exporter {
+	return &Exporter{dest: dest, httpClient: httpClient}
+}
+
+// IsWebhook reports whether dest is a webhook URL
+func IsWebhook(dest string) bool {
+	return strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://")
+}
+
+// SetLabels sets the run labels sent along with a webhook's leads
+func (e *Exporter) SetLabels(labels map[string]string) {
+	e.labels = labels
+}
+
+// Add queues a lead for export
+func (e *Exporter) Add(lead *models.Lead) {
+	e.leads = append(e.leads, lead)
+}
+
+// Count returns how many leads were queued
+func (e *Exporter) Count() int {
+	return len(e.leads)
+}
+
+// webhookPayload is the body POSTed to a webhook
+type webhookPayload struct {
+	SyncedAt time.Time         `json:"syncedAt"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Leads    []*models.Lead    `json:"leads"`
+}
+
+// Write writes the queued leads. A file is written even without leads, so
+// a consumer can tell a quiet sync from a failed one; a webhook is only
+// called when there are leads.
+func (e *Exporter) Write(syncedAt time.Time) error {
+	if !IsWebhook(e.dest) {
+		return e.writeFile()
+	}
+	if len(e.leads) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(webhookPayload{SyncedAt: syncedAt, Labels: e.labels, Leads: e.leads})
+	if err != nil {
+		return err
+	}
+	resp, err := e.httpClient.Post(e.dest, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to call the sync webhook: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the sync webhook refused the changes: %w", &errcode.StatusError{Status: resp.StatusCode})
+	}
+	return nil
+}
+
+// writeFile writes the leads as CSV, with columns process reads back
+func (e *Exporter) writeFile() error {
+	tmpPath := e.dest + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to write CRM changes: %w", err)
+	}
+	w := csv.NewWriter(file)
+	w.Write([]string{"ID", "Name", "Email", "Company", "Source", "Status", "Updated At"})
+	for _, lead := range e.leads {
+		updatedAt := ""
+		if lead.UpdatedAt != nil {
+			updatedAt = lead.UpdatedAt.UTC().Format(time.RFC3339)
+		}
+		w.Write([]string{lead.ID, lead.Name, lead.Email, lead.Company, lead.Source, lead.Status, updatedAt})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write CRM changes: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write CRM changes: %w", err)
+	}
+	return os.Rename(tmpPath, e.dest)
+}
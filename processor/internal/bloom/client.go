@@ -0,0 +1,62 @@
+package bloom
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"sync/atomic"
+)
+
+// Client skips the lookups of emails a bloom filter says the CRM doesn't
+// have, so those leads go straight to create, and adds the emails of leads
+// it finds or creates to the filter. Other calls are passed through to the
+// wrapped client; the emails of archived and deleted leads stay in the
+// filter, which only costs their lookups.
+type Client struct {
+	processor.Passthrough
+	filter  *Filter
+	skipped atomic.Int64
+}
+
+// NewClient wraps next so lookups are checked against f first
+func NewClient(next processor.APIClient, f *Filter) *Client {
+	return &Client{Passthrough: processor.Passthrough{Next: next}, filter: f}
+}
+
+// Skipped returns how many lookups the filter answered
+func (c *Client) Skipped() int {
+	return int(c.skipped.Load())
+}
+
+// LookupLead reports the lead as not found without calling the API when the
+// filter rules it out
+func (c *Client) LookupLead(email string) (*processor.LookupResponse, error) {
+	if !c.filter.MayContain(email) {
+		c.skipped.Add(1)
+		return &processor.LookupResponse{Found: false}, nil
+	}
+	resp, err := c.Next.LookupLead(email)
+	if err == nil && resp != nil && resp.Found {
+		c.filter.Add(email)
+	}
+	return resp, err
+}
+
+// CreateLead creates the lead and adds its email to the filter
+func (c *Client) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	created, err := c.Next.CreateLead(lead)
+	if err != nil {
+		return nil, err
+	}
+	c.filter.Add(lead.Email)
+	return created, nil
+}
+
+// UpdateLead updates the lead
+func (c *Client) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	updated, err := c.Next.UpdateLead(lead)
+	if err != nil {
+		return nil, err
+	}
+	c.filter.Add(lead.Email)
+	return updated, nil
+}
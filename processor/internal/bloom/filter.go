@@ -0,0 +1,268 @@
+package bloom
+
+import (
+	"code/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is how far back an incremental sync reaches before the previous
+// one started, as for the lead mirror
+const clockSkew = 5 * time.Minute
+
+// minCapacity is the fewest emails a filter is sized for, so a small CRM
+// can grow for a while before a rebuild
+const minCapacity = 100000
+
+// DefaultFalsePositiveRate is the share of absent emails a filter sized for
+// its emails still reports as maybe present
+const DefaultFalsePositiveRate = 0.01
+
+// Filter is a bloom filter of the emails the CRM has, kept in a file between
+// runs. It never reports an email it was given as absent, so a lookup of an
+// email it reports absent can be skipped. It is safe for concurrent use.
+type Filter struct {
+	path string
+
+	mu       sync.RWMutex
+	bits     []byte
+	hashes   int
+	count    int
+	capacity int
+	syncedAt time.Time
+}
+
+// file is the filter's layout on disk
+type file struct {
+	SyncedAt time.Time `json:"syncedAt"`
+	Hashes   int       `json:"hashes"`
+	Count    int       `json:"count"`
+	Capacity int       `json:"capacity"`
+	Bits     []byte    `json:"bits"`
+}
+
+// Open loads the filter at path, starting empty and unsized if the file does
+// not exist
+func Open(path string) (*Filter, error) {
+	f := &Filter{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("failed to read bloom filter: %w", err)
+	}
+	if len(data) == 0 {
+		return f, nil
+	}
+
+	var stored file
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode bloom filter %s: %w", path, err)
+	}
+	if stored.Hashes < 1 || len(stored.Bits) == 0 {
+		return nil, fmt.Errorf("bloom filter %s is corrupt", path)
+	}
+	f.bits = stored.Bits
+	f.hashes = stored.Hashes
+	f.count = stored.Count
+	f.capacity = stored.Capacity
+	f.syncedAt = stored.SyncedAt
+	return f, nil
+}
+
+// SyncedAt returns when the last sync started, or the zero time if the
+// filter was never built
+func (f *Filter) SyncedAt() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.syncedAt
+}
+
+// Count returns roughly how many distinct emails the filter holds: emails
+// added again, or whose bits were all set already, aren't counted
+func (f *Filter) Count() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.count
+}
+
+// Capacity returns how many emails the filter was sized for
+func (f *Filter) Capacity() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.capacity
+}
+
+// Size returns the filter's size in bytes
+func (f *Filter) Size() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.bits)
+}
+
+// Add records that the CRM has the email
+func (f *Filter) Add(email string) {
+	k := key(email)
+	if k == "" {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.bits) == 0 {
+		return
+	}
+	added := false
+	for _, bit := range f.positions(k) {
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			f.bits[bit/8] |= 1 << (bit % 8)
+			added = true
+		}
+	}
+	if added {
+		f.count++
+	}
+}
+
+// MayContain reports whether the CRM may have the email. False means it
+// certainly doesn't, as far as the filter was told; an unsized filter
+// reports every email as maybe present.
+func (f *Filter) MayContain(email string) bool {
+	k := key(email)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.bits) == 0 {
+		return true
+	}
+	for _, bit := range f.positions(k) {
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// positions returns the bits of an email, by double hashing one 64-bit FNV
+// hash
+func (f *Filter) positions(k string) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	size := uint64(len(f.bits)) * 8
+
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % size
+	}
+	return positions
+}
+
+// reset sizes the filter afresh for capacity emails at the false positive
+// rate, dropping every email it had
+func (f *Filter) reset(capacity int, rate float64) {
+	if capacity < minCapacity {
+		capacity = minCapacity
+	}
+	bits := math.Ceil(-float64(capacity) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	hashes := int(math.Round(bits / float64(capacity) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bits = make([]byte, int(bits+7)/8)
+	f.hashes = hashes
+	f.count = 0
+	f.capacity = capacity
+}
+
+// SyncStats counts what a sync did
+type SyncStats struct {
+	// Added is how many emails the export returned
+	Added int
+	// Full reports whether the filter was rebuilt from every lead
+	Full bool
+}
+
+// Export calls fn with every lead updated since the given time, or with all
+// leads when since is zero
+type Export func(since time.Time, fn func(*models.Lead) error) error
+
+// Sync brings the filter up to date from export. A filter never built, or a
+// full sync, is rebuilt from every lead, sized for twice as many emails as
+// the CRM has so it can grow; otherwise the emails of leads updated since the
+// last sync are added. Deleted leads stay in the filter until it is rebuilt,
+// which only costs their lookups.
+func (f *Filter) Sync(export Export, full bool, rate float64) (SyncStats, error) {
+	startedAt := time.Now().UTC()
+	since := f.SyncedAt()
+	stats := SyncStats{Full: full || since.IsZero()}
+
+	if !stats.Full {
+		err := export(since.Add(-clockSkew), func(lead *models.Lead) error {
+			stats.Added++
+			f.Add(lead.Email)
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+		f.mu.Lock()
+		f.syncedAt = startedAt
+		f.mu.Unlock()
+		return stats, nil
+	}
+
+	// The filter is sized before any email is added, so a rebuild holds
+	// the emails until the export is done
+	var emails []string
+	err := export(time.Time{}, func(lead *models.Lead) error {
+		if k := key(lead.Email); k != "" {
+			emails = append(emails, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	f.reset(2*len(emails), rate)
+	for _, email := range emails {
+		f.Add(email)
+	}
+	stats.Added = len(emails)
+	f.mu.Lock()
+	f.syncedAt = startedAt
+	f.mu.Unlock()
+	return stats, nil
+}
+
+// Save writes the filter back to disk
+func (f *Filter) Save() error {
+	f.mu.RLock()
+	stored := file{SyncedAt: f.syncedAt, Hashes: f.hashes, Count: f.count, Capacity: f.capacity, Bits: f.bits}
+	data, err := json.Marshal(stored)
+	f.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode bloom filter: %w", err)
+	}
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write bloom filter: %w", err)
+	}
+	return os.Rename(tmpPath, f.path)
+}
+
+// key normalizes an email; the API matches emails case-insensitively
+func key(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,169 @@
+package bloom
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExport serves leads and records the times it was asked for
+type fakeExport struct {
+	leads []*models.Lead
+	since []time.Time
+}
+
+func (f *fakeExport) export(since time.Time, fn func(*models.Lead) error) error {
+	f.since = append(f.since, since)
+	for _, lead := range f.leads {
+		if err := fn(lead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	t.Run("builds from every lead, then adds updates", func(t *testing.T) {
+		// Arrange
+		f, err := Open(filepath.Join(t.TempDir(), "bloom.json"))
+		assert.NoError(t, err)
+		export := &fakeExport{leads: []*models.Lead{{Email: "Jane@TechFirm.com"}, {Email: "john@startup.io"}}}
+
+		// Act
+		first, firstErr := f.Sync(export.export, false, DefaultFalsePositiveRate)
+		export.leads = []*models.Lead{{Email: "mia@labs.dev"}}
+		second, secondErr := f.Sync(export.export, false, DefaultFalsePositiveRate)
+
+		// Assert
+		assert.NoError(t, firstErr)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, SyncStats{Added: 2, Full: true}, first)
+		assert.Equal(t, SyncStats{Added: 1}, second)
+		assert.True(t, export.since[0].IsZero())
+		assert.WithinDuration(t, time.Now().Add(-clockSkew), export.since[1], time.Minute)
+		assert.True(t, f.MayContain("jane@techfirm.com"))
+		assert.True(t, f.MayContain("mia@labs.dev"))
+		assert.False(t, f.MayContain("nobody@example.com"))
+		assert.Equal(t, minCapacity, f.Capacity())
+	})
+
+	t.Run("rules out most absent emails", func(t *testing.T) {
+		// Arrange
+		f := &Filter{}
+		f.reset(1000, DefaultFalsePositiveRate)
+		for i := 0; i < 1000; i++ {
+			f.Add(fmt.Sprintf("lead%d@example.com", i))
+		}
+
+		// Act
+		maybe := 0
+		for i := 0; i < 10000; i++ {
+			if f.MayContain(fmt.Sprintf("new%d@example.com", i)) {
+				maybe++
+			}
+		}
+
+		// Assert
+		for i := 0; i < 1000; i++ {
+			assert.True(t, f.MayContain(fmt.Sprintf("lead%d@example.com", i)))
+		}
+		assert.Less(t, maybe, 100)
+	})
+
+	t.Run("treats every email as maybe present until built", func(t *testing.T) {
+		// Arrange
+		f, _ := Open(filepath.Join(t.TempDir(), "bloom.json"))
+
+		// Act
+		f.Add("jane@techfirm.com")
+
+		// Assert
+		assert.True(t, f.MayContain("nobody@example.com"))
+		assert.Equal(t, 0, f.Count())
+	})
+
+	t.Run("keeps the last sync time when the export fails", func(t *testing.T) {
+		// Arrange
+		f, _ := Open(filepath.Join(t.TempDir(), "bloom.json"))
+
+		// Act
+		_, err := f.Sync(func(time.Time, func(*models.Lead) error) error {
+			return errors.New("API returned 503")
+		}, false, DefaultFalsePositiveRate)
+
+		// Assert
+		assert.EqualError(t, err, "API returned 503")
+		assert.True(t, f.SyncedAt().IsZero())
+	})
+
+	t.Run("saves and reopens", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "bloom.json")
+		f, _ := Open(path)
+		f.Sync((&fakeExport{leads: []*models.Lead{{Email: "jane@techfirm.com"}}}).export, false, DefaultFalsePositiveRate)
+
+		// Act
+		saveErr := f.Save()
+		reopened, openErr := Open(path)
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, openErr)
+		assert.Equal(t, f.SyncedAt().Unix(), reopened.SyncedAt().Unix())
+		assert.Equal(t, 1, reopened.Count())
+		assert.True(t, reopened.MayContain("jane@techfirm.com"))
+		assert.False(t, reopened.MayContain("nobody@example.com"))
+	})
+}
+
+// fakeAPI records the calls that reach the API
+type fakeAPI struct {
+	calls []string
+}
+
+func (f *fakeAPI) LookupLead(email string) (*processor.LookupResponse, error) {
+	f.calls = append(f.calls, "lookup "+email)
+	return &processor.LookupResponse{Found: true, Lead: &models.Lead{ID: "1", Email: email}}, nil
+}
+
+func (f *fakeAPI) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	f.calls = append(f.calls, "create "+lead.Email)
+	return lead, nil
+}
+
+func (f *fakeAPI) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	f.calls = append(f.calls, "update "+lead.Email)
+	return lead, nil
+}
+
+func TestClient(t *testing.T) {
+	t.Run("skips lookups of emails the filter rules out", func(t *testing.T) {
+		// Arrange
+		f := &Filter{}
+		f.reset(10, DefaultFalsePositiveRate)
+		f.Add("jane@techfirm.com")
+		next := &fakeAPI{}
+		client := NewClient(next, f)
+
+		// Act
+		found, foundErr := client.LookupLead("Jane@TechFirm.com")
+		missing, missingErr := client.LookupLead("mia@labs.dev")
+		_, createErr := client.CreateLead(&models.Lead{Email: "mia@labs.dev"})
+
+		// Assert
+		assert.NoError(t, foundErr)
+		assert.NoError(t, missingErr)
+		assert.NoError(t, createErr)
+		assert.True(t, found.Found)
+		assert.False(t, missing.Found)
+		assert.Equal(t, 1, client.Skipped())
+		assert.Equal(t, []string{"lookup Jane@TechFirm.com", "create mia@labs.dev"}, next.calls)
+		assert.True(t, f.MayContain("mia@labs.dev"))
+	})
+}
@@ -0,0 +1,121 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// version is the checkpoint layout this binary writes. Checkpoints without
+// one predate versioning and share version 1's layout.
+const version = 1
+
+// Checkpoint records how far a paused run got through its file, so the next
+// run can skip the leads it already processed
+type Checkpoint struct {
+	Version  int    `json:"version"`
+	File     string `json:"file"`
+	FileHash string `json:"fileHash"`
+	// OrderBy is the --order-by the leads were processed in; the count is
+	// only meaningful in the same order
+	OrderBy string `json:"orderBy,omitempty"`
+	// Completed is how many leads, from the start of the file, are done
+	Completed int       `json:"completed"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Load reads the checkpoint at path, returning nil if there is none
+func Load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if cp.Version > version {
+		return nil, fmt.Errorf("checkpoint %s was written by a newer version of lead-processor (version %d); resume the run with that version or delete the checkpoint", path, cp.Version)
+	}
+	return &cp, nil
+}
+
+// Matches reports whether the checkpoint was taken for the same file content
+// and ordering
+func (c *Checkpoint) Matches(fileHash, orderBy string) bool {
+	return c.FileHash == fileHash && c.OrderBy == orderBy
+}
+
+// Save writes the checkpoint to path, replacing the previous one atomically
+// so an interrupted write never leaves a corrupt checkpoint behind
+func (c *Checkpoint) Save(path string) error {
+	c.Version = version
+	c.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint at path once its run has finished
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Tracker follows which leads are done when workers finish them out of
+// order. Only the unbroken run of done leads from the start counts as
+// completed, so resuming never skips a lead that was still in flight.
+type Tracker struct {
+	completed int
+	done      map[int]bool
+}
+
+// NewTracker creates a tracker for a run that skipped the first completed leads
+func NewTracker(completed int) *Tracker {
+	return &Tracker{completed: completed, done: make(map[int]bool)}
+}
+
+// Done marks the lead at the 1-based index as processed
+func (t *Tracker) Done(index int) {
+	if index <= t.completed {
+		return
+	}
+	t.done[index] = true
+	for t.done[t.completed+1] {
+		delete(t.done, t.completed+1)
+		t.completed++
+	}
+}
+
+// Completed returns how many leads from the start of the file are done
+func (t *Tracker) Completed() int {
+	return t.completed
+}
@@ -0,0 +1,89 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Run("round-trips through a file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "leads.checkpoint.json")
+		cp := &Checkpoint{File: "leads.csv", FileHash: "abc", OrderBy: "score", Completed: 42}
+
+		// Act
+		saveErr := cp.Save(path)
+		loaded, loadErr := Load(path)
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, loadErr)
+		assert.Equal(t, 42, loaded.Completed)
+		assert.True(t, loaded.Matches("abc", "score"))
+		assert.False(t, loaded.Matches("abc", ""))
+		assert.False(t, loaded.Matches("def", "score"))
+	})
+
+	t.Run("returns nil when there is no checkpoint", func(t *testing.T) {
+		// Act
+		cp, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, cp)
+	})
+
+	t.Run("removes a checkpoint and tolerates a missing one", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "leads.checkpoint.json")
+		assert.NoError(t, (&Checkpoint{Completed: 1}).Save(path))
+
+		// Act
+		first := Remove(path)
+		second := Remove(path)
+		cp, _ := Load(path)
+
+		// Assert
+		assert.NoError(t, first)
+		assert.NoError(t, second)
+		assert.Nil(t, cp)
+	})
+
+	t.Run("reads unversioned checkpoints and refuses newer ones", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		old := filepath.Join(dir, "old.checkpoint.json")
+		newer := filepath.Join(dir, "newer.checkpoint.json")
+		os.WriteFile(old, []byte(`{"file":"leads.csv","fileHash":"abc","completed":7}`), 0o600)
+		os.WriteFile(newer, []byte(`{"version":99,"file":"leads.csv","fileHash":"abc","completed":7}`), 0o600)
+
+		// Act
+		oldCp, oldErr := Load(old)
+		_, newerErr := Load(newer)
+
+		// Assert
+		assert.NoError(t, oldErr)
+		assert.Equal(t, 7, oldCp.Completed)
+		assert.ErrorContains(t, newerErr, "newer version")
+	})
+}
+
+func TestTracker(t *testing.T) {
+	t.Run("only counts leads done without gaps", func(t *testing.T) {
+		// Arrange
+		tracker := NewTracker(10)
+
+		// Act
+		tracker.Done(12)
+		tracker.Done(13)
+		afterGap := tracker.Completed()
+		tracker.Done(11)
+
+		// Assert
+		assert.Equal(t, 10, afterGap)
+		assert.Equal(t, 13, tracker.Completed())
+	})
+}
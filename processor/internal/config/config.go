@@ -0,0 +1,429 @@
+package config
+
+import (
+	"bytes"
+	"code/internal/anomaly"
+	"code/internal/api"
+	"code/internal/attachments"
+	"code/internal/auth"
+	"code/internal/bisync"
+	"code/internal/csv"
+	"code/internal/datalake"
+	"code/internal/fixedwidth"
+	"code/internal/inference"
+	"code/internal/langdetect"
+	"code/internal/mailchimp"
+	"code/internal/merge"
+	"code/internal/models"
+	"code/internal/pipedrive"
+	"code/internal/processor"
+	"code/internal/secrets"
+	"code/internal/territory"
+	"code/internal/usage"
+	"code/internal/xmlfeed"
+	"code/internal/zoho"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds settings loaded from the YAML config file
+type Config struct {
+	// ProtectedFields may be set when a lead is created but are never
+	// changed on update, e.g. values sales reps maintain by hand
+	ProtectedFields []string `yaml:"protected_fields"`
+
+	// Equality adjusts how an input lead is compared with the CRM's copy to
+	// decide between SKIP and UPDATE
+	Equality models.Equality `yaml:"equality"`
+
+	// Mapping names the CSV header column to read for a lead field when
+	// the file doesn't use the standard column names
+	Mapping map[string]string `yaml:"mapping"`
+
+	// APIToken is sent as a bearer token with every API request. Like the
+	// other secrets, it may be a reference such as
+	// vault://secret/data/lead-processor#api_token, resolved at startup.
+	APIToken string `yaml:"api_token"`
+
+	// RawSource sends each lead created through the lead API with the row it
+	// was read from, by header, for settling mapping disputes with partners
+	RawSource bool `yaml:"raw_source"`
+
+	// SourcePriority ranks sources for --order-by source, highest first
+	SourcePriority []string `yaml:"source_priority"`
+
+	// MaxLengths overrides the longest value, in characters, accepted for
+	// free-text fields; 0 removes the limit
+	MaxLengths map[string]int `yaml:"max_lengths"`
+
+	// SourceRules replace the built-in heuristics --infer-source uses to fill
+	// in a missing source
+	SourceRules []inference.Rule `yaml:"source_rules"`
+
+	// Territories assign leads a sales territory from their country, state
+	// or IP address
+	Territories territory.Config `yaml:"territories"`
+
+	// LanguageDetection sets the language of leads from free-text columns
+	LanguageDetection langdetect.Config `yaml:"language_detection"`
+
+	// MergePrecedence picks, per field, which lead's value `merge` keeps:
+	// primary, duplicate or newest
+	MergePrecedence map[string]string `yaml:"merge_precedence"`
+
+	// SyncPrecedence picks, per field, which side `sync two-way` keeps when
+	// the file and the CRM both changed it: crm or file
+	SyncPrecedence map[string]string `yaml:"sync_precedence"`
+
+	// XML locates the leads and their fields in XML feeds
+	XML xmlfeed.Config `yaml:"xml"`
+
+	// FixedWidth lays out the columns of fixed-width files
+	FixedWidth fixedwidth.Config `yaml:"fixed_width"`
+
+	// Pipedrive configures --provider pipedrive
+	Pipedrive pipedrive.Config `yaml:"pipedrive"`
+
+	// Zoho configures --provider zoho
+	Zoho zoho.Config `yaml:"zoho"`
+
+	// Mailchimp configures the audience --mailchimp subscribes leads to
+	Mailchimp mailchimp.Config `yaml:"mailchimp"`
+
+	// Attachments lists the photo and document columns --attachments
+	// uploads to written leads
+	Attachments attachments.Config `yaml:"attachments"`
+
+	// Export adjusts the files --export writes, e.g. hashing emails and
+	// names
+	Export datalake.Config `yaml:"export"`
+
+	// Costs prices API requests so runs can report their estimated spend
+	Costs usage.Pricing `yaml:"costs"`
+
+	// RateLimits paces the requests to each target, such as the lead API or
+	// Mailchimp, and caps how many are in flight at once
+	RateLimits api.Limits `yaml:"rate_limits"`
+
+	// Serve lists the tokens serve mode accepts, with their roles
+	Serve auth.Config `yaml:"serve"`
+
+	// Profiles are named per-partner import settings, selected with
+	// --profile
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Pipelines are named imports declared end to end, from source to
+	// notifications, and run with run <name>
+	Pipelines map[string]Pipeline `yaml:"pipelines"`
+
+	// Anomalies flags runs whose figures stray from the earlier runs of
+	// the same feed
+	Anomalies anomaly.Config `yaml:"anomalies"`
+
+	// IDScheme picks how the IDs of leads read from files are made: uuid4
+	// (random, the default), uuid7 (time-ordered) or uuid5 (derived from
+	// email and source, so re-imports keep their IDs)
+	IDScheme string `yaml:"id_scheme"`
+
+	// Stages orders the steps each lead goes through, built-in and
+	// registered custom ones; empty keeps the default order
+	Stages []string `yaml:"stages"`
+}
+
+// Default returns the configuration used when no config file is given
+func Default() *Config {
+	return &Config{}
+}
+
+// Load reads and validates the config file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse decodes and validates YAML config, rejecting unknown keys so typos
+// don't silently fall back to defaults
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config for unknown or unsupported values
+func (c *Config) Validate() error {
+	for i, field := range c.ProtectedFields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !isProtectableField(field) {
+			return fmt.Errorf("protected_fields: %q cannot be protected (allowed: %s)", c.ProtectedFields[i], strings.Join(models.ProtectableFields(), ", "))
+		}
+		c.ProtectedFields[i] = field
+	}
+
+	mapping := make(map[string]string, len(c.Mapping))
+	for field, column := range c.Mapping {
+		name, ok := mappableField(field)
+		if !ok {
+			return fmt.Errorf("mapping: unknown field %q (allowed: %s)", field, strings.Join(csv.Fields(), ", "))
+		}
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("mapping: no column given for %s", name)
+		}
+		mapping[name] = column
+	}
+	if c.Mapping != nil {
+		c.Mapping = mapping
+	}
+
+	for i, source := range c.SourcePriority {
+		canonical, ok := validSource(source)
+		if !ok {
+			return fmt.Errorf("source_priority: unknown source %q (allowed: %s)", source, strings.Join(models.GetValidSources(), ", "))
+		}
+		c.SourcePriority[i] = canonical
+	}
+
+	maxLengths := make(map[string]int, len(c.MaxLengths))
+	for field, limit := range c.MaxLengths {
+		name, ok := lengthLimitedField(field)
+		if !ok {
+			return fmt.Errorf("max_lengths: unknown field %q (allowed: %s)", field, strings.Join(models.LengthLimitedFields(), ", "))
+		}
+		if limit < 0 {
+			return fmt.Errorf("max_lengths: %s cannot be negative", name)
+		}
+		maxLengths[name] = limit
+	}
+	if c.MaxLengths != nil {
+		c.MaxLengths = maxLengths
+	}
+
+	for i := range c.SourceRules {
+		if err := c.SourceRules[i].Validate(); err != nil {
+			return fmt.Errorf("source_rules[%d]: %w", i, err)
+		}
+	}
+
+	if err := c.Territories.Validate(); err != nil {
+		return fmt.Errorf("territories: %w", err)
+	}
+
+	if err := c.LanguageDetection.Validate(); err != nil {
+		return fmt.Errorf("language_detection: %w", err)
+	}
+
+	if err := c.Equality.Validate(); err != nil {
+		return fmt.Errorf("equality: %w", err)
+	}
+
+	if _, err := merge.ParsePolicy(c.MergePrecedence); err != nil {
+		return fmt.Errorf("merge_precedence: %w", err)
+	}
+
+	if _, err := bisync.ParsePolicy(c.SyncPrecedence); err != nil {
+		return fmt.Errorf("sync_precedence: %w", err)
+	}
+
+	if err := c.XML.Validate(); err != nil {
+		return fmt.Errorf("xml: %w", err)
+	}
+
+	if err := c.FixedWidth.Validate(); err != nil {
+		return fmt.Errorf("fixed_width: %w", err)
+	}
+
+	if err := c.Pipedrive.Validate(); err != nil {
+		return fmt.Errorf("pipedrive: %w", err)
+	}
+
+	if err := c.Zoho.Validate(); err != nil {
+		return fmt.Errorf("zoho: %w", err)
+	}
+
+	if err := c.Mailchimp.Validate(); err != nil {
+		return fmt.Errorf("mailchimp: %w", err)
+	}
+
+	if err := c.Attachments.Validate(); err != nil {
+		return fmt.Errorf("attachments: %w", err)
+	}
+
+	if err := c.Export.Validate(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	if err := c.Costs.Validate(); err != nil {
+		return fmt.Errorf("costs: %w", err)
+	}
+
+	if err := c.RateLimits.Validate(); err != nil {
+		return fmt.Errorf("rate_limits: %w", err)
+	}
+
+	if err := c.Serve.Validate(); err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	for name, profile := range c.Profiles {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("profiles: a profile has no name")
+		}
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("profiles.%s: %w", name, err)
+		}
+		c.Profiles[name] = profile
+	}
+
+	for name, pipeline := range c.Pipelines {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("pipelines: a pipeline has no name")
+		}
+		if err := pipeline.Validate(); err != nil {
+			return fmt.Errorf("pipelines.%s: %w", name, err)
+		}
+		if pipeline.Profile != "" {
+			if _, ok := c.Profiles[pipeline.Profile]; !ok {
+				return fmt.Errorf("pipelines.%s: profile: unknown profile %q", name, pipeline.Profile)
+			}
+		}
+		c.Pipelines[name] = pipeline
+	}
+
+	if err := c.Anomalies.Validate(); err != nil {
+		return fmt.Errorf("anomalies: %w", err)
+	}
+
+	if _, err := models.ParseIDScheme(c.IDScheme); err != nil {
+		return fmt.Errorf("id_scheme: %w", err)
+	}
+
+	if len(c.Stages) > 0 {
+		if err := processor.ValidateStages(c.Stages); err != nil {
+			return fmt.Errorf("stages: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveSecrets replaces the secrets given as references, such as
+// vault://secret/data/lead-processor#api_token or aws-sm://prod/crm, with
+// the values they point at, and returns how many it resolved. Secrets given
+// as plain values are left as they are.
+func (c *Config) ResolveSecrets(ctx context.Context) (int, error) {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"api_token", &c.APIToken},
+		{"pipedrive.api_token", &c.Pipedrive.APIToken},
+		{"zoho.client_secret", &c.Zoho.ClientSecret},
+		{"zoho.refresh_token", &c.Zoho.RefreshToken},
+		{"mailchimp.api_key", &c.Mailchimp.APIKey},
+		{"serve.typeform_secret", &c.Serve.TypeformSecret},
+		{"serve.forms_token", &c.Serve.FormsToken},
+		{"export.pii.key", &c.Export.PII.Key},
+	}
+	for i := range c.Serve.Tokens {
+		fields = append(fields, struct {
+			name  string
+			value *string
+		}{fmt.Sprintf("serve.tokens[%d].token", i), &c.Serve.Tokens[i].Token})
+	}
+	// A pipeline's notifications share their backing array with the map's copy
+	for _, name := range c.PipelineNames() {
+		notifications := c.Pipelines[name].Notifications
+		for i := range notifications {
+			fields = append(fields, struct {
+				name  string
+				value *string
+			}{fmt.Sprintf("pipelines.%s.notifications[%d].url", name, i), &notifications[i].URL})
+		}
+	}
+
+	resolved := 0
+	for _, field := range fields {
+		if !secrets.IsReference(*field.value) {
+			continue
+		}
+		secret, err := secrets.Resolve(ctx, *field.value)
+		if err != nil {
+			return resolved, fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.value = secret
+		resolved++
+	}
+	return resolved, nil
+}
+
+// IDGenerator returns the generator of the configured ID scheme
+func (c *Config) IDGenerator() models.IDGenerator {
+	newID, err := models.ParseIDScheme(c.IDScheme)
+	if err != nil {
+		return models.RandomID
+	}
+	return newID
+}
+
+// validSource returns the canonical spelling of a lead source
+func validSource(source string) (string, bool) {
+	for _, valid := range models.GetValidSources() {
+		if strings.EqualFold(strings.TrimSpace(source), valid) {
+			return valid, true
+		}
+	}
+	return "", false
+}
+
+// lengthLimitedField returns the canonical name of a field that takes a max length
+func lengthLimitedField(field string) (string, bool) {
+	for _, name := range models.LengthLimitedFields() {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// mappableField returns the canonical name of a field a CSV column can be mapped to
+func mappableField(field string) (string, bool) {
+	for _, name := range csv.Fields() {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func isProtectableField(field string) bool {
+	for _, protectable := range models.ProtectableFields() {
+		if field == protectable {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,550 @@
+package config
+
+import (
+	"code/internal/inference"
+	"code/internal/models"
+	"code/internal/secrets"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	t.Run("reads protected fields", func(t *testing.T) {
+		// Arrange
+		data := []byte("protected_fields:\n  - Source\n  - status\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"source", "status"}, cfg.ProtectedFields)
+	})
+
+	t.Run("reads the equality settings", func(t *testing.T) {
+		// Arrange
+		data := []byte("equality:\n  ignore: [source]\n  case_insensitive: true\n  comparators:\n    name: whitespace\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, models.Equality{Ignore: []string{"source"}, CaseInsensitive: true, Comparators: map[string]string{"name": "whitespace"}}, cfg.Equality)
+	})
+
+	t.Run("rejects unknown equality fields and comparators", func(t *testing.T) {
+		// Act
+		_, fieldErr := Parse([]byte("equality:\n  ignore: [phone]\n"))
+		_, comparatorErr := Parse([]byte("equality:\n  comparators:\n    company: fuzzy\n"))
+		_, bothErr := Parse([]byte("equality:\n  fields: [name]\n  ignore: [source]\n"))
+
+		// Assert
+		assert.EqualError(t, fieldErr, `equality: unknown field "phone" (allowed: name, email, company, source, status, territory, language, consent)`)
+		assert.ErrorContains(t, comparatorErr, `equality: comparators: unknown comparator "fuzzy" for company`)
+		assert.EqualError(t, bothErr, "equality: set fields or ignore, not both")
+	})
+
+	t.Run("accepts an empty file", func(t *testing.T) {
+		// Act
+		cfg, err := Parse(nil)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, cfg.ProtectedFields)
+	})
+
+	t.Run("rejects fields that cannot be protected", func(t *testing.T) {
+		// Arrange
+		data := []byte("protected_fields: [email]\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "email")
+	})
+
+	t.Run("reads column mapping", func(t *testing.T) {
+		// Arrange
+		data := []byte("mapping:\n  Company: Organisation\n  consentgiven: Opt In\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"company": "Organisation", "consentGiven": "Opt In"}, cfg.Mapping)
+	})
+
+	t.Run("rejects mappings for unknown fields", func(t *testing.T) {
+		// Arrange
+		data := []byte("mapping:\n  phone: Phone Number\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "phone")
+	})
+
+	t.Run("reads source priority", func(t *testing.T) {
+		// Arrange
+		data := []byte("source_priority: [webinar, Conference]\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Webinar", "Conference"}, cfg.SourcePriority)
+	})
+
+	t.Run("reads max lengths", func(t *testing.T) {
+		// Arrange
+		data := []byte("max_lengths:\n  Name: 100\n  consentsource: 0\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{"name": 100, "consentSource": 0}, cfg.MaxLengths)
+	})
+
+	t.Run("rejects max lengths for unknown fields", func(t *testing.T) {
+		// Arrange
+		data := []byte("max_lengths:\n  source: 10\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects unknown merge precedence rules", func(t *testing.T) {
+		// Arrange
+		data := []byte("merge_precedence:\n  company: longest\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, "merge_precedence")
+	})
+
+	t.Run("rejects unknown sync precedence sides", func(t *testing.T) {
+		// Arrange
+		data := []byte("sync_precedence:\n  status: newest\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, "sync_precedence")
+	})
+
+	t.Run("rejects rate limits for unknown targets", func(t *testing.T) {
+		// Arrange
+		data := []byte("rate_limits:\n  hubspot:\n    requests_per_second: 5\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, "rate_limits")
+	})
+
+	t.Run("rejects serve tokens with unknown roles", func(t *testing.T) {
+		// Arrange
+		data := []byte("serve:\n  tokens:\n    - name: marketing\n      token_env: MARKETING_TOKEN\n      role: owner\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, `serve: tokens[0]: unknown role "owner"`)
+	})
+
+	t.Run("reads Pipedrive custom fields", func(t *testing.T) {
+		// Arrange
+		data := []byte("pipedrive:\n  company_domain: acme\n  fields:\n    Source: a1b2c3\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", cfg.Pipedrive.CompanyDomain)
+		assert.Equal(t, map[string]string{"source": "a1b2c3"}, cfg.Pipedrive.Fields)
+	})
+
+	t.Run("rejects unknown Zoho fields", func(t *testing.T) {
+		// Arrange
+		data := []byte("zoho:\n  fields:\n    name: Full_Name\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, "zoho")
+	})
+
+	t.Run("reads Mailchimp merge fields", func(t *testing.T) {
+		// Arrange
+		data := []byte("mailchimp:\n  list_id: a1b2c3\n  merge_fields:\n    company: Company\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"COMPANY": "company"}, cfg.Mailchimp.MergeFields)
+	})
+
+	t.Run("rejects unsupported XML paths", func(t *testing.T) {
+		// Arrange
+		data := []byte("xml:\n  record_path: /feed/lead\n  fields:\n    Email: contact[1]/email\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, "xml: fields: Email: unsupported step")
+	})
+
+	t.Run("rejects overlapping fixed-width records", func(t *testing.T) {
+		// Arrange
+		data := []byte("fixed_width:\n  record_length: 40\n  columns:\n    - {name: Name, start: 1, length: 30}\n    - {name: Email, start: 31, length: 40}\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.ErrorContains(t, err, "fixed_width: columns[1]: Email ends at 70, past the record length of 40")
+	})
+
+	t.Run("reads source inference rules", func(t *testing.T) {
+		// Arrange
+		data := []byte("source_rules:\n  - column: Campaign\n    contains: summit\n    source: conference\n  - email_domain: partner.com\n    source: Referral\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []inference.Rule{
+			{Column: "Campaign", Contains: "summit", Source: "Conference"},
+			{EmailDomain: "partner.com", Source: "Referral"},
+		}, cfg.SourceRules)
+	})
+
+	t.Run("rejects source rules without a condition", func(t *testing.T) {
+		// Arrange
+		data := []byte("source_rules:\n  - source: LinkedIn\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "source_rules[0]")
+	})
+
+	t.Run("reads API costs", func(t *testing.T) {
+		// Arrange
+		data := []byte("costs:\n  currency: EUR\n  per_call: 0.002\n  per_type:\n    create: 0.01\n")
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "EUR", cfg.Costs.Currency)
+		assert.Equal(t, 0.002, cfg.Costs.PerCall)
+		assert.Equal(t, map[string]float64{"create": 0.01}, cfg.Costs.PerType)
+	})
+
+	t.Run("reads import profiles", func(t *testing.T) {
+		// Arrange
+		data := []byte(`
+profiles:
+  acme:
+    delimiter: tab
+    mapping:
+      Email: E-Mail
+    transforms:
+      defaults:
+        Source: referral
+`)
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		profile, err := cfg.Profile("acme")
+		assert.NoError(t, err)
+		assert.Equal(t, '\t', profile.Comma())
+		assert.Equal(t, map[string]string{"email": "E-Mail"}, profile.Mapping)
+		assert.Equal(t, map[string]string{"source": "Referral"}, profile.Transforms.Defaults)
+	})
+
+	t.Run("rejects invalid profiles", func(t *testing.T) {
+		// Arrange
+		cases := map[string]string{
+			"profiles:\n  acme:\n    delimiter: ';;'\n":                                "profiles.acme: delimiter",
+			"profiles:\n  acme:\n    mapping:\n      phone: Tel\n":                     "profiles.acme: mapping",
+			"profiles:\n  acme:\n    transforms:\n      defaults:\n        email: x\n": "profiles.acme: transforms.defaults",
+			"profiles:\n  acme:\n    dedup:\n      skip_seen: -1\n":                    "profiles.acme: dedup.skip_seen",
+			"profiles:\n  acme:\n    schema:\n      strict: true\n":                    "profiles.acme: schema.strict needs a header",
+			"profiles:\n  acme:\n    schema:\n      header: [Name, '']\n":              "profiles.acme: schema.header: column 2",
+		}
+
+		for data, expected := range cases {
+			// Act
+			_, err := Parse([]byte(data))
+
+			// Assert
+			assert.ErrorContains(t, err, expected, data)
+		}
+	})
+
+	t.Run("reads named pipelines", func(t *testing.T) {
+		// Arrange
+		data := []byte(`
+profiles:
+  acme:
+    delimiter: ";"
+pipelines:
+  acme-daily:
+    profile: acme
+    source:
+      input: /data/acme/leads.csv
+    validation:
+      email: dns
+      preflight: 200
+      preflight_max_invalid: 2
+      reconcile_delay: 30s
+    target:
+      policy:
+        name: create-only
+    notifications:
+      - url: https://hooks.example.com/leads
+        on: failure
+`)
+
+		// Act
+		cfg, err := Parse(data)
+
+		// Assert
+		assert.NoError(t, err)
+		pipeline, err := cfg.Pipeline("acme-daily")
+		assert.NoError(t, err)
+		assert.Equal(t, "/data/acme/leads.csv", pipeline.Source.Input)
+		assert.Equal(t, 2.0, *pipeline.Validation.PreflightMaxInvalid)
+		assert.Nil(t, pipeline.Validation.PreflightMaxConflicts)
+		assert.Equal(t, 30*time.Second, pipeline.Validation.ReconcileDelay)
+		assert.Equal(t, "failure", pipeline.Notifications[0].On)
+		_, err = cfg.Pipeline("globex")
+		assert.EqualError(t, err, `unknown pipeline "globex" (available: acme-daily)`)
+	})
+
+	t.Run("rejects invalid pipelines", func(t *testing.T) {
+		// Arrange
+		cases := map[string]string{
+			"pipelines:\n  daily:\n    source:\n      format: csv\n":                                                                 "pipelines.daily: source.input is required",
+			"pipelines:\n  daily:\n    profile: acme\n    source:\n      input: a.csv\n":                                             "pipelines.daily: profile: unknown profile",
+			"pipelines:\n  daily:\n    source:\n      input: a.csv\n    validation:\n      email: strict\n":                          "pipelines.daily: validation.email",
+			"pipelines:\n  daily:\n    source:\n      input: a.csv\n    target:\n      workers: -1\n":                                "pipelines.daily: target.workers",
+			"pipelines:\n  daily:\n    source:\n      input: a.csv\n    sinks:\n      export_format: json\n":                         "pipelines.daily: sinks.export_format",
+			"pipelines:\n  daily:\n    source:\n      input: a.csv\n    notifications:\n      - url: ftp://x\n":                      "pipelines.daily: notifications[0]: url",
+			"pipelines:\n  daily:\n    source:\n      input: a.csv\n    notifications:\n      - url: https://x\n        on: never\n": "pipelines.daily: notifications[0]: on",
+		}
+
+		for data, expected := range cases {
+			// Act
+			_, err := Parse([]byte(data))
+
+			// Assert
+			assert.ErrorContains(t, err, expected, data)
+		}
+	})
+
+	t.Run("detects profiles by file name, then header", func(t *testing.T) {
+		// Arrange
+		cfg, err := Parse([]byte(`
+profiles:
+  acme:
+    delimiter: ";"
+    match:
+      filename: ^acme_.*\.csv$
+      header: [E-Mail, Firma]
+  globex:
+    match:
+      header: [Email Address, Organisation]
+`))
+		assert.NoError(t, err)
+
+		// Act
+		byName, _ := cfg.DetectProfile("acme_2026-10.csv", "Email Address,Organisation")
+		byHeader, _ := cfg.DetectProfile("drop.csv", "Name;e-mail;FIRMA;Quelle")
+		byOtherHeader, _ := cfg.DetectProfile("", "\ufeffName,Email Address,Organisation")
+		_, matched := cfg.DetectProfile("drop.csv", "Name,Email,Company")
+
+		// Assert
+		assert.Equal(t, "acme", byName)
+		assert.Equal(t, "acme", byHeader)
+		assert.Equal(t, "globex", byOtherHeader)
+		assert.False(t, matched)
+	})
+
+	t.Run("rejects invalid filename patterns", func(t *testing.T) {
+		// Act
+		_, err := Parse([]byte("profiles:\n  acme:\n    match:\n      filename: '[acme'\n"))
+
+		// Assert
+		assert.ErrorContains(t, err, "profiles.acme: match.filename")
+	})
+
+	t.Run("rejects profile policies it doesn't know", func(t *testing.T) {
+		// Act
+		_, err := Parse([]byte("profiles:\n  acme:\n    policy:\n      name: fields\n      fields: [email]\n"))
+
+		// Assert
+		assert.ErrorContains(t, err, `profiles.acme: policy: unknown field "email"`)
+	})
+
+	t.Run("rejects unknown anomaly actions", func(t *testing.T) {
+		// Act
+		_, err := Parse([]byte("anomalies:\n  action: page\n  created_drop: 80\n"))
+
+		// Assert
+		assert.ErrorContains(t, err, "anomalies: action")
+	})
+
+	t.Run("rejects unknown ID schemes", func(t *testing.T) {
+		// Act
+		_, err := Parse([]byte("id_scheme: uuid1\n"))
+
+		// Assert
+		assert.ErrorContains(t, err, "id_scheme: unknown ID scheme")
+	})
+
+	t.Run("rejects stage orders that skip or reorder built-in stages", func(t *testing.T) {
+		// Act
+		_, unknown := Parse([]byte("stages: [validate, normalize, dedup, lookup, enrich, decide, write]\n"))
+		_, reordered := Parse([]byte("stages: [validate, normalize, dedup, decide, lookup, write]\n"))
+		cfg, valid := Parse([]byte("stages: [validate, normalize, dedup, lookup, decide, write]\n"))
+
+		// Assert
+		assert.ErrorContains(t, unknown, `stages: unknown stage "enrich"`)
+		assert.ErrorContains(t, reordered, "stages: the built-in stages must all be listed")
+		assert.NoError(t, valid)
+		assert.Len(t, cfg.Stages, 6)
+	})
+
+	t.Run("reads territory rules", func(t *testing.T) {
+		// Act
+		cfg, err := Parse([]byte("territories:\n  default: EMEA\n  rules:\n    - territory: Americas\n      countries: [US, CA]\n"))
+		_, invalid := Parse([]byte("territories:\n  rules:\n    - territory: Americas\n"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "EMEA", cfg.Territories.Default)
+		assert.Equal(t, []string{"US", "CA"}, cfg.Territories.Rules[0].Countries)
+		assert.ErrorContains(t, invalid, "territories: rules[0]")
+	})
+
+	t.Run("reads language detection", func(t *testing.T) {
+		// Act
+		cfg, err := Parse([]byte("language_detection:\n  columns: [Comments]\n  languages: [en, de]\n"))
+		_, invalid := Parse([]byte("language_detection:\n  columns: [Comments]\n  languages: [english]\n"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Comments"}, cfg.LanguageDetection.Columns)
+		assert.ErrorContains(t, invalid, `language_detection: unknown language "english"`)
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		// Arrange
+		data := []byte("protected_feilds: [source]\n")
+
+		// Act
+		_, err := Parse(data)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestConfig_Load(t *testing.T) {
+	t.Run("reports missing files", func(t *testing.T) {
+		// Act
+		_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("loads a file from disk", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte("protected_fields: [company]\n"), 0o644))
+
+		// Act
+		cfg, err := Load(path)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"company"}, cfg.ProtectedFields)
+	})
+}
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	assert.NoError(t, secrets.Register("config-test", secrets.ResolverFunc(func(ctx context.Context, ref string) (string, error) {
+		if ref == "missing" {
+			return "", errors.New("no such secret")
+		}
+		return "resolved-" + ref, nil
+	})))
+
+	t.Run("replaces references and keeps plain values", func(t *testing.T) {
+		// Arrange
+		cfg, err := Parse([]byte("api_token: config-test://api\nmailchimp:\n  api_key: plain-us1\nserve:\n  forms_token: config-test://forms\n  tokens:\n    - name: ci\n      token: config-test://ci\n      role: uploader\n"))
+		assert.NoError(t, err)
+
+		// Act
+		resolved, err := cfg.ResolveSecrets(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 3, resolved)
+		assert.Equal(t, "resolved-api", cfg.APIToken)
+		assert.Equal(t, "plain-us1", cfg.Mailchimp.APIKey)
+		assert.Equal(t, "resolved-forms", cfg.Serve.FormsToken)
+		assert.Equal(t, "resolved-ci", cfg.Serve.Tokens[0].Token)
+	})
+
+	t.Run("names the field of a failed reference", func(t *testing.T) {
+		// Arrange
+		cfg := &Config{}
+		cfg.Zoho.RefreshToken = "config-test://missing"
+
+		// Act
+		_, err := cfg.ResolveSecrets(context.Background())
+
+		// Assert
+		assert.EqualError(t, err, "zoho.refresh_token: failed to resolve config-test://missing: no such secret")
+	})
+}
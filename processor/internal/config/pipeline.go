@@ -0,0 +1,192 @@
+package config
+
+import (
+	"code/internal/csv"
+	"code/internal/datalake"
+	"code/internal/emailcheck"
+	"code/internal/notify"
+	"code/internal/processor"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pipeline is a whole import declared in the config and run with run <name>:
+// where its leads come from, how they are transformed, validated and
+// deduplicated, the CRM they go to, where the leftovers are written and who
+// is told how it went. Each setting stands in for the process flag of the
+// same name.
+type Pipeline struct {
+	// Description says what the pipeline is for, listed by run without a
+	// pipeline
+	Description string `yaml:"description"`
+
+	// Profile applies a partner's profile first; the pipeline's own
+	// settings take precedence over it
+	Profile string `yaml:"profile"`
+
+	// Labels label every run of the pipeline, like --label
+	Labels map[string]string `yaml:"labels"`
+
+	Source        PipelineSource     `yaml:"source"`
+	Transforms    PipelineTransforms `yaml:"transforms"`
+	Validation    PipelineValidation `yaml:"validation"`
+	Dedup         PipelineDedup      `yaml:"dedup"`
+	Target        PipelineTarget     `yaml:"target"`
+	Sinks         PipelineSinks      `yaml:"sinks"`
+	Notifications []notify.Webhook   `yaml:"notifications"`
+}
+
+// PipelineSource is the input process reads
+type PipelineSource struct {
+	// Input is a file, a Google Sheet URL or meta:<form-ids>, as process
+	// takes it
+	Input      string `yaml:"input"`
+	Format     string `yaml:"format"`
+	Delimiter  string `yaml:"delimiter"`
+	Quote      string `yaml:"quote"`
+	Header     string `yaml:"header"`
+	SheetRange string `yaml:"sheet_range"`
+	MetaSource string `yaml:"meta_source"`
+}
+
+// PipelineTransforms pick and rewrite the leads read
+type PipelineTransforms struct {
+	InferSource bool   `yaml:"infer_source"`
+	Filter      string `yaml:"filter"`
+	OrderBy     string `yaml:"order_by"`
+}
+
+// PipelineValidation decides which leads are fit to send, before and after
+// the run
+type PipelineValidation struct {
+	// Email is the --email-validation level
+	Email          string `yaml:"email"`
+	RequireConsent bool   `yaml:"require_consent"`
+	Preflight      int    `yaml:"preflight"`
+	// PreflightMaxInvalid and PreflightMaxConflicts keep process's defaults
+	// when left out
+	PreflightMaxInvalid   *float64      `yaml:"preflight_max_invalid"`
+	PreflightMaxConflicts *float64      `yaml:"preflight_max_conflicts"`
+	Reconcile             int           `yaml:"reconcile"`
+	ReconcileDelay        time.Duration `yaml:"reconcile_delay"`
+}
+
+// PipelineDedup decides which leads are already known
+type PipelineDedup struct {
+	HistoryDir  string `yaml:"history_dir"`
+	SkipSeen    int    `yaml:"skip_seen"`
+	NoReprocess bool   `yaml:"no_reprocess"`
+	Mirror      string `yaml:"mirror"`
+	Bloom       string `yaml:"bloom"`
+}
+
+// PipelineTarget is the CRM the leads are written to, and how
+type PipelineTarget struct {
+	Provider         string        `yaml:"provider"`
+	APIURL           string        `yaml:"api_url"`
+	Policy           ProfilePolicy `yaml:"policy"`
+	RespectOwnership bool          `yaml:"respect_ownership"`
+	Workers          int           `yaml:"workers"`
+	MaxAPICalls      int           `yaml:"max_api_calls"`
+	MaxCreates       int           `yaml:"max_creates"`
+	Mailchimp        bool          `yaml:"mailchimp"`
+	Attachments      bool          `yaml:"attachments"`
+	Note             string        `yaml:"note"`
+}
+
+// PipelineSinks are where the run writes what it didn't send, and its
+// record of what it did
+type PipelineSinks struct {
+	Rejects        string `yaml:"rejects"`
+	SplitRejectsBy string `yaml:"split_rejects_by"`
+	DeadLetter     string `yaml:"dead_letter"`
+	Deferred       string `yaml:"deferred"`
+	Review         string `yaml:"review"`
+	Export         string `yaml:"export"`
+	ExportFormat   string `yaml:"export_format"`
+	Events         string `yaml:"events"`
+}
+
+// Validate checks the pipeline's fields and values. Whether its profile
+// exists is checked by the config, which holds the profiles.
+func (p *Pipeline) Validate() error {
+	if strings.TrimSpace(p.Source.Input) == "" {
+		return fmt.Errorf("source.input is required")
+	}
+	if p.Source.Delimiter != "" {
+		if _, err := csv.ParseDelimiter(p.Source.Delimiter); err != nil {
+			return fmt.Errorf("source.delimiter: %w", err)
+		}
+	}
+
+	if p.Validation.Email != "" {
+		if _, err := emailcheck.ParseLevel(p.Validation.Email); err != nil {
+			return fmt.Errorf("validation.email: %w", err)
+		}
+	}
+	if p.Validation.Preflight < 0 {
+		return fmt.Errorf("validation.preflight cannot be negative")
+	}
+	for name, limit := range map[string]*float64{"preflight_max_invalid": p.Validation.PreflightMaxInvalid, "preflight_max_conflicts": p.Validation.PreflightMaxConflicts} {
+		if limit != nil && (*limit < 0 || *limit > 100) {
+			return fmt.Errorf("validation.%s must be between 0 and 100", name)
+		}
+	}
+	if p.Validation.Reconcile < 0 {
+		return fmt.Errorf("validation.reconcile cannot be negative")
+	}
+	if p.Validation.ReconcileDelay < 0 {
+		return fmt.Errorf("validation.reconcile_delay cannot be negative")
+	}
+
+	if p.Dedup.SkipSeen < 0 {
+		return fmt.Errorf("dedup.skip_seen cannot be negative")
+	}
+
+	if p.Target.Policy.Name != "" || len(p.Target.Policy.Fields) > 0 {
+		if _, err := processor.ParsePolicy(p.Target.Policy.Name, p.Target.Policy.Fields); err != nil {
+			return fmt.Errorf("target.policy: %w", err)
+		}
+	}
+	for name, value := range map[string]int{"workers": p.Target.Workers, "max_api_calls": p.Target.MaxAPICalls, "max_creates": p.Target.MaxCreates} {
+		if value < 0 {
+			return fmt.Errorf("target.%s cannot be negative", name)
+		}
+	}
+
+	if p.Sinks.ExportFormat != "" && !slices.Contains(datalake.Formats, p.Sinks.ExportFormat) {
+		return fmt.Errorf("sinks.export_format: unknown format %q (allowed: %s)", p.Sinks.ExportFormat, strings.Join(datalake.Formats, ", "))
+	}
+
+	for i, webhook := range p.Notifications {
+		if err := webhook.Validate(); err != nil {
+			return fmt.Errorf("notifications[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// PipelineNames lists the config's pipelines alphabetically
+func (c *Config) PipelineNames() []string {
+	names := make([]string, 0, len(c.Pipelines))
+	for name := range c.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Pipeline returns the named pipeline
+func (c *Config) Pipeline(name string) (Pipeline, error) {
+	pipeline, ok := c.Pipelines[name]
+	if !ok {
+		if len(c.Pipelines) == 0 {
+			return Pipeline{}, fmt.Errorf("unknown pipeline %q: the config has no pipelines", name)
+		}
+		return Pipeline{}, fmt.Errorf("unknown pipeline %q (available: %s)", name, strings.Join(c.PipelineNames(), ", "))
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"code/internal/csv"
+	"code/internal/processor"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Profile holds the settings of one partner's imports, selected with
+// --profile, so their quirks live in versioned config instead of on the
+// command line. Flags given explicitly still take precedence.
+type Profile struct {
+	// Delimiter separates the columns of the partner's CSV files, e.g. ";"
+	// or "tab"; a comma by default
+	Delimiter string `yaml:"delimiter"`
+
+	// Mapping names the column to read for a lead field, on top of the
+	// config's mapping
+	Mapping map[string]string `yaml:"mapping"`
+
+	// Transforms rewrite the partner's leads before validation
+	Transforms ProfileTransforms `yaml:"transforms"`
+
+	// Dedup decides which leads of earlier runs are skipped
+	Dedup ProfileDedup `yaml:"dedup"`
+
+	// Target is the CRM the partner's leads are written to
+	Target ProfileTarget `yaml:"target"`
+
+	// Policy decides whether the partner's leads are created, updated or
+	// left alone
+	Policy ProfilePolicy `yaml:"policy"`
+
+	// Match recognises the partner's files, so serve can pick the profile
+	// of each import itself
+	Match ProfileMatch `yaml:"match"`
+
+	// Schema is the exact header the partner's files are expected to have
+	Schema ProfileSchema `yaml:"schema"`
+}
+
+// ProfileTransforms are the rewrites a profile applies to each lead
+type ProfileTransforms struct {
+	// InferSource fills in a missing source, like --infer-source
+	InferSource bool `yaml:"infer_source"`
+	// Defaults fill in fields the file leaves empty, e.g. a fixed source
+	Defaults map[string]string `yaml:"defaults"`
+}
+
+// ProfileDedup stands in for the run-history flags of the same names
+type ProfileDedup struct {
+	SkipSeen    int  `yaml:"skip_seen"`
+	NoReprocess bool `yaml:"no_reprocess"`
+}
+
+// ProfileTarget stands in for --provider and --api-url
+type ProfileTarget struct {
+	Provider string `yaml:"provider"`
+	APIURL   string `yaml:"api_url"`
+}
+
+// ProfilePolicy stands in for --policy and --policy-fields
+type ProfilePolicy struct {
+	Name   string   `yaml:"name"`
+	Fields []string `yaml:"fields"`
+}
+
+// ProfileMatch recognises a partner's files by name or header
+type ProfileMatch struct {
+	// Filename is a regular expression the file's name matches, e.g.
+	// ^acme_.*\.csv$
+	Filename string `yaml:"filename"`
+	// Header lists columns the file's header has, in any order and case
+	Header []string `yaml:"header"`
+
+	filename *regexp.Regexp
+}
+
+// ProfileSchema is a manifest of a partner's header, to catch changes to
+// their format before their data is imported
+type ProfileSchema struct {
+	// Header lists every column in order, named exactly as in the file
+	Header []string `yaml:"header"`
+	// Strict fails a file whose header differs in any way, instead of only
+	// warning about it
+	Strict bool `yaml:"strict"`
+}
+
+// Comma returns the profile's column delimiter, or 0 for the default
+func (p Profile) Comma() rune {
+	comma, _ := csv.ParseDelimiter(p.Delimiter)
+	return comma
+}
+
+// Validate checks the profile's fields and values, canonicalising their names
+func (p *Profile) Validate() error {
+	if _, err := csv.ParseDelimiter(p.Delimiter); err != nil {
+		return fmt.Errorf("delimiter: %w", err)
+	}
+
+	mapping := make(map[string]string, len(p.Mapping))
+	for field, column := range p.Mapping {
+		name, ok := mappableField(field)
+		if !ok {
+			return fmt.Errorf("mapping: unknown field %q (allowed: %s)", field, strings.Join(csv.Fields(), ", "))
+		}
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("mapping: no column given for %s", name)
+		}
+		mapping[name] = column
+	}
+	if p.Mapping != nil {
+		p.Mapping = mapping
+	}
+
+	defaults := make(map[string]string, len(p.Transforms.Defaults))
+	for field, value := range p.Transforms.Defaults {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if !isProtectableField(name) {
+			return fmt.Errorf("transforms.defaults: %q cannot be defaulted (allowed: name, company, source, status)", field)
+		}
+		if name == "source" {
+			canonical, ok := validSource(value)
+			if !ok {
+				return fmt.Errorf("transforms.defaults: unknown source %q", value)
+			}
+			value = canonical
+		}
+		defaults[name] = value
+	}
+	if p.Transforms.Defaults != nil {
+		p.Transforms.Defaults = defaults
+	}
+
+	if p.Dedup.SkipSeen < 0 {
+		return fmt.Errorf("dedup.skip_seen cannot be negative")
+	}
+
+	if p.Policy.Name != "" || len(p.Policy.Fields) > 0 {
+		if _, err := processor.ParsePolicy(p.Policy.Name, p.Policy.Fields); err != nil {
+			return fmt.Errorf("policy: %w", err)
+		}
+	}
+
+	if p.Match.Filename != "" {
+		pattern, err := regexp.Compile(p.Match.Filename)
+		if err != nil {
+			return fmt.Errorf("match.filename: %w", err)
+		}
+		p.Match.filename = pattern
+	}
+	for _, column := range p.Match.Header {
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("match.header: a column has no name")
+		}
+	}
+
+	if p.Schema.Strict && len(p.Schema.Header) == 0 {
+		return fmt.Errorf("schema.strict needs a header")
+	}
+	for i, column := range p.Schema.Header {
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("schema.header: column %d has no name", i+1)
+		}
+	}
+	return nil
+}
+
+// matchesFilename reports whether the file's name fits the profile's pattern
+func (p Profile) matchesFilename(filename string) bool {
+	return p.Match.filename != nil && filename != "" && p.Match.filename.MatchString(filename)
+}
+
+// matchesHeader reports whether the header line, split on the profile's
+// delimiter, has every column of the profile's signature
+func (p Profile) matchesHeader(headerLine string) bool {
+	return len(p.Match.Header) > 0 && csv.HasColumns(headerLine, p.Comma(), p.Match.Header)
+}
+
+// ProfileNames lists the config's profiles alphabetically
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile
+func (c *Config) Profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return Profile{}, fmt.Errorf("unknown profile %q: the config has no profiles", name)
+		}
+		return Profile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// DetectProfile picks the profile of a file from its name, or failing that
+// its header line. Names are tried before headers, and profiles in
+// alphabetical order, so the pick doesn't depend on map order.
+func (c *Config) DetectProfile(filename, headerLine string) (string, bool) {
+	names := c.ProfileNames()
+	for _, name := range names {
+		if c.Profiles[name].matchesFilename(filename) {
+			return name, true
+		}
+	}
+	for _, name := range names {
+		if c.Profiles[name].matchesHeader(headerLine) {
+			return name, true
+		}
+	}
+	return "", false
+}
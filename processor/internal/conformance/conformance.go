@@ -0,0 +1,467 @@
+package conformance
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Check outcomes; skipped checks couldn't run against this API
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Check names, in the order the suite runs them
+const (
+	CheckVersion       = "version"
+	CheckLookupMiss    = "lookup-miss"
+	CheckCreate        = "create"
+	CheckLookupHit     = "lookup-hit"
+	CheckDuplicate     = "duplicate-create"
+	CheckUpdate        = "update"
+	CheckUpdateMissing = "update-missing"
+	CheckValidation    = "validation-error"
+	CheckRateLimit     = "rate-limit"
+	CheckCleanup       = "cleanup"
+)
+
+// Retries of rate-limited requests outside the rate-limit check
+const (
+	maxAttempts       = 4
+	defaultRetryDelay = 500 * time.Millisecond
+	maxRetryDelay     = 5 * time.Second
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report collects the results of the suite
+type Report struct {
+	Target  string   `json:"target"`
+	Results []Result `json:"results"`
+}
+
+// Count returns the number of results with the given status
+func (r Report) Count(status Status) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Options adjust the suite
+type Options struct {
+	// Burst is how many lookups are sent at once to provoke a 429; 0 skips
+	// the rate-limit check
+	Burst int
+}
+
+// Suite runs requests against a lead API and checks its answers against
+// the contract lead-processor relies on. It creates, updates and, where the
+// API allows, deletes one test lead with a random example.com email.
+type Suite struct {
+	client  *http.Client
+	baseURL string
+	opts    Options
+
+	email  string
+	leadID string
+}
+
+// New prepares a suite against the API at baseURL. client must not retry
+// or pace requests, or the rate-limit check can't see the API's 429s.
+func New(client *http.Client, baseURL string, opts Options) *Suite {
+	return &Suite{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		opts:    opts,
+		email:   "conformance-" + randomHex() + "@example.com",
+	}
+}
+
+// lead is a lead as the API returns it
+type lead struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Company   string `json:"company"`
+	Source    string `json:"source"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// errorBody is the API's error format
+type errorBody struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Run runs every check in order. A check whose setup failed, such as the
+// lookup hit after a failed create, is skipped.
+func (s *Suite) Run() Report {
+	report := Report{Target: s.baseURL}
+	add := func(name string, status Status, format string, args ...interface{}) {
+		report.Results = append(report.Results, Result{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	s.checkVersion(add)
+	s.checkLookupMiss(add)
+	created := s.checkCreate(add)
+	if created {
+		s.checkLookupHit(add)
+		s.checkDuplicate(add)
+		s.checkUpdate(add)
+	} else {
+		for _, name := range []string{CheckLookupHit, CheckDuplicate, CheckUpdate} {
+			add(name, StatusSkip, "needs the test lead, which wasn't created")
+		}
+	}
+	s.checkUpdateMissing(add)
+	s.checkValidation(add)
+	s.checkRateLimit(add)
+	if created {
+		s.checkCleanup(add)
+	}
+	return report
+}
+
+type addFunc func(name string, status Status, format string, args ...interface{})
+
+func (s *Suite) checkVersion(add addFunc) {
+	var version struct {
+		Version      string   `json:"version"`
+		Capabilities []string `json:"capabilities"`
+	}
+	status, err := s.do(http.MethodGet, "/api/version", nil, &version)
+	switch {
+	case err != nil:
+		add(CheckVersion, StatusFail, "%v", err)
+	case status == http.StatusNotFound:
+		add(CheckVersion, StatusPass, "no /api/version; treated as a legacy server with lookup, create and update only")
+	case status != http.StatusOK:
+		add(CheckVersion, StatusFail, "expected 200 or 404, got %d", status)
+	case version.Version == "":
+		add(CheckVersion, StatusFail, "the response has no version")
+	default:
+		add(CheckVersion, StatusPass, "version %s, capabilities: %s", version.Version, listOrNone(version.Capabilities))
+	}
+}
+
+func (s *Suite) checkLookupMiss(add addFunc) {
+	var lookup struct {
+		Found *bool `json:"found"`
+	}
+	status, err := s.do(http.MethodGet, s.lookupPath("conformance-missing-"+randomHex()+"@example.com"), nil, &lookup)
+	switch {
+	case err != nil:
+		add(CheckLookupMiss, StatusFail, "%v", err)
+	case status != http.StatusOK:
+		add(CheckLookupMiss, StatusFail, "expected 200 with found false for an unknown email, got %d", status)
+	case lookup.Found == nil:
+		add(CheckLookupMiss, StatusFail, "the response has no found field")
+	case *lookup.Found:
+		add(CheckLookupMiss, StatusFail, "an unknown email was reported found")
+	default:
+		add(CheckLookupMiss, StatusPass, "an unknown email is reported as not found")
+	}
+}
+
+func (s *Suite) checkCreate(add addFunc) bool {
+	var created struct {
+		Lead *lead `json:"lead"`
+	}
+	status, err := s.do(http.MethodPost, "/api/leads/create", s.testLead("Conformance Inc"), &created)
+	switch {
+	case err != nil:
+		add(CheckCreate, StatusFail, "%v", err)
+	case status != http.StatusCreated:
+		add(CheckCreate, StatusFail, "expected 201, got %d", status)
+	case created.Lead == nil:
+		add(CheckCreate, StatusFail, "the response has no lead")
+	default:
+		s.leadID = created.Lead.ID
+		if missing := missingFields(created.Lead); len(missing) > 0 {
+			add(CheckCreate, StatusFail, "the created lead lacks required fields: %s", strings.Join(missing, ", "))
+		} else {
+			add(CheckCreate, StatusPass, "created %s as lead %s", s.email, created.Lead.ID)
+		}
+		return true
+	}
+	return false
+}
+
+func (s *Suite) checkLookupHit(add addFunc) {
+	var lookup struct {
+		Found bool  `json:"found"`
+		Lead  *lead `json:"lead"`
+	}
+	status, err := s.do(http.MethodGet, s.lookupPath(s.email), nil, &lookup)
+	switch {
+	case err != nil:
+		add(CheckLookupHit, StatusFail, "%v", err)
+	case status != http.StatusOK:
+		add(CheckLookupHit, StatusFail, "expected 200, got %d", status)
+	case !lookup.Found || lookup.Lead == nil:
+		add(CheckLookupHit, StatusFail, "the created lead wasn't found")
+	case !strings.EqualFold(lookup.Lead.Email, s.email):
+		add(CheckLookupHit, StatusFail, "the lookup returned %s instead of %s", lookup.Lead.Email, s.email)
+	default:
+		add(CheckLookupHit, StatusPass, "the created lead is found by email")
+	}
+}
+
+func (s *Suite) checkDuplicate(add addFunc) {
+	var body errorBody
+	status, err := s.do(http.MethodPost, "/api/leads/create", s.testLead("Conformance Inc"), &body)
+	switch {
+	case err != nil:
+		add(CheckDuplicate, StatusFail, "%v", err)
+	case status != http.StatusConflict:
+		add(CheckDuplicate, StatusFail, "expected 409 for an email that already exists, got %d", status)
+	default:
+		s.addErrorFormat(add, CheckDuplicate, body, "a second create of the same email is refused with 409")
+	}
+}
+
+func (s *Suite) checkUpdate(add addFunc) {
+	var updated struct {
+		Lead *lead `json:"lead"`
+	}
+	status, err := s.do(http.MethodPost, "/api/leads/update", s.testLead("Conformance Updated Inc"), &updated)
+	switch {
+	case err != nil:
+		add(CheckUpdate, StatusFail, "%v", err)
+	case status != http.StatusOK:
+		add(CheckUpdate, StatusFail, "expected 200, got %d", status)
+	case updated.Lead == nil:
+		add(CheckUpdate, StatusFail, "the response has no lead")
+	case updated.Lead.Company != "Conformance Updated Inc":
+		add(CheckUpdate, StatusFail, "the returned lead's company is %q, not the updated value", updated.Lead.Company)
+	default:
+		add(CheckUpdate, StatusPass, "the lead's company was changed")
+	}
+}
+
+func (s *Suite) checkUpdateMissing(add addFunc) {
+	missing := map[string]string{"name": "Conformance Missing", "email": "conformance-missing-" + randomHex() + "@example.com", "company": "Conformance Inc", "source": "Website"}
+	var body errorBody
+	status, err := s.do(http.MethodPost, "/api/leads/update", missing, &body)
+	switch {
+	case err != nil:
+		add(CheckUpdateMissing, StatusFail, "%v", err)
+	case status != http.StatusNotFound:
+		add(CheckUpdateMissing, StatusFail, "expected 404 for an unknown email, got %d", status)
+	default:
+		s.addErrorFormat(add, CheckUpdateMissing, body, "updating an unknown email is refused with 404")
+	}
+}
+
+func (s *Suite) checkValidation(add addFunc) {
+	invalid := map[string]string{"name": "Conformance Invalid", "email": "not-an-email", "company": "Conformance Inc", "source": "Website"}
+	var body errorBody
+	status, err := s.do(http.MethodPost, "/api/leads/create", invalid, &body)
+	switch {
+	case err != nil:
+		add(CheckValidation, StatusFail, "%v", err)
+	case status != http.StatusBadRequest:
+		add(CheckValidation, StatusFail, "expected 400 for an invalid email, got %d", status)
+	default:
+		s.addErrorFormat(add, CheckValidation, body, "an invalid lead is refused with 400")
+	}
+}
+
+// addErrorFormat passes an error response that says what went wrong
+func (s *Suite) addErrorFormat(add addFunc, name string, body errorBody, passed string) {
+	if body.Error == "" && body.Message == "" {
+		add(name, StatusFail, "the error response has neither error nor message")
+		return
+	}
+	add(name, StatusPass, "%s", passed)
+}
+
+// checkRateLimit sends a burst of lookups at once. A 429 must say when to
+// retry, so the client's backoff waits as long as the API asks.
+func (s *Suite) checkRateLimit(add addFunc) {
+	if s.opts.Burst <= 0 {
+		add(CheckRateLimit, StatusSkip, "no burst requested")
+		return
+	}
+
+	var (
+		mu         sync.Mutex
+		limited    int
+		retryAfter bool
+		failure    error
+		wg         sync.WaitGroup
+	)
+	for i := 0; i < s.opts.Burst; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest(http.MethodGet, s.baseURL+s.lookupPath(s.email), nil)
+			if err != nil {
+				return
+			}
+			resp, err := s.client.Do(req)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failure = err
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusTooManyRequests {
+				return
+			}
+			limited++
+			var body struct {
+				RetryAfter int `json:"retryAfter"`
+			}
+			json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+			if _, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil || body.RetryAfter > 0 {
+				retryAfter = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case limited == 0 && failure != nil:
+		add(CheckRateLimit, StatusFail, "%v", failure)
+	case limited == 0:
+		add(CheckRateLimit, StatusSkip, "no 429 after %d simultaneous lookups", s.opts.Burst)
+	case !retryAfter:
+		add(CheckRateLimit, StatusFail, "%d of %d lookups got 429 without Retry-After or retryAfter", limited, s.opts.Burst)
+	default:
+		add(CheckRateLimit, StatusPass, "%d of %d lookups got 429 saying when to retry", limited, s.opts.Burst)
+	}
+}
+
+// checkCleanup deletes the test lead where the API supports deletes
+func (s *Suite) checkCleanup(add addFunc) {
+	if s.leadID == "" {
+		add(CheckCleanup, StatusSkip, "the created lead has no id; %s was left in place", s.email)
+		return
+	}
+	status, err := s.do(http.MethodDelete, "/api/leads/"+url.PathEscape(s.leadID), nil, nil)
+	switch {
+	case err != nil:
+		add(CheckCleanup, StatusFail, "%v", err)
+	case status == http.StatusNoContent || status == http.StatusOK:
+		add(CheckCleanup, StatusPass, "deleted the test lead")
+	case status == http.StatusNotFound || status == http.StatusMethodNotAllowed:
+		add(CheckCleanup, StatusSkip, "the API doesn't delete leads; %s was left in place", s.email)
+	default:
+		add(CheckCleanup, StatusFail, "expected 204, got %d; %s was left in place", status, s.email)
+	}
+}
+
+// testLead is the suite's lead with the given company
+func (s *Suite) testLead(company string) map[string]string {
+	return map[string]string{"name": "Conformance Test", "email": s.email, "company": company, "source": "Website", "status": "new"}
+}
+
+func (s *Suite) lookupPath(email string) string {
+	return "/api/leads/lookup?email=" + url.QueryEscape(email)
+}
+
+// do sends a request and decodes a JSON answer into out, returning the
+// status. A body that isn't JSON is an error for 2xx answers only, since
+// error formats are checked by the caller. Rate-limited requests are
+// retried, as the processor would, so other checks aren't failed by a 429.
+func (s *Suite) do(method, path string, body, out interface{}) (int, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, s.baseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return 0, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err = s.client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("request failed: %w", err)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == maxAttempts {
+			break
+		}
+		resp.Body.Close()
+		time.Sleep(retryDelay(resp))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return resp.StatusCode, nil
+	}
+	if err := json.Unmarshal(data, out); err != nil && resp.StatusCode < 300 {
+		return resp.StatusCode, fmt.Errorf("status %d with a body that isn't the expected JSON: %w", resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay returns how long a 429 asks to wait, within reason
+func retryDelay(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, maxRetryDelay)
+	}
+	return defaultRetryDelay
+}
+
+// missingFields lists the lead's empty required fields
+func missingFields(l *lead) []string {
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"id", l.ID}, {"name", l.Name}, {"email", l.Email}, {"company", l.Company}, {"source", l.Source}, {"createdAt", l.CreatedAt},
+	} {
+		if field.value == "" {
+			missing = append(missing, field.name)
+		}
+	}
+	return missing
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func randomHex() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,153 @@
+package conformance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPI is a small lead API that follows the contract, with switches to
+// break parts of it
+type fakeAPI struct {
+	mu    sync.Mutex
+	leads map[string]map[string]string
+
+	rateLimitAfter int64
+	lookups        atomic.Int64
+	plainErrors    bool
+	noDelete       bool
+}
+
+func (f *fakeAPI) fail(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	if f.plainErrors {
+		w.Write([]byte(message))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/version":
+		json.NewEncoder(w).Encode(map[string]interface{}{"version": "2.1.0", "capabilities": []string{"patch"}})
+	case r.URL.Path == "/api/leads/lookup":
+		if f.rateLimitAfter > 0 && f.lookups.Add(1) > f.rateLimitAfter {
+			w.Header().Set("Retry-After", "1")
+			f.fail(w, http.StatusTooManyRequests, "slow down")
+			return
+		}
+		lead, found := f.leads[r.URL.Query().Get("email")]
+		json.NewEncoder(w).Encode(map[string]interface{}{"found": found, "lead": lead})
+	case r.URL.Path == "/api/leads/create" || r.URL.Path == "/api/leads/update":
+		var lead map[string]string
+		json.NewDecoder(r.Body).Decode(&lead)
+		_, exists := f.leads[lead["email"]]
+		switch {
+		case !strings.Contains(lead["email"], "@"):
+			f.fail(w, http.StatusBadRequest, "invalid email")
+		case r.URL.Path == "/api/leads/create" && exists:
+			f.fail(w, http.StatusConflict, "lead exists")
+		case r.URL.Path == "/api/leads/update" && !exists:
+			f.fail(w, http.StatusNotFound, "lead not found")
+		default:
+			lead["id"] = "lead-1"
+			lead["createdAt"] = "2026-10-16T09:00:00Z"
+			f.leads[lead["email"]] = lead
+			if r.URL.Path == "/api/leads/create" {
+				w.WriteHeader(http.StatusCreated)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "lead": lead})
+		}
+	case r.Method == http.MethodDelete && !f.noDelete:
+		for email, lead := range f.leads {
+			if "/api/leads/"+lead["id"] == r.URL.Path {
+				delete(f.leads, email)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func runSuite(t *testing.T, api *fakeAPI, opts Options) map[string]Result {
+	api.leads = make(map[string]map[string]string)
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	report := New(server.Client(), server.URL, opts).Run()
+	results := make(map[string]Result)
+	for _, result := range report.Results {
+		results[result.Name] = result
+	}
+	return results
+}
+
+func TestSuite(t *testing.T) {
+	t.Run("passes an API that follows the contract", func(t *testing.T) {
+		// Arrange
+		api := &fakeAPI{rateLimitAfter: 5}
+
+		// Act
+		results := runSuite(t, api, Options{Burst: 20})
+
+		// Assert
+		assert.Len(t, results, 10)
+		for name, result := range results {
+			assert.Equal(t, StatusPass, result.Status, "%s: %s", name, result.Message)
+		}
+		assert.Contains(t, results[CheckVersion].Message, "2.1.0")
+		assert.Empty(t, api.leads)
+	})
+
+	t.Run("fails error responses without a message", func(t *testing.T) {
+		// Arrange
+		api := &fakeAPI{plainErrors: true}
+
+		// Act
+		results := runSuite(t, api, Options{})
+
+		// Assert
+		assert.Equal(t, StatusFail, results[CheckDuplicate].Status)
+		assert.Equal(t, StatusFail, results[CheckValidation].Status)
+		assert.Equal(t, StatusPass, results[CheckCreate].Status)
+	})
+
+	t.Run("skips what the API doesn't do", func(t *testing.T) {
+		// Arrange
+		api := &fakeAPI{noDelete: true}
+
+		// Act
+		results := runSuite(t, api, Options{Burst: 5})
+
+		// Assert
+		assert.Equal(t, StatusSkip, results[CheckRateLimit].Status)
+		assert.Equal(t, StatusSkip, results[CheckCleanup].Status)
+		assert.Contains(t, results[CheckCleanup].Message, "left in place")
+	})
+
+	t.Run("skips the checks needing the test lead when the create fails", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		// Act
+		report := New(server.Client(), server.URL, Options{}).Run()
+
+		// Assert
+		assert.Equal(t, 5, report.Count(StatusFail))
+		assert.Equal(t, 4, report.Count(StatusSkip))
+	})
+}
@@ -2,18 +2,271 @@ package csv
 
 import (
 	"code/internal/models"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
+// LeadSource is where a run reads its leads from: a CSV file, or a connector
+// such as a spreadsheet that yields rows in the same layout
+type LeadSource interface {
+	// Name identifies the source in logs, run history and checkpoints
+	Name() string
+	// Hash fingerprints the source's content so later runs recognise it
+	Hash() (string, error)
+	// ReadHeader returns the column names
+	ReadHeader() ([]string, error)
+	// StreamLeads calls emit for each lead in order
+	StreamLeads(emit func(*models.Lead) error) error
+}
+
 // CSVReader handles reading and parsing CSV files
-type CSVReader struct{}
+type CSVReader struct {
+	mapping    map[string]string
+	comma      rune
+	quote      rune
+	headerless bool
+	newID      models.IDGenerator
+	projection []string
+}
 
 // NewCSVReader creates a new CSV reader
 func NewCSVReader() *CSVReader {
 	return &CSVReader{}
 }
 
+// SetMapping names the header column to read for each lead field, overriding
+// the columns recognised by name. Keys are field names as listed by Fields.
+func (r *CSVReader) SetMapping(mapping map[string]string) {
+	r.mapping = mapping
+}
+
+// SetDelimiter sets the character separating columns; 0 keeps the comma
+func (r *CSVReader) SetDelimiter(comma rune) {
+	r.comma = comma
+}
+
+// SetQuote sets the character quoting cells, a double or single quote; 0 keeps
+// double quotes
+func (r *CSVReader) SetQuote(quote rune) {
+	r.quote = quote
+}
+
+// SetHeaderless reads the first row as a lead rather than a header. The
+// columns are then named "Column 1", "Column 2" and so on, and the core
+// fields read by position.
+func (r *CSVReader) SetHeaderless(headerless bool) {
+	r.headerless = headerless
+}
+
+// SetIDGenerator sets how the IDs of leads read are made; nil keeps
+// random UUIDs
+func (r *CSVReader) SetIDGenerator(newID models.IDGenerator) {
+	r.newID = newID
+}
+
+// SetProjection keeps only the named columns, besides those holding lead
+// fields, in the source row of each lead read (Lead.Raw). The cells of other
+// columns are never copied out of the parser's buffer, so a very wide file
+// takes memory for the columns used only. Columns the header lacks are
+// ignored; nil keeps every column.
+func (r *CSVReader) SetProjection(columns []string) {
+	r.projection = columns
+}
+
+// Projection splits a header into the columns leads keep under the
+// projection and the ones they drop, both in header order
+func (r *CSVReader) Projection(header []string) (kept, dropped []string, err error) {
+	columns, err := r.columns(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if columns.keep == nil {
+		return header, nil, nil
+	}
+	keep := make(map[int]bool, len(columns.keep))
+	for _, i := range columns.keep {
+		keep[i] = true
+	}
+	for i, column := range header {
+		if keep[i] {
+			kept = append(kept, column)
+		} else {
+			dropped = append(dropped, column)
+		}
+	}
+	return kept, dropped, nil
+}
+
+// columns maps the header's columns to lead fields
+func (r *CSVReader) columns(header []string) (columnMap, error) {
+	columns, err := newColumnMap(header, r.mapping)
+	if err != nil {
+		return columnMap{}, err
+	}
+	columns.newID = models.RandomID
+	if r.newID != nil {
+		columns.newID = r.newID
+	}
+	if r.projection != nil {
+		columns.project(r.projection)
+	}
+	return columns, nil
+}
+
+// newReader reads CSV from input in the reader's dialect
+func (r *CSVReader) newReader(input io.Reader) *rowReader {
+	swapQuotes := r.quote == '\''
+	if swapQuotes {
+		input = quoteSwapper{input}
+	}
+	reader := csv.NewReader(input)
+	if r.comma != 0 {
+		reader.Comma = r.comma
+	}
+	return &rowReader{reader: reader, swapQuotes: swapQuotes, headerless: r.headerless}
+}
+
+// rowReader reads the rows of CSV data, making up a header for a file
+// without one
+type rowReader struct {
+	reader *csv.Reader
+	// swapQuotes undoes quoteSwapper in each cell
+	swapQuotes bool
+	headerless bool
+	// first is the first row of a headerless file, read to make up the
+	// header and returned after it
+	first []string
+}
+
+// Read returns the next row, or io.EOF after the last. The first row is the
+// header.
+func (r *rowReader) Read() ([]string, error) {
+	if r.first != nil {
+		record := r.first
+		r.first = nil
+		return record, nil
+	}
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if r.swapQuotes {
+		for i, cell := range record {
+			if strings.ContainsAny(cell, `"'`) {
+				record[i] = strings.Map(swapQuote, cell)
+			}
+		}
+	}
+	if r.headerless {
+		r.headerless = false
+		r.first = slices.Clone(record)
+		header := make([]string, len(record))
+		for i := range header {
+			header[i] = fmt.Sprintf("Column %d", i+1)
+		}
+		return header, nil
+	}
+	return record, nil
+}
+
+// quoteSwapper swaps single and double quotes in CSV data, so that
+// encoding/csv, which only knows double quotes, reads cells quoted with
+// single ones. rowReader swaps them back in each cell.
+type quoteSwapper struct {
+	input io.Reader
+}
+
+func (s quoteSwapper) Read(p []byte) (int, error) {
+	n, err := s.input.Read(p)
+	for i, b := range p[:n] {
+		switch b {
+		case '"':
+			p[i] = '\''
+		case '\'':
+			p[i] = '"'
+		}
+	}
+	return n, err
+}
+
+// swapQuote swaps a single quote with a double one
+func swapQuote(r rune) rune {
+	switch r {
+	case '"':
+		return '\''
+	case '\'':
+		return '"'
+	}
+	return r
+}
+
+// File returns the CSV file at filePath as a lead source
+func (r *CSVReader) File(filePath string) *FileSource {
+	return &FileSource{reader: r, path: filePath}
+}
+
+// FileSource is a CSV file read as a lead source
+type FileSource struct {
+	reader *CSVReader
+	path   string
+}
+
+// Name returns the file's path
+func (s *FileSource) Name() string {
+	return s.path
+}
+
+// Hash returns the SHA-256 of the file's content
+func (s *FileSource) Hash() (string, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadHeader returns the file's header row
+func (s *FileSource) ReadHeader() ([]string, error) {
+	return s.reader.ReadHeader(s.path)
+}
+
+// StreamLeads streams the file's leads
+func (s *FileSource) StreamLeads(emit func(*models.Lead) error) error {
+	return s.reader.StreamLeads(s.path, emit)
+}
+
+// ReadHeader returns the header row of a CSV file
+func (r *CSVReader) ReadHeader(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, err := r.newReader(file).Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	return header, nil
+}
+
 // ReadLeads reads leads from a CSV file
 func (r *CSVReader) ReadLeads(filePath string) ([]*models.Lead, error) {
 	// Open the CSV file
@@ -24,26 +277,378 @@ func (r *CSVReader) ReadLeads(filePath string) ([]*models.Lead, error) {
 	defer file.Close()
 
 	// Create CSV reader
-	csvReader := csv.NewReader(file)
+	csvReader := r.newReader(file)
 
 	// Read all records
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, err
+	var records [][]string
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
 	}
 
-	// Skip header row and convert records to leads
+	// Map columns from the header row and convert records to leads
 	var leads []*models.Lead
+	var columns columnMap
 	for i, record := range records {
-		if i == 0 { // Skip header
+		if i == 0 { // Header
+			columns, err = r.columns(record)
+			if err != nil {
+				return nil, err
+			}
 			continue
 		}
 
-		if len(record) >= 4 {
-			lead := models.NewLead(record[0], record[1], record[2], record[3])
+		lead, ok := columns.lead(record)
+		if ok {
 			leads = append(leads, lead)
 		}
 	}
 
 	return leads, nil
 }
+
+// StreamLeads reads leads from a CSV file one row at a time, calling emit for
+// each lead so the whole file never has to be held in memory
+func (r *CSVReader) StreamLeads(filePath string, emit func(*models.Lead) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return r.StreamLeadsFrom(file, emit)
+}
+
+// StreamLeadsFrom reads leads from CSV data in input, calling emit for each lead
+func (r *CSVReader) StreamLeadsFrom(input io.Reader, emit func(*models.Lead) error) error {
+	csvReader := r.newReader(input)
+	csvReader.reader.ReuseRecord = true
+
+	// Map columns from the header row
+	header, err := csvReader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	columns, err := r.columns(header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		lead, ok := columns.lead(record)
+		if ok {
+			if err := emit(lead); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StreamRecords converts rows already split into cells, such as a
+// spreadsheet's, to leads, finding the columns by header
+func (r *CSVReader) StreamRecords(header []string, rows [][]string, emit func(*models.Lead) error) error {
+	next := 0
+	return r.StreamRows(header, func() ([]string, error) {
+		if next == len(rows) {
+			return nil, io.EOF
+		}
+		next++
+		return rows[next-1], nil
+	}, emit)
+}
+
+// StreamRows is StreamRecords for rows read one at a time, such as a Parquet
+// file's: next returns io.EOF after the last row
+func (r *CSVReader) StreamRows(header []string, next func() ([]string, error), emit func(*models.Lead) error) error {
+	columns, err := r.columns(header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		lead, ok := columns.lead(record)
+		if ok {
+			if err := emit(lead); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// columnMap records which CSV column holds each lead field. The four core
+// columns default to Name, Email, Company, Source order; optional columns are
+// only read when the header names them.
+type columnMap struct {
+	header           []string
+	name             int
+	email            int
+	company          int
+	source           int
+	status           int
+	consentGiven     int
+	consentTimestamp int
+	consentSource    int
+	territory        int
+	language         int
+	newID            models.IDGenerator
+	// keep lists the indexes of the columns kept in Lead.Raw, in order, or
+	// nil for every column
+	keep []int
+}
+
+// Fields lists the lead fields a CSV column can be mapped to
+func Fields() []string {
+	return []string{"name", "email", "company", "source", "status", "consentGiven", "consentTimestamp", "consentSource", "territory", "language"}
+}
+
+// coreFields are read by position when no header names them
+var coreFields = []string{"name", "email", "company", "source"}
+
+// columnAliases maps normalized header names to the field they hold
+var columnAliases = map[string]string{
+	"name":             "name",
+	"email":            "email",
+	"company":          "company",
+	"source":           "source",
+	"status":           "status",
+	"consent":          "consentGiven",
+	"consentgiven":     "consentGiven",
+	"consenttimestamp": "consentTimestamp",
+	"consentdate":      "consentTimestamp",
+	"consentsource":    "consentSource",
+	"territory":        "territory",
+	"language":         "language",
+}
+
+// newColumnMap builds a column map from a header row, matching names
+// case-insensitively. Columns named in mapping take precedence and must exist.
+func newColumnMap(header []string, mapping map[string]string) (columnMap, error) {
+	columns := columnMap{
+		header: append([]string(nil), header...),
+		name:   0, email: 1, company: 2, source: 3,
+		status: -1, consentGiven: -1, consentTimestamp: -1, consentSource: -1, territory: -1, language: -1,
+	}
+
+	for i, column := range header {
+		if field, ok := columnAliases[normalizeColumnName(column)]; ok {
+			columns.set(field, i)
+		}
+	}
+
+	for field, column := range mapping {
+		index := headerIndex(header, column)
+		if index < 0 {
+			return columns, fmt.Errorf("mapping: column %q for %s is not in the header", column, field)
+		}
+		if !columns.set(field, index) {
+			return columns, fmt.Errorf("mapping: unknown field %q", field)
+		}
+	}
+
+	return columns, nil
+}
+
+// set points field at the column index, reporting false for unknown fields
+func (m *columnMap) set(field string, index int) bool {
+	switch field {
+	case "name":
+		m.name = index
+	case "email":
+		m.email = index
+	case "company":
+		m.company = index
+	case "source":
+		m.source = index
+	case "status":
+		m.status = index
+	case "consentGiven":
+		m.consentGiven = index
+	case "consentTimestamp":
+		m.consentTimestamp = index
+	case "consentSource":
+		m.consentSource = index
+	case "territory":
+		m.territory = index
+	case "language":
+		m.language = index
+	default:
+		return false
+	}
+	return true
+}
+
+// project keeps the columns holding lead fields and the named ones
+func (m *columnMap) project(columns []string) {
+	keep := make(map[int]bool)
+	for _, index := range []int{m.name, m.email, m.company, m.source, m.status, m.consentGiven, m.consentTimestamp, m.consentSource, m.territory, m.language} {
+		if index >= 0 && index < len(m.header) {
+			keep[index] = true
+		}
+	}
+	for _, column := range columns {
+		if index := headerIndex(m.header, column); index >= 0 {
+			keep[index] = true
+		}
+	}
+	m.keep = make([]int, 0, len(keep))
+	for i := range m.header {
+		if keep[i] {
+			m.keep = append(m.keep, i)
+		}
+	}
+}
+
+// HasColumns reports whether a header line, split on comma (0 for a comma),
+// names every one of columns, matched like mapped columns
+func HasColumns(headerLine string, comma rune, columns []string) bool {
+	header, err := ParseHeader(headerLine, comma)
+	if err != nil {
+		return false
+	}
+	for _, column := range columns {
+		if headerIndex(header, column) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// headerIndex returns the position of column in header, or -1
+func headerIndex(header []string, column string) int {
+	for i, name := range header {
+		if normalizeColumnName(name) == normalizeColumnName(column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// HeaderCheck describes how a header row maps to lead fields
+type HeaderCheck struct {
+	// Columns maps each field to the header column it is read from
+	Columns map[string]string
+	// Positional lists core fields no column names, read by position instead
+	Positional []string
+	// Ignored lists header columns that no field reads
+	Ignored []string
+}
+
+// CheckHeader reports how a file with this header would be read, returning
+// an error when mapping names a column the header does not have
+func CheckHeader(header []string, mapping map[string]string) (*HeaderCheck, error) {
+	if _, err := newColumnMap(header, mapping); err != nil {
+		return nil, err
+	}
+
+	check := &HeaderCheck{Columns: make(map[string]string)}
+	used := make(map[int]bool)
+	for i, column := range header {
+		if field, ok := columnAliases[normalizeColumnName(column)]; ok {
+			if _, mapped := mapping[field]; !mapped {
+				check.Columns[field] = column
+				used[i] = true
+			}
+		}
+	}
+	for field, column := range mapping {
+		index := headerIndex(header, column)
+		check.Columns[field] = header[index]
+		used[index] = true
+	}
+
+	for position, field := range coreFields {
+		if _, ok := check.Columns[field]; !ok {
+			check.Positional = append(check.Positional, field)
+			used[position] = true
+		}
+	}
+	for i, column := range header {
+		if !used[i] {
+			check.Ignored = append(check.Ignored, column)
+		}
+	}
+
+	return check, nil
+}
+
+// normalizeColumnName lowercases a header and drops spaces, underscores and
+// dashes, so "Consent Source", "consent_source" and "ConsentSource" all match.
+// The UTF-8 byte order mark Excel writes at the start of CSV files is ignored.
+func normalizeColumnName(column string) string {
+	column = strings.TrimPrefix(column, "\ufeff")
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(column)))
+}
+
+// lead converts a record to a lead, reporting false when core columns are
+// missing. Optional columns holding unparseable values are left to fail the
+// lead's validation.
+func (m columnMap) lead(record []string) (*models.Lead, bool) {
+	for _, index := range []int{m.name, m.email, m.company, m.source} {
+		if index >= len(record) {
+			return nil, false
+		}
+	}
+
+	lead := models.NewLeadWithID(m.newID, record[m.name], record[m.email], record[m.company], record[m.source])
+	lead.Status = m.optional(record, m.status)
+	lead.SetConsentCells(m.optional(record, m.consentGiven), m.optional(record, m.consentTimestamp), m.optional(record, m.consentSource))
+	lead.Territory = m.optional(record, m.territory)
+	lead.Language = m.optional(record, m.language)
+
+	if m.keep == nil {
+		lead.Raw = make(map[string]string, len(record))
+		for i, value := range record {
+			if i < len(m.header) {
+				lead.Raw[strings.TrimPrefix(m.header[i], "\ufeff")] = value
+			}
+		}
+		return lead, true
+	}
+
+	// The parser's cells share one buffer per row, so the cells kept are
+	// copied to let the rest of a wide row be freed
+	lead.Raw = make(map[string]string, len(m.keep))
+	for _, i := range m.keep {
+		if i < len(record) {
+			lead.Raw[strings.TrimPrefix(m.header[i], "\ufeff")] = strings.Clone(record[i])
+		}
+	}
+	for _, field := range []*string{&lead.Name, &lead.Email, &lead.Company, &lead.Source, &lead.Status, &lead.ConsentSource, &lead.Territory, &lead.Language} {
+		*field = strings.Clone(*field)
+	}
+
+	return lead, true
+}
+
+// optional returns the value of an optional column, or "" if it is absent
+func (m columnMap) optional(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
@@ -1,7 +1,13 @@
 package csv
 
 import (
+	"code/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -56,3 +62,473 @@ func TestCSVReader_ReadLeads(t *testing.T) {
 		assert.Nil(t, leads)
 	})
 }
+
+func TestCSVReader_StreamLeads(t *testing.T) {
+	t.Run("streams every lead in file order", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		var emails []string
+
+		// Act
+		err := reader.StreamLeads("../../testdata/leads.csv", func(lead *models.Lead) error {
+			emails = append(emails, lead.Email)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, emails, 10)
+		assert.Equal(t, "alice@example.com", emails[0])
+	})
+
+	t.Run("stops when emit returns an error", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		count := 0
+
+		// Act
+		err := reader.StreamLeads("../../testdata/leads.csv", func(lead *models.Lead) error {
+			count++
+			return assert.AnError
+		})
+
+		// Assert
+		assert.Equal(t, assert.AnError, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("reports malformed rows", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+
+		// Act
+		err := reader.StreamLeads("../../testdata/leads_missing_fields.csv", func(lead *models.Lead) error {
+			return nil
+		})
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("maps columns by header name", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		input := "Email,Status,Name,Source,Company,Territory\njane@example.com,qualified,Jane Roe,Website,Globex,EMEA\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "Jane Roe", leads[0].Name)
+		assert.Equal(t, "jane@example.com", leads[0].Email)
+		assert.Equal(t, "Globex", leads[0].Company)
+		assert.Equal(t, "Website", leads[0].Source)
+		assert.Equal(t, "qualified", leads[0].Status)
+		assert.Equal(t, "EMEA", leads[0].Territory)
+	})
+
+	t.Run("keeps only projected columns in the source row", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetProjection([]string{"utm source", "Missing"})
+		input := "Name,Email,Company,Source,Notes,UTM Source,Fax\nJane Roe,jane@example.com,Globex,Website,call back,newsletter,555\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+		kept, dropped, projectionErr := reader.Projection([]string{"Name", "Email", "Company", "Source", "Notes", "UTM Source", "Fax"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane Roe", leads[0].Name)
+		assert.Equal(t, map[string]string{"Name": "Jane Roe", "Email": "jane@example.com", "Company": "Globex", "Source": "Website", "UTM Source": "newsletter"}, leads[0].Raw)
+		assert.NoError(t, projectionErr)
+		assert.Equal(t, []string{"Name", "Email", "Company", "Source", "UTM Source"}, kept)
+		assert.Equal(t, []string{"Notes", "Fax"}, dropped)
+	})
+
+	t.Run("reads Windows line endings and byte order mark", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		input := "\ufeffEmail,Name,Company,Source\r\njane@example.com,Jane Roe,Globex,Website\r\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "jane@example.com", leads[0].Email)
+		assert.Equal(t, "Website", leads[0].Source)
+	})
+
+	t.Run("parses consent columns", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		input := "Name,Email,Company,Source,Consent Given,Consent Timestamp,Consent Source\n" +
+			"Jane Roe,jane@example.com,Globex,Website,yes,2024-05-01T10:00:00Z,signup-form\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.True(t, leads[0].ConsentGiven)
+		assert.Equal(t, "2024-05-01T10:00:00Z", leads[0].ConsentTimestamp.Format(time.RFC3339))
+		assert.Equal(t, "signup-form", leads[0].ConsentSource)
+	})
+
+	t.Run("leaves unparseable consent values to fail the lead's validation", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		input := "Name,Email,Company,Source,Consent,Consent Timestamp\n" +
+			"Jane Roe,jane@example.com,Globex,Website,maybe,\n" +
+			"John Doe,john@example.com,Initech,Website,yes,last tuesday\n" +
+			"Ann Lee,ann@example.com,Hooli,Website,yes,2024-05-01\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 3)
+		var validationErr *models.ValidationError
+		assert.ErrorAs(t, leads[0].Validate(), &validationErr)
+		assert.Equal(t, "consentGiven", validationErr.Problems[0].Field)
+		assert.ErrorContains(t, leads[1].Validate(), `consent timestamp "last tuesday" is not RFC 3339 or YYYY-MM-DD`)
+		assert.NoError(t, leads[2].Validate())
+	})
+
+	t.Run("reads mapped columns", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetMapping(map[string]string{"company": "Organisation", "source": "utm_source"})
+		input := "Name,E-mail,Organisation,UTM Source\nJane Roe,jane@example.com,Globex,Website\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "jane@example.com", leads[0].Email)
+		assert.Equal(t, "Globex", leads[0].Company)
+		assert.Equal(t, "Website", leads[0].Source)
+	})
+
+	t.Run("rejects mappings to missing columns", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetMapping(map[string]string{"company": "Organisation"})
+		input := "Name,Email,Company,Source\nJane Roe,jane@example.com,Globex,Website\n"
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			return nil
+		})
+
+		// Assert
+		assert.EqualError(t, err, `mapping: column "Organisation" for company is not in the header`)
+	})
+
+	t.Run("splits columns on the configured delimiter", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetDelimiter(';')
+		input := "Name;Email;Company;Source\nJane Roe;jane@example.com;\"Globex, Inc\";Website\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "jane@example.com", leads[0].Email)
+		assert.Equal(t, "Globex, Inc", leads[0].Company)
+	})
+
+	t.Run("reads cells quoted with single quotes", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetQuote('\'')
+		input := "Name,Email,Company,Source\n'O''Brien, Pat',pat@example.com,'Acme \"Labs\"',Website\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "O'Brien, Pat", leads[0].Name)
+		assert.Equal(t, `Acme "Labs"`, leads[0].Company)
+	})
+
+	t.Run("reads the first row of a headerless file as a lead", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetHeaderless(true)
+		input := "Jane Roe,jane@example.com,Acme,Website,EMEA\nJohn Doe,john@example.com,Globex,LinkedIn,APAC\n"
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 2)
+		assert.Equal(t, "jane@example.com", leads[0].Email)
+		assert.Equal(t, "EMEA", leads[0].Raw["Column 5"])
+		assert.Equal(t, "APAC", leads[1].Raw["Column 5"])
+	})
+
+	t.Run("gives re-read leads the same deterministic IDs", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		reader.SetIDGenerator(models.DeterministicID)
+		read := func(input string) []string {
+			var ids []string
+			reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+				ids = append(ids, lead.ID)
+				return nil
+			})
+			return ids
+		}
+
+		// Act
+		first := read("Name,Email,Company,Source\nJane Roe,jane@example.com,Acme,Website\nJohn Doe,john@example.com,Acme,Website\n")
+		again := read("Name,Email,Company,Source\nJane R.,JANE@example.com ,Globex,Website\n")
+		otherSource := read("Name,Email,Company,Source\nJane Roe,jane@example.com,Acme,Referral\n")
+
+		// Assert
+		assert.Len(t, first, 2)
+		assert.NotEqual(t, first[0], first[1])
+		assert.Equal(t, first[0], again[0])
+		assert.NotEqual(t, first[0], otherSource[0])
+	})
+}
+
+func TestCSVReader_StreamRecords(t *testing.T) {
+	t.Run("reads rows split into cells by header", func(t *testing.T) {
+		// Arrange
+		reader := NewCSVReader()
+		header := []string{"Email", "Name", "Company", "Source", "Consent Given"}
+		rows := [][]string{
+			{"jane@example.com", "Jane Smith", "Tech Firm", "LinkedIn", "yes"},
+			{"john@example.com", "John Doe", "Acme", "Webinar", "maybe"},
+		}
+		var leads []*models.Lead
+
+		// Act
+		err := reader.StreamRecords(header, rows, func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 2)
+		assert.Equal(t, "Jane Smith", leads[0].Name)
+		assert.True(t, leads[0].ConsentGiven)
+		assert.Error(t, leads[1].Validate())
+	})
+}
+
+func TestFileSource(t *testing.T) {
+	t.Run("reads a file as a lead source", func(t *testing.T) {
+		// Arrange
+		var source LeadSource = NewCSVReader().File("../../testdata/leads.csv")
+		count := 0
+
+		// Act
+		header, headerErr := source.ReadHeader()
+		hash, hashErr := source.Hash()
+		err := source.StreamLeads(func(lead *models.Lead) error {
+			count++
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, headerErr)
+		assert.NoError(t, hashErr)
+		assert.NoError(t, err)
+		assert.Equal(t, "Name", header[0])
+		assert.Len(t, hash, 64)
+		assert.Equal(t, 10, count)
+	})
+}
+
+func TestCheckHeader(t *testing.T) {
+	t.Run("describes how each column is read", func(t *testing.T) {
+		// Arrange
+		header := []string{"Full Name", "Email", "Organisation", "Lead Source", "Notes"}
+		mapping := map[string]string{"company": "organisation", "source": "Lead Source"}
+
+		// Act
+		check, err := CheckHeader(header, mapping)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"email": "Email", "company": "Organisation", "source": "Lead Source"}, check.Columns)
+		assert.Equal(t, []string{"name"}, check.Positional)
+		assert.Equal(t, []string{"Notes"}, check.Ignored)
+	})
+
+	t.Run("reports mapped columns missing from the header", func(t *testing.T) {
+		// Act
+		_, err := CheckHeader([]string{"Name", "Email"}, map[string]string{"source": "utm_source"})
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+// writeBenchmarkCSV writes a CSV file with rows leads and returns its path
+func writeBenchmarkCSV(b *testing.B, rows int) string {
+	b.Helper()
+
+	var sb strings.Builder
+	sb.WriteString("Name,Email,Company,Source\n")
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&sb, "Lead %d,lead%d@example.com,Company %d,LinkedIn\n", i, i, i)
+	}
+
+	path := filepath.Join(b.TempDir(), "bench.csv")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkCSVReader_ReadLeads(b *testing.B) {
+	path := writeBenchmarkCSV(b, 10000)
+	reader := NewCSVReader()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := reader.ReadLeads(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCSVReader_StreamLeads(b *testing.B) {
+	path := writeBenchmarkCSV(b, 10000)
+	reader := NewCSVReader()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := reader.StreamLeads(path, func(*models.Lead) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRowWriter(t *testing.T) {
+	t.Run("writes leads in their source layout", func(t *testing.T) {
+		// Arrange
+		input := "\ufeffFull Name,E-mail,Organisation,Source,Notes\nJane Roe,jane@example.com,Globex,Website,call back\n"
+		reader := NewCSVReader()
+		reader.SetMapping(map[string]string{"name": "Full Name", "email": "E-mail", "company": "Organisation"})
+		var leads []*models.Lead
+		err := reader.StreamLeadsFrom(strings.NewReader(input), func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "deferred.csv")
+		writer := NewRowWriter(path, []string{"Full Name", "E-mail", "Organisation", "Source", "Notes"})
+
+		// Act
+		writeErr := writer.Write(leads[0])
+		closeErr := writer.Close()
+
+		// Assert
+		assert.NoError(t, writeErr)
+		assert.NoError(t, closeErr)
+		assert.Equal(t, 1, writer.Count())
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "Full Name,E-mail,Organisation,Source,Notes\nJane Roe,jane@example.com,Globex,Website,call back\n", string(content))
+	})
+
+	t.Run("appends to an existing file without repeating the header", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "deferred.csv")
+		header := []string{"Name", "Email"}
+		lead := func(name, email string) *models.Lead {
+			l := models.NewLead(name, email, "Acme", "Website")
+			l.Raw = map[string]string{"Name": name, "Email": email}
+			return l
+		}
+		first := NewRowWriter(path, header)
+		assert.NoError(t, first.Write(lead("Jane Roe", "jane@example.com")))
+		assert.NoError(t, first.Close())
+
+		// Act
+		second := NewRowWriter(path, header)
+		second.SetAppend(true)
+		writeErr := second.Write(lead("John Doe", "john@example.com"))
+		closeErr := second.Close()
+
+		// Assert
+		assert.NoError(t, writeErr)
+		assert.NoError(t, closeErr)
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "Name,Email\nJane Roe,jane@example.com\nJohn Doe,john@example.com\n", string(content))
+	})
+
+	t.Run("creates no file when nothing is written", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "deferred.csv")
+		writer := NewRowWriter(path, []string{"Name"})
+
+		// Act
+		err := writer.Close()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoFileExists(t, path)
+	})
+}
@@ -0,0 +1,137 @@
+package csv
+
+import (
+	"code/internal/models"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ReasonColumn is added to rejected rows with why the lead was rejected
+const ReasonColumn = "Reject Reason"
+
+// unknownPartner names the file of rejected rows with no value in the
+// column they are split by
+const unknownPartner = "unknown"
+
+// RejectWriter writes rejected leads in the column layout of the file they
+// were read from, with a ReasonColumn, so they can be fixed and sent again.
+// Split by a column, such as the partner of a merged feed, it writes one file
+// per value of the column.
+type RejectWriter struct {
+	path    string
+	header  []string
+	splitBy string
+	append  bool
+	files   map[string]*RowWriter
+}
+
+// NewRejectWriter creates a writer for path using header as the column
+// layout. Files are only created once a lead is written to them.
+func NewRejectWriter(path string, header []string) *RejectWriter {
+	return &RejectWriter{
+		path:   path,
+		header: append(slices.Clip(header), ReasonColumn),
+		files:  make(map[string]*RowWriter),
+	}
+}
+
+// SetSplitBy splits the rejects into a file per value of column, named
+// after path with the value before its extension: leads.rejects.acme.csv.
+// The column must be in the header, in any case.
+func (w *RejectWriter) SetSplitBy(column string) error {
+	for _, name := range w.header[:len(w.header)-1] {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+			w.splitBy = name
+			return nil
+		}
+	}
+	return fmt.Errorf("no column %q", column)
+}
+
+// SetAppend makes the writer add to existing files instead of replacing them
+func (w *RejectWriter) SetAppend(append bool) {
+	w.append = append
+}
+
+// Write appends the source row of lead, rejected with reason
+func (w *RejectWriter) Write(lead *models.Lead, reason error) error {
+	partner := ""
+	if w.splitBy != "" {
+		partner = partnerName(lead.Raw[w.splitBy])
+	}
+	file, ok := w.files[partner]
+	if !ok {
+		file = NewRowWriter(w.pathOf(partner), w.header)
+		file.SetAppend(w.append)
+		w.files[partner] = file
+	}
+
+	row := maps.Clone(lead.Raw)
+	if row == nil {
+		row = make(map[string]string, 1)
+	}
+	row[ReasonColumn] = reason.Error()
+	return file.WriteRow(row)
+}
+
+// pathOf returns the file of a partner's rejects, path itself when they
+// aren't split
+func (w *RejectWriter) pathOf(partner string) string {
+	if w.splitBy == "" {
+		return w.path
+	}
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "." + partner + ext
+}
+
+// partnerName turns a column value into a file name part: lowercased,
+// with runs of other characters than letters and digits made a dash
+func partnerName(value string) string {
+	name := strings.Join(strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "-")
+	if name == "" {
+		return unknownPartner
+	}
+	return name
+}
+
+// RejectFile is a file of rejected leads and how many it holds
+type RejectFile struct {
+	Path  string
+	Count int
+}
+
+// Files returns the files written, by path
+func (w *RejectWriter) Files() []RejectFile {
+	files := make([]RejectFile, 0, len(w.files))
+	for _, file := range w.files {
+		files = append(files, RejectFile{Path: file.path, Count: file.Count()})
+	}
+	slices.SortFunc(files, func(a, b RejectFile) int { return strings.Compare(a.Path, b.Path) })
+	return files
+}
+
+// Count returns the number of leads written
+func (w *RejectWriter) Count() int {
+	count := 0
+	for _, file := range w.files {
+		count += file.Count()
+	}
+	return count
+}
+
+// Close flushes and closes the files
+func (w *RejectWriter) Close() error {
+	var first error
+	for _, file := range w.files {
+		if err := file.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,74 @@
+package csv
+
+import (
+	"code/internal/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectWriter(t *testing.T) {
+	header := []string{"Name", "Email", "Partner"}
+	lead := func(name, email, partner string) *models.Lead {
+		l := models.NewLead(name, email, "Acme", "Website")
+		l.Raw = map[string]string{"Name": name, "Email": email, "Partner": partner}
+		return l
+	}
+
+	t.Run("writes rejected rows with the reason", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "leads.rejects.csv")
+		writer := NewRejectWriter(path, header)
+
+		// Act
+		writeErr := writer.Write(lead("Jane Roe", "jane@", "Acme Media"), errors.New("invalid email"))
+		closeErr := writer.Close()
+
+		// Assert
+		assert.NoError(t, writeErr)
+		assert.NoError(t, closeErr)
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "Name,Email,Partner,Reject Reason\nJane Roe,jane@,Acme Media,invalid email\n", string(content))
+	})
+
+	t.Run("splits rejected rows by partner", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		writer := NewRejectWriter(filepath.Join(dir, "leads.rejects.csv"), header)
+		assert.NoError(t, writer.SetSplitBy("partner"))
+
+		// Act
+		assert.NoError(t, writer.Write(lead("Jane Roe", "jane@", "Acme Media"), errors.New("invalid email")))
+		assert.NoError(t, writer.Write(lead("John Doe", "john@", "Globex"), errors.New("invalid email")))
+		assert.NoError(t, writer.Write(lead("Pat Poe", "pat@", "ACME media"), errors.New("invalid email")))
+		assert.NoError(t, writer.Write(lead("Sam Soe", "sam@", ""), errors.New("invalid email")))
+		err := writer.Close()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 4, writer.Count())
+		assert.Equal(t, []RejectFile{
+			{Path: filepath.Join(dir, "leads.rejects.acme-media.csv"), Count: 2},
+			{Path: filepath.Join(dir, "leads.rejects.globex.csv"), Count: 1},
+			{Path: filepath.Join(dir, "leads.rejects.unknown.csv"), Count: 1},
+		}, writer.Files())
+		content, err := os.ReadFile(filepath.Join(dir, "leads.rejects.globex.csv"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Name,Email,Partner,Reject Reason\nJohn Doe,john@,Globex,invalid email\n", string(content))
+	})
+
+	t.Run("refuses to split by a column the file lacks", func(t *testing.T) {
+		// Arrange
+		writer := NewRejectWriter(filepath.Join(t.TempDir(), "leads.rejects.csv"), header)
+
+		// Act
+		err := writer.SetSplitBy("Source")
+
+		// Assert
+		assert.ErrorContains(t, err, `no column "Source"`)
+	})
+}
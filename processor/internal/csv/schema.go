@@ -0,0 +1,135 @@
+package csv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HeaderDiff is how a header differs from the exact header expected of it.
+// Columns are compared by name as written, so a renamed column is missing
+// under its old name and extra under its new one.
+type HeaderDiff struct {
+	// Missing are the expected columns the header lacks
+	Missing []Column
+	// Extra are the header's columns that aren't expected
+	Extra []Column
+	// Moved are the columns both have that are out of the expected order
+	Moved []MovedColumn
+}
+
+// Column is a named column at a 1-based position
+type Column struct {
+	Name     string
+	Position int
+}
+
+// MovedColumn is a column found at another position than expected, both
+// 1-based
+type MovedColumn struct {
+	Name     string
+	Expected int
+	Actual   int
+}
+
+// DiffHeader compares a header with the one expected, returning nil when
+// they are the same. Of the columns both have, the fewest are reported as
+// moved that put the rest in the expected order.
+func DiffHeader(expected, actual []string) *HeaderDiff {
+	if len(actual) > 0 {
+		actual = slices.Clone(actual)
+		actual[0] = strings.TrimPrefix(actual[0], "\ufeff")
+	}
+	if slices.Equal(expected, actual) {
+		return nil
+	}
+
+	// Pair the nth column of a name in one header with the nth in the other
+	positions := make(map[string][]int)
+	for i, name := range actual {
+		positions[name] = append(positions[name], i)
+	}
+	diff := &HeaderDiff{}
+	var pairs [][2]int
+	for i, name := range expected {
+		if len(positions[name]) == 0 {
+			diff.Missing = append(diff.Missing, Column{Name: name, Position: i + 1})
+			continue
+		}
+		pairs = append(pairs, [2]int{i, positions[name][0]})
+		positions[name] = positions[name][1:]
+	}
+	paired := make(map[int]bool, len(pairs))
+	for _, pair := range pairs {
+		paired[pair[1]] = true
+	}
+	for i, name := range actual {
+		if !paired[i] {
+			diff.Extra = append(diff.Extra, Column{Name: name, Position: i + 1})
+		}
+	}
+
+	inOrder := longestIncreasing(pairs)
+	for i, pair := range pairs {
+		if !inOrder[i] {
+			diff.Moved = append(diff.Moved, MovedColumn{Name: expected[pair[0]], Expected: pair[0] + 1, Actual: pair[1] + 1})
+		}
+	}
+	return diff
+}
+
+// longestIncreasing marks the longest run of pairs, in expected order, whose
+// actual positions increase too
+func longestIncreasing(pairs [][2]int) []bool {
+	length := make([]int, len(pairs))
+	previous := make([]int, len(pairs))
+	best := -1
+	for i := range pairs {
+		length[i], previous[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if pairs[j][1] < pairs[i][1] && length[j]+1 > length[i] {
+				length[i], previous[i] = length[j]+1, j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	marked := make([]bool, len(pairs))
+	for i := best; i >= 0; i = previous[i] {
+		marked[i] = true
+	}
+	return marked
+}
+
+// Changes describes each difference on a line of its own
+func (d *HeaderDiff) Changes() []string {
+	var changes []string
+	for _, column := range d.Missing {
+		changes = append(changes, fmt.Sprintf("missing column %q (expected at %d)", column.Name, column.Position))
+	}
+	for _, column := range d.Extra {
+		changes = append(changes, fmt.Sprintf("unexpected column %q at %d", column.Name, column.Position))
+	}
+	for _, column := range d.Moved {
+		changes = append(changes, fmt.Sprintf("column %q at %d, expected at %d", column.Name, column.Actual, column.Expected))
+	}
+	return changes
+}
+
+// String joins the changes into one line
+func (d *HeaderDiff) String() string {
+	return strings.Join(d.Changes(), "; ")
+}
+
+// ParseHeader splits a header line into its columns
+func ParseHeader(headerLine string, comma rune) ([]string, error) {
+	reader := NewCSVReader()
+	reader.SetDelimiter(comma)
+	header, err := reader.newReader(strings.NewReader(headerLine)).Read()
+	if err != nil {
+		return nil, err
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	return header, nil
+}
@@ -0,0 +1,51 @@
+package csv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffHeader(t *testing.T) {
+	expected := []string{"Name", "Email", "Company", "Source"}
+
+	t.Run("accepts the expected header", func(t *testing.T) {
+		// Act
+		diff := DiffHeader(expected, []string{"\ufeffName", "Email", "Company", "Source"})
+
+		// Assert
+		assert.Nil(t, diff)
+	})
+
+	t.Run("reports missing, extra and moved columns", func(t *testing.T) {
+		// Act
+		diff := DiffHeader(expected, []string{"Company", "Name", "Email", "E-Mail Opt-In"})
+
+		// Assert
+		assert.Equal(t, []Column{{Name: "Source", Position: 4}}, diff.Missing)
+		assert.Equal(t, []Column{{Name: "E-Mail Opt-In", Position: 4}}, diff.Extra)
+		assert.Equal(t, []MovedColumn{{Name: "Company", Expected: 3, Actual: 1}}, diff.Moved)
+		assert.Equal(t, []string{
+			`missing column "Source" (expected at 4)`,
+			`unexpected column "E-Mail Opt-In" at 4`,
+			`column "Company" at 1, expected at 3`,
+		}, diff.Changes())
+	})
+
+	t.Run("compares names exactly", func(t *testing.T) {
+		// Act
+		diff := DiffHeader(expected, []string{"Name", "email", "Company", "Source"})
+
+		// Assert
+		assert.Equal(t, `missing column "Email" (expected at 2); unexpected column "email" at 2`, diff.String())
+	})
+
+	t.Run("doesn't count columns shifted by others as moved", func(t *testing.T) {
+		// Act
+		diff := DiffHeader(expected, []string{"Phone", "Name", "Email", "Company", "Source"})
+
+		// Assert
+		assert.Equal(t, []Column{{Name: "Phone", Position: 1}}, diff.Extra)
+		assert.Empty(t, diff.Moved)
+	})
+}
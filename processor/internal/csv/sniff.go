@@ -0,0 +1,194 @@
+package csv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SniffSize is how much of the start of a file Sniff is given
+const SniffSize = 1024
+
+// Dialect is how a CSV file is written
+type Dialect struct {
+	// Comma separates the columns
+	Comma rune
+	// Quote encloses cells holding the comma, '"' or '\''
+	Quote rune
+	// Header reports whether the first row names the columns
+	Header bool
+}
+
+// sniffCommas are the delimiters Sniff tells apart, the most common first
+var sniffCommas = []rune{',', ';', '\t', '|'}
+
+// Sniff guesses the dialect of CSV data from its start, with the delimiter
+// given as comma or, when 0, guessed too. The delimiter is the one splitting
+// the most lines into the same number of cells, the quote the one enclosing
+// cells, and the first row is taken for data rather than a header only when
+// it holds an email address or a number and no column name a lead field is
+// read from.
+func Sniff(sample []byte, comma rune) Dialect {
+	lines := sniffLines(sample)
+	dialect := Dialect{Comma: comma, Header: true}
+
+	if comma == 0 {
+		dialect.Comma = ','
+		quote := sniffQuote(lines, sniffCommas)
+		best, bestLines := 0, 0
+		for _, candidate := range sniffCommas {
+			counts := make(map[int]int)
+			for _, line := range lines {
+				if n := len(splitLine(line, candidate, quote)); n > 1 {
+					counts[n]++
+				}
+			}
+			for n, count := range counts {
+				if count > bestLines || (count == bestLines && n > best) {
+					dialect.Comma, best, bestLines = candidate, n, count
+				}
+			}
+		}
+	}
+	dialect.Quote = sniffQuote(lines, []rune{dialect.Comma})
+
+	if len(lines) > 0 {
+		dialect.Header = looksLikeHeader(splitLine(lines[0], dialect.Comma, dialect.Quote))
+	}
+	return dialect
+}
+
+// sniffLines splits a sample into its non-empty lines, dropping a last line
+// cut off by the end of the sample
+func sniffLines(sample []byte) []string {
+	sample = bytes.TrimPrefix(sample, []byte("\ufeff"))
+	if len(sample) >= SniffSize {
+		if i := bytes.LastIndexByte(sample, '\n'); i > 0 {
+			sample = sample[:i]
+		}
+	}
+	var lines []string
+	for _, line := range strings.Split(string(sample), "\n") {
+		if line = strings.TrimSuffix(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// sniffQuote picks single quotes only when they, and never double quotes,
+// open cells split by one of commas; an apostrophe inside a word doesn't
+// count
+func sniffQuote(lines []string, commas []rune) rune {
+	opens := func(quote rune) bool {
+		for _, line := range lines {
+			previous := rune(0)
+			for i, r := range line {
+				if r == quote && (i == 0 || slices.Contains(commas, previous)) {
+					return true
+				}
+				previous = r
+			}
+		}
+		return false
+	}
+	if !slices.Contains(commas, '\'') && !opens('"') && opens('\'') {
+		return '\''
+	}
+	return '"'
+}
+
+// splitLine splits a line on comma outside of quotes, unquoting its cells
+func splitLine(line string, comma, quote rune) []string {
+	var cells []string
+	var cell strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == quote:
+			quoted = !quoted
+		case r == comma && !quoted:
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteRune(r)
+		}
+	}
+	return append(cells, cell.String())
+}
+
+// looksLikeHeader reports whether a first row names columns rather than
+// holding a lead
+func looksLikeHeader(cells []string) bool {
+	data := false
+	for _, cell := range cells {
+		cell = strings.TrimSpace(cell)
+		if _, ok := columnAliases[normalizeColumnName(cell)]; ok {
+			return true
+		}
+		if _, err := strconv.ParseFloat(cell, 64); err == nil || strings.Contains(cell, "@") {
+			data = true
+		}
+	}
+	return !data
+}
+
+// Sniff guesses the file's dialect from its first SniffSize bytes, see Sniff
+func (s *FileSource) Sniff(comma rune) (Dialect, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return Dialect{}, err
+	}
+	defer file.Close()
+
+	sample := make([]byte, SniffSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Dialect{}, err
+	}
+	return Sniff(sample[:n], comma), nil
+}
+
+// ParseDelimiter reads a delimiter as written in the config or on the command
+// line: a single character, or tab. An empty one is 0, keeping the comma.
+func ParseDelimiter(delimiter string) (rune, error) {
+	if delimiter == "" {
+		return 0, nil
+	}
+	if strings.EqualFold(delimiter, "tab") || delimiter == `\t` {
+		return '\t', nil
+	}
+	comma, size := utf8.DecodeRuneInString(delimiter)
+	if comma == utf8.RuneError || size != len(delimiter) {
+		return 0, fmt.Errorf("%q must be a single character or tab", delimiter)
+	}
+	if comma == '"' || comma == '\r' || comma == '\n' {
+		return 0, fmt.Errorf("%q cannot separate columns", delimiter)
+	}
+	return comma, nil
+}
+
+// ParseQuote reads a quote character, a double or single quote. An empty one
+// is 0, keeping double quotes.
+func ParseQuote(quote string) (rune, error) {
+	switch quote {
+	case "":
+		return 0, nil
+	case `"`, `'`:
+		return rune(quote[0]), nil
+	}
+	return 0, fmt.Errorf("%q is not a quote character (use \" or ')", quote)
+}
+
+// DelimiterName names a delimiter for messages, spelling out tab
+func DelimiterName(comma rune) string {
+	if comma == '\t' {
+		return "tab"
+	}
+	return string(comma)
+}
@@ -0,0 +1,89 @@
+package csv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniff(t *testing.T) {
+	t.Run("guesses the delimiter splitting rows evenly", func(t *testing.T) {
+		for input, want := range map[string]rune{
+			"Name,Email,Company,Source\nJane Roe,jane@example.com,Acme,Website\n":             ',',
+			"Name;Email;Company;Source\nJane Roe;jane@example.com;Acme, Inc;Website\n":        ';',
+			"Name\tEmail\tCompany\tSource\nJane Roe\tjane@example.com\tAcme; Labs\tWebsite\n": '\t',
+			"Name|Email|Company|Source\nJane Roe|jane@example.com|\"Acme | Labs\"|Website\n":  '|',
+			"Name,Email;Company,Source\n\"Roe, Jane\",jane@example.com;Acme,Website\n":        ',',
+			"Name\n": ',',
+		} {
+			// Act
+			dialect := Sniff([]byte(input), 0)
+
+			// Assert
+			assert.Equal(t, want, dialect.Comma, input)
+		}
+	})
+
+	t.Run("guesses single quotes only when they enclose cells", func(t *testing.T) {
+		// Act
+		single := Sniff([]byte("Name,Email\n'Roe, Jane',jane@example.com\n"), 0)
+		apostrophe := Sniff([]byte("Name,Email\nJane O'Brien,jane@example.com\n"), 0)
+		double := Sniff([]byte("Name,Email\n\"Roe, Jane\",'jane@example.com'\n"), 0)
+		otherDelimiter := Sniff([]byte("Email;Name\njane@example.com;'Roe; Jane'\n"), '\t')
+
+		// Assert
+		assert.Equal(t, '\'', single.Quote)
+		assert.Equal(t, '"', apostrophe.Quote)
+		assert.Equal(t, '"', double.Quote)
+		assert.Equal(t, '"', otherDelimiter.Quote)
+	})
+
+	t.Run("tells a header from a first lead", func(t *testing.T) {
+		// Act
+		header := Sniff([]byte("Full Name;E-Mail;Firma\nJane Roe;jane@example.com;Acme\n"), 0)
+		known := Sniff([]byte("Email,Score\njane@example.com,85\n"), 0)
+		lead := Sniff([]byte("Jane Roe;jane@example.com;Acme\nJohn Doe;john@example.com;Globex\n"), 0)
+
+		// Assert
+		assert.True(t, header.Header)
+		assert.True(t, known.Header)
+		assert.False(t, lead.Header)
+	})
+
+	t.Run("ignores a last line cut off by the sample", func(t *testing.T) {
+		// Arrange
+		row := "Jane Roe;jane@example.com;Acme;Website\n"
+		sample := "Name;Email;Company;Source\n" + strings.Repeat(row, SniffSize/len(row))
+		sample = sample[:SniffSize-5] + ",,,,,"
+
+		// Act
+		dialect := Sniff([]byte(sample), 0)
+
+		// Assert
+		assert.Equal(t, ';', dialect.Comma)
+	})
+}
+
+func TestParseDelimiter(t *testing.T) {
+	t.Run("reads characters and tab", func(t *testing.T) {
+		for delimiter, want := range map[string]rune{"": 0, ";": ';', "tab": '\t', "TAB": '\t', `\t`: '\t', "|": '|'} {
+			// Act
+			comma, err := ParseDelimiter(delimiter)
+
+			// Assert
+			assert.NoError(t, err, delimiter)
+			assert.Equal(t, want, comma, delimiter)
+		}
+	})
+
+	t.Run("rejects what cannot separate columns", func(t *testing.T) {
+		// Act
+		_, long := ParseDelimiter(";;")
+		_, quote := ParseDelimiter(`"`)
+
+		// Assert
+		assert.ErrorContains(t, long, "must be a single character or tab")
+		assert.ErrorContains(t, quote, "cannot separate columns")
+	})
+}
@@ -0,0 +1,100 @@
+package csv
+
+import (
+	"code/internal/models"
+	"encoding/csv"
+	"os"
+)
+
+// RowWriter writes leads back out in the column layout of the file they were
+// read from, so the output can be processed again with the same mapping
+type RowWriter struct {
+	path   string
+	header []string
+	file   *os.File
+	writer *csv.Writer
+	count  int
+	append bool
+}
+
+// NewRowWriter creates a writer for path using header as the column layout.
+// The file is only created once the first lead is written.
+func NewRowWriter(path string, header []string) *RowWriter {
+	return &RowWriter{path: path, header: header}
+}
+
+// SetAppend makes the writer add to an existing file instead of replacing
+// it; the header is only written if the file is new or empty
+func (w *RowWriter) SetAppend(append bool) {
+	w.append = append
+}
+
+// Write appends the source row of lead
+func (w *RowWriter) Write(lead *models.Lead) error {
+	return w.WriteRow(lead.Raw)
+}
+
+// WriteRow appends a row, keyed by header column
+func (w *RowWriter) WriteRow(record map[string]string) error {
+	if w.writer == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	row := make([]string, len(w.header))
+	for i, column := range w.header {
+		row[i] = record[column]
+	}
+	if err := w.writer.Write(row); err != nil {
+		return err
+	}
+	w.count++
+
+	return nil
+}
+
+// open creates the file, or opens it for appending, and writes the header
+func (w *RowWriter) open() error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if w.append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(w.path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.writer = csv.NewWriter(file)
+	if info.Size() > 0 {
+		return nil
+	}
+	return w.writer.Write(w.header)
+}
+
+// Count returns the number of leads written
+func (w *RowWriter) Count() int {
+	return w.count
+}
+
+// Close flushes and closes the file, if one was created
+func (w *RowWriter) Close() error {
+	if w.writer == nil {
+		return nil
+	}
+
+	w.writer.Flush()
+	err := w.writer.Error()
+	w.writer = nil
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,540 @@
+package datalake
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// avroMagic starts every Avro object container file
+const avroMagic = "Obj\x01"
+
+// maxAvroBlock bounds the size of a block read into memory
+const maxAvroBlock = 256 << 20
+
+// avroSchema is a parsed Avro schema
+type avroSchema struct {
+	typ         string
+	name        string
+	fields      []avroField
+	items       *avroSchema
+	symbols     []string
+	size        int
+	union       []*avroSchema
+	logicalType string
+	scale       int64
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// parseAvroSchema parses a schema, resolving references to named types
+// defined earlier in it
+func parseAvroSchema(data []byte, named map[string]*avroSchema, namespace string) (*avroSchema, error) {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		switch name {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchema{typ: name}, nil
+		}
+		if s, ok := named[name]; ok {
+			return s, nil
+		}
+		if s, ok := named[namespace+"."+name]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown Avro type %q", name)
+	}
+
+	var union []json.RawMessage
+	if err := json.Unmarshal(data, &union); err == nil {
+		s := &avroSchema{typ: "union"}
+		for _, branch := range union {
+			b, err := parseAvroSchema(branch, named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			s.union = append(s.union, b)
+		}
+		return s, nil
+	}
+
+	var obj struct {
+		Type      json.RawMessage `json:"type"`
+		Name      string          `json:"name"`
+		Namespace string          `json:"namespace"`
+		Fields    []struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+		} `json:"fields"`
+		Items       json.RawMessage `json:"items"`
+		Values      json.RawMessage `json:"values"`
+		Symbols     []string        `json:"symbols"`
+		Size        int             `json:"size"`
+		LogicalType string          `json:"logicalType"`
+		Scale       int64           `json:"scale"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+
+	var typ string
+	if err := json.Unmarshal(obj.Type, &typ); err != nil {
+		// {"type": {...}} wraps another schema
+		return parseAvroSchema(obj.Type, named, namespace)
+	}
+
+	s := &avroSchema{typ: typ, logicalType: obj.LogicalType, scale: obj.Scale, symbols: obj.Symbols, size: obj.Size}
+	if obj.Name != "" {
+		if obj.Namespace != "" {
+			namespace = obj.Namespace
+		}
+		s.name = obj.Name
+		named[obj.Name] = s
+		if namespace != "" && !strings.Contains(obj.Name, ".") {
+			named[namespace+"."+obj.Name] = s
+		}
+	}
+
+	switch typ {
+	case "record", "error":
+		s.typ = "record"
+		for _, f := range obj.Fields {
+			fs, err := parseAvroSchema(f.Type, named, namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			s.fields = append(s.fields, avroField{name: f.Name, schema: fs})
+		}
+	case "array":
+		items, err := parseAvroSchema(obj.Items, named, namespace)
+		if err != nil {
+			return nil, err
+		}
+		s.items = items
+	case "map":
+		values, err := parseAvroSchema(obj.Values, named, namespace)
+		if err != nil {
+			return nil, err
+		}
+		s.items = values
+	case "enum", "fixed", "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+	default:
+		return nil, fmt.Errorf("unknown Avro type %q", typ)
+	}
+	return s, nil
+}
+
+// AvroReader reads the records of an Avro object container file one block
+// at a time, rendering top-level fields as text like CSV cells. Nested
+// records, arrays and maps are rendered as JSON.
+type AvroReader struct {
+	file      *os.File
+	in        *bufio.Reader
+	schema    *avroSchema
+	codec     string
+	sync      []byte
+	block     avroDecoder
+	remaining int64
+}
+
+// OpenAvro opens an Avro object container file and reads its header
+func OpenAvro(path string) (*AvroReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &AvroReader{file: file, in: bufio.NewReader(file)}
+	if err := r.readHeader(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func (r *AvroReader) readHeader() error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r.in, magic); err != nil || string(magic) != avroMagic {
+		return fmt.Errorf("not an Avro object container file")
+	}
+
+	meta := map[string][]byte{}
+	for {
+		count, err := binary.ReadVarint(r.in)
+		if err != nil {
+			return fmt.Errorf("corrupt Avro header: %w", err)
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			count = -count
+			if _, err := binary.ReadVarint(r.in); err != nil {
+				return fmt.Errorf("corrupt Avro header: %w", err)
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			key, err := r.readBytes()
+			if err != nil {
+				return fmt.Errorf("corrupt Avro header: %w", err)
+			}
+			value, err := r.readBytes()
+			if err != nil {
+				return fmt.Errorf("corrupt Avro header: %w", err)
+			}
+			meta[string(key)] = value
+		}
+	}
+
+	r.sync = make([]byte, 16)
+	if _, err := io.ReadFull(r.in, r.sync); err != nil {
+		return fmt.Errorf("corrupt Avro header: %w", err)
+	}
+
+	schema, err := parseAvroSchema(meta["avro.schema"], map[string]*avroSchema{}, "")
+	if err != nil {
+		return err
+	}
+	if schema.typ != "record" {
+		return fmt.Errorf("the file holds %s values; only records are supported", schema.typ)
+	}
+	r.schema = schema
+
+	r.codec = string(meta["avro.codec"])
+	switch r.codec {
+	case "":
+		r.codec = "null"
+	case "null", "deflate", "snappy":
+	default:
+		return fmt.Errorf("%s compression is not supported; write the file with snappy, deflate or no compression", r.codec)
+	}
+	return nil
+}
+
+func (r *AvroReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadVarint(r.in)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > maxAvroBlock {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r.in, b)
+	return b, err
+}
+
+// Header returns the record's field names
+func (r *AvroReader) Header() []string {
+	names := make([]string, len(r.schema.fields))
+	for i, f := range r.schema.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Next returns the next record, or io.EOF after the last
+func (r *AvroReader) Next() ([]string, error) {
+	for r.remaining == 0 {
+		if err := r.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	row := make([]string, len(r.schema.fields))
+	for i, f := range r.schema.fields {
+		value, err := r.block.text(f.schema)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		row[i] = value
+	}
+	r.remaining--
+	return row, nil
+}
+
+// Close closes the file
+func (r *AvroReader) Close() error {
+	return r.file.Close()
+}
+
+func (r *AvroReader) readBlock() error {
+	count, err := binary.ReadVarint(r.in)
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("corrupt Avro block: %w", err)
+	}
+	data, err := r.readBytes()
+	if err != nil {
+		return fmt.Errorf("corrupt Avro block: %w", err)
+	}
+	sync := make([]byte, 16)
+	if _, err := io.ReadFull(r.in, sync); err != nil || !bytes.Equal(sync, r.sync) {
+		return fmt.Errorf("corrupt Avro block: sync marker mismatch")
+	}
+
+	switch r.codec {
+	case "deflate":
+		if data, err = io.ReadAll(flate.NewReader(bytes.NewReader(data))); err != nil {
+			return fmt.Errorf("corrupt Avro block: %w", err)
+		}
+	case "snappy":
+		// The block ends with the CRC-32 of the uncompressed data
+		if len(data) < 4 {
+			return fmt.Errorf("corrupt Avro block: %w", errCorruptSnappy)
+		}
+		checksum := binary.BigEndian.Uint32(data[len(data)-4:])
+		if data, err = decodeSnappy(data[:len(data)-4]); err != nil {
+			return fmt.Errorf("corrupt Avro block: %w", err)
+		}
+		if crc32.ChecksumIEEE(data) != checksum {
+			return fmt.Errorf("corrupt Avro block: checksum mismatch")
+		}
+	}
+
+	if count < 0 {
+		return fmt.Errorf("corrupt Avro block: negative record count")
+	}
+	r.block = avroDecoder{data: data}
+	r.remaining = count
+	return nil
+}
+
+// avroDecoder decodes Avro binary-encoded values
+type avroDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *avroDecoder) long() (int64, error) {
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *avroDecoder) fixed(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n > int64(len(d.data)) {
+		return nil, errTruncated
+	}
+	return d.fixed(int(n))
+}
+
+// text decodes a value and renders it as a CSV cell: logical dates and
+// timestamps as in Parquet files, nulls as empty, nested values as JSON
+func (d *avroDecoder) text(s *avroSchema) (string, error) {
+	switch s.typ {
+	case "union":
+		index, err := d.long()
+		if err != nil {
+			return "", err
+		}
+		if index < 0 || index >= int64(len(s.union)) {
+			return "", fmt.Errorf("union branch %d out of range", index)
+		}
+		return d.text(s.union[index])
+	case "null":
+		return "", nil
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return "", err
+		}
+		switch s.logicalType {
+		case "date":
+			return time.Unix(v*86400, 0).UTC().Format("2006-01-02"), nil
+		case "timestamp-millis", "local-timestamp-millis":
+			return time.UnixMilli(v).UTC().Format(time.RFC3339Nano), nil
+		case "timestamp-micros", "local-timestamp-micros":
+			return time.UnixMicro(v).UTC().Format(time.RFC3339Nano), nil
+		case "timestamp-nanos", "local-timestamp-nanos":
+			return time.Unix(0, v).UTC().Format(time.RFC3339Nano), nil
+		}
+		return strconv.FormatInt(v, 10), nil
+	case "bytes", "fixed":
+		var b []byte
+		var err error
+		if s.typ == "fixed" {
+			b, err = d.fixed(s.size)
+		} else {
+			b, err = d.bytes()
+		}
+		if err != nil {
+			return "", err
+		}
+		if s.logicalType == "decimal" {
+			return formatDecimal(twosComplement(b), s.scale), nil
+		}
+		return string(b), nil
+	case "string", "boolean", "float", "double", "enum":
+		v, err := d.value(s)
+		if err != nil {
+			return "", err
+		}
+		switch v := v.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case float64:
+			bits := 64
+			if s.typ == "float" {
+				bits = 32
+			}
+			return strconv.FormatFloat(v, 'f', -1, bits), nil
+		}
+		return v.(string), nil
+	}
+
+	v, err := d.value(s)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// value decodes a value into Go types JSON can render
+func (d *avroDecoder) value(s *avroSchema) (interface{}, error) {
+	switch s.typ {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int", "long":
+		return d.long()
+	case "float":
+		b, err := d.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case "double":
+		b, err := d.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes", "fixed":
+		var b []byte
+		var err error
+		if s.typ == "fixed" {
+			b, err = d.fixed(s.size)
+		} else {
+			b, err = d.bytes()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.logicalType == "decimal" {
+			return json.Number(formatDecimal(twosComplement(b), s.scale)), nil
+		}
+		return string(b), nil
+	case "string":
+		b, err := d.bytes()
+		return string(b), err
+	case "enum":
+		index, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("enum symbol %d out of range", index)
+		}
+		return s.symbols[index], nil
+	case "union":
+		index, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s.union)) {
+			return nil, fmt.Errorf("union branch %d out of range", index)
+		}
+		return d.value(s.union[index])
+	case "record":
+		record := make(map[string]interface{}, len(s.fields))
+		for _, f := range s.fields {
+			v, err := d.value(f.schema)
+			if err != nil {
+				return nil, err
+			}
+			record[f.name] = v
+		}
+		return record, nil
+	case "array", "map":
+		list := []interface{}{}
+		entries := map[string]interface{}{}
+		for {
+			count, err := d.long()
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				break
+			}
+			if count < 0 {
+				// A negative count is followed by the block's size in bytes
+				count = -count
+				if _, err := d.long(); err != nil {
+					return nil, err
+				}
+			}
+			if count > int64(len(d.data)) {
+				return nil, errTruncated
+			}
+			for i := int64(0); i < count; i++ {
+				var key []byte
+				if s.typ == "map" {
+					if key, err = d.bytes(); err != nil {
+						return nil, err
+					}
+				}
+				v, err := d.value(s.items)
+				if err != nil {
+					return nil, err
+				}
+				if s.typ == "map" {
+					entries[string(key)] = v
+				} else {
+					list = append(list, v)
+				}
+			}
+		}
+		if s.typ == "map" {
+			return entries, nil
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("unknown Avro type %q", s.typ)
+}
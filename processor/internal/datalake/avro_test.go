@@ -0,0 +1,149 @@
+package datalake
+
+import (
+	"bytes"
+	"code/internal/csv"
+	"code/internal/models"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const leadSchema = `{
+	"type": "record", "name": "Lead", "namespace": "com.acme",
+	"fields": [
+		{"name": "full_name", "type": "string"},
+		{"name": "work_email", "type": "string"},
+		{"name": "company", "type": ["null", "string"], "default": null},
+		{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}}
+	]
+}`
+
+var avroSync = []byte("0123456789abcdef")
+
+func avroString(b []byte, s string) []byte {
+	b = binary.AppendVarint(b, int64(len(s)))
+	return append(b, s...)
+}
+
+// avroLead encodes one record of leadSchema
+func avroLead(name, email, company string, createdAt int64, tags ...string) []byte {
+	b := avroString(nil, name)
+	b = avroString(b, email)
+	if company == "" {
+		b = binary.AppendVarint(b, 0)
+	} else {
+		b = avroString(binary.AppendVarint(b, 1), company)
+	}
+	b = binary.AppendVarint(b, createdAt)
+	if len(tags) > 0 {
+		b = binary.AppendVarint(b, int64(len(tags)))
+		for _, tag := range tags {
+			b = avroString(b, tag)
+		}
+	}
+	return binary.AppendVarint(b, 0)
+}
+
+// avroFile writes an object container file of leadSchema with one block
+func avroFile(t *testing.T, codec string, records ...[]byte) string {
+	b := []byte(avroMagic)
+	b = binary.AppendVarint(b, 2)
+	b = avroString(avroString(b, "avro.schema"), leadSchema)
+	b = avroString(avroString(b, "avro.codec"), codec)
+	b = binary.AppendVarint(b, 0)
+	b = append(b, avroSync...)
+
+	block := bytes.Join(records, nil)
+	switch codec {
+	case "deflate":
+		var buf bytes.Buffer
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		w.Write(block)
+		w.Close()
+		block = buf.Bytes()
+	case "snappy":
+		checksum := crc32.ChecksumIEEE(block)
+		block = binary.BigEndian.AppendUint32(snappyLiteral(block), checksum)
+	}
+	b = binary.AppendVarint(b, int64(len(records)))
+	b = binary.AppendVarint(b, int64(len(block)))
+	b = append(b, block...)
+	b = append(b, avroSync...)
+
+	path := filepath.Join(t.TempDir(), "leads.avro")
+	assert.NoError(t, os.WriteFile(path, b, 0o644))
+	return path
+}
+
+func TestAvroReader(t *testing.T) {
+	records := [][]byte{
+		avroLead("Jane Smith", "jane@techfirm.com", "TechFirm", 1792145700000, "webinar", "q4"),
+		avroLead("John Doe", "john@acme.com", "", 1792145760000),
+	}
+
+	for _, codec := range []string{"null", "deflate", "snappy"} {
+		t.Run("reads "+codec+" blocks", func(t *testing.T) {
+			// Arrange
+			r, err := OpenAvro(avroFile(t, codec, records...))
+			assert.NoError(t, err)
+			defer r.Close()
+
+			// Act
+			first, firstErr := r.Next()
+			second, secondErr := r.Next()
+			_, endErr := r.Next()
+
+			// Assert
+			assert.Equal(t, []string{"full_name", "work_email", "company", "created_at", "tags"}, r.Header())
+			assert.NoError(t, firstErr)
+			assert.NoError(t, secondErr)
+			assert.Equal(t, []string{"Jane Smith", "jane@techfirm.com", "TechFirm", "2026-10-16T10:15:00Z", `["webinar","q4"]`}, first)
+			assert.Equal(t, []string{"John Doe", "john@acme.com", "", "2026-10-16T10:16:00Z", "[]"}, second)
+			assert.Equal(t, io.EOF, endErr)
+		})
+	}
+
+	t.Run("rejects files that aren't Avro", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "leads.avro")
+		os.WriteFile(path, []byte("Name,Email\n"), 0o644)
+
+		// Act
+		_, err := OpenAvro(path)
+
+		// Assert
+		assert.ErrorContains(t, err, "not an Avro")
+	})
+}
+
+func TestFileSource(t *testing.T) {
+	t.Run("maps columns to lead fields", func(t *testing.T) {
+		// Arrange
+		path := avroFile(t, "null", avroLead("Jane Smith", "jane@techfirm.com", "TechFirm", 1792145700000))
+		reader := csv.NewCSVReader()
+		reader.SetMapping(map[string]string{"name": "full_name", "email": "work_email"})
+		source := NewFileSource(path, reader)
+
+		// Act
+		var leads []*models.Lead
+		err := source.StreamLeads(func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "Jane Smith", leads[0].Name)
+		assert.Equal(t, "jane@techfirm.com", leads[0].Email)
+		assert.Equal(t, "TechFirm", leads[0].Company)
+	})
+}
@@ -0,0 +1,230 @@
+package datalake
+
+import (
+	"code/internal/pii"
+	"code/internal/report"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatParquet, FormatCSV}
+
+// Row is one lead outcome of a run, as exported
+type Row struct {
+	RunID string
+	// Labels are the run's labels, e.g. campaign=q3-webinar,team=emea
+	Labels string
+	report.Record
+}
+
+// rowWriter writes rows in one of the export formats
+type rowWriter interface {
+	Write(row Row) error
+	Close() error
+}
+
+// Exporter writes a run's processed leads to S3 or a local directory,
+// partitioned by run date so tools like Athena can prune by day
+type Exporter struct {
+	format string
+	bucket string
+	prefix string
+	dir    string
+	s3     *S3
+	labels string
+	pii    *pii.Hasher
+}
+
+// Config adjusts what --export writes
+type Config struct {
+	// PII pseudonymizes identifying columns, so the data lake holds no raw
+	// names or emails
+	PII pii.Config `yaml:"pii"`
+}
+
+// Validate checks the PII settings
+func (c Config) Validate() error {
+	if err := c.PII.Validate(); err != nil {
+		return fmt.Errorf("pii: %w", err)
+	}
+	return nil
+}
+
+// New creates an exporter to dest, either s3://bucket/prefix or a local
+// directory. S3 credentials come from the AWS environment variables.
+func New(dest, format string, httpClient *http.Client) (*Exporter, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != FormatParquet && format != FormatCSV {
+		return nil, fmt.Errorf("invalid export format %q (use %s)", format, strings.Join(Formats, ", "))
+	}
+
+	e := &Exporter{format: format}
+	if !strings.HasPrefix(dest, "s3://") {
+		if dest == "" {
+			return nil, fmt.Errorf("an export destination is required")
+		}
+		e.dir = dest
+		return e, nil
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(dest, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid export destination %s: no bucket", dest)
+	}
+	s3, err := NewS3FromEnv(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	e.bucket = bucket
+	e.prefix = strings.Trim(prefix, "/")
+	e.s3 = s3
+	return e, nil
+}
+
+// SetLabels sets the run labels exported with every row, joined as
+// key=value pairs
+func (e *Exporter) SetLabels(labels string) {
+	e.labels = labels
+}
+
+// SetPII replaces the columns hasher protects with their hashes or tokens
+func (e *Exporter) SetPII(hasher *pii.Hasher) {
+	e.pii = hasher
+}
+
+// Key is where a run's file is stored, relative to the destination:
+// run_date=YYYY-MM-DD/<run-id>.<format>
+func (e *Exporter) Key(runID string, runDate time.Time) string {
+	return path.Join("run_date="+runDate.UTC().Format("2006-01-02"), runID+"."+e.format)
+}
+
+// Export writes the records of a run and returns where they were stored.
+// each calls its function once per record, like report.Store.Each.
+func (e *Exporter) Export(runID string, runDate time.Time, each func(func(report.Record) error) error) (string, error) {
+	file, err := os.CreateTemp("", "lead-export-*."+e.format)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var w rowWriter
+	if e.format == FormatCSV {
+		w = newCSVWriter(file)
+	} else {
+		w = NewParquetWriter(file)
+	}
+	err = each(func(record report.Record) error {
+		if e.pii != nil {
+			record = e.pii.Record(record)
+		}
+		return w.Write(Row{RunID: runID, Labels: e.labels, Record: record})
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write export: %w", err)
+	}
+
+	key := e.Key(runID, runDate)
+	if e.s3 == nil {
+		target := filepath.Join(e.dir, filepath.FromSlash(key))
+		if err := copyFile(file, target); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+
+	if e.prefix != "" {
+		key = e.prefix + "/" + key
+	}
+	contentType := "application/vnd.apache.parquet"
+	if e.format == FormatCSV {
+		contentType = "text/csv"
+	}
+	if err := e.s3.PutObject(e.bucket, key, contentType, file); err != nil {
+		return "", err
+	}
+	return "s3://" + e.bucket + "/" + key, nil
+}
+
+// copyFile copies the written export into its partition directory
+func copyFile(file *os.File, target string) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return out.Close()
+}
+
+// csvWriter writes rows as CSV with a header of the Parquet column names
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Write(row Row) error {
+	if !c.header {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	values := make([]string, len(columns))
+	for i, col := range columns {
+		if col.int64 != nil {
+			values[i] = strconv.FormatInt(col.int64(row), 10)
+		} else {
+			values[i] = col.str(row)
+		}
+	}
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) writeHeader() error {
+	c.header = true
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+	return c.w.Write(names)
+}
+
+func (c *csvWriter) Close() error {
+	if !c.header {
+		if err := c.writeHeader(); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
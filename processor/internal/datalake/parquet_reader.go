@@ -0,0 +1,717 @@
+package datalake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parquet types, encodings and codecs only the reader needs
+const (
+	typeBoolean           = 0
+	typeInt32             = 1
+	typeInt96             = 3
+	typeFloat             = 4
+	typeDouble            = 5
+	typeFixedLenByteArray = 7
+
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+
+	repetitionRepeated = 2
+
+	pageTypeDictionary = 2
+	pageTypeDataV2     = 3
+
+	encodingPlainDictionary      = 2
+	encodingDeltaBinaryPacked    = 5
+	encodingDeltaLengthByteArray = 6
+	encodingDeltaByteArray       = 7
+	encodingRLEDictionary        = 8
+
+	codecSnappy = 1
+	codecGzip   = 2
+)
+
+// codecNames names the compression codecs that aren't supported
+var codecNames = map[int64]string{3: "LZO", 4: "Brotli", 5: "LZ4", 6: "Zstandard", 7: "LZ4"}
+
+// julianUnixEpoch is the Julian day of 1970-01-01, for INT96 timestamps
+const julianUnixEpoch = 2440588
+
+// leaf is a column of a flat Parquet schema
+type leaf struct {
+	name     string
+	typ      int64
+	optional bool
+	fixedLen int64
+	decimal  bool
+	scale    int64
+	date     bool
+	uuid     bool
+	// unit is millis, micros or nanos for timestamp columns
+	unit string
+}
+
+// ParquetReader reads the rows of a flat Parquet file one row group at a
+// time, rendering every value as text like a CSV cell
+type ParquetReader struct {
+	file    *os.File
+	size    int64
+	leaves  []leaf
+	groups  []interface{}
+	group   int
+	columns [][]string
+	rows    int
+	row     int
+}
+
+// OpenParquet opens a Parquet file and reads its schema. Nested and
+// repeated columns are not supported.
+func OpenParquet(path string) (*ParquetReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &ParquetReader{file: file}
+	if err := r.readFooter(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+func (r *ParquetReader) readFooter() error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+	r.size = info.Size()
+
+	tail := make([]byte, 8)
+	if r.size < 12 {
+		return fmt.Errorf("not a Parquet file")
+	}
+	if _, err := r.file.ReadAt(tail, r.size-8); err != nil {
+		return err
+	}
+	if string(tail[4:]) != parquetMagic {
+		return fmt.Errorf("not a Parquet file")
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(tail))
+	if footerSize > r.size-12 {
+		return fmt.Errorf("corrupt Parquet footer")
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.file.ReadAt(footer, r.size-8-footerSize); err != nil {
+		return err
+	}
+
+	c := compactReader{data: footer}
+	meta, err := c.readStruct()
+	if err != nil {
+		return fmt.Errorf("corrupt Parquet footer: %w", err)
+	}
+
+	schema := meta.list(2)
+	if len(schema) < 2 {
+		return fmt.Errorf("the file has no columns")
+	}
+	for _, e := range schema[1:] {
+		el, _ := e.(tstruct)
+		if el.int(5) > 0 || el.int(3) == repetitionRepeated {
+			return fmt.Errorf("column %s is nested or repeated; only flat schemas are supported", el.str(4))
+		}
+		r.leaves = append(r.leaves, newLeaf(el))
+	}
+	r.groups = meta.list(4)
+	return nil
+}
+
+// newLeaf reads a column's type from its schema element, preferring the
+// logical type over the legacy converted type
+func newLeaf(el tstruct) leaf {
+	l := leaf{
+		name:     el.str(4),
+		typ:      el.int(1),
+		optional: el.int(3) == repetitionOptional,
+		fixedLen: el.int(2),
+	}
+
+	if el.has(6) {
+		switch el.int(6) {
+		case convertedDecimal:
+			l.decimal, l.scale = true, el.int(7)
+		case convertedDate:
+			l.date = true
+		case convertedTimestampMillis:
+			l.unit = "millis"
+		case convertedTimestampMicros:
+			l.unit = "micros"
+		}
+	}
+
+	if logical := el.strct(10); logical != nil {
+		if decimal := logical.strct(5); decimal != nil {
+			l.decimal, l.scale = true, decimal.int(1)
+		}
+		l.date = l.date || logical.has(6)
+		l.uuid = logical.has(14)
+		if timestamp := logical.strct(8); timestamp != nil {
+			unit := timestamp.strct(2)
+			switch {
+			case unit.has(1):
+				l.unit = "millis"
+			case unit.has(2):
+				l.unit = "micros"
+			case unit.has(3):
+				l.unit = "nanos"
+			}
+		}
+	}
+	return l
+}
+
+// Header returns the column names
+func (r *ParquetReader) Header() []string {
+	names := make([]string, len(r.leaves))
+	for i, l := range r.leaves {
+		names[i] = l.name
+	}
+	return names
+}
+
+// Next returns the next row, or io.EOF after the last
+func (r *ParquetReader) Next() ([]string, error) {
+	for r.row >= r.rows {
+		if r.group >= len(r.groups) {
+			return nil, io.EOF
+		}
+		group, _ := r.groups[r.group].(tstruct)
+		r.group++
+		if err := r.readGroup(group); err != nil {
+			return nil, fmt.Errorf("row group %d: %w", r.group, err)
+		}
+	}
+
+	row := make([]string, len(r.leaves))
+	for i := range r.leaves {
+		row[i] = r.columns[i][r.row]
+	}
+	r.row++
+	return row, nil
+}
+
+// Close closes the file
+func (r *ParquetReader) Close() error {
+	return r.file.Close()
+}
+
+func (r *ParquetReader) readGroup(group tstruct) error {
+	chunks := group.list(1)
+	if len(chunks) != len(r.leaves) {
+		return fmt.Errorf("%d column chunks for %d columns", len(chunks), len(r.leaves))
+	}
+
+	r.rows = int(group.int(3))
+	r.row = 0
+	r.columns = make([][]string, len(r.leaves))
+	for i, chunk := range chunks {
+		meta := chunk.(tstruct).strct(3)
+		if meta == nil {
+			return fmt.Errorf("column %s is stored in another file, which is not supported", r.leaves[i].name)
+		}
+		values, err := r.readChunk(r.leaves[i], meta)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", r.leaves[i].name, err)
+		}
+		r.columns[i] = values
+	}
+	return nil
+}
+
+// readChunk decodes every page of a column chunk
+func (r *ParquetReader) readChunk(l leaf, meta tstruct) ([]string, error) {
+	start := meta.int(9)
+	if offset := meta.int(11); offset > 0 && offset < start {
+		start = offset
+	}
+	size := meta.int(7)
+	if start < 4 || size < 0 || start+size > r.size {
+		return nil, fmt.Errorf("column chunk is out of the file's bounds")
+	}
+	data := make([]byte, size)
+	if _, err := r.file.ReadAt(data, start); err != nil {
+		return nil, err
+	}
+
+	codec := meta.int(4)
+	values := make([]string, 0, r.rows)
+	var dict []string
+	for pos := 0; len(values) < r.rows && pos < len(data); {
+		c := compactReader{data: data, pos: pos}
+		header, err := c.readStruct()
+		if err != nil {
+			return nil, fmt.Errorf("corrupt page header: %w", err)
+		}
+		end := c.pos + int(header.int(3))
+		if end > len(data) || end < c.pos {
+			return nil, fmt.Errorf("page is out of the column chunk's bounds")
+		}
+		body := data[c.pos:end]
+		pos = end
+
+		switch header.int(1) {
+		case pageTypeDictionary:
+			raw, err := decompress(codec, body)
+			if err != nil {
+				return nil, err
+			}
+			if dict, err = decodePlain(l, raw, int(header.strct(7).int(1))); err != nil {
+				return nil, fmt.Errorf("dictionary: %w", err)
+			}
+		case pageTypeData:
+			raw, err := decompress(codec, body)
+			if err != nil {
+				return nil, err
+			}
+			page := header.strct(5)
+			n := int(page.int(1))
+			var defs []int
+			if l.optional {
+				if len(raw) < 4 || int(binary.LittleEndian.Uint32(raw))+4 > len(raw) {
+					return nil, fmt.Errorf("corrupt definition levels")
+				}
+				length := int(binary.LittleEndian.Uint32(raw))
+				if defs, err = decodeHybrid(raw[4:4+length], 1, n); err != nil {
+					return nil, err
+				}
+				raw = raw[4+length:]
+			}
+			if values, err = appendPage(values, l, page.int(2), raw, defs, n, dict); err != nil {
+				return nil, err
+			}
+		case pageTypeDataV2:
+			page := header.strct(8)
+			n := int(page.int(1))
+			levels := int(page.int(5) + page.int(6))
+			if levels > len(body) {
+				return nil, fmt.Errorf("corrupt definition levels")
+			}
+			raw := body[levels:]
+			if compressed, ok := page[7].(bool); !ok || compressed {
+				if raw, err = decompress(codec, raw); err != nil {
+					return nil, err
+				}
+			}
+			var defs []int
+			if l.optional {
+				if defs, err = decodeHybrid(body[page.int(6):levels], 1, n); err != nil {
+					return nil, err
+				}
+			}
+			if values, err = appendPage(values, l, page.int(4), raw, defs, n, dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(values) < r.rows {
+		return nil, fmt.Errorf("%d values for %d rows", len(values), r.rows)
+	}
+	return values[:r.rows], nil
+}
+
+// appendPage decodes a data page's values, leaving nulls as empty strings
+func appendPage(values []string, l leaf, encoding int64, data []byte, defs []int, n int, dict []string) ([]string, error) {
+	present := n
+	if defs != nil {
+		present = 0
+		for _, d := range defs {
+			present += d
+		}
+	}
+
+	decoded, err := decodeValues(l, encoding, data, present, dict)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < present {
+		return nil, fmt.Errorf("page holds %d of %d values", len(decoded), present)
+	}
+	if defs == nil {
+		return append(values, decoded[:n]...), nil
+	}
+	next := 0
+	for _, d := range defs {
+		if d == 0 {
+			values = append(values, "")
+			continue
+		}
+		values = append(values, decoded[next])
+		next++
+	}
+	return values, nil
+}
+
+func decodeValues(l leaf, encoding int64, data []byte, n int, dict []string) ([]string, error) {
+	switch encoding {
+	case encodingPlain:
+		return decodePlain(l, data, n)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if dict == nil {
+			return nil, fmt.Errorf("dictionary-encoded page without a dictionary")
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("corrupt dictionary indices")
+		}
+		indices, err := decodeHybrid(data[1:], int(data[0]), n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, n)
+		for i, index := range indices {
+			if index >= len(dict) {
+				return nil, fmt.Errorf("dictionary index %d out of range", index)
+			}
+			values[i] = dict[index]
+		}
+		return values, nil
+	case encodingRLE:
+		// Only booleans are RLE-encoded, with a length prefix
+		if l.typ != typeBoolean || len(data) < 4 {
+			return nil, fmt.Errorf("unexpected RLE-encoded values")
+		}
+		bits, err := decodeHybrid(data[4:], 1, n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, n)
+		for i, bit := range bits {
+			values[i] = strconv.FormatBool(bit == 1)
+		}
+		return values, nil
+	case encodingDeltaBinaryPacked:
+		ints, _, err := decodeDeltaBinaryPacked(data)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, len(ints))
+		for i, v := range ints {
+			values[i] = formatInt(l, v)
+		}
+		return values, nil
+	case encodingDeltaLengthByteArray:
+		raw, err := decodeDeltaLength(data)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, len(raw))
+		for i, b := range raw {
+			values[i] = formatBytes(l, b)
+		}
+		return values, nil
+	case encodingDeltaByteArray:
+		prefixes, rest, err := decodeDeltaBinaryPacked(data)
+		if err != nil {
+			return nil, err
+		}
+		suffixes, err := decodeDeltaLength(rest)
+		if err != nil {
+			return nil, err
+		}
+		if len(suffixes) != len(prefixes) {
+			return nil, fmt.Errorf("corrupt delta byte array")
+		}
+		values := make([]string, len(suffixes))
+		var previous []byte
+		for i, suffix := range suffixes {
+			if prefixes[i] < 0 || int(prefixes[i]) > len(previous) {
+				return nil, fmt.Errorf("corrupt delta byte array")
+			}
+			value := append(append([]byte{}, previous[:prefixes[i]]...), suffix...)
+			values[i] = formatBytes(l, value)
+			previous = value
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("encoding %d is not supported", encoding)
+}
+
+// decodePlain decodes n PLAIN-encoded values
+func decodePlain(l leaf, data []byte, n int) ([]string, error) {
+	values := make([]string, 0, n)
+	width := map[int64]int{typeInt32: 4, typeInt64: 8, typeInt96: 12, typeFloat: 4, typeDouble: 8, typeFixedLenByteArray: int(l.fixedLen)}[l.typ]
+
+	for i := 0; i < n; i++ {
+		switch l.typ {
+		case typeBoolean:
+			if i/8 >= len(data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			values = append(values, strconv.FormatBool(data[i/8]>>(i%8)&1 == 1))
+			continue
+		case typeByteArray:
+			if len(data) < 4 || int(binary.LittleEndian.Uint32(data)) > len(data)-4 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			length := int(binary.LittleEndian.Uint32(data))
+			values = append(values, formatBytes(l, data[4:4+length]))
+			data = data[4+length:]
+			continue
+		}
+
+		if len(data) < width {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := data[:width]
+		data = data[width:]
+		switch l.typ {
+		case typeInt32:
+			values = append(values, formatInt(l, int64(int32(binary.LittleEndian.Uint32(b)))))
+		case typeInt64:
+			values = append(values, formatInt(l, int64(binary.LittleEndian.Uint64(b))))
+		case typeInt96:
+			nanos := int64(binary.LittleEndian.Uint64(b))
+			days := int64(binary.LittleEndian.Uint32(b[8:])) - julianUnixEpoch
+			values = append(values, time.Unix(days*86400, nanos).UTC().Format(time.RFC3339Nano))
+		case typeFloat:
+			values = append(values, strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'f', -1, 32))
+		case typeDouble:
+			values = append(values, strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'f', -1, 64))
+		case typeFixedLenByteArray:
+			values = append(values, formatBytes(l, b))
+		default:
+			return nil, fmt.Errorf("unknown type %d", l.typ)
+		}
+	}
+	return values, nil
+}
+
+// formatInt renders an integer with its logical type: dates as YYYY-MM-DD,
+// timestamps as RFC 3339 in UTC, decimals with their scale
+func formatInt(l leaf, v int64) string {
+	switch {
+	case l.decimal:
+		return formatDecimal(big.NewInt(v), l.scale)
+	case l.date:
+		return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+	case l.unit == "millis":
+		return time.UnixMilli(v).UTC().Format(time.RFC3339Nano)
+	case l.unit == "micros":
+		return time.UnixMicro(v).UTC().Format(time.RFC3339Nano)
+	case l.unit == "nanos":
+		return time.Unix(0, v).UTC().Format(time.RFC3339Nano)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// formatBytes renders a binary value as text, decimals as numbers and UUIDs
+// in their usual form
+func formatBytes(l leaf, b []byte) string {
+	switch {
+	case l.decimal:
+		return formatDecimal(twosComplement(b), l.scale)
+	case l.uuid && len(b) == 16:
+		h := hex.EncodeToString(b)
+		return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	}
+	return string(b)
+}
+
+// twosComplement reads a big-endian two's complement integer
+func twosComplement(b []byte) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return v
+}
+
+// formatDecimal places the decimal point scale digits from the right
+func formatDecimal(unscaled *big.Int, scale int64) string {
+	digits := new(big.Int).Abs(unscaled).String()
+	if scale > 0 {
+		if pad := int(scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(scale)] + "." + digits[len(digits)-int(scale):]
+	}
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+func decompress(codec int64, data []byte) ([]byte, error) {
+	switch codec {
+	case codecNone:
+		return data, nil
+	case codecSnappy:
+		return decodeSnappy(data)
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+	name := codecNames[codec]
+	if name == "" {
+		name = fmt.Sprintf("codec %d", codec)
+	}
+	return nil, fmt.Errorf("%s compression is not supported; write the file with snappy, gzip or no compression", name)
+}
+
+// decodeHybrid decodes n values of the RLE/bit-packing hybrid encoding used
+// for levels and dictionary indices
+func decodeHybrid(data []byte, bitWidth, n int) ([]int, error) {
+	if bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	values := make([]int, 0, n)
+	for len(values) < n {
+		header, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, fmt.Errorf("corrupt RLE data")
+		}
+		data = data[size:]
+
+		if header&1 == 1 {
+			count := int(header>>1) * 8
+			packed := count * bitWidth / 8
+			if count == 0 || packed > len(data) {
+				return nil, fmt.Errorf("corrupt RLE data")
+			}
+			for _, v := range unpackBits(data[:packed], bitWidth, count) {
+				values = append(values, int(v))
+			}
+			data = data[packed:]
+			continue
+		}
+
+		count := int(header >> 1)
+		width := (bitWidth + 7) / 8
+		if count == 0 || width > len(data) {
+			return nil, fmt.Errorf("corrupt RLE data")
+		}
+		value := 0
+		for i := width - 1; i >= 0; i-- {
+			value = value<<8 | int(data[i])
+		}
+		data = data[width:]
+		for i := 0; i < count; i++ {
+			values = append(values, value)
+		}
+	}
+	return values[:n], nil
+}
+
+// unpackBits reads count values of bitWidth bits each, least significant
+// bit first
+func unpackBits(data []byte, bitWidth, count int) []uint64 {
+	values := make([]uint64, count)
+	for i := range values {
+		var v uint64
+		for b := 0; b < bitWidth; b++ {
+			bit := i*bitWidth + b
+			if bit/8 < len(data) && data[bit/8]>>(bit%8)&1 == 1 {
+				v |= 1 << b
+			}
+		}
+		values[i] = v
+	}
+	return values
+}
+
+// decodeDeltaBinaryPacked decodes DELTA_BINARY_PACKED integers, returning
+// the data that follows them
+func decodeDeltaBinaryPacked(data []byte) ([]int64, []byte, error) {
+	c := compactReader{data: data}
+	blockSize, err := c.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	miniblocks, err := c.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	total, err := c.uvarint()
+	if err != nil {
+		return nil, nil, err
+	}
+	last, err := c.zigzag()
+	if err != nil {
+		return nil, nil, err
+	}
+	if miniblocks == 0 || blockSize%miniblocks != 0 || total > uint64(len(data))*64 {
+		return nil, nil, fmt.Errorf("corrupt delta encoding")
+	}
+	perMiniblock := int(blockSize / miniblocks)
+
+	values := make([]int64, 0, total)
+	if total > 0 {
+		values = append(values, last)
+	}
+	for uint64(len(values)) < total {
+		minDelta, err := c.zigzag()
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.pos+int(miniblocks) > len(data) {
+			return nil, nil, errTruncated
+		}
+		widths := data[c.pos : c.pos+int(miniblocks)]
+		c.pos += int(miniblocks)
+
+		for _, width := range widths {
+			if uint64(len(values)) >= total {
+				break
+			}
+			size := perMiniblock * int(width) / 8
+			if c.pos+size > len(data) {
+				return nil, nil, errTruncated
+			}
+			for _, delta := range unpackBits(data[c.pos:c.pos+size], int(width), perMiniblock) {
+				if uint64(len(values)) >= total {
+					break
+				}
+				last += minDelta + int64(delta)
+				values = append(values, last)
+			}
+			c.pos += size
+		}
+	}
+	return values, data[c.pos:], nil
+}
+
+// decodeDeltaLength decodes DELTA_LENGTH_BYTE_ARRAY values
+func decodeDeltaLength(data []byte) ([][]byte, error) {
+	lengths, rest, err := decodeDeltaBinaryPacked(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(lengths))
+	for i, length := range lengths {
+		if length < 0 || int(length) > len(rest) {
+			return nil, errTruncated
+		}
+		values[i] = rest[:length]
+		rest = rest[length:]
+	}
+	return values, nil
+}
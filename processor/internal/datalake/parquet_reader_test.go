@@ -0,0 +1,209 @@
+package datalake
+
+import (
+	"code/internal/report"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readAll reads every row of a Parquet file
+func readAll(t *testing.T, path string) ([]string, [][]string) {
+	r, err := OpenParquet(path)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	var rows [][]string
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		rows = append(rows, row)
+	}
+	return r.Header(), rows
+}
+
+// snappyLiteral encodes data as a single snappy literal
+func snappyLiteral(data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(data)))
+	out = append(out, 60<<2, byte(len(data)-1))
+	return append(out, data...)
+}
+
+// dictionaryFile builds a snappy-compressed file with one optional email
+// column, dictionary-encoded the way Spark and pyarrow write strings
+func dictionaryFile(t *testing.T) string {
+	dict := []byte{}
+	for _, email := range []string{"jane@techfirm.com", "john@acme.com"} {
+		dict = binary.LittleEndian.AppendUint32(dict, uint32(len(email)))
+		dict = append(dict, email...)
+	}
+	// Definition levels 1, 0, 1 then indices 0, 1 at bit width 1
+	data := []byte{2, 0, 0, 0, 0x03, 0x05, 1, 0x03, 0x02}
+
+	var file []byte
+	file = append(file, parquetMagic...)
+	page := func(typ int32, headerField int16, body []byte, values int32, encoding int32) {
+		compressed := snappyLiteral(body)
+		var h compactWriter
+		h.begin()
+		h.i32(1, typ)
+		h.i32(2, int32(len(body)))
+		h.i32(3, int32(len(compressed)))
+		h.structField(headerField)
+		h.i32(1, values)
+		h.i32(2, encoding)
+		if typ == pageTypeData {
+			h.i32(3, encodingRLE)
+			h.i32(4, encodingRLE)
+		}
+		h.end()
+		h.end()
+		file = append(file, h.buf.Bytes()...)
+		file = append(file, compressed...)
+	}
+	page(pageTypeDictionary, 7, dict, 2, encodingPlainDictionary)
+	dataOffset := int64(len(file))
+	page(pageTypeData, 5, data, 3, encodingRLEDictionary)
+	chunkSize := int64(len(file)) - 4
+
+	var c compactWriter
+	c.begin()
+	c.i32(1, 1)
+	c.list(2, ctStruct, 2)
+	c.begin()
+	c.str(4, "spark_schema")
+	c.i32(5, 1)
+	c.end()
+	c.begin()
+	c.i32(1, typeByteArray)
+	c.i32(3, repetitionOptional)
+	c.str(4, "email")
+	c.i32(6, convertedUTF8)
+	c.end()
+	c.i64(3, 3)
+	c.list(4, ctStruct, 1)
+	c.begin()
+	c.list(1, ctStruct, 1)
+	c.begin()
+	c.i64(2, 4)
+	c.structField(3)
+	c.i32(1, typeByteArray)
+	c.list(2, ctI32, 1)
+	c.varint(zigzag(encodingRLEDictionary))
+	c.list(3, ctBinary, 1)
+	c.bytes("email")
+	c.i32(4, codecSnappy)
+	c.i64(5, 3)
+	c.i64(6, chunkSize)
+	c.i64(7, chunkSize)
+	c.i64(9, dataOffset)
+	c.i64(11, 4)
+	c.end()
+	c.end()
+	c.i64(2, chunkSize)
+	c.i64(3, 3)
+	c.end()
+	c.end()
+
+	file = append(file, c.buf.Bytes()...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(c.buf.Len()))
+	file = append(file, parquetMagic...)
+
+	path := filepath.Join(t.TempDir(), "leads.parquet")
+	assert.NoError(t, os.WriteFile(path, file, 0o644))
+	return path
+}
+
+func TestParquetReader(t *testing.T) {
+	t.Run("reads back an exported file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "run.parquet")
+		file, _ := os.Create(path)
+		w := NewParquetWriter(file)
+		w.Write(Row{RunID: "run-1", Record: report.Record{Index: 1, Name: "Jane Smith", Email: "jane@techfirm.com", Action: "CREATE", LeadID: "lead_1"}})
+		w.Write(Row{RunID: "run-1", Record: report.Record{Index: 2, Name: "John Doe", Email: "john@acme.com", Action: "SKIP"}})
+		w.Close()
+		file.Close()
+
+		// Act
+		header, rows := readAll(t, path)
+
+		// Assert
+		assert.Equal(t, []string{"run_id", "row", "name", "email", "company", "source", "status", "action", "lead_id", "error_code", "error", "labels"}, header)
+		assert.Equal(t, [][]string{
+			{"run-1", "1", "Jane Smith", "jane@techfirm.com", "", "", "", "CREATE", "lead_1", "", "", ""},
+			{"run-1", "2", "John Doe", "john@acme.com", "", "", "", "SKIP", "", "", "", ""},
+		}, rows)
+	})
+
+	t.Run("reads snappy-compressed dictionary pages", func(t *testing.T) {
+		// Act
+		header, rows := readAll(t, dictionaryFile(t))
+
+		// Assert
+		assert.Equal(t, []string{"email"}, header)
+		assert.Equal(t, [][]string{{"jane@techfirm.com"}, {""}, {"john@acme.com"}}, rows)
+	})
+
+	t.Run("rejects files that aren't Parquet", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "leads.parquet")
+		os.WriteFile(path, []byte("Name,Email\nJane,jane@techfirm.com\n"), 0o644)
+
+		// Act
+		_, err := OpenParquet(path)
+
+		// Assert
+		assert.ErrorContains(t, err, "not a Parquet file")
+	})
+}
+
+func TestDecodeDeltaBinaryPacked(t *testing.T) {
+	t.Run("decodes the examples of the Parquet spec", func(t *testing.T) {
+		// Arrange
+		ascending := []byte{0x80, 0x01, 0x04, 0x05, 0x02, 0x02, 0x00, 0x00, 0x00, 0x00}
+		mixed := []byte{0x80, 0x01, 0x04, 0x08, 0x0e, 0x03, 0x02, 0x00, 0x00, 0x00, 0xc0, 0x3f, 0, 0, 0, 0, 0, 0}
+
+		// Act
+		first, _, firstErr := decodeDeltaBinaryPacked(ascending)
+		second, rest, secondErr := decodeDeltaBinaryPacked(mixed)
+
+		// Assert
+		assert.NoError(t, firstErr)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, first)
+		assert.Equal(t, []int64{7, 5, 3, 1, 2, 3, 4, 5}, second)
+		assert.Empty(t, rest)
+	})
+}
+
+func TestFormatValues(t *testing.T) {
+	t.Run("renders logical types as text", func(t *testing.T) {
+		// Assert
+		assert.Equal(t, "2026-10-16", formatInt(leaf{date: true}, 20742))
+		assert.Equal(t, "2026-10-16T10:15:00Z", formatInt(leaf{unit: "millis"}, 1792145700000))
+		assert.Equal(t, "-12.05", formatInt(leaf{decimal: true, scale: 2}, -1205))
+		assert.Equal(t, "0.05", formatDecimal(twosComplement([]byte{0x05}), 2))
+		assert.Equal(t, "-1", formatDecimal(twosComplement([]byte{0xff, 0xff}), 0))
+	})
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	t.Run("expands literals and back-references", func(t *testing.T) {
+		// Act
+		data, err := decodeSnappy([]byte{0x09, 0x08, 'a', 'b', 'c', 0x09, 0x03})
+		_, corrupt := decodeSnappy([]byte{0x09, 0x08, 'a', 'b', 'c', 0x09, 0x07})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "abcabcabc", string(data))
+		assert.Error(t, corrupt)
+	})
+}
@@ -0,0 +1,272 @@
+package datalake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// rowGroupSize is how many rows are buffered before they are written out as
+// one row group
+const rowGroupSize = 50000
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// Parquet physical and logical types, encodings and repetitions used here
+const (
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8 = 0
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageTypeData = 0
+	codecNone    = 0
+)
+
+// column is one column of the export schema
+type column struct {
+	name string
+	// int64 or str reads the column's value from a row
+	int64 func(row Row) int64
+	str   func(row Row) string
+	// optional columns store empty strings as null
+	optional bool
+}
+
+// columns is the schema of exported files, in order
+var columns = []column{
+	{name: "run_id", str: func(r Row) string { return r.RunID }},
+	{name: "row", int64: func(r Row) int64 { return int64(r.Index) }},
+	{name: "name", str: func(r Row) string { return r.Name }},
+	{name: "email", str: func(r Row) string { return r.Email }},
+	{name: "company", str: func(r Row) string { return r.Company }},
+	{name: "source", str: func(r Row) string { return r.Source }},
+	{name: "status", str: func(r Row) string { return r.Status }, optional: true},
+	{name: "action", str: func(r Row) string { return r.Action }},
+	{name: "lead_id", str: func(r Row) string { return r.LeadID }, optional: true},
+	{name: "error_code", str: func(r Row) string { return r.Code }, optional: true},
+	{name: "error", str: func(r Row) string { return r.Error }, optional: true},
+	{name: "labels", str: func(r Row) string { return r.Labels }, optional: true},
+}
+
+// ParquetWriter writes rows as an uncompressed, PLAIN-encoded Parquet file,
+// which Athena, Spark and DuckDB read without extra setup
+type ParquetWriter struct {
+	w         io.Writer
+	offset    int64
+	rows      []Row
+	numRows   int64
+	rowGroups []rowGroup
+}
+
+// rowGroup is the footer metadata of a written row group
+type rowGroup struct {
+	numRows   int64
+	totalSize int64
+	chunks    []columnChunk
+}
+
+// columnChunk is the footer metadata of one column of a row group
+type columnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// NewParquetWriter creates a writer of a Parquet file to w
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{w: w}
+}
+
+// Write buffers a row, writing a row group once enough are buffered
+func (p *ParquetWriter) Write(row Row) error {
+	if p.offset == 0 {
+		if err := p.write([]byte(parquetMagic)); err != nil {
+			return err
+		}
+	}
+	p.rows = append(p.rows, row)
+	if len(p.rows) >= rowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer
+func (p *ParquetWriter) Close() error {
+	if p.offset == 0 {
+		if err := p.write([]byte(parquetMagic)); err != nil {
+			return err
+		}
+	}
+	if err := p.flush(); err != nil {
+		return err
+	}
+
+	footer := p.footer()
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(footer)))
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	if err := p.write(size); err != nil {
+		return err
+	}
+	return p.write([]byte(parquetMagic))
+}
+
+func (p *ParquetWriter) write(data []byte) error {
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write parquet file: %w", err)
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group of one data page per column
+func (p *ParquetWriter) flush() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+
+	group := rowGroup{numRows: int64(len(p.rows))}
+	for _, col := range columns {
+		page := encodePage(col, p.rows)
+		chunk := columnChunk{offset: p.offset, size: int64(len(page)), values: int64(len(p.rows))}
+		if err := p.write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.totalSize += chunk.size
+	}
+
+	p.rowGroups = append(p.rowGroups, group)
+	p.numRows += group.numRows
+	p.rows = p.rows[:0]
+	return nil
+}
+
+// encodePage encodes a column of rows as a data page with its header
+func encodePage(col column, rows []Row) []byte {
+	var body bytes.Buffer
+	if col.optional {
+		// Definition levels: 1 for a value, 0 for null, bit-packed with a
+		// 4-byte length prefix
+		levels := make([]byte, (len(rows)+7)/8)
+		for i, row := range rows {
+			if col.str(row) != "" {
+				levels[i/8] |= 1 << (i % 8)
+			}
+		}
+		run := binary.AppendUvarint(nil, uint64(len(levels))<<1|1)
+		run = append(run, levels...)
+		body.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(run))))
+		body.Write(run)
+	}
+
+	for _, row := range rows {
+		if col.int64 != nil {
+			body.Write(binary.LittleEndian.AppendUint64(nil, uint64(col.int64(row))))
+			continue
+		}
+		value := col.str(row)
+		if col.optional && value == "" {
+			continue
+		}
+		body.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(value))))
+		body.WriteString(value)
+	}
+
+	var header compactWriter
+	header.begin()
+	header.i32(1, pageTypeData)
+	header.i32(2, int32(body.Len()))
+	header.i32(3, int32(body.Len()))
+	header.structField(5)
+	header.i32(1, int32(len(rows)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.end()
+	header.end()
+
+	return append(header.buf.Bytes(), body.Bytes()...)
+}
+
+// footer encodes the FileMetaData: schema, row groups and column chunks
+func (p *ParquetWriter) footer() []byte {
+	var c compactWriter
+	c.begin()
+	c.i32(1, 1)
+
+	c.list(2, ctStruct, len(columns)+1)
+	c.begin()
+	c.str(4, "schema")
+	c.i32(5, int32(len(columns)))
+	c.end()
+	for _, col := range columns {
+		c.begin()
+		if col.int64 != nil {
+			c.i32(1, typeInt64)
+		} else {
+			c.i32(1, typeByteArray)
+		}
+		if col.optional {
+			c.i32(3, repetitionOptional)
+		} else {
+			c.i32(3, repetitionRequired)
+		}
+		c.str(4, col.name)
+		if col.str != nil {
+			c.i32(6, convertedUTF8)
+		}
+		c.end()
+	}
+
+	c.i64(3, p.numRows)
+
+	c.list(4, ctStruct, len(p.rowGroups))
+	for _, group := range p.rowGroups {
+		c.begin()
+		c.list(1, ctStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			col := columns[i]
+			c.begin()
+			c.i64(2, chunk.offset)
+			c.structField(3)
+			if col.int64 != nil {
+				c.i32(1, typeInt64)
+			} else {
+				c.i32(1, typeByteArray)
+			}
+			c.list(2, ctI32, 2)
+			c.varint(zigzag(encodingPlain))
+			c.varint(zigzag(encodingRLE))
+			c.list(3, ctBinary, 1)
+			c.bytes(col.name)
+			c.i32(4, codecNone)
+			c.i64(5, chunk.values)
+			c.i64(6, chunk.size)
+			c.i64(7, chunk.size)
+			c.i64(9, chunk.offset)
+			c.end()
+			c.end()
+		}
+		c.i64(2, group.totalSize)
+		c.i64(3, group.numRows)
+		c.end()
+	}
+
+	c.str(6, "lead-processor")
+	c.end()
+	return c.buf.Bytes()
+}
@@ -0,0 +1,81 @@
+package datalake
+
+import (
+	"bytes"
+	"code/internal/report"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParquetWriter(t *testing.T) {
+	t.Run("writes a framed file with the schema in its footer", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+		w := NewParquetWriter(&buf)
+
+		// Act
+		w.Write(Row{RunID: "20261016T101500-abc", Record: report.Record{Index: 1, Name: "Jane Smith", Email: "jane@techfirm.com", Action: "CREATE", LeadID: "lead_1"}})
+		w.Write(Row{RunID: "20261016T101500-abc", Record: report.Record{Index: 2, Name: "John Doe", Email: "john", Action: "VALIDATION_ERROR", Error: "valid email is required", Code: "VALIDATION"}})
+		err := w.Close()
+
+		// Assert
+		assert.NoError(t, err)
+		data := buf.Bytes()
+		assert.Equal(t, "PAR1", string(data[:4]))
+		assert.Equal(t, "PAR1", string(data[len(data)-4:]))
+		footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+		footer := data[len(data)-8-footerSize : len(data)-8]
+		for _, col := range columns {
+			assert.Contains(t, string(footer), col.name)
+		}
+		assert.Contains(t, string(data[:len(data)-8-footerSize]), "jane@techfirm.com")
+		assert.Contains(t, string(data[:len(data)-8-footerSize]), "valid email is required")
+	})
+
+	t.Run("writes an empty run as a file without row groups", func(t *testing.T) {
+		// Arrange
+		var buf bytes.Buffer
+
+		// Act
+		err := NewParquetWriter(&buf).Close()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "PAR1", string(buf.Bytes()[:4]))
+	})
+}
+
+func TestEncodePage(t *testing.T) {
+	t.Run("stores empty optional values as nulls", func(t *testing.T) {
+		// Arrange
+		leadID := columns[8]
+		rows := []Row{{Record: report.Record{LeadID: "a"}}, {}, {Record: report.Record{LeadID: "b"}}}
+
+		// Act
+		page := encodePage(leadID, rows)
+
+		// Assert
+		// Definition levels 1, 0, 1 as one bit-packed group, then two values
+		body := []byte{2, 0, 0, 0, 0x03, 0x05, 1, 0, 0, 0, 'a', 1, 0, 0, 0, 'b'}
+		assert.Equal(t, body, page[len(page)-len(body):])
+	})
+}
+
+func TestCompactWriter(t *testing.T) {
+	t.Run("writes field IDs as deltas unless they go backwards", func(t *testing.T) {
+		// Arrange
+		var c compactWriter
+
+		// Act
+		c.begin()
+		c.i32(1, 1)
+		c.i64(9, -1)
+		c.i32(3, 2)
+		c.end()
+
+		// Assert
+		assert.Equal(t, []byte{0x15, 0x02, 0x86, 0x01, 0x05, 0x06, 0x04, 0x00}, c.buf.Bytes())
+	})
+}
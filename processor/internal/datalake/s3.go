@@ -0,0 +1,189 @@
+package datalake
+
+import (
+	"code/internal/awsauth"
+	"code/internal/errcode"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 uploads and reads objects in Amazon S3, or an S3-compatible store,
+// signing requests with AWS Signature Version 4
+type S3 struct {
+	region       string
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	httpClient   *http.Client
+	now          func() time.Time
+}
+
+// NewS3FromEnv reads credentials and the region from the standard AWS
+// environment variables. AWS_ENDPOINT_URL_S3 selects an S3-compatible store,
+// addressed path-style.
+func NewS3FromEnv(httpClient *http.Client) (*S3, error) {
+	s3 := &S3{
+		region:       awsauth.FirstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		endpoint:     strings.TrimSuffix(awsauth.FirstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"), "/"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		httpClient:   httpClient,
+		now:          time.Now,
+	}
+	if s3.accessKey == "" || s3.secretKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+	if s3.region == "" {
+		return nil, fmt.Errorf("an S3 region is required (AWS_REGION)")
+	}
+	if s3.httpClient == nil {
+		s3.httpClient = &http.Client{Timeout: 5 * time.Minute}
+	}
+	return s3, nil
+}
+
+// objectURL addresses the object virtual-hosted style on AWS, or path-style
+// on a custom endpoint
+func (s *S3) objectURL(bucket, key string) string {
+	if s.endpoint != "" {
+		return s.endpoint + "/" + bucket + "/" + awsauth.EscapePath(key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, s.region, awsauth.EscapePath(key))
+}
+
+// PutObject uploads the file to bucket under key
+func (s *S3) PutObject(bucket, key, contentType string, file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Name(), err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(bucket, key), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, hex.EncodeToString(hash.Sum(nil)))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, "upload")
+}
+
+// ListObjects returns the keys of the objects under prefix, in key order
+func (s *S3) ListObjects(bucket, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequest(http.MethodGet, s.objectURL(bucket, "")+"?"+awsauth.CanonicalQuery(query), nil)
+		if err != nil {
+			return nil, err
+		}
+		s.sign(req, awsauth.EmptyPayloadHash)
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list S3 objects: %w", errcode.Network(err))
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = checkResponse(resp, "listing")
+		if err == nil {
+			err = xml.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			sort.Strings(keys)
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// GetObject downloads the object at key; the caller closes it
+func (s *S3) GetObject(bucket, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, awsauth.EmptyPayloadHash)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", errcode.Network(err))
+	}
+	if err := checkResponse(resp, "download"); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// checkResponse turns an S3 error response into an error classed by its status
+func checkResponse(resp *http.Response, action string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(resp.Body).Decode(&s3Err)
+	err := &errcode.StatusError{Status: resp.StatusCode}
+	if s3Err.Code != "" {
+		return fmt.Errorf("S3 refused the %s: %s: %s: %w", action, s3Err.Code, s3Err.Message, err)
+	}
+	return fmt.Errorf("S3 refused the %s: %w", action, err)
+}
+
+// sign adds the SigV4 headers to req, signing the host and every header
+// already set on it
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	signer := awsauth.Signer{
+		Region:       s.region,
+		Service:      "s3",
+		AccessKey:    s.accessKey,
+		SecretKey:    s.secretKey,
+		SessionToken: s.sessionToken,
+		Now:          s.now,
+	}
+	signer.Sign(req, payloadHash)
+}
@@ -0,0 +1,84 @@
+package datalake
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errCorruptSnappy reports snappy data that doesn't decode
+var errCorruptSnappy = errors.New("corrupt snappy data")
+
+// decodeSnappy decompresses a raw snappy block, the codec Spark and pyarrow
+// write Parquet with by default and Avro's snappy codec uses
+func decodeSnappy(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 || size > 1<<31 {
+		return nil, errCorruptSnappy
+	}
+	src = src[n:]
+	dst := make([]byte, 0, size)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+		switch tag & 0x03 {
+		case 0:
+			// Literal; lengths over 60 follow the tag in 1 to 4 bytes
+			length = int(tag>>2) + 1
+			src = src[1:]
+			if length > 60 {
+				extra := length - 60
+				if len(src) < extra {
+					return nil, errCorruptSnappy
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				length++
+				src = src[extra:]
+			}
+			if length > len(src) {
+				return nil, errCorruptSnappy
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, errCorruptSnappy
+			}
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			if len(src) < 3 {
+				return nil, errCorruptSnappy
+			}
+			length = int(tag>>2) + 1
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, errCorruptSnappy
+			}
+			length = int(tag>>2) + 1
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+
+		// Copies may overlap their own output, so go byte by byte
+		if offset <= 0 || offset > len(dst) {
+			return nil, errCorruptSnappy
+		}
+		start := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+
+	if uint64(len(dst)) != size {
+		return nil, errCorruptSnappy
+	}
+	return dst, nil
+}
@@ -0,0 +1,97 @@
+package datalake
+
+import (
+	"code/internal/csv"
+	"code/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rowReader reads the rows of a Parquet or Avro file as text
+type rowReader interface {
+	Header() []string
+	Next() ([]string, error)
+	Close() error
+}
+
+// IsFile reports whether path names a Parquet or Avro file, by extension
+func IsFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet", ".avro":
+		return true
+	}
+	return false
+}
+
+// openRows opens a Parquet or Avro file, telling them apart by their magic
+// bytes so the extension doesn't matter
+func openRows(path string) (rowReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(file, magic)
+	file.Close()
+	if err == nil && string(magic) == avroMagic {
+		return OpenAvro(path)
+	}
+	return OpenParquet(path)
+}
+
+// FileSource is a Parquet or Avro file read as a lead source. Its columns
+// are mapped to lead fields like a CSV file's header.
+type FileSource struct {
+	path   string
+	reader *csv.CSVReader
+}
+
+// NewFileSource returns the file at path as a lead source, mapping columns
+// with reader
+func NewFileSource(path string, reader *csv.CSVReader) *FileSource {
+	return &FileSource{path: path, reader: reader}
+}
+
+// Name returns the file's path
+func (s *FileSource) Name() string {
+	return s.path
+}
+
+// Hash returns the SHA-256 of the file's content
+func (s *FileSource) Hash() (string, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadHeader returns the column names
+func (s *FileSource) ReadHeader() ([]string, error) {
+	rows, err := openRows(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Header(), nil
+}
+
+// StreamLeads streams the file's leads, decoding a row group or block at a time
+func (s *FileSource) StreamLeads(emit func(*models.Lead) error) error {
+	rows, err := openRows(s.path)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return s.reader.StreamRows(rows.Header(), rows.Next, emit)
+}
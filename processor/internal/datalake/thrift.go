@@ -0,0 +1,278 @@
+package datalake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Thrift compact protocol types
+const (
+	ctTrue   = 1
+	ctFalse  = 2
+	ctByte   = 3
+	ctI16    = 4
+	ctI32    = 5
+	ctI64    = 6
+	ctDouble = 7
+	ctBinary = 8
+	ctList   = 9
+	ctSet    = 10
+	ctMap    = 11
+	ctStruct = 12
+)
+
+// compactWriter encodes the Thrift compact protocol Parquet metadata uses.
+// Only the types the footer and page headers need are supported.
+type compactWriter struct {
+	buf bytes.Buffer
+	// last holds the previous field ID of each open struct, as field IDs
+	// are written as deltas
+	last []int16
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (c *compactWriter) varint(v uint64) {
+	c.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (c *compactWriter) field(id int16, typ byte) {
+	last := c.last[len(c.last)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(zigzag(int64(id)))
+	}
+	c.last[len(c.last)-1] = id
+}
+
+// begin opens a struct: the top-level one, a list element or, after
+// structField, a nested field
+func (c *compactWriter) begin() {
+	c.last = append(c.last, 0)
+}
+
+// end closes the innermost struct
+func (c *compactWriter) end() {
+	c.buf.WriteByte(0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compactWriter) structField(id int16) {
+	c.field(id, ctStruct)
+	c.begin()
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, ctI32)
+	c.varint(zigzag(int64(v)))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, ctI64)
+	c.varint(zigzag(v))
+}
+
+func (c *compactWriter) str(id int16, v string) {
+	c.field(id, ctBinary)
+	c.bytes(v)
+}
+
+// bytes writes a binary value without a field header, e.g. a list element
+func (c *compactWriter) bytes(v string) {
+	c.varint(uint64(len(v)))
+	c.buf.WriteString(v)
+}
+
+// list writes the header of a list of n elements, which follow it
+func (c *compactWriter) list(id int16, elem byte, n int) {
+	c.field(id, ctList)
+	if n < 15 {
+		c.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	c.buf.WriteByte(0xf0 | elem)
+	c.varint(uint64(n))
+}
+
+// tstruct is a decoded Thrift struct: field ID to value. Integers decode to
+// int64, binaries to []byte, lists and sets to []interface{}.
+type tstruct map[int16]interface{}
+
+func (t tstruct) int(id int16) int64 {
+	v, _ := t[id].(int64)
+	return v
+}
+
+func (t tstruct) has(id int16) bool {
+	_, ok := t[id]
+	return ok
+}
+
+func (t tstruct) str(id int16) string {
+	v, _ := t[id].([]byte)
+	return string(v)
+}
+
+func (t tstruct) strct(id int16) tstruct {
+	v, _ := t[id].(tstruct)
+	return v
+}
+
+func (t tstruct) list(id int16) []interface{} {
+	v, _ := t[id].([]interface{})
+	return v
+}
+
+// errTruncated reports metadata that ends mid-value
+var errTruncated = errors.New("truncated thrift data")
+
+// compactReader decodes Thrift compact protocol structs without a schema,
+// the way a reader must skip fields it doesn't know
+type compactReader struct {
+	data []byte
+	pos  int
+}
+
+func (c *compactReader) byte() (byte, error) {
+	if c.pos >= len(c.data) {
+		return 0, errTruncated
+	}
+	b := c.data[c.pos]
+	c.pos++
+	return b, nil
+}
+
+func (c *compactReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(c.data[c.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	c.pos += n
+	return v, nil
+}
+
+func (c *compactReader) zigzag() (int64, error) {
+	v, err := c.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+// readStruct decodes a struct up to its stop field
+func (c *compactReader) readStruct() (tstruct, error) {
+	t := tstruct{}
+	var last int16
+	for {
+		header, err := c.byte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return t, nil
+		}
+
+		typ := header & 0x0f
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := c.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		last = id
+
+		switch typ {
+		case ctTrue:
+			t[id] = true
+		case ctFalse:
+			t[id] = false
+		default:
+			if t[id], err = c.value(typ); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (c *compactReader) value(typ byte) (interface{}, error) {
+	switch typ {
+	case ctTrue, ctFalse:
+		// Booleans inside lists take a byte of their own
+		b, err := c.byte()
+		return b == ctTrue, err
+	case ctByte:
+		b, err := c.byte()
+		return int64(int8(b)), err
+	case ctI16, ctI32, ctI64:
+		return c.zigzag()
+	case ctDouble:
+		if c.pos+8 > len(c.data) {
+			return nil, errTruncated
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(c.data[c.pos:]))
+		c.pos += 8
+		return v, nil
+	case ctBinary:
+		n, err := c.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(c.data)-c.pos) < n {
+			return nil, errTruncated
+		}
+		v := c.data[c.pos : c.pos+int(n)]
+		c.pos += int(n)
+		return v, nil
+	case ctList, ctSet:
+		header, err := c.byte()
+		if err != nil {
+			return nil, err
+		}
+		n := uint64(header >> 4)
+		if n == 15 {
+			if n, err = c.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if n > uint64(len(c.data)-c.pos) {
+			return nil, errTruncated
+		}
+		list := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := c.value(header & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case ctMap:
+		n, err := c.uvarint()
+		if err != nil || n == 0 {
+			return nil, err
+		}
+		types, err := c.byte()
+		if err != nil {
+			return nil, err
+		}
+		// Parquet metadata has no maps; they are only skipped
+		for i := uint64(0); i < n; i++ {
+			if _, err := c.value(types >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := c.value(types & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case ctStruct:
+		return c.readStruct()
+	}
+	return nil, fmt.Errorf("unknown thrift type %d", typ)
+}
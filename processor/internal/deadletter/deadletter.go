@@ -0,0 +1,197 @@
+package deadletter
+
+import (
+	"code/internal/datalake"
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a lead that failed for good, with what's needed to inspect and
+// replay it
+type Entry struct {
+	At    time.Time `json:"at"`
+	RunID string    `json:"runId,omitempty"`
+	// Input is where the lead came from: a file, sheet or serve endpoint
+	Input  string       `json:"input"`
+	Index  int          `json:"index,omitempty"`
+	Action string       `json:"action"`
+	Code   string       `json:"code"`
+	Error  string       `json:"error"`
+	Lead   *models.Lead `json:"lead"`
+	// Row is the source row, keyed by header column
+	Row map[string]string `json:"row,omitempty"`
+	// Schema is the models.LeadSchema the lead was stored at
+	Schema int `json:"schema,omitempty"`
+}
+
+// Terminal reports whether an outcome is a terminal failure: the lead
+// couldn't be processed, or the API refused it or was still failing once its
+// retries ran out. Validation errors and conflicts need the source fixed, so
+// they aren't dead-lettered.
+func Terminal(action string, err error) bool {
+	if err == nil {
+		return false
+	}
+	switch action {
+	case "ERROR", "API_ERROR", "CREATE_ERROR", "UPDATE_ERROR", "ROLLBACK_FAILED":
+		return true
+	}
+	return false
+}
+
+// NewEntry describes a lead that failed with err
+func NewEntry(input string, index int, lead *models.Lead, action string, err error) Entry {
+	return Entry{
+		Input:  input,
+		Index:  index,
+		Action: action,
+		Code:   string(errcode.Of(err)),
+		Error:  err.Error(),
+		Lead:   lead,
+		Row:    lead.Raw,
+		Schema: models.LeadSchema,
+	}
+}
+
+// Writer writes dead-lettered leads as NDJSON to a file, or to an S3 prefix
+// where each flush becomes an object under run_date=YYYY-MM-DD/. It is safe
+// for concurrent use.
+type Writer struct {
+	runID string
+
+	bucket string
+	prefix string
+	s3     *datalake.S3
+
+	mu      sync.Mutex
+	file    *os.File
+	pending int
+	parts   int
+	count   int
+}
+
+// Open opens the dead-letter destination dest, an NDJSON file appended to or
+// s3://bucket/prefix. S3 credentials come from the AWS environment variables.
+func Open(dest, runID string, httpClient *http.Client) (*Writer, error) {
+	w := &Writer{runID: runID}
+	if !strings.HasPrefix(dest, "s3://") {
+		file, err := os.OpenFile(dest, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+		}
+		w.file = file
+		return w, nil
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(dest, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid dead-letter destination %s: no bucket", dest)
+	}
+	s3, err := datalake.NewS3FromEnv(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	w.bucket = bucket
+	w.prefix = strings.Trim(prefix, "/")
+	w.s3 = s3
+	return w, nil
+}
+
+// Write appends an entry, stamping it with the run ID and time
+func (w *Writer) Write(entry Entry) error {
+	if entry.At.IsZero() {
+		entry.At = time.Now().UTC()
+	}
+	if entry.RunID == "" {
+		entry.RunID = w.runID
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		// Entries for S3 are buffered in a temporary file until the flush
+		if w.file, err = os.CreateTemp("", "dead-letter-*.ndjson"); err != nil {
+			return fmt.Errorf("failed to buffer dead-letter entry: %w", err)
+		}
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead-letter entry: %w", err)
+	}
+	w.pending++
+	w.count++
+	return nil
+}
+
+// Count returns how many entries were written
+func (w *Writer) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Flush makes written entries durable: it syncs the file, or uploads the
+// entries buffered since the last flush to S3
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil || w.pending == 0 {
+		return nil
+	}
+	if w.s3 == nil {
+		w.pending = 0
+		return w.file.Sync()
+	}
+
+	if err := w.s3.PutObject(w.bucket, w.key(), "application/x-ndjson", w.file); err != nil {
+		return fmt.Errorf("failed to upload dead-letter entries: %w", err)
+	}
+	w.parts++
+	w.pending = 0
+	w.file.Close()
+	os.Remove(w.file.Name())
+	w.file = nil
+	return nil
+}
+
+// key is the object the next flush is uploaded to: <run-id>.ndjson, then
+// <run-id>-2.ndjson and so on
+func (w *Writer) key() string {
+	name := w.runID
+	if w.parts > 0 {
+		name += "-" + strconv.Itoa(w.parts+1)
+	}
+	key := path.Join("run_date="+time.Now().UTC().Format("2006-01-02"), name+".ndjson")
+	if w.prefix != "" {
+		key = w.prefix + "/" + key
+	}
+	return key
+}
+
+// Close flushes the entries and closes the writer
+func (w *Writer) Close() error {
+	err := w.Flush()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		w.file.Close()
+		if w.s3 != nil {
+			os.Remove(w.file.Name())
+		}
+		w.file = nil
+	}
+	return err
+}
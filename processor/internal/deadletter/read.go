@@ -0,0 +1,158 @@
+package deadletter
+
+import (
+	"bufio"
+	"code/internal/datalake"
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// replayableCodes are the error classes a dead-lettered lead can have
+var replayableCodes = []errcode.Code{
+	errcode.CodeAuth, errcode.CodeNetwork, errcode.CodeRateLimited, errcode.CodeAPI,
+	errcode.CodeValidation, errcode.CodeConflict, errcode.CodeUnknown,
+}
+
+// Filter selects the entries to replay. Zero values match everything.
+type Filter struct {
+	// Codes are error classes, e.g. RATE_LIMITED
+	Codes []string
+	// Since and Until bound when the lead failed; Until is exclusive
+	Since time.Time
+	Until time.Time
+}
+
+// ParseCodes normalises error class codes, rejecting unknown ones
+func ParseCodes(codes []string) ([]string, error) {
+	parsed := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		known := false
+		for _, valid := range replayableCodes {
+			known = known || code == string(valid)
+		}
+		if !known {
+			names := make([]string, len(replayableCodes))
+			for i, valid := range replayableCodes {
+				names[i] = string(valid)
+			}
+			return nil, fmt.Errorf("unknown error code %q (use %s)", code, strings.Join(names, ", "))
+		}
+		parsed = append(parsed, code)
+	}
+	return parsed, nil
+}
+
+// Match reports whether the filter selects entry
+func (f Filter) Match(entry Entry) bool {
+	if !f.Since.IsZero() && entry.At.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.At.Before(f.Until) {
+		return false
+	}
+	if len(f.Codes) == 0 {
+		return true
+	}
+	for _, code := range f.Codes {
+		if strings.EqualFold(code, entry.Code) {
+			return true
+		}
+	}
+	return false
+}
+
+// storedEntry is an entry whose lead is decoded once its schema is known
+type storedEntry struct {
+	Entry
+	Lead json.RawMessage `json:"lead"`
+}
+
+// Read calls fn with each entry of an NDJSON dead-letter stream and returns
+// how many lines couldn't be read as entries, such as a line torn by a crash
+func Read(r io.Reader, fn func(Entry) error) (int, error) {
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var stored storedEntry
+		if err := json.Unmarshal(line, &stored); err != nil {
+			skipped++
+			continue
+		}
+		if err := models.CheckSchema(stored.Schema); err != nil {
+			return skipped, fmt.Errorf("dead letter of %s: %w", stored.At.Format(time.RFC3339), err)
+		}
+		entry := stored.Entry
+		lead, err := models.DecodeLead(stored.Lead, stored.Schema)
+		if err != nil || lead == nil {
+			skipped++
+			continue
+		}
+		entry.Lead = lead
+		entry.Lead.Raw = entry.Row
+		if err := fn(entry); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, scanner.Err()
+}
+
+// Load reads the entries at location: a dead-letter file, or an
+// s3://bucket/prefix whose .ndjson objects are read in key order
+func Load(location string, httpClient *http.Client, fn func(Entry) error) (int, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		file, err := os.Open(location)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		return Read(file, fn)
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if bucket == "" {
+		return 0, fmt.Errorf("invalid dead-letter location %s: no bucket", location)
+	}
+	s3, err := datalake.NewS3FromEnv(httpClient)
+	if err != nil {
+		return 0, err
+	}
+	prefix = strings.TrimLeft(prefix, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") && !strings.HasSuffix(prefix, ".ndjson") {
+		prefix += "/"
+	}
+	keys, err := s3.ListObjects(bucket, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".ndjson") {
+			continue
+		}
+		object, err := s3.GetObject(bucket, key)
+		if err != nil {
+			return skipped, err
+		}
+		n, err := Read(object, fn)
+		object.Close()
+		skipped += n
+		if err != nil {
+			return skipped, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+		}
+	}
+	return skipped, nil
+}
@@ -0,0 +1,206 @@
+package dedupe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the index's file in the run-history directory
+const FileName = "dedupe-index.json"
+
+// Entry is what the index knows of one lead, by its normalized email
+type Entry struct {
+	Email string `json:"email"`
+	// LeadID is the CRM's ID of the lead, empty until a write returned it
+	LeadID      string `json:"leadId,omitempty"`
+	ContentHash string `json:"contentHash"`
+	// LastAction is CREATE, UPDATE or SKIP
+	LastAction string    `json:"lastAction"`
+	LastSeen   time.Time `json:"lastSeen"`
+	RunID      string    `json:"runId,omitempty"`
+}
+
+// file is the index's layout on disk
+type file struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// Index maps the normalized email of every lead processed successfully to
+// its latest entry. It is kept in a JSON file and safe for concurrent use.
+type Index struct {
+	path string
+
+	mu        sync.RWMutex
+	entries   map[string]Entry
+	updatedAt time.Time
+	exists    bool
+}
+
+// Open loads the index at path, starting empty if the file does not exist
+func Open(path string) (*Index, error) {
+	x := &Index{path: path, entries: make(map[string]Entry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return x, nil
+		}
+		return nil, fmt.Errorf("failed to read dedupe index: %w", err)
+	}
+	x.exists = true
+	if len(data) == 0 {
+		return x, nil
+	}
+
+	var stored file
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode dedupe index %s: %w", path, err)
+	}
+	x.updatedAt = stored.UpdatedAt
+	for _, entry := range stored.Entries {
+		if k := Key(entry.Email); k != "" {
+			x.entries[k] = entry
+		}
+	}
+	return x, nil
+}
+
+// Exists reports whether the index was read from a file rather than started
+// empty
+func (x *Index) Exists() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.exists
+}
+
+// UpdatedAt returns when the index was last saved, or the zero time
+func (x *Index) UpdatedAt() time.Time {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.updatedAt
+}
+
+// Len returns how many leads the index holds
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.entries)
+}
+
+// Get returns the entry of the lead with the email, if the index has it
+func (x *Index) Get(email string) (Entry, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	entry, ok := x.entries[Key(email)]
+	return entry, ok
+}
+
+// ContentHash returns the content hash the lead with the email was last
+// processed with
+func (x *Index) ContentHash(email string) (string, bool) {
+	entry, ok := x.Get(email)
+	return entry.ContentHash, ok
+}
+
+// Record stores entry as the latest of its lead, keeping the lead ID known
+// from earlier entries when it has none. Entries without an email are
+// ignored.
+func (x *Index) Record(entry Entry) {
+	k := Key(entry.Email)
+	if k == "" {
+		return
+	}
+	if entry.LastSeen.IsZero() {
+		entry.LastSeen = time.Now().UTC()
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if previous, ok := x.entries[k]; ok {
+		if previous.LastSeen.After(entry.LastSeen) {
+			return
+		}
+		if entry.LeadID == "" {
+			entry.LeadID = previous.LeadID
+		}
+	}
+	x.entries[k] = entry
+}
+
+// SetLeadID records the CRM's ID of a lead the index has
+func (x *Index) SetLeadID(email, id string) bool {
+	k := Key(email)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	entry, ok := x.entries[k]
+	if !ok || id == "" {
+		return false
+	}
+	entry.LeadID = id
+	x.entries[k] = entry
+	return true
+}
+
+// SeenWithin reports whether email was last processed with the same content
+// hash within the given window
+func (x *Index) SeenWithin(email, contentHash string, window time.Duration) bool {
+	entry, ok := x.Get(email)
+	if !ok || entry.ContentHash != contentHash {
+		return false
+	}
+	return time.Since(entry.LastSeen) <= window
+}
+
+// Entries returns every entry, ordered by email
+func (x *Index) Entries() []Entry {
+	x.mu.RLock()
+	entries := make([]Entry, 0, len(x.entries))
+	for _, entry := range x.entries {
+		entries = append(entries, entry)
+	}
+	x.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return Key(entries[i].Email) < Key(entries[j].Email) })
+	return entries
+}
+
+// Reset empties the index, e.g. before a rebuild
+func (x *Index) Reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.entries = make(map[string]Entry)
+}
+
+// Save writes the index back to disk, entries ordered by email
+func (x *Index) Save() error {
+	updatedAt := time.Now().UTC()
+	data, err := json.Marshal(file{UpdatedAt: updatedAt, Entries: x.Entries()})
+	if err != nil {
+		return fmt.Errorf("failed to encode dedupe index: %w", err)
+	}
+	tmpPath := x.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write dedupe index: %w", err)
+	}
+	if err := os.Rename(tmpPath, x.path); err != nil {
+		return fmt.Errorf("failed to write dedupe index: %w", err)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.updatedAt = updatedAt
+	x.exists = true
+	return nil
+}
+
+// Key normalizes an email into the identity leads are indexed by: trimmed
+// and lowercased, as the API matches emails
+func Key(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,67 @@
+package dedupe
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	t.Run("persists entries across reopen", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), FileName)
+		index, err := Open(path)
+		assert.NoError(t, err)
+		assert.False(t, index.Exists())
+
+		// Act
+		index.Record(Entry{Email: " Jane@Example.com", LeadID: "lead-1", ContentHash: "hash-1", LastAction: "CREATE", RunID: "run-1"})
+		assert.NoError(t, index.Save())
+		reopened, err := Open(path)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, reopened.Exists())
+		assert.Equal(t, 1, reopened.Len())
+		entry, ok := reopened.Get("jane@example.com")
+		assert.True(t, ok)
+		assert.Equal(t, "lead-1", entry.LeadID)
+		assert.Equal(t, "CREATE", entry.LastAction)
+		assert.True(t, reopened.SeenWithin("JANE@example.com", "hash-1", time.Hour))
+	})
+
+	t.Run("keeps the lead ID and the latest entry", func(t *testing.T) {
+		// Arrange
+		index, err := Open(filepath.Join(t.TempDir(), FileName))
+		assert.NoError(t, err)
+		now := time.Now().UTC()
+
+		// Act
+		index.Record(Entry{Email: "jane@example.com", LeadID: "lead-1", ContentHash: "hash-1", LastAction: "CREATE", LastSeen: now.Add(-time.Hour)})
+		index.Record(Entry{Email: "jane@example.com", ContentHash: "hash-2", LastAction: "SKIP", LastSeen: now})
+		index.Record(Entry{Email: "jane@example.com", ContentHash: "hash-0", LastAction: "CREATE", LastSeen: now.Add(-2 * time.Hour)})
+		index.Record(Entry{ContentHash: "hash-3", LastAction: "CREATE"})
+
+		// Assert
+		entry, _ := index.Get("jane@example.com")
+		assert.Equal(t, Entry{Email: "jane@example.com", LeadID: "lead-1", ContentHash: "hash-2", LastAction: "SKIP", LastSeen: now}, entry)
+		assert.Equal(t, 1, index.Len())
+	})
+
+	t.Run("only matches identical content within the window", func(t *testing.T) {
+		// Arrange
+		index, err := Open(filepath.Join(t.TempDir(), FileName))
+		assert.NoError(t, err)
+
+		// Act
+		index.Record(Entry{Email: "bob@startup.com", ContentHash: "hash-1", LastAction: "UPDATE"})
+
+		// Assert
+		assert.True(t, index.SeenWithin("bob@startup.com", "hash-1", time.Hour))
+		assert.False(t, index.SeenWithin("bob@startup.com", "hash-2", time.Hour))
+		assert.False(t, index.SeenWithin("bob@startup.com", "hash-1", 0))
+		assert.False(t, index.SeenWithin("carol@example.com", "hash-1", time.Hour))
+	})
+}
@@ -0,0 +1,163 @@
+package delta
+
+import (
+	"code/internal/csv"
+	"code/internal/models"
+	"sort"
+	"strings"
+)
+
+// Kind is how a lead differs between two snapshots
+type Kind string
+
+const (
+	// KindAdded is a lead only in the new snapshot
+	KindAdded Kind = "added"
+	// KindChanged is a lead in both whose fields differ
+	KindChanged Kind = "changed"
+	// KindRemoved is a lead only in the old snapshot
+	KindRemoved Kind = "removed"
+)
+
+// Change is one lead of the delta
+type Change struct {
+	Kind Kind
+	// Lead is the lead as the new snapshot has it, or as the old one had it
+	// when removed
+	Lead *models.Lead
+	// Previous is the lead as the old snapshot had it, for changed leads
+	Previous *models.Lead
+	// Fields are the changed fields, by JSON name
+	Fields []string
+}
+
+// Stats counts the leads of each kind
+type Stats struct {
+	Added     int
+	Changed   int
+	Removed   int
+	Unchanged int
+}
+
+// Total returns how many leads differ
+func (s Stats) Total() int {
+	return s.Added + s.Changed + s.Removed
+}
+
+// Compare streams the new snapshot, after, against the old one, before,
+// calling fn with each added or changed lead in after's order, then with each
+// removed lead in before's order. Leads are matched by email, case-insensitively;
+// a lead without an email can't be matched and so always counts as added.
+// Only the old snapshot is held in memory.
+func Compare(before, after csv.LeadSource, fn func(Change) error) (Stats, error) {
+	var stats Stats
+	type previous struct {
+		lead  *models.Lead
+		order int
+		seen  bool
+	}
+	previousLeads := make(map[string]*previous)
+	err := before.StreamLeads(func(lead *models.Lead) error {
+		key := Key(lead)
+		if key == "" {
+			return nil
+		}
+		// A later row for the same email is the one the API ends up with
+		previousLeads[key] = &previous{lead: models.Sanitize(lead), order: len(previousLeads)}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = after.StreamLeads(func(lead *models.Lead) error {
+		lead = models.Sanitize(lead)
+		prev := previousLeads[Key(lead)]
+		if prev == nil {
+			stats.Added++
+			return fn(Change{Kind: KindAdded, Lead: lead})
+		}
+		prev.seen = true
+
+		fields := changedFields(lead, prev.lead)
+		if len(fields) == 0 {
+			stats.Unchanged++
+			return nil
+		}
+		stats.Changed++
+		return fn(Change{Kind: KindChanged, Lead: lead, Previous: prev.lead, Fields: fields})
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	var removed []*previous
+	for _, prev := range previousLeads {
+		if !prev.seen {
+			removed = append(removed, prev)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].order < removed[j].order })
+	for _, prev := range removed {
+		stats.Removed++
+		if err := fn(Change{Kind: KindRemoved, Lead: prev.lead}); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Hashes looks up the content hash a lead was last processed with, by email
+type Hashes interface {
+	ContentHash(email string) (string, bool)
+}
+
+// CompareIndex streams after against the content hashes its leads were last
+// processed with, calling fn with each added or changed lead in after's
+// order. Only hashes are kept, so changed leads carry no fields or previous
+// lead, and a repeated row counts once. Nothing is removed: the hashes span
+// every feed, not one snapshot.
+func CompareIndex(hashes Hashes, after csv.LeadSource, fn func(Change) error) (Stats, error) {
+	var stats Stats
+	seen := make(map[string]bool)
+	err := after.StreamLeads(func(lead *models.Lead) error {
+		lead = models.Sanitize(lead)
+		key := Key(lead)
+		if key != "" {
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+		}
+
+		hash, ok := hashes.ContentHash(key)
+		switch {
+		case !ok || key == "":
+			stats.Added++
+			return fn(Change{Kind: KindAdded, Lead: lead})
+		case hash != lead.ContentHash():
+			stats.Changed++
+			return fn(Change{Kind: KindChanged, Lead: lead})
+		default:
+			stats.Unchanged++
+			return nil
+		}
+	})
+	return stats, err
+}
+
+// Key identifies a lead across snapshots: its email, trimmed and lowercased
+func Key(lead *models.Lead) string {
+	return strings.ToLower(strings.TrimSpace(lead.Email))
+}
+
+// changedFields lists the fields of lead that differ from prev. A status or
+// consent the new snapshot leaves blank isn't a change, as for updates.
+func changedFields(lead, prev *models.Lead) []string {
+	fields := make([]string, 0)
+	for field := range lead.Diff(prev) {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,146 @@
+package delta
+
+import (
+	"code/internal/csv"
+	"code/internal/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, content string) csv.LeadSource {
+	path := filepath.Join(t.TempDir(), "leads.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return csv.NewCSVReader().File(path)
+}
+
+func TestCompare(t *testing.T) {
+	old := writeFile(t, `Name,Email,Company,Source,Campaign
+Jane Smith,jane@techfirm.com,TechFirm,LinkedIn,Q3
+John Doe,john@startup.io,Startup,Website,Q3
+Ann Lee,ann@corp.com,Corp,Referral,Q3
+Bob Ray,bob@shop.com,Shop,Website,Q3
+`)
+
+	t.Run("lists added, changed and removed leads", func(t *testing.T) {
+		// Arrange
+		after := writeFile(t, `Name,Email,Company,Source,Campaign
+Jane Smith,JANE@techfirm.com ,TechFirm,LinkedIn,Q4
+John Doe,john@startup.io,Startup Inc,Website,Q3
+Mia Chen,mia@labs.dev,Labs,Website,Q4
+`)
+
+		// Act
+		var changes []Change
+		stats, err := Compare(old, after, func(change Change) error {
+			changes = append(changes, change)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{Added: 1, Changed: 2, Removed: 2, Unchanged: 0}, stats)
+		assert.Equal(t, 5, stats.Total())
+		kinds := make([]Kind, len(changes))
+		emails := make([]string, len(changes))
+		for i, change := range changes {
+			kinds[i] = change.Kind
+			emails[i] = change.Lead.Email
+		}
+		assert.Equal(t, []Kind{KindChanged, KindChanged, KindAdded, KindRemoved, KindRemoved}, kinds)
+		assert.Equal(t, []string{"JANE@techfirm.com", "john@startup.io", "mia@labs.dev", "ann@corp.com", "bob@shop.com"}, emails)
+		assert.Equal(t, []string{"email"}, changes[0].Fields)
+		assert.Equal(t, []string{"company"}, changes[1].Fields)
+		assert.Equal(t, "Startup", changes[1].Previous.Company)
+		assert.Equal(t, "Q3", changes[1].Lead.Raw["Campaign"])
+	})
+
+	t.Run("ignores unmapped columns and surrounding whitespace", func(t *testing.T) {
+		// Arrange
+		after := writeFile(t, `Name,Email,Company,Source,Campaign
+Jane Smith ,jane@techfirm.com,TechFirm,LinkedIn,Q4
+John Doe,john@startup.io,Startup,Website,Q4
+Ann Lee,ann@corp.com,Corp,Referral,Q4
+Bob Ray,bob@shop.com,Shop,Website,Q4
+`)
+
+		// Act
+		calls := 0
+		stats, err := Compare(old, after, func(Change) error {
+			calls++
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{Unchanged: 4}, stats)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("counts leads without an email as added", func(t *testing.T) {
+		// Arrange
+		after := writeFile(t, `Name,Email,Company,Source
+No Email,,Corp,Website
+`)
+
+		// Act
+		stats, err := Compare(writeFile(t, "Name,Email,Company,Source\nNo Email,,Corp,Website\n"), after, func(Change) error { return nil })
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{Added: 1}, stats)
+	})
+
+	t.Run("stops at the callback's error", func(t *testing.T) {
+		// Act
+		_, err := Compare(old, writeFile(t, "Name,Email,Company,Source\nMia Chen,mia@labs.dev,Labs,Website\n"), func(Change) error {
+			return errors.New("disk full")
+		})
+
+		// Assert
+		assert.EqualError(t, err, "disk full")
+	})
+}
+
+// hashes is a map of email to content hash
+type hashes map[string]string
+
+func (h hashes) ContentHash(email string) (string, bool) {
+	hash, ok := h[email]
+	return hash, ok
+}
+
+func TestCompareIndex(t *testing.T) {
+	t.Run("lists leads added or changed since they were last processed", func(t *testing.T) {
+		// Arrange
+		index := hashes{
+			"jane@techfirm.com": models.NewLead("Jane Smith", "jane@techfirm.com", "TechFirm", "LinkedIn").ContentHash(),
+			"john@startup.io":   models.NewLead("John Doe", "john@startup.io", "Startup", "Website").ContentHash(),
+		}
+		after := writeFile(t, `Name,Email,Company,Source
+Jane Smith,JANE@techfirm.com ,TechFirm,LinkedIn
+John Doe,john@startup.io,Startup Inc,Website
+Mia Chen,mia@labs.dev,Labs,Website
+Mia Chen,mia@labs.dev,Labs,Website
+`)
+
+		// Act
+		var changes []Change
+		stats, err := CompareIndex(index, after, func(change Change) error {
+			changes = append(changes, change)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{Added: 1, Changed: 1, Unchanged: 1}, stats)
+		assert.Len(t, changes, 2)
+		assert.Equal(t, KindChanged, changes[0].Kind)
+		assert.Equal(t, "john@startup.io", changes[0].Lead.Email)
+		assert.Equal(t, KindAdded, changes[1].Kind)
+		assert.Equal(t, "mia@labs.dev", changes[1].Lead.Email)
+	})
+}
@@ -0,0 +1,236 @@
+package doctor
+
+import (
+	"code/internal/config"
+	"code/internal/csv"
+	"code/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Check outcomes; only failures make a run unsafe to schedule
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// probeEmail is looked up to check that the API accepts our credentials
+const probeEmail = "doctor-probe@example.com"
+
+// Check is the result of one diagnostic
+type Check struct {
+	Name    string
+	Status  Status
+	Message string
+	// Hint says what to do about a warning or failure
+	Hint string
+}
+
+// Report collects the results of all checks
+type Report struct {
+	Checks []Check
+}
+
+// Add records a check result
+func (r *Report) Add(checks ...Check) {
+	r.Checks = append(r.Checks, checks...)
+}
+
+// Count returns the number of checks with the given status
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckConfig loads the config file at path. A broken file is reported and
+// the defaults are returned so the remaining checks can still run.
+func CheckConfig(path string) (*config.Config, Check) {
+	check := Check{Name: "Config file"}
+
+	if path == "" {
+		check.Status = StatusOK
+		check.Message = "no --config given, using defaults"
+		return config.Default(), check
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = err.Error()
+		check.Hint = "Fix the file; unknown keys and unsupported field names are rejected so typos don't go unnoticed"
+		return config.Default(), check
+	}
+
+	check.Status = StatusOK
+	check.Message = fmt.Sprintf("%s is valid", path)
+	return cfg, check
+}
+
+// CheckAPI checks that the API at baseURL answers and accepts the
+// credentials client sends
+func CheckAPI(client *http.Client, baseURL string, hasToken bool) []Check {
+	reachable := Check{Name: "API reachable"}
+
+	resp, err := client.Get(strings.TrimRight(baseURL, "/") + "/api/version")
+	if err != nil {
+		reachable.Status = StatusFail
+		reachable.Message = fmt.Sprintf("cannot reach %s: %v", baseURL, err)
+		reachable.Hint = "Check --api-url, that the API is running, and that proxies or firewalls allow this machine to reach it"
+		return []Check{reachable}
+	}
+	resp.Body.Close()
+
+	reachable.Status = StatusOK
+	switch resp.StatusCode {
+	case http.StatusOK:
+		reachable.Message = fmt.Sprintf("%s answered", baseURL)
+	case http.StatusNotFound:
+		reachable.Message = fmt.Sprintf("%s answered (older server without /api/version)", baseURL)
+	default:
+		reachable.Message = fmt.Sprintf("%s answered with status %d", baseURL, resp.StatusCode)
+	}
+
+	return []Check{reachable, checkAuth(client, baseURL, hasToken)}
+}
+
+// checkAuth looks up a probe email, which needs the same access as processing
+func checkAuth(client *http.Client, baseURL string, hasToken bool) Check {
+	check := Check{Name: "API authentication"}
+
+	lookupURL := fmt.Sprintf("%s/api/leads/lookup?email=%s", strings.TrimRight(baseURL, "/"), url.QueryEscape(probeEmail))
+	resp, err := client.Get(lookupURL)
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("lookup failed: %v", err)
+		check.Hint = "Check that the API is stable; the version endpoint answered but a lead lookup did not"
+		return check
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		check.Status = StatusFail
+		check.Message = fmt.Sprintf("API rejected the lookup with status %d", resp.StatusCode)
+		if hasToken {
+			check.Hint = "Check api_token in the config file; it may have expired or lack access to leads"
+		} else {
+			check.Hint = "Set api_token in the config file"
+		}
+	case resp.StatusCode == http.StatusOK:
+		check.Status = StatusOK
+		check.Message = "lead lookups are allowed"
+	default:
+		check.Status = StatusWarn
+		check.Message = fmt.Sprintf("lead lookup returned status %d", resp.StatusCode)
+		check.Hint = "Runs may fail; check the API's logs or status page"
+	}
+
+	return check
+}
+
+// CheckCSV verifies the column mapping against the header of a sample file
+// and validates up to sampleSize of its leads
+func CheckCSV(path string, mapping map[string]string, sampleSize int) []Check {
+	reader := csv.NewCSVReader()
+	reader.SetMapping(mapping)
+
+	columns := Check{Name: "CSV columns"}
+	header, err := reader.ReadHeader(path)
+	if err != nil {
+		columns.Status = StatusFail
+		columns.Message = fmt.Sprintf("cannot read %s: %v", path, err)
+		columns.Hint = "Check the path and that the file is a CSV with a header row"
+		return []Check{columns}
+	}
+
+	headerCheck, err := csv.CheckHeader(header, mapping)
+	if err != nil {
+		columns.Status = StatusFail
+		columns.Message = err.Error()
+		columns.Hint = fmt.Sprintf("Fix mapping in the config file; the file's columns are: %s", strings.Join(header, ", "))
+		return []Check{columns}
+	}
+
+	columns.Status = StatusOK
+	columns.Message = describeColumns(headerCheck)
+	if len(headerCheck.Positional) > 0 {
+		columns.Status = StatusWarn
+		columns.Hint = fmt.Sprintf("No column is named for %s, so they are read by position; add them to mapping in the config file", strings.Join(headerCheck.Positional, ", "))
+	}
+
+	return []Check{columns, checkSample(reader, path, sampleSize)}
+}
+
+// errSampleFull stops reading once the sample is complete
+var errSampleFull = errors.New("sample full")
+
+// checkSample validates the first sampleSize leads of the file
+func checkSample(reader *csv.CSVReader, path string, sampleSize int) Check {
+	check := Check{Name: "CSV sample"}
+
+	read := 0
+	var problems []string
+	err := reader.StreamLeads(path, func(lead *models.Lead) error {
+		if read == sampleSize {
+			return errSampleFull
+		}
+		read++
+		if err := lead.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("lead %d: %v", read, err))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSampleFull) {
+		check.Status = StatusFail
+		check.Message = err.Error()
+		check.Hint = "Fix the row in the file or the column it is read from"
+		return check
+	}
+
+	switch {
+	case read == 0:
+		check.Status = StatusWarn
+		check.Message = "the file has no leads"
+	case len(problems) == 0:
+		check.Status = StatusOK
+		check.Message = fmt.Sprintf("first %d lead(s) are valid", read)
+	default:
+		check.Status = StatusWarn
+		check.Message = fmt.Sprintf("%d of the first %d lead(s) fail validation, e.g. %s", len(problems), read, problems[0])
+		check.Hint = "If most rows fail, a column is probably mapped to the wrong field"
+	}
+
+	return check
+}
+
+// describeColumns lists which column each field is read from
+func describeColumns(check *csv.HeaderCheck) string {
+	fields := make([]string, 0, len(check.Columns))
+	for field, column := range check.Columns {
+		fields = append(fields, fmt.Sprintf("%s=%q", field, column))
+	}
+	sort.Strings(fields)
+
+	message := "no columns recognised by name"
+	if len(fields) > 0 {
+		message = "reading " + strings.Join(fields, ", ")
+	}
+	if len(check.Ignored) > 0 {
+		message += fmt.Sprintf("; ignoring %s", strings.Join(check.Ignored, ", "))
+	}
+
+	return message
+}
@@ -0,0 +1,137 @@
+package doctor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConfig(t *testing.T) {
+	t.Run("accepts a valid file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte("protected_fields: [source]\n"), 0o644))
+
+		// Act
+		cfg, check := CheckConfig(path)
+
+		// Assert
+		assert.Equal(t, StatusOK, check.Status)
+		assert.Equal(t, []string{"source"}, cfg.ProtectedFields)
+	})
+
+	t.Run("reports invalid files and falls back to defaults", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		assert.NoError(t, os.WriteFile(path, []byte("protected_feilds: [source]\n"), 0o644))
+
+		// Act
+		cfg, check := CheckConfig(path)
+
+		// Assert
+		assert.Equal(t, StatusFail, check.Status)
+		assert.NotEmpty(t, check.Hint)
+		assert.NotNil(t, cfg)
+	})
+}
+
+func TestCheckAPI(t *testing.T) {
+	t.Run("passes when lookups are allowed", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		// Act
+		checks := CheckAPI(server.Client(), server.URL, false)
+
+		// Assert
+		assert.Len(t, checks, 2)
+		assert.Equal(t, StatusOK, checks[0].Status)
+		assert.Equal(t, StatusOK, checks[1].Status)
+	})
+
+	t.Run("fails when credentials are rejected", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/leads/lookup" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		defer server.Close()
+
+		// Act
+		checks := CheckAPI(server.Client(), server.URL, false)
+
+		// Assert
+		assert.Equal(t, StatusFail, checks[1].Status)
+		assert.Contains(t, checks[1].Hint, "api_token")
+	})
+
+	t.Run("fails when the API is unreachable", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		// Act
+		checks := CheckAPI(http.DefaultClient, server.URL, false)
+
+		// Assert
+		assert.Len(t, checks, 1)
+		assert.Equal(t, StatusFail, checks[0].Status)
+	})
+}
+
+func TestCheckCSV(t *testing.T) {
+	writeCSV := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "leads.csv")
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("passes a mapped file with valid leads", func(t *testing.T) {
+		// Arrange
+		path := writeCSV(t, "Name,Email,Organisation,Source\nJane Roe,jane@example.com,Globex,Website\n")
+
+		// Act
+		checks := CheckCSV(path, map[string]string{"company": "Organisation"}, 20)
+
+		// Assert
+		assert.Len(t, checks, 2)
+		assert.Equal(t, StatusOK, checks[0].Status)
+		assert.Contains(t, checks[0].Message, `company="Organisation"`)
+		assert.Equal(t, StatusOK, checks[1].Status)
+	})
+
+	t.Run("fails when a mapped column is missing", func(t *testing.T) {
+		// Arrange
+		path := writeCSV(t, "Name,Email,Company,Source\n")
+
+		// Act
+		checks := CheckCSV(path, map[string]string{"company": "Organisation"}, 20)
+
+		// Assert
+		assert.Len(t, checks, 1)
+		assert.Equal(t, StatusFail, checks[0].Status)
+		assert.Contains(t, checks[0].Hint, "Name, Email, Company, Source")
+	})
+
+	t.Run("warns about columns read by position and invalid samples", func(t *testing.T) {
+		// Arrange
+		path := writeCSV(t, "Full Name,E-Mail Address,Company,Source\nJane Roe,not-an-email,Globex,Website\n")
+
+		// Act
+		checks := CheckCSV(path, nil, 20)
+
+		// Assert
+		assert.Equal(t, StatusWarn, checks[0].Status)
+		assert.Contains(t, checks[0].Hint, "name, email")
+		assert.Equal(t, StatusWarn, checks[1].Status)
+		assert.Contains(t, checks[1].Message, "1 of the first 1")
+	})
+}
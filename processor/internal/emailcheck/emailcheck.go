@@ -0,0 +1,268 @@
+package emailcheck
+
+import (
+	"code/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is how thoroughly email addresses are checked; each level includes
+// the checks of the ones before it
+type Level string
+
+// Validation levels
+const (
+	// LevelSyntax is the built-in pattern check
+	LevelSyntax Level = "syntax"
+	// LevelRFC5322 parses the address per RFC 5322, accepting any TLD,
+	// quoted local parts and international characters
+	LevelRFC5322 Level = "rfc5322"
+	// LevelDNS also requires the domain to accept mail (MX, or A/AAAA)
+	LevelDNS Level = "dns"
+	// LevelSMTP also asks the domain's mail server whether the mailbox exists
+	LevelSMTP Level = "smtp"
+)
+
+// Levels returns every level, least thorough first
+func Levels() []Level {
+	return []Level{LevelSyntax, LevelRFC5322, LevelDNS, LevelSMTP}
+}
+
+// ParseLevel parses a --email-validation value
+func ParseLevel(value string) (Level, error) {
+	for _, level := range Levels() {
+		if strings.EqualFold(strings.TrimSpace(value), string(level)) {
+			return level, nil
+		}
+	}
+
+	names := make([]string, len(Levels()))
+	for i, level := range Levels() {
+		names[i] = string(level)
+	}
+	return "", fmt.Errorf("unknown email validation level %q (use %s)", value, strings.Join(names, ", "))
+}
+
+// DefaultTimeout bounds each DNS lookup and SMTP conversation
+const DefaultTimeout = 10 * time.Second
+
+// Checker checks email addresses at a given level. DNS and SMTP results are
+// cached per domain and address, since a file usually repeats domains.
+type Checker struct {
+	level   Level
+	timeout time.Duration
+
+	lookupMX   func(ctx context.Context, domain string) ([]*net.MX, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	dial       func(ctx context.Context, address string) (net.Conn, error)
+	smtpPort   string
+	heloName   string
+
+	mu        sync.Mutex
+	exchanges map[string]domainResult
+	mailboxes map[string]*models.ValidationProblem
+}
+
+// domainResult is the outcome of looking up where a domain's mail goes
+type domainResult struct {
+	hosts   []string
+	problem *models.ValidationProblem
+}
+
+// NewChecker creates a checker for level using the system resolver
+func NewChecker(level Level) *Checker {
+	dialer := &net.Dialer{Timeout: DefaultTimeout}
+	heloName, err := os.Hostname()
+	if err != nil {
+		heloName = "localhost"
+	}
+
+	return &Checker{
+		level:      level,
+		timeout:    DefaultTimeout,
+		lookupMX:   net.DefaultResolver.LookupMX,
+		lookupHost: net.DefaultResolver.LookupHost,
+		dial: func(ctx context.Context, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", address)
+		},
+		smtpPort:  "25",
+		heloName:  heloName,
+		exchanges: make(map[string]domainResult),
+		mailboxes: make(map[string]*models.ValidationProblem),
+	}
+}
+
+// Rule returns the check as a models.EmailRule, or nil for LevelSyntax,
+// which is the built-in check
+func (c *Checker) Rule() models.EmailRule {
+	if c.level == LevelSyntax {
+		return nil
+	}
+	return c.Check
+}
+
+// Check checks email at the checker's level. Lookups that fail for reasons
+// other than the address, such as timeouts, don't reject it.
+func (c *Checker) Check(email string) *models.ValidationProblem {
+	domain, problem := parseAddress(email)
+	if problem != nil || c.level == LevelRFC5322 || c.level == LevelSyntax {
+		return problem
+	}
+
+	exchange := c.exchange(domain)
+	if exchange.problem != nil || c.level == LevelDNS {
+		return exchange.problem
+	}
+
+	return c.mailbox(email, exchange.hosts)
+}
+
+// parseAddress checks email against RFC 5322 and returns its domain
+func parseAddress(email string) (string, *models.ValidationProblem) {
+	invalid := &models.ValidationProblem{Message: "valid email is required"}
+
+	// Display names and angle brackets aren't part of an address
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || strings.ContainsAny(email, "<>") {
+		return "", invalid
+	}
+
+	at := strings.LastIndex(addr.Address, "@")
+	domain := addr.Address[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, "[") {
+		return "", invalid
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", invalid
+		}
+	}
+
+	return strings.ToLower(domain), nil
+}
+
+// exchange finds the hosts that accept mail for domain
+func (c *Checker) exchange(domain string) domainResult {
+	c.mu.Lock()
+	if result, ok := c.exchanges[domain]; ok {
+		c.mu.Unlock()
+		return result
+	}
+	c.mu.Unlock()
+
+	result := c.lookupExchange(domain)
+
+	c.mu.Lock()
+	c.exchanges[domain] = result
+	c.mu.Unlock()
+	return result
+}
+
+func (c *Checker) lookupExchange(domain string) domainResult {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	records, err := c.lookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		// A single "." exchange is a null MX: the domain takes no mail
+		if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+			return domainResult{problem: &models.ValidationProblem{Message: "email domain %s does not accept mail", Args: []interface{}{domain}}}
+		}
+		hosts := make([]string, len(records))
+		for i, record := range records {
+			hosts[i] = strings.TrimSuffix(record.Host, ".")
+		}
+		return domainResult{hosts: hosts}
+	}
+	if err != nil && !isNotFound(err) {
+		return domainResult{}
+	}
+
+	// Without MX records mail goes to the domain's own address
+	if _, err := c.lookupHost(ctx, domain); err != nil {
+		if isNotFound(err) {
+			return domainResult{problem: &models.ValidationProblem{Message: "email domain %s does not exist", Args: []interface{}{domain}}}
+		}
+		return domainResult{}
+	}
+	return domainResult{hosts: []string{domain}}
+}
+
+// mailbox asks the first reachable mail server whether it accepts email
+func (c *Checker) mailbox(email string, hosts []string) *models.ValidationProblem {
+	key := strings.ToLower(email)
+	c.mu.Lock()
+	if problem, ok := c.mailboxes[key]; ok {
+		c.mu.Unlock()
+		return problem
+	}
+	c.mu.Unlock()
+
+	var problem *models.ValidationProblem
+	for _, host := range hosts {
+		rejected, err := c.callout(host, email)
+		if err != nil {
+			continue
+		}
+		if rejected {
+			problem = &models.ValidationProblem{Message: "mail server rejected %s", Args: []interface{}{email}}
+		}
+		break
+	}
+
+	c.mu.Lock()
+	c.mailboxes[key] = problem
+	c.mu.Unlock()
+	return problem
+}
+
+// callout runs an SMTP conversation up to RCPT TO without sending anything.
+// Only a permanent (5xx) reply to RCPT counts as a rejection.
+func (c *Checker) callout(host, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn, err := c.dial(ctx, net.JoinHostPort(host, c.smtpPort))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	if err := client.Hello(c.heloName); err != nil {
+		return false, err
+	}
+	if err := client.Mail(""); err != nil {
+		return false, err
+	}
+	err = client.Rcpt(email)
+	client.Quit()
+
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 && reply.Code < 600 {
+		return true, nil
+	}
+	return false, err
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
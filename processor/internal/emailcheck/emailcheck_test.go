@@ -0,0 +1,203 @@
+package emailcheck
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDNS answers lookups from fixed tables; unknown names don't exist
+func fakeDNS(c *Checker, mx map[string][]*net.MX, hosts map[string]bool) {
+	notFound := func(name string) error {
+		return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	c.lookupMX = func(ctx context.Context, domain string) ([]*net.MX, error) {
+		if records, ok := mx[domain]; ok {
+			return records, nil
+		}
+		return nil, notFound(domain)
+	}
+	c.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if hosts[host] {
+			return []string{"192.0.2.1"}, nil
+		}
+		return nil, notFound(host)
+	}
+}
+
+// fakeSMTP serves one SMTP conversation per dial, replying rcptReply to RCPT TO
+func fakeSMTP(c *Checker, rcptReply string) {
+	c.dial = func(ctx context.Context, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			w := bufio.NewWriter(server)
+			r := bufio.NewReader(server)
+			reply := func(line string) {
+				w.WriteString(line + "\r\n")
+				w.Flush()
+			}
+
+			reply("220 mx.example.com ESMTP")
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+				case "EHLO", "HELO", "MAIL", "NOOP", "RSET":
+					reply("250 OK")
+				case "RCPT":
+					reply(rcptReply)
+				case "QUIT":
+					reply("221 Bye")
+					return
+				default:
+					reply("502 Not implemented")
+				}
+			}
+		}()
+		return client, nil
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Run("accepts every level case-insensitively", func(t *testing.T) {
+		for _, level := range Levels() {
+			// Act
+			parsed, err := ParseLevel(strings.ToUpper(string(level)))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, level, parsed)
+		}
+	})
+
+	t.Run("rejects unknown levels", func(t *testing.T) {
+		// Act
+		_, err := ParseLevel("strict")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestChecker_RFC5322(t *testing.T) {
+	checker := NewChecker(LevelRFC5322)
+
+	t.Run("accepts addresses the syntax pattern rejects", func(t *testing.T) {
+		for _, email := range []string{
+			"jane+leads@example.photography",
+			"o'brien@example.ie",
+			"josé@example.es",
+			`"jane doe"@example.com`,
+		} {
+			// Act
+			problem := checker.Check(email)
+
+			// Assert
+			assert.Nil(t, problem, email)
+		}
+	})
+
+	t.Run("rejects malformed addresses", func(t *testing.T) {
+		for _, email := range []string{
+			"invalid-email",
+			"jane@localhost",
+			"Jane <jane@example.com>",
+			"jane@-example.com",
+			"jane@example..com",
+		} {
+			// Act
+			problem := checker.Check(email)
+
+			// Assert
+			assert.NotNil(t, problem, email)
+		}
+	})
+}
+
+func TestChecker_DNS(t *testing.T) {
+	checker := NewChecker(LevelDNS)
+	fakeDNS(checker, map[string][]*net.MX{
+		"example.com": {{Host: "mx.example.com.", Pref: 10}},
+		"nomail.com":  {{Host: ".", Pref: 0}},
+	}, map[string]bool{"a-only.com": true})
+
+	t.Run("accepts domains with MX or address records", func(t *testing.T) {
+		// Assert
+		assert.Nil(t, checker.Check("jane@example.com"))
+		assert.Nil(t, checker.Check("jane@a-only.com"))
+	})
+
+	t.Run("rejects missing domains and null MX", func(t *testing.T) {
+		// Act
+		missing := checker.Check("jane@no-such-domain.com")
+		nullMX := checker.Check("jane@nomail.com")
+
+		// Assert
+		assert.Equal(t, "email domain no-such-domain.com does not exist", missing.String())
+		assert.Equal(t, "email domain nomail.com does not accept mail", nullMX.String())
+	})
+
+	t.Run("accepts addresses when the lookup itself fails", func(t *testing.T) {
+		// Arrange
+		flaky := NewChecker(LevelDNS)
+		flaky.lookupMX = func(ctx context.Context, domain string) ([]*net.MX, error) {
+			return nil, &net.DNSError{Err: "i/o timeout", Name: domain, IsTimeout: true}
+		}
+
+		// Act
+		problem := flaky.Check("jane@example.com")
+
+		// Assert
+		assert.Nil(t, problem)
+	})
+}
+
+func TestChecker_SMTP(t *testing.T) {
+	mx := map[string][]*net.MX{"example.com": {{Host: "mx.example.com.", Pref: 10}}}
+
+	t.Run("accepts mailboxes the server accepts", func(t *testing.T) {
+		// Arrange
+		checker := NewChecker(LevelSMTP)
+		fakeDNS(checker, mx, nil)
+		fakeSMTP(checker, "250 OK")
+
+		// Act
+		problem := checker.Check("jane@example.com")
+
+		// Assert
+		assert.Nil(t, problem)
+	})
+
+	t.Run("rejects mailboxes the server permanently refuses", func(t *testing.T) {
+		// Arrange
+		checker := NewChecker(LevelSMTP)
+		fakeDNS(checker, mx, nil)
+		fakeSMTP(checker, "550 No such user")
+
+		// Act
+		problem := checker.Check("nobody@example.com")
+
+		// Assert
+		assert.Equal(t, "mail server rejected nobody@example.com", problem.String())
+	})
+
+	t.Run("accepts mailboxes when the answer is only temporary", func(t *testing.T) {
+		// Arrange
+		checker := NewChecker(LevelSMTP)
+		fakeDNS(checker, mx, nil)
+		fakeSMTP(checker, "451 Try again later")
+
+		// Act
+		problem := checker.Check("jane@example.com")
+
+		// Assert
+		assert.Nil(t, problem)
+	})
+}
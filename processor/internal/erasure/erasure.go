@@ -0,0 +1,198 @@
+package erasure
+
+import (
+	"bufio"
+	"code/internal/auditlog"
+	"code/internal/processor"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Mode selects how an erased lead is removed from the CRM
+type Mode string
+
+const (
+	// ModeAnonymize replaces personal data but keeps the record
+	ModeAnonymize Mode = "anonymize"
+	// ModeDelete removes the record: permanently, or, when the eraser has
+	// an archiving remover, by anonymizing and then archiving it
+	ModeDelete Mode = "delete"
+)
+
+// Erasure outcomes recorded in the audit log
+const (
+	OutcomeErased   = "erased"
+	OutcomeNotFound = "not_found"
+	OutcomeFailed   = "failed"
+)
+
+// ParseMode parses a --mode value
+func ParseMode(value string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(value))) {
+	case ModeAnonymize:
+		return ModeAnonymize, nil
+	case ModeDelete:
+		return ModeDelete, nil
+	}
+	return "", fmt.Errorf("invalid erasure mode %q (use %s or %s)", value, ModeAnonymize, ModeDelete)
+}
+
+// Client is the subset of the API client needed to erase leads
+type Client interface {
+	LookupLead(email string) (*processor.LookupResponse, error)
+	AnonymizeLead(id string) error
+	DeleteLead(id string) error
+}
+
+// Record is one entry in the erasure audit log. The email is stored only as
+// a hash so the audit log doesn't itself retain the erased personal data.
+// Archived marks a lead that was anonymized and archived rather than deleted,
+// and Anonymized one whose personal data is gone even if archiving it failed.
+type Record struct {
+	EmailHash  string    `json:"emailHash"`
+	LeadID     string    `json:"leadId,omitempty"`
+	Mode       Mode      `json:"mode"`
+	Outcome    string    `json:"outcome"`
+	Anonymized bool      `json:"anonymized,omitempty"`
+	Archived   bool      `json:"archived,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Operator   string    `json:"operator,omitempty"`
+	ErasedAt   time.Time `json:"erasedAt"`
+}
+
+// AuditLog is an append-only NDJSON file of erasure records. Each is synced
+// to disk, so evidence of an erasure survives even if the process dies right
+// after.
+type AuditLog = auditlog.Log[Record]
+
+// OpenAuditLog opens (creating if needed) the audit log at path for appending
+func OpenAuditLog(path string) (*AuditLog, error) {
+	audit, err := auditlog.Open[Record](path, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open erasure audit log: %w", err)
+	}
+	return audit, nil
+}
+
+// Eraser looks up leads by email and anonymizes or deletes them, recording
+// every attempt in the audit log
+type Eraser struct {
+	client   Client
+	audit    *AuditLog
+	mode     Mode
+	operator string
+	remover  *processor.Remover
+}
+
+// New creates an eraser; operator identifies who requested the erasure in the audit log
+func New(client Client, audit *AuditLog, mode Mode, operator string) *Eraser {
+	return &Eraser{
+		client:   client,
+		audit:    audit,
+		mode:     mode,
+		operator: operator,
+	}
+}
+
+// SetRemover makes ModeDelete remove leads with remover. An archiving
+// remover first anonymizes the lead, so the archived record holds no
+// personal data.
+func (e *Eraser) SetRemover(remover *processor.Remover) {
+	e.remover = remover
+}
+
+// Erase erases the lead with the given email and returns its audit record.
+// API failures are reported in the record; an error is only returned when
+// the audit record itself could not be written.
+func (e *Eraser) Erase(email string) (Record, error) {
+	record := Record{
+		EmailHash: HashEmail(email),
+		Mode:      e.mode,
+		Operator:  e.operator,
+	}
+
+	if err := e.erase(email, &record); err != nil {
+		record.Outcome = OutcomeFailed
+		record.Error = redactEmail(err.Error(), email)
+	}
+	record.ErasedAt = time.Now().UTC()
+
+	if err := e.audit.Append(record); err != nil {
+		return record, err
+	}
+	return record, nil
+}
+
+func (e *Eraser) erase(email string, record *Record) error {
+	lookup, err := e.client.LookupLead(email)
+	if err != nil {
+		return fmt.Errorf("lookup failed: %w", err)
+	}
+	if !lookup.Found || lookup.Lead == nil {
+		record.Outcome = OutcomeNotFound
+		return nil
+	}
+	record.LeadID = lookup.Lead.ID
+
+	switch {
+	case e.mode == ModeDelete && e.remover != nil && e.remover.Mode() == processor.DeletionArchive:
+		if err := e.client.AnonymizeLead(lookup.Lead.ID); err != nil {
+			return fmt.Errorf("%s failed: %w", ModeAnonymize, err)
+		}
+		record.Anonymized = true
+		err = e.remover.Remove(lookup.Lead.ID)
+		record.Archived = err == nil
+	case e.mode == ModeDelete && e.remover != nil:
+		err = e.remover.Remove(lookup.Lead.ID)
+	case e.mode == ModeDelete:
+		err = e.client.DeleteLead(lookup.Lead.ID)
+	default:
+		err = e.client.AnonymizeLead(lookup.Lead.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", e.mode, err)
+	}
+
+	record.Outcome = OutcomeErased
+	return nil
+}
+
+// HashEmail returns the SHA-256 of the normalized email, so audit entries can
+// be matched to a request without storing the address
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// redactEmail removes the email, plain or URL-encoded, from an error message
+// before it is written to the audit log
+func redactEmail(message, email string) string {
+	return strings.NewReplacer(email, "[redacted]", url.QueryEscape(email), "[redacted]").Replace(message)
+}
+
+// ReadEmails reads one email per line from path, skipping blank lines and
+// lines starting with #
+func ReadEmails(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var emails []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		emails = append(emails, line)
+	}
+
+	return emails, scanner.Err()
+}
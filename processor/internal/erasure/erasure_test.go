@@ -0,0 +1,224 @@
+package erasure
+
+import (
+	"bufio"
+	"code/internal/models"
+	"code/internal/processor"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockClient records which leads were anonymized or deleted
+type mockClient struct {
+	leads        map[string]*models.Lead
+	eraseError   error
+	archiveError error
+	anonymized   []string
+	deleted      []string
+	archived     []string
+}
+
+func (m *mockClient) LookupLead(email string) (*processor.LookupResponse, error) {
+	lead, ok := m.leads[email]
+	return &processor.LookupResponse{Found: ok, Lead: lead}, nil
+}
+
+func (m *mockClient) AnonymizeLead(id string) error {
+	m.anonymized = append(m.anonymized, id)
+	return m.eraseError
+}
+
+func (m *mockClient) DeleteLead(id string) error {
+	m.deleted = append(m.deleted, id)
+	return m.eraseError
+}
+
+func (m *mockClient) ArchiveLead(id string) error {
+	m.archived = append(m.archived, id)
+	if m.archiveError != nil {
+		return m.archiveError
+	}
+	return m.eraseError
+}
+
+// lookupErrorClient fails every lookup
+type lookupErrorClient struct {
+	mockClient
+	err error
+}
+
+func (m *lookupErrorClient) LookupLead(email string) (*processor.LookupResponse, error) {
+	return nil, m.err
+}
+
+func newTestAuditLog(t *testing.T) (*AuditLog, string) {
+	path := filepath.Join(t.TempDir(), "erasures.ndjson")
+	audit, err := OpenAuditLog(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { audit.Close() })
+	return audit, path
+}
+
+func readAuditLog(t *testing.T, path string) []Record {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestEraser_Erase(t *testing.T) {
+	t.Run("anonymizes a matching lead and audits it", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Globex", "Website")
+		client := &mockClient{leads: map[string]*models.Lead{"jane@example.com": lead}}
+		audit, path := newTestAuditLog(t)
+		eraser := New(client, audit, ModeAnonymize, "dpo")
+
+		// Act
+		record, err := eraser.Erase("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeErased, record.Outcome)
+		assert.Equal(t, []string{lead.ID}, client.anonymized)
+		assert.Empty(t, client.deleted)
+
+		records := readAuditLog(t, path)
+		assert.Len(t, records, 1)
+		assert.Equal(t, lead.ID, records[0].LeadID)
+		assert.Equal(t, HashEmail("Jane@Example.com"), records[0].EmailHash)
+		assert.Equal(t, "dpo", records[0].Operator)
+	})
+
+	t.Run("deletes in delete mode", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Globex", "Website")
+		client := &mockClient{leads: map[string]*models.Lead{"jane@example.com": lead}}
+		audit, _ := newTestAuditLog(t)
+		eraser := New(client, audit, ModeDelete, "")
+
+		// Act
+		_, err := eraser.Erase("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{lead.ID}, client.deleted)
+	})
+
+	t.Run("anonymizes and archives in delete mode with an archiving remover", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Globex", "Website")
+		client := &mockClient{leads: map[string]*models.Lead{"jane@example.com": lead}}
+		audit, path := newTestAuditLog(t)
+		eraser := New(client, audit, ModeDelete, "")
+		remover, _ := processor.NewRemover(client, processor.DeletionArchive, processor.ServerFeatures{Archive: true})
+		eraser.SetRemover(remover)
+
+		// Act
+		record, err := eraser.Erase("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, record.Archived)
+		assert.Equal(t, []string{lead.ID}, client.anonymized)
+		assert.Equal(t, []string{lead.ID}, client.archived)
+		assert.Empty(t, client.deleted)
+		assert.True(t, readAuditLog(t, path)[0].Archived)
+	})
+
+	t.Run("audits the anonymization when archiving then fails", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Globex", "Website")
+		client := &mockClient{leads: map[string]*models.Lead{"jane@example.com": lead}, archiveError: assert.AnError}
+		audit, path := newTestAuditLog(t)
+		eraser := New(client, audit, ModeDelete, "")
+		remover, _ := processor.NewRemover(client, processor.DeletionArchive, processor.ServerFeatures{Archive: true})
+		eraser.SetRemover(remover)
+
+		// Act
+		record, err := eraser.Erase("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeFailed, record.Outcome)
+		assert.True(t, record.Anonymized)
+		assert.False(t, record.Archived)
+		audited := readAuditLog(t, path)[0]
+		assert.True(t, audited.Anonymized)
+		assert.Equal(t, OutcomeFailed, audited.Outcome)
+	})
+
+	t.Run("audits emails with no matching lead", func(t *testing.T) {
+		// Arrange
+		client := &mockClient{}
+		audit, path := newTestAuditLog(t)
+		eraser := New(client, audit, ModeAnonymize, "")
+
+		// Act
+		record, err := eraser.Erase("nobody@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeNotFound, record.Outcome)
+		assert.Len(t, readAuditLog(t, path), 1)
+	})
+
+	t.Run("records API failures", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Globex", "Website")
+		client := &mockClient{leads: map[string]*models.Lead{"jane@example.com": lead}, eraseError: assert.AnError}
+		audit, _ := newTestAuditLog(t)
+		eraser := New(client, audit, ModeAnonymize, "")
+
+		// Act
+		record, err := eraser.Erase("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeFailed, record.Outcome)
+		assert.Contains(t, record.Error, assert.AnError.Error())
+	})
+
+	t.Run("keeps the email out of audited errors", func(t *testing.T) {
+		// Arrange
+		client := &lookupErrorClient{err: errors.New(`Get "http://api/lookup?email=jane%40example.com": connection refused`)}
+		audit, _ := newTestAuditLog(t)
+		eraser := New(client, audit, ModeAnonymize, "")
+
+		// Act
+		record, err := eraser.Erase("jane@example.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeFailed, record.Outcome)
+		assert.NotContains(t, record.Error, "jane")
+	})
+}
+
+func TestReadEmails(t *testing.T) {
+	t.Run("skips blank lines and comments", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "emails.txt")
+		assert.NoError(t, os.WriteFile(path, []byte("# ticket 42\na@example.com\n\n  b@example.com  \n"), 0o644))
+
+		// Act
+		emails, err := ReadEmails(path)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a@example.com", "b@example.com"}, emails)
+	})
+}
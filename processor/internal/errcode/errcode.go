@@ -0,0 +1,167 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// Error classes. Errors returned by the API client and processor match one of
+// these with errors.Is, so callers can branch on the class of a failure
+// rather than its message.
+var (
+	ErrValidation  = errors.New("validation failed")
+	ErrRateLimited = errors.New("rate limited")
+	ErrConflict    = errors.New("conflict")
+	ErrNetwork     = errors.New("network error")
+	ErrAuth        = errors.New("not authorized")
+	// ErrRolledBack marks a lead whose change was undone, or never made,
+	// because another lead in its atomic batch failed
+	ErrRolledBack = errors.New("rolled back")
+	// ErrUnmatchedProfile marks a file serve couldn't pick an import
+	// profile for, so it was left for a person to route
+	ErrUnmatchedProfile = errors.New("no profile matches the file")
+	// ErrSchemaMismatch marks a file whose header differs from the strict
+	// schema of its import profile
+	ErrSchemaMismatch = errors.New("header does not match the schema")
+	// ErrQuotaExceeded marks a lead left unprocessed because its tenant
+	// used up its daily lead quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Code is the stable, machine-readable name of an error class, as written
+// to reports
+type Code string
+
+// Error codes
+const (
+	CodeValidation  Code = "VALIDATION"
+	CodeRateLimited Code = "RATE_LIMITED"
+	CodeConflict    Code = "CONFLICT"
+	CodeNetwork     Code = "NETWORK"
+	CodeAuth        Code = "AUTH"
+	CodeRolledBack  Code = "ROLLED_BACK"
+	// CodeUnmatchedProfile is a file no import profile matches
+	CodeUnmatchedProfile Code = "UNMATCHED_PROFILE"
+	// CodeSchemaMismatch is a file whose header breaks its profile's schema
+	CodeSchemaMismatch Code = "SCHEMA_MISMATCH"
+	// CodeQuotaExceeded is a lead past its tenant's daily lead quota
+	CodeQuotaExceeded Code = "QUOTA_EXCEEDED"
+	// CodeAPI is any other unexpected API response, such as a server error
+	CodeAPI Code = "API"
+	// CodeUnknown is an error of no known class
+	CodeUnknown Code = "UNKNOWN"
+)
+
+// classes maps each class to its code, most specific first
+var classes = []struct {
+	class error
+	code  Code
+}{
+	{ErrAuth, CodeAuth},
+	{ErrRateLimited, CodeRateLimited},
+	{ErrConflict, CodeConflict},
+	{ErrValidation, CodeValidation},
+	{ErrNetwork, CodeNetwork},
+	{ErrRolledBack, CodeRolledBack},
+	{ErrUnmatchedProfile, CodeUnmatchedProfile},
+	{ErrSchemaMismatch, CodeSchemaMismatch},
+	{ErrQuotaExceeded, CodeQuotaExceeded},
+}
+
+// Of returns the code of err's class, or "" for a nil error
+func Of(err error) Code {
+	if err == nil {
+		return ""
+	}
+	for _, c := range classes {
+		if errors.Is(err, c.class) {
+			return c.code
+		}
+	}
+
+	var status *StatusError
+	var tooLarge *TooLargeError
+	if errors.As(err, &status) || errors.As(err, &tooLarge) {
+		return CodeAPI
+	}
+	return CodeUnknown
+}
+
+// StatusError is an API response with an unexpected status. It matches the
+// class its status belongs to.
+type StatusError struct {
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned status %d", e.Status)
+}
+
+// Is reports whether target is the class of e's status
+func (e *StatusError) Is(target error) bool {
+	class := statusClass(e.Status)
+	return class != nil && target == class
+}
+
+// TooLargeError is an API response bigger than the client accepts. The
+// response is abandoned as soon as it passes the limit.
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("API response is larger than the %d-byte limit", e.Limit)
+}
+
+func statusClass(status int) error {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrValidation
+	}
+	return nil
+}
+
+// classified adds a class to an error without changing its message
+type classified struct {
+	class error
+	err   error
+}
+
+func (e *classified) Error() string        { return e.err.Error() }
+func (e *classified) Unwrap() error        { return e.err }
+func (e *classified) Is(target error) bool { return target == e.class }
+
+// Classify marks err as belonging to class; a nil err stays nil
+func Classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classified{class: class, err: err}
+}
+
+// Network marks an error from an HTTP client as a network failure when the
+// connection itself failed. Errors a middleware returned on purpose, such as
+// a spent budget, are left unclassified.
+func Network(err error) error {
+	cause := err
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		cause = urlErr.Err
+	}
+
+	var netErr net.Error
+	if errors.As(cause, &netErr) || errors.Is(cause, io.EOF) || errors.Is(cause, io.ErrUnexpectedEOF) {
+		return Classify(ErrNetwork, err)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusError(t *testing.T) {
+	t.Run("matches the class of its status", func(t *testing.T) {
+		cases := map[int]error{
+			401: ErrAuth,
+			403: ErrAuth,
+			429: ErrRateLimited,
+			409: ErrConflict,
+			422: ErrValidation,
+		}
+		for status, class := range cases {
+			// Act
+			err := fmt.Errorf("lookup: %w", &StatusError{Status: status})
+
+			// Assert
+			assert.ErrorIs(t, err, class, "status %d", status)
+		}
+	})
+
+	t.Run("keeps the existing message and codes other statuses as API", func(t *testing.T) {
+		// Act
+		err := &StatusError{Status: 503}
+
+		// Assert
+		assert.Equal(t, "API returned status 503", err.Error())
+		assert.Equal(t, CodeAPI, Of(err))
+	})
+}
+
+func TestNetwork(t *testing.T) {
+	t.Run("classifies connection failures", func(t *testing.T) {
+		// Arrange
+		err := &url.Error{Op: "Get", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+
+		// Act
+		classified := Network(err)
+
+		// Assert
+		assert.ErrorIs(t, classified, ErrNetwork)
+		assert.Equal(t, err.Error(), classified.Error())
+		assert.Equal(t, CodeNetwork, Of(classified))
+	})
+
+	t.Run("leaves errors returned by middleware alone", func(t *testing.T) {
+		// Arrange
+		budget := errors.New("run budget exhausted")
+		err := &url.Error{Op: "Get", URL: "http://localhost:1", Err: budget}
+
+		// Act
+		classified := Network(err)
+
+		// Assert
+		assert.NotErrorIs(t, classified, ErrNetwork)
+		assert.ErrorIs(t, classified, budget)
+	})
+}
+
+func TestOf(t *testing.T) {
+	// Assert
+	assert.Equal(t, Code(""), Of(nil))
+	assert.Equal(t, CodeConflict, Of(Classify(ErrConflict, errors.New("cannot change status"))))
+	assert.Equal(t, CodeUnknown, Of(errors.New("boom")))
+	assert.Equal(t, CodeAPI, Of(fmt.Errorf("failed to decode response: %w", &TooLargeError{Limit: 1024})))
+}
@@ -0,0 +1,137 @@
+package failures
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// NoDomain groups leads whose email has no domain
+const NoDomain = "(none)"
+
+// Causes names what made a lead fail, more precisely than its error code:
+// VALIDATION(company) for each field that failed validation, API(503) for an
+// unexpected status, or else the code alone, such as NETWORK
+func Causes(err error) []string {
+	var validation *models.ValidationError
+	if errors.As(err, &validation) && len(validation.Problems) > 0 {
+		causes := make([]string, 0, len(validation.Problems))
+		for _, problem := range validation.Problems {
+			cause := fmt.Sprintf("%s(%s)", errcode.CodeValidation, problem.Field)
+			if !slices.Contains(causes, cause) {
+				causes = append(causes, cause)
+			}
+		}
+		return causes
+	}
+
+	code := errcode.Of(err)
+	if code == "" {
+		code = errcode.CodeUnknown
+	}
+	var status *errcode.StatusError
+	if errors.As(err, &status) {
+		return []string{fmt.Sprintf("%s(%d)", code, status.Status)}
+	}
+	return []string{string(code)}
+}
+
+// Domain returns the lowercased domain of an email, or NoDomain
+func Domain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return NoDomain
+	}
+	if domain := strings.ToLower(strings.TrimSpace(email[at+1:])); domain != "" {
+		return domain
+	}
+	return NoDomain
+}
+
+// Group is a cause or domain and the number of failed leads under it
+type Group struct {
+	Name  string
+	Count int
+	// TopCause is, for a domain, the cause most of its leads failed with
+	TopCause      string
+	TopCauseCount int
+}
+
+// Tally counts failed leads by cause and by email domain
+type Tally struct {
+	causes  map[string]int
+	domains map[string]map[string]int
+	total   int
+}
+
+// NewTally returns an empty tally
+func NewTally() *Tally {
+	return &Tally{causes: make(map[string]int), domains: make(map[string]map[string]int)}
+}
+
+// Add counts a lead that failed with err. A lead failing validation on
+// several fields counts under each of them.
+func (t *Tally) Add(email string, err error) {
+	domain := Domain(email)
+	if t.domains[domain] == nil {
+		t.domains[domain] = make(map[string]int)
+	}
+	for _, cause := range Causes(err) {
+		t.causes[cause]++
+		t.domains[domain][cause]++
+	}
+	t.domains[domain][""]++
+	t.total++
+}
+
+// Total returns the number of leads counted
+func (t *Tally) Total() int {
+	return t.total
+}
+
+// Causes returns the n most common causes, most common first; n <= 0
+// returns them all
+func (t *Tally) Causes(n int) []Group {
+	groups := make([]Group, 0, len(t.causes))
+	for cause, count := range t.causes {
+		groups = append(groups, Group{Name: cause, Count: count})
+	}
+	return top(groups, n)
+}
+
+// Domains returns the n domains with the most failed leads, each with its
+// most common cause; n <= 0 returns them all
+func (t *Tally) Domains(n int) []Group {
+	groups := make([]Group, 0, len(t.domains))
+	for domain, causes := range t.domains {
+		group := Group{Name: domain, Count: causes[""]}
+		for cause, count := range causes {
+			if cause == "" {
+				continue
+			}
+			if count > group.TopCauseCount || count == group.TopCauseCount && cause < group.TopCause {
+				group.TopCause, group.TopCauseCount = cause, count
+			}
+		}
+		groups = append(groups, group)
+	}
+	return top(groups, n)
+}
+
+// top sorts groups by count, then name, and keeps the first n
+func top(groups []Group, n int) []Group {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	if n > 0 && len(groups) > n {
+		groups = groups[:n]
+	}
+	return groups
+}
@@ -0,0 +1,68 @@
+package failures
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCauses(t *testing.T) {
+	t.Run("names each field that failed validation once", func(t *testing.T) {
+		// Arrange
+		err := &models.ValidationError{Problems: []models.ValidationProblem{
+			{Field: "company", Message: "company is required"},
+			{Field: "email", Message: "valid email is required"},
+			{Field: "company", Message: "company is too long"},
+		}}
+
+		// Act
+		causes := Causes(fmt.Errorf("row 3: %w", err))
+
+		// Assert
+		assert.Equal(t, []string{"VALIDATION(company)", "VALIDATION(email)"}, causes)
+	})
+
+	t.Run("adds the status to API errors", func(t *testing.T) {
+		// Assert
+		assert.Equal(t, []string{"API(503)"}, Causes(&errcode.StatusError{Status: 503}))
+		assert.Equal(t, []string{"RATE_LIMITED(429)"}, Causes(&errcode.StatusError{Status: 429}))
+		assert.Equal(t, []string{"NETWORK"}, Causes(errcode.Classify(errcode.ErrNetwork, assert.AnError)))
+		assert.Equal(t, []string{"UNKNOWN"}, Causes(nil))
+	})
+}
+
+func TestDomain(t *testing.T) {
+	// Assert
+	assert.Equal(t, "example.com", Domain("Jane@Example.COM"))
+	assert.Equal(t, NoDomain, Domain("jane"))
+	assert.Equal(t, NoDomain, Domain("jane@"))
+}
+
+func TestTally(t *testing.T) {
+	t.Run("groups failures by cause and domain", func(t *testing.T) {
+		// Arrange
+		missingCompany := &models.ValidationError{Problems: []models.ValidationProblem{{Field: "company", Message: "company is required"}}}
+		tally := NewTally()
+		tally.Add("a@partner-x.com", missingCompany)
+		tally.Add("b@partner-x.com", missingCompany)
+		tally.Add("c@partner-x.com", &errcode.StatusError{Status: 503})
+		tally.Add("d@other.com", &errcode.StatusError{Status: 503})
+		tally.Add("e@other.com", &errcode.StatusError{Status: 503})
+		tally.Add("f@third.com", missingCompany)
+
+		// Act
+		causes := tally.Causes(0)
+		domains := tally.Domains(2)
+
+		// Assert
+		assert.Equal(t, 6, tally.Total())
+		assert.Equal(t, []Group{{Name: "API(503)", Count: 3}, {Name: "VALIDATION(company)", Count: 3}}, causes)
+		assert.Equal(t, []Group{
+			{Name: "partner-x.com", Count: 3, TopCause: "VALIDATION(company)", TopCauseCount: 2},
+			{Name: "other.com", Count: 2, TopCause: "API(503)", TopCauseCount: 2},
+		}, domains)
+	})
+}
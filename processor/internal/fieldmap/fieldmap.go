@@ -0,0 +1,166 @@
+package fieldmap
+
+import (
+	"code/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields are the lead fields a mapping can place. firstName and lastName are
+// the name split at its last space, for providers keeping them apart.
+var Fields = []string{"name", "firstName", "lastName", "email", "company", "source", "status", "territory", "language", "consentGiven", "consentTimestamp", "consentSource"}
+
+// Mapping declares where a provider keeps each lead field, so a lead is
+// turned into the provider's record, and back, the same way for every
+// provider; only what a mapping can't say, such as an email held in a list,
+// is left to the provider's code
+type Mapping struct {
+	// Fields maps lead fields to the provider's field names
+	Fields map[string]string
+	// FlagsAsText sends consentGiven as "true" or "false", for providers
+	// keeping it in a text field
+	FlagsAsText bool
+}
+
+// New returns a mapping of defaults with overrides applied on top
+func New(defaults, overrides map[string]string) Mapping {
+	fields := make(map[string]string, len(defaults)+len(overrides))
+	for field, name := range defaults {
+		fields[field] = name
+	}
+	for field, name := range overrides {
+		fields[field] = name
+	}
+	return Mapping{Fields: fields}
+}
+
+// Normalize checks a mapping from a config file against the lead fields a
+// provider lets it map, matching them case-insensitively, and returns it with
+// canonical field names and trimmed provider names
+func Normalize(fields map[string]string, allowed []string) (map[string]string, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	normalized := make(map[string]string, len(fields))
+	for field, name := range fields {
+		canonical, ok := lookup(field, allowed)
+		if !ok {
+			return nil, fmt.Errorf("%q cannot be mapped (allowed: %s)", field, strings.Join(allowed, ", "))
+		}
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("no provider field given for %s", canonical)
+		}
+		normalized[canonical] = strings.TrimSpace(name)
+	}
+	return normalized, nil
+}
+
+func lookup(field string, allowed []string) (string, bool) {
+	for _, name := range allowed {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Values renders a lead's fields as text, by lead field. consentTimestamp is
+// RFC 3339 in UTC, or empty when the lead has none.
+func Values(lead *models.Lead) map[string]string {
+	first, last := SplitName(lead.Name)
+	values := map[string]string{
+		"name":          lead.Name,
+		"firstName":     first,
+		"lastName":      last,
+		"email":         lead.Email,
+		"company":       lead.Company,
+		"source":        lead.Source,
+		"status":        lead.Status,
+		"territory":     lead.Territory,
+		"language":      lead.Language,
+		"consentGiven":  strconv.FormatBool(lead.ConsentGiven),
+		"consentSource": lead.ConsentSource,
+	}
+	if lead.ConsentTimestamp != nil {
+		values["consentTimestamp"] = lead.ConsentTimestamp.UTC().Format(time.RFC3339)
+	}
+	return values
+}
+
+// SplitName splits a full name at its last space. A single name is the last
+// name, which providers that keep names apart usually require.
+func SplitName(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, " "); i > 0 {
+		return strings.TrimSpace(name[:i]), name[i+1:]
+	}
+	return "", name
+}
+
+// Has reports whether the mapping places field
+func (m Mapping) Has(field string) bool {
+	_, ok := m.Fields[field]
+	return ok
+}
+
+// Payload returns the mapped fields of a lead, by provider field name.
+// Status, territory, language and consent timestamp are left out when the
+// lead has none, since most inputs don't carry them, so the provider keeps
+// its value.
+func (m Mapping) Payload(lead *models.Lead) map[string]interface{} {
+	values := Values(lead)
+	payload := make(map[string]interface{}, len(m.Fields))
+	for field, name := range m.Fields {
+		switch {
+		case (field == "status" || field == "territory" || field == "language" || field == "consentTimestamp") && values[field] == "":
+		case field == "consentGiven" && !m.FlagsAsText:
+			payload[name] = lead.ConsentGiven
+		default:
+			payload[name] = values[field]
+		}
+	}
+	return payload
+}
+
+// Lead reads the mapped fields of a provider record, given the text value of
+// each provider field. A name the provider keeps apart is joined again.
+func (m Mapping) Lead(value func(name string) string) *models.Lead {
+	text := func(field string) string {
+		if name, ok := m.Fields[field]; ok {
+			return value(name)
+		}
+		return ""
+	}
+
+	lead := &models.Lead{
+		Name:          text("name"),
+		Email:         text("email"),
+		Company:       text("company"),
+		Source:        text("source"),
+		Status:        text("status"),
+		Territory:     text("territory"),
+		Language:      text("language"),
+		ConsentSource: text("consentSource"),
+	}
+	if !m.Has("name") {
+		lead.Name = strings.TrimSpace(text("firstName") + " " + text("lastName"))
+	}
+	lead.ConsentGiven, _ = strconv.ParseBool(text("consentGiven"))
+	if at, err := time.Parse(time.RFC3339, text("consentTimestamp")); err == nil {
+		lead.ConsentTimestamp = &at
+	}
+	return lead
+}
+
+// Names returns the provider field names the mapping uses
+func (m Mapping) Names() []string {
+	names := make([]string, 0, len(m.Fields))
+	for _, field := range Fields {
+		if name, ok := m.Fields[field]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
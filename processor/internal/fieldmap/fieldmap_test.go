@@ -0,0 +1,81 @@
+package fieldmap
+
+import (
+	"code/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapping(t *testing.T) {
+	t.Run("turns a lead into a provider record and back", func(t *testing.T) {
+		// Arrange
+		mapping := New(map[string]string{
+			"firstName": "First_Name",
+			"lastName":  "Last_Name",
+			"email":     "Email",
+			"company":   "Company",
+			"source":    "Lead_Source",
+		}, map[string]string{"company": "Organization", "consentGiven": "Opted_In", "consentTimestamp": "Opted_In_At"})
+		consentAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+		lead := models.NewLead("Mary Ann Smith", "mary@example.com", "Acme", "Webinar")
+		lead.ConsentGiven, lead.ConsentTimestamp = true, &consentAt
+
+		// Act
+		payload := mapping.Payload(lead)
+		read := mapping.Lead(func(name string) string {
+			value, _ := payload[name].(string)
+			if flag, ok := payload[name].(bool); ok && flag {
+				value = "true"
+			}
+			return value
+		})
+
+		// Assert
+		assert.Equal(t, map[string]interface{}{
+			"First_Name":   "Mary Ann",
+			"Last_Name":    "Smith",
+			"Email":        "mary@example.com",
+			"Organization": "Acme",
+			"Lead_Source":  "Webinar",
+			"Opted_In":     true,
+			"Opted_In_At":  "2026-03-04T05:06:07Z",
+		}, payload)
+		assert.Equal(t, lead.Name, read.Name)
+		assert.Equal(t, lead.Company, read.Company)
+		assert.True(t, read.ConsentGiven)
+		assert.Equal(t, consentAt, *read.ConsentTimestamp)
+	})
+
+	t.Run("leaves out an unset status and sends flags as text when asked", func(t *testing.T) {
+		// Arrange
+		mapping := New(map[string]string{"status": "Lead_Status", "consentGiven": "opted_in"}, nil)
+		mapping.FlagsAsText = true
+
+		// Act
+		payload := mapping.Payload(models.NewLead("Cher", "cher@example.com", "", "Website"))
+
+		// Assert
+		assert.Equal(t, map[string]interface{}{"opted_in": "false"}, payload)
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	t.Run("matches lead fields case-insensitively", func(t *testing.T) {
+		// Act
+		fields, err := Normalize(map[string]string{" Source ": " LeadSource "}, []string{"source"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"source": "LeadSource"}, fields)
+	})
+
+	t.Run("rejects fields the provider can't map and empty names", func(t *testing.T) {
+		_, err := Normalize(map[string]string{"email": "Secondary_Email"}, []string{"source"})
+		assert.ErrorContains(t, err, "cannot be mapped")
+
+		_, err = Normalize(map[string]string{"source": " "}, []string{"source"})
+		assert.ErrorContains(t, err, "no provider field given for source")
+	})
+}
@@ -0,0 +1,365 @@
+package filter
+
+import (
+	"code/internal/models"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Fields are the lead fields an expression can name. consentGiven is "true"
+// or "false"; column("Header") reads any column of the lead's source row.
+var Fields = []string{"name", "email", "company", "source", "status", "territory", "language", "consentGiven", "consentSource"}
+
+// Filter is a compiled --filter expression, such as
+//
+//	source == "Webinar" && company != ""
+//
+// Values are text. Conditions compare them with ==, !=, contains,
+// startsWith, endsWith, matches (a regular expression) or in ["a", "b"], and
+// combine with &&, || and !, grouped with parentheses. A value on its own is
+// true unless it is empty or "false", so `company` keeps leads with a company.
+type Filter struct {
+	expr    string
+	root    condition
+	columns []string
+}
+
+// Parse compiles an expression, reporting where it stops making sense
+func Parse(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEnd {
+		return nil, p.unexpected(next)
+	}
+	return &Filter{expr: expr, root: root, columns: p.columns}, nil
+}
+
+// Match reports whether the lead passes the filter
+func (f *Filter) Match(lead *models.Lead) bool {
+	return f.root(lead)
+}
+
+// Columns returns the source columns the expression reads with column()
+func (f *Filter) Columns() []string {
+	return f.columns
+}
+
+// String returns the expression the filter was parsed from
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// condition decides a lead; value reads a text value of it
+type (
+	condition func(lead *models.Lead) bool
+	value     func(lead *models.Lead) string
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits an expression into identifiers, quoted strings and operators
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(expr) && expr[end] != c {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at %d", i+1)
+			}
+			text := expr[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(expr[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at %d: %w", i+1, err)
+				}
+				text = unquoted
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i + 1})
+			i = end + 1
+		case isIdentStart(rune(c)):
+			end := i
+			for end < len(expr) && isIdentPart(rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[i:end], pos: i + 1})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "&&", "||", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i + 1})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(expr) + 1}), nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+// parser builds conditions by recursive descent: || binds loosest, then &&,
+// then !, then the comparisons
+type parser struct {
+	tokens  []token
+	pos     int
+	columns []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(tokenOp, text) {
+		return fmt.Errorf("expected %q, %w", text, p.unexpected(p.peek()))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEnd {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+func (p *parser) or() (condition, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		left = func(lead *models.Lead) bool { return a(lead) || b(lead) }
+	}
+	return left, nil
+}
+
+func (p *parser) and() (condition, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "&&") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		left = func(lead *models.Lead) bool { return a(lead) && b(lead) }
+	}
+	return left, nil
+}
+
+func (p *parser) not() (condition, error) {
+	if p.accept(tokenOp, "!") {
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(lead *models.Lead) bool { return !inner(lead) }, nil
+	}
+	if p.accept(tokenOp, "(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (condition, error) {
+	left, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch {
+	case op.kind == tokenOp && (op.text == "==" || op.text == "!="):
+		p.next()
+		right, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		negate := op.text == "!="
+		return func(lead *models.Lead) bool { return (left(lead) == right(lead)) != negate }, nil
+	case op.kind == tokenIdent && op.text == "in":
+		p.next()
+		list, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		return func(lead *models.Lead) bool { return slices.Contains(list, left(lead)) }, nil
+	case op.kind == tokenIdent && op.text == "matches":
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, fmt.Errorf("matches needs a quoted pattern, %w", p.unexpected(pattern))
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern at %d: %w", pattern.pos, err)
+		}
+		return func(lead *models.Lead) bool { return re.MatchString(left(lead)) }, nil
+	case op.kind == tokenIdent && textOps[op.text] != nil:
+		p.next()
+		right, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		test := textOps[op.text]
+		return func(lead *models.Lead) bool { return test(left(lead), right(lead)) }, nil
+	}
+
+	// A value on its own tests whether it is set
+	return func(lead *models.Lead) bool {
+		v := left(lead)
+		return v != "" && v != "false"
+	}, nil
+}
+
+// textOps are the comparisons written as words, besides in and matches
+var textOps = map[string]func(s, sub string) bool{
+	"contains":   strings.Contains,
+	"startsWith": strings.HasPrefix,
+	"endsWith":   strings.HasSuffix,
+}
+
+func (p *parser) value() (value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return func(*models.Lead) string { return t.text }, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return func(*models.Lead) string { return t.text }, nil
+		case "column":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			column := p.next()
+			if column.kind != tokenString {
+				return nil, fmt.Errorf("column needs a quoted header, %w", p.unexpected(column))
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			p.columns = append(p.columns, column.text)
+			return func(lead *models.Lead) string {
+				v, _ := lead.RawValue(column.text)
+				return v
+			}, nil
+		}
+		return field(t)
+	}
+	return nil, p.unexpected(t)
+}
+
+// field reads a lead field, named case-insensitively
+func field(t token) (value, error) {
+	for _, name := range Fields {
+		if !strings.EqualFold(name, t.text) {
+			continue
+		}
+		switch name {
+		case "consentGiven":
+			return func(lead *models.Lead) string { return strconv.FormatBool(lead.ConsentGiven) }, nil
+		case "consentSource":
+			return func(lead *models.Lead) string { return lead.ConsentSource }, nil
+		}
+		return func(lead *models.Lead) string {
+			v, _ := lead.Field(name)
+			return v
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q at %d (fields: %s, or column(\"Header\"))", t.text, t.pos, strings.Join(Fields, ", "))
+}
+
+func (p *parser) list() ([]string, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var list []string
+	for !p.accept(tokenOp, "]") {
+		if len(list) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, fmt.Errorf("in needs a list of quoted values, %w", p.unexpected(t))
+		}
+		list = append(list, t.text)
+	}
+	return list, nil
+}
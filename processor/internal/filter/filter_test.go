@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	webinar := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "Webinar")
+	webinar.ConsentGiven = true
+	webinar.Raw = map[string]string{"UTM Campaign": "spring-launch"}
+	referral := models.NewLead("John Doe", "john@example.com", "", "Referral")
+
+	tests := []struct {
+		name     string
+		expr     string
+		webinar  bool
+		referral bool
+	}{
+		{"compares fields", `source == "Webinar"`, true, false},
+		{"combines conditions", `source == "Webinar" && company != ""`, true, false},
+		{"lets || bind looser than &&", `source == "Referral" || source == "Webinar" && company == ""`, false, true},
+		{"groups with parentheses", `(source == "Referral" || source == "Webinar") && company == ""`, false, true},
+		{"negates", `!(source == "Webinar")`, false, true},
+		{"tests whether a value is set", `company`, true, false},
+		{"reads consent as true or false", `consentGiven`, true, false},
+		{"compares consent with a literal", `consentGiven == false`, false, true},
+		{"matches text", `email endsWith "@example.com" || name contains "Smith"`, true, true},
+		{"matches prefixes", `name startsWith 'Jo'`, false, true},
+		{"matches regular expressions", `email matches "^[a-z]+@tech"`, true, false},
+		{"tests membership", `source in ["Webinar", "Event"]`, true, false},
+		{"reads source columns", `column("utm campaign") == "spring-launch"`, true, false},
+		{"names fields case-insensitively", `Source == "Referral"`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			f, err := Parse(tt.expr)
+			assert.NoError(t, err)
+
+			// Act
+			webinarMatched := f.Match(webinar)
+			referralMatched := f.Match(referral)
+
+			// Assert
+			assert.Equal(t, tt.webinar, webinarMatched)
+			assert.Equal(t, tt.referral, referralMatched)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{`source = "Webinar"`, `unexpected '=' at 8`},
+		{`phone == "1"`, `unknown field "phone" at 1`},
+		{`source == "Webinar`, `unterminated string at 11`},
+		{`source == "Webinar" &&`, `unexpected end of expression`},
+		{`(source == "Webinar"`, `expected ")", unexpected end of expression`},
+		{`source == "Webinar" company`, `unexpected "company" at 21`},
+		{`email matches "["`, "invalid pattern at 15"},
+		{`source in "Webinar"`, `expected "[", unexpected "Webinar" at 11`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			// Act
+			_, err := Parse(tt.expr)
+
+			// Assert
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestFilter_Columns(t *testing.T) {
+	// Act
+	f, err := Parse(`column("UTM Source") == "ads" || (source == "Webinar" && column('Event') != "")`)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"UTM Source", "Event"}, f.Columns())
+}
@@ -0,0 +1,265 @@
+package fixedwidth
+
+import (
+	"bufio"
+	"code/internal/csv"
+	"code/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Config holds the fixed-width layout of the config file
+type Config struct {
+	// Columns are the fields of a record, at their positions
+	Columns []Column `yaml:"columns"`
+
+	// SkipLines ignores banner or header lines at the start of the file
+	SkipLines int `yaml:"skip_lines"`
+
+	// RecordLength splits the file into records of this many characters,
+	// for mainframe files without line breaks (0 reads one record per line)
+	RecordLength int `yaml:"record_length"`
+
+	// Encoding is the file's character set, e.g. ISO-8859-1 or IBM037
+	// (EBCDIC); default UTF-8
+	Encoding string `yaml:"encoding"`
+}
+
+// Column is one field of a record
+type Column struct {
+	// Name is the column's header name, matched like a CSV header
+	Name string `yaml:"name"`
+
+	// Start is the field's first character, counting from 1 as record
+	// layouts do
+	Start int `yaml:"start"`
+
+	// Length is the field's width in characters
+	Length int `yaml:"length"`
+}
+
+// Validate checks the columns, their positions and the encoding
+func (c *Config) Validate() error {
+	names := make(map[string]bool, len(c.Columns))
+	for i, col := range c.Columns {
+		name := strings.TrimSpace(col.Name)
+		if name == "" {
+			return fmt.Errorf("columns[%d]: a name is required", i)
+		}
+		if names[strings.ToLower(name)] {
+			return fmt.Errorf("columns[%d]: %s is defined twice", i, name)
+		}
+		names[strings.ToLower(name)] = true
+		if col.Start < 1 {
+			return fmt.Errorf("columns[%d]: %s: start must be 1 or more", i, name)
+		}
+		if col.Length < 1 {
+			return fmt.Errorf("columns[%d]: %s: length must be 1 or more", i, name)
+		}
+		if c.RecordLength > 0 && col.Start+col.Length-1 > c.RecordLength {
+			return fmt.Errorf("columns[%d]: %s ends at %d, past the record length of %d", i, name, col.Start+col.Length-1, c.RecordLength)
+		}
+		c.Columns[i].Name = name
+	}
+	if c.SkipLines < 0 {
+		return fmt.Errorf("skip_lines cannot be negative")
+	}
+	if c.RecordLength < 0 {
+		return fmt.Errorf("record_length cannot be negative")
+	}
+	if _, err := c.encoding(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Configured reports whether a layout was given
+func (c *Config) Configured() bool {
+	return len(c.Columns) > 0
+}
+
+// encoding looks up the file's character set; "ebcdic" is taken as IBM037,
+// the US and Canada code page
+func (c *Config) encoding() (encoding.Encoding, error) {
+	name := strings.TrimSpace(c.Encoding)
+	if name == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(name, "ebcdic") {
+		name = "IBM037"
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("encoding: unsupported character set %q", c.Encoding)
+	}
+	return enc, nil
+}
+
+// Reader reads the records of a fixed-width file as rows
+type Reader struct {
+	file    *os.File
+	input   *bufio.Reader
+	cfg     Config
+	skipped int
+}
+
+// Open opens the file at path, decoding it with the layout's encoding
+func Open(path string, cfg Config) (*Reader, error) {
+	if !cfg.Configured() {
+		return nil, fmt.Errorf("fixed-width input needs fixed_width.columns in the config file")
+	}
+	enc, err := cfg.encoding()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var input io.Reader = file
+	if enc != nil {
+		input = enc.NewDecoder().Reader(file)
+	}
+	return &Reader{file: file, input: bufio.NewReader(input), cfg: cfg}, nil
+}
+
+// Header returns the column names
+func (r *Reader) Header() []string {
+	names := make([]string, len(r.cfg.Columns))
+	for i, col := range r.cfg.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// Next returns the next record's values, trimmed of padding, or io.EOF
+// after the last one. Blank lines are skipped.
+func (r *Reader) Next() ([]string, error) {
+	for {
+		record, err := r.record()
+		if err != nil {
+			return nil, err
+		}
+		if r.skipped < r.cfg.SkipLines {
+			r.skipped++
+			continue
+		}
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		return r.fields(record), nil
+	}
+}
+
+// record reads one line, or RecordLength characters
+func (r *Reader) record() (string, error) {
+	if r.cfg.RecordLength == 0 {
+		line, err := r.input.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	var b strings.Builder
+	for n := 0; n < r.cfg.RecordLength; n++ {
+		c, _, err := r.input.ReadRune()
+		if err == io.EOF && n > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), nil
+}
+
+// fields cuts a record into its columns; those past the end of a short
+// record are empty
+func (r *Reader) fields(record string) []string {
+	chars := []rune(strings.TrimPrefix(record, "\ufeff"))
+	values := make([]string, len(r.cfg.Columns))
+	for i, col := range r.cfg.Columns {
+		start := col.Start - 1
+		if start >= len(chars) {
+			continue
+		}
+		end := start + col.Length
+		if end > len(chars) {
+			end = len(chars)
+		}
+		values[i] = strings.TrimSpace(string(chars[start:end]))
+	}
+	return values
+}
+
+// Close closes the file
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// Source is a fixed-width file read as a lead source. Its columns are
+// mapped to lead fields like a CSV file's header.
+type Source struct {
+	path   string
+	cfg    Config
+	reader *csv.CSVReader
+}
+
+// NewSource returns the file at path as a lead source, cut into columns by
+// cfg and mapped with reader
+func NewSource(path string, cfg Config, reader *csv.CSVReader) *Source {
+	return &Source{path: path, cfg: cfg, reader: reader}
+}
+
+// Name returns the file's path
+func (s *Source) Name() string {
+	return s.path
+}
+
+// Hash returns the SHA-256 of the file's content
+func (s *Source) Hash() (string, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadHeader returns the configured column names
+func (s *Source) ReadHeader() ([]string, error) {
+	r, err := Open(s.path, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.Header(), nil
+}
+
+// StreamLeads streams the file's leads
+func (s *Source) StreamLeads(emit func(*models.Lead) error) error {
+	r, err := Open(s.path, s.cfg)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.reader.StreamRows(r.Header(), r.Next, emit)
+}
@@ -0,0 +1,125 @@
+package fixedwidth
+
+import (
+	"code/internal/csv"
+	"code/internal/models"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var layout = []Column{
+	{Name: "Name", Start: 1, Length: 12},
+	{Name: "Email", Start: 13, Length: 20},
+	{Name: "Source", Start: 33, Length: 8},
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "partner.dat")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func readAll(t *testing.T, r *Reader) [][]string {
+	var rows [][]string
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows
+		}
+		assert.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Run("cuts lines into trimmed columns", func(t *testing.T) {
+		// Arrange
+		content := "HDR PARTNER EXTRACT 20261016\r\n" +
+			"Jane Smith  jane@techfirm.com   Webinar \r\n" +
+			"\r\n" +
+			"Renée Müllerrenee@acme.de\r\n"
+		cfg := Config{Columns: layout, SkipLines: 1}
+
+		// Act
+		r, err := Open(writeFile(t, content), cfg)
+		assert.NoError(t, err)
+		defer r.Close()
+		rows := readAll(t, r)
+
+		// Assert
+		assert.Equal(t, []string{"Name", "Email", "Source"}, r.Header())
+		assert.Equal(t, [][]string{
+			{"Jane Smith", "jane@techfirm.com", "Webinar"},
+			{"Renée Müller", "renee@acme.de", ""},
+		}, rows)
+	})
+
+	t.Run("splits EBCDIC records without line breaks", func(t *testing.T) {
+		// Arrange
+		records := "Jane Smith  jane@techfirm.com   Webinar " + "John Doe    john@acme.com       Referral"
+		encoded, _ := charmap.CodePage037.NewEncoder().String(records)
+		cfg := Config{Columns: layout, RecordLength: 40, Encoding: "ebcdic"}
+
+		// Act
+		r, err := Open(writeFile(t, encoded), cfg)
+		assert.NoError(t, err)
+		defer r.Close()
+		rows := readAll(t, r)
+
+		// Assert
+		assert.Equal(t, [][]string{
+			{"Jane Smith", "jane@techfirm.com", "Webinar"},
+			{"John Doe", "john@acme.com", "Referral"},
+		}, rows)
+	})
+
+	t.Run("requires a layout", func(t *testing.T) {
+		// Act
+		_, err := Open(writeFile(t, "Jane Smith\n"), Config{})
+
+		// Assert
+		assert.ErrorContains(t, err, "fixed_width.columns")
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects invalid columns and encodings", func(t *testing.T) {
+		// Arrange
+		duplicate := Config{Columns: []Column{{Name: "Email", Start: 1, Length: 5}, {Name: "email", Start: 6, Length: 5}}}
+		zeroStart := Config{Columns: []Column{{Name: "Email", Start: 0, Length: 5}}}
+		encoding := Config{Columns: layout, Encoding: "klingon"}
+
+		// Assert
+		assert.ErrorContains(t, duplicate.Validate(), "email is defined twice")
+		assert.ErrorContains(t, zeroStart.Validate(), "start must be 1 or more")
+		assert.ErrorContains(t, encoding.Validate(), "unsupported character set")
+	})
+}
+
+func TestSource(t *testing.T) {
+	t.Run("streams leads with the column mapping", func(t *testing.T) {
+		// Arrange
+		reader := csv.NewCSVReader()
+		reader.SetMapping(map[string]string{"email": "Work Email"})
+		columns := []Column{{Name: "Name", Start: 1, Length: 12}, {Name: "Work Email", Start: 13, Length: 20}, {Name: "Source", Start: 33, Length: 8}}
+		source := NewSource(writeFile(t, "Jane Smith  jane@techfirm.com   Webinar \n"), Config{Columns: columns}, reader)
+
+		// Act
+		var leads []*models.Lead
+		err := source.StreamLeads(func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 1)
+		assert.Equal(t, "jane@techfirm.com", leads[0].Email)
+		assert.Equal(t, "Webinar", leads[0].Source)
+	})
+}
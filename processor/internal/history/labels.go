@@ -0,0 +1,76 @@
+package history
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// labelKeyPattern is what a label key may be made of, so labels survive
+// being joined into a header or a CSV cell
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Labels tag a run, e.g. campaign=q3-webinar, so the imports of one
+// initiative can be found together across months
+type Labels map[string]string
+
+// ParseLabels checks labels given as key=value pairs, trimming them. Keys are
+// letters, digits, '_', '.' and '-'; values can't hold commas or line breaks.
+func ParseLabels(pairs map[string]string) (Labels, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	labels := make(Labels, len(pairs))
+	for key, value := range pairs {
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !labelKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid label key %q: use letters, digits, '_', '.' and '-'", key)
+		}
+		if value == "" || strings.ContainsAny(value, ",\r\n") {
+			return nil, fmt.Errorf("invalid value for label %s: it must be non-empty without commas or line breaks", key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// String joins the labels as key=value pairs sorted by key, e.g.
+// campaign=q3-webinar,team=emea
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + l[key]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Matches reports whether the labels include every label of filter
+func (l Labels) Matches(filter Labels) bool {
+	for key, value := range filter {
+		if l[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RunsLabelled returns the runs carrying every label of filter, oldest
+// first; all runs for an empty filter
+func (s *Store) RunsLabelled(filter Labels) []*Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []*Run
+	for _, run := range s.runs {
+		if run.Labels.Matches(filter) {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabels(t *testing.T) {
+	t.Run("trims and joins labels sorted by key", func(t *testing.T) {
+		// Act
+		labels, err := ParseLabels(map[string]string{" team ": "emea", "campaign": " q3-webinar"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "campaign=q3-webinar,team=emea", labels.String())
+	})
+
+	t.Run("rejects keys and values that wouldn't survive a header or CSV", func(t *testing.T) {
+		for _, pairs := range []map[string]string{
+			{"": "q3"},
+			{"campaign name": "q3"},
+			{"campaign": ""},
+			{"campaign": "q3,q4"},
+		} {
+			_, err := ParseLabels(pairs)
+			assert.Error(t, err, pairs)
+		}
+	})
+}
+
+func TestStore_RunsLabelled(t *testing.T) {
+	t.Run("returns the runs carrying every label of the filter", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		store, err := Open(dir)
+		assert.NoError(t, err)
+		webinar := store.StartRun("webinar-emea.csv")
+		webinar.Labels = Labels{"campaign": "q3-webinar", "team": "emea"}
+		other := store.StartRun("webinar-us.csv")
+		other.Labels = Labels{"campaign": "q3-webinar", "team": "us"}
+		unlabelled := store.StartRun("leads.csv")
+		assert.NoError(t, store.FinishRun(webinar))
+		assert.NoError(t, store.FinishRun(other))
+		assert.NoError(t, store.FinishRun(unlabelled))
+		assert.NoError(t, store.Close())
+		reopened, err := Open(dir)
+		assert.NoError(t, err)
+		defer reopened.Close()
+
+		// Act
+		campaign := reopened.RunsLabelled(Labels{"campaign": "q3-webinar"})
+		emea := reopened.RunsLabelled(Labels{"campaign": "q3-webinar", "team": "emea"})
+
+		// Assert
+		assert.Len(t, campaign, 2)
+		assert.Len(t, emea, 1)
+		assert.Equal(t, webinar.ID, emea[0].ID)
+		assert.Len(t, reopened.RunsLabelled(nil), 3)
+	})
+}
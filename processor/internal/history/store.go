@@ -0,0 +1,294 @@
+package history
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+const (
+	runsFile  = "runs.ndjson"
+	leadsFile = "leads.ndjson"
+)
+
+// Run is one processing run recorded in the history store
+type Run struct {
+	ID         string    `json:"id"`
+	File       string    `json:"file"`
+	FileHash   string    `json:"fileHash,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+	Total      int       `json:"total"`
+	Created    int       `json:"created"`
+	Updated    int       `json:"updated"`
+	Skipped    int       `json:"skipped"`
+	Deferred   int       `json:"deferred,omitempty"`
+	Errors     int       `json:"errors"`
+	// Paused runs stopped when their run window closed and will be resumed
+	Paused bool `json:"paused,omitempty"`
+
+	// Feed groups the runs of one partner's recurring files, whose figures
+	// are the baseline anomalies are measured against
+	Feed string `json:"feed,omitempty"`
+	// Bytes is the size of the input file, 0 for other sources
+	Bytes int64 `json:"bytes,omitempty"`
+	// Labels were given with --label, e.g. campaign=q3-webinar
+	Labels Labels `json:"labels,omitempty"`
+
+	// API usage of the run, for budgeting providers that bill per request
+	APICalls      map[string]int `json:"apiCalls,omitempty"`
+	BytesSent     int64          `json:"bytesSent,omitempty"`
+	BytesReceived int64          `json:"bytesReceived,omitempty"`
+	EstimatedCost float64        `json:"estimatedCost,omitempty"`
+}
+
+// LeadRecord marks a lead as successfully processed in a run
+type LeadRecord struct {
+	Email       string    `json:"email"`
+	ContentHash string    `json:"contentHash"`
+	RunID       string    `json:"runId"`
+	Action      string    `json:"action"`
+	ProcessedAt time.Time `json:"processedAt"`
+}
+
+// Store is an append-only, file-based run history kept in a directory. Runs
+// and processed leads are stored as NDJSON; only runs are held in memory,
+// since the lead log grows with every lead processed.
+type Store struct {
+	dir string
+
+	mu    sync.Mutex
+	runs  []*Run
+	leads *os.File
+	out   *bufio.Writer
+}
+
+// Open opens (creating if needed) the history store in dir
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	s := &Store{dir: dir}
+
+	if err := readNDJSON(filepath.Join(dir, runsFile), func(decode func(interface{}) error) error {
+		var run Run
+		if err := decode(&run); err != nil {
+			return err
+		}
+		s.runs = append(s.runs, &run)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	leads, err := os.OpenFile(filepath.Join(dir, leadsFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lead history: %w", err)
+	}
+	s.leads = leads
+	s.out = bufio.NewWriter(leads)
+
+	return s, nil
+}
+
+// StartRun creates a new run with a fresh ID; it is persisted by FinishRun
+func (s *Store) StartRun(file string) *Run {
+	return &Run{
+		ID:        newRunID(),
+		File:      file,
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+// FinishRun stamps the run's finish time and appends it to the run log
+func (s *Store) FinishRun(run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.FinishedAt = time.Now().UTC()
+	if err := appendNDJSON(filepath.Join(s.dir, runsFile), run); err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+
+	return s.out.Flush()
+}
+
+// Runs returns all recorded runs, oldest first
+func (s *Store) Runs() []*Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Run(nil), s.runs...)
+}
+
+// FindRunByFileHash returns the most recent run that processed a file with
+// the given hash, or nil if none did. Paused runs don't count, since the
+// file is still being worked through.
+func (s *Store) FindRunByFileHash(fileHash string) *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		if s.runs[i].FileHash == fileHash && !s.runs[i].Paused {
+			return s.runs[i]
+		}
+	}
+	return nil
+}
+
+// RecentRuns returns up to n of the feed's latest finished runs, newest
+// first. Paused runs only cover part of their file, so they are left out.
+func (s *Store) RecentRuns(feed string, n int) []*Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []*Run
+	for i := len(s.runs) - 1; i >= 0 && len(runs) < n; i-- {
+		if s.runs[i].Feed == feed && !s.runs[i].Paused {
+			runs = append(runs, s.runs[i])
+		}
+	}
+	return runs
+}
+
+// FeedOf names the feed of a file after its base name with every run of
+// digits replaced by #, so leads-2026-10-15.csv and leads-2026-10-16.csv
+// share the feed leads-#-#-#.csv
+func FeedOf(file string) string {
+	return digits.ReplaceAllString(filepath.Base(file), "#")
+}
+
+// RecordLead remembers that a lead was successfully processed in a run
+func (s *Store) RecordLead(runID, email, contentHash, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := LeadRecord{
+		Email:       email,
+		ContentHash: contentHash,
+		RunID:       runID,
+		Action:      action,
+		ProcessedAt: time.Now().UTC(),
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := s.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write lead history: %w", err)
+	}
+
+	return nil
+}
+
+// EachLead calls fn with every lead record, oldest first. A lead processed
+// in several runs has a record per run. The log is read as it goes, so it is
+// never held in memory whole.
+func (s *Store) EachLead(fn func(LeadRecord)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.out.Flush(); err != nil {
+		return fmt.Errorf("failed to write lead history: %w", err)
+	}
+	return readNDJSON(filepath.Join(s.dir, leadsFile), func(decode func(interface{}) error) error {
+		var record LeadRecord
+		if err := decode(&record); err != nil {
+			return err
+		}
+		fn(record)
+		return nil
+	})
+}
+
+// Close flushes pending writes and closes the store
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.out.Flush(); err != nil {
+		s.leads.Close()
+		return err
+	}
+	return s.leads.Close()
+}
+
+// HashFile returns the hex SHA-256 of the file at path
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newRunID() string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(buf)
+}
+
+// readNDJSON calls fn once per line of path; a missing file is treated as empty
+func readNDJSON(path string, fn func(decode func(interface{}) error) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if err := fn(func(v interface{}) error { return json.Unmarshal(line, v) }); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// appendNDJSON appends v as one JSON line to path
+func appendNDJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
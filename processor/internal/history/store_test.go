@@ -0,0 +1,140 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("persists runs and processed leads across reopen", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		store, err := Open(dir)
+		assert.NoError(t, err)
+
+		run := store.StartRun("leads.csv")
+		run.Total = 1
+		run.Created = 1
+
+		// Act
+		assert.NoError(t, store.RecordLead(run.ID, "Alice@Example.com", "hash-1", "CREATE"))
+		assert.NoError(t, store.FinishRun(run))
+		assert.NoError(t, store.Close())
+		reopened, err := Open(dir)
+
+		// Assert
+		assert.NoError(t, err)
+		defer reopened.Close()
+		runs := reopened.Runs()
+		assert.Len(t, runs, 1)
+		assert.Equal(t, run.ID, runs[0].ID)
+		assert.Equal(t, 1, runs[0].Created)
+		var records []LeadRecord
+		assert.NoError(t, reopened.EachLead(func(record LeadRecord) { records = append(records, record) }))
+		assert.Len(t, records, 1)
+		assert.Equal(t, "Alice@Example.com", records[0].Email)
+		assert.Equal(t, "hash-1", records[0].ContentHash)
+	})
+
+	t.Run("streams every record, including ones not yet flushed", func(t *testing.T) {
+		// Arrange
+		store, err := Open(t.TempDir())
+		assert.NoError(t, err)
+		defer store.Close()
+		store.RecordLead("run-1", "bob@startup.com", "hash-1", "CREATE")
+		store.RecordLead("run-2", "bob@startup.com", "hash-2", "UPDATE")
+
+		// Act
+		var hashes []string
+		err = store.EachLead(func(record LeadRecord) { hashes = append(hashes, record.ContentHash) })
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"hash-1", "hash-2"}, hashes)
+	})
+}
+
+func TestStore_FindRunByFileHash(t *testing.T) {
+	t.Run("finds the latest run for an identical file", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		path := filepath.Join(dir, "leads.csv")
+		os.WriteFile(path, []byte("Name,Email,Company,Source\n"), 0o644)
+		fileHash, err := HashFile(path)
+		assert.NoError(t, err)
+
+		store, err := Open(filepath.Join(dir, "history"))
+		assert.NoError(t, err)
+		defer store.Close()
+
+		first := store.StartRun(path)
+		first.FileHash = fileHash
+		store.FinishRun(first)
+		second := store.StartRun(path)
+		second.FileHash = fileHash
+		store.FinishRun(second)
+
+		// Act
+		found := store.FindRunByFileHash(fileHash)
+		missing := store.FindRunByFileHash("other")
+
+		// Assert
+		assert.Equal(t, second.ID, found.ID)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("ignores paused runs", func(t *testing.T) {
+		// Arrange
+		store, err := Open(t.TempDir())
+		assert.NoError(t, err)
+		defer store.Close()
+
+		run := store.StartRun("leads.csv")
+		run.FileHash = "abc"
+		run.Paused = true
+		store.FinishRun(run)
+
+		// Act
+		found := store.FindRunByFileHash("abc")
+
+		// Assert
+		assert.Nil(t, found)
+	})
+}
+
+func TestStore_RecentRuns(t *testing.T) {
+	t.Run("returns the feed's latest finished runs, newest first", func(t *testing.T) {
+		// Arrange
+		store, err := Open(t.TempDir())
+		assert.NoError(t, err)
+		defer store.Close()
+
+		for i, file := range []string{"acme-01.csv", "other.csv", "acme-02.csv", "acme-03.csv", "acme-04.csv"} {
+			run := store.StartRun(file)
+			run.Feed = FeedOf(file)
+			run.Total = i
+			run.Paused = file == "acme-04.csv"
+			store.FinishRun(run)
+		}
+
+		// Act
+		runs := store.RecentRuns("acme-#.csv", 2)
+
+		// Assert
+		assert.Len(t, runs, 2)
+		assert.Equal(t, 3, runs[0].Total)
+		assert.Equal(t, 2, runs[1].Total)
+	})
+}
+
+func TestFeedOf(t *testing.T) {
+	t.Run("groups dated files of one feed", func(t *testing.T) {
+		// Assert
+		assert.Equal(t, "leads-#-#-#.csv", FeedOf("/exports/leads-2026-10-15.csv"))
+		assert.Equal(t, FeedOf("acme_20261015.csv"), FeedOf("acme_20261016.csv"))
+		assert.Equal(t, "leads.csv", FeedOf("leads.csv"))
+	})
+}
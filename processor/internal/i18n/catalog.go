@@ -0,0 +1,434 @@
+package i18n
+
+// catalogs maps English messages to their translations. Format verbs must
+// stay in the same order as in the English message.
+var catalogs = map[Language]map[string]string{
+	German: {
+		// CLI
+		"Lead Processor CLI initialized": "Lead Processor CLI initialisiert",
+		"Processing leads from: %s\n":    "Verarbeite Leads aus: %s\n",
+		"API URL: %s\n":                  "API-URL: %s\n",
+		"Profile: %s\n":                  "Profil: %s\n",
+		"Labels: %s\n":                   "Labels: %s\n",
+		"Pre-flight: looking up a sample of %d lead(s)...\n":                                                                  "Vorabprüfung: Stichprobe von %d Lead(s) wird nachgeschlagen...\n",
+		"Pre-flight: %d create(s), %d update(s), %d skip(s), %d invalid (%.1f%%), %d conflict(s) (%.1f%%), %d API error(s)\n": "Vorabprüfung: %d Neuanlage(n), %d Aktualisierung(en), %d übersprungen, %d ungültig (%.1f%%), %d Konflikt(e) (%.1f%%), %d API-Fehler\n",
+		"Reconciliation: waiting %s for the CRM to settle...\n":                                                               "Abgleich: %s warten, bis das CRM nachzieht...\n",
+		"Reconciliation: looking up %d written lead(s) again...\n":                                                            "Abgleich: %d geschriebene(r) Lead(s) wird erneut nachgeschlagen...\n",
+		"Reconciliation: %d checked, %d mismatch(es), %d lookup(s) failed\n":                                                  "Abgleich: %d geprüft, %d Abweichung(en), %d Abfrage(n) fehlgeschlagen\n",
+		"Profile of %s\n":                      "Profil von %s\n",
+		"Conformance of %s\n":                  "Konformität von %s\n",
+		"\n%d passed, %d failed, %d skipped\n": "\n%d bestanden, %d fehlgeschlagen, %d übersprungen\n",
+		"Rows: %d\n":                           "Zeilen: %d\n",
+		"Invalid emails: %d (%.1f%%)\n":        "Ungültige E-Mails: %d (%.1f%%)\n",
+		"Duplicate emails: %d\n":               "Doppelte E-Mails: %d\n",
+		"Invalid sources: %d\n":                "Ungültige Quellen: %d\n",
+		"\n=== Sources ===\n":                  "\n=== Quellen ===\n",
+		"\n=== Columns ===\n":                  "\n=== Spalten ===\n",
+		"COLUMN":                               "SPALTE",
+		"FILLED":                               "GEFÜLLT",
+		"DISTINCT":                             "EINDEUTIG",
+		"TOP VALUES":                           "HÄUFIGSTE WERTE",
+		"(empty)":                              "(leer)",
+		"RUN":                                  "LAUF",
+		"STARTED":                              "GESTARTET",
+		"FILE":                                 "DATEI",
+		"CREATED":                              "ERSTELLT",
+		"UPDATED":                              "AKTUALISIERT",
+		"SKIPPED":                              "ÜBERSPRUNGEN",
+		"ERRORS":                               "FEHLER",
+		"LABELS":                               "LABELS",
+		"No runs found\n":                      "Keine Läufe gefunden\n",
+		"%d run(s) labelled %s: %d created, %d updated, %d skipped, %d errors\n":  "%d Lauf/Läufe mit Label %s: %d erstellt, %d aktualisiert, %d übersprungen, %d Fehler\n",
+		"Warning: this file was already processed in run %s on %s\n":              "Warnung: Diese Datei wurde bereits in Lauf %s am %s verarbeitet\n",
+		"Outside the run window (%s), waiting until %s\n":                         "Außerhalb des Laufzeitfensters (%s), warte bis %s\n",
+		"Resuming from checkpoint: %d lead(s) already processed\n":                "Setze ab Checkpoint fort: %d Lead(s) bereits verarbeitet\n",
+		"Warning: ignoring %s, it was saved for a different file or --order-by\n": "Warnung: %s wird ignoriert, er wurde für eine andere Datei oder --order-by gespeichert\n",
+		"Warning: ignoring %d of %d columns: %s\n":                                "Warnung: %d von %d Spalten werden ignoriert: %s\n",
+		"CSV dialect: delimiter %s, quote %c, first row is the header\n":          "CSV-Dialekt: Trennzeichen %s, Anführungszeichen %c, die erste Zeile ist die Kopfzeile\n",
+		"CSV dialect: delimiter %s, quote %c, no header row\n":                    "CSV-Dialekt: Trennzeichen %s, Anführungszeichen %c, keine Kopfzeile\n",
+		"Header of %s differs from the schema of profile %s:\n":                   "Kopfzeile von %s weicht vom Schema des Profils %s ab:\n",
+		"  expected: %s\n": "  erwartet: %s\n",
+		"  actual:   %s\n": "  gelesen:  %s\n",
+		"Warning: %s has %d columns; --project-columns keeps only the ones this run reads\n": "Warnung: %s hat %d Spalten; --project-columns behält nur die, die dieser Lauf liest\n",
+		"%s and %d more": "%s und %d weitere",
+		"Warning: failed to export results: %v\n": "Warnung: Ergebnisse konnten nicht exportiert werden: %v\n",
+		"Results exported to %s\n":                "Ergebnisse exportiert nach %s\n",
+		"Paused: %d lead(s) of the file are done; run the same command again to resume\n": "Pausiert: %d Lead(s) der Datei sind erledigt; denselben Befehl erneut ausführen, um fortzusetzen\n",
+		"Reading leads from CSV file...\n":                                                "Lese Leads aus CSV-Datei...\n",
+		"Processed lead %d: %s (%s)\n":                                                    "Lead %d verarbeitet: %s (%s)\n",
+		"queues: %s":                                                                      "Warteschlangen: %s",
+		"Error: %v":                                                                       "Fehler: %v",
+		"Created new lead":                                                                "Neuer Lead angelegt",
+		"Updated existing lead":                                                           "Bestehender Lead aktualisiert",
+		"Skipped (%s)":                                                                    "Übersprungen (%s)",
+		"Skipped (no changes needed)":                                                     "Übersprungen (keine Änderungen nötig)",
+		"Deferred (run budget exhausted)":                                                 "Zurückgestellt (Laufbudget erschöpft)",
+		"Validation error: %s":                                                            "Validierungsfehler: %s",
+		"Status conflict: %v":                                                             "Statuskonflikt: %v",
+		"API error: %v":                                                                   "API-Fehler: %v",
+		"Rolled back: %v":                                                                 "Zurückgenommen: %v",
+		"Rollback failed: %v":                                                             "Zurücknahme fehlgeschlagen: %v",
+		"Sync failed: %v":                                                                 "Synchronisierung fehlgeschlagen: %v",
+		"Unknown action: %s":                                                              "Unbekannte Aktion: %s",
+		"Listening on %s\n":                                                               "Lausche auf %s\n",
+		"Erasing %d lead(s) (%s) via %s\n":                                                "Lösche %d Lead(s) (%s) über %s\n",
+		"%s: erased lead %s":                                                              "%s: Lead %s gelöscht",
+		"%s: anonymized and archived lead %s":                                             "%s: Lead %s anonymisiert und archiviert",
+		"%s: anonymized lead %s, but not archived":                                        "%s: Lead %s anonymisiert, aber nicht archiviert",
+		"Warning: the server cannot archive leads, so they will be deleted permanently instead\n": "Warnung: Der Server kann keine Leads archivieren, sie werden stattdessen endgültig gelöscht\n",
+		"%s: no matching lead":                    "%s: kein passender Lead",
+		"Would merge lead %s into lead %s:\n":     "Lead %s würde in Lead %s zusammengeführt:\n",
+		"Merged lead %s into lead %s":             "Lead %s in Lead %s zusammengeführt",
+		" (from duplicate)":                       " (aus Duplikat)",
+		"A newer release is available: %s (%s)\n": "Eine neuere Version ist verfügbar: %s (%s)\n",
+		"No newer release found (latest is %s)\n": "Keine neuere Version gefunden (aktuell ist %s)\n",
+
+		// Skip reasons
+		"already processed in a previous run":        "bereits in einem früheren Lauf verarbeitet",
+		"only protected fields differ":               "nur geschützte Felder weichen ab",
+		"the create-only policy never updates leads": "die Richtlinie create-only aktualisiert keine Leads",
+		"the update-only policy never creates leads": "die Richtlinie update-only legt keine Leads an",
+		"only fields outside the policy differ":      "nur Felder außerhalb der Richtlinie weichen ab",
+		"Held for review (%s)":                       "Zur Prüfung zurückgehalten (%s)",
+		"Held for review: %d (written to %s)\n":      "Zur Prüfung zurückgehalten: %d (geschrieben nach %s)\n",
+
+		// Report labels
+		"\n=== Processing Summary ===\n":                     "\n=== Verarbeitungsübersicht ===\n",
+		"Total leads: %d\n":                                  "Leads gesamt: %d\n",
+		"Filtered out: %d\n":                                 "Herausgefiltert: %d\n",
+		"Created: %d\n":                                      "Angelegt: %d\n",
+		"Updated: %d\n":                                      "Aktualisiert: %d\n",
+		"Skipped: %d\n":                                      "Übersprungen: %d\n",
+		"Deferred: %d (written to %s)\n":                     "Zurückgestellt: %d (geschrieben nach %s)\n",
+		"Rejected: %d (written to %s)\n":                     "Abgewiesen: %d (geschrieben nach %s)\n",
+		"Rejected: %d (split by %s into %d files)\n":         "Abgewiesen: %d (nach %s auf %d Dateien aufgeteilt)\n",
+		"Dead-lettered: %d (written to %s)\n":                "In die Dead-Letter-Queue: %d (geschrieben nach %s)\n",
+		"Warning: failed to write dead-letter entries: %v\n": "Warnung: Dead-Letter-Einträge konnten nicht geschrieben werden: %v\n",
+		"Rolled back: %d\n":                                  "Zurückgenommen: %d\n",
+		"Sync failures: %d\n":                                "Synchronisierungsfehler: %d\n",
+		"Errors: %d\n":                                       "Fehler: %d\n",
+		"Lookups answered by the not-found cache: %d\n":      "Vom Nicht-gefunden-Cache beantwortete Abfragen: %d\n",
+		"Top error causes:\n":                                "Häufigste Fehlerursachen:\n",
+		"Top failing domains:\n":                             "Domains mit den meisten Fehlern:\n",
+		"%s: %d (mostly %s: %d)":                             "%s: %d (meist %s: %d)",
+		"\n=== Anomalies (feed %s) ===\n":                    "\n=== Auffälligkeiten (Feed %s) ===\n",
+		"Rows: %.0f, usually %.0f\n":                         "Zeilen: %.0f, üblich %.0f\n",
+		"Created: %.0f, usually %.0f\n":                      "Erstellt: %.0f, üblich %.0f\n",
+		"File size: %s, usually %s\n":                        "Dateigröße: %s, üblich %s\n",
+		"Error rate: %.1f%%, usually %.1f%%\n":               "Fehlerquote: %.1f%%, üblich %.1f%%\n",
+		"Errors by class: %s\n":                              "Fehler nach Klasse: %s\n",
+		"Reconciled %d interrupted request(s) from an earlier run: %d applied, %d not applied\n":     "%d unterbrochene Anfrage(n) eines früheren Laufs abgeglichen: %d übernommen, %d nicht übernommen\n",
+		"Stopped early: %d retries and %s spent retrying; the API looks degraded, try again later\n": "Vorzeitig beendet: %d Wiederholungen und %s mit Wiederholen verbracht; die API scheint gestört, später erneut versuchen\n",
+		"Sources inferred: %d\n":                                     "Quellen abgeleitet: %d\n",
+		"Territories assigned: %d\n":                                 "Gebiete zugewiesen: %d\n",
+		"Languages detected: %d\n":                                   "Sprachen erkannt: %d\n",
+		"API calls: %d (%s)\n":                                       "API-Aufrufe: %d (%s)\n",
+		"Data transferred: %s sent, %s received\n":                   "Übertragene Daten: %s gesendet, %s empfangen\n",
+		"Estimated cost: %.4f %s\n":                                  "Geschätzte Kosten: %.4f %s\n",
+		"Peak queue depths: %s\n":                                    "Maximale Warteschlangentiefen: %s\n",
+		"Results were spilled to disk to stay within --max-memory\n": "Ergebnisse wurden auf die Festplatte ausgelagert, um --max-memory einzuhalten\n",
+		"\n=== Failed Leads ===\n":                                   "\n=== Fehlgeschlagene Leads ===\n",
+		"\n=== Erasure Summary ===\n":                                "\n=== Löschübersicht ===\n",
+		"Erased: %d\n":                                               "Gelöscht: %d\n",
+		"Not found: %d\n":                                            "Nicht gefunden: %d\n",
+		"Failed: %d\n":                                               "Fehlgeschlagen: %d\n",
+		"Audit log: %s\n":                                            "Prüfprotokoll: %s\n",
+		"Replaying %d of %d dead-lettered lead(s) from %s\n":         "%d von %d Dead-Letter-Lead(s) aus %s werden erneut verarbeitet\n",
+		"\n=== Replay Summary ===\n":                                 "\n=== Übersicht der erneuten Verarbeitung ===\n",
+		"%s: created":                                                "%s: angelegt",
+		"%s: updated":                                                "%s: aktualisiert",
+		"%s: no changes needed":                                      "%s: keine Änderungen nötig",
+		"Comparing %s with %s\n":                                     "%s wird mit %s verglichen\n",
+		"\n=== Delta Summary ===\n":                                  "\n=== Änderungsübersicht ===\n",
+		"Added: %d\n":                                                "Hinzugekommen: %d\n",
+		"Changed: %d\n":                                              "Geändert: %d\n",
+		"Removed: %d\n":                                              "Entfallen: %d\n",
+		"Unchanged: %d\n":                                            "Unverändert: %d\n",
+		"Delta written to: %s (%d leads)\n":                          "Änderungen geschrieben nach: %s (%d Leads)\n",
+		"\nProcessing %d added or changed lead(s)\n":                 "\n%d hinzugekommene oder geänderte Lead(s) werden verarbeitet\n",
+		"%s: added":                                                  "%s: hinzugekommen",
+		"%s: changed %s":                                             "%s: geändert %s",
+		"%s: not in the mirror":                                      "%s: nicht im Spiegel",
+		"%s: no longer in the CRM":                                   "%s: nicht mehr im CRM",
+		"Comparing %s (synced %s) with the CRM\n":                    "Vergleiche %s (synchronisiert %s) mit dem CRM\n",
+		"\n=== Drift Summary ===\n":                                  "\n=== Abweichungsübersicht ===\n",
+		"Not in the mirror: %d\n":                                    "Nicht im Spiegel: %d\n",
+		"No longer in the CRM: %d\n":                                 "Nicht mehr im CRM: %d\n",
+		"%s: removed":                                                "%s: entfallen",
+		"Syncing %s with the CRM\n":                                  "%s wird mit dem CRM synchronisiert\n",
+		"%s: %s changed on both sides, kept the file's %q":           "%s: %s auf beiden Seiten geändert, %q aus der Datei übernommen",
+		"%s: %s changed on both sides, kept the CRM's %q":            "%s: %s auf beiden Seiten geändert, %q aus dem CRM beibehalten",
+		"%s: would create":                                           "%s: würde angelegt",
+		"%s: would update":                                           "%s: würde aktualisiert",
+		"\n=== Sync Summary ===\n":                                   "\n=== Synchronisationsübersicht ===\n",
+		"Conflicts: %d\n":                                            "Konflikte: %d\n",
+		"CRM changes to export: %d\n":                                "Zu exportierende CRM-Änderungen: %d\n",
+		"CRM changes exported: %d (to %s)\n":                         "Exportierte CRM-Änderungen: %d (nach %s)\n",
+		"Mirrored %d lead(s) to %s (%d removed)\n":                   "%d Lead(s) nach %s gespiegelt (%d entfernt)\n",
+		"Refreshed %d lead(s) in %s, which now holds %d\n":           "%d Lead(s) in %s aktualisiert, das jetzt %d enthält\n",
+		"Built a bloom filter of %d email(s) in %s (%s)\n":           "Bloom-Filter mit %d E-Mail(s) in %s erstellt (%s)\n",
+		"Added %d email(s) to %s, which now holds %d\n":              "%d E-Mail(s) zu %s hinzugefügt, das jetzt %d enthält\n",
+		"Warning: %s holds more emails than it was sized for; rebuild it with --full\n": "Warnung: %s enthält mehr E-Mails als vorgesehen; mit --full neu erstellen\n",
+		"Lookups skipped by the bloom filter: %d\n":                                     "Durch den Bloom-Filter übersprungene Abfragen: %d\n",
+		"Events written to: %s (%d)\n":                                                  "Ereignisse geschrieben nach: %s (%d)\n",
+		"Warning: failed to write trace events: %v\n":                                   "Warnung: Trace-Ereignisse konnten nicht geschrieben werden: %v\n",
+		"\n%d passed, %d warning(s), %d failed\n":                                       "\n%d bestanden, %d Warnung(en), %d fehlgeschlagen\n",
+		"%s: changed":      "%s: geändert",
+		"the dedupe index": "dem Dedupe-Index",
+		"No dedupe index yet, it is built by the next process run or dedupe rebuild\n": "Noch kein Dedupe-Index, er wird beim nächsten process-Lauf oder mit dedupe rebuild erstellt\n",
+		"EMAIL":            "E-MAIL",
+		"LEAD ID":          "LEAD-ID",
+		"LAST ACTION":      "LETZTE AKTION",
+		"LAST SEEN":        "ZULETZT GESEHEN",
+		"not in the index": "nicht im Index",
+		"Dedupe index: %d lead(s), %d with a lead ID, updated %s\n":   "Dedupe-Index: %d Lead(s), %d mit Lead-ID, aktualisiert %s\n",
+		"Rebuilt the dedupe index from the run history: %d lead(s)\n": "Dedupe-Index aus dem Laufverlauf neu erstellt: %d Lead(s)\n",
+		"Lead IDs filled in from %s: %d\n":                            "Lead-IDs aus %s ergänzt: %d\n",
+		"Checking %s against the dedupe index (%d leads)\n":           "Prüfe %s gegen den Dedupe-Index (%d Leads)\n",
+		"%s: repeated in the file":                                    "%s: in der Datei wiederholt",
+		"%s: processed %s (%s, lead %s)":                              "%s: verarbeitet %s (%s, Lead %s)",
+		"%s: processed %s (%s, lead %s), changed since":               "%s: verarbeitet %s (%s, Lead %s), seitdem geändert",
+		"\n=== Dedupe Summary ===\n":                                  "\n=== Dedupe-Übersicht ===\n",
+		"New: %d\n":                                                   "Neu: %d\n",
+		"Already processed, unchanged: %d\n":                          "Bereits verarbeitet, unverändert: %d\n",
+		"Already processed, changed: %d\n":                            "Bereits verarbeitet, geändert: %d\n",
+		"Repeated in the file: %d\n":                                  "In der Datei wiederholt: %d\n",
+		"Running pipeline %s\n":                                       "Pipeline %s wird ausgeführt\n",
+		"The config has no pipelines\n":                               "Die Konfiguration hat keine Pipelines\n",
+		"PIPELINE":                                                    "PIPELINE",
+		"INPUT":                                                       "EINGABE",
+		"DESCRIPTION":                                                 "BESCHREIBUNG",
+		"Warning: failed to notify a webhook of pipeline %s: %v\n":    "Warnung: ein Webhook der Pipeline %s konnte nicht benachrichtigt werden: %v\n",
+
+		// Validation
+		"name is required":                                         "Name ist erforderlich",
+		"valid email is required":                                  "gültige E-Mail-Adresse ist erforderlich",
+		"email domain %s does not exist":                           "E-Mail-Domain %s existiert nicht",
+		"email domain %s does not accept mail":                     "E-Mail-Domain %s nimmt keine E-Mails an",
+		"mail server rejected %s":                                  "Mailserver hat %s abgelehnt",
+		"%s is not valid UTF-8 (check the file's encoding)":        "%s ist kein gültiges UTF-8 (Kodierung der Datei prüfen)",
+		"%s is longer than %d characters":                          "%s ist länger als %d Zeichen",
+		"company is required":                                      "Firma ist erforderlich",
+		"source must be one of: %s":                                "Quelle muss eine der folgenden sein: %s",
+		"status must be one of: %s":                                "Status muss einer der folgenden sein: %s",
+		"consent timestamp cannot be in the future":                "Einwilligungszeitpunkt darf nicht in der Zukunft liegen",
+		"consent timestamp and source require consent to be given": "Einwilligungszeitpunkt und -quelle setzen eine erteilte Einwilligung voraus",
+		"consent is required":                                      "Einwilligung ist erforderlich",
+		"consent must be yes or no, not %q":                        "Einwilligung muss yes oder no sein, nicht %q",
+		"consent timestamp %q is not RFC 3339 or YYYY-MM-DD":       "Einwilligungszeitpunkt %q ist weder RFC 3339 noch JJJJ-MM-TT",
+	},
+	French: {
+		// CLI
+		"Lead Processor CLI initialized": "Lead Processor CLI initialisé",
+		"Processing leads from: %s\n":    "Traitement des leads depuis : %s\n",
+		"API URL: %s\n":                  "URL de l'API : %s\n",
+		"Profile: %s\n":                  "Profil : %s\n",
+		"Labels: %s\n":                   "Libellés : %s\n",
+		"Pre-flight: looking up a sample of %d lead(s)...\n":                                                                  "Pré-vérification : recherche d'un échantillon de %d lead(s)...\n",
+		"Pre-flight: %d create(s), %d update(s), %d skip(s), %d invalid (%.1f%%), %d conflict(s) (%.1f%%), %d API error(s)\n": "Pré-vérification : %d création(s), %d mise(s) à jour, %d ignoré(s), %d invalide(s) (%.1f%%), %d conflit(s) (%.1f%%), %d erreur(s) d'API\n",
+		"Reconciliation: waiting %s for the CRM to settle...\n":                                                               "Rapprochement : attente de %s que le CRM se stabilise...\n",
+		"Reconciliation: looking up %d written lead(s) again...\n":                                                            "Rapprochement : nouvelle recherche de %d lead(s) écrit(s)...\n",
+		"Reconciliation: %d checked, %d mismatch(es), %d lookup(s) failed\n":                                                  "Rapprochement : %d vérifié(s), %d écart(s), %d recherche(s) en échec\n",
+		"Profile of %s\n":                      "Profil de %s\n",
+		"Conformance of %s\n":                  "Conformité de %s\n",
+		"\n%d passed, %d failed, %d skipped\n": "\n%d réussi(s), %d échoué(s), %d ignoré(s)\n",
+		"Rows: %d\n":                           "Lignes : %d\n",
+		"Invalid emails: %d (%.1f%%)\n":        "E-mails invalides : %d (%.1f%%)\n",
+		"Duplicate emails: %d\n":               "E-mails en double : %d\n",
+		"Invalid sources: %d\n":                "Sources invalides : %d\n",
+		"\n=== Sources ===\n":                  "\n=== Sources ===\n",
+		"\n=== Columns ===\n":                  "\n=== Colonnes ===\n",
+		"COLUMN":                               "COLONNE",
+		"FILLED":                               "REMPLI",
+		"DISTINCT":                             "DISTINCTES",
+		"TOP VALUES":                           "VALEURS FRÉQUENTES",
+		"(empty)":                              "(vide)",
+		"RUN":                                  "EXÉCUTION",
+		"STARTED":                              "DÉBUT",
+		"FILE":                                 "FICHIER",
+		"CREATED":                              "CRÉÉS",
+		"UPDATED":                              "MIS À JOUR",
+		"SKIPPED":                              "IGNORÉS",
+		"ERRORS":                               "ERREURS",
+		"LABELS":                               "LIBELLÉS",
+		"No runs found\n":                      "Aucune exécution trouvée\n",
+		"%d run(s) labelled %s: %d created, %d updated, %d skipped, %d errors\n":  "%d exécution(s) avec le libellé %s : %d créés, %d mis à jour, %d ignorés, %d erreurs\n",
+		"Warning: this file was already processed in run %s on %s\n":              "Attention : ce fichier a déjà été traité lors de l'exécution %s le %s\n",
+		"Outside the run window (%s), waiting until %s\n":                         "En dehors de la plage d'exécution (%s), attente jusqu'à %s\n",
+		"Resuming from checkpoint: %d lead(s) already processed\n":                "Reprise depuis le point de contrôle : %d lead(s) déjà traité(s)\n",
+		"Warning: ignoring %s, it was saved for a different file or --order-by\n": "Attention : %s est ignoré, il a été enregistré pour un autre fichier ou un autre --order-by\n",
+		"Warning: ignoring %d of %d columns: %s\n":                                "Attention : %d colonnes sur %d sont ignorées : %s\n",
+		"CSV dialect: delimiter %s, quote %c, first row is the header\n":          "Dialecte CSV : séparateur %s, guillemet %c, la première ligne est l'en-tête\n",
+		"CSV dialect: delimiter %s, quote %c, no header row\n":                    "Dialecte CSV : séparateur %s, guillemet %c, pas de ligne d'en-tête\n",
+		"Header of %s differs from the schema of profile %s:\n":                   "L'en-tête de %s diffère du schéma du profil %s :\n",
+		"  expected: %s\n": "  attendu : %s\n",
+		"  actual:   %s\n": "  lu :      %s\n",
+		"Warning: %s has %d columns; --project-columns keeps only the ones this run reads\n": "Attention : %s a %d colonnes ; --project-columns ne garde que celles que cette exécution lit\n",
+		"%s and %d more": "%s et %d autres",
+		"Warning: failed to export results: %v\n": "Attention : échec de l'export des résultats : %v\n",
+		"Results exported to %s\n":                "Résultats exportés vers %s\n",
+		"Paused: %d lead(s) of the file are done; run the same command again to resume\n": "En pause : %d lead(s) du fichier sont traités ; relancez la même commande pour reprendre\n",
+		"Reading leads from CSV file...\n":                                                "Lecture des leads depuis le fichier CSV...\n",
+		"Processed lead %d: %s (%s)\n":                                                    "Lead %d traité : %s (%s)\n",
+		"queues: %s":                                                                      "files d'attente : %s",
+		"Error: %v":                                                                       "Erreur : %v",
+		"Created new lead":                                                                "Nouveau lead créé",
+		"Updated existing lead":                                                           "Lead existant mis à jour",
+		"Skipped (%s)":                                                                    "Ignoré (%s)",
+		"Skipped (no changes needed)":                                                     "Ignoré (aucune modification nécessaire)",
+		"Deferred (run budget exhausted)":                                                 "Reporté (budget de l'exécution épuisé)",
+		"Validation error: %s":                                                            "Erreur de validation : %s",
+		"Status conflict: %v":                                                             "Conflit de statut : %v",
+		"API error: %v":                                                                   "Erreur d'API : %v",
+		"Rolled back: %v":                                                                 "Annulé : %v",
+		"Rollback failed: %v":                                                             "Échec de l'annulation : %v",
+		"Sync failed: %v":                                                                 "Échec de la synchronisation : %v",
+		"Unknown action: %s":                                                              "Action inconnue : %s",
+		"Listening on %s\n":                                                               "En écoute sur %s\n",
+		"Erasing %d lead(s) (%s) via %s\n":                                                "Effacement de %d lead(s) (%s) via %s\n",
+		"%s: erased lead %s":                                                              "%s : lead %s effacé",
+		"%s: anonymized and archived lead %s":                                             "%s : lead %s anonymisé et archivé",
+		"%s: anonymized lead %s, but not archived":                                        "%s : lead %s anonymisé, mais pas archivé",
+		"Warning: the server cannot archive leads, so they will be deleted permanently instead\n": "Avertissement : le serveur ne peut pas archiver les leads, ils seront supprimés définitivement à la place\n",
+		"%s: no matching lead":                    "%s : aucun lead correspondant",
+		"Would merge lead %s into lead %s:\n":     "Le lead %s serait fusionné dans le lead %s :\n",
+		"Merged lead %s into lead %s":             "Lead %s fusionné dans le lead %s",
+		" (from duplicate)":                       " (du doublon)",
+		"A newer release is available: %s (%s)\n": "Une version plus récente est disponible : %s (%s)\n",
+		"No newer release found (latest is %s)\n": "Aucune version plus récente (la dernière est %s)\n",
+
+		// Skip reasons
+		"already processed in a previous run":        "déjà traité lors d'une exécution précédente",
+		"only protected fields differ":               "seuls des champs protégés diffèrent",
+		"the create-only policy never updates leads": "la règle create-only ne met jamais à jour les leads",
+		"the update-only policy never creates leads": "la règle update-only ne crée jamais de leads",
+		"only fields outside the policy differ":      "seuls des champs hors de la règle diffèrent",
+		"Held for review (%s)":                       "Retenu pour vérification (%s)",
+		"Held for review: %d (written to %s)\n":      "Retenus pour vérification : %d (écrits dans %s)\n",
+
+		// Report labels
+		"\n=== Processing Summary ===\n":                     "\n=== Récapitulatif du traitement ===\n",
+		"Total leads: %d\n":                                  "Total des leads : %d\n",
+		"Filtered out: %d\n":                                 "Filtrés : %d\n",
+		"Created: %d\n":                                      "Créés : %d\n",
+		"Updated: %d\n":                                      "Mis à jour : %d\n",
+		"Skipped: %d\n":                                      "Ignorés : %d\n",
+		"Deferred: %d (written to %s)\n":                     "Reportés : %d (écrits dans %s)\n",
+		"Rejected: %d (written to %s)\n":                     "Rejetés : %d (écrits dans %s)\n",
+		"Rejected: %d (split by %s into %d files)\n":         "Rejetés : %d (répartis par %s dans %d fichiers)\n",
+		"Dead-lettered: %d (written to %s)\n":                "En file de lettres mortes : %d (écrits dans %s)\n",
+		"Warning: failed to write dead-letter entries: %v\n": "Attention : échec de l'écriture des lettres mortes : %v\n",
+		"Rolled back: %d\n":                                  "Annulés : %d\n",
+		"Sync failures: %d\n":                                "Échecs de synchronisation : %d\n",
+		"Errors: %d\n":                                       "Erreurs : %d\n",
+		"Lookups answered by the not-found cache: %d\n":      "Recherches servies par le cache des absents : %d\n",
+		"Top error causes:\n":                                "Principales causes d'erreur :\n",
+		"Top failing domains:\n":                             "Domaines avec le plus d'erreurs :\n",
+		"%s: %d (mostly %s: %d)":                             "%s : %d (surtout %s : %d)",
+		"\n=== Anomalies (feed %s) ===\n":                    "\n=== Anomalies (flux %s) ===\n",
+		"Rows: %.0f, usually %.0f\n":                         "Lignes : %.0f, habituellement %.0f\n",
+		"Created: %.0f, usually %.0f\n":                      "Créés : %.0f, habituellement %.0f\n",
+		"File size: %s, usually %s\n":                        "Taille du fichier : %s, habituellement %s\n",
+		"Error rate: %.1f%%, usually %.1f%%\n":               "Taux d'erreur : %.1f%%, habituellement %.1f%%\n",
+		"Errors by class: %s\n":                              "Erreurs par classe : %s\n",
+		"Reconciled %d interrupted request(s) from an earlier run: %d applied, %d not applied\n":     "%d requête(s) interrompue(s) d'une exécution précédente rapprochée(s) : %d appliquée(s), %d non appliquée(s)\n",
+		"Stopped early: %d retries and %s spent retrying; the API looks degraded, try again later\n": "Arrêt anticipé : %d nouvelles tentatives et %s passés à réessayer ; l'API semble dégradée, réessayez plus tard\n",
+		"Sources inferred: %d\n":                                     "Sources déduites : %d\n",
+		"Territories assigned: %d\n":                                 "Territoires attribués : %d\n",
+		"Languages detected: %d\n":                                   "Langues détectées : %d\n",
+		"API calls: %d (%s)\n":                                       "Appels API : %d (%s)\n",
+		"Data transferred: %s sent, %s received\n":                   "Données transférées : %s envoyés, %s reçus\n",
+		"Estimated cost: %.4f %s\n":                                  "Coût estimé : %.4f %s\n",
+		"Peak queue depths: %s\n":                                    "Profondeur maximale des files : %s\n",
+		"Results were spilled to disk to stay within --max-memory\n": "Les résultats ont été écrits sur disque pour respecter --max-memory\n",
+		"\n=== Failed Leads ===\n":                                   "\n=== Leads en échec ===\n",
+		"\n=== Erasure Summary ===\n":                                "\n=== Récapitulatif de l'effacement ===\n",
+		"Erased: %d\n":                                               "Effacés : %d\n",
+		"Not found: %d\n":                                            "Introuvables : %d\n",
+		"Failed: %d\n":                                               "En échec : %d\n",
+		"Audit log: %s\n":                                            "Journal d'audit : %s\n",
+		"Replaying %d of %d dead-lettered lead(s) from %s\n":         "Retraitement de %d lead(s) en file de lettres mortes sur %d depuis %s\n",
+		"\n=== Replay Summary ===\n":                                 "\n=== Récapitulatif du retraitement ===\n",
+		"%s: created":                                                "%s : créé",
+		"%s: updated":                                                "%s : mis à jour",
+		"%s: no changes needed":                                      "%s : aucune modification nécessaire",
+		"Comparing %s with %s\n":                                     "Comparaison de %s avec %s\n",
+		"\n=== Delta Summary ===\n":                                  "\n=== Récapitulatif des différences ===\n",
+		"Added: %d\n":                                                "Ajoutés : %d\n",
+		"Changed: %d\n":                                              "Modifiés : %d\n",
+		"Removed: %d\n":                                              "Retirés : %d\n",
+		"Unchanged: %d\n":                                            "Inchangés : %d\n",
+		"Delta written to: %s (%d leads)\n":                          "Différences écrites dans : %s (%d leads)\n",
+		"\nProcessing %d added or changed lead(s)\n":                 "\nTraitement de %d lead(s) ajouté(s) ou modifié(s)\n",
+		"%s: added":                                                  "%s : ajouté",
+		"%s: changed %s":                                             "%s : modifié %s",
+		"%s: not in the mirror":                                      "%s : absent du miroir",
+		"%s: no longer in the CRM":                                   "%s : plus dans le CRM",
+		"Comparing %s (synced %s) with the CRM\n":                    "Comparaison de %s (synchronisé %s) avec le CRM\n",
+		"\n=== Drift Summary ===\n":                                  "\n=== Récapitulatif des écarts ===\n",
+		"Not in the mirror: %d\n":                                    "Absents du miroir : %d\n",
+		"No longer in the CRM: %d\n":                                 "Plus dans le CRM : %d\n",
+		"%s: removed":                                                "%s : retiré",
+		"Syncing %s with the CRM\n":                                  "Synchronisation de %s avec le CRM\n",
+		"%s: %s changed on both sides, kept the file's %q":           "%s : %s modifié des deux côtés, %q du fichier retenu",
+		"%s: %s changed on both sides, kept the CRM's %q":            "%s : %s modifié des deux côtés, %q du CRM conservé",
+		"%s: would create":                                           "%s : serait créé",
+		"%s: would update":                                           "%s : serait mis à jour",
+		"\n=== Sync Summary ===\n":                                   "\n=== Résumé de la synchronisation ===\n",
+		"Conflicts: %d\n":                                            "Conflits : %d\n",
+		"CRM changes to export: %d\n":                                "Modifications du CRM à exporter : %d\n",
+		"CRM changes exported: %d (to %s)\n":                         "Modifications du CRM exportées : %d (vers %s)\n",
+		"Mirrored %d lead(s) to %s (%d removed)\n":                   "%d lead(s) copié(s) dans %s (%d supprimé(s))\n",
+		"Refreshed %d lead(s) in %s, which now holds %d\n":           "%d lead(s) actualisé(s) dans %s, qui en contient maintenant %d\n",
+		"Built a bloom filter of %d email(s) in %s (%s)\n":           "Filtre de Bloom de %d e-mail(s) créé dans %s (%s)\n",
+		"Added %d email(s) to %s, which now holds %d\n":              "%d e-mail(s) ajouté(s) à %s, qui en contient maintenant %d\n",
+		"Warning: %s holds more emails than it was sized for; rebuild it with --full\n": "Avertissement : %s contient plus d'e-mails que prévu ; reconstruisez-le avec --full\n",
+		"Lookups skipped by the bloom filter: %d\n":                                     "Recherches évitées par le filtre de Bloom : %d\n",
+		"Events written to: %s (%d)\n":                                                  "Événements écrits dans : %s (%d)\n",
+		"Warning: failed to write trace events: %v\n":                                   "Avertissement : échec de l'écriture des événements de trace : %v\n",
+		"\n%d passed, %d warning(s), %d failed\n":                                       "\n%d réussi(s), %d avertissement(s), %d en échec\n",
+		"%s: changed":      "%s : modifié",
+		"the dedupe index": "l'index de dédoublonnage",
+		"No dedupe index yet, it is built by the next process run or dedupe rebuild\n": "Pas encore d'index de dédoublonnage, il sera créé par la prochaine exécution de process ou par dedupe rebuild\n",
+		"EMAIL":            "E-MAIL",
+		"LEAD ID":          "ID DU LEAD",
+		"LAST ACTION":      "DERNIÈRE ACTION",
+		"LAST SEEN":        "VU LE",
+		"not in the index": "absent de l'index",
+		"Dedupe index: %d lead(s), %d with a lead ID, updated %s\n":   "Index de dédoublonnage : %d lead(s), %d avec un ID, mis à jour %s\n",
+		"Rebuilt the dedupe index from the run history: %d lead(s)\n": "Index de dédoublonnage reconstruit depuis l'historique : %d lead(s)\n",
+		"Lead IDs filled in from %s: %d\n":                            "ID de leads complétés depuis %s : %d\n",
+		"Checking %s against the dedupe index (%d leads)\n":           "Vérification de %s avec l'index de dédoublonnage (%d leads)\n",
+		"%s: repeated in the file":                                    "%s : répété dans le fichier",
+		"%s: processed %s (%s, lead %s)":                              "%s : traité %s (%s, lead %s)",
+		"%s: processed %s (%s, lead %s), changed since":               "%s : traité %s (%s, lead %s), modifié depuis",
+		"\n=== Dedupe Summary ===\n":                                  "\n=== Récapitulatif du dédoublonnage ===\n",
+		"New: %d\n":                                                   "Nouveaux : %d\n",
+		"Already processed, unchanged: %d\n":                          "Déjà traités, inchangés : %d\n",
+		"Already processed, changed: %d\n":                            "Déjà traités, modifiés : %d\n",
+		"Repeated in the file: %d\n":                                  "Répétés dans le fichier : %d\n",
+		"Running pipeline %s\n":                                       "Exécution du pipeline %s\n",
+		"The config has no pipelines\n":                               "La configuration n'a aucun pipeline\n",
+		"PIPELINE":                                                    "PIPELINE",
+		"INPUT":                                                       "ENTRÉE",
+		"DESCRIPTION":                                                 "DESCRIPTION",
+		"Warning: failed to notify a webhook of pipeline %s: %v\n":    "Avertissement : échec de la notification d'un webhook du pipeline %s : %v\n",
+
+		// Validation
+		"name is required":                                         "le nom est obligatoire",
+		"valid email is required":                                  "une adresse e-mail valide est obligatoire",
+		"email domain %s does not exist":                           "le domaine e-mail %s n'existe pas",
+		"email domain %s does not accept mail":                     "le domaine e-mail %s n'accepte pas de courrier",
+		"mail server rejected %s":                                  "le serveur de messagerie a refusé %s",
+		"%s is not valid UTF-8 (check the file's encoding)":        "%s n'est pas de l'UTF-8 valide (vérifiez l'encodage du fichier)",
+		"%s is longer than %d characters":                          "%s dépasse %d caractères",
+		"company is required":                                      "l'entreprise est obligatoire",
+		"source must be one of: %s":                                "la source doit être l'une des suivantes : %s",
+		"status must be one of: %s":                                "le statut doit être l'un des suivants : %s",
+		"consent timestamp cannot be in the future":                "la date du consentement ne peut pas être dans le futur",
+		"consent timestamp and source require consent to be given": "la date et la source du consentement exigent un consentement donné",
+		"consent is required":                                      "le consentement est obligatoire",
+		"consent must be yes or no, not %q":                        "le consentement doit être yes ou no, pas %q",
+		"consent timestamp %q is not RFC 3339 or YYYY-MM-DD":       "la date du consentement %q n'est ni au format RFC 3339 ni AAAA-MM-JJ",
+	},
+}
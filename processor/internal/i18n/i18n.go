@@ -0,0 +1,120 @@
+package i18n
+
+import (
+	"code/internal/models"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Language is a supported output language, identified by its ISO 639-1 code
+type Language string
+
+// Supported languages
+const (
+	English Language = "en"
+	German  Language = "de"
+	French  Language = "fr"
+)
+
+// Supported returns every language with a message catalog
+func Supported() []Language {
+	return []Language{English, German, French}
+}
+
+// ParseLanguage parses a language code or locale such as "de" or "fr_FR.UTF-8"
+func ParseLanguage(value string) (Language, error) {
+	code := strings.ToLower(strings.TrimSpace(value))
+	if i := strings.IndexAny(code, "_.-@"); i >= 0 {
+		code = code[:i]
+	}
+
+	for _, lang := range Supported() {
+		if Language(code) == lang {
+			return lang, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported language %q (supported: en, de, fr)", value)
+}
+
+// Detect picks the language from an explicit --lang value, falling back to
+// the LC_ALL, LC_MESSAGES and LANG environment variables, then English.
+// Only the explicit value is an error when unsupported.
+func Detect(flag string) (Language, error) {
+	if flag != "" {
+		return ParseLanguage(flag)
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if lang, err := ParseLanguage(value); err == nil {
+			return lang, nil
+		}
+		// The first locale variable that is set wins, as in POSIX
+		break
+	}
+
+	return English, nil
+}
+
+// Printer formats messages in one language. Messages are looked up by their
+// English text; anything missing from the catalog is printed in English.
+type Printer struct {
+	lang     Language
+	messages map[string]string
+}
+
+// NewPrinter creates a printer for lang
+func NewPrinter(lang Language) *Printer {
+	return &Printer{
+		lang:     lang,
+		messages: catalogs[lang],
+	}
+}
+
+// Language returns the printer's language
+func (p *Printer) Language() Language {
+	return p.lang
+}
+
+// Text translates a message that takes no arguments
+func (p *Printer) Text(message string) string {
+	if translated, ok := p.messages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Sprintf translates format and then formats it with args
+func (p *Printer) Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(p.Text(format), args...)
+}
+
+// Printf translates format and prints it to stdout
+func (p *Printer) Printf(format string, args ...interface{}) {
+	fmt.Print(p.Sprintf(format, args...))
+}
+
+// Error translates an error message. Validation errors are translated
+// problem by problem; other errors are translated only if their whole
+// message is in the catalog.
+func (p *Printer) Error(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		messages := make([]string, len(validationErr.Problems))
+		for i, problem := range validationErr.Problems {
+			messages[i] = p.Sprintf(problem.Message, problem.Args...)
+		}
+		return strings.Join(messages, "; ")
+	}
+
+	return p.Text(err.Error())
+}
@@ -0,0 +1,155 @@
+package i18n
+
+import (
+	"code/internal/models"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLanguage(t *testing.T) {
+	t.Run("accepts codes and locales", func(t *testing.T) {
+		for value, expected := range map[string]Language{
+			"de":          German,
+			"FR":          French,
+			"de_DE.UTF-8": German,
+			"fr-CA":       French,
+			"en_US":       English,
+		} {
+			// Act
+			lang, err := ParseLanguage(value)
+
+			// Assert
+			assert.NoError(t, err, value)
+			assert.Equal(t, expected, lang, value)
+		}
+	})
+
+	t.Run("rejects unsupported languages", func(t *testing.T) {
+		// Act
+		_, err := ParseLanguage("es")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestDetect(t *testing.T) {
+	t.Run("prefers the explicit value", func(t *testing.T) {
+		// Arrange
+		t.Setenv("LANG", "de_DE.UTF-8")
+
+		// Act
+		lang, err := Detect("fr")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, French, lang)
+	})
+
+	t.Run("falls back to the locale", func(t *testing.T) {
+		// Arrange
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", "de_DE.UTF-8")
+
+		// Act
+		lang, err := Detect("")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, German, lang)
+	})
+
+	t.Run("uses English for unsupported locales", func(t *testing.T) {
+		// Arrange
+		t.Setenv("LC_ALL", "C.UTF-8")
+
+		// Act
+		lang, err := Detect("")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, English, lang)
+	})
+}
+
+func TestPrinter(t *testing.T) {
+	t.Run("translates known messages", func(t *testing.T) {
+		// Arrange
+		printer := NewPrinter(German)
+
+		// Act
+		text := printer.Sprintf("Created: %d\n", 3)
+
+		// Assert
+		assert.Equal(t, "Angelegt: 3\n", text)
+	})
+
+	t.Run("falls back to English", func(t *testing.T) {
+		// Arrange
+		printer := NewPrinter(French)
+
+		// Act
+		text := printer.Sprintf("not in the catalog: %d", 1)
+
+		// Assert
+		assert.Equal(t, "not in the catalog: 1", text)
+	})
+
+	t.Run("translates each validation problem", func(t *testing.T) {
+		// Arrange
+		printer := NewPrinter(French)
+		lead := models.NewLead("", "jane@example.com", "", "LinkedIn")
+
+		// Act
+		text := printer.Error(lead.Validate())
+
+		// Assert
+		assert.Equal(t, "le nom est obligatoire; l'entreprise est obligatoire", text)
+	})
+
+	t.Run("translates whole error messages", func(t *testing.T) {
+		// Arrange
+		printer := NewPrinter(German)
+
+		// Act
+		text := printer.Error(models.ErrConsentRequired)
+
+		// Assert
+		assert.Equal(t, "Einwilligung ist erforderlich", text)
+	})
+
+	t.Run("leaves unknown errors alone", func(t *testing.T) {
+		// Arrange
+		printer := NewPrinter(German)
+
+		// Act
+		text := printer.Error(errors.New("connection refused"))
+
+		// Assert
+		assert.Equal(t, "connection refused", text)
+	})
+}
+
+func TestCatalogs(t *testing.T) {
+	verbs := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+	t.Run("translations keep the English format verbs", func(t *testing.T) {
+		for lang, messages := range catalogs {
+			for english, translated := range messages {
+				assert.Equal(t, verbs.FindAllString(english, -1), verbs.FindAllString(translated, -1), "%s: %q", lang, english)
+			}
+		}
+	})
+
+	t.Run("every language translates the same messages", func(t *testing.T) {
+		for _, messages := range catalogs {
+			for english := range catalogs[German] {
+				assert.Contains(t, messages, english)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package inference
+
+import (
+	"code/internal/models"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Rule fills in a missing lead source when its conditions hold. A rule needs
+// a column, an email domain, or both; when both are set both must match.
+type Rule struct {
+	// Column is a CSV header column that must have a value
+	Column string `yaml:"column"`
+	// Contains, if set, must appear in Column's value (case-insensitive)
+	Contains string `yaml:"contains"`
+	// EmailDomain matches the lead's email domain and its subdomains
+	EmailDomain string `yaml:"email_domain"`
+	// Source is what the lead's source is set to
+	Source string `yaml:"source"`
+}
+
+// referrerColumns are the header names a referring URL is commonly read from
+var referrerColumns = []string{"Referrer", "Referer", "Referrer URL", "Landing Page", "UTM Source", "utm_source"}
+
+// DefaultRules returns the built-in heuristics, tried in order:
+//   - a referrer or UTM source naming a social network or webinar platform
+//   - an event registration or booth column → Conference
+//   - a referred-by column → Referral
+func DefaultRules() []Rule {
+	var rules []Rule
+	for _, column := range referrerColumns {
+		rules = append(rules,
+			Rule{Column: column, Contains: "linkedin", Source: "LinkedIn"},
+			Rule{Column: column, Contains: "twitter", Source: "Twitter"},
+			Rule{Column: column, Contains: "t.co/", Source: "Twitter"},
+			Rule{Column: column, Contains: "webinar", Source: "Webinar"},
+			Rule{Column: column, Contains: "zoom.us", Source: "Webinar"},
+		)
+	}
+	for _, column := range []string{"Event", "Event Name", "Event Registration", "Booth", "Badge ID"} {
+		rules = append(rules, Rule{Column: column, Source: "Conference"})
+	}
+	for _, column := range []string{"Referred By", "Referral", "Referrer Name"} {
+		rules = append(rules, Rule{Column: column, Source: "Referral"})
+	}
+	return rules
+}
+
+// Validate checks that the rule has a condition and sets a known source,
+// canonicalising the source's spelling
+func (r *Rule) Validate() error {
+	if strings.TrimSpace(r.Column) == "" && strings.TrimSpace(r.EmailDomain) == "" {
+		return fmt.Errorf("a rule needs a column or an email_domain")
+	}
+	if strings.TrimSpace(r.Contains) != "" && strings.TrimSpace(r.Column) == "" {
+		return fmt.Errorf("contains needs a column to look in")
+	}
+	for _, valid := range models.GetValidSources() {
+		if strings.EqualFold(strings.TrimSpace(r.Source), valid) {
+			r.Source = valid
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown source %q (allowed: %s)", r.Source, strings.Join(models.GetValidSources(), ", "))
+}
+
+// Matches reports whether the rule applies to lead
+func (r Rule) Matches(lead *models.Lead) bool {
+	if r.Column != "" {
+		value, _ := lead.RawValue(r.Column)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return false
+		}
+		if r.Contains != "" && !strings.Contains(strings.ToLower(value), strings.ToLower(r.Contains)) {
+			return false
+		}
+	}
+	if r.EmailDomain != "" && !hasDomain(lead.Email, r.EmailDomain) {
+		return false
+	}
+	return true
+}
+
+// hasDomain reports whether email is at domain or one of its subdomains
+func hasDomain(email, domain string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// Inferrer fills in missing lead sources using the first matching rule
+type Inferrer struct {
+	rules    []Rule
+	inferred atomic.Int64
+}
+
+// NewInferrer creates an inferrer that tries rules in order
+func NewInferrer(rules []Rule) *Inferrer {
+	return &Inferrer{rules: rules}
+}
+
+// Infer sets lead's source if it has none and a rule matches; it has the
+// shape of pipeline.Transform
+func (i *Inferrer) Infer(lead *models.Lead) *models.Lead {
+	if strings.TrimSpace(lead.Source) != "" {
+		return lead
+	}
+	for _, rule := range i.rules {
+		if rule.Matches(lead) {
+			lead.Source = rule.Source
+			i.inferred.Add(1)
+			break
+		}
+	}
+	return lead
+}
+
+// Count returns how many sources have been inferred
+func (i *Inferrer) Count() int {
+	return int(i.inferred.Load())
+}
+
+// Columns returns the source columns the rules read, each once
+func (i *Inferrer) Columns() []string {
+	var columns []string
+	for _, rule := range i.rules {
+		if rule.Column != "" && !slices.Contains(columns, rule.Column) {
+			columns = append(columns, rule.Column)
+		}
+	}
+	return columns
+}
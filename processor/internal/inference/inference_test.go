@@ -0,0 +1,96 @@
+package inference
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func leadWith(email, source string, raw map[string]string) *models.Lead {
+	lead := models.NewLead("Jane Roe", email, "Acme", source)
+	lead.Raw = raw
+	return lead
+}
+
+func TestInferrer_Infer(t *testing.T) {
+	t.Run("applies the built-in heuristics", func(t *testing.T) {
+		// Arrange
+		inferrer := NewInferrer(DefaultRules())
+		cases := []struct {
+			raw      map[string]string
+			expected string
+		}{
+			{map[string]string{"Referrer": "https://www.linkedin.com/feed/"}, "LinkedIn"},
+			{map[string]string{"utm_source": "twitter"}, "Twitter"},
+			{map[string]string{"Event Registration": "SaaStr 2024"}, "Conference"},
+			{map[string]string{"Referred By": "Bob"}, "Referral"},
+			{map[string]string{"Referrer": "https://example.com"}, ""},
+			{map[string]string{"Event": "  "}, ""},
+		}
+
+		for _, c := range cases {
+			// Act
+			lead := inferrer.Infer(leadWith("jane@example.com", "", c.raw))
+
+			// Assert
+			assert.Equal(t, c.expected, lead.Source, "%v", c.raw)
+		}
+		assert.Equal(t, 4, inferrer.Count())
+	})
+
+	t.Run("keeps a source that is already set", func(t *testing.T) {
+		// Arrange
+		inferrer := NewInferrer(DefaultRules())
+
+		// Act
+		lead := inferrer.Infer(leadWith("jane@example.com", "Website", map[string]string{"Referrer": "linkedin.com"}))
+
+		// Assert
+		assert.Equal(t, "Website", lead.Source)
+		assert.Equal(t, 0, inferrer.Count())
+	})
+
+	t.Run("matches email domains and their subdomains", func(t *testing.T) {
+		// Arrange
+		inferrer := NewInferrer([]Rule{{EmailDomain: "partner.com", Source: "Referral"}})
+
+		// Act
+		exact := inferrer.Infer(leadWith("jane@partner.com", "", nil))
+		sub := inferrer.Infer(leadWith("jane@eu.partner.com", "", nil))
+		other := inferrer.Infer(leadWith("jane@notpartner.com", "", nil))
+
+		// Assert
+		assert.Equal(t, "Referral", exact.Source)
+		assert.Equal(t, "Referral", sub.Source)
+		assert.Equal(t, "", other.Source)
+	})
+}
+
+func TestRule_Validate(t *testing.T) {
+	t.Run("canonicalises the source", func(t *testing.T) {
+		// Arrange
+		rule := Rule{Column: "Referrer", Contains: "linkedin", Source: "linkedin"}
+
+		// Act
+		err := rule.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "LinkedIn", rule.Source)
+	})
+
+	t.Run("rejects rules without a condition or a known source", func(t *testing.T) {
+		for _, rule := range []Rule{
+			{Source: "LinkedIn"},
+			{Contains: "linkedin", Source: "LinkedIn"},
+			{Column: "Referrer", Source: "Billboard"},
+		} {
+			// Act
+			err := rule.Validate()
+
+			// Assert
+			assert.Error(t, err, "%+v", rule)
+		}
+	})
+}
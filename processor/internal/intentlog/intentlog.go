@@ -0,0 +1,271 @@
+package intentlog
+
+import (
+	"bufio"
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of an intent
+const (
+	// OutcomeDone means the API confirmed the request
+	OutcomeDone = "done"
+	// OutcomeFailed means the API refused the request, so nothing changed
+	OutcomeFailed = "failed"
+	// OutcomeApplied means reconciling found the change in the API
+	OutcomeApplied = "applied"
+	// OutcomeNotApplied means reconciling found the change missing from the API
+	OutcomeNotApplied = "not-applied"
+)
+
+// Intent is a create, update or delete about to be sent to the API
+type Intent struct {
+	ID          string    `json:"id"`
+	RunID       string    `json:"runId,omitempty"`
+	Action      string    `json:"action"`
+	Email       string    `json:"email"`
+	ContentHash string    `json:"contentHash"`
+	At          time.Time `json:"at"`
+}
+
+// record is one line of the log: an intent, or the outcome of one
+type record struct {
+	Intent  *Intent   `json:"intent,omitempty"`
+	ID      string    `json:"id,omitempty"`
+	Outcome string    `json:"outcome,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Log is a write-ahead log of creates and updates. Each intent is synced to
+// disk before its request is sent and followed by the outcome once the API
+// answers, so after a crash the intents without an outcome are exactly the
+// requests whose effect is unknown.
+type Log struct {
+	path  string
+	runID string
+
+	mu      sync.Mutex
+	file    *os.File
+	pending map[string]Intent
+	order   []string
+}
+
+// Open opens (creating if needed) the log at path. Intents left pending by an
+// earlier run are kept for Reconcile; everything else is compacted away.
+func Open(path, runID string) (*Log, error) {
+	l := &Log{path: path, runID: runID, pending: make(map[string]Intent)}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	if err := l.compact(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open intent log: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// load reads the pending intents. A torn last line from a crash mid-write is
+// skipped: its request was never sent.
+func (l *Log) load() error {
+	file, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open intent log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		switch {
+		case rec.Intent != nil:
+			l.pending[rec.Intent.ID] = *rec.Intent
+			l.order = append(l.order, rec.Intent.ID)
+		case rec.ID != "":
+			delete(l.pending, rec.ID)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the log with only its pending intents
+func (l *Log) compact() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create intent log directory: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact intent log: %w", err)
+	}
+	for _, intent := range l.Pending() {
+		if err := writeRecord(file, record{Intent: &intent}); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to compact intent log: %w", err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact intent log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to compact intent log: %w", err)
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// Pending returns the intents without an outcome, oldest first
+func (l *Log) Pending() []Intent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var intents []Intent
+	for _, id := range l.order {
+		if intent, ok := l.pending[id]; ok {
+			intents = append(intents, intent)
+		}
+	}
+	return intents
+}
+
+// Begin records that action is about to be sent for lead and syncs the log
+// to disk, returning the intent's ID. It has the shape processor.IntentLog
+// expects.
+func (l *Log) Begin(action string, lead *models.Lead) (string, error) {
+	intent := Intent{
+		ID:          uuid.New().String(),
+		RunID:       l.runID,
+		Action:      action,
+		Email:       lead.Email,
+		ContentHash: lead.ContentHash(),
+		At:          time.Now().UTC(),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := writeRecord(l.file, record{Intent: &intent}); err != nil {
+		return "", err
+	}
+	if err := l.file.Sync(); err != nil {
+		return "", err
+	}
+	return intent.ID, nil
+}
+
+// Finish records the API's answer to an intent. Only a definitive rejection
+// marks it failed; any other error, such as a network failure, a spent retry
+// budget, a cancelled context or an unreadable 2xx response, leaves it
+// pending, since the request may have reached the API anyway.
+func (l *Log) Finish(id string, err error) error {
+	if err != nil && !rejected(err) {
+		return nil
+	}
+	outcome := OutcomeDone
+	if err != nil {
+		outcome = OutcomeFailed
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return writeRecord(l.file, record{ID: id, Outcome: outcome, At: time.Now().UTC()})
+}
+
+// rejected reports whether err is the API refusing a request, which it then
+// didn't apply: a validation, conflict or authorization failure, or any other
+// 4xx status but 429
+func rejected(err error) bool {
+	if errors.Is(err, errcode.ErrNetwork) || errors.Is(err, errcode.ErrRateLimited) {
+		return false
+	}
+	if errors.Is(err, errcode.ErrValidation) || errors.Is(err, errcode.ErrConflict) || errors.Is(err, errcode.ErrAuth) {
+		return true
+	}
+	var status *errcode.StatusError
+	return errors.As(err, &status) && status.Status >= 400 && status.Status < 500
+}
+
+// Lookup finds the lead with email as the API has it now
+type Lookup func(email string) (lead *models.Lead, found bool, err error)
+
+// Resolution is what reconciling a pending intent found
+type Resolution struct {
+	Intent  Intent
+	Outcome string
+}
+
+// Reconcile looks up the lead of every intent left pending by an earlier
+// run and records whether its change reached the API: a create applied if
+// the lead exists, a delete or archive if it doesn't, and an update if the
+// lead has the intended content. Intents whose lookup fails stay pending for
+// the next run.
+func (l *Log) Reconcile(lookup Lookup) ([]Resolution, error) {
+	var resolutions []Resolution
+	for _, intent := range l.Pending() {
+		lead, found, err := lookup(intent.Email)
+		if err != nil {
+			return resolutions, fmt.Errorf("failed to look up %s: %w", intent.Email, err)
+		}
+
+		outcome := OutcomeNotApplied
+		switch {
+		case intent.Action == "CREATE" && found:
+			outcome = OutcomeApplied
+		case intent.Action == "DELETE" || intent.Action == "ARCHIVE":
+			if !found {
+				outcome = OutcomeApplied
+			}
+		case found && lead != nil && lead.ContentHash() == intent.ContentHash:
+			outcome = OutcomeApplied
+		}
+
+		l.mu.Lock()
+		err = writeRecord(l.file, record{ID: intent.ID, Outcome: outcome, At: time.Now().UTC()})
+		delete(l.pending, intent.ID)
+		l.mu.Unlock()
+		if err != nil {
+			return resolutions, err
+		}
+		resolutions = append(resolutions, Resolution{Intent: intent, Outcome: outcome})
+	}
+	return resolutions, nil
+}
+
+// Close closes the log
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// writeRecord appends rec as one JSON line
+func writeRecord(file *os.File, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write intent log: %w", err)
+	}
+	return nil
+}
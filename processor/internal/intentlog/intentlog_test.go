@@ -0,0 +1,155 @@
+package intentlog
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	t.Run("keeps only unanswered intents across a restart", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "intents.ndjson")
+		log, err := Open(path, "run-1")
+		assert.NoError(t, err)
+
+		answered, _ := log.Begin("CREATE", models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+		refused, _ := log.Begin("CREATE", models.NewLead("Jane Roe", "jane@example.com", "Test Corp", "LinkedIn"))
+		timedOut, _ := log.Begin("UPDATE", models.NewLead("Jim Poe", "jim@example.com", "Test Corp", "LinkedIn"))
+		log.Begin("CREATE", models.NewLead("Joe Bloggs", "joe@example.com", "Test Corp", "LinkedIn"))
+		assert.NoError(t, log.Finish(answered, nil))
+		assert.NoError(t, log.Finish(refused, &errcode.StatusError{Status: 422}))
+		assert.NoError(t, log.Finish(timedOut, errcode.Classify(errcode.ErrNetwork, assert.AnError)))
+		assert.NoError(t, log.Close())
+
+		// Act
+		reopened, err := Open(path, "run-2")
+
+		// Assert
+		assert.NoError(t, err)
+		defer reopened.Close()
+		pending := reopened.Pending()
+		assert.Len(t, pending, 2)
+		assert.Equal(t, "jim@example.com", pending[0].Email)
+		assert.Equal(t, "joe@example.com", pending[1].Email)
+		assert.Equal(t, "run-1", pending[0].RunID)
+	})
+
+	t.Run("fails intents only on a definitive rejection", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "intents.ndjson")
+		log, _ := Open(path, "run-1")
+		finish := func(email string, err error) {
+			id, _ := log.Begin("UPDATE", models.NewLead("John Doe", email, "Test Corp", "LinkedIn"))
+			assert.NoError(t, log.Finish(id, err))
+		}
+		finish("conflict@example.com", errcode.Classify(errcode.ErrConflict, assert.AnError))
+		finish("auth@example.com", &errcode.StatusError{Status: 403})
+		finish("gone@example.com", &errcode.StatusError{Status: 404})
+		finish("server@example.com", &errcode.StatusError{Status: 503})
+		finish("throttled@example.com", &errcode.StatusError{Status: 429})
+		finish("cancelled@example.com", context.Canceled)
+		finish("decode@example.com", fmt.Errorf("failed to decode response: %w", io.ErrUnexpectedEOF))
+		log.Close()
+
+		// Act
+		reopened, _ := Open(path, "run-2")
+		defer reopened.Close()
+
+		// Assert
+		var emails []string
+		for _, intent := range reopened.Pending() {
+			emails = append(emails, intent.Email)
+		}
+		assert.ElementsMatch(t, []string{"server@example.com", "throttled@example.com", "cancelled@example.com", "decode@example.com"}, emails)
+	})
+
+	t.Run("skips a torn last line", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "intents.ndjson")
+		log, _ := Open(path, "")
+		log.Begin("CREATE", models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+		log.Close()
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		file.WriteString(`{"intent":{"id":"abc","act`)
+		file.Close()
+
+		// Act
+		reopened, err := Open(path, "")
+
+		// Assert
+		assert.NoError(t, err)
+		defer reopened.Close()
+		assert.Len(t, reopened.Pending(), 1)
+	})
+}
+
+func TestLog_Reconcile(t *testing.T) {
+	t.Run("settles pending intents by looking the leads up", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "intents.ndjson")
+		log, _ := Open(path, "run-1")
+		created := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		updated := models.NewLead("Jane Roe", "jane@example.com", "New Corp", "LinkedIn")
+		lost := models.NewLead("Jim Poe", "jim@example.com", "Test Corp", "LinkedIn")
+		deleted := models.NewLead("Joe Bloggs", "joe@example.com", "Test Corp", "LinkedIn")
+		log.Begin("CREATE", created)
+		log.Begin("UPDATE", updated)
+		log.Begin("CREATE", lost)
+		log.Begin("DELETE", deleted)
+		log.Close()
+
+		api := map[string]*models.Lead{
+			"john@example.com": created,
+			"jane@example.com": models.NewLead("Jane Roe", "jane@example.com", "Old Corp", "LinkedIn"),
+		}
+		log, _ = Open(path, "run-2")
+
+		// Act
+		resolutions, err := log.Reconcile(func(email string) (*models.Lead, bool, error) {
+			lead, found := api[email]
+			return lead, found, nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, resolutions, 4)
+		assert.Equal(t, OutcomeApplied, resolutions[0].Outcome)
+		assert.Equal(t, OutcomeNotApplied, resolutions[1].Outcome)
+		assert.Equal(t, OutcomeNotApplied, resolutions[2].Outcome)
+		assert.Equal(t, OutcomeApplied, resolutions[3].Outcome)
+		assert.Empty(t, log.Pending())
+		log.Close()
+
+		reopened, _ := Open(path, "run-3")
+		defer reopened.Close()
+		assert.Empty(t, reopened.Pending())
+	})
+
+	t.Run("leaves intents pending when the lookup fails", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "intents.ndjson")
+		log, _ := Open(path, "run-1")
+		log.Begin("CREATE", models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+		log.Close()
+		log, _ = Open(path, "run-2")
+		defer log.Close()
+
+		// Act
+		resolutions, err := log.Reconcile(func(email string) (*models.Lead, bool, error) {
+			return nil, false, assert.AnError
+		})
+
+		// Assert
+		assert.Error(t, err)
+		assert.Empty(t, resolutions)
+		assert.Len(t, log.Pending(), 1)
+	})
+}
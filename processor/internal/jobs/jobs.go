@@ -0,0 +1,498 @@
+package jobs
+
+import (
+	"code/internal/errcode"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State is where a job is in its life
+type State string
+
+// Job states
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateFailed    State = "failed"
+	// StatePlanning and StatePendingReview hold a job of a queue requiring
+	// approval: its plan is made, then it waits for a reviewer
+	StatePlanning      State = "planning"
+	StatePendingReview State = "pending_review"
+	// StateRejected ends a job a reviewer turned down
+	StateRejected State = "rejected"
+)
+
+// ParseState checks a state named in a filter
+func ParseState(s string) (State, error) {
+	switch state := State(s); state {
+	case StateQueued, StateRunning, StateCompleted, StateFailed, StatePlanning, StatePendingReview, StateRejected:
+		return state, nil
+	}
+	return "", fmt.Errorf("unknown state %q (allowed: queued, running, completed, failed, planning, pending_review, rejected)", s)
+}
+
+// Review decisions
+const (
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+)
+
+// Review records who decided on a job held for review
+type Review struct {
+	// Decision is approved or rejected
+	Decision string    `json:"decision"`
+	By       string    `json:"by"`
+	At       time.Time `json:"at"`
+	Comment  string    `json:"comment,omitempty"`
+}
+
+var (
+	// ErrNotFound means no job has the ID
+	ErrNotFound = errors.New("import not found")
+	// ErrNotPending means a job isn't waiting for review
+	ErrNotPending = errors.New("the import is not pending review")
+	// ErrOwnImport means a reviewer tried to decide on their own upload
+	ErrOwnImport = errors.New("an import must be reviewed by someone other than its submitter")
+)
+
+// Job is one queued upload
+type Job struct {
+	ID    string `json:"id"`
+	State State  `json:"state"`
+	// Priority orders queued jobs, highest first; jobs of equal priority
+	// run in the order they were queued
+	Priority int `json:"priority"`
+	// Name labels the upload, e.g. the file it came from
+	Name string `json:"name,omitempty"`
+	// URL is where the file is fetched from when the job runs, for a job
+	// queued with a link rather than an upload. A pre-signed URL carries
+	// credentials, so it is redacted wherever a job is shown.
+	URL string `json:"url,omitempty"`
+	// SubmittedBy names the token the upload was made with
+	SubmittedBy string     `json:"submittedBy,omitempty"`
+	Size        int64      `json:"size"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	// Attempts counts the times the job was started; more than one means a
+	// restart interrupted it
+	Attempts int `json:"attempts"`
+	// Result is what the run returned, e.g. an import summary
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	// Code classifies the error when it has a known class, e.g.
+	// UNMATCHED_PROFILE
+	Code string `json:"code,omitempty"`
+	// Plan is what a dry run of the upload would do, for a job held for
+	// review
+	Plan json.RawMessage `json:"plan,omitempty"`
+	// Review is the reviewer's decision on a job held for review
+	Review *Review `json:"review,omitempty"`
+}
+
+// RunFunc processes a job's upload and returns its result; upload is nil
+// for a job with a URL
+type RunFunc func(ctx context.Context, job Job, upload io.Reader) (interface{}, error)
+
+// Filter selects jobs to list
+type Filter struct {
+	// State, when set, keeps jobs in that state
+	State State
+	// Since and Until, when set, bound when jobs were queued
+	Since time.Time
+	Until time.Time
+	// Limit caps the jobs returned; 0 returns them all
+	Limit int
+}
+
+// Queue is a priority queue of uploads kept in a Store. Jobs a restart
+// interrupted are queued again when it is opened, so they run from the
+// start; processing is idempotent, so leads already written are skipped.
+// It is safe for concurrent use.
+type Queue struct {
+	store Store
+	wake  chan struct{}
+	plan  RunFunc
+	// maxRunning caps the running jobs of a submitter; 0 leaves them uncapped
+	maxRunning func(submittedBy string) int
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	// planning holds the jobs whose plan is being made
+	planning map[string]bool
+}
+
+// Open loads the store's jobs
+func Open(store Store) (*Queue, error) {
+	saved, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{store: store, wake: make(chan struct{}, 1), jobs: make(map[string]*Job, len(saved)), planning: make(map[string]bool)}
+	for _, job := range saved {
+		if job.State == StateRunning {
+			job.State = StateQueued
+			if err := store.Save(job); err != nil {
+				return nil, err
+			}
+		}
+		q.jobs[job.ID] = job
+	}
+	return q, nil
+}
+
+// RequireApproval holds new jobs for review: each is first run with plan,
+// which must not write anything, and its result kept as the job's plan. The
+// job then waits in pending_review until Approve queues it or Reject drops
+// it. Call it before Start.
+func (q *Queue) RequireApproval(plan RunFunc) {
+	q.plan = plan
+}
+
+// SetMaxRunning caps the jobs of each submitter running at once, so one
+// submitter's jobs can't take every worker. A job over its submitter's cap
+// waits, and jobs of others run first. Call it before Start.
+func (q *Queue) SetMaxRunning(limit func(submittedBy string) int) {
+	q.maxRunning = limit
+}
+
+// Enqueue stores the upload and queues a job for it; submittedBy may be empty
+func (q *Queue) Enqueue(upload io.Reader, name string, priority int, submittedBy string) (Job, error) {
+	return q.enqueue(newJob(name, priority, submittedBy), func(id string) (int64, error) {
+		return q.store.SaveUpload(id, upload)
+	}, q.store.RemoveUpload)
+}
+
+// EnqueueFile queues a job for a file already on disk, such as a finished
+// resumable upload, moving it into the store. If the job can't be queued,
+// the file is moved back, so it isn't lost.
+func (q *Queue) EnqueueFile(path, name string, priority int, submittedBy string) (Job, error) {
+	return q.enqueue(newJob(name, priority, submittedBy), func(id string) (int64, error) {
+		return q.store.AdoptUpload(id, path)
+	}, func(id string) error {
+		return q.store.ReleaseUpload(id, path)
+	})
+}
+
+// EnqueueURL queues a job for a file the run fetches from url
+func (q *Queue) EnqueueURL(url, name string, priority int, submittedBy string) (Job, error) {
+	job := newJob(name, priority, submittedBy)
+	job.URL = url
+	return q.enqueue(job, nil, nil)
+}
+
+func newJob(name string, priority int, submittedBy string) *Job {
+	return &Job{ID: uuid.NewString(), State: StateQueued, Priority: priority, Name: name, SubmittedBy: submittedBy, CreatedAt: time.Now().UTC()}
+}
+
+// enqueue stores the job's upload, if it has one, then the job itself. If
+// the job can't be saved, undoUpload undoes storeUpload.
+func (q *Queue) enqueue(job *Job, storeUpload func(id string) (int64, error), undoUpload func(id string) error) (Job, error) {
+	if q.plan != nil {
+		job.State = StatePlanning
+	}
+	if storeUpload != nil {
+		size, err := storeUpload(job.ID)
+		if err != nil {
+			return Job{}, err
+		}
+		job.Size = size
+	}
+	if err := q.store.Save(job); err != nil {
+		if undoUpload != nil {
+			if undoErr := undoUpload(job.ID); undoErr != nil {
+				return Job{}, errors.Join(err, undoErr)
+			}
+		}
+		return Job{}, err
+	}
+
+	q.mu.Lock()
+	q.jobs[job.ID] = job
+	q.mu.Unlock()
+	q.signal()
+	return *job, nil
+}
+
+// Get returns a job by ID
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns the jobs matching the filter, newest first
+func (q *Queue) List(filter Filter) []Job {
+	q.mu.Lock()
+	jobs := make([]Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		if filter.State != "" && job.State != filter.State {
+			continue
+		}
+		if !filter.Since.IsZero() && job.CreatedAt.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !job.CreatedAt.Before(filter.Until) {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs
+}
+
+// Approve queues a job pending review, recording reviewer as having approved
+// its plan
+func (q *Queue) Approve(id, reviewer, comment string) (Job, error) {
+	return q.review(id, Review{Decision: DecisionApproved, By: reviewer, Comment: comment})
+}
+
+// Reject ends a job pending review without running it, dropping its upload
+func (q *Queue) Reject(id, reviewer, comment string) (Job, error) {
+	return q.review(id, Review{Decision: DecisionRejected, By: reviewer, Comment: comment})
+}
+
+func (q *Queue) review(id string, review Review) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if job.State != StatePendingReview {
+		return *job, ErrNotPending
+	}
+	if job.SubmittedBy != "" && job.SubmittedBy == review.By {
+		return *job, ErrOwnImport
+	}
+
+	review.At = time.Now().UTC()
+	job.Review = &review
+	if review.Decision == DecisionApproved {
+		job.State = StateQueued
+		q.save(job)
+		q.signal()
+		return *job, nil
+	}
+	job.State = StateRejected
+	job.FinishedAt = &review.At
+	q.save(job)
+	if err := q.store.RemoveUpload(job.ID); err != nil {
+		log.Printf("Job %s: %v", job.ID, err)
+	}
+	return *job, nil
+}
+
+// Start runs queued jobs on the given number of workers until ctx is done
+func (q *Queue) Start(ctx context.Context, workers int, run RunFunc) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go q.work(ctx, run)
+	}
+	q.signal()
+}
+
+func (q *Queue) work(ctx context.Context, run RunFunc) {
+	for {
+		job, planning := q.next()
+		if job == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		// Another worker may take the next job while this one runs
+		q.signal()
+		if planning {
+			q.planJob(ctx, job)
+			continue
+		}
+		q.runJob(ctx, job, run)
+	}
+}
+
+// next claims the job to run or plan next, or returns nil if there is none
+func (q *Queue) next() (next *Job, planning bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	running := q.runningBySubmitter()
+	for _, job := range q.jobs {
+		claimable := job.State == StateQueued && !q.atMaxRunning(job.SubmittedBy, running) ||
+			job.State == StatePlanning && q.plan != nil && !q.planning[job.ID]
+		if !claimable {
+			continue
+		}
+		if next == nil || job.Priority > next.Priority ||
+			job.Priority == next.Priority && job.CreatedAt.Before(next.CreatedAt) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, false
+	}
+	if next.State == StatePlanning {
+		// The job stays in planning, so a restart plans it again
+		q.planning[next.ID] = true
+		return next, true
+	}
+
+	now := time.Now().UTC()
+	next.State = StateRunning
+	next.StartedAt = &now
+	next.FinishedAt = nil
+	next.Attempts++
+	q.save(next)
+	return next, false
+}
+
+// runningBySubmitter counts the running jobs of each submitter when their
+// number is capped; the caller holds q.mu
+func (q *Queue) runningBySubmitter() map[string]int {
+	if q.maxRunning == nil {
+		return nil
+	}
+	running := make(map[string]int)
+	for _, job := range q.jobs {
+		if job.State == StateRunning {
+			running[job.SubmittedBy]++
+		}
+	}
+	return running
+}
+
+// atMaxRunning reports whether the submitter has as many jobs running as it
+// may
+func (q *Queue) atMaxRunning(submittedBy string, running map[string]int) bool {
+	if q.maxRunning == nil {
+		return false
+	}
+	limit := q.maxRunning(submittedBy)
+	return limit > 0 && running[submittedBy] >= limit
+}
+
+// planJob makes a job's plan and holds it for review, or fails it if its
+// upload can't be read
+func (q *Queue) planJob(ctx context.Context, job *Job) {
+	plan, err := q.runUpload(ctx, job, q.plan)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.planning, job.ID)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+		job.State = StateFailed
+		job.Error = err.Error()
+		if code := errcode.Of(err); code != errcode.CodeUnknown {
+			job.Code = string(code)
+		}
+		q.save(job)
+		if err := q.store.RemoveUpload(job.ID); err != nil {
+			log.Printf("Job %s: %v", job.ID, err)
+		}
+		return
+	}
+	if data, marshalErr := json.Marshal(plan); marshalErr == nil {
+		job.Plan = data
+	}
+	job.State = StatePendingReview
+	q.save(job)
+}
+
+func (q *Queue) runJob(ctx context.Context, job *Job, run RunFunc) {
+	result, err := q.runUpload(ctx, job, run)
+	if ctx.Err() != nil {
+		// Shutting down: leave the job running, so the next start queues
+		// it again
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.State = StateCompleted
+	job.Error = ""
+	job.Code = ""
+	if result != nil {
+		if data, marshalErr := json.Marshal(result); marshalErr == nil {
+			job.Result = data
+		}
+	}
+	if err != nil {
+		job.State = StateFailed
+		job.Error = err.Error()
+		if code := errcode.Of(err); code != errcode.CodeUnknown {
+			job.Code = string(code)
+		}
+	}
+	q.save(job)
+	if err := q.store.RemoveUpload(job.ID); err != nil {
+		log.Printf("Job %s: %v", job.ID, err)
+	}
+}
+
+func (q *Queue) runUpload(ctx context.Context, job *Job, run RunFunc) (interface{}, error) {
+	q.mu.Lock()
+	snapshot := *job
+	q.mu.Unlock()
+	if snapshot.URL != "" {
+		return run(ctx, snapshot, nil)
+	}
+
+	upload, err := q.store.OpenUpload(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.Close()
+	return run(ctx, snapshot, upload)
+}
+
+// save persists a job; the caller holds q.mu. A failed write is logged, since
+// the job's state in memory is still right.
+func (q *Queue) save(job *Job) {
+	if err := q.store.Save(job); err != nil {
+		log.Printf("Job %s: %v", job.ID, err)
+	}
+}
+
+// signal wakes a worker waiting for a job
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
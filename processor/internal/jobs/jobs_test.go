@@ -0,0 +1,282 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingSaveStore fails to save any job
+type failingSaveStore struct {
+	*FileStore
+}
+
+func (failingSaveStore) Save(job *Job) error {
+	return errors.New("disk full")
+}
+
+// recordingRun records the uploads it runs, failing any that say "fail"
+type recordingRun struct {
+	mu   sync.Mutex
+	ran  []string
+	done chan struct{}
+}
+
+func (r *recordingRun) run(ctx context.Context, job Job, upload io.Reader) (interface{}, error) {
+	data, _ := io.ReadAll(upload)
+	r.mu.Lock()
+	r.ran = append(r.ran, string(data))
+	r.mu.Unlock()
+	defer func() { r.done <- struct{}{} }()
+	if string(data) == "fail" {
+		return nil, errors.New("invalid CSV")
+	}
+	return map[string]int{"total": len(data)}, nil
+}
+
+func waitFor(t *testing.T, done chan struct{}, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("jobs did not finish")
+		}
+	}
+}
+
+// waitForState polls until the job reaches the state, since it is saved
+// just after the run returns
+func waitForState(t *testing.T, q *Queue, id string, state State) Job {
+	assert.Eventually(t, func() bool {
+		job, _ := q.Get(id)
+		return job.State == state
+	}, 5*time.Second, time.Millisecond)
+	job, _ := q.Get(id)
+	return job
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("runs queued jobs by priority, then in order", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		low, _ := q.Enqueue(strings.NewReader("low"), "", 0, "")
+		q.Enqueue(strings.NewReader("urgent"), "", 10, "")
+		last, _ := q.Enqueue(strings.NewReader("later"), "", 0, "")
+		run := &recordingRun{done: make(chan struct{}, 3)}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Act
+		q.Start(ctx, 1, run.run)
+		waitFor(t, run.done, 3)
+
+		// Assert
+		assert.Equal(t, []string{"urgent", "low", "later"}, run.ran)
+		job := waitForState(t, q, low.ID, StateCompleted)
+		assert.JSONEq(t, `{"total":3}`, string(job.Result))
+		assert.Equal(t, 1, job.Attempts)
+		assert.NotNil(t, job.FinishedAt)
+		waitForState(t, q, last.ID, StateCompleted)
+	})
+
+	t.Run("records failures", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		job, _ := q.Enqueue(strings.NewReader("fail"), "leads.csv", 0, "")
+		run := &recordingRun{done: make(chan struct{}, 1)}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Act
+		q.Start(ctx, 1, run.run)
+		waitFor(t, run.done, 1)
+
+		// Assert
+		failed := waitForState(t, q, job.ID, StateFailed)
+		assert.Equal(t, "invalid CSV", failed.Error)
+		assert.Equal(t, "leads.csv", failed.Name)
+		_, err := store.OpenUpload(job.ID)
+		assert.Error(t, err, "the upload is dropped once the job is done")
+	})
+
+	t.Run("queues interrupted jobs again when reopened", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		job, _ := q.Enqueue(strings.NewReader("leads"), "", 0, "")
+		q.next()
+
+		// Act
+		reopened, err := Open(store)
+
+		// Assert
+		assert.NoError(t, err)
+		restored, ok := reopened.Get(job.ID)
+		assert.True(t, ok)
+		assert.Equal(t, StateQueued, restored.State)
+		assert.Equal(t, 1, restored.Attempts)
+		assert.Equal(t, int64(5), restored.Size)
+	})
+
+	t.Run("hands a file back when its job can't be saved", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		fileStore, _ := NewFileStore(filepath.Join(dir, "queue"))
+		q, _ := Open(failingSaveStore{fileStore})
+		path := filepath.Join(dir, "upload.part")
+		os.WriteFile(path, []byte("leads"), 0o644)
+
+		// Act
+		_, err := q.EnqueueFile(path, "leads.csv", 0, "")
+
+		// Assert
+		assert.Error(t, err)
+		data, readErr := os.ReadFile(path)
+		assert.NoError(t, readErr)
+		assert.Equal(t, "leads", string(data))
+		assert.Empty(t, q.List(Filter{}))
+	})
+
+	t.Run("lists jobs newest first, filtered", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		first, _ := q.Enqueue(strings.NewReader("a"), "", 0, "")
+		second, _ := q.Enqueue(strings.NewReader("b"), "", 0, "")
+		third, _ := q.Enqueue(strings.NewReader("c"), "", 0, "")
+		q.jobs[first.ID].CreatedAt = time.Now().Add(-48 * time.Hour)
+		q.jobs[second.ID].CreatedAt = time.Now().Add(-time.Hour)
+		q.jobs[second.ID].State = StateFailed
+
+		// Act
+		all := q.List(Filter{})
+		failed := q.List(Filter{State: StateFailed})
+		recent := q.List(Filter{Since: time.Now().Add(-24 * time.Hour), Limit: 1})
+
+		// Assert
+		assert.Equal(t, []string{third.ID, second.ID, first.ID}, []string{all[0].ID, all[1].ID, all[2].ID})
+		assert.Len(t, failed, 1)
+		assert.Equal(t, second.ID, failed[0].ID)
+		assert.Len(t, recent, 1)
+		assert.Equal(t, third.ID, recent[0].ID)
+	})
+}
+
+func TestQueue_SetMaxRunning(t *testing.T) {
+	t.Run("runs another submitter's job while one is at its cap", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		q.SetMaxRunning(func(submittedBy string) int {
+			if submittedBy == "emea" {
+				return 1
+			}
+			return 0
+		})
+		running, _ := q.Enqueue(strings.NewReader("big"), "", 10, "emea")
+		waiting, _ := q.Enqueue(strings.NewReader("bigger"), "", 10, "emea")
+		other, _ := q.Enqueue(strings.NewReader("small"), "", 0, "us")
+
+		// Act
+		first, _ := q.next()
+		second, _ := q.next()
+		third, _ := q.next()
+
+		// Assert
+		assert.Equal(t, running.ID, first.ID)
+		assert.Equal(t, other.ID, second.ID, "the capped submitter's next job waits")
+		assert.Nil(t, third)
+		queued, _ := q.Get(waiting.ID)
+		assert.Equal(t, StateQueued, queued.State)
+	})
+}
+
+func TestQueue_RequireApproval(t *testing.T) {
+	plan := func(ctx context.Context, job Job, upload io.Reader) (interface{}, error) {
+		data, _ := io.ReadAll(upload)
+		return map[string]int{"planned": len(data)}, nil
+	}
+
+	t.Run("plans jobs and runs them only once approved", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		q.RequireApproval(plan)
+		job, _ := q.Enqueue(strings.NewReader("leads"), "leads.csv", 0, "marketing")
+		run := &recordingRun{done: make(chan struct{}, 1)}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Act
+		q.Start(ctx, 1, run.run)
+		pending := waitForState(t, q, job.ID, StatePendingReview)
+		_, ownErr := q.Approve(job.ID, "marketing", "")
+		approved, err := q.Approve(job.ID, "ops", "checked the plan")
+		waitFor(t, run.done, 1)
+
+		// Assert
+		assert.Equal(t, StatePlanning, job.State)
+		assert.JSONEq(t, `{"planned":5}`, string(pending.Plan))
+		assert.ErrorIs(t, ownErr, ErrOwnImport)
+		assert.NoError(t, err)
+		assert.Equal(t, StateQueued, approved.State)
+		assert.Equal(t, DecisionApproved, approved.Review.Decision)
+		assert.Equal(t, "ops", approved.Review.By)
+		assert.Equal(t, "checked the plan", approved.Review.Comment)
+		assert.Equal(t, []string{"leads"}, run.ran)
+		waitForState(t, q, job.ID, StateCompleted)
+	})
+
+	t.Run("drops rejected jobs without running them", func(t *testing.T) {
+		// Arrange
+		store, _ := NewFileStore(t.TempDir())
+		q, _ := Open(store)
+		q.RequireApproval(plan)
+		job, _ := q.Enqueue(strings.NewReader("leads"), "", 0, "marketing")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		q.Start(ctx, 1, func(ctx context.Context, job Job, upload io.Reader) (interface{}, error) {
+			t.Error("a rejected job ran")
+			return nil, nil
+		})
+		waitForState(t, q, job.ID, StatePendingReview)
+
+		// Act
+		rejected, err := q.Reject(job.ID, "ops", "wrong campaign")
+		_, againErr := q.Approve(job.ID, "ops", "")
+		_, missingErr := q.Reject("unknown", "ops", "")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, StateRejected, rejected.State)
+		assert.Equal(t, DecisionRejected, rejected.Review.Decision)
+		assert.NotNil(t, rejected.FinishedAt)
+		assert.ErrorIs(t, againErr, ErrNotPending)
+		assert.ErrorIs(t, missingErr, ErrNotFound)
+		_, openErr := store.OpenUpload(job.ID)
+		assert.Error(t, openErr, "the upload is dropped once rejected")
+	})
+}
+
+func TestParseState(t *testing.T) {
+	t.Run("accepts the job states only", func(t *testing.T) {
+		// Act
+		state, err := ParseState("running")
+		_, unknownErr := ParseState("done")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, StateRunning, state)
+		assert.ErrorContains(t, unknownErr, `unknown state "done"`)
+	})
+}
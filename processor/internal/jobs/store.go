@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	jobSuffix    = ".json"
+	uploadSuffix = ".upload"
+)
+
+// Store keeps jobs and their uploads where they survive a restart
+type Store interface {
+	// Save writes a job, replacing any earlier version of it
+	Save(job *Job) error
+	// Load returns every saved job
+	Load() ([]*Job, error)
+	// SaveUpload stores a job's upload, returning its size
+	SaveUpload(id string, upload io.Reader) (int64, error)
+	// AdoptUpload takes over a file already on disk as a job's upload,
+	// returning its size
+	AdoptUpload(id, path string) (int64, error)
+	// ReleaseUpload hands an adopted upload back to path, undoing AdoptUpload
+	ReleaseUpload(id, path string) error
+	// OpenUpload reads back a job's upload
+	OpenUpload(id string) (io.ReadCloser, error)
+	// RemoveUpload drops a job's upload once it is no longer needed
+	RemoveUpload(id string) error
+}
+
+// FileStore is a Store kept in a directory, one JSON file per job next to
+// its upload. Point it at a persistent volume to keep the queue across pod
+// restarts.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes the job to a temporary file and renames it into place, so a
+// crash leaves either version, never half of one
+func (s *FileStore) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+	path := filepath.Join(s.dir, job.ID+jobSuffix)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write job %s: %w", job.ID, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Load reads every job file in the directory
+func (s *FileStore) Load() ([]*Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job directory: %w", err)
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), jobSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job: %w", err)
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("failed to decode job %s: %w", entry.Name(), err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// SaveUpload copies the upload to disk and syncs it before the job is
+// queued, so an accepted upload is never lost
+func (s *FileStore) SaveUpload(id string, upload io.Reader) (int64, error) {
+	file, err := os.OpenFile(s.uploadPath(id), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store upload: %w", err)
+	}
+	size, err := io.Copy(file, upload)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(s.uploadPath(id))
+		return 0, fmt.Errorf("failed to store upload: %w", err)
+	}
+	return size, nil
+}
+
+// AdoptUpload moves the file into the directory; it must be on the same
+// filesystem, so even a large file moves without being copied
+func (s *FileStore) AdoptUpload(id, path string) (int64, error) {
+	if err := os.Rename(path, s.uploadPath(id)); err != nil {
+		return 0, fmt.Errorf("failed to store upload: %w", err)
+	}
+	stat, err := os.Stat(s.uploadPath(id))
+	if err != nil {
+		return 0, fmt.Errorf("failed to store upload: %w", err)
+	}
+	return stat.Size(), nil
+}
+
+// ReleaseUpload moves an adopted upload back to where it came from
+func (s *FileStore) ReleaseUpload(id, path string) error {
+	if err := os.Rename(s.uploadPath(id), path); err != nil {
+		return fmt.Errorf("failed to release upload: %w", err)
+	}
+	return nil
+}
+
+// OpenUpload opens a job's stored upload
+func (s *FileStore) OpenUpload(id string) (io.ReadCloser, error) {
+	file, err := os.Open(s.uploadPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	return file, nil
+}
+
+// RemoveUpload deletes a job's upload; one already gone is not an error
+func (s *FileStore) RemoveUpload(id string) error {
+	if err := os.Remove(s.uploadPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) uploadPath(id string) string {
+	return filepath.Join(s.dir, id+uploadSuffix)
+}
@@ -0,0 +1,217 @@
+package langdetect
+
+import (
+	"code/internal/models"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// DefaultMinLetters is the least text, in letters, a language is told from
+const DefaultMinLetters = 20
+
+// scriptLanguages are the languages told apart by their script alone
+var scriptLanguages = []struct {
+	language string
+	script   *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"ru", unicode.Cyrillic},
+	{"el", unicode.Greek},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"th", unicode.Thai},
+	{"hi", unicode.Devanagari},
+}
+
+// stopWords are frequent short words of the languages written in Latin
+// script. Some are shared, such as "de"; the words only one language uses
+// decide between them.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "for", "it", "with", "was", "on", "are", "be", "this", "have", "you", "not", "at", "by", "from", "but", "or", "we", "our", "your", "will", "can", "would", "please", "my", "me", "interested", "thanks"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "es", "mit", "den", "von", "zu", "ein", "eine", "auf", "für", "dem", "des", "auch", "wir", "ihr", "uns", "bitte", "sind", "haben", "wie", "aber", "oder", "noch", "nach", "bei", "danke"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "un", "pour", "que", "qui", "dans", "pas", "sur", "avec", "nous", "vous", "je", "ce", "au", "du", "mais", "ou", "sont", "être", "avoir", "merci", "très", "plus", "leur", "votre", "notre"},
+	"es": {"el", "la", "los", "las", "y", "es", "que", "de", "en", "por", "para", "una", "un", "con", "no", "se", "del", "al", "lo", "como", "pero", "más", "su", "sus", "gracias", "muy", "está", "estoy", "nuestro", "nuestra"},
+	"it": {"il", "lo", "la", "gli", "le", "e", "è", "che", "di", "per", "una", "un", "con", "non", "sono", "del", "della", "al", "come", "ma", "più", "grazie", "siamo", "molto", "questo", "anche", "vorrei"},
+	"pt": {"o", "a", "os", "as", "e", "é", "que", "de", "em", "para", "uma", "um", "com", "não", "do", "da", "dos", "das", "no", "na", "mas", "mais", "obrigado", "obrigada", "muito", "você", "está", "estou", "gostaria"},
+	"nl": {"de", "het", "een", "en", "is", "dat", "van", "in", "niet", "ik", "je", "we", "met", "voor", "op", "zijn", "te", "ook", "maar", "wij", "graag", "bedankt", "dit", "er", "onze"},
+}
+
+// latinLanguages lists the stop word languages in a fixed order, so ties
+// are detected the same way every run
+var latinLanguages = []string{"en", "de", "fr", "es", "it", "pt", "nl"}
+
+// Languages lists the ISO 639-1 codes Detect can return
+func Languages() []string {
+	languages := slices.Clone(latinLanguages)
+	for _, s := range scriptLanguages {
+		if !slices.Contains(languages, s.language) {
+			languages = append(languages, s.language)
+		}
+	}
+	return languages
+}
+
+// Detect returns the ISO 639-1 code of the language text is written in, or
+// false when there is too little text or it could be several languages
+func Detect(text string) (string, bool) {
+	return detect(text, DefaultMinLetters, nil)
+}
+
+// detect is Detect limited to the allowed languages, or all when nil
+func detect(text string, minLetters int, allowed map[string]bool) (string, bool) {
+	letters, latin := 0, 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				scripts[s.language]++
+				break
+			}
+		}
+	}
+	if letters < minLetters {
+		return "", false
+	}
+
+	// Japanese mixes kana with Han characters, so any kana makes it Japanese
+	best, bestCount := "", 0
+	for _, s := range scriptLanguages {
+		if count := scripts[s.language]; count > bestCount {
+			best, bestCount = s.language, count
+		}
+	}
+	if scripts["ja"] > 0 && scripts["zh"] > 0 {
+		best, bestCount = "ja", scripts["ja"]+scripts["zh"]
+	}
+	if bestCount > latin {
+		if allowed != nil && !allowed[best] {
+			return "", false
+		}
+		return best, true
+	}
+	return detectLatin(text, allowed)
+}
+
+// detectLatin scores Latin-script text by the stop words of each language.
+// A language needs two stop words and more than any other language.
+func detectLatin(text string, allowed map[string]bool) (string, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	best, bestScore, tied := "", 0, false
+	for _, language := range latinLanguages {
+		if allowed != nil && !allowed[language] {
+			continue
+		}
+		score := 0
+		for _, word := range words {
+			if slices.Contains(stopWords[language], word) {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = language, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if bestScore < 2 || tied {
+		return "", false
+	}
+	return best, true
+}
+
+// Config picks the free-text columns whose language is detected
+type Config struct {
+	// Columns are read in order and their text joined, e.g. a comments field
+	Columns []string `yaml:"columns"`
+	// Languages limits the answers to these ISO 639-1 codes; default all
+	// Detect knows
+	Languages []string `yaml:"languages"`
+	// MinLetters is the least text, in letters, a language is told from;
+	// default 20
+	MinLetters int `yaml:"min_letters"`
+}
+
+// IsZero reports whether no columns are read
+func (c Config) IsZero() bool {
+	return len(c.Columns) == 0
+}
+
+// Validate checks the languages, and that there are columns to read them from
+func (c Config) Validate() error {
+	if c.IsZero() && (len(c.Languages) > 0 || c.MinLetters != 0) {
+		return fmt.Errorf("columns are required")
+	}
+	for _, language := range c.Languages {
+		if !slices.Contains(Languages(), language) {
+			return fmt.Errorf("unknown language %q (known: %s)", language, strings.Join(Languages(), ", "))
+		}
+	}
+	if c.MinLetters < 0 {
+		return fmt.Errorf("min_letters must not be negative")
+	}
+	return nil
+}
+
+// Detector sets the language of leads from their free-text columns
+type Detector struct {
+	cfg      Config
+	allowed  map[string]bool
+	detected atomic.Int64
+}
+
+// New creates a detector for a config
+func New(cfg Config) *Detector {
+	if cfg.MinLetters == 0 {
+		cfg.MinLetters = DefaultMinLetters
+	}
+	d := &Detector{cfg: cfg}
+	if len(cfg.Languages) > 0 {
+		d.allowed = make(map[string]bool, len(cfg.Languages))
+		for _, language := range cfg.Languages {
+			d.allowed[language] = true
+		}
+	}
+	return d
+}
+
+// Detect sets lead's language if it has none and its text tells it; it has
+// the shape of pipeline.Transform
+func (d *Detector) Detect(lead *models.Lead) *models.Lead {
+	if strings.TrimSpace(lead.Language) != "" {
+		return lead
+	}
+	var text []string
+	for _, column := range d.cfg.Columns {
+		if value, ok := lead.RawValue(column); ok {
+			text = append(text, value)
+		}
+	}
+	if language, ok := detect(strings.Join(text, "\n"), d.cfg.MinLetters, d.allowed); ok {
+		lead.Language = language
+		d.detected.Add(1)
+	}
+	return lead
+}
+
+// Count returns how many leads had their language detected
+func (d *Detector) Count() int {
+	return int(d.detected.Load())
+}
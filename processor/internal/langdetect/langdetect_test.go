@@ -0,0 +1,121 @@
+package langdetect
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	t.Run("tells languages written in Latin script apart", func(t *testing.T) {
+		for text, want := range map[string]string{
+			"We are interested in a demo of your product for our sales team":   "en",
+			"Wir haben Interesse an einer Demo und bitte um einen Rückruf":     "de",
+			"Nous sommes intéressés par une démo pour notre équipe, merci":     "fr",
+			"Estamos interesados en una demo para nuestro equipo, gracias":     "es",
+			"Vorrei una demo del prodotto per il nostro team, grazie mille":    "it",
+			"Gostaria de uma demonstração para a nossa equipe, muito obrigado": "pt",
+			"Wij willen graag een demo van het product voor ons team":          "nl",
+		} {
+			// Act
+			language, ok := Detect(text)
+
+			// Assert
+			assert.True(t, ok, text)
+			assert.Equal(t, want, language, text)
+		}
+	})
+
+	t.Run("tells languages from their script", func(t *testing.T) {
+		for text, want := range map[string]string{
+			"Мы хотели бы получить демонстрацию продукта":       "ru",
+			"製品のデモをお願いしたいです。よろしくお願いします":                         "ja",
+			"我们希望为我们的销售团队安排一次产品演示谢谢大家":                          "zh",
+			"영업팀을 위한 제품 데모를 요청하고 싶습니다 감사합니다":                    "ko",
+			"Θα θέλαμε μια επίδειξη του προϊόντος σας παρακαλώ": "el",
+		} {
+			// Act
+			language, ok := Detect(text)
+
+			// Assert
+			assert.True(t, ok, text)
+			assert.Equal(t, want, language, text)
+		}
+	})
+
+	t.Run("gives no answer for short or unclear text", func(t *testing.T) {
+		for _, text := range []string{"", "Call me", "Acme Inc 2024 Q3 budget ASAP", "1234567890 1234567890 1234567890"} {
+			// Act
+			_, ok := Detect(text)
+
+			// Assert
+			assert.False(t, ok, text)
+		}
+	})
+}
+
+func TestDetector_Detect(t *testing.T) {
+	lead := func(comments string) *models.Lead {
+		l := models.NewLead("Jane Smith", "jane@example.com", "Tech Firm", "Website")
+		l.Raw = map[string]string{"Comments": comments, "Notes": ""}
+		return l
+	}
+
+	t.Run("sets the language from the configured columns", func(t *testing.T) {
+		// Arrange
+		detector := New(Config{Columns: []string{"comments", "Notes"}})
+
+		// Act
+		german := detector.Detect(lead("Wir haben Interesse an einer Demo und bitte um einen Rückruf"))
+		unclear := detector.Detect(lead("Demo"))
+
+		// Assert
+		assert.Equal(t, "de", german.Language)
+		assert.Empty(t, unclear.Language)
+		assert.Equal(t, 1, detector.Count())
+	})
+
+	t.Run("keeps a language the lead already has", func(t *testing.T) {
+		// Arrange
+		detector := New(Config{Columns: []string{"Comments"}})
+		l := lead("We are interested in a demo of your product for our sales team")
+		l.Language = "fr"
+
+		// Act
+		detector.Detect(l)
+
+		// Assert
+		assert.Equal(t, "fr", l.Language)
+		assert.Equal(t, 0, detector.Count())
+	})
+
+	t.Run("answers only with the configured languages", func(t *testing.T) {
+		// Arrange
+		detector := New(Config{Columns: []string{"Comments"}, Languages: []string{"en", "de"}})
+
+		// Act
+		l := detector.Detect(lead("Nous sommes intéressés par une démo pour notre équipe, merci"))
+
+		// Assert
+		assert.Empty(t, l.Language)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects unknown languages", func(t *testing.T) {
+		// Act
+		err := Config{Columns: []string{"Comments"}, Languages: []string{"en", "xx"}}.Validate()
+
+		// Assert
+		assert.ErrorContains(t, err, `unknown language "xx"`)
+	})
+
+	t.Run("needs columns", func(t *testing.T) {
+		// Act
+		err := Config{Languages: []string{"en"}}.Validate()
+
+		// Assert
+		assert.ErrorContains(t, err, "columns are required")
+	})
+}
@@ -0,0 +1,258 @@
+package mailchimp
+
+import (
+	"bytes"
+	"code/internal/errcode"
+	"code/internal/models"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Member statuses a new audience member can be given
+const (
+	// StatusPending sends a double opt-in email, so the person confirms
+	// the subscription themselves
+	StatusPending = "pending"
+	// StatusSubscribed subscribes directly; only leads that gave consent are
+	// subscribed this way
+	StatusSubscribed = "subscribed"
+)
+
+// leadFields are the lead values merge fields and tags can be taken from
+var leadFields = []string{"name", "firstName", "lastName", "company", "source", "status", "consentSource"}
+
+// Config holds the Mailchimp settings of the config file
+type Config struct {
+	// APIKey authenticates requests; $MAILCHIMP_API_KEY is used when empty.
+	// Its suffix names the data centre, e.g. us21.
+	APIKey string `yaml:"api_key"`
+
+	// ListID is the audience leads are subscribed to
+	ListID string `yaml:"list_id"`
+
+	// Status is given to new members: pending (default) or subscribed
+	Status string `yaml:"status"`
+
+	// MergeFields maps audience merge tags to lead fields (default FNAME:
+	// firstName, LNAME: lastName)
+	MergeFields map[string]string `yaml:"merge_fields"`
+
+	// TagFields lists lead fields whose values tag the member (default source)
+	TagFields []string `yaml:"tag_fields"`
+
+	// Tags are added to every member, e.g. to mark imported leads
+	Tags []string `yaml:"tags"`
+
+	// APIURL overrides the API base URL derived from the API key
+	APIURL string `yaml:"api_url"`
+}
+
+// Validate checks the status, merge fields and tag fields, normalising names
+func (c *Config) Validate() error {
+	switch strings.ToLower(strings.TrimSpace(c.Status)) {
+	case "", StatusPending, StatusSubscribed:
+		c.Status = strings.ToLower(strings.TrimSpace(c.Status))
+	default:
+		return fmt.Errorf("status: %q is not %s or %s", c.Status, StatusPending, StatusSubscribed)
+	}
+
+	mergeFields := make(map[string]string, len(c.MergeFields))
+	for tag, field := range c.MergeFields {
+		name, ok := leadField(field)
+		if !ok {
+			return fmt.Errorf("merge_fields: %s: unknown lead field %q (allowed: %s)", tag, field, strings.Join(leadFields, ", "))
+		}
+		mergeFields[strings.ToUpper(strings.TrimSpace(tag))] = name
+	}
+	if c.MergeFields != nil {
+		c.MergeFields = mergeFields
+	}
+
+	for i, field := range c.TagFields {
+		name, ok := leadField(field)
+		if !ok {
+			return fmt.Errorf("tag_fields: unknown lead field %q (allowed: %s)", field, strings.Join(leadFields, ", "))
+		}
+		c.TagFields[i] = name
+	}
+	return nil
+}
+
+func leadField(field string) (string, bool) {
+	for _, name := range leadFields {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Client subscribes leads to a Mailchimp audience. It is a processor.Sink,
+// run after the CRM write succeeded.
+type Client struct {
+	baseURL     string
+	apiKey      string
+	listID      string
+	status      string
+	mergeFields map[string]string
+	tagFields   []string
+	tags        []string
+	httpClient  *http.Client
+}
+
+// New creates a client for the audience in cfg, sending requests through httpClient
+func New(cfg Config, httpClient *http.Client) (*Client, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("mailchimp: an API key is required (mailchimp.api_key or MAILCHIMP_API_KEY)")
+	}
+	if cfg.ListID == "" {
+		return nil, fmt.Errorf("mailchimp: list_id is required")
+	}
+
+	baseURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if baseURL == "" {
+		_, dc, ok := strings.Cut(cfg.APIKey, "-")
+		if !ok || dc == "" {
+			return nil, fmt.Errorf("mailchimp: the API key has no data centre suffix, e.g. -us21")
+		}
+		baseURL = fmt.Sprintf("https://%s.api.mailchimp.com/3.0", url.PathEscape(dc))
+	}
+
+	status := cfg.Status
+	if status == "" {
+		status = StatusPending
+	}
+	mergeFields := cfg.MergeFields
+	if mergeFields == nil {
+		mergeFields = map[string]string{"FNAME": "firstName", "LNAME": "lastName"}
+	}
+	tagFields := cfg.TagFields
+	if tagFields == nil {
+		tagFields = []string{"source"}
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Client{
+		baseURL:     baseURL,
+		apiKey:      cfg.APIKey,
+		listID:      cfg.ListID,
+		status:      status,
+		mergeFields: mergeFields,
+		tagFields:   tagFields,
+		tags:        cfg.Tags,
+		httpClient:  httpClient,
+	}, nil
+}
+
+// Name identifies the sink in reports
+func (c *Client) Name() string {
+	return "mailchimp"
+}
+
+// Send adds the lead to the audience or updates its member, then tags it.
+// Existing members keep their subscription status, so someone who
+// unsubscribed is never resubscribed. Leads without consent are only added
+// as pending, which asks the person to confirm.
+func (c *Client) Send(lead *models.Lead) error {
+	if c.status == StatusSubscribed && !lead.ConsentGiven {
+		return nil
+	}
+
+	values := fieldValues(lead)
+	mergeFields := make(map[string]string, len(c.mergeFields))
+	for tag, field := range c.mergeFields {
+		mergeFields[tag] = values[field]
+	}
+
+	memberURL := fmt.Sprintf("%s/lists/%s/members/%s", c.baseURL, url.PathEscape(c.listID), subscriberHash(lead.Email))
+	member := map[string]interface{}{
+		"email_address": lead.Email,
+		"status_if_new": c.status,
+		"merge_fields":  mergeFields,
+	}
+	if err := c.do(http.MethodPut, memberURL, member); err != nil {
+		return fmt.Errorf("failed to subscribe %s: %w", lead.Email, err)
+	}
+
+	var tags []map[string]string
+	for _, field := range c.tagFields {
+		if value := values[field]; value != "" {
+			tags = append(tags, map[string]string{"name": value, "status": "active"})
+		}
+	}
+	for _, tag := range c.tags {
+		tags = append(tags, map[string]string{"name": tag, "status": "active"})
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := c.do(http.MethodPost, memberURL+"/tags", map[string]interface{}{"tags": tags}); err != nil {
+		return fmt.Errorf("failed to tag %s: %w", lead.Email, err)
+	}
+	return nil
+}
+
+// subscriberHash is the member ID Mailchimp derives from an email
+func subscriberHash(email string) string {
+	sum := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// fieldValues renders the lead values merge fields and tags are taken from
+func fieldValues(lead *models.Lead) map[string]string {
+	first, last := lead.Name, ""
+	if i := strings.LastIndex(strings.TrimSpace(lead.Name), " "); i > 0 {
+		first, last = strings.TrimSpace(lead.Name[:i]), strings.TrimSpace(lead.Name[i+1:])
+	}
+	return map[string]string{
+		"name":          lead.Name,
+		"firstName":     first,
+		"lastName":      last,
+		"company":       lead.Company,
+		"source":        lead.Source,
+		"status":        lead.Status,
+		"consentSource": lead.ConsentSource,
+	}
+}
+
+func (c *Client) do(method, apiURL string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequest(method, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("lead-processor", c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Errors are application/problem+json
+		var problem struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		}
+		json.NewDecoder(resp.Body).Decode(&problem)
+		err := &errcode.StatusError{Status: resp.StatusCode}
+		if problem.Detail != "" {
+			return fmt.Errorf("%s: %s: %w", problem.Title, problem.Detail, err)
+		}
+		return err
+	}
+	return nil
+}
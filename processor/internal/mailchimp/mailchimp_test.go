@@ -0,0 +1,126 @@
+package mailchimp
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMailchimp records member and tag requests
+type fakeMailchimp struct {
+	paths  []string
+	member map[string]interface{}
+	tags   []map[string]string
+}
+
+func (f *fakeMailchimp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, key, _ := r.BasicAuth(); key != "0123abcd-us21" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"title":"API Key Invalid","status":401,"detail":"Your API key may be invalid, or you've attempted to access the wrong datacenter."}`))
+		return
+	}
+	f.paths = append(f.paths, r.Method+" "+r.URL.Path)
+	if r.Method == http.MethodPut {
+		json.NewDecoder(r.Body).Decode(&f.member)
+		w.Write([]byte(`{"id":"b642b4217b34b1e8d3bd915fc65c4452","status":"pending"}`))
+		return
+	}
+	var body struct {
+		Tags []map[string]string `json:"tags"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.tags = body.Tags
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestClient_Send(t *testing.T) {
+	t.Run("subscribes the member with merge fields and tags", func(t *testing.T) {
+		// Arrange
+		fake := &fakeMailchimp{}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+		client, err := New(Config{APIKey: "0123abcd-us21", ListID: "a1b2c3", APIURL: server.URL, Tags: []string{"imported"}}, nil)
+		assert.NoError(t, err)
+
+		// Act
+		err = client.Send(models.NewLead("Mary Ann Lee", "Mary@Acme.com", "Acme", "Webinar"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"PUT /lists/a1b2c3/members/" + subscriberHash("mary@acme.com"),
+			"POST /lists/a1b2c3/members/" + subscriberHash("mary@acme.com") + "/tags",
+		}, fake.paths)
+		assert.Equal(t, "pending", fake.member["status_if_new"])
+		assert.Equal(t, map[string]interface{}{"FNAME": "Mary Ann", "LNAME": "Lee"}, fake.member["merge_fields"])
+		assert.Equal(t, []map[string]string{{"name": "Webinar", "status": "active"}, {"name": "imported", "status": "active"}}, fake.tags)
+	})
+
+	t.Run("only subscribes leads that gave consent directly", func(t *testing.T) {
+		// Arrange
+		fake := &fakeMailchimp{}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+		client, _ := New(Config{APIKey: "0123abcd-us21", ListID: "a1b2c3", APIURL: server.URL, Status: StatusSubscribed}, nil)
+		consented := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+		consented.ConsentGiven = true
+
+		// Act
+		client.Send(models.NewLead("John Doe", "john@acme.com", "Acme", "Webinar"))
+		err := client.Send(consented)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, fake.paths, 2)
+		assert.Equal(t, "jane@techfirm.com", fake.member["email_address"])
+		assert.Equal(t, "subscribed", fake.member["status_if_new"])
+	})
+
+	t.Run("classifies a refused API key", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(&fakeMailchimp{})
+		defer server.Close()
+		client, _ := New(Config{APIKey: "wrong-us21", ListID: "a1b2c3", APIURL: server.URL}, nil)
+
+		// Act
+		err := client.Send(models.NewLead("John Doe", "john@acme.com", "Acme", "Webinar"))
+
+		// Assert
+		assert.ErrorIs(t, err, errcode.ErrAuth)
+		assert.ErrorContains(t, err, "API Key Invalid")
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("derives the API URL from the key's data centre", func(t *testing.T) {
+		// Act
+		client, err := New(Config{APIKey: "0123abcd-us21", ListID: "a1b2c3"}, nil)
+		_, noDC := New(Config{APIKey: "0123abcd", ListID: "a1b2c3"}, nil)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "https://us21.api.mailchimp.com/3.0", client.baseURL)
+		assert.Error(t, noDC)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects unknown lead fields and statuses", func(t *testing.T) {
+		// Arrange
+		badField := Config{MergeFields: map[string]string{"PHONE": "phone"}}
+		badStatus := Config{Status: "cleaned"}
+
+		// Act
+		fieldErr := badField.Validate()
+		statusErr := badStatus.Validate()
+
+		// Assert
+		assert.Error(t, fieldErr)
+		assert.Error(t, statusErr)
+	})
+}
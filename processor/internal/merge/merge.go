@@ -0,0 +1,265 @@
+package merge
+
+import (
+	"code/internal/auditlog"
+	"code/internal/models"
+	"code/internal/processor"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rule decides which of two leads a merged field takes its value from
+type Rule string
+
+const (
+	// RulePrimary keeps the primary lead's value
+	RulePrimary Rule = "primary"
+	// RuleDuplicate takes the duplicate lead's value
+	RuleDuplicate Rule = "duplicate"
+	// RuleNewest takes the value of whichever lead was updated last
+	RuleNewest Rule = "newest"
+)
+
+// Sides a merged value can come from, as recorded in the audit log
+const (
+	SidePrimary   = "primary"
+	SideDuplicate = "duplicate"
+)
+
+// Merge outcomes recorded in the audit log
+const (
+	OutcomeMerged = "merged"
+	OutcomeFailed = "failed"
+)
+
+// ParseRule parses a precedence rule
+func ParseRule(value string) (Rule, error) {
+	switch Rule(strings.ToLower(strings.TrimSpace(value))) {
+	case RulePrimary:
+		return RulePrimary, nil
+	case RuleDuplicate:
+		return RuleDuplicate, nil
+	case RuleNewest:
+		return RuleNewest, nil
+	}
+	return "", fmt.Errorf("invalid merge rule %q (use %s, %s or %s)", value, RulePrimary, RuleDuplicate, RuleNewest)
+}
+
+// Policy maps fields, by JSON name, to the rule that decides their merged
+// value. Fields it doesn't mention keep the primary lead's value.
+type Policy map[string]Rule
+
+// ParsePolicy parses field=rule pairs, e.g. from --prefer or the config's
+// merge_precedence
+func ParsePolicy(values map[string]string) (Policy, error) {
+	policy := make(Policy, len(values))
+	for field, value := range values {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if !isMergeableField(name) {
+			return nil, fmt.Errorf("field %q cannot be merged (allowed: %s)", field, strings.Join(models.ProtectableFields(), ", "))
+		}
+		rule, err := ParseRule(value)
+		if err != nil {
+			return nil, err
+		}
+		policy[name] = rule
+	}
+	return policy, nil
+}
+
+func isMergeableField(field string) bool {
+	for _, name := range models.ProtectableFields() {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields combines primary and duplicate into the merged lead, returning it
+// with the side each field's value came from. The merged lead keeps the
+// primary's ID and email; a rule never replaces a value with a blank one.
+// Consent given on either lead is kept, since both describe the same person.
+func Fields(primary, duplicate *models.Lead, policy Policy) (*models.Lead, map[string]string) {
+	merged := *primary
+	merged.Raw = nil
+	sources := make(map[string]string)
+
+	for _, field := range models.ProtectableFields() {
+		primaryValue, _ := primary.Field(field)
+		duplicateValue, _ := duplicate.Field(field)
+
+		side := SidePrimary
+		switch policy[field] {
+		case RuleDuplicate:
+			side = SideDuplicate
+		case RuleNewest:
+			if updatedAt(duplicate).After(updatedAt(primary)) {
+				side = SideDuplicate
+			}
+		}
+		if side == SideDuplicate && strings.TrimSpace(duplicateValue) == "" {
+			side = SidePrimary
+		}
+		if side == SidePrimary && strings.TrimSpace(primaryValue) == "" && strings.TrimSpace(duplicateValue) != "" {
+			side = SideDuplicate
+		}
+
+		if side == SideDuplicate {
+			merged.SetField(field, duplicateValue)
+		}
+		sources[field] = side
+	}
+
+	if !primary.ConsentGiven && duplicate.ConsentGiven {
+		merged.ConsentGiven = true
+		merged.ConsentTimestamp = duplicate.ConsentTimestamp
+		merged.ConsentSource = duplicate.ConsentSource
+		sources["consent"] = SideDuplicate
+	}
+
+	return &merged, sources
+}
+
+// updatedAt is when the lead last changed, falling back to its creation
+func updatedAt(lead *models.Lead) time.Time {
+	if lead.UpdatedAt != nil {
+		return *lead.UpdatedAt
+	}
+	return lead.CreatedAt
+}
+
+// Client is the subset of the API client needed to merge leads
+type Client interface {
+	LookupLead(email string) (*processor.LookupResponse, error)
+	MergeLeads(primaryID, duplicateID string, merged *models.Lead) (*models.Lead, error)
+}
+
+// Record is one entry in the merge audit log
+type Record struct {
+	PrimaryID      string            `json:"primaryId,omitempty"`
+	DuplicateID    string            `json:"duplicateId,omitempty"`
+	PrimaryEmail   string            `json:"primaryEmail"`
+	DuplicateEmail string            `json:"duplicateEmail"`
+	Sources        map[string]string `json:"sources,omitempty"`
+	Outcome        string            `json:"outcome"`
+	Error          string            `json:"error,omitempty"`
+	Operator       string            `json:"operator,omitempty"`
+	MergedAt       time.Time         `json:"mergedAt"`
+}
+
+// DuplicateFields lists, sorted, the fields whose merged value came from the duplicate
+func (r Record) DuplicateFields() []string {
+	var fields []string
+	for field, side := range r.Sources {
+		if side == SideDuplicate {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// AuditLog is an append-only NDJSON file of merge records. Each is synced to
+// disk, so a merge, which can't be undone, is on record even if the process
+// dies right after.
+type AuditLog = auditlog.Log[Record]
+
+// OpenAuditLog opens (creating if needed) the audit log at path for appending
+func OpenAuditLog(path string) (*AuditLog, error) {
+	audit, err := auditlog.Open[Record](path, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open merge audit log: %w", err)
+	}
+	return audit, nil
+}
+
+// Merger looks up two leads by email and merges the duplicate into the
+// primary, recording every attempt in the audit log
+type Merger struct {
+	client   Client
+	audit    *AuditLog
+	policy   Policy
+	operator string
+}
+
+// New creates a merger; operator identifies who requested the merge in the audit log
+func New(client Client, audit *AuditLog, policy Policy, operator string) *Merger {
+	return &Merger{
+		client:   client,
+		audit:    audit,
+		policy:   policy,
+		operator: operator,
+	}
+}
+
+// Plan looks up both leads and returns the merged lead without changing
+// anything, for previews
+func (m *Merger) Plan(primaryEmail, duplicateEmail string) (Record, *models.Lead, error) {
+	record := Record{PrimaryEmail: primaryEmail, DuplicateEmail: duplicateEmail, Operator: m.operator}
+	primary, duplicate, err := m.lookup(primaryEmail, duplicateEmail)
+	if err != nil {
+		return record, nil, err
+	}
+	record.PrimaryID = primary.ID
+	record.DuplicateID = duplicate.ID
+
+	merged, sources := Fields(primary, duplicate, m.policy)
+	record.Sources = sources
+	return record, merged, nil
+}
+
+// Merge merges the lead with duplicateEmail into the one with primaryEmail
+// and returns its audit record and the stored lead. API failures are
+// reported in the record; an error is only returned when the audit record
+// itself could not be written.
+func (m *Merger) Merge(primaryEmail, duplicateEmail string) (Record, *models.Lead, error) {
+	record, merged, err := m.Plan(primaryEmail, duplicateEmail)
+	var stored *models.Lead
+	if err == nil {
+		stored, err = m.client.MergeLeads(record.PrimaryID, record.DuplicateID, merged)
+		if err != nil {
+			err = fmt.Errorf("merge failed: %w", err)
+		}
+	}
+
+	record.Outcome = OutcomeMerged
+	if err != nil {
+		record.Outcome = OutcomeFailed
+		record.Error = err.Error()
+	}
+	record.MergedAt = time.Now().UTC()
+
+	if err := m.audit.Append(record); err != nil {
+		return record, nil, err
+	}
+	return record, stored, nil
+}
+
+func (m *Merger) lookup(primaryEmail, duplicateEmail string) (*models.Lead, *models.Lead, error) {
+	primary, err := m.find(primaryEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	duplicate, err := m.find(duplicateEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	if primary.ID == duplicate.ID {
+		return nil, nil, fmt.Errorf("%s and %s are the same lead", primaryEmail, duplicateEmail)
+	}
+	return primary, duplicate, nil
+}
+
+func (m *Merger) find(email string) (*models.Lead, error) {
+	lookup, err := m.client.LookupLead(email)
+	if err != nil {
+		return nil, fmt.Errorf("lookup of %s failed: %w", email, err)
+	}
+	if !lookup.Found || lookup.Lead == nil {
+		return nil, fmt.Errorf("no lead with email %s", email)
+	}
+	return lookup.Lead, nil
+}
@@ -0,0 +1,186 @@
+package merge
+
+import (
+	"bufio"
+	"code/internal/models"
+	"code/internal/processor"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockClient merges leads held in memory
+type mockClient struct {
+	leads      map[string]*models.Lead
+	mergeError error
+	merged     *models.Lead
+	ids        []string
+}
+
+func (m *mockClient) LookupLead(email string) (*processor.LookupResponse, error) {
+	lead, ok := m.leads[email]
+	return &processor.LookupResponse{Found: ok, Lead: lead}, nil
+}
+
+func (m *mockClient) MergeLeads(primaryID, duplicateID string, merged *models.Lead) (*models.Lead, error) {
+	m.ids = []string{primaryID, duplicateID}
+	m.merged = merged
+	return merged, m.mergeError
+}
+
+func newLead(id, name, email, company string, updated time.Time) *models.Lead {
+	lead := models.NewLead(name, email, company, "LinkedIn")
+	lead.ID = id
+	lead.UpdatedAt = &updated
+	return lead
+}
+
+func readAuditLog(t *testing.T, path string) []Record {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestFields(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+
+	t.Run("keeps the primary's values unless a rule says otherwise", func(t *testing.T) {
+		// Arrange
+		primary := newLead("1", "Jane Smith", "jane@techfirm.com", "Tech Firm", older)
+		duplicate := newLead("2", "J. Smith", "jsmith@techfirm.com", "TechFirm Inc", newer)
+
+		// Act
+		merged, sources := Fields(primary, duplicate, Policy{"company": RuleDuplicate})
+
+		// Assert
+		assert.Equal(t, "1", merged.ID)
+		assert.Equal(t, "jane@techfirm.com", merged.Email)
+		assert.Equal(t, "Jane Smith", merged.Name)
+		assert.Equal(t, "TechFirm Inc", merged.Company)
+		assert.Equal(t, SidePrimary, sources["name"])
+		assert.Equal(t, SideDuplicate, sources["company"])
+	})
+
+	t.Run("takes the newest value and never a blank one", func(t *testing.T) {
+		// Arrange
+		primary := newLead("1", "Jane Smith", "jane@techfirm.com", "", newer)
+		duplicate := newLead("2", "J. Smith", "jsmith@techfirm.com", "Tech Firm", older)
+		duplicate.Status = "qualified"
+
+		// Act
+		merged, _ := Fields(primary, duplicate, Policy{"name": RuleNewest, "status": RulePrimary})
+
+		// Assert
+		assert.Equal(t, "Jane Smith", merged.Name)
+		assert.Equal(t, "Tech Firm", merged.Company)
+		assert.Equal(t, "qualified", merged.Status)
+	})
+
+	t.Run("keeps consent given on the duplicate", func(t *testing.T) {
+		// Arrange
+		primary := newLead("1", "Jane Smith", "jane@techfirm.com", "Tech Firm", older)
+		duplicate := newLead("2", "Jane Smith", "jsmith@techfirm.com", "Tech Firm", older)
+		duplicate.ConsentGiven = true
+		duplicate.ConsentSource = "webform"
+
+		// Act
+		merged, sources := Fields(primary, duplicate, nil)
+
+		// Assert
+		assert.True(t, merged.ConsentGiven)
+		assert.Equal(t, "webform", merged.ConsentSource)
+		assert.Equal(t, SideDuplicate, sources["consent"])
+	})
+}
+
+func TestParsePolicy(t *testing.T) {
+	t.Run("parses field rules", func(t *testing.T) {
+		// Act
+		policy, err := ParsePolicy(map[string]string{"Company": "Newest"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Policy{"company": RuleNewest}, policy)
+	})
+
+	t.Run("rejects unknown fields and rules", func(t *testing.T) {
+		// Act
+		_, fieldErr := ParsePolicy(map[string]string{"email": "primary"})
+		_, ruleErr := ParsePolicy(map[string]string{"name": "longest"})
+
+		// Assert
+		assert.Error(t, fieldErr)
+		assert.Error(t, ruleErr)
+	})
+}
+
+func TestMerger_Merge(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("merges the duplicate and records it", func(t *testing.T) {
+		// Arrange
+		client := &mockClient{leads: map[string]*models.Lead{
+			"jane@techfirm.com":   newLead("1", "Jane Smith", "jane@techfirm.com", "Tech Firm", older),
+			"jsmith@techfirm.com": newLead("2", "J. Smith", "jsmith@techfirm.com", "TechFirm Inc", older),
+		}}
+		path := filepath.Join(t.TempDir(), "merges.ndjson")
+		audit, _ := OpenAuditLog(path)
+		defer audit.Close()
+		merger := New(client, audit, Policy{"company": RuleDuplicate}, "ops")
+
+		// Act
+		record, stored, err := merger.Merge("jane@techfirm.com", "jsmith@techfirm.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, OutcomeMerged, record.Outcome)
+		assert.Equal(t, []string{"1", "2"}, client.ids)
+		assert.Equal(t, "TechFirm Inc", stored.Company)
+		assert.Equal(t, []string{"company"}, record.DuplicateFields())
+
+		records := readAuditLog(t, path)
+		assert.Len(t, records, 1)
+		assert.Equal(t, "ops", records[0].Operator)
+		assert.Equal(t, "2", records[0].DuplicateID)
+	})
+
+	t.Run("records failures without merging", func(t *testing.T) {
+		// Arrange
+		lead := newLead("1", "Jane Smith", "jane@techfirm.com", "Tech Firm", older)
+		client := &mockClient{leads: map[string]*models.Lead{
+			"jane@techfirm.com": lead,
+			"jane@techfirm.org": lead,
+		}}
+		path := filepath.Join(t.TempDir(), "merges.ndjson")
+		audit, _ := OpenAuditLog(path)
+		defer audit.Close()
+		merger := New(client, audit, nil, "")
+
+		// Act
+		same, _, sameErr := merger.Merge("jane@techfirm.com", "jane@techfirm.org")
+		missing, _, missingErr := merger.Merge("jane@techfirm.com", "nobody@techfirm.com")
+
+		// Assert
+		assert.NoError(t, sameErr)
+		assert.NoError(t, missingErr)
+		assert.Equal(t, OutcomeFailed, same.Outcome)
+		assert.Contains(t, same.Error, "same lead")
+		assert.Equal(t, OutcomeFailed, missing.Outcome)
+		assert.Nil(t, client.ids)
+		assert.Len(t, readAuditLog(t, path), 2)
+	})
+}
@@ -0,0 +1,404 @@
+package metaleads
+
+import (
+	"code/internal/csv"
+	"code/internal/errcode"
+	"code/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIURL is the Meta Graph API, including the version the reader was written against
+const APIURL = "https://graph.facebook.com/v19.0"
+
+// inputPrefix starts a process input naming Lead Ads forms, e.g. meta:123,456
+const inputPrefix = "meta:"
+
+// pageSize is how many leads are requested per page
+const pageSize = 100
+
+// timeLayout is how the Graph API formats created_time
+const timeLayout = "2006-01-02T15:04:05-0700"
+
+// Metadata columns added to every lead, after the form's own fields
+const (
+	ColumnSource      = "source"
+	ColumnLeadID      = "meta_lead_id"
+	ColumnFormID      = "meta_form_id"
+	ColumnCreatedTime = "meta_created_time"
+	ColumnPlatform    = "meta_platform"
+)
+
+// standardFields are Lead Ads' prefilled questions for the core lead fields,
+// put first so they are read by position when the config has no mapping
+var standardFields = []string{"full_name", "email", "company_name"}
+
+// Graph API error codes for refused tokens and throttling
+var (
+	authErrorCodes      = map[int]bool{102: true, 190: true}
+	rateLimitErrorCodes = map[int]bool{4: true, 17: true, 32: true, 613: true}
+)
+
+// IsInput reports whether s names Lead Ads forms rather than a file
+func IsInput(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), inputPrefix)
+}
+
+// ParseInput returns the form IDs of an input such as meta:123,456
+func ParseInput(input string) ([]string, error) {
+	var forms []string
+	for _, id := range strings.Split(strings.TrimPrefix(strings.TrimSpace(input), inputPrefix), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			forms = append(forms, id)
+		}
+	}
+	if !IsInput(input) || len(forms) == 0 {
+		return nil, fmt.Errorf("not a Meta Lead Ads input: %s (use meta:<form-id>[,<form-id>...])", input)
+	}
+	return forms, nil
+}
+
+// Cursor records, per form, the creation time of the newest lead already
+// processed, so each run only pulls leads submitted since the last one
+type Cursor struct {
+	Forms     map[string]int64 `json:"forms"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// LoadCursor reads the cursor at path, returning an empty cursor if there is none
+func LoadCursor(path string) (*Cursor, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Cursor{Forms: make(map[string]int64)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Meta cursor: %w", err)
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("failed to parse Meta cursor %s: %w", path, err)
+	}
+	if cursor.Forms == nil {
+		cursor.Forms = make(map[string]int64)
+	}
+	return &cursor, nil
+}
+
+// Save writes the cursor to path, replacing the previous one atomically
+func (c *Cursor) Save(path string) error {
+	c.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write Meta cursor: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write Meta cursor: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write Meta cursor: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write Meta cursor: %w", err)
+	}
+	return nil
+}
+
+// Option configures a Reader
+type Option func(*Reader)
+
+// WithHTTPClient sets the HTTP client used for Graph API requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Reader) {
+		r.client = client
+	}
+}
+
+// WithAPIURL points the reader at another Graph API endpoint, e.g. a test server
+func WithAPIURL(apiURL string) Option {
+	return func(r *Reader) {
+		r.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+}
+
+// WithSource fills the source column of every lead, since Lead Ads forms
+// don't ask for one
+func WithSource(source string) Option {
+	return func(r *Reader) {
+		r.source = source
+	}
+}
+
+// Reader reads the leads submitted to Lead Ads forms since a cursor, laid
+// out like a CSV file: one column per form question plus metadata columns.
+// The leads are fetched once, on first use.
+type Reader struct {
+	forms     []string
+	pageToken string
+	since     *Cursor
+	reader    *csv.CSVReader
+	client    *http.Client
+	apiURL    string
+	source    string
+
+	once   sync.Once
+	header []string
+	rows   [][]string
+	next   *Cursor
+	hash   string
+	err    error
+}
+
+// NewReader creates a reader for the forms, authenticating with a page
+// access token and pulling leads newer than since
+func NewReader(forms []string, pageToken string, since *Cursor, reader *csv.CSVReader, opts ...Option) (*Reader, error) {
+	if len(forms) == 0 {
+		return nil, fmt.Errorf("no Lead Ads forms given")
+	}
+	if strings.TrimSpace(pageToken) == "" {
+		return nil, fmt.Errorf("a Meta page access token is required")
+	}
+	if since == nil {
+		since = &Cursor{Forms: make(map[string]int64)}
+	}
+
+	r := &Reader{
+		forms:     forms,
+		pageToken: pageToken,
+		since:     since,
+		reader:    reader,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		apiURL:    APIURL,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Name identifies the forms
+func (r *Reader) Name() string {
+	return inputPrefix + strings.Join(r.forms, ",")
+}
+
+// Hash returns the SHA-256 of the pulled leads and the cursor they were pulled from
+func (r *Reader) Hash() (string, error) {
+	if err := r.fetch(); err != nil {
+		return "", err
+	}
+	return r.hash, nil
+}
+
+// ReadHeader returns the column names
+func (r *Reader) ReadHeader() ([]string, error) {
+	if err := r.fetch(); err != nil {
+		return nil, err
+	}
+	return r.header, nil
+}
+
+// StreamLeads converts the pulled leads, oldest first
+func (r *Reader) StreamLeads(emit func(*models.Lead) error) error {
+	if err := r.fetch(); err != nil {
+		return err
+	}
+	return r.reader.StreamRecords(r.header, r.rows, emit)
+}
+
+// Cursor returns the cursor advanced past the pulled leads. Save it once the
+// leads are processed; until then the next run pulls them again.
+func (r *Reader) Cursor() (*Cursor, error) {
+	if err := r.fetch(); err != nil {
+		return nil, err
+	}
+	return r.next, nil
+}
+
+func (r *Reader) fetch() error {
+	r.once.Do(func() {
+		r.err = r.load()
+	})
+	return r.err
+}
+
+// graphLead is one lead as the Graph API returns it
+type graphLead struct {
+	ID          string `json:"id"`
+	CreatedTime string `json:"created_time"`
+	Platform    string `json:"platform"`
+	FieldData   []struct {
+		Name   string   `json:"name"`
+		Values []string `json:"values"`
+	} `json:"field_data"`
+
+	formID  string
+	created time.Time
+}
+
+// load pulls every form's new leads and lays them out as rows
+func (r *Reader) load() error {
+	r.next = &Cursor{Forms: make(map[string]int64)}
+	for form, created := range r.since.Forms {
+		r.next.Forms[form] = created
+	}
+
+	var leads []*graphLead
+	for _, form := range r.forms {
+		formLeads, err := r.formLeads(form, r.since.Forms[form])
+		if err != nil {
+			return err
+		}
+		for _, lead := range formLeads {
+			if created := lead.created.Unix(); created > r.next.Forms[form] {
+				r.next.Forms[form] = created
+			}
+		}
+		leads = append(leads, formLeads...)
+	}
+	sort.SliceStable(leads, func(i, j int) bool {
+		return leads[i].created.Before(leads[j].created)
+	})
+
+	// Standard questions first, then the forms' own questions as they appear
+	columns := append([]string(nil), standardFields...)
+	seen := make(map[string]bool)
+	for _, column := range columns {
+		seen[column] = true
+	}
+	for _, lead := range leads {
+		for _, field := range lead.FieldData {
+			if !seen[field.Name] {
+				seen[field.Name] = true
+				columns = append(columns, field.Name)
+			}
+		}
+	}
+	if !seen[ColumnSource] {
+		columns = append(columns, ColumnSource)
+	}
+	r.header = append(columns, ColumnLeadID, ColumnFormID, ColumnCreatedTime, ColumnPlatform)
+
+	for _, lead := range leads {
+		values := map[string]string{
+			ColumnSource:      r.source,
+			ColumnLeadID:      lead.ID,
+			ColumnFormID:      lead.formID,
+			ColumnCreatedTime: lead.CreatedTime,
+			ColumnPlatform:    lead.Platform,
+		}
+		for _, field := range lead.FieldData {
+			values[field.Name] = strings.Join(field.Values, ", ")
+		}
+		row := make([]string, len(r.header))
+		for i, column := range r.header {
+			row[i] = values[column]
+		}
+		r.rows = append(r.rows, row)
+	}
+
+	data, _ := json.Marshal(struct {
+		Since map[string]int64
+		Rows  [][]string
+	}{r.since.Forms, r.rows})
+	sum := sha256.Sum256(data)
+	r.hash = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// formLeads pages through the leads of a form created after since (Unix seconds)
+func (r *Reader) formLeads(form string, since int64) ([]*graphLead, error) {
+	query := url.Values{
+		"fields": {"id,created_time,field_data,platform"},
+		"limit":  {fmt.Sprint(pageSize)},
+	}
+	if since > 0 {
+		query.Set("filtering", fmt.Sprintf(`[{"field":"time_created","operator":"GREATER_THAN","value":%d}]`, since))
+	}
+	next := fmt.Sprintf("%s/%s/leads?%s", r.apiURL, url.PathEscape(form), query.Encode())
+
+	var leads []*graphLead
+	for next != "" {
+		var page struct {
+			Data   []*graphLead `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := r.get(next, &page); err != nil {
+			return nil, fmt.Errorf("failed to read leads of form %s: %w", form, err)
+		}
+		for _, lead := range page.Data {
+			created, err := time.Parse(timeLayout, lead.CreatedTime)
+			if err != nil {
+				return nil, fmt.Errorf("lead %s of form %s has an invalid created_time %q", lead.ID, form, lead.CreatedTime)
+			}
+			lead.formID = form
+			lead.created = created
+			leads = append(leads, lead)
+		}
+		next = page.Paging.Next
+	}
+	return leads, nil
+}
+
+// get fetches a Graph API URL into v. The token is sent as a header, not in
+// the URL, so it doesn't end up in error messages and logs.
+func (r *Reader) get(apiURL string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.pageToken)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return errcode.Network(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+				Code    int    `json:"code"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+
+		err := error(&errcode.StatusError{Status: resp.StatusCode})
+		switch {
+		case authErrorCodes[body.Error.Code]:
+			err = errcode.Classify(errcode.ErrAuth, err)
+		case rateLimitErrorCodes[body.Error.Code]:
+			err = errcode.Classify(errcode.ErrRateLimited, err)
+		}
+		if body.Error.Message != "" {
+			return fmt.Errorf("%s: %w", body.Error.Message, err)
+		}
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("malformed Graph API response: %w", err)
+	}
+	return nil
+}
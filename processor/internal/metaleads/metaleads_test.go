@@ -0,0 +1,110 @@
+package metaleads
+
+import (
+	"code/internal/csv"
+	"code/internal/errcode"
+	"code/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInput(t *testing.T) {
+	t.Run("splits form IDs", func(t *testing.T) {
+		// Act
+		forms, err := ParseInput("meta:123, 456")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"123", "456"}, forms)
+	})
+
+	t.Run("rejects inputs without forms", func(t *testing.T) {
+		// Act
+		_, err := ParseInput("meta:")
+
+		// Assert
+		assert.Error(t, err)
+		assert.False(t, IsInput("leads.csv"))
+	})
+}
+
+func TestReader(t *testing.T) {
+	t.Run("pulls new leads across pages and advances the cursor", func(t *testing.T) {
+		// Arrange
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer page-t0ken", r.Header.Get("Authorization"))
+			assert.Equal(t, "/111/leads", r.URL.Path)
+			if r.URL.Query().Get("after") == "" {
+				assert.Contains(t, r.URL.Query().Get("filtering"), `"value":1700000000`)
+				w.Write([]byte(`{"data":[{"id":"l2","created_time":"2026-10-16T10:05:00+0000","platform":"ig","field_data":[{"name":"email","values":["john@acme.com"]},{"name":"full_name","values":["John Doe"]},{"name":"company_name","values":["Acme"]}]}],
+					"paging":{"next":"` + server.URL + `/111/leads?after=abc"}}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"id":"l1","created_time":"2026-10-16T10:00:00+0000","platform":"fb","field_data":[{"name":"full_name","values":["Jane Smith"]},{"name":"email","values":["jane@techfirm.com"]},{"name":"company_name","values":["Tech Firm"]},{"name":"interests","values":["CRM","Email"]}]}]}`))
+		}))
+		defer server.Close()
+
+		since := &Cursor{Forms: map[string]int64{"111": 1700000000}}
+		reader, err := NewReader([]string{"111"}, "page-t0ken", since, csv.NewCSVReader(), WithAPIURL(server.URL), WithSource("Website"))
+		assert.NoError(t, err)
+
+		// Act
+		header, _ := reader.ReadHeader()
+		var leads []*models.Lead
+		err = reader.StreamLeads(func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+		cursor, _ := reader.Cursor()
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"full_name", "email", "company_name", "interests", "source", "meta_lead_id", "meta_form_id", "meta_created_time", "meta_platform"}, header)
+		assert.Len(t, leads, 2)
+		assert.Equal(t, "jane@techfirm.com", leads[0].Email, "oldest lead first")
+		assert.Equal(t, "Website", leads[0].Source)
+		assert.Equal(t, "Acme", leads[1].Company)
+		assert.Equal(t, time.Date(2026, 10, 16, 10, 5, 0, 0, time.UTC).Unix(), cursor.Forms["111"])
+		assert.Equal(t, int64(1700000000), since.Forms["111"], "the loaded cursor is left alone")
+	})
+
+	t.Run("classifies refused tokens", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"Error validating access token","code":190}}`))
+		}))
+		defer server.Close()
+		reader, _ := NewReader([]string{"111"}, "expired", nil, csv.NewCSVReader(), WithAPIURL(server.URL))
+
+		// Act
+		_, err := reader.ReadHeader()
+
+		// Assert
+		assert.ErrorIs(t, err, errcode.ErrAuth)
+		assert.ErrorContains(t, err, "Error validating access token")
+	})
+}
+
+func TestCursor(t *testing.T) {
+	t.Run("round-trips through a file", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "meta.cursor.json")
+		empty, _ := LoadCursor(path)
+
+		// Act
+		err := (&Cursor{Forms: map[string]int64{"111": 42}}).Save(path)
+		loaded, _ := LoadCursor(path)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, empty.Forms)
+		assert.Equal(t, int64(42), loaded.Forms["111"])
+	})
+}
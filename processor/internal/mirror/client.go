@@ -0,0 +1,87 @@
+package mirror
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+)
+
+// Client answers lookups from a mirror and sends writes to the API,
+// keeping the mirror up to date with what the API returns. Other calls are
+// passed through to the wrapped client.
+type Client struct {
+	processor.Passthrough
+	mirror *Mirror
+}
+
+// NewClient wraps next so lookups are answered from m
+func NewClient(next processor.APIClient, m *Mirror) *Client {
+	return &Client{Passthrough: processor.Passthrough{Next: next}, mirror: m}
+}
+
+// LookupLead finds the lead in the mirror without calling the API
+func (c *Client) LookupLead(email string) (*processor.LookupResponse, error) {
+	lead, ok := c.mirror.Get(email)
+	return &processor.LookupResponse{Found: ok, Lead: lead}, nil
+}
+
+// CreateLead creates the lead and adds it to the mirror
+func (c *Client) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	created, err := c.Next.CreateLead(lead)
+	if err != nil {
+		return nil, err
+	}
+	c.store(created, lead)
+	return created, nil
+}
+
+// UpdateLead updates the lead and the mirror's copy
+func (c *Client) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	updated, err := c.Next.UpdateLead(lead)
+	if err != nil {
+		return nil, err
+	}
+	c.store(updated, lead)
+	return updated, nil
+}
+
+// PatchLead sends a partial update, when the wrapped client can
+func (c *Client) PatchLead(id string, changes map[string]string) (*models.Lead, error) {
+	patched, err := c.Passthrough.PatchLead(id, changes)
+	if err != nil {
+		return nil, err
+	}
+	if patched != nil {
+		c.mirror.Put(patched)
+	}
+	return patched, nil
+}
+
+// ArchiveLead archives the lead and drops it from the mirror, as archived
+// leads no longer turn up in lookups, when the wrapped client can archive
+func (c *Client) ArchiveLead(id string) error {
+	if err := c.Passthrough.ArchiveLead(id); err != nil {
+		return err
+	}
+	c.mirror.Delete(id)
+	return nil
+}
+
+// DeleteLead deletes the lead and drops it from the mirror, when the
+// wrapped client can delete
+func (c *Client) DeleteLead(id string) error {
+	if err := c.Passthrough.DeleteLead(id); err != nil {
+		return err
+	}
+	c.mirror.Delete(id)
+	return nil
+}
+
+// store records the API's copy of a written lead, or the lead as sent when
+// the API returned none
+func (c *Client) store(returned, sent *models.Lead) {
+	if returned != nil && returned.Email != "" {
+		c.mirror.Put(returned)
+		return
+	}
+	c.mirror.Put(sent)
+}
@@ -0,0 +1,327 @@
+package mirror
+
+import (
+	"code/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is how far back an incremental sync reaches before the previous
+// one started, so leads updated while it ran, or stamped by a server clock
+// running behind ours, aren't missed. Leads fetched twice are simply
+// replaced.
+const clockSkew = 5 * time.Minute
+
+// Mirror is a local copy of the CRM's leads, keyed by email and kept in a
+// JSON file between runs. It is safe for concurrent use.
+type Mirror struct {
+	path string
+
+	mu       sync.RWMutex
+	leads    map[string]*models.Lead
+	syncedAt time.Time
+}
+
+// file is the mirror's layout on disk; Schema is the models.LeadSchema the
+// leads were stored at
+type file struct {
+	Schema   int            `json:"schema"`
+	SyncedAt time.Time      `json:"syncedAt"`
+	Leads    []*models.Lead `json:"leads"`
+}
+
+// storedFile is a file whose leads are decoded once its schema is known
+type storedFile struct {
+	Schema   int               `json:"schema"`
+	SyncedAt time.Time         `json:"syncedAt"`
+	Leads    []json.RawMessage `json:"leads"`
+}
+
+// Open loads the mirror at path, starting empty if the file does not exist
+func Open(path string) (*Mirror, error) {
+	m := &Mirror{path: path, leads: make(map[string]*models.Lead)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read lead mirror: %w", err)
+	}
+	if len(data) == 0 {
+		return m, nil
+	}
+
+	var stored storedFile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode lead mirror %s: %w", path, err)
+	}
+	if err := models.CheckSchema(stored.Schema); err != nil {
+		return nil, fmt.Errorf("lead mirror %s: %w", path, err)
+	}
+	m.syncedAt = stored.SyncedAt
+	for _, data := range stored.Leads {
+		lead, err := models.DecodeLead(data, stored.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode lead mirror %s: %w", path, err)
+		}
+		if lead == nil {
+			continue
+		}
+		if key := key(lead.Email); key != "" {
+			m.leads[key] = lead
+		}
+	}
+	return m, nil
+}
+
+// SyncedAt returns when the last sync started, or the zero time if the
+// mirror was never synced
+func (m *Mirror) SyncedAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.syncedAt
+}
+
+// Len returns how many leads the mirror holds
+func (m *Mirror) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.leads)
+}
+
+// Get returns a copy of the lead with the email, if the mirror has it
+func (m *Mirror) Get(email string) (*models.Lead, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lead, ok := m.leads[key(email)]
+	if !ok {
+		return nil, false
+	}
+	copied := *lead
+	return &copied, true
+}
+
+// Each calls fn with a copy of every lead, in no particular order
+func (m *Mirror) Each(fn func(*models.Lead)) {
+	m.mu.RLock()
+	leads := make([]models.Lead, 0, len(m.leads))
+	for _, lead := range m.leads {
+		leads = append(leads, *lead)
+	}
+	m.mu.RUnlock()
+	for i := range leads {
+		fn(&leads[i])
+	}
+}
+
+// Put stores a copy of lead, replacing any lead with the same email
+func (m *Mirror) Put(lead *models.Lead) {
+	k := key(lead.Email)
+	if k == "" {
+		return
+	}
+	copied := *lead
+	copied.Raw = nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leads[k] = &copied
+}
+
+// Delete removes the lead with the ID, if the mirror has it
+func (m *Mirror) Delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, lead := range m.leads {
+		if lead.ID == id {
+			delete(m.leads, k)
+		}
+	}
+}
+
+// SyncStats counts what a sync changed
+type SyncStats struct {
+	// Fetched is how many leads the export returned
+	Fetched int
+	// Removed is how many leads a full sync dropped because the CRM no
+	// longer has them
+	Removed int
+	// Full reports whether every lead was fetched
+	Full bool
+}
+
+// Export calls fn with every lead updated since the given time, or with all
+// leads when since is zero
+type Export func(since time.Time, fn func(*models.Lead) error) error
+
+// Sync brings the mirror up to date from export. A mirror never synced, or
+// a full sync, fetches every lead and drops those the CRM no longer has;
+// otherwise only the leads updated since the last sync are fetched, and
+// deleted leads stay until the next full sync.
+func (m *Mirror) Sync(export Export, full bool) (SyncStats, error) {
+	startedAt := time.Now().UTC()
+	since := m.SyncedAt()
+	stats := SyncStats{Full: full || since.IsZero()}
+	if stats.Full {
+		since = time.Time{}
+	} else {
+		since = since.Add(-clockSkew)
+	}
+
+	fetched := make(map[string]bool)
+	err := export(since, func(lead *models.Lead) error {
+		stats.Fetched++
+		if stats.Full {
+			fetched[key(lead.Email)] = true
+		}
+		m.Put(lead)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stats.Full {
+		for k := range m.leads {
+			if !fetched[k] {
+				delete(m.leads, k)
+				stats.Removed++
+			}
+		}
+	}
+	m.syncedAt = startedAt
+	return stats, nil
+}
+
+// ErrDrift means the CRM's leads differ from the mirror's in ways no sync
+// accounts for
+var ErrDrift = errors.New("mirror drift")
+
+// DriftKind is how a lead of the CRM differs from the mirror
+type DriftKind string
+
+const (
+	// DriftChanged is a lead whose fields differ from the mirror's copy
+	DriftChanged DriftKind = "changed"
+	// DriftUnknown is a lead the mirror doesn't have
+	DriftUnknown DriftKind = "unknown"
+	// DriftMissing is a lead the mirror has and the CRM no longer does
+	DriftMissing DriftKind = "missing"
+)
+
+// Drift is one lead the CRM and the mirror disagree on
+type Drift struct {
+	Kind DriftKind
+	// Lead is the CRM's lead, or the mirror's copy when missing
+	Lead *models.Lead
+	// Mirrored is the mirror's copy of a changed lead
+	Mirrored *models.Lead
+	// Fields are the changed fields, by JSON name
+	Fields []string
+}
+
+// VerifyStats counts the leads of each kind of drift
+type VerifyStats struct {
+	Changed   int
+	Unknown   int
+	Missing   int
+	Unchanged int
+}
+
+// Total returns how many leads drifted
+func (s VerifyStats) Total() int {
+	return s.Changed + s.Unknown + s.Missing
+}
+
+// Verify compares every lead of export, a full one, with the mirror,
+// calling fn with each lead that drifted in the export's order, then with
+// each missing lead by email. Leads changed since the last sync drift until
+// the next; the mirror itself is left as it is.
+func (m *Mirror) Verify(export Export, fn func(Drift) error) (VerifyStats, error) {
+	var stats VerifyStats
+	seen := make(map[string]bool)
+	err := export(time.Time{}, func(lead *models.Lead) error {
+		k := key(lead.Email)
+		if k == "" {
+			return nil
+		}
+		seen[k] = true
+		mirrored, ok := m.Get(lead.Email)
+		if !ok {
+			stats.Unknown++
+			return fn(Drift{Kind: DriftUnknown, Lead: lead})
+		}
+		fields := make([]string, 0)
+		for field := range lead.Diff(mirrored) {
+			fields = append(fields, field)
+		}
+		if lead.ID != mirrored.ID {
+			fields = append(fields, "id")
+		}
+		if len(fields) == 0 {
+			stats.Unchanged++
+			return nil
+		}
+		sort.Strings(fields)
+		stats.Changed++
+		return fn(Drift{Kind: DriftChanged, Lead: lead, Mirrored: mirrored, Fields: fields})
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	var missing []*models.Lead
+	m.mu.RLock()
+	for k, lead := range m.leads {
+		if !seen[k] {
+			copied := *lead
+			missing = append(missing, &copied)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(missing, func(i, j int) bool { return key(missing[i].Email) < key(missing[j].Email) })
+	for _, lead := range missing {
+		stats.Missing++
+		if err := fn(Drift{Kind: DriftMissing, Lead: lead}); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Save writes the mirror back to disk, leads ordered by email
+func (m *Mirror) Save() error {
+	m.mu.RLock()
+	stored := file{Schema: models.LeadSchema, SyncedAt: m.syncedAt, Leads: make([]*models.Lead, 0, len(m.leads))}
+	for _, lead := range m.leads {
+		stored.Leads = append(stored.Leads, lead)
+	}
+	m.mu.RUnlock()
+	sort.Slice(stored.Leads, func(i, j int) bool {
+		return key(stored.Leads[i].Email) < key(stored.Leads[j].Email)
+	})
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode lead mirror: %w", err)
+	}
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write lead mirror: %w", err)
+	}
+	return os.Rename(tmpPath, m.path)
+}
+
+// key normalizes an email; the API matches emails case-insensitively
+func key(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,247 @@
+package mirror
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExport serves leads and records the times it was asked for
+type fakeExport struct {
+	leads []*models.Lead
+	since []time.Time
+}
+
+func (f *fakeExport) export(since time.Time, fn func(*models.Lead) error) error {
+	f.since = append(f.since, since)
+	for _, lead := range f.leads {
+		if err := fn(lead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMirror(t *testing.T) {
+	t.Run("fetches everything first, then only updates", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "mirror.json")
+		m, err := Open(path)
+		assert.NoError(t, err)
+		export := &fakeExport{leads: []*models.Lead{
+			{ID: "1", Email: "Jane@TechFirm.com", Name: "Jane Smith"},
+			{ID: "2", Email: "john@startup.io", Name: "John Doe"},
+		}}
+
+		// Act
+		first, firstErr := m.Sync(export.export, false)
+		export.leads = []*models.Lead{{ID: "2", Email: "john@startup.io", Name: "John Q. Doe"}}
+		second, secondErr := m.Sync(export.export, false)
+
+		// Assert
+		assert.NoError(t, firstErr)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, SyncStats{Fetched: 2, Full: true}, first)
+		assert.Equal(t, SyncStats{Fetched: 1}, second)
+		assert.True(t, export.since[0].IsZero())
+		assert.WithinDuration(t, time.Now().Add(-clockSkew), export.since[1], time.Minute)
+		lead, ok := m.Get("jane@techfirm.com")
+		assert.True(t, ok)
+		assert.Equal(t, "1", lead.ID)
+		lead, _ = m.Get("john@startup.io")
+		assert.Equal(t, "John Q. Doe", lead.Name)
+	})
+
+	t.Run("drops leads the CRM no longer has on a full sync", func(t *testing.T) {
+		// Arrange
+		m, _ := Open(filepath.Join(t.TempDir(), "mirror.json"))
+		export := &fakeExport{leads: []*models.Lead{{ID: "1", Email: "jane@techfirm.com"}, {ID: "2", Email: "john@startup.io"}}}
+		m.Sync(export.export, false)
+		export.leads = export.leads[:1]
+
+		// Act
+		stats, err := m.Sync(export.export, true)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, SyncStats{Fetched: 1, Removed: 1, Full: true}, stats)
+		assert.Equal(t, 1, m.Len())
+	})
+
+	t.Run("keeps the last sync time when the export fails", func(t *testing.T) {
+		// Arrange
+		m, _ := Open(filepath.Join(t.TempDir(), "mirror.json"))
+
+		// Act
+		_, err := m.Sync(func(time.Time, func(*models.Lead) error) error {
+			return errors.New("API returned 503")
+		}, false)
+
+		// Assert
+		assert.EqualError(t, err, "API returned 503")
+		assert.True(t, m.SyncedAt().IsZero())
+	})
+
+	t.Run("saves and reopens", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "mirror.json")
+		m, _ := Open(path)
+		m.Sync((&fakeExport{leads: []*models.Lead{{ID: "1", Email: "jane@techfirm.com", Company: "TechFirm"}}}).export, false)
+
+		// Act
+		saveErr := m.Save()
+		reopened, openErr := Open(path)
+
+		// Assert
+		assert.NoError(t, saveErr)
+		assert.NoError(t, openErr)
+		assert.Equal(t, m.SyncedAt().Unix(), reopened.SyncedAt().Unix())
+		lead, ok := reopened.Get("jane@techfirm.com")
+		assert.True(t, ok)
+		assert.Equal(t, "TechFirm", lead.Company)
+	})
+
+	t.Run("reads mirrors stored before leads were versioned", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "mirror.json")
+		os.WriteFile(path, []byte(`{"syncedAt":"2026-10-01T09:00:00Z","leads":[{"id":"1","email":"jane@techfirm.com","company":"TechFirm","createdAt":"2026-09-01T09:00:00Z"}]}`), 0o600)
+
+		// Act
+		m, err := Open(path)
+
+		// Assert
+		assert.NoError(t, err)
+		lead, ok := m.Get("jane@techfirm.com")
+		assert.True(t, ok)
+		assert.Equal(t, "TechFirm", lead.Company)
+	})
+
+	t.Run("refuses mirrors stored by a newer version", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "mirror.json")
+		os.WriteFile(path, []byte(`{"schema":99,"syncedAt":"2026-10-01T09:00:00Z","leads":[]}`), 0o600)
+
+		// Act
+		_, err := Open(path)
+
+		// Assert
+		assert.ErrorIs(t, err, models.ErrNewerSchema)
+	})
+}
+
+// fakeAPI records the calls that reach the API
+type fakeAPI struct {
+	calls []string
+}
+
+func (f *fakeAPI) LookupLead(email string) (*processor.LookupResponse, error) {
+	f.calls = append(f.calls, "lookup "+email)
+	return &processor.LookupResponse{}, nil
+}
+
+func (f *fakeAPI) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	f.calls = append(f.calls, "create "+lead.Email)
+	created := *lead
+	created.ID = "new-id"
+	return &created, nil
+}
+
+func (f *fakeAPI) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	f.calls = append(f.calls, "update "+lead.Email)
+	return nil, errors.New("API returned 500")
+}
+
+func TestClient(t *testing.T) {
+	t.Run("answers lookups locally and mirrors writes", func(t *testing.T) {
+		// Arrange
+		m, _ := Open(filepath.Join(t.TempDir(), "mirror.json"))
+		m.Put(&models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith"})
+		next := &fakeAPI{}
+		client := NewClient(next, m)
+
+		// Act
+		found, foundErr := client.LookupLead("JANE@techfirm.com")
+		missing, _ := client.LookupLead("mia@labs.dev")
+		_, createErr := client.CreateLead(&models.Lead{Email: "mia@labs.dev", Name: "Mia Chen"})
+		_, updateErr := client.UpdateLead(&models.Lead{ID: "1", Email: "jane@techfirm.com", Name: "Jane Doe"})
+
+		// Assert
+		assert.NoError(t, foundErr)
+		assert.True(t, found.Found)
+		assert.Equal(t, "Jane Smith", found.Lead.Name)
+		assert.False(t, missing.Found)
+		assert.NoError(t, createErr)
+		assert.Error(t, updateErr)
+		assert.Equal(t, []string{"create mia@labs.dev", "update jane@techfirm.com"}, next.calls)
+		created, ok := m.Get("mia@labs.dev")
+		assert.True(t, ok)
+		assert.Equal(t, "new-id", created.ID)
+		unchanged, _ := m.Get("jane@techfirm.com")
+		assert.Equal(t, "Jane Smith", unchanged.Name)
+	})
+
+	t.Run("reports features the wrapped client lacks", func(t *testing.T) {
+		// Arrange
+		client := NewClient(&fakeAPI{}, &Mirror{leads: map[string]*models.Lead{}})
+
+		// Act
+		features, err := client.DetectFeatures()
+		_, patchErr := client.PatchLead("1", map[string]string{"name": "Jane"})
+		_, canDelete := processor.Unwrap(client).(processor.DeleteClient)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, features.Patch)
+		assert.False(t, canDelete)
+		assert.Error(t, patchErr)
+	})
+}
+
+func TestMirror_Verify(t *testing.T) {
+	t.Run("reports leads changed, added and deleted outside of syncs", func(t *testing.T) {
+		// Arrange
+		m, err := Open(filepath.Join(t.TempDir(), "mirror.json"))
+		assert.NoError(t, err)
+		export := &fakeExport{leads: []*models.Lead{
+			{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "TechFirm"},
+			{ID: "2", Email: "john@startup.io", Name: "John Doe"},
+			{ID: "3", Email: "pat@acme.com", Name: "Pat Poe"},
+		}}
+		_, err = m.Sync(export.export, false)
+		assert.NoError(t, err)
+		export.leads = []*models.Lead{
+			{ID: "4", Email: "sam@globex.com", Name: "Sam Soe"},
+			{ID: "1", Email: "jane@techfirm.com", Name: "Jane Smith", Company: "Initech"},
+			{ID: "2", Email: "john@startup.io", Name: "John Doe"},
+		}
+		var drifts []Drift
+
+		// Act
+		stats, err := m.Verify(export.export, func(drift Drift) error {
+			drifts = append(drifts, drift)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, VerifyStats{Changed: 1, Unknown: 1, Missing: 1, Unchanged: 1}, stats)
+		assert.Equal(t, 3, stats.Total())
+		assert.Equal(t, DriftUnknown, drifts[0].Kind)
+		assert.Equal(t, "sam@globex.com", drifts[0].Lead.Email)
+		assert.Equal(t, DriftChanged, drifts[1].Kind)
+		assert.Equal(t, []string{"company"}, drifts[1].Fields)
+		assert.Equal(t, "TechFirm", drifts[1].Mirrored.Company)
+		assert.Equal(t, DriftMissing, drifts[2].Kind)
+		assert.Equal(t, "pat@acme.com", drifts[2].Lead.Email)
+		assert.True(t, export.since[1].IsZero())
+		lead, ok := m.Get("pat@acme.com")
+		assert.True(t, ok)
+		assert.Equal(t, "Pat Poe", lead.Name)
+	})
+}
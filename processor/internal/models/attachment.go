@@ -0,0 +1,14 @@
+package models
+
+// Attachment is a file to attach to a lead, such as a photo or a scanned
+// business card. Without Data, URL is passed on for the CRM to fetch itself.
+type Attachment struct {
+	// Name is the file name, e.g. card.pdf
+	Name string
+	// ContentType is the file's media type, e.g. image/png
+	ContentType string
+	// URL is where the file came from, if it was a URL
+	URL string
+	// Data is the file's content
+	Data []byte
+}
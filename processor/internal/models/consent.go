@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrConsentRequired is returned by RequireConsent for leads without consent
+var ErrConsentRequired = errors.New("consent is required")
+
+// HasConsentDetails reports whether any consent field is set on the lead
+func (l *Lead) HasConsentDetails() bool {
+	return l.ConsentGiven || l.ConsentTimestamp != nil || l.ConsentSource != ""
+}
+
+// RequireConsent returns ErrConsentRequired unless the lead has given consent
+func (l *Lead) RequireConsent() error {
+	if !l.ConsentGiven {
+		return ErrConsentRequired
+	}
+	return nil
+}
+
+// ParseConsent parses a consent flag such as "yes", "true", "1" or "no".
+// An empty value means no consent was recorded.
+func ParseConsent(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "no", "n", "false", "0":
+		return false, nil
+	case "yes", "y", "true", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid consent value %q", value)
+}
+
+// ParseConsentTimestamp parses an RFC 3339 timestamp or a plain date.
+// An empty value returns nil.
+func ParseConsentTimestamp(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid consent timestamp %q (use RFC 3339 or YYYY-MM-DD)", value)
+}
+
+// SetConsentCells sets the lead's consent from the cells of its source row.
+// A flag or timestamp that doesn't parse is left unset and recorded in
+// ReadProblems, so it fails only this lead's validation.
+func (l *Lead) SetConsentCells(given, timestamp, source string) {
+	consentGiven, err := ParseConsent(given)
+	if err != nil {
+		l.ReadProblems = append(l.ReadProblems, ValidationProblem{Field: "consentGiven", Message: "consent must be yes or no, not %q", Args: []interface{}{strings.Clone(given)}})
+	}
+	consentTimestamp, err := ParseConsentTimestamp(timestamp)
+	if err != nil {
+		l.ReadProblems = append(l.ReadProblems, ValidationProblem{Field: "consentTimestamp", Message: "consent timestamp %q is not RFC 3339 or YYYY-MM-DD", Args: []interface{}{strings.Clone(timestamp)}})
+	}
+	l.ConsentGiven = consentGiven
+	l.ConsentTimestamp = consentTimestamp
+	l.ConsentSource = source
+}
+
+// consentProblems validates consent details that are present
+func (l *Lead) consentProblems() []ValidationProblem {
+	var problems []ValidationProblem
+
+	if l.ConsentTimestamp != nil && l.ConsentTimestamp.After(time.Now()) {
+		problems = append(problems, ValidationProblem{Field: "consentTimestamp", Message: "consent timestamp cannot be in the future"})
+	}
+	if !l.ConsentGiven && (l.ConsentTimestamp != nil || l.ConsentSource != "") {
+		problems = append(problems, ValidationProblem{Field: "consentGiven", Message: "consent timestamp and source require consent to be given"})
+	}
+
+	return problems
+}
+
+// consentEqual compares the consent fields of two leads
+func (l *Lead) consentEqual(other *Lead) bool {
+	if l.ConsentGiven != other.ConsentGiven || l.ConsentSource != other.ConsentSource {
+		return false
+	}
+	if l.ConsentTimestamp == nil || other.ConsentTimestamp == nil {
+		return l.ConsentTimestamp == other.ConsentTimestamp
+	}
+	return l.ConsentTimestamp.Equal(*other.ConsentTimestamp)
+}
+
+// consentFields returns the consent fields keyed by JSON field name
+func (l *Lead) consentFields() map[string]string {
+	fields := map[string]string{
+		"consentGiven":  strconv.FormatBool(l.ConsentGiven),
+		"consentSource": l.ConsentSource,
+	}
+	if l.ConsentTimestamp != nil {
+		fields["consentTimestamp"] = l.ConsentTimestamp.Format(time.RFC3339)
+	}
+	return fields
+}
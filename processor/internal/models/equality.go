@@ -0,0 +1,194 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/cases"
+)
+
+// Comparator reports whether two values of a field are the same
+type Comparator func(a, b string) bool
+
+// Built-in comparators, by the name the config picks them with
+const (
+	CompareExact           = "exact"
+	CompareCaseInsensitive = "case_insensitive"
+	// CompareWhitespace ignores leading, trailing and repeated whitespace
+	CompareWhitespace = "whitespace"
+	// CompareNormalized ignores whitespace like CompareWhitespace and case,
+	// folding it the Unicode way, so "STRASSE" matches "straße"
+	CompareNormalized = "normalized"
+)
+
+// defaultComparators compare the fields imports most often disagree with the
+// CRM on in ways that don't matter, so an unchanged file is all skipped
+// rather than updated with no change; other fields are compared exactly
+var defaultComparators = map[string]string{
+	"email":   CompareNormalized,
+	"name":    CompareWhitespace,
+	"company": CompareWhitespace,
+}
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = map[string]Comparator{
+		CompareExact:           func(a, b string) bool { return a == b },
+		CompareCaseInsensitive: strings.EqualFold,
+		CompareWhitespace: func(a, b string) bool {
+			return collapseSpace(a) == collapseSpace(b)
+		},
+		CompareNormalized: func(a, b string) bool {
+			return Canonical(a) == Canonical(b)
+		},
+	}
+)
+
+// collapseSpace trims a value and joins its words with single spaces
+func collapseSpace(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Canonical returns the form of a value CompareNormalized compares: trimmed,
+// with runs of whitespace made one space, and case-folded
+func Canonical(value string) string {
+	return cases.Fold().String(collapseSpace(value))
+}
+
+// RegisterComparator makes a custom comparator available under name, so the
+// config's equality.comparators can pick it. Call it from an init function.
+func RegisterComparator(name string, comparator Comparator) error {
+	name = strings.TrimSpace(name)
+	if name == "" || comparator == nil {
+		return fmt.Errorf("a comparator needs a name and a function")
+	}
+
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	if _, exists := comparators[name]; exists {
+		return fmt.Errorf("comparator %q is already registered", name)
+	}
+	comparators[name] = comparator
+	return nil
+}
+
+// comparatorNames lists the registered comparators, sorted
+func comparatorNames() []string {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	names := make([]string, 0, len(comparators))
+	for name := range comparators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ComparableFields returns the fields, by JSON name, an Equality can
+// compare; consent stands for the consent flag, timestamp and source together
+func ComparableFields() []string {
+	return []string{"name", "email", "company", "source", "status", "territory", "language", "consent"}
+}
+
+// Equality decides whether an input lead matches the CRM's copy, which
+// tells a SKIP from an UPDATE. The zero value compares like IsEqual, with
+// the default comparators.
+type Equality struct {
+	// Fields, when set, are the only fields compared
+	Fields []string `yaml:"fields"`
+	// Ignore lists fields never compared, so a difference in them alone
+	// doesn't update the lead
+	Ignore []string `yaml:"ignore"`
+	// CaseInsensitive compares every text field normalized, ignoring case
+	// and whitespace
+	CaseInsensitive bool `yaml:"case_insensitive"`
+	// Comparators picks the comparator of single text fields by name,
+	// overriding CaseInsensitive. By default email is compared normalized,
+	// name and company ignoring whitespace and the rest exactly.
+	Comparators map[string]string `yaml:"comparators"`
+}
+
+// Validate checks the fields and comparators named
+func (e Equality) Validate() error {
+	if len(e.Fields) > 0 && len(e.Ignore) > 0 {
+		return fmt.Errorf("set fields or ignore, not both")
+	}
+	for _, field := range append(slices.Clone(e.Fields), e.Ignore...) {
+		if !slices.Contains(ComparableFields(), field) {
+			return fmt.Errorf("unknown field %q (allowed: %s)", field, strings.Join(ComparableFields(), ", "))
+		}
+	}
+	for field, name := range e.Comparators {
+		if field == "consent" || !slices.Contains(ComparableFields(), field) {
+			return fmt.Errorf("comparators: %q is not a text field", field)
+		}
+		if e.comparator(field) == nil {
+			return fmt.Errorf("comparators: unknown comparator %q for %s (registered: %s)", name, field, strings.Join(comparatorNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// Equal reports whether lead matches other on the compared fields. Status,
+// territory, language and consent are only compared when lead sets them,
+// since most inputs don't carry them.
+func (e Equality) Equal(lead, other *Lead) bool {
+	if other == nil {
+		return false
+	}
+
+	for _, field := range e.compared() {
+		switch field {
+		case "consent":
+			if lead.HasConsentDetails() && !lead.consentEqual(other) {
+				return false
+			}
+		case "status", "territory", "language":
+			value, _ := lead.Field(field)
+			otherValue, _ := other.Field(field)
+			if value != "" && !e.comparator(field)(value, otherValue) {
+				return false
+			}
+		default:
+			value, _ := lead.Field(field)
+			otherValue, _ := other.Field(field)
+			if !e.comparator(field)(value, otherValue) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compared returns the fields to compare
+func (e Equality) compared() []string {
+	if len(e.Fields) > 0 {
+		return e.Fields
+	}
+	fields := ComparableFields()
+	if len(e.Ignore) > 0 {
+		fields = slices.DeleteFunc(fields, func(field string) bool { return slices.Contains(e.Ignore, field) })
+	}
+	return fields
+}
+
+// comparator returns the comparator of a text field, or nil if the one
+// picked isn't registered
+func (e Equality) comparator(field string) Comparator {
+	name := e.Comparators[field]
+	if name == "" {
+		name = defaultComparators[field]
+		if e.CaseInsensitive {
+			name = CompareNormalized
+		}
+	}
+	if name == "" {
+		name = CompareExact
+	}
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	return comparators[name]
+}
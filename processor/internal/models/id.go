@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ID schemes for leads read from files
+const (
+	// IDRandom is a random UUIDv4, the default
+	IDRandom = "uuid4"
+	// IDTimeOrdered is a UUIDv7, which sorts by creation time
+	IDTimeOrdered = "uuid7"
+	// IDDeterministic is a UUIDv5 of the lead's email and source, so a
+	// re-imported lead gets the same ID
+	IDDeterministic = "uuid5"
+)
+
+// leadNamespace is the UUIDv5 namespace of deterministic lead IDs
+var leadNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:lead-processor:lead"))
+
+// IDGenerator returns the ID of a lead read with the given email and source
+type IDGenerator func(email, source string) string
+
+// IDSchemes lists the ID schemes ParseIDScheme accepts
+func IDSchemes() []string {
+	return []string{IDRandom, IDTimeOrdered, IDDeterministic}
+}
+
+// ParseIDScheme returns the generator of an ID scheme; empty means IDRandom
+func ParseIDScheme(scheme string) (IDGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(scheme)) {
+	case "", IDRandom:
+		return RandomID, nil
+	case IDTimeOrdered:
+		return TimeOrderedID, nil
+	case IDDeterministic:
+		return DeterministicID, nil
+	}
+	return nil, fmt.Errorf("unknown ID scheme %q (allowed: %s)", scheme, strings.Join(IDSchemes(), ", "))
+}
+
+// RandomID returns a random UUIDv4
+func RandomID(email, source string) string {
+	return uuid.NewString()
+}
+
+// TimeOrderedID returns a UUIDv7, falling back to a UUIDv4 if the clock or
+// random source fails
+func TimeOrderedID(email, source string) string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// DeterministicID returns a UUIDv5 of the email, ignoring case and
+// surrounding whitespace, and the source
+func DeterministicID(email, source string) string {
+	key := strings.ToLower(strings.TrimSpace(email)) + "\x00" + strings.TrimSpace(source)
+	return uuid.NewSHA1(leadNamespace, []byte(key)).String()
+}
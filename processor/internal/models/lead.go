@@ -1,12 +1,14 @@
 package models
 
 import (
+	"code/internal/errcode"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 // Lead represents a lead in the system
@@ -16,14 +18,45 @@ type Lead struct {
 	Email     string     `json:"email"`
 	Company   string     `json:"company"`
 	Source    string     `json:"source"`
+	Status    string     `json:"status,omitempty"`
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+
+	// Owner is the sales rep the CRM assigned the lead to, if any. Imports
+	// never set it.
+	Owner string `json:"owner,omitempty"`
+
+	// Territory is the sales territory the config's territories assigned
+	// from the lead's country, state or IP address
+	Territory string `json:"territory,omitempty"`
+
+	// Language is the ISO 639-1 code of the language the lead writes in,
+	// detected from free-text columns such as comments
+	Language string `json:"language,omitempty"`
+
+	// Marketing consent, required for EU campaigns
+	ConsentGiven     bool       `json:"consentGiven,omitempty"`
+	ConsentTimestamp *time.Time `json:"consentTimestamp,omitempty"`
+	ConsentSource    string     `json:"consentSource,omitempty"`
+
+	// Raw is the source row the lead was read from, keyed by header column.
+	// It is never sent to the API.
+	Raw map[string]string `json:"-"`
+
+	// ReadProblems are cells of the source row that couldn't be read into
+	// the lead, such as an unparseable consent flag; Validate reports them
+	ReadProblems []ValidationProblem `json:"-"`
 }
 
-// NewLead creates a new lead with generated ID and timestamp
+// NewLead creates a new lead with a random ID and the current time
 func NewLead(name, email, company, source string) *Lead {
+	return NewLeadWithID(RandomID, name, email, company, source)
+}
+
+// NewLeadWithID creates a new lead with an ID from newID and the current time
+func NewLeadWithID(newID IDGenerator, name, email, company, source string) *Lead {
 	return &Lead{
-		ID:        uuid.New().String(),
+		ID:        newID(email, source),
 		Name:      name,
 		Email:     email,
 		Company:   company,
@@ -32,48 +65,232 @@ func NewLead(name, email, company, source string) *Lead {
 	}
 }
 
-// Validate validates the lead data
+// ValidationProblem is one reason a lead failed validation. Message is an
+// English format string for Args, kept apart so callers can translate it.
+type ValidationProblem struct {
+	Field   string
+	Message string
+	Args    []interface{}
+}
+
+// String renders the problem in English
+func (p ValidationProblem) String() string {
+	return fmt.Sprintf(p.Message, p.Args...)
+}
+
+// ValidationError lists every problem found while validating a lead
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+// Error joins the problems in English
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is makes validation errors match errcode.ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == errcode.ErrValidation
+}
+
+// EmailRule checks an email address more thoroughly than the built-in
+// syntax check, returning the problem found or nil
+type EmailRule func(email string) *ValidationProblem
+
+// ValidationOptions adjust how a lead is validated; the zero value gives
+// the defaults Validate uses
+type ValidationOptions struct {
+	// EmailRule replaces the built-in email syntax check
+	EmailRule EmailRule
+	// MaxLengths overrides DefaultMaxLengths per field
+	MaxLengths map[string]int
+}
+
+// Validate validates the lead data, returning a *ValidationError
 func (l *Lead) Validate() error {
-	var validationErrors []string
+	return l.ValidateWith(ValidationOptions{})
+}
+
+// ValidateWith validates the lead like Validate, adjusted by opts
+func (l *Lead) ValidateWith(opts ValidationOptions) error {
+	var problems []ValidationProblem
 
 	// Validate name
 	if strings.TrimSpace(l.Name) == "" {
-		validationErrors = append(validationErrors, "name is required")
+		problems = append(problems, ValidationProblem{Field: "name", Message: "name is required"})
 	}
 
 	// Validate email
-	if !isValidEmail(l.Email) {
-		validationErrors = append(validationErrors, "valid email is required")
+	switch {
+	case strings.TrimSpace(l.Email) == "":
+		problems = append(problems, ValidationProblem{Field: "email", Message: "valid email is required"})
+	case opts.EmailRule != nil:
+		if problem := opts.EmailRule(l.Email); problem != nil {
+			problem.Field = "email"
+			problems = append(problems, *problem)
+		}
+	case !isValidEmail(l.Email):
+		problems = append(problems, ValidationProblem{Field: "email", Message: "valid email is required"})
 	}
 
 	// Validate company
 	if strings.TrimSpace(l.Company) == "" {
-		validationErrors = append(validationErrors, "company is required")
+		problems = append(problems, ValidationProblem{Field: "company", Message: "company is required"})
 	}
 
 	// Validate source
 	if !isValidSource(l.Source) {
 		validSources := strings.Join(GetValidSources(), ", ")
-		validationErrors = append(validationErrors, fmt.Sprintf("source must be one of: %s", validSources))
+		problems = append(problems, ValidationProblem{Field: "source", Message: "source must be one of: %s", Args: []interface{}{validSources}})
+	}
+
+	// Validate status, which is optional
+	if l.Status != "" && !isValidStatus(l.Status) {
+		validStatuses := strings.Join(GetValidStatuses(), ", ")
+		problems = append(problems, ValidationProblem{Field: "status", Message: "status must be one of: %s", Args: []interface{}{validStatuses}})
 	}
 
-	if len(validationErrors) > 0 {
-		return fmt.Errorf("%s", strings.Join(validationErrors, "; "))
+	// Validate consent details, which are optional
+	problems = append(problems, l.consentProblems()...)
+
+	// Report cells that couldn't be read
+	problems = append(problems, l.ReadProblems...)
+
+	// Validate encoding and length of free text
+	problems = append(problems, l.textProblems(opts.MaxLengths)...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
 	}
 
 	return nil
 }
 
-// IsEqual compares two leads for equality (ignoring ID and timestamps)
+// IsEqual compares two leads for equality (ignoring ID and timestamps).
+// Status, territory, language and consent are only compared when l sets
+// them, since most inputs don't carry them. Email is compared normalized, name and company ignoring
+// whitespace, so cosmetic differences don't make an update.
 func (l *Lead) IsEqual(other *Lead) bool {
+	return Equality{}.Equal(l, other)
+}
+
+// Diff returns the fields of l that differ from other, keyed by JSON field name
+func (l *Lead) Diff(other *Lead) map[string]string {
+	changes := make(map[string]string)
 	if other == nil {
+		other = &Lead{}
+	}
+
+	if l.Name != other.Name {
+		changes["name"] = l.Name
+	}
+	if l.Email != other.Email {
+		changes["email"] = l.Email
+	}
+	if l.Company != other.Company {
+		changes["company"] = l.Company
+	}
+	if l.Source != other.Source {
+		changes["source"] = l.Source
+	}
+	if l.Status != "" && l.Status != other.Status {
+		changes["status"] = l.Status
+	}
+	if l.Territory != "" && l.Territory != other.Territory {
+		changes["territory"] = l.Territory
+	}
+	if l.Language != "" && l.Language != other.Language {
+		changes["language"] = l.Language
+	}
+	if l.HasConsentDetails() && !l.consentEqual(other) {
+		for field, value := range l.consentFields() {
+			changes[field] = value
+		}
+	}
+
+	return changes
+}
+
+// ProtectableFields returns the fields, by JSON name, that can be shielded
+// from updates. Email identifies the lead and so is never protectable.
+func ProtectableFields() []string {
+	return []string{"name", "company", "source", "status"}
+}
+
+// Field returns the value of the field with the given JSON name
+func (l *Lead) Field(name string) (string, bool) {
+	switch name {
+	case "name":
+		return l.Name, true
+	case "email":
+		return l.Email, true
+	case "company":
+		return l.Company, true
+	case "source":
+		return l.Source, true
+	case "status":
+		return l.Status, true
+	case "territory":
+		return l.Territory, true
+	case "language":
+		return l.Language, true
+	}
+	return "", false
+}
+
+// SetField sets the field with the given JSON name, reporting false for unknown fields
+func (l *Lead) SetField(name, value string) bool {
+	switch name {
+	case "name":
+		l.Name = value
+	case "email":
+		l.Email = value
+	case "company":
+		l.Company = value
+	case "source":
+		l.Source = value
+	case "status":
+		l.Status = value
+	case "territory":
+		l.Territory = value
+	case "language":
+		l.Language = value
+	default:
 		return false
 	}
+	return true
+}
+
+// RawValue returns the value of a source column, matching its name case-insensitively
+func (l *Lead) RawValue(column string) (string, bool) {
+	column = strings.TrimSpace(column)
+	for name, value := range l.Raw {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return value, true
+		}
+	}
+	return "", false
+}
 
-	return l.Name == other.Name &&
-		l.Email == other.Email &&
-		l.Company == other.Company &&
-		l.Source == other.Source
+// ContentHash returns a stable hash of the lead's business fields, status and
+// consent, used to recognise rows that were already processed with identical
+// content
+func (l *Lead) ContentHash() string {
+	var consentTimestamp string
+	if l.ConsentTimestamp != nil {
+		consentTimestamp = l.ConsentTimestamp.UTC().Format(time.RFC3339Nano)
+	}
+	h := sha256.New()
+	for _, field := range []string{strings.ToLower(strings.TrimSpace(l.Email)), l.Name, l.Company, l.Source,
+		l.Status, strconv.FormatBool(l.ConsentGiven), consentTimestamp, l.ConsentSource} {
+		h.Write([]byte(field))
+		h.Write([]byte{0x1f})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // GetValidSources returns the list of valid source values
@@ -88,6 +305,61 @@ func GetValidSources() []string {
 	}
 }
 
+// Lead lifecycle statuses
+const (
+	StatusNew          = "new"
+	StatusContacted    = "contacted"
+	StatusQualified    = "qualified"
+	StatusDisqualified = "disqualified"
+)
+
+// GetValidStatuses returns the list of valid status values
+func GetValidStatuses() []string {
+	return []string{
+		StatusNew,
+		StatusContacted,
+		StatusQualified,
+		StatusDisqualified,
+	}
+}
+
+// statusRank orders the statuses a lead progresses through
+var statusRank = map[string]int{
+	StatusNew:       0,
+	StatusContacted: 1,
+	StatusQualified: 2,
+}
+
+// CanTransitionStatus reports whether an import may move a lead from one
+// status to another. Leads only move forward (new → contacted → qualified)
+// or to disqualified; disqualified leads and downgrades need manual action.
+// An empty status on either side means there is nothing to enforce.
+func CanTransitionStatus(from, to string) bool {
+	if from == "" || to == "" || from == to {
+		return true
+	}
+	if from == StatusDisqualified {
+		return false
+	}
+	if to == StatusDisqualified {
+		return true
+	}
+
+	fromRank, fromOK := statusRank[from]
+	toRank, toOK := statusRank[to]
+	return fromOK && toOK && toRank > fromRank
+}
+
+// isValidStatus checks if the status is in the valid statuses list
+func isValidStatus(status string) bool {
+	for _, validStatus := range GetValidStatuses() {
+		if status == validStatus {
+			return true
+		}
+	}
+	return false
+}
+
 // isValidEmail validates email format
 func isValidEmail(email string) bool {
 	if strings.TrimSpace(email) == "" {
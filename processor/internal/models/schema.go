@@ -0,0 +1,70 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// LeadSchema is the version of the lead layout this binary stores in lead
+// mirrors, sync state and dead letters. Bump it, and add a migration from
+// the previous version to leadMigrations, when a change to Lead's JSON, such
+// as a renamed or retyped field, would make leads stored by earlier binaries
+// decode wrongly. Added fields with omitempty need no new version.
+const LeadSchema = 1
+
+// ErrNewerSchema marks data stored by a newer binary than this one
+var ErrNewerSchema = errors.New("stored by a newer version of lead-processor")
+
+// LeadMigration rewrites a stored lead, decoded as a JSON object, from the
+// previous schema version to the next
+type LeadMigration func(fields map[string]interface{}) error
+
+// leadMigrations[v] upgrades a lead from version v-1 to v. Version 0 is a
+// lead stored before leads were versioned.
+var leadMigrations = map[int]LeadMigration{
+	// Version 1 only starts recording the version; the layout is unchanged
+	1: func(fields map[string]interface{}) error { return nil },
+}
+
+// CheckSchema returns ErrNewerSchema for a version this binary can't read
+func CheckSchema(version int) error {
+	if version > LeadSchema {
+		return fmt.Errorf("%w (schema %d, this version reads up to %d)", ErrNewerSchema, version, LeadSchema)
+	}
+	return nil
+}
+
+// DecodeLead decodes a lead stored at schema version, migrating it to the
+// current layout. A JSON null decodes to nil.
+func DecodeLead(data []byte, version int) (*Lead, error) {
+	if err := CheckSchema(version); err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+
+	if version < LeadSchema {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		for v := max(version, 0) + 1; v <= LeadSchema; v++ {
+			if err := leadMigrations[v](fields); err != nil {
+				return nil, fmt.Errorf("failed to migrate lead %v to schema %d: %w", fields["email"], v, err)
+			}
+		}
+		var err error
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var lead Lead
+	if err := json.Unmarshal(data, &lead); err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
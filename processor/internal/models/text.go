@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxLengths returns the longest value, in characters, the API accepts
+// for each free-text field, keyed by JSON field name
+func DefaultMaxLengths() map[string]int {
+	return map[string]int{
+		"name":          255,
+		"email":         254,
+		"company":       255,
+		"consentSource": 255,
+	}
+}
+
+// LengthLimitedFields returns the fields max lengths can be set for
+func LengthLimitedFields() []string {
+	return []string{"name", "email", "company", "consentSource"}
+}
+
+// Sanitize cleans the lead's text fields so the API never rejects them for
+// their encoding: surrounding whitespace is trimmed, tabs and line breaks
+// become spaces, other control characters are dropped, and text is
+// normalised to NFC so "é" typed either way compares equal. Invalid UTF-8 is
+// left alone for Validate to report. It has the shape of pipeline.Transform.
+func Sanitize(lead *Lead) *Lead {
+	for _, field := range []*string{&lead.Name, &lead.Email, &lead.Company, &lead.Source, &lead.Status, &lead.ConsentSource} {
+		*field = sanitizeText(*field)
+	}
+	return lead
+}
+
+func sanitizeText(value string) string {
+	if !utf8.ValidString(value) {
+		return value
+	}
+
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, value)
+
+	return norm.NFC.String(strings.TrimSpace(value))
+}
+
+// textFields returns the lead's text fields keyed by JSON field name
+func (l *Lead) textFields() map[string]string {
+	return map[string]string{
+		"name":          l.Name,
+		"email":         l.Email,
+		"company":       l.Company,
+		"source":        l.Source,
+		"status":        l.Status,
+		"consentSource": l.ConsentSource,
+	}
+}
+
+// textProblems reports fields that aren't valid UTF-8 or are longer than
+// maxLengths allows; fields missing from maxLengths use DefaultMaxLengths,
+// and a limit of zero or less means no limit
+func (l *Lead) textProblems(maxLengths map[string]int) []ValidationProblem {
+	var problems []ValidationProblem
+	fields := l.textFields()
+
+	for _, field := range []string{"name", "email", "company", "source", "status", "consentSource"} {
+		if !utf8.ValidString(fields[field]) {
+			problems = append(problems, ValidationProblem{Field: field, Message: "%s is not valid UTF-8 (check the file's encoding)", Args: []interface{}{field}})
+		}
+	}
+
+	limits := DefaultMaxLengths()
+	for field, limit := range maxLengths {
+		limits[field] = limit
+	}
+	for _, field := range LengthLimitedFields() {
+		limit := limits[field]
+		if limit > 0 && utf8.RuneCountInString(fields[field]) > limit {
+			problems = append(problems, ValidationProblem{Field: field, Message: "%s is longer than %d characters", Args: []interface{}{field, limit}})
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,70 @@
+package notes
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// placeholderPattern matches a {name} placeholder in a note template
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Run describes the run a note is added by, for expanding placeholders
+type Run struct {
+	// File is the name of the input, e.g. conference-2024.csv
+	File string
+	// ID is the run's ID
+	ID string
+	// Date is when the run started
+	Date time.Time
+}
+
+// Expand replaces the {file}, {run} and {date} placeholders in template with
+// the run's input file name, ID and start date. Other placeholders are
+// rejected, as they are most likely typos.
+func Expand(template string, run Run) (string, error) {
+	var unknown []string
+	text := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{file}":
+			return run.File
+		case "{run}":
+			return run.ID
+		case "{date}":
+			return run.Date.Format(time.DateOnly)
+		}
+		unknown = append(unknown, placeholder)
+		return placeholder
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s (allowed: {file}, {run}, {date})", strings.Join(unknown, ", "))
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("the note is empty")
+	}
+	return text, nil
+}
+
+// Sink adds the same note to every lead it is sent. It is a processor.Sink.
+type Sink struct {
+	client processor.NoteClient
+	text   string
+}
+
+// NewSink returns a sink adding text to leads' activity through client
+func NewSink(client processor.NoteClient, text string) *Sink {
+	return &Sink{client: client, text: text}
+}
+
+// Name names the sink in sync errors
+func (s *Sink) Name() string {
+	return "note"
+}
+
+// Send adds the note to the lead
+func (s *Sink) Send(lead *models.Lead) error {
+	return s.client.AddNote(lead.ID, s.text)
+}
@@ -0,0 +1,57 @@
+package notes
+
+import (
+	"code/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNoter records the notes it is given, by lead ID
+type fakeNoter struct {
+	notes map[string]string
+}
+
+func (f *fakeNoter) AddNote(id string, text string) error {
+	f.notes[id] = text
+	return nil
+}
+
+func TestExpand(t *testing.T) {
+	run := Run{File: "conference-2024.csv", ID: "abc123", Date: time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC)}
+
+	t.Run("fills in the run's placeholders", func(t *testing.T) {
+		// Act
+		text, err := Expand("Imported from {file} run {run} on {date}", run)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Imported from conference-2024.csv run abc123 on 2024-05-17", text)
+	})
+
+	t.Run("rejects unknown placeholders and empty notes", func(t *testing.T) {
+		// Act
+		_, unknownErr := Expand("Imported from {filename} by {user}", run)
+		_, emptyErr := Expand("  ", run)
+
+		// Assert
+		assert.EqualError(t, unknownErr, "unknown placeholder {filename}, {user} (allowed: {file}, {run}, {date})")
+		assert.Error(t, emptyErr)
+	})
+}
+
+func TestSink_Send(t *testing.T) {
+	// Arrange
+	noter := &fakeNoter{notes: map[string]string{}}
+	sink := NewSink(noter, "Imported from leads.csv run abc123")
+	lead := models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "LinkedIn")
+	lead.ID = "lead-1"
+
+	// Act
+	err := sink.Send(lead)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lead-1": "Imported from leads.csv run abc123"}, noter.notes)
+}
@@ -0,0 +1,90 @@
+package notfound
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Client remembers for a short time which emails the CRM didn't have, so
+// a duplicate later in the file is answered without a second lookup. Our
+// own creates forget the email, whether they succeed or not, so the next
+// lookup asks the API again and finds the new lead instead of creating it a
+// second time. Other calls are passed through to the wrapped client.
+type Client struct {
+	processor.Passthrough
+	ttl  time.Duration
+	now  func() time.Time
+	hits atomic.Int64
+
+	mu       sync.Mutex
+	notFound map[string]time.Time
+	// creates counts our creates per email, so a lookup under way while one
+	// was sent doesn't cache its stale answer
+	creates map[string]int
+}
+
+// NewClient wraps next so "not found" answers are reused for ttl
+func NewClient(next processor.APIClient, ttl time.Duration) *Client {
+	return &Client{Passthrough: processor.Passthrough{Next: next}, ttl: ttl, now: time.Now, notFound: make(map[string]time.Time), creates: make(map[string]int)}
+}
+
+// Hits returns how many lookups were answered from the cache
+func (c *Client) Hits() int {
+	return int(c.hits.Load())
+}
+
+// LookupLead reports the lead as not found without calling the API when the
+// API said so less than the TTL ago
+func (c *Client) LookupLead(email string) (*processor.LookupResponse, error) {
+	key := cacheKey(email)
+
+	c.mu.Lock()
+	expires, ok := c.notFound[key]
+	if ok && c.now().After(expires) {
+		delete(c.notFound, key)
+		ok = false
+	}
+	creates := c.creates[key]
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		return &processor.LookupResponse{Found: false}, nil
+	}
+
+	resp, err := c.Next.LookupLead(email)
+	if err == nil && resp != nil && !resp.Found {
+		c.mu.Lock()
+		if c.creates[key] == creates {
+			c.notFound[key] = c.now().Add(c.ttl)
+		}
+		c.mu.Unlock()
+	}
+	return resp, err
+}
+
+// CreateLead forgets that the CRM didn't have the lead and creates it,
+// forgetting it again afterwards in case a lookup made meanwhile got in. A
+// failed create may still have created the lead, so it is forgotten either
+// way.
+func (c *Client) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	c.forget(lead.Email)
+	defer c.forget(lead.Email)
+	return c.Next.CreateLead(lead)
+}
+
+func (c *Client) forget(email string) {
+	key := cacheKey(email)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.notFound, key)
+	c.creates[key]++
+}
+
+// cacheKey normalizes an email; the API matches emails case-insensitively
+func cacheKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,102 @@
+package notfound
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAPI has the leads created through it and counts lookups
+type fakeAPI struct {
+	leads   map[string]*models.Lead
+	lookups int
+}
+
+func (f *fakeAPI) LookupLead(email string) (*processor.LookupResponse, error) {
+	f.lookups++
+	lead, ok := f.leads[email]
+	return &processor.LookupResponse{Found: ok, Lead: lead}, nil
+}
+
+func (f *fakeAPI) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	f.leads[lead.Email] = lead
+	return lead, nil
+}
+
+func (f *fakeAPI) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	return lead, nil
+}
+
+func TestClient(t *testing.T) {
+	t.Run("answers repeated lookups of missing leads until the TTL passes", func(t *testing.T) {
+		// Arrange
+		api := &fakeAPI{leads: map[string]*models.Lead{}}
+		now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+		client := NewClient(api, 30*time.Second)
+		client.now = func() time.Time { return now }
+
+		// Act
+		client.LookupLead("jane@example.com")
+		cached, _ := client.LookupLead("Jane@Example.com")
+		now = now.Add(31 * time.Second)
+		client.LookupLead("jane@example.com")
+
+		// Assert
+		assert.False(t, cached.Found)
+		assert.Equal(t, 1, client.Hits())
+		assert.Equal(t, 2, api.lookups)
+	})
+
+	t.Run("asks the API again after creating the lead", func(t *testing.T) {
+		// Arrange
+		api := &fakeAPI{leads: map[string]*models.Lead{}}
+		client := NewClient(api, time.Minute)
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")
+
+		// Act
+		client.LookupLead(lead.Email)
+		client.CreateLead(lead)
+		resp, _ := client.LookupLead(lead.Email)
+
+		// Assert
+		assert.True(t, resp.Found)
+		assert.Equal(t, 0, client.Hits())
+		assert.Equal(t, 2, api.lookups)
+	})
+
+	t.Run("doesn't cache an answer that a create made stale", func(t *testing.T) {
+		// Arrange
+		api := &fakeAPI{leads: map[string]*models.Lead{}}
+		client := NewClient(api, time.Minute)
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")
+		stale := &racingAPI{fakeAPI: api, during: func() { client.CreateLead(lead) }}
+		client.Next = stale
+
+		// Act
+		client.LookupLead(lead.Email)
+		resp, _ := client.LookupLead(lead.Email)
+
+		// Assert
+		assert.True(t, resp.Found)
+		assert.Equal(t, 0, client.Hits())
+	})
+}
+
+// racingAPI runs during once, while its first lookup is under way, after the
+// lookup has its answer
+type racingAPI struct {
+	*fakeAPI
+	during func()
+}
+
+func (r *racingAPI) LookupLead(email string) (*processor.LookupResponse, error) {
+	resp, err := r.fakeAPI.LookupLead(email)
+	if during := r.during; during != nil {
+		r.during = nil
+		during()
+	}
+	return resp, err
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"code/internal/errcode"
+	"code/internal/history"
+	"code/internal/secrets"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// When a webhook is called
+const (
+	OnAlways  = "always"
+	OnFailure = "failure"
+	OnSuccess = "success"
+)
+
+// Webhook is told the outcome of a pipeline's runs
+type Webhook struct {
+	// URL is the http(s) endpoint the outcome is POSTed to as JSON, or a
+	// vault:// or aws-sm:// reference to it, since chat webhooks embed
+	// their secret
+	URL string `yaml:"url"`
+	// On is when the webhook is called: always (the default), failure or
+	// success
+	On string `yaml:"on"`
+}
+
+// Validate checks the webhook's URL and when it is called
+func (w Webhook) Validate() error {
+	if strings.TrimSpace(w.URL) == "" {
+		return fmt.Errorf("url is required")
+	}
+	// A reference is only resolved once the config is loaded
+	if !secrets.IsReference(w.URL) {
+		if parsed, err := url.Parse(w.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("url is not an http(s) URL")
+		}
+	}
+	switch w.On {
+	case "", OnAlways, OnFailure, OnSuccess:
+		return nil
+	}
+	return fmt.Errorf("on: unknown value %q (allowed: %s, %s, %s)", w.On, OnAlways, OnFailure, OnSuccess)
+}
+
+// Wants reports whether the webhook is called for a run that failed or not
+func (w Webhook) Wants(failed bool) bool {
+	switch w.On {
+	case OnFailure:
+		return failed
+	case OnSuccess:
+		return !failed
+	}
+	return true
+}
+
+// Outcome statuses
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Message is the body POSTed to a webhook
+type Message struct {
+	// Text summarizes the outcome in one line; it is the field Slack,
+	// Mattermost and Teams incoming webhooks display
+	Text       string    `json:"text"`
+	Pipeline   string    `json:"pipeline"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	ExitCode   int       `json:"exitCode"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Run is the run as recorded in the run history, when it was
+	Run *history.Run `json:"run,omitempty"`
+}
+
+// NewMessage describes the outcome of a pipeline's run, which failed with
+// err unless it is nil
+func NewMessage(pipeline string, startedAt time.Time, run *history.Run, err error, exitCode int) Message {
+	message := Message{
+		Pipeline:   pipeline,
+		Status:     StatusSucceeded,
+		ExitCode:   exitCode,
+		StartedAt:  startedAt.UTC(),
+		FinishedAt: time.Now().UTC(),
+		Run:        run,
+	}
+	if err != nil {
+		message.Status = StatusFailed
+		message.Error = err.Error()
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Pipeline %s %s", pipeline, message.Status)
+	if run != nil {
+		fmt.Fprintf(&text, ": %d leads, %d created, %d updated, %d skipped, %d errors", run.Total, run.Created, run.Updated, run.Skipped, run.Errors)
+	}
+	if err != nil {
+		fmt.Fprintf(&text, ": %v", err)
+	}
+	message.Text = text.String()
+	return message
+}
+
+// Send POSTs the message to the webhook's URL. Errors don't quote the URL,
+// which may hold the webhook's secret.
+func Send(client *http.Client, webhook Webhook, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The client's error quotes the whole URL; keep only its cause
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call the notification webhook: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("the notification webhook refused the message: %w", &errcode.StatusError{Status: resp.StatusCode})
+	}
+	return nil
+}
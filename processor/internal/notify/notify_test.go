@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"code/internal/history"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_Wants(t *testing.T) {
+	t.Run("calls each webhook when it asked to be", func(t *testing.T) {
+		// Assert
+		assert.True(t, Webhook{}.Wants(true))
+		assert.True(t, Webhook{On: OnAlways}.Wants(false))
+		assert.True(t, Webhook{On: OnFailure}.Wants(true))
+		assert.False(t, Webhook{On: OnFailure}.Wants(false))
+		assert.True(t, Webhook{On: OnSuccess}.Wants(false))
+		assert.False(t, Webhook{On: OnSuccess}.Wants(true))
+	})
+}
+
+func TestSend(t *testing.T) {
+	t.Run("posts the outcome with a one-line summary", func(t *testing.T) {
+		// Arrange
+		var received Message
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		}))
+		defer server.Close()
+		run := &history.Run{ID: "run-1", Total: 10, Created: 6, Updated: 2, Skipped: 1, Errors: 1}
+		message := NewMessage("acme-daily", time.Now(), run, errors.New("1 lead(s) failed"), 1)
+
+		// Act
+		err := Send(server.Client(), Webhook{URL: server.URL}, message)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, StatusFailed, received.Status)
+		assert.Equal(t, "run-1", received.Run.ID)
+		assert.Equal(t, "Pipeline acme-daily failed: 10 leads, 6 created, 2 updated, 1 skipped, 1 errors: 1 lead(s) failed", received.Text)
+	})
+
+	t.Run("fails when the webhook refuses the message", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		// Act
+		err := Send(server.Client(), Webhook{URL: server.URL}, NewMessage("acme-daily", time.Now(), nil, nil, 0))
+
+		// Assert
+		assert.ErrorContains(t, err, "refused")
+	})
+	t.Run("keeps the webhook's URL out of its errors", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		webhookURL := server.URL + "/services/T000/B000/s3cr3t-token"
+		server.Close()
+
+		// Act
+		err := Send(http.DefaultClient, Webhook{URL: webhookURL}, NewMessage("acme-daily", time.Now(), nil, nil, 0))
+
+		// Assert
+		assert.ErrorContains(t, err, "failed to call the notification webhook")
+		assert.NotContains(t, err.Error(), "s3cr3t-token")
+		assert.NotContains(t, err.Error(), webhookURL)
+	})
+}
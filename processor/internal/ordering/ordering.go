@@ -0,0 +1,138 @@
+package ordering
+
+import (
+	"code/internal/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key kinds accepted by ParseKey
+const (
+	ByScore  = "score"
+	BySource = "source"
+	ByColumn = "column"
+)
+
+// scoreColumns are the header names a lead score is read from
+var scoreColumns = []string{"score", "lead score", "lead_score", "leadscore"}
+
+// DefaultSourcePriority ranks sources by typical lead value, highest first
+func DefaultSourcePriority() []string {
+	return []string{"Conference", "Referral", "Webinar", "LinkedIn", "Website", "Twitter"}
+}
+
+// Key says what leads are ordered by
+type Key struct {
+	Kind   string
+	Column string
+}
+
+// ParseKey parses an --order-by value: "score", "source" or "column:<name>"
+func ParseKey(value string) (Key, error) {
+	value = strings.TrimSpace(value)
+
+	switch kind, column, _ := strings.Cut(value, ":"); strings.ToLower(kind) {
+	case ByScore:
+		return Key{Kind: ByScore}, nil
+	case BySource:
+		return Key{Kind: BySource}, nil
+	case ByColumn:
+		if strings.TrimSpace(column) == "" {
+			return Key{}, fmt.Errorf("column ordering needs a column name, e.g. column:Revenue")
+		}
+		return Key{Kind: ByColumn, Column: strings.TrimSpace(column)}, nil
+	}
+
+	return Key{}, fmt.Errorf("unknown ordering %q (use score, source or column:<name>)", value)
+}
+
+// Columns returns the source columns the key reads
+func (k Key) Columns() []string {
+	switch k.Kind {
+	case ByScore:
+		return scoreColumns
+	case ByColumn:
+		return []string{k.Column}
+	}
+	return nil
+}
+
+// Sort orders leads highest priority first, keeping file order among equals:
+//   - score: the Score column, highest first
+//   - source: the position of the lead's source in sourcePriority
+//   - column: the named column, highest first; numbers compare numerically
+//
+// Leads without a value sort last.
+func Sort(leads []*models.Lead, key Key, sourcePriority []string) {
+	var less func(a, b *models.Lead) bool
+
+	switch key.Kind {
+	case ByScore:
+		less = func(a, b *models.Lead) bool {
+			return higher(scoreOf(a), scoreOf(b))
+		}
+	case BySource:
+		ranks := make(map[string]int, len(sourcePriority))
+		for i, source := range sourcePriority {
+			ranks[strings.ToLower(source)] = i
+		}
+		rank := func(lead *models.Lead) int {
+			if r, ok := ranks[strings.ToLower(strings.TrimSpace(lead.Source))]; ok {
+				return r
+			}
+			return len(sourcePriority)
+		}
+		less = func(a, b *models.Lead) bool {
+			return rank(a) < rank(b)
+		}
+	case ByColumn:
+		less = func(a, b *models.Lead) bool {
+			return higher(columnOf(a, key.Column), columnOf(b, key.Column))
+		}
+	default:
+		return
+	}
+
+	sort.SliceStable(leads, func(i, j int) bool {
+		return less(leads[i], leads[j])
+	})
+}
+
+// scoreOf returns the lead's score column, or "" if it has none
+func scoreOf(lead *models.Lead) string {
+	for _, column := range scoreColumns {
+		if value, ok := lead.RawValue(column); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func columnOf(lead *models.Lead, column string) string {
+	value, _ := lead.RawValue(column)
+	return value
+}
+
+// higher reports whether a sorts before b in highest-first order. Numbers
+// compare numerically and before text; empty values sort last.
+func higher(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == "" || b == "" {
+		return a != "" && b == ""
+	}
+
+	aNum, aErr := strconv.ParseFloat(a, 64)
+	bNum, bErr := strconv.ParseFloat(b, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return aNum > bNum
+	case aErr == nil:
+		return true
+	case bErr == nil:
+		return false
+	}
+
+	return strings.ToLower(a) > strings.ToLower(b)
+}
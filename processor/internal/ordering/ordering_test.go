@@ -0,0 +1,97 @@
+package ordering
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey(t *testing.T) {
+	t.Run("accepts score, source and columns", func(t *testing.T) {
+		for value, expected := range map[string]Key{
+			"score":          {Kind: ByScore},
+			"Source":         {Kind: BySource},
+			"column:Revenue": {Kind: ByColumn, Column: "Revenue"},
+		} {
+			// Act
+			key, err := ParseKey(value)
+
+			// Assert
+			assert.NoError(t, err, value)
+			assert.Equal(t, expected, key, value)
+		}
+	})
+
+	t.Run("rejects unknown orderings", func(t *testing.T) {
+		for _, value := range []string{"size", "column:", ""} {
+			// Act
+			_, err := ParseKey(value)
+
+			// Assert
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestSort(t *testing.T) {
+	lead := func(email, source string, raw map[string]string) *models.Lead {
+		l := models.NewLead("Lead", email, "Acme", source)
+		l.Raw = raw
+		return l
+	}
+	emails := func(leads []*models.Lead) []string {
+		result := make([]string, len(leads))
+		for i, l := range leads {
+			result[i] = l.Email
+		}
+		return result
+	}
+
+	t.Run("orders by score, highest first and missing last", func(t *testing.T) {
+		// Arrange
+		leads := []*models.Lead{
+			lead("a@example.com", "Website", map[string]string{"Score": "40"}),
+			lead("b@example.com", "Website", map[string]string{"Score": ""}),
+			lead("c@example.com", "Website", map[string]string{"Score": "95.5"}),
+			lead("d@example.com", "Website", map[string]string{"Score": "40"}),
+		}
+
+		// Act
+		Sort(leads, Key{Kind: ByScore}, nil)
+
+		// Assert
+		assert.Equal(t, []string{"c@example.com", "a@example.com", "d@example.com", "b@example.com"}, emails(leads))
+	})
+
+	t.Run("orders by source priority", func(t *testing.T) {
+		// Arrange
+		leads := []*models.Lead{
+			lead("a@example.com", "Website", nil),
+			lead("b@example.com", "Unknown", nil),
+			lead("c@example.com", "conference", nil),
+			lead("d@example.com", "LinkedIn", nil),
+		}
+
+		// Act
+		Sort(leads, Key{Kind: BySource}, DefaultSourcePriority())
+
+		// Assert
+		assert.Equal(t, []string{"c@example.com", "d@example.com", "a@example.com", "b@example.com"}, emails(leads))
+	})
+
+	t.Run("orders by a named column", func(t *testing.T) {
+		// Arrange
+		leads := []*models.Lead{
+			lead("a@example.com", "Website", map[string]string{"Revenue": "900"}),
+			lead("b@example.com", "Website", map[string]string{"Revenue": "12000"}),
+			lead("c@example.com", "Website", map[string]string{}),
+		}
+
+		// Act
+		Sort(leads, Key{Kind: ByColumn, Column: "revenue"}, nil)
+
+		// Assert
+		assert.Equal(t, []string{"b@example.com", "a@example.com", "c@example.com"}, emails(leads))
+	})
+}
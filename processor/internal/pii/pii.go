@@ -0,0 +1,128 @@
+package pii
+
+import (
+	"code/internal/models"
+	"code/internal/report"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// How a field is protected
+const (
+	// ModeHash replaces a value with the hex HMAC-SHA256 of it
+	ModeHash = "hash"
+	// ModeToken replaces a value with a short token of the same HMAC,
+	// e.g. email_2xq7m4kd5v3rfwq6, which reads better in dashboards
+	ModeToken = "token"
+)
+
+// minKeyLength is the shortest key accepted, in bytes
+const minKeyLength = 16
+
+// Fields are the record fields that can be protected
+func Fields() []string {
+	return []string{"name", "email", "company"}
+}
+
+// Config picks the fields of exported records to pseudonymize. The same
+// value always gets the same hash under one key, so analytics can still join
+// on it, but the raw value can't be read back without the key.
+type Config struct {
+	// Fields maps fields to their mode, hash or token
+	Fields map[string]string `yaml:"fields"`
+	// Key is the HMAC key; prefer KeyEnv or a secret reference to keep it
+	// out of the config file
+	Key string `yaml:"key"`
+	// KeyEnv names the environment variable holding the key
+	KeyEnv string `yaml:"key_env"`
+}
+
+// IsZero reports whether no field is protected
+func (c Config) IsZero() bool {
+	return len(c.Fields) == 0
+}
+
+// Validate checks the fields and modes, and that a key is given
+func (c Config) Validate() error {
+	for field, mode := range c.Fields {
+		if !slices.Contains(Fields(), field) {
+			return fmt.Errorf("fields: %q cannot be protected (allowed: %s)", field, strings.Join(Fields(), ", "))
+		}
+		if mode != ModeHash && mode != ModeToken {
+			return fmt.Errorf("fields: unknown mode %q for %s (use %s or %s)", mode, field, ModeHash, ModeToken)
+		}
+	}
+	if !c.IsZero() && (c.Key == "") == (c.KeyEnv == "") {
+		return fmt.Errorf("set one of key or key_env")
+	}
+	return nil
+}
+
+// Hasher pseudonymizes the configured fields of records
+type Hasher struct {
+	key    []byte
+	fields map[string]string
+}
+
+// New resolves the key of cfg
+func New(cfg Config) (*Hasher, error) {
+	key := cfg.Key
+	if cfg.KeyEnv != "" {
+		key = os.Getenv(cfg.KeyEnv)
+		if key == "" {
+			return nil, fmt.Errorf("$%s is not set", cfg.KeyEnv)
+		}
+	}
+	if len(key) < minKeyLength {
+		return nil, fmt.Errorf("the key must be at least %d bytes", minKeyLength)
+	}
+	return &Hasher{key: []byte(key), fields: cfg.Fields}, nil
+}
+
+// Value returns the protected form of a field's value. Values are compared
+// normalized, ignoring case and whitespace, so "Jane@Example.com " and
+// "jane@example.com" hash alike; an empty value stays empty.
+func (h *Hasher) Value(field, value string) string {
+	mode, ok := h.fields[field]
+	canonical := models.Canonical(value)
+	if !ok || canonical == "" {
+		return value
+	}
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(canonical))
+	sum := mac.Sum(nil)
+	if mode == ModeToken {
+		return field + "_" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:10]))
+	}
+	return hex.EncodeToString(sum)
+}
+
+// Record returns the record with its protected fields replaced. A raw value
+// quoted in the error, such as an invalid email, is replaced there too.
+func (h *Hasher) Record(record report.Record) report.Record {
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"name", &record.Name},
+		{"email", &record.Email},
+		{"company", &record.Company},
+	} {
+		raw := *field.value
+		protected := h.Value(field.name, raw)
+		if protected == raw {
+			continue
+		}
+		*field.value = protected
+		if strings.TrimSpace(raw) != "" {
+			record.Error = strings.ReplaceAll(record.Error, raw, protected)
+		}
+	}
+	return record
+}
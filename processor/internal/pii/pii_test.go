@@ -0,0 +1,121 @@
+package pii
+
+import (
+	"code/internal/report"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const key = "0123456789abcdef"
+
+func TestHasher_Value(t *testing.T) {
+	hasher, err := New(Config{Fields: map[string]string{"email": ModeHash, "name": ModeToken}, Key: key})
+	assert.NoError(t, err)
+
+	t.Run("hashes with HMAC-SHA256 of the normalized value", func(t *testing.T) {
+		// Arrange
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte("jane@example.com"))
+
+		// Act
+		hashed := hasher.Value("email", " Jane@Example.COM ")
+
+		// Assert
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), hashed)
+		assert.Equal(t, hashed, hasher.Value("email", "jane@example.com"))
+	})
+
+	t.Run("tokenizes with a short prefixed form", func(t *testing.T) {
+		// Act
+		token := hasher.Value("name", "Jane  Smith")
+
+		// Assert
+		assert.Regexp(t, `^name_[a-z2-7]{16}$`, token)
+		assert.Equal(t, token, hasher.Value("name", "jane smith"))
+		assert.NotEqual(t, token, hasher.Value("name", "John Smith"))
+	})
+
+	t.Run("leaves other fields and empty values alone", func(t *testing.T) {
+		// Act & Assert
+		assert.Equal(t, "Tech Firm", hasher.Value("company", "Tech Firm"))
+		assert.Equal(t, "", hasher.Value("email", ""))
+	})
+
+	t.Run("hashes differently under another key", func(t *testing.T) {
+		// Arrange
+		other, err := New(Config{Fields: map[string]string{"email": ModeHash}, Key: "fedcba9876543210"})
+		assert.NoError(t, err)
+
+		// Act & Assert
+		assert.NotEqual(t, hasher.Value("email", "jane@example.com"), other.Value("email", "jane@example.com"))
+	})
+}
+
+func TestHasher_Record(t *testing.T) {
+	t.Run("replaces raw values in the error too", func(t *testing.T) {
+		// Arrange
+		hasher, err := New(Config{Fields: map[string]string{"email": ModeToken}, Key: key})
+		assert.NoError(t, err)
+		record := report.Record{Name: "Jane", Email: "jane@@example.com", Action: "VALIDATION_ERROR", Error: "invalid email: jane@@example.com"}
+
+		// Act
+		protected := hasher.Record(record)
+
+		// Assert
+		assert.Equal(t, "Jane", protected.Name)
+		assert.NotContains(t, protected.Error, "jane@@example.com")
+		assert.Equal(t, "invalid email: "+protected.Email, protected.Error)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"accepts nothing protected", Config{}, ""},
+		{"accepts a field with a key", Config{Fields: map[string]string{"email": ModeHash}, KeyEnv: "PII_KEY"}, ""},
+		{"rejects unknown fields", Config{Fields: map[string]string{"phone": ModeHash}, Key: key}, `"phone" cannot be protected`},
+		{"rejects unknown modes", Config{Fields: map[string]string{"email": "encrypt"}, Key: key}, `unknown mode "encrypt"`},
+		{"requires a key", Config{Fields: map[string]string{"email": ModeHash}}, "set one of key or key_env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.cfg.Validate()
+
+			// Assert
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("reads the key from the environment", func(t *testing.T) {
+		// Arrange
+		t.Setenv("PII_KEY", key)
+
+		// Act
+		_, err := New(Config{Fields: map[string]string{"email": ModeHash}, KeyEnv: "PII_KEY"})
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects short keys", func(t *testing.T) {
+		// Act
+		_, err := New(Config{Fields: map[string]string{"email": ModeHash}, Key: "short"})
+
+		// Assert
+		assert.EqualError(t, err, "the key must be at least 16 bytes")
+	})
+}
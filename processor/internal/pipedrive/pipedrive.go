@@ -0,0 +1,412 @@
+package pipedrive
+
+import (
+	"bytes"
+	"code/internal/errcode"
+	"code/internal/fieldmap"
+	"code/internal/models"
+	"code/internal/processor"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPersonNotFound is returned when Pipedrive has no person with the requested ID
+var ErrPersonNotFound = errors.New("person not found")
+
+// searchPageSize is how many search results are requested per page
+const searchPageSize = 100
+
+// timeLayout is how Pipedrive formats add_time and update_time, in UTC
+const timeLayout = "2006-01-02 15:04:05"
+
+// customFields are the lead fields stored in person custom fields. Company is
+// the person's organization unless it is mapped to a custom field too.
+var customFields = []string{"company", "source", "status", "territory", "language", "consentGiven", "consentTimestamp", "consentSource"}
+
+// Config holds the Pipedrive settings of the config file
+type Config struct {
+	// APIToken authenticates requests; $PIPEDRIVE_API_TOKEN is used when empty
+	APIToken string `yaml:"api_token"`
+
+	// CompanyDomain is the account's subdomain, as in https://<domain>.pipedrive.com
+	CompanyDomain string `yaml:"company_domain"`
+
+	// APIURL overrides the API base URL derived from the company domain
+	APIURL string `yaml:"api_url"`
+
+	// Fields maps lead fields to the API keys of person custom fields, the
+	// 40-character hashes Pipedrive shows under Data fields
+	Fields map[string]string `yaml:"fields"`
+}
+
+// Validate checks the custom field mapping, normalising field names
+func (c *Config) Validate() error {
+	fields, err := fieldmap.Normalize(c.Fields, customFields)
+	if err != nil {
+		return fmt.Errorf("fields: %w", err)
+	}
+	c.Fields = fields
+	return nil
+}
+
+// Client stores leads as Pipedrive persons, implementing processor.APIClient.
+// Persons are found by exact email; name and email are person fields, company
+// is the person's organization and the remaining lead fields go to the
+// custom fields named in Config.Fields.
+type Client struct {
+	baseURL    string
+	token      string
+	fields     fieldmap.Mapping
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	organizations map[string]int
+	// persons remembers the person ID of each email looked up, since updates
+	// identify the lead by email
+	persons sync.Map
+}
+
+// New creates a Pipedrive client sending requests through httpClient. Source
+// must be mapped to a custom field, since every lead has one.
+func New(cfg Config, httpClient *http.Client) (*Client, error) {
+	if cfg.APIToken == "" {
+		return nil, fmt.Errorf("pipedrive: an API token is required (pipedrive.api_token or PIPEDRIVE_API_TOKEN)")
+	}
+	baseURL := strings.TrimSuffix(cfg.APIURL, "/")
+	if baseURL == "" {
+		if cfg.CompanyDomain == "" {
+			return nil, fmt.Errorf("pipedrive: company_domain is required")
+		}
+		baseURL = fmt.Sprintf("https://%s.pipedrive.com/api/v1", url.PathEscape(cfg.CompanyDomain))
+	}
+	if cfg.Fields["source"] == "" {
+		return nil, fmt.Errorf("pipedrive: fields.source must name the custom field leads' source is kept in")
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	// Custom fields are text fields, so the consent flag is sent as text too
+	fields := fieldmap.New(map[string]string{"name": "name"}, cfg.Fields)
+	fields.FlagsAsText = true
+
+	return &Client{
+		baseURL:       baseURL,
+		token:         cfg.APIToken,
+		fields:        fields,
+		httpClient:    httpClient,
+		organizations: make(map[string]int),
+	}, nil
+}
+
+// person is a Pipedrive person as returned by GET /persons/{id}. Custom
+// fields are top-level keys, so the raw object is kept alongside.
+type person struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	AddTime    string `json:"add_time"`
+	UpdateTime string `json:"update_time"`
+	Email      []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"email"`
+	OrgName string `json:"org_name"`
+	// OrgID is an object with the organization's name when read, but just
+	// the ID in some write responses
+	OrgID json.RawMessage `json:"org_id"`
+
+	raw map[string]json.RawMessage
+}
+
+// envelope is the wrapper of every Pipedrive response
+type envelope struct {
+	Success        bool            `json:"success"`
+	Error          string          `json:"error"`
+	Data           json.RawMessage `json:"data"`
+	AdditionalData struct {
+		Pagination struct {
+			MoreItemsInCollection bool `json:"more_items_in_collection"`
+			NextStart             int  `json:"next_start"`
+		} `json:"pagination"`
+	} `json:"additional_data"`
+}
+
+// LookupLead finds the person with exactly this email, paging through the
+// search results since Pipedrive's search also matches partial emails
+func (c *Client) LookupLead(email string) (*processor.LookupResponse, error) {
+	id, err := c.findPerson(email)
+	if err != nil || id == 0 {
+		return &processor.LookupResponse{Found: false}, err
+	}
+
+	p, err := c.getPerson(id)
+	if errors.Is(err, ErrPersonNotFound) {
+		// Deleted between the search and the read
+		return &processor.LookupResponse{Found: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.persons.Store(strings.ToLower(strings.TrimSpace(email)), id)
+	return &processor.LookupResponse{Found: true, Lead: c.toLead(p)}, nil
+}
+
+func (c *Client) findPerson(email string) (int, error) {
+	start := 0
+	for {
+		query := url.Values{
+			"term":        {email},
+			"fields":      {"email"},
+			"exact_match": {"true"},
+			"start":       {strconv.Itoa(start)},
+			"limit":       {strconv.Itoa(searchPageSize)},
+		}
+		var items struct {
+			Items []struct {
+				Item struct {
+					ID     int      `json:"id"`
+					Emails []string `json:"emails"`
+				} `json:"item"`
+			} `json:"items"`
+		}
+		env, err := c.do(http.MethodGet, "/persons/search?"+query.Encode(), nil, &items)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, result := range items.Items {
+			for _, candidate := range result.Item.Emails {
+				if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(email)) {
+					return result.Item.ID, nil
+				}
+			}
+		}
+
+		pagination := env.AdditionalData.Pagination
+		if !pagination.MoreItemsInCollection || pagination.NextStart <= start {
+			return 0, nil
+		}
+		start = pagination.NextStart
+	}
+}
+
+func (c *Client) getPerson(id int) (*person, error) {
+	var raw map[string]json.RawMessage
+	if _, err := c.do(http.MethodGet, fmt.Sprintf("/persons/%d", id), nil, &raw); err != nil {
+		return nil, err
+	}
+	return decodePerson(raw)
+}
+
+func decodePerson(raw map[string]json.RawMessage) (*person, error) {
+	data, _ := json.Marshal(raw)
+	var p person
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode Pipedrive person: %w", err)
+	}
+	p.raw = raw
+	return &p, nil
+}
+
+// CreateLead adds a person for the lead
+func (c *Client) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	payload, err := c.personPayload(lead)
+	if err != nil {
+		return nil, err
+	}
+	return c.writePerson(http.MethodPost, "/persons", payload)
+}
+
+// UpdateLead updates the person with the lead's ID or, without one, its email
+func (c *Client) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	id := lead.ID
+	if id == "" {
+		personID, err := c.personID(lead.Email)
+		if err != nil {
+			return nil, err
+		}
+		id = strconv.Itoa(personID)
+	}
+
+	payload, err := c.personPayload(lead)
+	if err != nil {
+		return nil, err
+	}
+	return c.writePerson(http.MethodPut, "/persons/"+url.PathEscape(id), payload)
+}
+
+// personID returns the ID of the person with this email, searching only when
+// it wasn't just looked up
+func (c *Client) personID(email string) (int, error) {
+	if id, ok := c.persons.Load(strings.ToLower(strings.TrimSpace(email))); ok {
+		return id.(int), nil
+	}
+	id, err := c.findPerson(email)
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		return 0, ErrPersonNotFound
+	}
+	return id, nil
+}
+
+// DeleteLead deletes a person; Pipedrive keeps it restorable for 30 days
+func (c *Client) DeleteLead(id string) error {
+	_, err := c.do(http.MethodDelete, "/persons/"+url.PathEscape(id), nil, nil)
+	return err
+}
+
+func (c *Client) writePerson(method, path string, payload map[string]interface{}) (*models.Lead, error) {
+	var raw map[string]json.RawMessage
+	if _, err := c.do(method, path, payload, &raw); err != nil {
+		return nil, err
+	}
+	p, err := decodePerson(raw)
+	if err != nil {
+		return nil, err
+	}
+	return c.toLead(p), nil
+}
+
+// personPayload builds the person fields for a lead
+func (c *Client) personPayload(lead *models.Lead) (map[string]interface{}, error) {
+	payload := c.fields.Payload(lead)
+	payload["email"] = []map[string]interface{}{{"value": lead.Email, "primary": true, "label": "work"}}
+
+	if !c.fields.Has("company") && lead.Company != "" {
+		orgID, err := c.organization(lead.Company)
+		if err != nil {
+			return nil, err
+		}
+		payload["org_id"] = orgID
+	}
+	return payload, nil
+}
+
+// organization returns the ID of the organization named name, adding it if
+// Pipedrive has none
+func (c *Client) organization(name string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id, ok := c.organizations[name]; ok {
+		return id, nil
+	}
+
+	query := url.Values{"term": {name}, "fields": {"name"}, "exact_match": {"true"}, "limit": {"1"}}
+	var found struct {
+		Items []struct {
+			Item struct {
+				ID int `json:"id"`
+			} `json:"item"`
+		} `json:"items"`
+	}
+	if _, err := c.do(http.MethodGet, "/organizations/search?"+query.Encode(), nil, &found); err != nil {
+		return 0, err
+	}
+
+	id := 0
+	if len(found.Items) > 0 {
+		id = found.Items[0].Item.ID
+	} else {
+		var created struct {
+			ID int `json:"id"`
+		}
+		if _, err := c.do(http.MethodPost, "/organizations", map[string]string{"name": name}, &created); err != nil {
+			return 0, err
+		}
+		id = created.ID
+	}
+	c.organizations[name] = id
+	return id, nil
+}
+
+// toLead converts a person to a lead
+func (c *Client) toLead(p *person) *models.Lead {
+	lead := c.fields.Lead(func(key string) string {
+		var value string
+		json.Unmarshal(p.raw[key], &value)
+		return value
+	})
+	lead.ID = strconv.Itoa(p.ID)
+	for _, email := range p.Email {
+		if email.Primary || lead.Email == "" {
+			lead.Email = email.Value
+		}
+	}
+	if !c.fields.Has("company") {
+		lead.Company = p.OrgName
+		var org struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(p.OrgID, &org) == nil && org.Name != "" {
+			lead.Company = org.Name
+		}
+	}
+
+	if created, err := time.Parse(timeLayout, p.AddTime); err == nil {
+		lead.CreatedAt = created
+	}
+	if updated, err := time.Parse(timeLayout, p.UpdateTime); err == nil {
+		lead.UpdatedAt = &updated
+	}
+	return lead
+}
+
+// do sends a request to the API and decodes the response's data into v
+func (c *Client) do(method, path string, payload interface{}, v interface{}) (*envelope, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	// A header keeps the token out of URLs, and so out of errors and logs
+	req.Header.Set("x-api-token", c.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	decodeErr := json.NewDecoder(resp.Body).Decode(&env)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrPersonNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := &errcode.StatusError{Status: resp.StatusCode}
+		if env.Error != "" {
+			return nil, fmt.Errorf("pipedrive: %s: %w", env.Error, err)
+		}
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	if v != nil && len(env.Data) > 0 && string(env.Data) != "null" {
+		if err := json.Unmarshal(env.Data, v); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return &env, nil
+}
@@ -0,0 +1,234 @@
+package pipedrive
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sourceKey = "a1b2c3d4e5"
+
+// fakePipedrive serves the person and organization endpoints from memory
+type fakePipedrive struct {
+	persons map[string]map[string]interface{}
+	orgs    map[string]int
+	written map[string]interface{}
+}
+
+func (f *fakePipedrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(data interface{}, more bool, nextStart int) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    data,
+			"additional_data": map[string]interface{}{
+				"pagination": map[string]interface{}{"more_items_in_collection": more, "next_start": nextStart},
+			},
+		})
+	}
+	if r.Header.Get("x-api-token") != "t0ken" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "unauthorized access"})
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/persons/search":
+		// The first page only holds a partial match
+		if r.URL.Query().Get("start") == "0" {
+			reply(map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"item": map[string]interface{}{"id": 9, "emails": []string{"jane@techfirm.com.au"}}},
+			}}, true, 1)
+			return
+		}
+		var items []interface{}
+		for id, p := range f.persons {
+			if p["email"].([]interface{})[0].(map[string]interface{})["value"] == r.URL.Query().Get("term") {
+				items = append(items, map[string]interface{}{"item": map[string]interface{}{"id": json.Number(id), "emails": []string{r.URL.Query().Get("term")}}})
+			}
+		}
+		reply(map[string]interface{}{"items": items}, false, 0)
+	case strings.HasPrefix(r.URL.Path, "/persons/") && r.Method == http.MethodGet:
+		p, ok := f.persons[strings.TrimPrefix(r.URL.Path, "/persons/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		reply(p, false, 0)
+	case strings.HasPrefix(r.URL.Path, "/persons"):
+		json.NewDecoder(r.Body).Decode(&f.written)
+		f.written["id"] = 2
+		f.written["add_time"] = "2026-10-16 10:00:00"
+		reply(f.written, false, 0)
+	case r.URL.Path == "/organizations/search":
+		var items []interface{}
+		if id, ok := f.orgs[r.URL.Query().Get("term")]; ok {
+			items = append(items, map[string]interface{}{"item": map[string]interface{}{"id": id}})
+		}
+		reply(map[string]interface{}{"items": items}, false, 0)
+	case r.URL.Path == "/organizations":
+		reply(map[string]interface{}{"id": 77}, false, 0)
+	}
+}
+
+func newFake() *fakePipedrive {
+	return &fakePipedrive{
+		persons: map[string]map[string]interface{}{
+			"1": {
+				"id":          1,
+				"name":        "Jane Smith",
+				"email":       []interface{}{map[string]interface{}{"value": "jane@techfirm.com", "primary": true}},
+				"org_id":      map[string]interface{}{"name": "Tech Firm", "value": 5},
+				"add_time":    "2026-01-02 03:04:05",
+				"update_time": "2026-02-02 03:04:05",
+				sourceKey:     "LinkedIn",
+			},
+		},
+		orgs: map[string]int{"Tech Firm": 5},
+	}
+}
+
+func newTestClient(t *testing.T, url string) *Client {
+	client, err := New(Config{APIToken: "t0ken", APIURL: url, Fields: map[string]string{"source": sourceKey}}, nil)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestClient_LookupLead(t *testing.T) {
+	t.Run("finds the person with exactly the email across search pages", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(newFake())
+		defer server.Close()
+		client := newTestClient(t, server.URL)
+
+		// Act
+		resp, err := client.LookupLead("jane@techfirm.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, resp.Found)
+		assert.Equal(t, "1", resp.Lead.ID)
+		assert.Equal(t, "Jane Smith", resp.Lead.Name)
+		assert.Equal(t, "Tech Firm", resp.Lead.Company)
+		assert.Equal(t, "LinkedIn", resp.Lead.Source)
+		assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), resp.Lead.CreatedAt)
+	})
+
+	t.Run("reports unknown emails as not found", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(newFake())
+		defer server.Close()
+		client := newTestClient(t, server.URL)
+
+		// Act
+		resp, err := client.LookupLead("nobody@techfirm.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, resp.Found)
+	})
+
+	t.Run("classifies a refused token", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(newFake())
+		defer server.Close()
+		client, _ := New(Config{APIToken: "wrong", APIURL: server.URL, Fields: map[string]string{"source": sourceKey}}, nil)
+
+		// Act
+		_, err := client.LookupLead("jane@techfirm.com")
+
+		// Assert
+		assert.ErrorIs(t, err, errcode.ErrAuth)
+		assert.ErrorContains(t, err, "unauthorized access")
+	})
+}
+
+func TestClient_Write(t *testing.T) {
+	t.Run("creates a person in a new organization with custom fields", func(t *testing.T) {
+		// Arrange
+		fake := newFake()
+		server := httptest.NewServer(fake)
+		defer server.Close()
+		client := newTestClient(t, server.URL)
+
+		// Act
+		created, err := client.CreateLead(models.NewLead("John Doe", "john@acme.com", "Acme", "Webinar"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "2", created.ID)
+		assert.Equal(t, "Webinar", fake.written[sourceKey])
+		assert.Equal(t, float64(77), fake.written["org_id"])
+	})
+
+	t.Run("updates the person found by the lookup", func(t *testing.T) {
+		// Arrange
+		fake := newFake()
+		server := httptest.NewServer(fake)
+		defer server.Close()
+		client := newTestClient(t, server.URL)
+		client.LookupLead("jane@techfirm.com")
+
+		// Act
+		_, err := client.UpdateLead(models.NewLead("Jane Smith", "jane@techfirm.com", "Tech Firm", "Referral"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Referral", fake.written[sourceKey])
+		assert.Equal(t, float64(5), fake.written["org_id"])
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects fields that have no custom field", func(t *testing.T) {
+		// Arrange
+		cfg := Config{Fields: map[string]string{"Email": "abc"}}
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("requires a source field", func(t *testing.T) {
+		// Act
+		_, err := New(Config{APIToken: "t0ken", CompanyDomain: "acme"}, nil)
+
+		// Assert
+		assert.ErrorContains(t, err, "fields.source")
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("waits for the window to reset once it is exhausted", func(t *testing.T) {
+		// Arrange
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("x-ratelimit-remaining", "0")
+			w.Header().Set("x-ratelimit-reset", "1")
+			if calls == 2 {
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer server.Close()
+		client := &http.Client{Transport: NewRateLimiter().Middleware(http.DefaultTransport)}
+
+		// Act
+		client.Get(server.URL)
+		start := time.Now()
+		resp, err := client.Get(server.URL)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+		assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	})
+}
@@ -0,0 +1,85 @@
+package pipedrive
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps requests within Pipedrive's rate limit. Every response
+// reports the requests left in the current window (x-ratelimit-remaining) and
+// the seconds until it resets (x-ratelimit-reset); once none are left, later
+// requests wait for the reset instead of being refused.
+type RateLimiter struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// NewRateLimiter creates a rate limiter with no wait pending
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{}
+}
+
+// Middleware holds requests back while the window is exhausted. A 429 without
+// Retry-After gets one from x-ratelimit-reset, so the retry middleware waits
+// for the window instead of its short backoff. It has the shape of api.Middleware.
+func (l *RateLimiter) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := l.wait(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+
+		reset := resetAfter(resp)
+		if resp.Header.Get("x-ratelimit-remaining") == "0" || resp.StatusCode == http.StatusTooManyRequests {
+			l.mu.Lock()
+			if until := time.Now().Add(reset); until.After(l.until) {
+				l.until = until
+			}
+			l.mu.Unlock()
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" && reset > 0 {
+			resp.Header.Set("Retry-After", strconv.Itoa(int(reset/time.Second)))
+		}
+		return resp, nil
+	})
+}
+
+// wait sleeps until the exhausted window resets or the request is cancelled
+func (l *RateLimiter) wait(req *http.Request) error {
+	l.mu.Lock()
+	delay := time.Until(l.until)
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// resetAfter reads x-ratelimit-reset, the seconds until the window resets
+func resetAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("x-ratelimit-reset"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
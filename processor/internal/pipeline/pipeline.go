@@ -0,0 +1,387 @@
+package pipeline
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stage names used when reporting queue depths and observing leads
+const (
+	StageRead      = "read"
+	StageTransform = "transform"
+	StageValidate  = "validate"
+	StageProcess   = "process"
+	StageResults   = "results"
+)
+
+// DefaultQueueSize bounds each stage queue when Config.QueueSize is not set
+const DefaultQueueSize = 100
+
+// Source streams leads into the pipeline by calling emit once per lead.
+// Returning an error from emit means the pipeline is shutting down.
+type Source func(emit func(*models.Lead) error) error
+
+// Transform rewrites a lead before validation
+type Transform func(*models.Lead) *models.Lead
+
+// LeadProcessor processes a single validated lead
+type LeadProcessor interface {
+	ProcessLead(lead *models.Lead) (*processor.ProcessResult, error)
+}
+
+// BatchProcessor processes leads a batch at a time. rejected holds the
+// results of leads an earlier stage already failed, nil for the rest.
+type BatchProcessor interface {
+	ProcessBatch(leads []*models.Lead, rejected []*processor.ProcessResult) []*processor.ProcessResult
+}
+
+// Item is a lead travelling through the pipeline together with its outcome
+type Item struct {
+	Index  int
+	Lead   *models.Lead
+	Result *processor.ProcessResult
+	Err    error
+	// ReadAt is when the reader emitted the lead
+	ReadAt time.Time
+	// Filtered is set when Config.Filter left the lead out; it then has
+	// neither Result nor Err
+	Filtered bool
+
+	// turn keeps the lead from being processed alongside an earlier one
+	// with the same email
+	turn *processor.Turn
+}
+
+// batch is a run of consecutive items processed together, with the turns
+// of their emails
+type batch struct {
+	items []*Item
+	turns []*processor.Turn
+}
+
+// Config controls queue sizes and concurrency
+type Config struct {
+	QueueSize  int
+	Workers    int
+	Transforms []Transform
+	// Filter, when set, lets through only the leads it matches once they are
+	// transformed; the rest go straight to the results, marked Filtered
+	Filter func(*models.Lead) bool
+	// Validation adjusts how leads are validated, e.g. with a stricter
+	// email check
+	Validation models.ValidationOptions
+	// Skip is how many leads at the start of the source are read but not
+	// processed, e.g. when resuming from a checkpoint; indexes still count them
+	Skip int
+	// BatchSize, when set and the processor is a BatchProcessor, hands leads
+	// to the processor in batches of this many, invalid ones included
+	BatchSize int
+	// Observe, when set, is called as each lead finishes a stage, with when
+	// the stage started on it; the reader's stage is StageRead. It is called
+	// from the stages' goroutines, concurrently with several workers.
+	Observe func(stage string, item *Item, started time.Time)
+}
+
+// QueueDepths is a snapshot of how many items are waiting in each stage queue
+type QueueDepths map[string]int
+
+// Pipeline runs leads through reader → transform → validate → process stages
+// connected by bounded channels, so a slow stage applies backpressure all the
+// way back to the reader instead of letting rows pile up in memory. With
+// several workers, leads with the same email are still processed one after
+// the other, in input order, so duplicates can't both be created.
+type Pipeline struct {
+	cfg       Config
+	processor LeadProcessor
+	batcher   BatchProcessor
+	batches   chan batch
+	// locks is nil with a single worker, which processes leads in order anyway
+	locks *processor.EmailLocks
+
+	queues    map[string]chan *Item
+	peaks     map[string]*int64
+	readErr   error
+	readErrMu sync.Mutex
+}
+
+// New creates a pipeline around the given processor
+func New(p LeadProcessor, cfg Config) *Pipeline {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+
+	pl := &Pipeline{
+		cfg:       cfg,
+		processor: p,
+		queues:    make(map[string]chan *Item),
+		peaks:     make(map[string]*int64),
+	}
+	for _, stage := range []string{StageTransform, StageValidate, StageProcess, StageResults} {
+		pl.queues[stage] = make(chan *Item, cfg.QueueSize)
+		pl.peaks[stage] = new(int64)
+	}
+	if batcher, ok := p.(BatchProcessor); ok && cfg.BatchSize > 0 {
+		pl.batcher = batcher
+		pl.batches = make(chan batch, cfg.Workers)
+	}
+	if cfg.Workers > 1 {
+		pl.locks = processor.NewEmailLocks()
+	}
+
+	return pl
+}
+
+// Run starts all stages and returns the results channel, which is closed once
+// every lead has been processed. Results arrive in completion order, which
+// only matches input order when Workers is 1.
+func (p *Pipeline) Run(ctx context.Context, source Source) <-chan *Item {
+	go p.read(ctx, source)
+	go p.transform(ctx)
+	go p.validate(ctx)
+	if p.batcher != nil {
+		go p.makeBatches(ctx)
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			p.process(ctx)
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(p.queues[StageResults])
+	}()
+
+	return p.queues[StageResults]
+}
+
+// Err returns the error that stopped the reader, if any. It is only
+// meaningful once the results channel has been drained.
+func (p *Pipeline) Err() error {
+	p.readErrMu.Lock()
+	defer p.readErrMu.Unlock()
+	return p.readErr
+}
+
+// Depths returns the current number of items waiting in each stage queue
+func (p *Pipeline) Depths() QueueDepths {
+	depths := make(QueueDepths, len(p.queues))
+	for stage, queue := range p.queues {
+		depths[stage] = len(queue)
+	}
+	return depths
+}
+
+// PeakDepths returns the deepest each stage queue has been during the run
+func (p *Pipeline) PeakDepths() QueueDepths {
+	depths := make(QueueDepths, len(p.peaks))
+	for stage, peak := range p.peaks {
+		depths[stage] = int(atomic.LoadInt64(peak))
+	}
+	return depths
+}
+
+func (p *Pipeline) read(ctx context.Context, source Source) {
+	out := p.queues[StageTransform]
+	defer close(out)
+
+	index := 0
+	err := source(func(lead *models.Lead) error {
+		index++
+		if index <= p.cfg.Skip {
+			return nil
+		}
+		item := &Item{Index: index, Lead: lead, ReadAt: time.Now()}
+		p.observe(StageRead, item, item.ReadAt)
+		return p.send(ctx, StageTransform, item)
+	})
+	if err != nil && err != ctx.Err() {
+		p.readErrMu.Lock()
+		p.readErr = err
+		p.readErrMu.Unlock()
+	}
+}
+
+func (p *Pipeline) transform(ctx context.Context) {
+	defer close(p.queues[StageValidate])
+
+	for item := range p.queues[StageTransform] {
+		started := time.Now()
+		for _, transform := range p.cfg.Transforms {
+			item.Lead = transform(item.Lead)
+		}
+		p.observe(StageTransform, item, started)
+		// The results are only closed once this stage is done, so a filtered
+		// lead can skip the stages between
+		if p.cfg.Filter != nil && !p.cfg.Filter(item.Lead) {
+			item.Filtered = true
+			if p.send(ctx, StageResults, item) != nil {
+				return
+			}
+			continue
+		}
+		if p.send(ctx, StageValidate, item) != nil {
+			return
+		}
+	}
+}
+
+func (p *Pipeline) validate(ctx context.Context) {
+	defer close(p.queues[StageProcess])
+
+	for item := range p.queues[StageValidate] {
+		started := time.Now()
+		if err := item.Lead.ValidateWith(p.cfg.Validation); err != nil {
+			// Invalid leads pass through the process stage untouched so
+			// results keep input order with a single worker
+			item.Result = &processor.ProcessResult{
+				Action: "VALIDATION_ERROR",
+				Lead:   item.Lead,
+				Error:  err,
+			}
+		}
+		// Turns are taken here, one lead at a time, so they follow input order
+		if item.Result == nil && p.locks != nil && p.batcher == nil {
+			item.turn = p.locks.Reserve(item.Lead.Email)
+		}
+		p.observe(StageValidate, item, started)
+		if p.send(ctx, StageProcess, item) != nil {
+			return
+		}
+	}
+}
+
+func (p *Pipeline) process(ctx context.Context) {
+	if p.batcher != nil {
+		p.processBatches(ctx)
+		return
+	}
+
+	for item := range p.queues[StageProcess] {
+		started := time.Now()
+		if item.Result == nil {
+			if item.turn != nil {
+				item.turn.Wait()
+			}
+			item.Result, item.Err = p.processor.ProcessLead(item.Lead)
+			if item.turn != nil {
+				item.turn.Release()
+			}
+		}
+		p.observe(StageProcess, item, started)
+		if p.send(ctx, StageResults, item) != nil {
+			return
+		}
+	}
+}
+
+// makeBatches cuts the items into batches of consecutive leads, the last one
+// partial when the input runs out. Batches are cut in one place and their
+// turns taken in order, so a batch only ever waits for earlier ones.
+func (p *Pipeline) makeBatches(ctx context.Context) {
+	defer close(p.batches)
+
+	items := make([]*Item, 0, p.cfg.BatchSize)
+	emit := func() error {
+		b := batch{items: items}
+		if p.locks != nil {
+			emails := make([]string, 0, len(items))
+			for _, item := range items {
+				if item.Result == nil {
+					emails = append(emails, item.Lead.Email)
+				}
+			}
+			b.turns = p.locks.ReserveAll(emails)
+		}
+		items = make([]*Item, 0, p.cfg.BatchSize)
+
+		select {
+		case p.batches <- b:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for item := range p.queues[StageProcess] {
+		items = append(items, item)
+		if len(items) < p.cfg.BatchSize {
+			continue
+		}
+		if emit() != nil {
+			return
+		}
+	}
+	if len(items) > 0 {
+		emit()
+	}
+}
+
+// processBatches hands each batch to the processor once earlier leads with
+// the same emails are done
+func (p *Pipeline) processBatches(ctx context.Context) {
+	for b := range p.batches {
+		started := time.Now()
+		leads := make([]*models.Lead, len(b.items))
+		rejected := make([]*processor.ProcessResult, len(b.items))
+		for i, item := range b.items {
+			leads[i] = item.Lead
+			rejected[i] = item.Result
+		}
+
+		for _, turn := range b.turns {
+			turn.Wait()
+		}
+		results := p.batcher.ProcessBatch(leads, rejected)
+		for _, turn := range b.turns {
+			turn.Release()
+		}
+
+		for i, result := range results {
+			b.items[i].Result = result
+			p.observe(StageProcess, b.items[i], started)
+			if err := p.send(ctx, StageResults, b.items[i]); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// observe reports that item finished stage, if anyone is observing
+func (p *Pipeline) observe(stage string, item *Item, started time.Time) {
+	if p.cfg.Observe != nil {
+		p.cfg.Observe(stage, item, started)
+	}
+}
+
+// send blocks until the stage queue has room or the context is cancelled
+func (p *Pipeline) send(ctx context.Context, stage string, item *Item) error {
+	queue := p.queues[stage]
+	select {
+	case queue <- item:
+		p.recordDepth(stage, len(queue))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) recordDepth(stage string, depth int) {
+	peak := p.peaks[stage]
+	for {
+		current := atomic.LoadInt64(peak)
+		if int64(depth) <= current || atomic.CompareAndSwapInt64(peak, current, int64(depth)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package pipeline
+
+import (
+	"code/internal/models"
+	"code/internal/processor"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingProcessor creates every lead and counts calls
+type countingProcessor struct {
+	calls int64
+}
+
+func (p *countingProcessor) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	atomic.AddInt64(&p.calls, 1)
+	return &processor.ProcessResult{Action: "CREATE", Lead: lead}, nil
+}
+
+// batchingProcessor records the batches it is handed
+type batchingProcessor struct {
+	countingProcessor
+	batches [][]*processor.ProcessResult
+}
+
+func (p *batchingProcessor) ProcessBatch(leads []*models.Lead, rejected []*processor.ProcessResult) []*processor.ProcessResult {
+	p.batches = append(p.batches, rejected)
+	results := make([]*processor.ProcessResult, len(leads))
+	for i, lead := range leads {
+		results[i] = rejected[i]
+		if results[i] == nil {
+			results[i] = &processor.ProcessResult{Action: "CREATE", Lead: lead}
+		}
+	}
+	return results
+}
+
+// orderingProcessor records the names of the leads it processes per email
+// and whether two leads with one email were ever processed at once
+type orderingProcessor struct {
+	mu       sync.Mutex
+	inFlight map[string]bool
+	names    map[string][]string
+	overlap  bool
+}
+
+func (p *orderingProcessor) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	p.mu.Lock()
+	if p.inFlight[lead.Email] {
+		p.overlap = true
+	}
+	p.inFlight[lead.Email] = true
+	p.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight[lead.Email] = false
+	p.names[lead.Email] = append(p.names[lead.Email], lead.Name)
+	p.mu.Unlock()
+	return &processor.ProcessResult{Action: "CREATE", Lead: lead}, nil
+}
+
+// orderingBatchProcessor processes each batch's leads one by one
+type orderingBatchProcessor struct {
+	*orderingProcessor
+}
+
+func (p orderingBatchProcessor) ProcessBatch(leads []*models.Lead, rejected []*processor.ProcessResult) []*processor.ProcessResult {
+	results := make([]*processor.ProcessResult, len(leads))
+	for i, lead := range leads {
+		results[i], _ = p.ProcessLead(lead)
+	}
+	return results
+}
+
+func sliceSource(leads ...*models.Lead) Source {
+	return func(emit func(*models.Lead) error) error {
+		for _, lead := range leads {
+			if err := emit(lead); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func collect(results <-chan *Item) []*Item {
+	var items []*Item
+	for item := range results {
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestPipeline_Run(t *testing.T) {
+	t.Run("processes valid leads and short-circuits invalid ones", func(t *testing.T) {
+		// Arrange
+		proc := &countingProcessor{}
+		p := New(proc, Config{QueueSize: 1})
+		source := sliceSource(
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("", "invalid-email", "", "Unknown"),
+		)
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.NoError(t, p.Err())
+		assert.Len(t, items, 2)
+		assert.Equal(t, "CREATE", items[0].Result.Action)
+		assert.Equal(t, 1, items[0].Index)
+		assert.Equal(t, "VALIDATION_ERROR", items[1].Result.Action)
+		assert.Equal(t, int64(1), proc.calls)
+	})
+
+	t.Run("applies transforms before validation", func(t *testing.T) {
+		// Arrange
+		p := New(&countingProcessor{}, Config{
+			Transforms: []Transform{func(lead *models.Lead) *models.Lead {
+				lead.Email = strings.TrimSpace(lead.Email)
+				return lead
+			}},
+		})
+		source := sliceSource(models.NewLead("John Doe", "  john@example.com ", "Test Corp", "LinkedIn"))
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Len(t, items, 1)
+		assert.Equal(t, "CREATE", items[0].Result.Action)
+		assert.Equal(t, "john@example.com", items[0].Lead.Email)
+	})
+
+	t.Run("sanitizes text so differently encoded names compare equal", func(t *testing.T) {
+		// Arrange
+		p := New(&countingProcessor{}, Config{Transforms: []Transform{models.Sanitize}})
+		source := sliceSource(models.NewLead(" Jose\u0301\tMari\u0301a\x00 ", "jose@example.com", "Acme\r\n", "LinkedIn"))
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Len(t, items, 1)
+		assert.Equal(t, "CREATE", items[0].Result.Action)
+		assert.Equal(t, "Jos\u00e9 Mar\u00eda", items[0].Lead.Name)
+		assert.Equal(t, "Acme", items[0].Lead.Company)
+	})
+
+	t.Run("passes leads the filter leaves out straight to the results", func(t *testing.T) {
+		// Arrange
+		proc := &countingProcessor{}
+		p := New(proc, Config{Filter: func(lead *models.Lead) bool { return lead.Source == "Webinar" }})
+		source := sliceSource(
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Jane Doe", "jane@example.com", "Test Corp", "Webinar"),
+		)
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Len(t, items, 2)
+		assert.Equal(t, int64(1), proc.calls)
+		for _, item := range items {
+			if item.Index == 1 {
+				assert.True(t, item.Filtered)
+				assert.Nil(t, item.Result)
+			} else {
+				assert.False(t, item.Filtered)
+				assert.Equal(t, "CREATE", item.Result.Action)
+			}
+		}
+	})
+
+	t.Run("skips leads at the start but keeps their indexes", func(t *testing.T) {
+		// Arrange
+		proc := &countingProcessor{}
+		p := New(proc, Config{Skip: 2})
+		source := sliceSource(
+			models.NewLead("Lead One", "one@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Lead Two", "two@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Lead Three", "three@example.com", "Test Corp", "LinkedIn"),
+		)
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Len(t, items, 1)
+		assert.Equal(t, 3, items[0].Index)
+		assert.Equal(t, "three@example.com", items[0].Lead.Email)
+		assert.Equal(t, int64(1), proc.calls)
+	})
+
+	t.Run("bounds queue depth and fans out to workers", func(t *testing.T) {
+		// Arrange
+		proc := &countingProcessor{}
+		p := New(proc, Config{QueueSize: 2, Workers: 4})
+		var leads []*models.Lead
+		for i := 0; i < 50; i++ {
+			leads = append(leads, models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+		}
+
+		// Act
+		items := collect(p.Run(context.Background(), sliceSource(leads...)))
+
+		// Assert
+		assert.Len(t, items, 50)
+		assert.Equal(t, int64(50), proc.calls)
+		for stage, depth := range p.PeakDepths() {
+			assert.LessOrEqual(t, depth, 2, "stage %s exceeded its queue size", stage)
+		}
+	})
+
+	t.Run("processes leads with one email one at a time, in order", func(t *testing.T) {
+		// Arrange
+		proc := &orderingProcessor{inFlight: map[string]bool{}, names: map[string][]string{}}
+		p := New(proc, Config{Workers: 8})
+		var leads []*models.Lead
+		for i := 1; i <= 40; i++ {
+			leads = append(leads, models.NewLead(fmt.Sprintf("Lead %d", i), fmt.Sprintf("lead%d@example.com", i%3), "Test Corp", "LinkedIn"))
+		}
+
+		// Act
+		items := collect(p.Run(context.Background(), sliceSource(leads...)))
+
+		// Assert
+		assert.Len(t, items, 40)
+		assert.False(t, proc.overlap)
+		assert.Equal(t, []string{"Lead 3", "Lead 6", "Lead 9", "Lead 12"}, proc.names["lead0@example.com"][:4])
+		assert.Len(t, proc.names["lead0@example.com"], 13)
+	})
+
+	t.Run("keeps batches sharing an email in order across workers", func(t *testing.T) {
+		// Arrange
+		proc := &orderingProcessor{inFlight: map[string]bool{}, names: map[string][]string{}}
+		p := New(orderingBatchProcessor{proc}, Config{Workers: 4, BatchSize: 3})
+		var leads []*models.Lead
+		for i := 1; i <= 30; i++ {
+			leads = append(leads, models.NewLead(fmt.Sprintf("Lead %d", i), fmt.Sprintf("lead%d@example.com", i%4), "Test Corp", "LinkedIn"))
+		}
+
+		// Act
+		items := collect(p.Run(context.Background(), sliceSource(leads...)))
+
+		// Assert
+		assert.Len(t, items, 30)
+		assert.False(t, proc.overlap)
+		assert.Equal(t, []string{"Lead 4", "Lead 8", "Lead 12", "Lead 16", "Lead 20", "Lead 24", "Lead 28"}, proc.names["lead0@example.com"])
+	})
+
+	t.Run("hands leads to a batch processor in batches", func(t *testing.T) {
+		// Arrange
+		proc := &batchingProcessor{}
+		p := New(proc, Config{BatchSize: 2})
+		source := sliceSource(
+			models.NewLead("Lead One", "one@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("", "invalid-email", "", "Unknown"),
+			models.NewLead("Lead Three", "three@example.com", "Test Corp", "LinkedIn"),
+		)
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Len(t, items, 3)
+		assert.Len(t, proc.batches, 2)
+		assert.Len(t, proc.batches[0], 2)
+		assert.Nil(t, proc.batches[0][0])
+		assert.Equal(t, "VALIDATION_ERROR", proc.batches[0][1].Action)
+		assert.Len(t, proc.batches[1], 1)
+		assert.Equal(t, "CREATE", items[2].Result.Action)
+		assert.Zero(t, proc.calls)
+	})
+
+	t.Run("reports each stage a lead finishes to the observer", func(t *testing.T) {
+		// Arrange
+		var stages []string
+		p := New(&countingProcessor{}, Config{
+			Observe: func(stage string, item *Item, started time.Time) {
+				stages = append(stages, fmt.Sprintf("%s %d", stage, item.Index))
+				assert.False(t, started.Before(item.ReadAt))
+			},
+		})
+		source := sliceSource(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+
+		// Act
+		collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Equal(t, []string{"read 1", "transform 1", "validate 1", "process 1"}, stages)
+	})
+
+	t.Run("reports source errors after draining", func(t *testing.T) {
+		// Arrange
+		p := New(&countingProcessor{}, Config{})
+		source := func(emit func(*models.Lead) error) error {
+			emit(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+			return assert.AnError
+		}
+
+		// Act
+		items := collect(p.Run(context.Background(), source))
+
+		// Assert
+		assert.Len(t, items, 1)
+		assert.Equal(t, assert.AnError, p.Err())
+	})
+}
+
+func BenchmarkPipeline_Run(b *testing.B) {
+	var leads []*models.Lead
+	for i := 0; i < 1000; i++ {
+		leads = append(leads, models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+	}
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := New(&countingProcessor{}, Config{Workers: workers})
+				for range p.Run(context.Background(), sliceSource(leads...)) {
+				}
+			}
+		})
+	}
+}
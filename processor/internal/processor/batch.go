@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"fmt"
+)
+
+// DeleteClient is implemented by API clients that can delete leads
+type DeleteClient interface {
+	DeleteLead(id string) error
+}
+
+// AtomicBatchProcessor applies batches of leads all or nothing. Leads are
+// validated up front; if any then fails on write, the leads of the batch
+// already applied are compensated in reverse order: created leads are
+// archived or deleted, as the deletion mode says, and updated ones restored
+// from their pre-images.
+type AtomicBatchProcessor struct {
+	next    Processor
+	leads   *LeadProcessor
+	remover *Remover
+}
+
+// NewAtomicBatchProcessor wraps next, which must end in leads, so batches
+// are atomic. The API client of leads has to support removing leads in the
+// processor's deletion mode, given the server's features.
+func NewAtomicBatchProcessor(next Processor, leads *LeadProcessor) (*AtomicBatchProcessor, error) {
+	remover, err := NewRemover(leads.apiClient, leads.deletionMode, leads.features)
+	if err != nil {
+		return nil, fmt.Errorf("%w, which atomic batches need for rollback", err)
+	}
+	return &AtomicBatchProcessor{next: next, leads: leads, remover: remover}, nil
+}
+
+// ProcessLead processes a single lead as a batch of one
+func (b *AtomicBatchProcessor) ProcessLead(lead *models.Lead) (*ProcessResult, error) {
+	return b.ProcessBatch([]*models.Lead{lead}, nil)[0], nil
+}
+
+// ProcessBatch processes leads as one batch, returning one result per lead.
+// A non-nil entry in rejected is a lead an earlier stage already failed, such
+// as one that did not validate, and fails the batch like any other. Leads of a
+// failed batch that made no change end up ROLLED_BACK, and ones whose change
+// could not be undone ROLLBACK_FAILED.
+func (b *AtomicBatchProcessor) ProcessBatch(leads []*models.Lead, rejected []*ProcessResult) []*ProcessResult {
+	results := make([]*ProcessResult, len(leads))
+	copy(results, rejected)
+
+	// An invalid lead fails the batch before anything is written
+	failed := -1
+	kept := make([]bool, len(leads))
+	for i, lead := range leads {
+		if results[i] == nil {
+			results[i] = b.leads.Check(lead)
+		}
+		if results[i] != nil {
+			kept[i] = true
+			if failed < 0 {
+				failed = i
+			}
+		}
+	}
+
+	if failed < 0 {
+		for i, lead := range leads {
+			result, err := b.next.ProcessLead(lead)
+			if err != nil {
+				result = &ProcessResult{Action: "API_ERROR", Lead: lead, Error: err}
+			}
+			results[i] = result
+			if !applied(result) {
+				failed = i
+				break
+			}
+		}
+	}
+	if failed < 0 {
+		return results
+	}
+
+	cause := leads[failed].Email
+	for i := len(leads) - 1; i >= 0; i-- {
+		switch {
+		case i == failed || kept[i]:
+		case results[i] == nil:
+			results[i] = rolledBack(leads[i], cause)
+		case results[i].Action == "SKIP" || results[i].Action == "REVIEW":
+		default:
+			results[i] = b.compensate(results[i], cause)
+		}
+	}
+	return results
+}
+
+// applied reports whether the lead reached the state the import wants
+func applied(result *ProcessResult) bool {
+	switch result.Action {
+	case "CREATE", "UPDATE", "SKIP", "REVIEW":
+		return true
+	}
+	return false
+}
+
+// compensate undoes an applied CREATE or UPDATE
+func (b *AtomicBatchProcessor) compensate(result *ProcessResult, cause string) *ProcessResult {
+	var err error
+	switch result.Action {
+	case "CREATE":
+		err = b.removeCreated(result.CreatedLead)
+	case "UPDATE":
+		err = b.restore(result.PreviousLead)
+	}
+	if err != nil {
+		return &ProcessResult{
+			Action: "ROLLBACK_FAILED",
+			Lead:   result.Lead,
+			Error:  fmt.Errorf("failed to roll back %s after %s failed: %w", result.Action, cause, err),
+		}
+	}
+	return rolledBack(result.Lead, cause)
+}
+
+func (b *AtomicBatchProcessor) removeCreated(created *models.Lead) error {
+	if created == nil || created.ID == "" {
+		return fmt.Errorf("the API did not return the created lead's ID")
+	}
+	finish, err := b.leads.writeAhead(b.remover.Action(), created)
+	if err != nil {
+		return err
+	}
+	err = b.remover.Remove(created.ID)
+	finish(err)
+	return err
+}
+
+func (b *AtomicBatchProcessor) restore(previous *models.Lead) error {
+	if previous == nil {
+		return fmt.Errorf("no pre-image of the updated lead")
+	}
+	finish, err := b.leads.writeAhead("UPDATE", previous)
+	if err != nil {
+		return err
+	}
+	_, err = b.leads.apiClient.UpdateLead(previous)
+	finish(err)
+	return err
+}
+
+func rolledBack(lead *models.Lead, cause string) *ProcessResult {
+	return &ProcessResult{
+		Action: "ROLLED_BACK",
+		Lead:   lead,
+		Error:  errcode.Classify(errcode.ErrRolledBack, fmt.Errorf("%s in the same batch failed", cause)),
+	}
+}
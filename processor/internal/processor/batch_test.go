@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// storeAPIClient keeps leads by email and fails writes for chosen emails
+type storeAPIClient struct {
+	leads     map[string]*models.Lead
+	failWrite map[string]bool
+	deleteErr error
+	nextID    int
+}
+
+func newStoreAPIClient(leads ...*models.Lead) *storeAPIClient {
+	s := &storeAPIClient{leads: make(map[string]*models.Lead), failWrite: make(map[string]bool)}
+	for _, lead := range leads {
+		s.leads[lead.Email] = lead
+	}
+	return s
+}
+
+func (s *storeAPIClient) LookupLead(email string) (*LookupResponse, error) {
+	lead, found := s.leads[email]
+	return &LookupResponse{Found: found, Lead: lead}, nil
+}
+
+func (s *storeAPIClient) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	if s.failWrite[lead.Email] {
+		return nil, &errcode.StatusError{Status: 500}
+	}
+	s.nextID++
+	created := *lead
+	created.ID = fmt.Sprintf("lead-%d", s.nextID)
+	s.leads[lead.Email] = &created
+	return &created, nil
+}
+
+func (s *storeAPIClient) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	if s.failWrite[lead.Email] {
+		return nil, &errcode.StatusError{Status: 500}
+	}
+	updated := *lead
+	s.leads[lead.Email] = &updated
+	return &updated, nil
+}
+
+func (s *storeAPIClient) DeleteLead(id string) error {
+	if s.deleteErr != nil {
+		return s.deleteErr
+	}
+	for email, lead := range s.leads {
+		if lead.ID == id {
+			delete(s.leads, email)
+		}
+	}
+	return nil
+}
+
+func TestAtomicBatchProcessor_ProcessBatch(t *testing.T) {
+	t.Run("applies a batch whose leads all succeed", func(t *testing.T) {
+		// Arrange
+		api := newStoreAPIClient()
+		leads := NewLeadProcessor(api)
+		leads.SetDeletionMode(DeletionDelete)
+		batch, _ := NewAtomicBatchProcessor(leads, leads)
+
+		// Act
+		results := batch.ProcessBatch([]*models.Lead{
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Jane Roe", "jane@example.com", "Test Corp", "LinkedIn"),
+		}, nil)
+
+		// Assert
+		assert.Equal(t, "CREATE", results[0].Action)
+		assert.Equal(t, "CREATE", results[1].Action)
+		assert.Len(t, api.leads, 2)
+	})
+
+	t.Run("rolls back creates and updates when a later lead fails", func(t *testing.T) {
+		// Arrange
+		existing := models.NewLead("Jane Roe", "jane@example.com", "Old Corp", "LinkedIn")
+		existing.ID = "jane"
+		api := newStoreAPIClient(existing)
+		api.failWrite["jim@example.com"] = true
+		leads := NewLeadProcessor(api)
+		leads.SetDeletionMode(DeletionDelete)
+		batch, _ := NewAtomicBatchProcessor(leads, leads)
+
+		// Act
+		results := batch.ProcessBatch([]*models.Lead{
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Jane Roe", "jane@example.com", "New Corp", "LinkedIn"),
+			models.NewLead("Jim Poe", "jim@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Joe Bloggs", "joe@example.com", "Test Corp", "LinkedIn"),
+		}, nil)
+
+		// Assert
+		assert.Equal(t, "ROLLED_BACK", results[0].Action)
+		assert.Equal(t, "ROLLED_BACK", results[1].Action)
+		assert.Equal(t, "CREATE_ERROR", results[2].Action)
+		assert.Equal(t, "ROLLED_BACK", results[3].Action)
+		assert.Equal(t, errcode.CodeRolledBack, errcode.Of(results[3].Error))
+		assert.Contains(t, results[0].Error.Error(), "jim@example.com")
+		assert.Len(t, api.leads, 1)
+		assert.Equal(t, "Old Corp", api.leads["jane@example.com"].Company)
+	})
+
+	t.Run("writes nothing when a lead was rejected up front", func(t *testing.T) {
+		// Arrange
+		api := newStoreAPIClient()
+		leads := NewLeadProcessor(api)
+		leads.SetDeletionMode(DeletionDelete)
+		batch, _ := NewAtomicBatchProcessor(leads, leads)
+		rejected := &ProcessResult{Action: "VALIDATION_ERROR", Error: assert.AnError}
+
+		// Act
+		results := batch.ProcessBatch([]*models.Lead{
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("", "invalid-email", "", "Unknown"),
+		}, []*ProcessResult{nil, rejected})
+
+		// Assert
+		assert.Equal(t, "ROLLED_BACK", results[0].Action)
+		assert.Equal(t, rejected, results[1])
+		assert.Empty(t, api.leads)
+	})
+
+	t.Run("reports leads whose change could not be undone", func(t *testing.T) {
+		// Arrange
+		api := newStoreAPIClient()
+		api.failWrite["jane@example.com"] = true
+		api.deleteErr = assert.AnError
+		leads := NewLeadProcessor(api)
+		leads.SetDeletionMode(DeletionDelete)
+		batch, _ := NewAtomicBatchProcessor(leads, leads)
+
+		// Act
+		results := batch.ProcessBatch([]*models.Lead{
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Jane Roe", "jane@example.com", "Test Corp", "LinkedIn"),
+		}, nil)
+
+		// Assert
+		assert.Equal(t, "ROLLBACK_FAILED", results[0].Action)
+		assert.ErrorIs(t, results[0].Error, assert.AnError)
+		assert.Equal(t, "CREATE_ERROR", results[1].Action)
+	})
+
+	t.Run("needs a client that can delete", func(t *testing.T) {
+		// Arrange
+		leads := NewLeadProcessor(&MockAPIClient{})
+		leads.SetDeletionMode(DeletionDelete)
+
+		// Act
+		_, err := NewAtomicBatchProcessor(leads, leads)
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("archives rolled-back creates by default", func(t *testing.T) {
+		// Arrange
+		api := &archivingAPIClient{storeAPIClient: newStoreAPIClient()}
+		api.failWrite["jane@example.com"] = true
+		leads := NewLeadProcessor(api)
+		leads.SetFeatures(ServerFeatures{Archive: true})
+		batch, _ := NewAtomicBatchProcessor(leads, leads)
+
+		// Act
+		results := batch.ProcessBatch([]*models.Lead{
+			models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+			models.NewLead("Jane Roe", "jane@example.com", "Test Corp", "LinkedIn"),
+		}, nil)
+
+		// Assert
+		assert.Equal(t, "ROLLED_BACK", results[0].Action)
+		assert.Equal(t, []string{"lead-1"}, api.archived)
+		assert.Len(t, api.leads, 1)
+	})
+
+	t.Run("refuses to archive when the server can't", func(t *testing.T) {
+		// Arrange
+		leads := NewLeadProcessor(&archivingAPIClient{storeAPIClient: newStoreAPIClient()})
+
+		// Act
+		_, err := NewAtomicBatchProcessor(leads, leads)
+
+		// Assert
+		assert.ErrorContains(t, err, "the server cannot archive leads")
+	})
+}
+
+// archivingAPIClient also archives leads, remembering which
+type archivingAPIClient struct {
+	*storeAPIClient
+	archived []string
+}
+
+func (a *archivingAPIClient) ArchiveLead(id string) error {
+	a.archived = append(a.archived, id)
+	return nil
+}
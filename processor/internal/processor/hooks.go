@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"code/internal/models"
+	"errors"
+	"fmt"
+)
+
+// ErrVetoed is wrapped by the errors hooks return to veto a write. The lead
+// is then skipped rather than failed, with the error as the reason.
+var ErrVetoed = errors.New("vetoed")
+
+// Veto returns an error vetoing a write for the reason given
+func Veto(reason string) error {
+	return fmt.Errorf("%w: %s", ErrVetoed, reason)
+}
+
+// PreCreateHook sees each lead just before it is created, e.g. to apply a
+// last business rule. It may change outgoing, which is a copy of the input
+// lead, or return an error to stop the create. Hooks are called concurrently
+// with several workers.
+type PreCreateHook interface {
+	PreCreate(outgoing *models.Lead) error
+}
+
+// PreUpdateHook sees each lead just before it is sent as the update of
+// existing, the CRM's copy, which it must not change. Like PreCreateHook, it
+// may change outgoing or return an error to stop the update.
+type PreUpdateHook interface {
+	PreUpdate(outgoing, existing *models.Lead) error
+}
+
+// PreCreateFunc lets a function be a PreCreateHook
+type PreCreateFunc func(outgoing *models.Lead) error
+
+// PreCreate calls f
+func (f PreCreateFunc) PreCreate(outgoing *models.Lead) error {
+	return f(outgoing)
+}
+
+// PreUpdateFunc lets a function be a PreUpdateHook
+type PreUpdateFunc func(outgoing, existing *models.Lead) error
+
+// PreUpdate calls f
+func (f PreUpdateFunc) PreUpdate(outgoing, existing *models.Lead) error {
+	return f(outgoing, existing)
+}
+
+// AddPreCreateHook runs hook before every create, after the hooks added
+// before it
+func (p *LeadProcessor) AddPreCreateHook(hook PreCreateHook) {
+	p.preCreate = append(p.preCreate, hook)
+}
+
+// AddPreUpdateHook runs hook before every update, after the hooks added
+// before it
+func (p *LeadProcessor) AddPreUpdateHook(hook PreUpdateHook) {
+	p.preUpdate = append(p.preUpdate, hook)
+}
+
+// runPreCreate returns the lead to create after the hooks, or the result of
+// a hook's veto or failure
+func (p *LeadProcessor) runPreCreate(lead, outgoing *models.Lead) (*models.Lead, *ProcessResult) {
+	if len(p.preCreate) == 0 {
+		return outgoing, nil
+	}
+	hooked := *outgoing
+	for _, hook := range p.preCreate {
+		if err := hook.PreCreate(&hooked); err != nil {
+			return nil, hookStopped(lead, "CREATE_ERROR", err)
+		}
+	}
+	return &hooked, nil
+}
+
+// runPreUpdate returns the lead to send as the update after the hooks, or
+// the result of a hook's veto or failure. An update the hooks leave equal to
+// the CRM's copy is skipped.
+func (p *LeadProcessor) runPreUpdate(lead, outgoing, existingLead *models.Lead) (*models.Lead, *ProcessResult) {
+	if len(p.preUpdate) == 0 {
+		return outgoing, nil
+	}
+	hooked := *outgoing
+	for _, hook := range p.preUpdate {
+		if err := hook.PreUpdate(&hooked, existingLead); err != nil {
+			return nil, hookStopped(lead, "UPDATE_ERROR", err)
+		}
+	}
+	if p.equality.Equal(&hooked, existingLead) {
+		return nil, &ProcessResult{
+			Action: "SKIP",
+			Lead:   lead,
+			Reason: "no changes left after pre-update hooks",
+		}
+	}
+	return &hooked, nil
+}
+
+// hookStopped reports a write a hook vetoed as a SKIP, and one it failed as
+// failedAction
+func hookStopped(lead *models.Lead, failedAction string, err error) *ProcessResult {
+	if errors.Is(err, ErrVetoed) {
+		return &ProcessResult{
+			Action: "SKIP",
+			Lead:   lead,
+			Reason: err.Error(),
+		}
+	}
+	return &ProcessResult{
+		Action: failedAction,
+		Lead:   lead,
+		Error:  fmt.Errorf("hook failed: %w", err),
+	}
+}
@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"code/internal/models"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeadProcessor_PreCreateHooks(t *testing.T) {
+	t.Run("sends the lead as the hooks changed it", func(t *testing.T) {
+		// Arrange
+		client := &recordingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: false}}}
+		processor := NewLeadProcessor(client)
+		processor.AddPreCreateHook(PreCreateFunc(func(outgoing *models.Lead) error {
+			outgoing.Company += " (test tenant)"
+			return nil
+		}))
+		processor.AddPreCreateHook(PreCreateFunc(func(outgoing *models.Lead) error {
+			outgoing.Company = strings.ToUpper(outgoing.Company)
+			return nil
+		}))
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")
+
+		// Act
+		result, _ := processor.ProcessLead(lead)
+
+		// Assert
+		assert.Equal(t, "CREATE", result.Action)
+		assert.Equal(t, "ACME (TEST TENANT)", client.created[0].Company)
+		assert.Equal(t, "Acme", lead.Company)
+	})
+
+	t.Run("skips vetoed leads and fails on other errors", func(t *testing.T) {
+		// Arrange
+		client := &recordingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: false}}}
+		processor := NewLeadProcessor(client)
+		processor.AddPreCreateHook(PreCreateFunc(func(outgoing *models.Lead) error {
+			switch outgoing.Source {
+			case "Twitter":
+				return Veto("no social leads for this tenant")
+			case "Referral":
+				return errors.New("referrer lookup unavailable")
+			}
+			return nil
+		}))
+
+		// Act
+		vetoed, _ := processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Twitter"))
+		failed, _ := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Globex", "Referral"))
+
+		// Assert
+		assert.Equal(t, "SKIP", vetoed.Action)
+		assert.Equal(t, "vetoed: no social leads for this tenant", vetoed.Reason)
+		assert.Equal(t, "CREATE_ERROR", failed.Action)
+		assert.ErrorContains(t, failed.Error, "hook failed: referrer lookup unavailable")
+		assert.Empty(t, client.created)
+	})
+
+	t.Run("releases the create budget of vetoed leads", func(t *testing.T) {
+		// Arrange
+		client := &recordingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: false}}}
+		processor := NewLeadProcessor(client)
+		processor.SetMaxCreates(1)
+		processor.AddPreCreateHook(PreCreateFunc(func(outgoing *models.Lead) error {
+			if outgoing.Source == "Twitter" {
+				return Veto("social")
+			}
+			return nil
+		}))
+
+		// Act
+		processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Twitter"))
+		created, _ := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Globex", "Website"))
+
+		// Assert
+		assert.Equal(t, "CREATE", created.Action)
+	})
+}
+
+func TestLeadProcessor_PreUpdateHooks(t *testing.T) {
+	existing := models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")
+	existing.Status = "qualified"
+
+	t.Run("sends the update as the hooks changed it", func(t *testing.T) {
+		// Arrange
+		client := &recordingUpdates{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existing}}}
+		processor := NewLeadProcessor(client)
+		processor.AddPreUpdateHook(PreUpdateFunc(func(outgoing, existing *models.Lead) error {
+			if outgoing.Company == "" {
+				outgoing.Company = existing.Company
+			}
+			outgoing.Company += " Ltd"
+			return nil
+		}))
+
+		// Act
+		result, _ := processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "LinkedIn"))
+
+		// Assert
+		assert.Equal(t, "UPDATE", result.Action)
+		assert.Equal(t, "Acme Ltd", client.updated[0].Company)
+		assert.Equal(t, "Acme", existing.Company)
+	})
+
+	t.Run("skips updates the hooks veto or undo", func(t *testing.T) {
+		// Arrange
+		client := &recordingUpdates{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existing}}}
+		processor := NewLeadProcessor(client)
+		processor.AddPreUpdateHook(PreUpdateFunc(func(outgoing, existing *models.Lead) error {
+			if existing.Status == "qualified" && outgoing.Source == "Twitter" {
+				return Veto("qualified leads keep their source")
+			}
+			outgoing.Source = existing.Source
+			return nil
+		}))
+
+		// Act
+		vetoed, _ := processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Twitter"))
+		undone, _ := processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "LinkedIn"))
+
+		// Assert
+		assert.Equal(t, "SKIP", vetoed.Action)
+		assert.Equal(t, "vetoed: qualified leads keep their source", vetoed.Reason)
+		assert.Equal(t, "SKIP", undone.Action)
+		assert.Equal(t, "no changes left after pre-update hooks", undone.Reason)
+		assert.Empty(t, client.updated)
+	})
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"strings"
+	"sync"
+)
+
+// EmailLocks keeps leads with the same email from being processed at the
+// same time, so two workers can't both look up a new email, find nothing and
+// both create it. Turns of one email are granted in the order they were
+// reserved: reserve them in file order and duplicates run in file order.
+type EmailLocks struct {
+	mu    sync.Mutex
+	tails map[string]*Turn
+}
+
+// Turn is a place in line for an email
+type Turn struct {
+	locks *EmailLocks
+	key   string
+	prev  *Turn
+	done  chan struct{}
+}
+
+// NewEmailLocks returns locks with no turns taken
+func NewEmailLocks() *EmailLocks {
+	return &EmailLocks{tails: make(map[string]*Turn)}
+}
+
+// Reserve takes the next turn for email. It doesn't block; Wait does.
+func (l *EmailLocks) Reserve(email string) *Turn {
+	return l.ReserveAll([]string{email})[0]
+}
+
+// ReserveAll takes the next turn for each distinct email, for work on all of
+// them at once, such as a batch of leads
+func (l *EmailLocks) ReserveAll(emails []string) []*Turn {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	turns := make([]*Turn, 0, len(emails))
+	reserved := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		key := strings.ToLower(strings.TrimSpace(email))
+		if reserved[key] {
+			continue
+		}
+		reserved[key] = true
+		turn := &Turn{locks: l, key: key, prev: l.tails[key], done: make(chan struct{})}
+		l.tails[key] = turn
+		turns = append(turns, turn)
+	}
+	return turns
+}
+
+// Wait blocks until every earlier turn for the email has been released
+func (t *Turn) Wait() {
+	if t.prev != nil {
+		<-t.prev.done
+		t.prev = nil
+	}
+}
+
+// Release ends the turn, letting the next one for the email go
+func (t *Turn) Release() {
+	close(t.done)
+
+	t.locks.mu.Lock()
+	defer t.locks.mu.Unlock()
+	if t.locks.tails[t.key] == t {
+		delete(t.locks.tails, t.key)
+	}
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailLocks(t *testing.T) {
+	t.Run("grants turns of one email in the order they were reserved", func(t *testing.T) {
+		// Arrange
+		locks := NewEmailLocks()
+		first := locks.Reserve("jane@example.com")
+		second := locks.Reserve("Jane@Example.com ")
+		other := locks.Reserve("john@example.com")
+		done := make(chan struct{})
+
+		// Act
+		go func() {
+			second.Wait()
+			close(done)
+		}()
+		other.Wait()
+		first.Wait()
+		var waitedForFirst bool
+		select {
+		case <-done:
+		case <-time.After(10 * time.Millisecond):
+			waitedForFirst = true
+		}
+		first.Release()
+		<-done
+		second.Release()
+		other.Release()
+
+		// Assert
+		assert.True(t, waitedForFirst)
+		assert.Empty(t, locks.tails)
+	})
+
+	t.Run("takes one turn per email for a batch", func(t *testing.T) {
+		// Arrange
+		locks := NewEmailLocks()
+
+		// Act
+		turns := locks.ReserveAll([]string{"jane@example.com", "john@example.com", "JANE@example.com"})
+		for _, turn := range turns {
+			turn.Wait()
+		}
+
+		// Assert
+		assert.Len(t, turns, 2)
+	})
+}
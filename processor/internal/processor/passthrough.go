@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"code/internal/models"
+	"fmt"
+)
+
+// featureDetector is implemented by API clients that can report server
+// capabilities
+type featureDetector interface {
+	DetectFeatures() (ServerFeatures, error)
+}
+
+// Passthrough forwards every call to the API client it wraps. Clients that
+// wrap another to change a few calls, such as answering lookups from a
+// mirror, embed it and override those calls; the optional features of the
+// wrapped client are passed through, failing when it doesn't have them.
+// Unwrap tells which features the wrapped client really has.
+type Passthrough struct {
+	Next APIClient
+}
+
+// LookupLead looks the lead up
+func (p Passthrough) LookupLead(email string) (*LookupResponse, error) {
+	return p.Next.LookupLead(email)
+}
+
+// CreateLead creates the lead
+func (p Passthrough) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	return p.Next.CreateLead(lead)
+}
+
+// UpdateLead updates the lead
+func (p Passthrough) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	return p.Next.UpdateLead(lead)
+}
+
+// PatchLead sends a partial update, when the wrapped client can
+func (p Passthrough) PatchLead(id string, changes map[string]string) (*models.Lead, error) {
+	patcher, ok := p.Next.(PatchClient)
+	if !ok {
+		return nil, fmt.Errorf("the API client cannot send partial updates")
+	}
+	return patcher.PatchLead(id, changes)
+}
+
+// AddNote adds a note to the lead's activity, when the wrapped client can
+func (p Passthrough) AddNote(id string, text string) error {
+	noter, ok := p.Next.(NoteClient)
+	if !ok {
+		return fmt.Errorf("the API client cannot add notes to leads")
+	}
+	return noter.AddNote(id, text)
+}
+
+// AttachToLead attaches a file to the lead, when the wrapped client can
+func (p Passthrough) AttachToLead(id string, attachment *models.Attachment) error {
+	attacher, ok := p.Next.(AttachClient)
+	if !ok {
+		return fmt.Errorf("the API client cannot attach files to leads")
+	}
+	return attacher.AttachToLead(id, attachment)
+}
+
+// ArchiveLead archives the lead, when the wrapped client can
+func (p Passthrough) ArchiveLead(id string) error {
+	archiver, ok := p.Next.(ArchiveClient)
+	if !ok {
+		return fmt.Errorf("the API client cannot archive leads")
+	}
+	return archiver.ArchiveLead(id)
+}
+
+// DeleteLead deletes the lead, when the wrapped client can
+func (p Passthrough) DeleteLead(id string) error {
+	deleter, ok := p.Next.(DeleteClient)
+	if !ok {
+		return fmt.Errorf("the API client cannot delete leads")
+	}
+	return deleter.DeleteLead(id)
+}
+
+// DetectFeatures reports the wrapped client's features, if it can
+func (p Passthrough) DetectFeatures() (ServerFeatures, error) {
+	detector, ok := p.Next.(featureDetector)
+	if !ok {
+		return ServerFeatures{}, nil
+	}
+	return detector.DetectFeatures()
+}
+
+// Unwrap returns the wrapped client
+func (p Passthrough) Unwrap() APIClient {
+	return p.Next
+}
+
+// Unwrap returns the client at the bottom of a chain of wrapping clients,
+// such as ones embedding Passthrough. Wrapping clients have every optional
+// method, so whether a chain can, say, delete leads is asked of it.
+func Unwrap(client interface{}) interface{} {
+	for {
+		wrapper, ok := client.(interface{ Unwrap() APIClient })
+		if !ok {
+			return client
+		}
+		client = wrapper.Unwrap()
+	}
+}
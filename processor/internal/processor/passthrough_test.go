@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wrappingClient changes nothing about the client it wraps
+type wrappingClient struct {
+	Passthrough
+}
+
+// archiveOnlyClient can archive leads, but not delete them
+type archiveOnlyClient struct {
+	APIClient
+	archived []string
+}
+
+func (a *archiveOnlyClient) ArchiveLead(id string) error {
+	a.archived = append(a.archived, id)
+	return nil
+}
+
+func TestUnwrap(t *testing.T) {
+	t.Run("finds the client at the bottom of wrapping clients", func(t *testing.T) {
+		// Arrange
+		store := newStoreAPIClient()
+		client := wrappingClient{Passthrough{Next: wrappingClient{Passthrough{Next: store}}}}
+
+		// Act
+		bottom := Unwrap(client)
+
+		// Assert
+		assert.Same(t, store, bottom)
+		assert.Same(t, store, Unwrap(store))
+	})
+
+	t.Run("tells features a wrapping client only passes through", func(t *testing.T) {
+		// Arrange
+		client := wrappingClient{Passthrough{Next: &archiveOnlyClient{APIClient: newStoreAPIClient()}}}
+
+		// Act
+		_, deleteErr := NewRemover(client, DeletionDelete, ServerFeatures{})
+		archiver, archiveErr := NewRemover(client, DeletionArchive, ServerFeatures{Archive: true})
+
+		// Assert
+		assert.ErrorContains(t, deleteErr, "cannot delete leads")
+		assert.NoError(t, archiveErr)
+		assert.NoError(t, archiver.Remove("lead-1"))
+		assert.Equal(t, []string{"lead-1"}, client.Next.(*archiveOnlyClient).archived)
+	})
+}
@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"code/internal/models"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Policy names accepted by ParsePolicy
+const (
+	// PolicyUpsert creates new leads and updates changed ones
+	PolicyUpsert = "upsert"
+	// PolicyCreateOnly creates new leads and never updates existing ones
+	PolicyCreateOnly = "create-only"
+	// PolicyUpdateOnly updates existing leads and never creates any
+	PolicyUpdateOnly = "update-only"
+	// PolicyFields updates only the policy's fields of existing leads
+	PolicyFields = "fields"
+	// PolicyReview creates new leads but holds changes to existing ones for
+	// someone to review
+	PolicyReview = "review"
+)
+
+// Policies lists the policies ParsePolicy accepts
+func Policies() []string {
+	return []string{PolicyUpsert, PolicyCreateOnly, PolicyUpdateOnly, PolicyFields, PolicyReview}
+}
+
+// Decision is what a policy wants done with a looked-up lead: either a
+// result that finishes it without a write, such as a SKIP, or an action,
+// CREATE or UPDATE, and the lead to send
+type Decision struct {
+	Result   *ProcessResult
+	Action   string
+	Outgoing *models.Lead
+}
+
+// Policy decides whether a looked-up lead is created, updated or left
+// alone. existing is the CRM's copy, nil if it has none. standard applies the
+// usual rules to a lead against existing: create if new, skip if equal, keep
+// protected fields and refuse status downgrades.
+type Policy interface {
+	Decide(lead, existing *models.Lead, standard func(lead *models.Lead) Decision) Decision
+}
+
+// ParsePolicy returns the named policy; fields are the lead fields, by JSON
+// name, PolicyFields updates. An empty name means PolicyUpsert.
+func ParsePolicy(name string, fields []string) (Policy, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name != PolicyFields && len(fields) > 0 {
+		return nil, fmt.Errorf("fields only apply to the %s policy", PolicyFields)
+	}
+
+	switch name {
+	case "", PolicyUpsert:
+		return upsertPolicy{}, nil
+	case PolicyCreateOnly:
+		return createOnlyPolicy{}, nil
+	case PolicyUpdateOnly:
+		return updateOnlyPolicy{}, nil
+	case PolicyReview:
+		return reviewPolicy{}, nil
+	case PolicyFields:
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("the %s policy needs the fields it updates", PolicyFields)
+		}
+		policy := fieldsPolicy{}
+		for _, field := range fields {
+			field = strings.ToLower(strings.TrimSpace(field))
+			if !slices.Contains(models.ProtectableFields(), field) {
+				return nil, fmt.Errorf("unknown field %q (allowed: %s)", field, strings.Join(models.ProtectableFields(), ", "))
+			}
+			policy.fields = append(policy.fields, field)
+		}
+		return policy, nil
+	}
+	return nil, fmt.Errorf("unknown policy %q (allowed: %s)", name, strings.Join(Policies(), ", "))
+}
+
+type upsertPolicy struct{}
+
+func (upsertPolicy) Decide(lead, existing *models.Lead, standard func(*models.Lead) Decision) Decision {
+	return standard(lead)
+}
+
+type createOnlyPolicy struct{}
+
+func (createOnlyPolicy) Decide(lead, existing *models.Lead, standard func(*models.Lead) Decision) Decision {
+	decision := standard(lead)
+	if decision.Action == "UPDATE" {
+		return Decision{Result: &ProcessResult{Action: "SKIP", Lead: lead, Reason: "the create-only policy never updates leads"}}
+	}
+	return decision
+}
+
+type updateOnlyPolicy struct{}
+
+func (updateOnlyPolicy) Decide(lead, existing *models.Lead, standard func(*models.Lead) Decision) Decision {
+	if existing == nil {
+		return Decision{Result: &ProcessResult{Action: "SKIP", Lead: lead, Reason: "the update-only policy never creates leads"}}
+	}
+	return standard(lead)
+}
+
+// fieldsPolicy applies the lead's values of its fields to the existing
+// lead, leaving every other field as the CRM has it
+type fieldsPolicy struct {
+	fields []string
+}
+
+func (f fieldsPolicy) Decide(lead, existing *models.Lead, standard func(*models.Lead) Decision) Decision {
+	if existing == nil {
+		return standard(lead)
+	}
+
+	restricted := *existing
+	for _, field := range f.fields {
+		value, _ := lead.Field(field)
+		restricted.SetField(field, value)
+	}
+	decision := standard(&restricted)
+	if decision.Result != nil {
+		decision.Result.Lead = lead
+		if decision.Result.Action == "SKIP" && decision.Result.Reason == "" && !lead.IsEqual(existing) {
+			decision.Result.Reason = "only fields outside the policy differ"
+		}
+	}
+	return decision
+}
+
+// reviewPolicy turns updates into REVIEW results naming the fields that
+// would change
+type reviewPolicy struct{}
+
+func (reviewPolicy) Decide(lead, existing *models.Lead, standard func(*models.Lead) Decision) Decision {
+	decision := standard(lead)
+	if decision.Action != "UPDATE" {
+		return decision
+	}
+
+	changes := decision.Outgoing.Diff(existing)
+	changed := make([]string, 0, len(changes))
+	for field := range changes {
+		changed = append(changed, field)
+	}
+	slices.Sort(changed)
+	return Decision{Result: &ProcessResult{
+		Action:       "REVIEW",
+		Lead:         lead,
+		PreviousLead: existing,
+		Reason:       "would change " + strings.Join(changed, ", "),
+	}}
+}
@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeadProcessor_Policy(t *testing.T) {
+	process := func(policy Policy, found bool, lead *models.Lead) (*ProcessResult, *recordingUpdates) {
+		existing := models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")
+		existing.Status = models.StatusContacted
+		client := &recordingUpdates{MockAPIClient: MockAPIClient{
+			lookupResponse: &LookupResponse{Found: found, Lead: existing},
+			createResponse: lead,
+			updateResponse: lead,
+		}}
+		processor := NewLeadProcessor(client)
+		processor.SetPolicy(policy)
+		result, _ := processor.ProcessLead(lead)
+		return result, client
+	}
+	changed := func() *models.Lead {
+		lead := models.NewLead("John Smith", "john@example.com", "New Corp", "LinkedIn")
+		lead.Status = models.StatusQualified
+		return lead
+	}
+
+	t.Run("creates and updates with the upsert policy", func(t *testing.T) {
+		// Arrange
+		policy, err := ParsePolicy("", nil)
+
+		// Act
+		created, _ := process(policy, false, changed())
+		updated, _ := process(policy, true, changed())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", created.Action)
+		assert.Equal(t, "UPDATE", updated.Action)
+	})
+
+	t.Run("never updates with the create-only policy", func(t *testing.T) {
+		// Arrange
+		policy, _ := ParsePolicy(PolicyCreateOnly, nil)
+
+		// Act
+		created, _ := process(policy, false, changed())
+		skipped, client := process(policy, true, changed())
+
+		// Assert
+		assert.Equal(t, "CREATE", created.Action)
+		assert.Equal(t, "SKIP", skipped.Action)
+		assert.Contains(t, skipped.Reason, "create-only")
+		assert.Empty(t, client.updated)
+	})
+
+	t.Run("never creates with the update-only policy", func(t *testing.T) {
+		// Arrange
+		policy, _ := ParsePolicy(PolicyUpdateOnly, nil)
+
+		// Act
+		skipped, _ := process(policy, false, changed())
+		updated, _ := process(policy, true, changed())
+
+		// Assert
+		assert.Equal(t, "SKIP", skipped.Action)
+		assert.Contains(t, skipped.Reason, "update-only")
+		assert.Equal(t, "UPDATE", updated.Action)
+	})
+
+	t.Run("updates only the policy's fields", func(t *testing.T) {
+		// Arrange
+		policy, err := ParsePolicy(PolicyFields, []string{"Company"})
+		sameCompany := changed()
+		sameCompany.Company = "Old Corp"
+
+		// Act
+		updated, client := process(policy, true, changed())
+		skipped, _ := process(policy, true, sameCompany)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", updated.Action)
+		assert.Equal(t, "New Corp", client.updated[0].Company)
+		assert.Equal(t, "John Doe", client.updated[0].Name)
+		assert.Equal(t, models.StatusContacted, client.updated[0].Status)
+		assert.Equal(t, "SKIP", skipped.Action)
+		assert.Equal(t, "only fields outside the policy differ", skipped.Reason)
+	})
+
+	t.Run("holds changes for review", func(t *testing.T) {
+		// Arrange
+		policy, _ := ParsePolicy(PolicyReview, nil)
+
+		// Act
+		created, _ := process(policy, false, changed())
+		held, client := process(policy, true, changed())
+
+		// Assert
+		assert.Equal(t, "CREATE", created.Action)
+		assert.Equal(t, "REVIEW", held.Action)
+		assert.Equal(t, "would change company, name, status", held.Reason)
+		assert.NotNil(t, held.PreviousLead)
+		assert.Empty(t, client.updated)
+	})
+
+	t.Run("rejects unknown policies and fields", func(t *testing.T) {
+		// Act
+		_, unknown := ParsePolicy("merge", nil)
+		_, noFields := ParsePolicy(PolicyFields, nil)
+		_, badField := ParsePolicy(PolicyFields, []string{"email"})
+		_, strayFields := ParsePolicy(PolicyReview, []string{"company"})
+
+		// Assert
+		assert.ErrorContains(t, unknown, `unknown policy "merge"`)
+		assert.ErrorContains(t, noFields, "needs the fields")
+		assert.ErrorContains(t, badField, `unknown field "email"`)
+		assert.ErrorContains(t, strayFields, "only apply to the fields policy")
+	})
+}
+
+// recordingUpdates remembers the leads it was asked to update
+type recordingUpdates struct {
+	MockAPIClient
+	updated []*models.Lead
+}
+
+func (c *recordingUpdates) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	c.updated = append(c.updated, lead)
+	return lead, nil
+}
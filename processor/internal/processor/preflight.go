@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"code/internal/models"
+	"errors"
+	"math/rand/v2"
+)
+
+// ErrPreflightFailed means a sample of the file failed validation or
+// conflicted with the CRM too often for the full run to go ahead
+var ErrPreflightFailed = errors.New("pre-flight failed")
+
+// Plan runs the lead through the processor's stages up to the write and
+// returns what ProcessLead would do with it, without writing anything:
+// CREATE, UPDATE or SKIP, or the validation, conflict or API error it would
+// fail with
+func (p *LeadProcessor) Plan(lead *models.Lead) *ProcessResult {
+	stages := make([]Stage, len(p.stages))
+	for i, stage := range p.stages {
+		stages[i] = stage.Stage
+		if stage.name == StageWrite {
+			stages[i] = StageFunc(planStage)
+		}
+	}
+	return chain(stages, unfinished)(&Step{Lead: lead})
+}
+
+// planStage stands in for the write stage, reporting the decided action
+func planStage(step *Step, next Handler) *ProcessResult {
+	return &ProcessResult{
+		Action:       step.Action,
+		Lead:         step.Lead,
+		PreviousLead: step.Existing,
+	}
+}
+
+// PreflightReport counts what a sample of leads would do
+type PreflightReport struct {
+	Sampled int
+	Actions map[string]int
+	// Failures holds the sample's validation and status conflict results
+	Failures []*ProcessResult
+}
+
+// Rate returns the share of the sample that would end in action
+func (r PreflightReport) Rate(action string) float64 {
+	if r.Sampled == 0 {
+		return 0
+	}
+	return float64(r.Actions[action]) / float64(r.Sampled)
+}
+
+// Preflight plans every lead of the sample, stopping early only when the
+// run would be interrupted, e.g. by its API call budget
+func (p *LeadProcessor) Preflight(sample []*models.Lead) (PreflightReport, error) {
+	report := PreflightReport{Actions: make(map[string]int)}
+	for _, lead := range sample {
+		result := p.Plan(lead)
+		if result.Action == "DEFERRED" || result.Action == "PAUSED" || result.Action == "ABORTED" {
+			return report, result.Error
+		}
+		report.Sampled++
+		report.Actions[result.Action]++
+		if result.Action == "VALIDATION_ERROR" || result.Action == "STATUS_CONFLICT" {
+			report.Failures = append(report.Failures, result)
+		}
+	}
+	return report, nil
+}
+
+// Sample picks n leads from the stream uniformly at random, reading it once
+// and keeping no more than n leads in memory. Fewer leads than n are all
+// kept, in their order.
+func Sample(stream func(emit func(*models.Lead) error) error, n int, rng *rand.Rand) ([]*models.Lead, error) {
+	sample := make([]*models.Lead, 0, n)
+	seen := 0
+	err := stream(func(lead *models.Lead) error {
+		seen++
+		if len(sample) < n {
+			sample = append(sample, lead)
+		} else if i := rng.IntN(seen); i < n {
+			sample[i] = lead
+		}
+		return nil
+	})
+	return sample, err
+}
@@ -0,0 +1,112 @@
+package processor
+
+import (
+	"code/internal/models"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeRefusingClient fails the test on any create or update
+type writeRefusingClient struct {
+	MockAPIClient
+	t *testing.T
+}
+
+func (c *writeRefusingClient) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	c.t.Fatal("pre-flight created a lead")
+	return nil, nil
+}
+
+func (c *writeRefusingClient) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	c.t.Fatal("pre-flight updated a lead")
+	return nil, nil
+}
+
+func TestLeadProcessor_Preflight(t *testing.T) {
+	t.Run("plans the sample without writing", func(t *testing.T) {
+		// Arrange
+		existing := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		existing.Status = models.StatusQualified
+		client := &writeRefusingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existing}}, t: t}
+		processor := NewLeadProcessor(client)
+
+		downgrade := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		downgrade.Status = models.StatusNew
+		changed := models.NewLead("John Doe", "john@example.com", "New Corp", "LinkedIn")
+		changed.Status = models.StatusQualified
+		invalid := models.NewLead("No Email", "", "Test Corp", "LinkedIn")
+
+		// Act
+		report, err := processor.Preflight([]*models.Lead{downgrade, changed, invalid, invalid})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 4, report.Sampled)
+		assert.Equal(t, map[string]int{"STATUS_CONFLICT": 1, "UPDATE": 1, "VALIDATION_ERROR": 2}, report.Actions)
+		assert.Equal(t, 0.5, report.Rate("VALIDATION_ERROR"))
+		assert.Len(t, report.Failures, 3)
+	})
+
+	t.Run("plans a create for unknown leads", func(t *testing.T) {
+		// Arrange
+		client := &writeRefusingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: false}}, t: t}
+
+		// Act
+		result := NewLeadProcessor(client).Plan(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website"))
+
+		// Assert
+		assert.Equal(t, "CREATE", result.Action)
+	})
+
+	t.Run("stops when the run budget runs out", func(t *testing.T) {
+		// Arrange
+		client := &MockAPIClient{lookupError: fmt.Errorf("%w: 10 API calls", ErrBudgetExhausted)}
+
+		// Act
+		report, err := NewLeadProcessor(client).Preflight([]*models.Lead{models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")})
+
+		// Assert
+		assert.ErrorIs(t, err, ErrBudgetExhausted)
+		assert.Equal(t, 0, report.Sampled)
+	})
+}
+
+func TestSample(t *testing.T) {
+	stream := func(count int) func(emit func(*models.Lead) error) error {
+		return func(emit func(*models.Lead) error) error {
+			for i := 0; i < count; i++ {
+				if err := emit(models.NewLead("Lead", fmt.Sprintf("lead%d@example.com", i), "Acme", "Website")); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	t.Run("keeps n distinct leads of a longer stream", func(t *testing.T) {
+		// Act
+		sample, err := Sample(stream(1000), 20, rand.New(rand.NewPCG(1, 2)))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, sample, 20)
+		emails := make(map[string]bool)
+		for _, lead := range sample {
+			emails[lead.Email] = true
+		}
+		assert.Len(t, emails, 20)
+	})
+
+	t.Run("keeps every lead of a shorter stream in order", func(t *testing.T) {
+		// Act
+		sample, err := Sample(stream(3), 20, rand.New(rand.NewPCG(1, 2)))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, sample, 3)
+		assert.Equal(t, "lead0@example.com", sample[0].Email)
+	})
+}
@@ -1,12 +1,52 @@
 package processor
 
 import (
+	"code/internal/errcode"
 	"code/internal/models"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
 )
 
+// ErrBudgetExhausted means the run has used up its API call or create budget.
+// Leads that hit it are deferred to a later run rather than failed.
+var ErrBudgetExhausted = errors.New("run budget exhausted")
+
+// ErrRunPaused means the run window closed before the lead's API calls were
+// made. Leads that hit it are left for the run to resume.
+var ErrRunPaused = errors.New("run window closed")
+
+// ErrRetryBudgetExhausted means the run has retried so much that the API is
+// failing systemically. Leads that hit it are aborted along with the run.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
 // LeadProcessor handles the business logic for processing leads
 type LeadProcessor struct {
-	apiClient APIClient
+	apiClient       APIClient
+	features        ServerFeatures
+	protectedFields []string
+	equality        models.Equality
+	requireConsent  bool
+	validation      models.ValidationOptions
+	maxCreates      int64
+	creates         atomic.Int64
+	intents         IntentLog
+	tracer          Tracer
+	seen            SeenChecker
+	seenWindow      time.Duration
+	policy          Policy
+	stages          []namedStage
+	deletionMode    DeletionMode
+	respectOwners   bool
+	preCreate       []PreCreateHook
+	preUpdate       []PreUpdateHook
+}
+
+// namedStage is a stage with the name it was built from
+type namedStage struct {
+	name string
+	Stage
 }
 
 // APIClient interface for API operations
@@ -16,6 +56,56 @@ type APIClient interface {
 	UpdateLead(lead *models.Lead) (*models.Lead, error)
 }
 
+// PatchClient is implemented by API clients that can send partial updates
+type PatchClient interface {
+	PatchLead(id string, changes map[string]string) (*models.Lead, error)
+}
+
+// NoteClient is implemented by API clients that can add notes to a lead's
+// activity
+type NoteClient interface {
+	AddNote(id string, text string) error
+}
+
+// AttachClient is implemented by API clients that can attach files to leads
+type AttachClient interface {
+	AttachToLead(id string, attachment *models.Attachment) error
+}
+
+// IntentLog records creates and updates before they are sent, so a crash
+// mid-request leaves a trace the next run can reconcile
+type IntentLog interface {
+	// Begin durably records action on lead, returning the intent's ID
+	Begin(action string, lead *models.Lead) (string, error)
+	// Finish records the API's answer to the intent
+	Finish(id string, err error) error
+}
+
+// Tracer is told about each API call made for a lead, e.g. to log where
+// leads spend their time. It is called concurrently with several workers.
+type Tracer interface {
+	// Call reports a lookup, create or update of lead that started at
+	// started and failed with err, or succeeded if err is nil
+	Call(lead *models.Lead, call string, started time.Time, err error)
+}
+
+// Calls reported to a Tracer
+const (
+	CallLookup = "lookup"
+	CallCreate = "create"
+	CallUpdate = "update"
+)
+
+// ServerFeatures describes optional API features the processor may use.
+// The zero value matches a legacy server, so the processor falls back to
+// full updates unless a feature is explicitly enabled.
+type ServerFeatures struct {
+	Patch        bool
+	DomainLookup bool
+	// Archive means leads can be archived rather than deleted
+	Archive bool
+}
+
 // LookupResponse represents the response from lookup API
 type LookupResponse struct {
 	Found bool
@@ -28,78 +118,343 @@ type ProcessResult struct {
 	Lead        *models.Lead
 	CreatedLead *models.Lead
 	UpdatedLead *models.Lead
-	Error       error
+	// PreviousLead is the lead as it was before an UPDATE
+	PreviousLead *models.Lead
+	// Sent is the lead a CREATE or UPDATE sent, with its protected fields
+	// kept and the hooks' changes
+	Sent   *models.Lead
+	Error  error
+	Reason string
+	// OwnedLead is the CRM's copy of a lead skipped because a rep owns it
+	OwnedLead *models.Lead
+	// SyncError is set when a secondary sink failed after the lead was written
+	SyncError error
 }
 
 // NewLeadProcessor creates a new lead processor
 func NewLeadProcessor(apiClient APIClient) *LeadProcessor {
-	return &LeadProcessor{
-		apiClient: apiClient,
+	p := &LeadProcessor{
+		apiClient:    apiClient,
+		policy:       upsertPolicy{},
+		deletionMode: DeletionArchive,
 	}
+	p.SetStages(nil)
+	return p
 }
 
-// ProcessLead processes a single lead according to business rules
-func (p *LeadProcessor) ProcessLead(lead *models.Lead) (*ProcessResult, error) {
-	// Validate the lead first
-	if err := lead.Validate(); err != nil {
+// SetFeatures tells the processor which optional server features it may use
+func (p *LeadProcessor) SetFeatures(features ServerFeatures) {
+	p.features = features
+}
+
+// SetProtectedFields lists fields, by JSON name, that imports may set when
+// creating a lead but must never change on an existing one
+func (p *LeadProcessor) SetProtectedFields(fields []string) {
+	p.protectedFields = fields
+}
+
+// SetEquality changes how a lead is compared with the CRM's copy to decide
+// between SKIP and UPDATE
+func (p *LeadProcessor) SetEquality(equality models.Equality) {
+	p.equality = equality
+}
+
+// SetRequireConsent makes leads without marketing consent fail validation
+func (p *LeadProcessor) SetRequireConsent(require bool) {
+	p.requireConsent = require
+}
+
+// SetValidation adjusts how leads are validated, e.g. with a DNS-backed
+// email check or different field length limits
+func (p *LeadProcessor) SetValidation(opts models.ValidationOptions) {
+	p.validation = opts
+}
+
+// SetMaxCreates limits how many leads one run may create; zero means no limit.
+// Leads that would need a create beyond it are deferred.
+func (p *LeadProcessor) SetMaxCreates(max int) {
+	p.maxCreates = int64(max)
+}
+
+// SetIntentLog makes the processor write every create and update ahead to log
+func (p *LeadProcessor) SetIntentLog(log IntentLog) {
+	p.intents = log
+}
+
+// SetTracer makes the processor report every API call it makes to tracer
+func (p *LeadProcessor) SetTracer(tracer Tracer) {
+	p.tracer = tracer
+}
+
+// SetPolicy picks how the decide stage chooses between creating, updating
+// and leaving leads alone; nil keeps the upsert policy
+func (p *LeadProcessor) SetPolicy(policy Policy) {
+	if policy == nil {
+		policy = upsertPolicy{}
+	}
+	p.policy = policy
+}
+
+// SetDeletionMode picks how leads the processor takes back, such as the
+// creates of a rolled-back atomic batch, are removed; archive by default
+func (p *LeadProcessor) SetDeletionMode(mode DeletionMode) {
+	p.deletionMode = mode
+}
+
+// SetRespectOwnership makes the decide stage skip updates to leads the CRM
+// has assigned to an owner, as a rep is working them
+func (p *LeadProcessor) SetRespectOwnership(respect bool) {
+	p.respectOwners = respect
+}
+
+// SetSkipSeen makes the dedup stage skip leads whose exact content checker
+// saw processed within window
+func (p *LeadProcessor) SetSkipSeen(checker SeenChecker, window time.Duration) {
+	p.seen, p.seenWindow = checker, window
+}
+
+// trace reports a call to the tracer, if any
+func (p *LeadProcessor) trace(lead *models.Lead, call string, started time.Time, err error) {
+	if p.tracer != nil {
+		p.tracer.Call(lead, call, started, err)
+	}
+}
+
+// writeAhead records action on lead in the intent log, if any, before it is
+// sent. The returned func records the API's answer; losing that only costs a
+// needless reconcile, so its error is ignored.
+func (p *LeadProcessor) writeAhead(action string, lead *models.Lead) (func(error), error) {
+	if p.intents == nil {
+		return func(error) {}, nil
+	}
+
+	id, err := p.intents.Begin(action, lead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write intent log: %w", err)
+	}
+	return func(err error) { p.intents.Finish(id, err) }, nil
+}
+
+// reserveCreate claims one create from the budget, reporting false if none are left
+func (p *LeadProcessor) reserveCreate() bool {
+	if p.maxCreates <= 0 {
+		return true
+	}
+	if p.creates.Add(1) > p.maxCreates {
+		p.creates.Add(-1)
+		return false
+	}
+	return true
+}
+
+// releaseCreate returns a reserved create that was never made
+func (p *LeadProcessor) releaseCreate() {
+	if p.maxCreates > 0 {
+		p.creates.Add(-1)
+	}
+}
+
+// deferred reports a lead that was not processed because the budget ran out
+func deferred(lead *models.Lead, err error) *ProcessResult {
+	return &ProcessResult{
+		Action: "DEFERRED",
+		Lead:   lead,
+		Error:  err,
+	}
+}
+
+// interrupted reports a lead whose API call was refused by a run budget,
+// window or retry budget, or returns nil for any other outcome
+func interrupted(lead *models.Lead, err error) *ProcessResult {
+	switch {
+	case errors.Is(err, ErrBudgetExhausted):
+		return deferred(lead, err)
+	case errors.Is(err, ErrRunPaused):
+		return &ProcessResult{
+			Action: "PAUSED",
+			Lead:   lead,
+			Error:  err,
+		}
+	case errors.Is(err, ErrRetryBudgetExhausted):
+		return &ProcessResult{
+			Action: "ABORTED",
+			Lead:   lead,
+			Error:  err,
+		}
+	}
+	return nil
+}
+
+// Check validates the lead without calling the API, returning a
+// VALIDATION_ERROR result if it is invalid and nil otherwise
+func (p *LeadProcessor) Check(lead *models.Lead) *ProcessResult {
+	if err := lead.ValidateWith(p.validation); err != nil {
 		return &ProcessResult{
 			Action: "VALIDATION_ERROR",
 			Lead:   lead,
 			Error:  err,
-		}, nil
+		}
+	}
+	if p.requireConsent {
+		if err := lead.RequireConsent(); err != nil {
+			return &ProcessResult{
+				Action: "VALIDATION_ERROR",
+				Lead:   lead,
+				Error:  errcode.Classify(errcode.ErrValidation, err),
+			}
+		}
 	}
+	return nil
+}
 
-	// Look up existing lead by email
-	lookupResp, err := p.apiClient.LookupLead(lead.Email)
+// ProcessLead processes a single lead according to business rules, running
+// it through the processor's stages
+func (p *LeadProcessor) ProcessLead(lead *models.Lead) (*ProcessResult, error) {
+	stages := make([]Stage, len(p.stages))
+	for i, stage := range p.stages {
+		stages[i] = stage.Stage
+	}
+	return chain(stages, unfinished)(&Step{Lead: lead}), nil
+}
+
+// create sends outgoing, as decided for lead and changed by the pre-create
+// hooks, to the CRM as a new lead
+func (p *LeadProcessor) create(lead, outgoing *models.Lead) *ProcessResult {
+	outgoing, stopped := p.runPreCreate(lead, outgoing)
+	if stopped != nil {
+		return stopped
+	}
+	if !p.reserveCreate() {
+		return deferred(lead, fmt.Errorf("%w: create limit of %d reached", ErrBudgetExhausted, p.maxCreates))
+	}
+	finish, err := p.writeAhead("CREATE", outgoing)
 	if err != nil {
+		p.releaseCreate()
 		return &ProcessResult{
-			Action: "API_ERROR",
+			Action: "CREATE_ERROR",
 			Lead:   lead,
 			Error:  err,
-		}, nil
+		}
+	}
+	started := time.Now()
+	createdLead, err := p.apiClient.CreateLead(outgoing)
+	p.trace(lead, CallCreate, started, err)
+	finish(err)
+	if result := interrupted(lead, err); result != nil {
+		p.releaseCreate()
+		return result
+	}
+	if err != nil {
+		return &ProcessResult{
+			Action: "CREATE_ERROR",
+			Lead:   lead,
+			Error:  err,
+		}
 	}
 
-	// If lead not found, create new lead
-	if !lookupResp.Found {
-		createdLead, err := p.apiClient.CreateLead(lead)
-		if err != nil {
-			return &ProcessResult{
-				Action: "CREATE_ERROR",
-				Lead:   lead,
-				Error:  err,
-			}, nil
+	return &ProcessResult{
+		Action:      "CREATE",
+		Lead:        lead,
+		CreatedLead: createdLead,
+		Sent:        outgoing,
+	}
+}
+
+// update sends outgoing, the lead with its protected fields kept, as the
+// update of existingLead once the pre-update hooks have seen it
+func (p *LeadProcessor) update(lead, outgoing, existingLead *models.Lead) *ProcessResult {
+	outgoing, stopped := p.runPreUpdate(lead, outgoing, existingLead)
+	if stopped != nil {
+		return stopped
+	}
+	finish, err := p.writeAhead("UPDATE", outgoing)
+	if err != nil {
+		return &ProcessResult{
+			Action: "UPDATE_ERROR",
+			Lead:   lead,
+			Error:  err,
+		}
+	}
+	started := time.Now()
+	updatedLead, err := p.updateLead(outgoing, existingLead)
+	p.trace(lead, CallUpdate, started, err)
+	finish(err)
+	if result := interrupted(lead, err); result != nil {
+		return result
+	}
+	if err != nil {
+		return &ProcessResult{
+			Action: "UPDATE_ERROR",
+			Lead:   lead,
+			Error:  err,
 		}
+	}
 
+	return &ProcessResult{
+		Action:       "UPDATE",
+		Lead:         lead,
+		UpdatedLead:  updatedLead,
+		PreviousLead: existingLead,
+		Sent:         outgoing,
+	}
+}
+
+// compare decides what an existing lead needs: a SKIP or STATUS_CONFLICT
+// result, or nil and the lead to send as its update
+func (p *LeadProcessor) compare(lead, existingLead *models.Lead) (*ProcessResult, *models.Lead) {
+	if p.equality.Equal(lead, existingLead) {
+		// Data is identical, skip
 		return &ProcessResult{
-			Action:      "CREATE",
-			Lead:        lead,
-			CreatedLead: createdLead,
+			Action: "SKIP",
+			Lead:   lead,
 		}, nil
 	}
 
-	// Lead found - check if data differs
-	existingLead := lookupResp.Lead
-	if lead.IsEqual(existingLead) {
-		// Data is identical, skip
+	// Keep hand-maintained values on protected fields
+	outgoing := p.withProtectedFields(lead, existingLead)
+	if p.equality.Equal(outgoing, existingLead) {
 		return &ProcessResult{
 			Action: "SKIP",
 			Lead:   lead,
+			Reason: "only protected fields differ",
 		}, nil
 	}
 
-	// Data differs, update the lead
-	updatedLead, err := p.apiClient.UpdateLead(lead)
-	if err != nil {
+	// Data differs; refuse status changes the lifecycle doesn't allow
+	if !models.CanTransitionStatus(existingLead.Status, outgoing.Status) {
 		return &ProcessResult{
-			Action: "UPDATE_ERROR",
+			Action: "STATUS_CONFLICT",
 			Lead:   lead,
-			Error:  err,
+			Error:  errcode.Classify(errcode.ErrConflict, fmt.Errorf("cannot change status from %s to %s", existingLead.Status, outgoing.Status)),
 		}, nil
 	}
 
-	return &ProcessResult{
-		Action:      "UPDATE",
-		Lead:        lead,
-		UpdatedLead: updatedLead,
-	}, nil
+	return nil, outgoing
+}
+
+// updateLead sends only the changed fields when the server supports PATCH,
+// falling back to a full update otherwise
+func (p *LeadProcessor) updateLead(lead, existingLead *models.Lead) (*models.Lead, error) {
+	if patchClient, ok := p.apiClient.(PatchClient); ok && p.features.Patch && existingLead.ID != "" {
+		return patchClient.PatchLead(existingLead.ID, lead.Diff(existingLead))
+	}
+
+	return p.apiClient.UpdateLead(lead)
+}
+
+// withProtectedFields returns lead with every protected field reset to the
+// existing lead's value, leaving lead itself untouched
+func (p *LeadProcessor) withProtectedFields(lead, existingLead *models.Lead) *models.Lead {
+	if len(p.protectedFields) == 0 {
+		return lead
+	}
+
+	outgoing := *lead
+	for _, field := range p.protectedFields {
+		if value, ok := existingLead.Field(field); ok {
+			outgoing.SetField(field, value)
+		}
+	}
+
+	return &outgoing
 }
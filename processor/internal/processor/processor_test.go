@@ -1,7 +1,10 @@
 package processor
 
 import (
+	"code/internal/errcode"
 	"code/internal/models"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,6 +32,19 @@ func (m *MockAPIClient) UpdateLead(lead *models.Lead) (*models.Lead, error) {
 	return m.updateResponse, m.updateError
 }
 
+// MockPatchAPIClient additionally supports partial updates
+type MockPatchAPIClient struct {
+	MockAPIClient
+	patchedID      string
+	patchedChanges map[string]string
+}
+
+func (m *MockPatchAPIClient) PatchLead(id string, changes map[string]string) (*models.Lead, error) {
+	m.patchedID = id
+	m.patchedChanges = changes
+	return m.updateResponse, m.updateError
+}
+
 func TestLeadProcessor_ProcessLead(t *testing.T) {
 	t.Run("creates new lead when not found in API", func(t *testing.T) {
 		// Arrange
@@ -182,4 +198,638 @@ func TestLeadProcessor_ProcessLead(t *testing.T) {
 		assert.NotNil(t, result.Error)
 		assert.Equal(t, assert.AnError, result.Error)
 	})
+
+	t.Run("keeps the error class of API failures", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createError:    fmt.Errorf("create failed: %w", &errcode.StatusError{Status: 429}),
+		}
+
+		// Act
+		result, err := NewLeadProcessor(mockAPI).ProcessLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE_ERROR", result.Action)
+		assert.ErrorIs(t, result.Error, errcode.ErrRateLimited)
+		assert.Equal(t, errcode.CodeRateLimited, errcode.Of(result.Error))
+	})
+
+	t.Run("classes invalid leads as validation errors", func(t *testing.T) {
+		// Act
+		result, err := NewLeadProcessor(&MockAPIClient{}).ProcessLead(models.NewLead("", "invalid-email", "", "Unknown"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "VALIDATION_ERROR", result.Action)
+		assert.Equal(t, errcode.CodeValidation, errcode.Of(result.Error))
+	})
+}
+
+func TestLeadProcessor_ServerFeatures(t *testing.T) {
+	t.Run("patches changed fields when the server supports it", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Smith", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead.ID = "existing-id"
+
+		mockAPI := &MockPatchAPIClient{MockAPIClient: MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+			updateResponse: newLead,
+		}}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetFeatures(ServerFeatures{Patch: true})
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", result.Action)
+		assert.Equal(t, "existing-id", mockAPI.patchedID)
+		assert.Equal(t, map[string]string{"name": "John Smith"}, mockAPI.patchedChanges)
+	})
+
+	t.Run("falls back to full update on legacy servers", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Smith", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+
+		mockAPI := &MockPatchAPIClient{MockAPIClient: MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+			updateResponse: newLead,
+		}}
+
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", result.Action)
+		assert.Empty(t, mockAPI.patchedID)
+	})
+}
+
+func TestLeadProcessor_StatusTransitions(t *testing.T) {
+	t.Run("moves a lead forward through its lifecycle", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		newLead.Status = models.StatusQualified
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead.Status = models.StatusContacted
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+			updateResponse: newLead,
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", result.Action)
+	})
+
+	t.Run("refuses to downgrade a qualified lead", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		newLead.Status = models.StatusNew
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead.Status = models.StatusQualified
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "STATUS_CONFLICT", result.Action)
+		assert.Error(t, result.Error)
+		assert.Contains(t, result.Error.Error(), "qualified to new")
+		assert.ErrorIs(t, result.Error, errcode.ErrConflict)
+	})
+
+	t.Run("ignores status when the input does not set one", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		existingLead.Status = models.StatusDisqualified
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "SKIP", result.Action)
+	})
+}
+
+func TestLeadProcessor_ProtectedFields(t *testing.T) {
+	t.Run("keeps protected values when updating", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Smith", "john@example.com", "Test Corp", "Website")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+
+		mockAPI := &MockPatchAPIClient{MockAPIClient: MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+			updateResponse: newLead,
+		}}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetFeatures(ServerFeatures{Patch: true})
+		processor.SetProtectedFields([]string{"source"})
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", result.Action)
+		assert.Equal(t, map[string]string{"name": "John Smith"}, mockAPI.patchedChanges)
+		assert.Equal(t, "Website", newLead.Source)
+	})
+
+	t.Run("skips when only protected fields differ", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "Website")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetProtectedFields([]string{"source"})
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "SKIP", result.Action)
+		assert.Equal(t, "only protected fields differ", result.Reason)
+	})
+
+	t.Run("sets protected fields when creating", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "Website")
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createResponse: newLead,
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetProtectedFields([]string{"source"})
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", result.Action)
+		assert.Equal(t, "Website", result.CreatedLead.Source)
+	})
+}
+
+func TestLeadProcessor_SetEquality(t *testing.T) {
+	t.Run("skips leads that differ only in case or ignored fields", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("john doe", "john@example.com", "ACME Inc", "Website")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Acme Inc", "LinkedIn")
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+			updateResponse: newLead,
+		}
+		exact := NewLeadProcessor(mockAPI)
+		loose := NewLeadProcessor(mockAPI)
+		loose.SetEquality(models.Equality{Ignore: []string{"source"}, CaseInsensitive: true})
+
+		// Act
+		updated, _ := exact.ProcessLead(newLead)
+		skipped, _ := loose.ProcessLead(newLead)
+
+		// Assert
+		assert.Equal(t, "UPDATE", updated.Action)
+		assert.Equal(t, "SKIP", skipped.Action)
+	})
+
+	t.Run("compares a field with its comparator", func(t *testing.T) {
+		// Arrange
+		assert.NoError(t, models.RegisterComparator("test_digits", func(a, b string) bool {
+			digits := func(s string) string {
+				return strings.Map(func(r rune) rune {
+					if r < '0' || r > '9' {
+						return -1
+					}
+					return r
+				}, s)
+			}
+			return digits(a) == digits(b)
+		}))
+		equality := models.Equality{Comparators: map[string]string{"company": "test_digits", "name": models.CompareWhitespace}}
+		newLead := models.NewLead("John  Doe ", "john@example.com", "Studio 54", "Website")
+		existingLead := models.NewLead("John Doe", "john@example.com", "studio-54", "Website")
+		processor := NewLeadProcessor(&MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existingLead}})
+		processor.SetEquality(equality)
+
+		// Act
+		result, _ := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, equality.Validate())
+		assert.Equal(t, "SKIP", result.Action)
+	})
+
+	t.Run("skips leads that differ only in email case or spacing by default", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John  Doe", "John@Example.COM", "Acme Inc ", "Website")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Acme  Inc", "Website")
+		mockAPI := &MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existingLead}}
+		strict := NewLeadProcessor(mockAPI)
+		strict.SetEquality(models.Equality{Comparators: map[string]string{"email": models.CompareExact}})
+
+		// Act
+		skipped, _ := NewLeadProcessor(mockAPI).ProcessLead(newLead)
+		updated, _ := strict.ProcessLead(newLead)
+
+		// Assert
+		assert.Equal(t, "SKIP", skipped.Action)
+		assert.Equal(t, "UPDATE", updated.Action)
+	})
+
+	t.Run("updates leads assigned another territory", func(t *testing.T) {
+		// Arrange
+		existingLead := models.NewLead("John Doe", "john@example.com", "Acme Inc", "Website")
+		existingLead.Territory = "EMEA"
+		mockAPI := &MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existingLead}}
+		moved := models.NewLead("John Doe", "john@example.com", "Acme Inc", "Website")
+		moved.Territory = "Americas"
+		unassigned := models.NewLead("John Doe", "john@example.com", "Acme Inc", "Website")
+
+		// Act
+		updated, _ := NewLeadProcessor(mockAPI).ProcessLead(moved)
+		skipped, _ := NewLeadProcessor(mockAPI).ProcessLead(unassigned)
+
+		// Assert
+		assert.Equal(t, "UPDATE", updated.Action)
+		assert.Equal(t, "SKIP", skipped.Action)
+	})
+}
+
+func TestLeadProcessor_RespectOwnership(t *testing.T) {
+	t.Run("skips updates to owned leads", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "New Corp", "Website")
+		existingLead := models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")
+		existingLead.Owner = "maria.garcia"
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetRespectOwnership(true)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "SKIP", result.Action)
+		assert.Equal(t, "owned by maria.garcia", result.Reason)
+		assert.Same(t, existingLead, result.OwnedLead)
+	})
+
+	t.Run("updates unowned leads and owned ones unless asked not to", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "New Corp", "Website")
+		unowned := models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")
+		owned := models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")
+		owned.Owner = "maria.garcia"
+
+		respecting := NewLeadProcessor(&MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: unowned},
+			updateResponse: newLead,
+		})
+		respecting.SetRespectOwnership(true)
+		ignoring := NewLeadProcessor(&MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: owned},
+			updateResponse: newLead,
+		})
+
+		// Act
+		respected, _ := respecting.ProcessLead(newLead)
+		ignored, _ := ignoring.ProcessLead(newLead)
+
+		// Assert
+		assert.Equal(t, "UPDATE", respected.Action)
+		assert.Equal(t, "UPDATE", ignored.Action)
+	})
+}
+
+func TestLeadProcessor_RequireConsent(t *testing.T) {
+	t.Run("rejects leads without consent", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		mockAPI := &MockAPIClient{}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetRequireConsent(true)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "VALIDATION_ERROR", result.Action)
+		assert.ErrorIs(t, result.Error, models.ErrConsentRequired)
+	})
+
+	t.Run("creates leads that have given consent", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		newLead.ConsentGiven = true
+		newLead.ConsentSource = "webinar-registration"
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createResponse: newLead,
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetRequireConsent(true)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", result.Action)
+	})
+
+	t.Run("updates existing leads when consent changes", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		newLead.ConsentGiven = true
+		existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: existingLead},
+			updateResponse: newLead,
+		}
+
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", result.Action)
+	})
+}
+
+func BenchmarkLeadProcessor_ProcessLead(b *testing.B) {
+	existingLead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+	scenarios := map[string]*MockAPIClient{
+		"create": {lookupResponse: &LookupResponse{Found: false}, createResponse: existingLead},
+		"update": {lookupResponse: &LookupResponse{Found: true, Lead: existingLead}, updateResponse: existingLead},
+	}
+
+	for name, mockAPI := range scenarios {
+		b.Run(name, func(b *testing.B) {
+			processor := NewLeadProcessor(mockAPI)
+			lead := models.NewLead("John Smith", "john@example.com", "New Corp", "Website")
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := processor.ProcessLead(lead); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLeadProcessor_Budget(t *testing.T) {
+	t.Run("defers creates beyond the limit", func(t *testing.T) {
+		// Arrange
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createResponse: models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"),
+		}
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetMaxCreates(1)
+
+		// Act
+		first, _ := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+		second, err := processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", first.Action)
+		assert.Equal(t, "DEFERRED", second.Action)
+		assert.ErrorIs(t, second.Error, ErrBudgetExhausted)
+	})
+
+	t.Run("defers leads once API calls are refused", func(t *testing.T) {
+		// Arrange
+		mockAPI := &MockAPIClient{
+			lookupError: fmt.Errorf("failed to make request: %w", ErrBudgetExhausted),
+		}
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "DEFERRED", result.Action)
+	})
+}
+
+func TestLeadProcessor_Paused(t *testing.T) {
+	t.Run("pauses leads once the run window closes", func(t *testing.T) {
+		// Arrange
+		mockAPI := &MockAPIClient{
+			lookupError: fmt.Errorf("failed to make request: %w", ErrRunPaused),
+		}
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "PAUSED", result.Action)
+		assert.ErrorIs(t, result.Error, ErrRunPaused)
+	})
+	t.Run("gives back the create reserved for a paused lead", func(t *testing.T) {
+		// Arrange
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createError:    fmt.Errorf("failed to make request: %w", ErrRunPaused),
+		}
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetMaxCreates(1)
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+
+		// Act
+		paused, _ := processor.ProcessLead(lead)
+		mockAPI.createError = nil
+		mockAPI.createResponse = lead
+		resumed, _ := processor.ProcessLead(lead)
+
+		// Assert
+		assert.Equal(t, "PAUSED", paused.Action)
+		assert.Equal(t, "CREATE", resumed.Action)
+	})
+}
+
+func TestLeadProcessor_RetryBudget(t *testing.T) {
+	t.Run("aborts leads once the retry budget is spent", func(t *testing.T) {
+		// Arrange
+		mockAPI := &MockAPIClient{
+			lookupError: fmt.Errorf("failed to make request: %w", ErrRetryBudgetExhausted),
+		}
+		processor := NewLeadProcessor(mockAPI)
+
+		// Act
+		result, err := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "ABORTED", result.Action)
+		assert.ErrorIs(t, result.Error, ErrRetryBudgetExhausted)
+	})
+}
+
+// recordingIntentLog remembers intents and their answers in order
+type recordingIntentLog struct {
+	events  []string
+	failing bool
+}
+
+func (l *recordingIntentLog) Begin(action string, lead *models.Lead) (string, error) {
+	if l.failing {
+		return "", assert.AnError
+	}
+	l.events = append(l.events, "begin "+action+" "+lead.Email)
+	return lead.Email, nil
+}
+
+func (l *recordingIntentLog) Finish(id string, err error) error {
+	l.events = append(l.events, fmt.Sprintf("finish %s %v", id, err))
+	return nil
+}
+
+func TestLeadProcessor_IntentLog(t *testing.T) {
+	t.Run("writes creates and updates ahead of sending them", func(t *testing.T) {
+		// Arrange
+		log := &recordingIntentLog{}
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		creator := NewLeadProcessor(&MockAPIClient{lookupResponse: &LookupResponse{Found: false}, createResponse: lead})
+		creator.SetIntentLog(log)
+		existing := models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")
+		updater := NewLeadProcessor(&MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: existing}, updateError: assert.AnError})
+		updater.SetIntentLog(log)
+
+		// Act
+		created, _ := creator.ProcessLead(lead)
+		failed, _ := updater.ProcessLead(lead)
+
+		// Assert
+		assert.Equal(t, "CREATE", created.Action)
+		assert.Equal(t, "UPDATE_ERROR", failed.Action)
+		assert.Equal(t, []string{
+			"begin CREATE john@example.com",
+			"finish john@example.com <nil>",
+			"begin UPDATE john@example.com",
+			"finish john@example.com " + assert.AnError.Error(),
+		}, log.events)
+	})
+
+	t.Run("doesn't send a create it couldn't write ahead", func(t *testing.T) {
+		// Arrange
+		mockAPI := &MockAPIClient{lookupResponse: &LookupResponse{Found: false}, createError: fmt.Errorf("must not be called")}
+		processor := NewLeadProcessor(mockAPI)
+		processor.SetIntentLog(&recordingIntentLog{failing: true})
+
+		// Act
+		result, err := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE_ERROR", result.Action)
+		assert.Contains(t, result.Error.Error(), "failed to write intent log")
+	})
+}
+
+func TestLeadProcessor_SetValidation(t *testing.T) {
+	t.Run("validates emails with the configured rule", func(t *testing.T) {
+		// Arrange
+		processor := NewLeadProcessor(&MockAPIClient{})
+		processor.SetValidation(models.ValidationOptions{
+			EmailRule: func(email string) *models.ValidationProblem {
+				return &models.ValidationProblem{Message: "email domain %s does not exist", Args: []interface{}{"example.invalid"}}
+			},
+		})
+
+		// Act
+		result, err := processor.ProcessLead(models.NewLead("John Doe", "john@example.invalid", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "VALIDATION_ERROR", result.Action)
+		assert.EqualError(t, result.Error, "email domain example.invalid does not exist")
+	})
+}
+
+func TestLeadProcessor_TextLimits(t *testing.T) {
+	t.Run("rejects over-long fields counting characters, not bytes", func(t *testing.T) {
+		// Arrange
+		processor := NewLeadProcessor(&MockAPIClient{lookupResponse: &LookupResponse{Found: false}})
+		processor.SetValidation(models.ValidationOptions{MaxLengths: map[string]int{"name": 5}})
+
+		// Act
+		fits, _ := processor.ProcessLead(models.NewLead("Zoë Ö", "zoe@example.com", "Test Corp", "LinkedIn"))
+		tooLong, _ := processor.ProcessLead(models.NewLead("Zoë Öz", "zoe@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.Equal(t, "CREATE", fits.Action)
+		assert.Equal(t, "VALIDATION_ERROR", tooLong.Action)
+		assert.EqualError(t, tooLong.Error, "name is longer than 5 characters")
+	})
+
+	t.Run("rejects text that is not valid UTF-8", func(t *testing.T) {
+		// Arrange
+		processor := NewLeadProcessor(&MockAPIClient{})
+
+		// Act
+		result, _ := processor.ProcessLead(models.NewLead("Zo\xeb", "zoe@example.com", "Test Corp", "LinkedIn"))
+
+		// Assert
+		assert.Equal(t, "VALIDATION_ERROR", result.Action)
+		assert.EqualError(t, result.Error, "name is not valid UTF-8 (check the file's encoding)")
+	})
 }
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// ReconcileSample keeps a uniform random sample of the leads a run created
+// or updated, holding no more than its size in memory
+type ReconcileSample struct {
+	size    int
+	seen    int
+	rng     *rand.Rand
+	results []*ProcessResult
+}
+
+// NewReconcileSample returns an empty sample of up to size results
+func NewReconcileSample(size int, rng *rand.Rand) *ReconcileSample {
+	return &ReconcileSample{size: size, rng: rng, results: make([]*ProcessResult, 0, size)}
+}
+
+// Add offers a result to the sample, which keeps it only if it created or
+// updated a lead. It isn't safe for concurrent use.
+func (s *ReconcileSample) Add(result *ProcessResult) {
+	if result == nil || result.Sent == nil || (result.Action != "CREATE" && result.Action != "UPDATE") {
+		return
+	}
+	s.seen++
+	if len(s.results) < s.size {
+		s.results = append(s.results, result)
+	} else if i := s.rng.IntN(s.seen); i < s.size {
+		s.results[i] = result
+	}
+}
+
+// Results returns the results sampled
+func (s *ReconcileSample) Results() []*ProcessResult {
+	return s.results
+}
+
+// Mismatch is a sampled lead whose state in the CRM isn't what was written
+type Mismatch struct {
+	Email  string
+	Action string
+	// Problem says what differs: the lead is missing, or the fields that
+	// don't hold the values sent
+	Problem string
+}
+
+// ReconcileReport is what a reconciliation found
+type ReconcileReport struct {
+	Checked    int
+	Mismatches []Mismatch
+	// Failed counts the leads that couldn't be looked up, which are neither
+	// checked nor mismatched
+	Failed int
+}
+
+// Reconcile looks up each sampled lead through client and checks that the
+// CRM holds what the run sent: a created lead exists and an updated one has
+// its changes, compared as the processor compares leads. Lookups go through
+// client rather than the processor's own, which may answer from a cache or
+// mirror.
+func (p *LeadProcessor) Reconcile(client APIClient, sample []*ProcessResult) ReconcileReport {
+	var report ReconcileReport
+	for _, result := range sample {
+		response, err := client.LookupLead(result.Sent.Email)
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		report.Checked++
+		if !response.Found || response.Lead == nil {
+			report.Mismatches = append(report.Mismatches, Mismatch{Email: result.Sent.Email, Action: result.Action, Problem: "not found"})
+			continue
+		}
+		if p.equality.Equal(result.Sent, response.Lead) {
+			continue
+		}
+		problem := "differs"
+		if fields := slices.Sorted(maps.Keys(result.Sent.Diff(response.Lead))); len(fields) > 0 {
+			problem = fmt.Sprintf("differs in %s", strings.Join(fields, ", "))
+		}
+		report.Mismatches = append(report.Mismatches, Mismatch{Email: result.Sent.Email, Action: result.Action, Problem: problem})
+	}
+	return report
+}
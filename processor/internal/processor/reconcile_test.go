@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"code/internal/models"
+	"errors"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeadProcessor_Reconcile(t *testing.T) {
+	t.Run("finds written leads the CRM doesn't hold", func(t *testing.T) {
+		// Arrange
+		api := newStoreAPIClient()
+		leads := NewLeadProcessor(api)
+		created, _ := leads.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website"))
+		updated, _ := leads.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme Labs", "Website"))
+		lost, _ := leads.ProcessLead(models.NewLead("John Doe", "john@example.com", "Globex", "LinkedIn"))
+		delete(api.leads, "john@example.com")
+		api.leads["pat@example.com"] = models.NewLead("Pat Poe", "pat@example.com", "Initech", "Website")
+		patched, _ := leads.ProcessLead(models.NewLead("Pat Poe", "pat@example.com", "Initech", "Conference"))
+		api.leads["pat@example.com"] = models.NewLead("Pat Poe", "pat@example.com", "Initrode", "Website")
+
+		// Act
+		report := leads.Reconcile(api, []*ProcessResult{updated, lost, patched})
+
+		// Assert
+		assert.Equal(t, "CREATE", created.Action)
+		assert.Equal(t, "UPDATE", updated.Action)
+		assert.Equal(t, 3, report.Checked)
+		assert.Equal(t, []Mismatch{
+			{Email: "john@example.com", Action: "CREATE", Problem: "not found"},
+			{Email: "pat@example.com", Action: "UPDATE", Problem: "differs in company, source"},
+		}, report.Mismatches)
+	})
+
+	t.Run("counts leads it couldn't look up apart", func(t *testing.T) {
+		// Arrange
+		written := &ProcessResult{Action: "CREATE", Sent: models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")}
+		client := &MockAPIClient{lookupError: errors.New("connection refused")}
+
+		// Act
+		report := NewLeadProcessor(client).Reconcile(client, []*ProcessResult{written})
+
+		// Assert
+		assert.Equal(t, 0, report.Checked)
+		assert.Equal(t, 1, report.Failed)
+		assert.Empty(t, report.Mismatches)
+	})
+}
+
+func TestReconcileSample(t *testing.T) {
+	t.Run("samples only leads that were written", func(t *testing.T) {
+		// Arrange
+		sample := NewReconcileSample(2, rand.New(rand.NewPCG(1, 2)))
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")
+
+		// Act
+		sample.Add(&ProcessResult{Action: "SKIP", Lead: lead})
+		sample.Add(&ProcessResult{Action: "VALIDATION_ERROR", Lead: lead})
+		sample.Add(&ProcessResult{Action: "CREATE", Lead: lead, Sent: lead})
+		sample.Add(nil)
+
+		// Assert
+		assert.Len(t, sample.Results(), 1)
+	})
+
+	t.Run("keeps no more than its size", func(t *testing.T) {
+		// Arrange
+		sample := NewReconcileSample(3, rand.New(rand.NewPCG(1, 2)))
+		lead := models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website")
+
+		// Act
+		for range 100 {
+			sample.Add(&ProcessResult{Action: "UPDATE", Lead: lead, Sent: lead})
+		}
+
+		// Assert
+		assert.Len(t, sample.Results(), 3)
+	})
+}
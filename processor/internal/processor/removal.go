@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeletionMode is how leads are removed, e.g. when an atomic batch rolls
+// back a create
+type DeletionMode string
+
+// Deletion modes
+const (
+	// DeletionArchive archives leads, so they can be restored; the default
+	DeletionArchive DeletionMode = "archive"
+	// DeletionDelete deletes leads permanently
+	DeletionDelete DeletionMode = "delete"
+)
+
+// DeletionModes lists the modes ParseDeletionMode accepts
+func DeletionModes() []string {
+	return []string{string(DeletionArchive), string(DeletionDelete)}
+}
+
+// ParseDeletionMode parses a deletion mode; an empty value means
+// DeletionArchive
+func ParseDeletionMode(value string) (DeletionMode, error) {
+	switch DeletionMode(strings.ToLower(strings.TrimSpace(value))) {
+	case "", DeletionArchive:
+		return DeletionArchive, nil
+	case DeletionDelete:
+		return DeletionDelete, nil
+	}
+	return "", fmt.Errorf("unknown deletion mode %q (allowed: %s)", value, strings.Join(DeletionModes(), ", "))
+}
+
+// ArchiveClient is implemented by API clients that can archive leads
+type ArchiveClient interface {
+	ArchiveLead(id string) error
+}
+
+// Remover removes leads the way its deletion mode says
+type Remover struct {
+	mode   DeletionMode
+	remove func(id string) error
+}
+
+// NewRemover returns a remover for client in mode. Archiving needs a client
+// that can archive and a server that advertises it; deleting a client that
+// can delete.
+func NewRemover(client interface{}, mode DeletionMode, features ServerFeatures) (*Remover, error) {
+	switch mode {
+	case DeletionDelete:
+		if _, ok := Unwrap(client).(DeleteClient); !ok {
+			return nil, fmt.Errorf("the API client cannot delete leads")
+		}
+		return &Remover{mode: mode, remove: client.(DeleteClient).DeleteLead}, nil
+	case DeletionArchive:
+		if !CanArchive(client, features) {
+			return nil, fmt.Errorf("the server cannot archive leads")
+		}
+		return &Remover{mode: mode, remove: client.(ArchiveClient).ArchiveLead}, nil
+	}
+	return nil, fmt.Errorf("unknown deletion mode %q", mode)
+}
+
+// CanArchive reports whether client can archive leads on a server with
+// features
+func CanArchive(client interface{}, features ServerFeatures) bool {
+	_, ok := Unwrap(client).(ArchiveClient)
+	return ok && features.Archive
+}
+
+// Mode returns how the remover removes leads
+func (r *Remover) Mode() DeletionMode {
+	return r.mode
+}
+
+// Action names a removal in the intent log: ARCHIVE or DELETE
+func (r *Remover) Action() string {
+	return strings.ToUpper(string(r.mode))
+}
+
+// Remove archives or deletes the lead with id
+func (r *Remover) Remove(id string) error {
+	return r.remove(id)
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"code/internal/models"
+	"time"
+)
+
+// Processor is anything that can process a single lead
+type Processor interface {
+	ProcessLead(lead *models.Lead) (*ProcessResult, error)
+}
+
+// SeenChecker reports whether a lead with the same content was processed recently
+type SeenChecker interface {
+	SeenWithin(email, contentHash string, window time.Duration) bool
+}
+
+// SkipSeenProcessor skips leads whose exact content was already processed
+// successfully within the window, avoiding redundant API traffic for
+// overlapping exports
+type SkipSeenProcessor struct {
+	next    Processor
+	checker SeenChecker
+	window  time.Duration
+}
+
+// NewSkipSeenProcessor wraps next so recently seen leads are skipped
+func NewSkipSeenProcessor(next Processor, checker SeenChecker, window time.Duration) *SkipSeenProcessor {
+	return &SkipSeenProcessor{
+		next:    next,
+		checker: checker,
+		window:  window,
+	}
+}
+
+// ProcessLead skips the lead if it was seen recently, otherwise delegates
+func (p *SkipSeenProcessor) ProcessLead(lead *models.Lead) (*ProcessResult, error) {
+	if result := skipSeen(p.checker, p.window, lead); result != nil {
+		return result, nil
+	}
+
+	return p.next.ProcessLead(lead)
+}
+
+// skipSeen returns a SKIP result for a lead checker saw within window, or nil
+func skipSeen(checker SeenChecker, window time.Duration, lead *models.Lead) *ProcessResult {
+	if checker.SeenWithin(lead.Email, lead.ContentHash(), window) {
+		return &ProcessResult{
+			Action: "SKIP",
+			Lead:   lead,
+			Reason: "already processed in a previous run",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package processor
+
+import (
+	"code/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubSeenChecker struct {
+	seen map[string]string
+}
+
+func (c *stubSeenChecker) SeenWithin(email, contentHash string, window time.Duration) bool {
+	return c.seen[email] == contentHash
+}
+
+func TestSkipSeenProcessor_ProcessLead(t *testing.T) {
+	t.Run("skips leads already processed with identical content", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		checker := &stubSeenChecker{seen: map[string]string{"john@example.com": lead.ContentHash()}}
+		mockAPI := &MockAPIClient{lookupError: assert.AnError}
+		processor := NewSkipSeenProcessor(NewLeadProcessor(mockAPI), checker, 24*time.Hour)
+
+		// Act
+		result, err := processor.ProcessLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "SKIP", result.Action)
+		assert.NotEmpty(t, result.Reason)
+	})
+
+	t.Run("processes leads whose content changed", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Doe", "john@example.com", "New Corp", "LinkedIn")
+		previous := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		checker := &stubSeenChecker{seen: map[string]string{"john@example.com": previous.ContentHash()}}
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createResponse: lead,
+		}
+		processor := NewSkipSeenProcessor(NewLeadProcessor(mockAPI), checker, 24*time.Hour)
+
+		// Act
+		result, err := processor.ProcessLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", result.Action)
+	})
+	t.Run("processes leads whose consent or status changed", func(t *testing.T) {
+		// Arrange
+		previous := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		consented := *previous
+		consented.ConsentGiven = true
+		consented.ConsentSource = "webinar form"
+		qualified := *previous
+		qualified.Status = models.StatusQualified
+
+		// Assert
+		assert.NotEqual(t, previous.ContentHash(), consented.ContentHash())
+		assert.NotEqual(t, previous.ContentHash(), qualified.ContentHash())
+	})
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"code/internal/models"
+	"fmt"
+)
+
+// Sink receives leads after they were written to the CRM, e.g. a marketing
+// audience
+type Sink interface {
+	Name() string
+	Send(lead *models.Lead) error
+}
+
+// SinkProcessor sends created and updated leads to a secondary sink once the
+// primary write succeeded
+type SinkProcessor struct {
+	next      Processor
+	sink      Sink
+	sendOwned bool
+}
+
+// NewSinkProcessor wraps next so written leads are also sent to sink
+func NewSinkProcessor(next Processor, sink Sink) *SinkProcessor {
+	return &SinkProcessor{
+		next: next,
+		sink: sink,
+	}
+}
+
+// SetSendOwned also sends the CRM's copy of leads skipped because a rep owns
+// them, e.g. so a note still tells the owner the import saw the lead
+func (p *SinkProcessor) SetSendOwned(send bool) {
+	p.sendOwned = send
+}
+
+// ProcessLead delegates, then sends the written lead to the sink. A sink
+// failure is reported as SyncError and does not fail the lead, since the CRM
+// write already happened.
+func (p *SinkProcessor) ProcessLead(lead *models.Lead) (*ProcessResult, error) {
+	result, err := p.next.ProcessLead(lead)
+	if err != nil || result == nil || result.Error != nil {
+		return result, err
+	}
+
+	written := result.Lead
+	switch result.Action {
+	case "CREATE":
+		if result.CreatedLead != nil {
+			written = result.CreatedLead
+		}
+	case "UPDATE":
+		if result.UpdatedLead != nil {
+			written = result.UpdatedLead
+		}
+	case "SKIP":
+		if !p.sendOwned || result.OwnedLead == nil {
+			return result, nil
+		}
+		written = result.OwnedLead
+	default:
+		return result, nil
+	}
+
+	// The API's copy has no source row, which sinks such as attachment
+	// uploads read columns from. It may be shared with a lookup cache, so
+	// it is copied rather than changed.
+	if written.Raw == nil {
+		withRaw := *written
+		withRaw.Raw = lead.Raw
+		written = &withRaw
+	}
+	if err := p.sink.Send(written); err != nil {
+		result.SyncError = fmt.Errorf("%s: %w", p.sink.Name(), err)
+	}
+	return result, nil
+}
+
+// Plan reports what the wrapped processor would do with the lead, sending
+// nothing, as nothing is written
+func (p *SinkProcessor) Plan(lead *models.Lead) *ProcessResult {
+	planner, ok := p.next.(interface {
+		Plan(lead *models.Lead) *ProcessResult
+	})
+	if !ok {
+		return &ProcessResult{Action: "ERROR", Lead: lead, Error: fmt.Errorf("%T cannot plan leads", p.next)}
+	}
+	return planner.Plan(lead)
+}
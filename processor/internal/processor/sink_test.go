@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubSink struct {
+	sent []*models.Lead
+	err  error
+}
+
+func (s *stubSink) Name() string {
+	return "stub"
+}
+
+func (s *stubSink) Send(lead *models.Lead) error {
+	s.sent = append(s.sent, lead)
+	return s.err
+}
+
+func TestSinkProcessor_ProcessLead(t *testing.T) {
+	t.Run("sends created leads after the CRM write", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		lead.Raw = map[string]string{"Photo": "john.png"}
+		created := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		created.ID = "lead_1"
+		sink := &stubSink{}
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createResponse: created,
+		}
+		processor := NewSinkProcessor(NewLeadProcessor(mockAPI), sink)
+
+		// Act
+		result, err := processor.ProcessLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", result.Action)
+		assert.Len(t, sink.sent, 1)
+		assert.Equal(t, "lead_1", sink.sent[0].ID)
+		assert.Equal(t, "john.png", sink.sent[0].Raw["Photo"])
+		assert.NoError(t, result.SyncError)
+	})
+
+	t.Run("does not send skipped or failed leads", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		sink := &stubSink{}
+		skipping := NewSinkProcessor(NewLeadProcessor(&MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")},
+		}), sink)
+		failing := NewSinkProcessor(NewLeadProcessor(&MockAPIClient{
+			lookupResponse: &LookupResponse{Found: false},
+			createError:    assert.AnError,
+		}), sink)
+
+		// Act
+		skipping.ProcessLead(lead)
+		failing.ProcessLead(lead)
+
+		// Assert
+		assert.Empty(t, sink.sent)
+	})
+
+	t.Run("sends leads skipped as owned only when asked to", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Smith", "john@example.com", "New Corp", "Website")
+		owned := models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")
+		owned.ID = "lead_1"
+		owned.Owner = "maria.garcia"
+		mockAPI := &MockAPIClient{lookupResponse: &LookupResponse{Found: true, Lead: owned}}
+		leads := NewLeadProcessor(mockAPI)
+		leads.SetRespectOwnership(true)
+		ignoring := &stubSink{}
+		sending := &stubSink{}
+		processor := NewSinkProcessor(leads, sending)
+		processor.SetSendOwned(true)
+
+		// Act
+		NewSinkProcessor(leads, ignoring).ProcessLead(lead)
+		result, _ := processor.ProcessLead(lead)
+
+		// Assert
+		assert.Equal(t, "SKIP", result.Action)
+		assert.Empty(t, ignoring.sent)
+		assert.Len(t, sending.sent, 1)
+		assert.Equal(t, "lead_1", sending.sent[0].ID)
+	})
+
+	t.Run("reports a sink failure without failing the lead", func(t *testing.T) {
+		// Arrange
+		newLead := models.NewLead("John Smith", "john@example.com", "New Corp", "Website")
+		sink := &stubSink{err: assert.AnError}
+		mockAPI := &MockAPIClient{
+			lookupResponse: &LookupResponse{Found: true, Lead: models.NewLead("John Doe", "john@example.com", "Old Corp", "LinkedIn")},
+			updateResponse: newLead,
+		}
+		processor := NewSinkProcessor(NewLeadProcessor(mockAPI), sink)
+
+		// Act
+		result, err := processor.ProcessLead(newLead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "UPDATE", result.Action)
+		assert.NoError(t, result.Error)
+		assert.ErrorIs(t, result.SyncError, assert.AnError)
+	})
+}
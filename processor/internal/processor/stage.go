@@ -0,0 +1,248 @@
+package processor
+
+import (
+	"code/internal/models"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Built-in stage names, in the order ProcessLead runs them by default
+const (
+	StageValidate  = "validate"
+	StageNormalize = "normalize"
+	StageDedup     = "dedup"
+	StageLookup    = "lookup"
+	StageDecide    = "decide"
+	StageWrite     = "write"
+)
+
+// Step is a lead on its way through the stages, with what the stages before
+// have found out about it
+type Step struct {
+	Lead *models.Lead
+	// Found and Existing are the lookup's answer: whether the CRM has the
+	// lead, and its copy if so
+	Found    bool
+	Existing *models.Lead
+	// Action is what the decide stage chose, CREATE or UPDATE, and Outgoing
+	// the lead to send
+	Action   string
+	Outgoing *models.Lead
+}
+
+// Handler runs a step through the rest of the chain
+type Handler func(step *Step) *ProcessResult
+
+// Stage is one step of processing a lead. It either finishes the lead with
+// its own result or hands the step on to next, the rest of the chain,
+// possibly changing the step first or looking at next's result.
+type Stage interface {
+	Process(step *Step, next Handler) *ProcessResult
+}
+
+// StageFunc lets a function be a Stage
+type StageFunc func(step *Step, next Handler) *ProcessResult
+
+// Process calls f
+func (f StageFunc) Process(step *Step, next Handler) *ProcessResult {
+	return f(step, next)
+}
+
+// StageFactory builds a stage for the processor it will run in
+type StageFactory func(p *LeadProcessor) Stage
+
+var (
+	stagesMu  sync.RWMutex
+	factories = map[string]StageFactory{
+		StageValidate:  func(p *LeadProcessor) Stage { return StageFunc(p.validateStage) },
+		StageNormalize: func(p *LeadProcessor) Stage { return StageFunc(normalizeStage) },
+		StageDedup:     func(p *LeadProcessor) Stage { return StageFunc(p.dedupStage) },
+		StageLookup:    func(p *LeadProcessor) Stage { return StageFunc(p.lookupStage) },
+		StageDecide:    func(p *LeadProcessor) Stage { return StageFunc(p.decideStage) },
+		StageWrite:     func(p *LeadProcessor) Stage { return StageFunc(p.writeStage) },
+	}
+)
+
+// DefaultStages returns the built-in stages in the order they run by default
+func DefaultStages() []string {
+	return []string{StageValidate, StageNormalize, StageDedup, StageLookup, StageDecide, StageWrite}
+}
+
+// RegisterStage makes a custom stage available under name, so the stages
+// listed in the config can include it. Call it from an init function.
+func RegisterStage(name string, factory StageFactory) error {
+	name = strings.TrimSpace(name)
+	if name == "" || factory == nil {
+		return fmt.Errorf("a stage needs a name and a factory")
+	}
+
+	stagesMu.Lock()
+	defer stagesMu.Unlock()
+	if _, exists := factories[name]; exists {
+		return fmt.Errorf("stage %q is already registered", name)
+	}
+	factories[name] = factory
+	return nil
+}
+
+// ValidateStages checks a stage order: every name registered and listed
+// once, and the built-in stages all present in their default order, so a
+// lead is still validated before it is looked up and decided on before it is
+// written. Custom stages may go anywhere between them.
+func ValidateStages(names []string) error {
+	stagesMu.RLock()
+	defer stagesMu.RUnlock()
+
+	seen := make(map[string]bool, len(names))
+	var builtIn []string
+	for _, name := range names {
+		if _, ok := factories[name]; !ok {
+			return fmt.Errorf("unknown stage %q", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("stage %q is listed twice", name)
+		}
+		seen[name] = true
+		if slices.Contains(DefaultStages(), name) {
+			builtIn = append(builtIn, name)
+		}
+	}
+	if !slices.Equal(builtIn, DefaultStages()) {
+		return fmt.Errorf("the built-in stages must all be listed, in the order %s", strings.Join(DefaultStages(), ", "))
+	}
+	return nil
+}
+
+// SetStages replaces the processor's stages with the named ones, in order.
+// An empty list keeps the default stages.
+func (p *LeadProcessor) SetStages(names []string) error {
+	if len(names) == 0 {
+		names = DefaultStages()
+	}
+	if err := ValidateStages(names); err != nil {
+		return err
+	}
+
+	stagesMu.RLock()
+	defer stagesMu.RUnlock()
+	stages := make([]namedStage, len(names))
+	for i, name := range names {
+		stages[i] = namedStage{name: name, Stage: factories[name](p)}
+	}
+	p.stages = stages
+	return nil
+}
+
+// chain links stages in order, ending in last
+func chain(stages []Stage, last Handler) Handler {
+	handler := last
+	for i := len(stages) - 1; i >= 0; i-- {
+		stage, next := stages[i], handler
+		handler = func(step *Step) *ProcessResult {
+			return stage.Process(step, next)
+		}
+	}
+	return handler
+}
+
+// unfinished is the end of the chain, reached only if no stage decided the
+// lead's outcome
+func unfinished(step *Step) *ProcessResult {
+	return &ProcessResult{
+		Action: "ERROR",
+		Lead:   step.Lead,
+		Error:  fmt.Errorf("no stage finished processing the lead"),
+	}
+}
+
+// validateStage fails leads that don't validate
+func (p *LeadProcessor) validateStage(step *Step, next Handler) *ProcessResult {
+	if result := p.Check(step.Lead); result != nil {
+		return result
+	}
+	return next(step)
+}
+
+// normalizeStage cleans the lead's text fields, as a pipeline's transforms
+// usually already have
+func normalizeStage(step *Step, next Handler) *ProcessResult {
+	step.Lead = models.Sanitize(step.Lead)
+	return next(step)
+}
+
+// dedupStage skips leads with content processed recently, if the processor
+// was told how to check
+func (p *LeadProcessor) dedupStage(step *Step, next Handler) *ProcessResult {
+	if p.seen != nil {
+		if result := skipSeen(p.seen, p.seenWindow, step.Lead); result != nil {
+			return result
+		}
+	}
+	return next(step)
+}
+
+// lookupStage asks the CRM for its copy of the lead
+func (p *LeadProcessor) lookupStage(step *Step, next Handler) *ProcessResult {
+	started := time.Now()
+	lookupResp, err := p.apiClient.LookupLead(step.Lead.Email)
+	p.trace(step.Lead, CallLookup, started, err)
+	if result := interrupted(step.Lead, err); result != nil {
+		return result
+	}
+	if err != nil {
+		return &ProcessResult{
+			Action: "API_ERROR",
+			Lead:   step.Lead,
+			Error:  err,
+		}
+	}
+
+	step.Found, step.Existing = lookupResp.Found, lookupResp.Lead
+	return next(step)
+}
+
+// decideStage leaves the choice between a create, an update and a result
+// without a write, such as a SKIP or STATUS_CONFLICT, to the policy
+func (p *LeadProcessor) decideStage(step *Step, next Handler) *ProcessResult {
+	var existing *models.Lead
+	if step.Found {
+		existing = step.Existing
+	}
+	decision := p.policy.Decide(step.Lead, existing, func(lead *models.Lead) Decision {
+		if existing == nil {
+			return Decision{Action: "CREATE", Outgoing: lead}
+		}
+		result, outgoing := p.compare(lead, existing)
+		if result != nil {
+			return Decision{Result: result}
+		}
+		return Decision{Action: "UPDATE", Outgoing: outgoing}
+	})
+	if decision.Result != nil {
+		return decision.Result
+	}
+	if decision.Action == "UPDATE" && p.respectOwners && existing.Owner != "" {
+		return &ProcessResult{
+			Action:    "SKIP",
+			Lead:      step.Lead,
+			Reason:    "owned by " + existing.Owner,
+			OwnedLead: existing,
+		}
+	}
+	step.Action, step.Outgoing = decision.Action, decision.Outgoing
+	return next(step)
+}
+
+// writeStage sends the decided create or update
+func (p *LeadProcessor) writeStage(step *Step, next Handler) *ProcessResult {
+	switch step.Action {
+	case "CREATE":
+		return p.create(step.Lead, step.Outgoing)
+	case "UPDATE":
+		return p.update(step.Lead, step.Outgoing, step.Existing)
+	}
+	return next(step)
+}
@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"code/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	// test-skip-twitter leaves Twitter leads alone before they are looked up
+	RegisterStage("test-skip-twitter", func(p *LeadProcessor) Stage {
+		return StageFunc(func(step *Step, next Handler) *ProcessResult {
+			if step.Lead.Source == "Twitter" {
+				return &ProcessResult{Action: "SKIP", Lead: step.Lead, Reason: "Twitter leads aren't imported"}
+			}
+			return next(step)
+		})
+	})
+	// test-upper-company rewrites the decided lead just before it is written
+	RegisterStage("test-upper-company", func(p *LeadProcessor) Stage {
+		return StageFunc(func(step *Step, next Handler) *ProcessResult {
+			if step.Outgoing != nil {
+				step.Outgoing.Company = strings.ToUpper(step.Outgoing.Company)
+			}
+			return next(step)
+		})
+	})
+}
+
+// recordingClient remembers the leads it was asked to create
+type recordingClient struct {
+	MockAPIClient
+	created []*models.Lead
+}
+
+func (c *recordingClient) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	c.created = append(c.created, lead)
+	return lead, nil
+}
+
+func TestLeadProcessor_Stages(t *testing.T) {
+	t.Run("runs custom stages where they are listed", func(t *testing.T) {
+		// Arrange
+		client := &recordingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: false}}}
+		processor := NewLeadProcessor(client)
+		err := processor.SetStages([]string{StageValidate, StageNormalize, StageDedup, "test-skip-twitter", StageLookup, StageDecide, "test-upper-company", StageWrite})
+
+		// Act
+		created, _ := processor.ProcessLead(models.NewLead("Jane Roe", "jane@example.com", "Acme", "Website"))
+		skipped, _ := processor.ProcessLead(models.NewLead("John Doe", "john@example.com", "Globex", "Twitter"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "CREATE", created.Action)
+		assert.Len(t, client.created, 1)
+		assert.Equal(t, "ACME", client.created[0].Company)
+		assert.Equal(t, "SKIP", skipped.Action)
+		assert.Equal(t, "Twitter leads aren't imported", skipped.Reason)
+	})
+
+	t.Run("plans through custom stages without writing", func(t *testing.T) {
+		// Arrange
+		client := &writeRefusingClient{MockAPIClient: MockAPIClient{lookupResponse: &LookupResponse{Found: false}}, t: t}
+		processor := NewLeadProcessor(client)
+		processor.SetStages([]string{StageValidate, StageNormalize, StageDedup, "test-skip-twitter", StageLookup, StageDecide, StageWrite})
+
+		// Act
+		result := processor.Plan(models.NewLead("John Doe", "john@example.com", "Globex", "Twitter"))
+
+		// Assert
+		assert.Equal(t, "SKIP", result.Action)
+	})
+
+	t.Run("skips recently seen leads in the dedup stage", func(t *testing.T) {
+		// Arrange
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		processor := NewLeadProcessor(&MockAPIClient{lookupError: assert.AnError})
+		processor.SetSkipSeen(&stubSeenChecker{seen: map[string]string{"john@example.com": lead.ContentHash()}}, 24*time.Hour)
+
+		// Act
+		result, err := processor.ProcessLead(lead)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "SKIP", result.Action)
+		assert.Equal(t, "already processed in a previous run", result.Reason)
+	})
+
+	t.Run("rejects orders that drop or reorder built-in stages", func(t *testing.T) {
+		// Assert
+		assert.NoError(t, ValidateStages(DefaultStages()))
+		assert.ErrorContains(t, ValidateStages([]string{StageValidate, StageLookup, StageDecide, StageWrite}), "must all be listed")
+		assert.ErrorContains(t, ValidateStages([]string{StageValidate, StageNormalize, StageDedup, StageLookup, StageWrite, StageDecide}), "must all be listed")
+		assert.ErrorContains(t, ValidateStages(append(DefaultStages(), StageWrite)), "listed twice")
+		assert.ErrorContains(t, ValidateStages([]string{"enrich"}), `unknown stage "enrich"`)
+		assert.ErrorContains(t, RegisterStage(StageLookup, func(*LeadProcessor) Stage { return nil }), "already registered")
+	})
+}
@@ -0,0 +1,188 @@
+package quality
+
+import (
+	"code/internal/models"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// maxDistinct bounds the values counted per column, so a column of unique
+// IDs doesn't hold the whole file in memory. Past it, distinct counts are a
+// lower bound and top values only count values seen before the bound.
+const maxDistinct = 100000
+
+// ValueCount is how often a value occurs
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Column describes the values of one column
+type Column struct {
+	Name string `json:"name"`
+	// Filled counts rows with a value other than whitespace
+	Filled   int     `json:"filled"`
+	FillRate float64 `json:"fillRate"`
+	Distinct int     `json:"distinct"`
+	// DistinctCapped is set when the column had more distinct values than
+	// are counted, making Distinct a lower bound
+	DistinctCapped bool         `json:"distinctCapped,omitempty"`
+	Top            []ValueCount `json:"top"`
+}
+
+// Report is the data-quality profile of a file
+type Report struct {
+	Rows    int      `json:"rows"`
+	Columns []Column `json:"columns"`
+	// InvalidEmails counts leads whose email fails the email check,
+	// including empty ones
+	InvalidEmails    int     `json:"invalidEmails"`
+	InvalidEmailRate float64 `json:"invalidEmailRate"`
+	// DuplicateEmails counts leads repeating an email seen earlier in the file
+	DuplicateEmails int `json:"duplicateEmails"`
+	// Sources is how the leads' sources are spread, most frequent first;
+	// an empty source is counted as ""
+	Sources []ValueCount `json:"sources"`
+	// InvalidSources counts leads with a source the API rejects
+	InvalidSources int `json:"invalidSources"`
+}
+
+// Profiler gathers statistics lead by lead
+type Profiler struct {
+	header     []string
+	top        int
+	checkEmail models.EmailRule
+
+	rows    int
+	filled  []int
+	values  []map[string]int
+	capped  []bool
+	emails  map[string]bool
+	report  Report
+	sources map[string]int
+}
+
+// New profiles the columns of header, keeping the top most frequent values
+// of each. checkEmail replaces the built-in email syntax check when set.
+func New(header []string, top int, checkEmail models.EmailRule) *Profiler {
+	p := &Profiler{
+		header:     header,
+		top:        top,
+		checkEmail: checkEmail,
+		filled:     make([]int, len(header)),
+		values:     make([]map[string]int, len(header)),
+		capped:     make([]bool, len(header)),
+		emails:     make(map[string]bool),
+		sources:    make(map[string]int),
+	}
+	for i := range p.values {
+		p.values[i] = make(map[string]int)
+	}
+	return p
+}
+
+// Add counts a lead's row and fields
+func (p *Profiler) Add(lead *models.Lead) {
+	p.rows++
+	for i, column := range p.header {
+		value, _ := lead.RawValue(column)
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		p.filled[i]++
+		if _, seen := p.values[i][value]; seen || len(p.values[i]) < maxDistinct {
+			p.values[i][value]++
+		} else {
+			p.capped[i] = true
+		}
+	}
+
+	if !p.validEmail(lead.Email) {
+		p.report.InvalidEmails++
+	}
+	email := strings.ToLower(strings.TrimSpace(lead.Email))
+	if email != "" {
+		if p.emails[email] {
+			p.report.DuplicateEmails++
+		}
+		p.emails[email] = true
+	}
+
+	source := strings.TrimSpace(lead.Source)
+	p.sources[source]++
+	if !isValidSource(source) {
+		p.report.InvalidSources++
+	}
+}
+
+// Report returns the statistics of the leads added so far
+func (p *Profiler) Report() Report {
+	report := p.report
+	report.Rows = p.rows
+	report.InvalidEmailRate = rate(report.InvalidEmails, p.rows)
+	report.Sources = topValues(p.sources, 0)
+	report.Columns = make([]Column, len(p.header))
+	for i, name := range p.header {
+		report.Columns[i] = Column{
+			Name:           name,
+			Filled:         p.filled[i],
+			FillRate:       rate(p.filled[i], p.rows),
+			Distinct:       len(p.values[i]),
+			DistinctCapped: p.capped[i],
+			Top:            topValues(p.values[i], p.top),
+		}
+	}
+	return report
+}
+
+// validEmail checks an email the way validation would
+func (p *Profiler) validEmail(email string) bool {
+	lead := models.Lead{Email: email}
+	var validationErr *models.ValidationError
+	if !errors.As(lead.ValidateWith(models.ValidationOptions{EmailRule: p.checkEmail}), &validationErr) {
+		return true
+	}
+	for _, problem := range validationErr.Problems {
+		if problem.Field == "email" {
+			return false
+		}
+	}
+	return true
+}
+
+// topValues returns the n most frequent values, or all of them for n = 0,
+// ties broken alphabetically
+func topValues(counts map[string]int, n int) []ValueCount {
+	values := make([]ValueCount, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if n > 0 && len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
+
+func isValidSource(source string) bool {
+	for _, valid := range models.GetValidSources() {
+		if source == valid {
+			return true
+		}
+	}
+	return false
+}
+
+func rate(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
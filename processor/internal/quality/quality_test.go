@@ -0,0 +1,82 @@
+package quality
+
+import (
+	"code/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lead(name, email, company, source string) *models.Lead {
+	lead := models.NewLead(name, email, company, source)
+	lead.Raw = map[string]string{"Name": name, "Email": email, "Company": company, "Source": source}
+	return lead
+}
+
+func TestProfiler(t *testing.T) {
+	t.Run("reports fill rates, distinct and top values per column", func(t *testing.T) {
+		// Arrange
+		profiler := New([]string{"Name", "Email", "Company", "Source"}, 2, nil)
+
+		// Act
+		profiler.Add(lead("Jane Roe", "jane@example.com", "Acme", "LinkedIn"))
+		profiler.Add(lead("John Doe", "john@example.com", "Acme", "Website"))
+		profiler.Add(lead("Ann Lee", "ann@example.com", "", "LinkedIn"))
+		profiler.Add(lead("Max Mustermann", "max@example.com", "Globex", "LinkedIn"))
+		report := profiler.Report()
+
+		// Assert
+		assert.Equal(t, 4, report.Rows)
+		company := report.Columns[2]
+		assert.Equal(t, "Company", company.Name)
+		assert.Equal(t, 3, company.Filled)
+		assert.Equal(t, 0.75, company.FillRate)
+		assert.Equal(t, 2, company.Distinct)
+		assert.Equal(t, []ValueCount{{"Acme", 2}, {"Globex", 1}}, company.Top)
+		assert.Len(t, report.Columns[0].Top, 2)
+		assert.Equal(t, []ValueCount{{"LinkedIn", 3}, {"Website", 1}}, report.Sources)
+	})
+
+	t.Run("counts invalid and duplicate emails and invalid sources", func(t *testing.T) {
+		// Arrange
+		profiler := New([]string{"Name", "Email", "Company", "Source"}, 5, nil)
+
+		// Act
+		profiler.Add(lead("Jane Roe", "jane@example.com", "Acme", "LinkedIn"))
+		profiler.Add(lead("Jane Roe", "JANE@example.com", "Acme", "LinkedIn"))
+		profiler.Add(lead("No Email", "", "Acme", "Fax"))
+		profiler.Add(lead("Bad Email", "not-an-email", "Acme", ""))
+		report := profiler.Report()
+
+		// Assert
+		assert.Equal(t, 2, report.InvalidEmails)
+		assert.Equal(t, 0.5, report.InvalidEmailRate)
+		assert.Equal(t, 1, report.DuplicateEmails)
+		assert.Equal(t, 2, report.InvalidSources)
+		assert.Contains(t, report.Sources, ValueCount{"", 1})
+	})
+
+	t.Run("uses the given email rule", func(t *testing.T) {
+		// Arrange
+		rejectAll := func(email string) *models.ValidationProblem {
+			return &models.ValidationProblem{Message: "rejected"}
+		}
+		profiler := New([]string{"Email"}, 5, rejectAll)
+
+		// Act
+		profiler.Add(lead("Jane Roe", "jane@example.com", "Acme", "LinkedIn"))
+		report := profiler.Report()
+
+		// Assert
+		assert.Equal(t, 1, report.InvalidEmails)
+	})
+
+	t.Run("handles an empty file", func(t *testing.T) {
+		// Act
+		report := New([]string{"Email"}, 5, nil).Report()
+
+		// Assert
+		assert.Equal(t, 0, report.Rows)
+		assert.Equal(t, 0.0, report.Columns[0].FillRate)
+	})
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Record is the outcome of processing one lead
+type Record struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Company string `json:"company"`
+	Source  string `json:"source"`
+	Status  string `json:"status,omitempty"`
+	Action  string `json:"action"`
+	LeadID  string `json:"leadId,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// Store accumulates per-lead records for the end-of-run report. It keeps up
+// to maxInMemory records in a slice and spills everything to a temporary
+// NDJSON file beyond that, so huge runs don't grow memory with row count.
+type Store struct {
+	maxInMemory int
+	records     []Record
+	count       int
+
+	spillFile *os.File
+	spillBuf  *bufio.Writer
+	encoder   *json.Encoder
+}
+
+// NewStore creates a store holding at most maxInMemory records in memory.
+// A value <= 0 never spills.
+func NewStore(maxInMemory int) *Store {
+	return &Store{maxInMemory: maxInMemory}
+}
+
+// Add appends a record, spilling to disk once the in-memory limit is reached
+func (s *Store) Add(record Record) error {
+	s.count++
+
+	if s.spillFile == nil && (s.maxInMemory <= 0 || len(s.records) < s.maxInMemory) {
+		s.records = append(s.records, record)
+		return nil
+	}
+
+	if s.spillFile == nil {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+
+	return s.encoder.Encode(record)
+}
+
+// Len returns the number of records added
+func (s *Store) Len() int {
+	return s.count
+}
+
+// Spilled reports whether records have been moved to a temporary file
+func (s *Store) Spilled() bool {
+	return s.spillFile != nil
+}
+
+// Each calls fn for every record in insertion order
+func (s *Store) Each(fn func(Record) error) error {
+	if s.spillFile == nil {
+		for _, record := range s.records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.spillBuf.Flush(); err != nil {
+		return fmt.Errorf("failed to flush spilled records: %w", err)
+	}
+	if _, err := s.spillFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind spilled records: %w", err)
+	}
+	defer s.spillFile.Seek(0, io.SeekEnd)
+
+	decoder := json.NewDecoder(bufio.NewReader(s.spillFile))
+	for {
+		var record Record
+		if err := decoder.Decode(&record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read spilled records: %w", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the spill file, if any
+func (s *Store) Close() error {
+	if s.spillFile == nil {
+		return nil
+	}
+
+	name := s.spillFile.Name()
+	s.spillFile.Close()
+	s.spillFile = nil
+	return os.Remove(name)
+}
+
+// spill moves the in-memory records to a temporary file
+func (s *Store) spill() error {
+	file, err := os.CreateTemp("", "lead-processor-results-*.ndjson")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %w", err)
+	}
+
+	s.spillFile = file
+	s.spillBuf = bufio.NewWriter(file)
+	s.encoder = json.NewEncoder(s.spillBuf)
+
+	for _, record := range s.records {
+		if err := s.encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to spill records: %w", err)
+		}
+	}
+	s.records = nil
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("keeps small runs in memory", func(t *testing.T) {
+		// Arrange
+		store := NewStore(10)
+		defer store.Close()
+
+		// Act
+		store.Add(Record{Index: 1, Email: "a@example.com", Action: "CREATE"})
+		store.Add(Record{Index: 2, Email: "b@example.com", Action: "SKIP"})
+
+		// Assert
+		assert.False(t, store.Spilled())
+		assert.Equal(t, 2, store.Len())
+	})
+
+	t.Run("spills to disk past the limit and replays in order", func(t *testing.T) {
+		// Arrange
+		store := NewStore(3)
+		defer store.Close()
+
+		// Act
+		for i := 1; i <= 10; i++ {
+			assert.NoError(t, store.Add(Record{Index: i, Email: fmt.Sprintf("lead%d@example.com", i)}))
+		}
+		var indexes []int
+		err := store.Each(func(r Record) error {
+			indexes = append(indexes, r.Index)
+			return nil
+		})
+		store.Add(Record{Index: 11})
+		count := 0
+		store.Each(func(r Record) error {
+			count++
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, store.Spilled())
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, indexes)
+		assert.Equal(t, 11, count)
+		assert.Equal(t, 11, store.Len())
+	})
+}
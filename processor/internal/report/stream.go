@@ -0,0 +1,72 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats of a decision stream
+const (
+	StreamTSV = "tsv"
+	StreamCSV = "csv"
+)
+
+// StreamFormats lists the formats NewStream accepts
+var StreamFormats = []string{StreamTSV, StreamCSV}
+
+// streamHeader names the columns of a decision stream
+var streamHeader = []string{"email", "action", "lead_id", "error"}
+
+// Stream writes one line per lead as its outcome is decided, after a header
+// line, so a run's decisions can be piped into other tools while it runs.
+// Each line is flushed as it is written.
+type Stream struct {
+	out io.Writer
+	csv *csv.Writer
+}
+
+// NewStream starts a decision stream in format, tsv or csv, on out
+func NewStream(out io.Writer, format string) (*Stream, error) {
+	s := &Stream{out: out}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case StreamTSV:
+	case StreamCSV:
+		s.csv = csv.NewWriter(out)
+	default:
+		return nil, fmt.Errorf("unknown format %q (allowed: %s)", format, strings.Join(StreamFormats, ", "))
+	}
+
+	if err := s.writeLine(streamHeader); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write writes the record's email, action, lead ID and error
+func (s *Stream) Write(record Record) error {
+	return s.writeLine([]string{record.Email, record.Action, record.LeadID, record.Error})
+}
+
+func (s *Stream) writeLine(fields []string) error {
+	if s.csv != nil {
+		if err := s.csv.Write(fields); err != nil {
+			return err
+		}
+		s.csv.Flush()
+		return s.csv.Error()
+	}
+
+	// TSV has no quoting, so tabs and line breaks in a value become spaces
+	cleaned := make([]string, len(fields))
+	for i, field := range fields {
+		cleaned[i] = strings.Join(strings.FieldsFunc(field, isTSVSeparator), " ")
+	}
+	_, err := io.WriteString(s.out, strings.Join(cleaned, "\t")+"\n")
+	return err
+}
+
+func isTSVSeparator(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r'
+}
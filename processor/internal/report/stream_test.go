@@ -0,0 +1,46 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	t.Run("writes one tab-separated line per lead after a header", func(t *testing.T) {
+		// Arrange
+		var out strings.Builder
+		stream, err := NewStream(&out, "tsv")
+
+		// Act
+		stream.Write(Record{Email: "jane@example.com", Action: "CREATE", LeadID: "lead-1"})
+		stream.Write(Record{Email: "john@example.com", Action: "VALIDATION_ERROR", Error: "company is required;\tsource\nis invalid"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "email\taction\tlead_id\terror\n"+
+			"jane@example.com\tCREATE\tlead-1\t\n"+
+			"john@example.com\tVALIDATION_ERROR\t\tcompany is required; source is invalid\n", out.String())
+	})
+
+	t.Run("quotes CSV values that need it", func(t *testing.T) {
+		// Arrange
+		var out strings.Builder
+		stream, _ := NewStream(&out, "csv")
+
+		// Act
+		stream.Write(Record{Email: "john@example.com", Action: "API_ERROR", Error: "lookup failed, status 503"})
+
+		// Assert
+		assert.Equal(t, "email,action,lead_id,error\njohn@example.com,API_ERROR,,\"lookup failed, status 503\"\n", out.String())
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		// Act
+		_, err := NewStream(&strings.Builder{}, "json")
+
+		// Assert
+		assert.ErrorContains(t, err, `unknown format "json"`)
+	})
+}
@@ -0,0 +1,169 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Window is a daily time range, in local time, during which API calls may be
+// made. A window whose end is before its start runs past midnight.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses a window such as "22:00-06:00"
+func ParseWindow(value string) (Window, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid run window %q (use HH:MM-HH:MM, e.g. 22:00-06:00)", value)
+	}
+
+	startOffset, err := parseClock(start)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid run window %q: %w", value, err)
+	}
+	endOffset, err := parseClock(end)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid run window %q: %w", value, err)
+	}
+	if startOffset == endOffset {
+		return Window{}, fmt.Errorf("invalid run window %q: start and end are the same", value)
+	}
+
+	return Window{Start: startOffset, End: endOffset}, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", strings.TrimSpace(value))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// String renders the window as HH:MM-HH:MM
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// Contains reports whether the window is open at t
+func (w Window) Contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpen returns t if the window is open at t, otherwise when it next opens
+func (w Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	opens := midnight(t).Add(w.Start)
+	if !opens.After(t) {
+		opens = midnight(t.AddDate(0, 0, 1)).Add(w.Start)
+	}
+	return opens
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return t.Sub(midnight(t))
+}
+
+// midnight returns the start of t's day; dates are rebuilt rather than
+// truncated so days with a DST change keep their wall-clock times
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Gate holds back API requests while a window is closed. It either fails
+// them with an error, so the run can stop and resume later, or waits for the
+// window to reopen.
+type Gate struct {
+	window Window
+	wait   bool
+	closed error
+	now    func() time.Time
+
+	mu          sync.Mutex
+	onPause     func(resumeAt time.Time)
+	pausedUntil time.Time
+}
+
+// NewGate creates a gate for window. With wait set, requests block until the
+// window reopens; otherwise they fail with closed.
+func NewGate(window Window, wait bool, closed error) *Gate {
+	return &Gate{
+		window: window,
+		wait:   wait,
+		closed: closed,
+		now:    time.Now,
+	}
+}
+
+// OnPause registers fn to be called once each time the gate starts waiting
+// for the window to reopen
+func (g *Gate) OnPause(fn func(resumeAt time.Time)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onPause = fn
+}
+
+// Wait returns once the window is open, or with an error if the gate doesn't
+// wait or ctx is cancelled first
+func (g *Gate) Wait(ctx context.Context) error {
+	now := g.now()
+	resumeAt := g.window.NextOpen(now)
+	if !resumeAt.After(now) {
+		return nil
+	}
+	if !g.wait {
+		return fmt.Errorf("%w: %s, it reopens at %s", g.closed, g.window, resumeAt.Format("2006-01-02 15:04"))
+	}
+
+	g.mu.Lock()
+	if !resumeAt.Equal(g.pausedUntil) {
+		g.pausedUntil = resumeAt
+		if g.onPause != nil {
+			g.onPause(resumeAt)
+		}
+	}
+	g.mu.Unlock()
+
+	timer := time.NewTimer(resumeAt.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Middleware wraps next so requests are only sent while the window is open;
+// it has the shape of api.Middleware
+func (g *Gate) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if err := g.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
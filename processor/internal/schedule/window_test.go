@@ -0,0 +1,121 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2024, time.March, 10, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseWindow(t *testing.T) {
+	t.Run("parses windows within a day and past midnight", func(t *testing.T) {
+		for value, expected := range map[string]Window{
+			"09:00-17:30":  {Start: 9 * time.Hour, End: 17*time.Hour + 30*time.Minute},
+			"22:00-06:00":  {Start: 22 * time.Hour, End: 6 * time.Hour},
+			" 1:05 - 2:00": {Start: time.Hour + 5*time.Minute, End: 2 * time.Hour},
+		} {
+			// Act
+			window, err := ParseWindow(value)
+
+			// Assert
+			assert.NoError(t, err, value)
+			assert.Equal(t, expected, window, value)
+		}
+	})
+
+	t.Run("rejects malformed and empty windows", func(t *testing.T) {
+		for _, value := range []string{"22:00", "25:00-06:00", "night-day", "06:00-06:00", ""} {
+			// Act
+			_, err := ParseWindow(value)
+
+			// Assert
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestWindow(t *testing.T) {
+	overnight := Window{Start: 22 * time.Hour, End: 6 * time.Hour}
+	daytime := Window{Start: 9 * time.Hour, End: 17 * time.Hour}
+
+	t.Run("is open between start and end", func(t *testing.T) {
+		// Assert
+		assert.True(t, overnight.Contains(at(23, 0)))
+		assert.True(t, overnight.Contains(at(2, 0)))
+		assert.False(t, overnight.Contains(at(6, 0)))
+		assert.False(t, overnight.Contains(at(12, 0)))
+		assert.True(t, daytime.Contains(at(9, 0)))
+		assert.False(t, daytime.Contains(at(17, 0)))
+		assert.Equal(t, "22:00-06:00", overnight.String())
+	})
+
+	t.Run("finds when the window next opens", func(t *testing.T) {
+		// Assert
+		assert.Equal(t, at(22, 0), overnight.NextOpen(at(12, 0)))
+		assert.Equal(t, at(23, 0), overnight.NextOpen(at(23, 0)))
+		assert.Equal(t, at(9, 0).AddDate(0, 0, 1), daytime.NextOpen(at(18, 0)))
+	})
+}
+
+func TestGate(t *testing.T) {
+	closed := errors.New("run window closed")
+	daytime := Window{Start: 9 * time.Hour, End: 17 * time.Hour}
+
+	t.Run("fails requests while the window is closed", func(t *testing.T) {
+		// Arrange
+		reached := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached++
+		}))
+		defer server.Close()
+
+		gate := NewGate(daytime, false, closed)
+		gate.now = func() time.Time { return at(20, 0) }
+		client := &http.Client{Transport: gate.Middleware(http.DefaultTransport)}
+
+		// Act
+		_, err := client.Get(server.URL)
+
+		// Assert
+		assert.ErrorIs(t, err, closed)
+		assert.Equal(t, 0, reached)
+	})
+
+	t.Run("waits for the window to reopen", func(t *testing.T) {
+		// Arrange
+		opens := at(9, 0)
+		gate := NewGate(daytime, true, closed)
+		gate.now = func() time.Time { return opens.Add(-20 * time.Millisecond) }
+		var pausedUntil []time.Time
+		gate.OnPause(func(resumeAt time.Time) { pausedUntil = append(pausedUntil, resumeAt) })
+
+		// Act
+		err := gate.Wait(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{opens}, pausedUntil)
+	})
+
+	t.Run("stops waiting when the context is cancelled", func(t *testing.T) {
+		// Arrange
+		gate := NewGate(daytime, true, closed)
+		gate.now = func() time.Time { return at(20, 0) }
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		err := gate.Wait(ctx)
+
+		// Assert
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"bytes"
+	"code/internal/awsauth"
+	"code/internal/errcode"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// resolveSecretsManager reads a secret from AWS Secrets Manager, for
+// references like aws-sm://prod/lead-processor#api_token or an ARN. The
+// credentials and region come from the standard AWS environment variables;
+// an ARN names its own region. Without a #key the secret string is the
+// value, with one it is read as JSON and the key's value taken.
+func resolveSecretsManager(ctx context.Context, ref string) (string, error) {
+	name, key := splitKey(ref)
+	signer, err := awsauth.SignerFromEnv("secretsmanager")
+	if err != nil {
+		return "", err
+	}
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(name, ":"); len(parts) > 3 && parts[0] == "arn" {
+		signer.Region = parts[3]
+	}
+	if signer.Region == "" {
+		return "", fmt.Errorf("an AWS region is required (AWS_REGION)")
+	}
+	endpoint := strings.TrimSuffix(awsauth.FirstEnv("AWS_ENDPOINT_URL_SECRETS_MANAGER", "AWS_ENDPOINT_URL"), "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", signer.Region)
+	}
+
+	payload, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signer.Sign(req, awsauth.PayloadHash(payload))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errcode.Network(err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		SecretString string `json:"SecretString"`
+		Type         string `json:"__type"`
+		Message      string `json:"message"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		if body.Type != "" {
+			return "", fmt.Errorf("Secrets Manager refused the read: %s: %s: %w", body.Type, body.Message, &errcode.StatusError{Status: resp.StatusCode})
+		}
+		return "", fmt.Errorf("Secrets Manager refused the read: %w", &errcode.StatusError{Status: resp.StatusCode})
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("malformed Secrets Manager response: %w", decodeErr)
+	}
+	if key == "" {
+		return body.SecretString, nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body.SecretString), &values); err != nil {
+		return "", fmt.Errorf("the secret is not JSON, so it has no %q", key)
+	}
+	return pick(values, key)
+}
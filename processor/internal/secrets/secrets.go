@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Resolver fetches the secret a reference points at. It gets the reference
+// without its scheme, e.g. secret/data/crm#api_token for
+// vault://secret/data/crm#api_token.
+type Resolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc adapts an ordinary function to Resolver
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve implements Resolver
+func (f ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Resolver{
+		"vault":  ResolverFunc(resolveVault),
+		"aws-sm": ResolverFunc(resolveSecretsManager),
+	}
+)
+
+// Register makes resolver fetch the secrets of references starting with
+// scheme://, e.g. a password manager's. Call it from an init function.
+func Register(scheme string, resolver Resolver) error {
+	scheme = strings.ToLower(strings.TrimSpace(scheme))
+	if scheme == "" || resolver == nil {
+		return fmt.Errorf("a secret resolver needs a scheme and a resolver")
+	}
+
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	if _, exists := resolvers[scheme]; exists {
+		return fmt.Errorf("secret scheme %q is already registered", scheme)
+	}
+	resolvers[scheme] = resolver
+	return nil
+}
+
+// Schemes lists the registered schemes, sorted
+func Schemes() []string {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	schemes := make([]string, 0, len(resolvers))
+	for scheme := range resolvers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// resolver returns the resolver of a reference's scheme and the rest of the
+// reference, or false if value isn't a reference to a registered scheme
+func resolver(value string) (Resolver, string, bool) {
+	scheme, ref, ok := strings.Cut(value, "://")
+	if !ok {
+		return nil, "", false
+	}
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	r, ok := resolvers[strings.ToLower(scheme)]
+	return r, ref, ok
+}
+
+// IsReference reports whether value points at a secret rather than being one
+func IsReference(value string) bool {
+	_, _, ok := resolver(value)
+	return ok
+}
+
+// Resolve returns the secret value points at, or value itself when it isn't
+// a reference. The error names the reference, never a secret.
+func Resolve(ctx context.Context, value string) (string, error) {
+	r, ref, ok := resolver(value)
+	if !ok {
+		return value, nil
+	}
+	secret, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", value, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("failed to resolve %s: the secret is empty", value)
+	}
+	return secret, nil
+}
+
+// splitKey splits a reference at its '#' into the secret's path and the key
+// of the value within it, which may be empty
+func splitKey(ref string) (string, string) {
+	path, key, _ := strings.Cut(ref, "#")
+	return strings.Trim(path, "/"), key
+}
@@ -0,0 +1,172 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	t.Run("returns plain values as they are", func(t *testing.T) {
+		// Act
+		value, err := Resolve(context.Background(), "s3cr3t://not-a-scheme")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cr3t://not-a-scheme", value)
+	})
+
+	t.Run("uses registered resolvers", func(t *testing.T) {
+		// Arrange
+		err := Register("Test-Store", ResolverFunc(func(ctx context.Context, ref string) (string, error) {
+			return "value of " + ref, nil
+		}))
+
+		// Act
+		value, resolveErr := Resolve(context.Background(), "test-store://crm#token")
+		duplicate := Register("test-store", ResolverFunc(func(context.Context, string) (string, error) { return "", nil }))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, resolveErr)
+		assert.Equal(t, "value of crm#token", value)
+		assert.Error(t, duplicate)
+		assert.Contains(t, Schemes(), "test-store")
+	})
+
+	t.Run("names the reference, not the secret, in errors", func(t *testing.T) {
+		// Arrange
+		_ = Register("failing", ResolverFunc(func(context.Context, string) (string, error) {
+			return "", errors.New("access denied")
+		}))
+		_ = Register("empty", ResolverFunc(func(context.Context, string) (string, error) {
+			return "", nil
+		}))
+
+		// Act
+		_, failErr := Resolve(context.Background(), "failing://crm")
+		_, emptyErr := Resolve(context.Background(), "empty://crm")
+
+		// Assert
+		assert.EqualError(t, failErr, "failed to resolve failing://crm: access denied")
+		assert.EqualError(t, emptyErr, "failed to resolve empty://crm: the secret is empty")
+	})
+}
+
+func TestResolveVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/crm":
+			_, _ = w.Write([]byte(`{"data":{"data":{"api_token":"t0k3n","other":"x"},"metadata":{"version":2}}}`))
+		case "/v1/kv/crm":
+			_, _ = w.Write([]byte(`{"data":{"api_token":"v1-t0k3n"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	t.Run("reads KV version 2 secrets", func(t *testing.T) {
+		// Act
+		value, err := Resolve(context.Background(), "vault://secret/data/crm#api_token")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "t0k3n", value)
+	})
+
+	t.Run("takes the only value of a secret without a key", func(t *testing.T) {
+		// Act
+		value, err := Resolve(context.Background(), "vault://kv/crm")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "v1-t0k3n", value)
+	})
+
+	t.Run("fails on missing keys and secrets", func(t *testing.T) {
+		// Act
+		_, keyErr := Resolve(context.Background(), "vault://secret/data/crm#password")
+		_, ambiguousErr := Resolve(context.Background(), "vault://secret/data/crm")
+		_, missingErr := Resolve(context.Background(), "vault://secret/data/missing#api_token")
+
+		// Assert
+		assert.ErrorContains(t, keyErr, `the secret has no "password"`)
+		assert.ErrorContains(t, ambiguousErr, "name one with #key")
+		assert.ErrorContains(t, missingErr, "Vault refused the read")
+	})
+}
+
+func TestResolveSecretsManager(t *testing.T) {
+	var target, secretID, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		authorization = r.Header.Get("Authorization")
+		var body struct {
+			SecretId string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		secretID = body.SecretId
+		if strings.HasSuffix(body.SecretId, "missing") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"SecretString": `{"api_token":"t0k3n"}`})
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	t.Run("reads a key of a JSON secret", func(t *testing.T) {
+		// Act
+		value, err := Resolve(context.Background(), "aws-sm://prod/crm#api_token")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "t0k3n", value)
+		assert.Equal(t, "secretsmanager.GetSecretValue", target)
+		assert.Equal(t, "prod/crm", secretID)
+		assert.Contains(t, authorization, "/eu-west-1/secretsmanager/aws4_request")
+	})
+
+	t.Run("returns the secret string without a key", func(t *testing.T) {
+		// Act
+		value, err := Resolve(context.Background(), "aws-sm://prod/crm")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, `{"api_token":"t0k3n"}`, value)
+	})
+
+	t.Run("takes the region from an ARN", func(t *testing.T) {
+		// Act
+		_, err := Resolve(context.Background(), "aws-sm://arn:aws:secretsmanager:us-east-2:123456789012:secret:crm#api_token")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Contains(t, authorization, "/us-east-2/secretsmanager/aws4_request")
+	})
+
+	t.Run("reports what Secrets Manager refused", func(t *testing.T) {
+		// Act
+		_, err := Resolve(context.Background(), "aws-sm://prod/missing")
+
+		// Assert
+		assert.ErrorContains(t, err, "ResourceNotFoundException")
+	})
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"code/internal/errcode"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// httpClient fetches secrets from Vault and AWS
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// resolveVault reads a secret from HashiCorp Vault, for references like
+// vault://secret/data/lead-processor#api_token. VAULT_ADDR and VAULT_TOKEN
+// say where and as whom, VAULT_NAMESPACE the namespace on Vault Enterprise.
+// Secrets of KV version 1 and 2 engines are both understood.
+func resolveVault(ctx context.Context, ref string) (string, error) {
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN are required")
+	}
+	path, key := splitKey(ref)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errcode.Network(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vault refused the read: %w", &errcode.StatusError{Status: resp.StatusCode})
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("malformed Vault response: %w", err)
+	}
+	values := body.Data
+	// A KV version 2 secret nests its values under data, next to metadata
+	if nested, ok := values["data"]; ok {
+		if _, versioned := values["metadata"]; versioned {
+			values = nil
+			if err := json.Unmarshal(nested, &values); err != nil {
+				return "", fmt.Errorf("malformed Vault response: %w", err)
+			}
+		}
+	}
+	return pick(values, key)
+}
+
+// pick returns the text value of key in a secret holding several values,
+// or its only value when no key is given
+func pick(values map[string]json.RawMessage, key string) (string, error) {
+	if key == "" {
+		if len(values) != 1 {
+			return "", fmt.Errorf("the secret holds %d values; name one with #key", len(values))
+		}
+		for name := range values {
+			key = name
+		}
+	}
+	raw, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("the secret has no %q", key)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("%q is not text", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/jobs"
+	"code/internal/models"
+	"code/internal/processor"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxReviewBody caps the JSON body of an approval or rejection
+const maxReviewBody = 64 << 10
+
+// Planner is a lead processor that can tell what it would do with a lead
+// without writing it, such as processor.LeadProcessor
+type Planner interface {
+	Plan(lead *models.Lead) *processor.ProcessResult
+}
+
+// planningProcessor processes leads by planning them, so a file run through
+// it writes nothing
+type planningProcessor struct {
+	planner Planner
+}
+
+func (p planningProcessor) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	return p.planner.Plan(lead), nil
+}
+
+// planImport makes the plan of an import held for review: the summary of a
+// dry run of its file, counting the leads it would create, update and skip
+// and listing those that would fail
+func (s *Server) planImport(ctx context.Context, job jobs.Job, upload io.Reader) (interface{}, error) {
+	planner, ok := s.newProcessor(job.SubmittedBy).(Planner)
+	if !ok {
+		return nil, fmt.Errorf("the lead processor cannot plan imports")
+	}
+	if job.URL != "" {
+		body, err := s.fetch(ctx, job.URL)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		upload = body
+	}
+	summary, _, err := s.processFile(ctx, upload, job.Name, planningProcessor{planner: planner}, nil, func(*ImportSummary) {})
+	return summary, err
+}
+
+// reviewHandler approves or rejects an import pending review, answering
+// with the job. A JSON body may give a {"comment": "..."} for the record.
+func (s *Server) reviewHandler(decision string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxReviewBody)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body: " + err.Error()})
+			return
+		}
+		job, err := s.review(w, r, decision, body.Comment)
+		if err != nil {
+			writeJSON(w, reviewStatus(err), map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, redactJob(job))
+	}
+}
+
+// handleDashboardReview takes the dashboard's approve and reject buttons,
+// then returns to the dashboard. Browsers resend basic auth on forms posted
+// from any site, so posts from another site are refused.
+func (s *Server) handleDashboardReview(w http.ResponseWriter, r *http.Request) {
+	if crossSite(r) {
+		http.Error(w, "cross-site request refused", http.StatusForbidden)
+		return
+	}
+	decision := r.FormValue("decision")
+	if decision != jobs.DecisionApproved && decision != jobs.DecisionRejected {
+		http.Error(w, "invalid decision: "+decision, http.StatusBadRequest)
+		return
+	}
+	if _, err := s.review(w, r, decision, r.FormValue("comment")); err != nil {
+		http.Error(w, err.Error(), reviewStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// crossSite reports whether a browser sent the request from a page of
+// another origin, going by Sec-Fetch-Site or else Origin. Requests carrying
+// neither come from outside a browser.
+func crossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return false
+	case "":
+	default:
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	originURL, err := url.Parse(origin)
+	return err != nil || originURL.Host != r.Host
+}
+
+// review records the caller's decision on the import, naming the import and
+// the decision for the audit log
+func (s *Server) review(w http.ResponseWriter, r *http.Request, decision, comment string) (jobs.Job, error) {
+	caller, _ := auth.CallerFrom(r.Context())
+	decide := s.cfg.Jobs.Approve
+	if decision == jobs.DecisionRejected {
+		decide = s.cfg.Jobs.Reject
+	}
+	job, err := decide(r.PathValue("id"), caller.Name, strings.TrimSpace(comment))
+	if errors.Is(err, jobs.ErrNotFound) {
+		return job, err
+	}
+	w.Header().Set(auth.ImportIDHeader, job.ID)
+	if err != nil {
+		return job, err
+	}
+	w.Header().Set(auth.ReviewHeader, decision)
+	log.Printf("Import %s %s by %s", job.ID, decision, caller.Name)
+	return job, nil
+}
+
+func reviewStatus(err error) int {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, jobs.ErrOwnImport):
+		return http.StatusForbidden
+	}
+	return http.StatusConflict
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/jobs"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// planningCreator plans and creates every lead, counting the writes
+type planningCreator struct {
+	writes *atomic.Int32
+}
+
+func (p planningCreator) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	p.writes.Add(1)
+	return &processor.ProcessResult{Action: "CREATE", Lead: lead}, nil
+}
+
+func (p planningCreator) Plan(lead *models.Lead) *processor.ProcessResult {
+	return &processor.ProcessResult{Action: "CREATE", Lead: lead}
+}
+
+func TestServer_RequireApproval(t *testing.T) {
+	t.Run("holds an import with its plan until a reviewer approves it", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		auditPath := filepath.Join(t.TempDir(), "audit.ndjson")
+		audit, _ := auth.OpenAuditLog(auditPath)
+		defer audit.Close()
+		authenticator, _ := auth.New(auth.Config{Tokens: []auth.Token{
+			{Name: "marketing", Token: "up", Role: auth.RoleUploader},
+			{Name: "ops", Token: "review", Role: auth.RoleReviewer},
+		}}, audit)
+		writes := &atomic.Int32{}
+		s := New(Config{Jobs: queue, Auth: authenticator, RequireApproval: true}, func(string) pipeline.LeadProcessor {
+			return planningCreator{writes: writes}
+		})
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.StartJobs(ctx)
+		post := func(path, token, body string) *http.Response {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+		body := "Name,Email,Company,Source\n" +
+			"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n"
+
+		// Act
+		id := post("/imports", "up", body).Header.Get(auth.ImportIDHeader)
+		pending := waitForJob(t, queue, id, jobs.StatePendingReview)
+		ownApproval := post("/imports/"+id+"/approve", "up", "")
+		approval := post("/imports/"+id+"/approve", "review", `{"comment": "plan looks right"}`)
+		completed := waitForJob(t, queue, id, jobs.StateCompleted)
+
+		// Assert
+		var plan ImportSummary
+		assert.NoError(t, json.Unmarshal(pending.Plan, &plan))
+		assert.Equal(t, 1, plan.Created)
+		assert.Equal(t, http.StatusForbidden, ownApproval.StatusCode, "uploaders can't approve")
+		assert.Equal(t, http.StatusOK, approval.StatusCode)
+		assert.Equal(t, int32(1), writes.Load(), "the plan wrote nothing")
+		assert.Equal(t, "ops", completed.Review.By)
+		assert.Equal(t, "plan looks right", completed.Review.Comment)
+
+		data, _ := os.ReadFile(auditPath)
+		var record auth.Record
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &record))
+		assert.Equal(t, "ops", record.Token)
+		assert.Equal(t, id, record.ImportID)
+		assert.Equal(t, jobs.DecisionApproved, record.Review)
+	})
+
+	t.Run("refuses to decide twice", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		writes := &atomic.Int32{}
+		s := New(Config{Jobs: queue, RequireApproval: true}, func(string) pipeline.LeadProcessor {
+			return planningCreator{writes: writes}
+		})
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.StartJobs(ctx)
+		job, _ := queue.Enqueue(strings.NewReader("Name,Email\n"), "", 0, "marketing")
+		waitForJob(t, queue, job.ID, jobs.StatePendingReview)
+
+		// Act
+		rejected, _ := http.Post(server.URL+"/imports/"+job.ID+"/reject", "application/json", nil)
+		again, _ := http.Post(server.URL+"/imports/"+job.ID+"/approve", "application/json", nil)
+		missing, _ := http.Post(server.URL+"/imports/unknown/approve", "application/json", nil)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rejected.StatusCode)
+		assert.Equal(t, http.StatusConflict, again.StatusCode)
+		assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+		assert.Zero(t, writes.Load())
+	})
+}
+
+// waitForJob polls until the job reaches the state
+func waitForJob(t *testing.T, queue *jobs.Queue, id string, state jobs.State) jobs.Job {
+	assert.Eventually(t, func() bool {
+		job, _ := queue.Get(id)
+		return job.State == state
+	}, 5*time.Second, time.Millisecond)
+	job, _ := queue.Get(id)
+	return job
+}
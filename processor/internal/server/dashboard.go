@@ -0,0 +1,256 @@
+package server
+
+import (
+	"code/internal/jobs"
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// dashboardRefresh is how often the dashboard page reloads itself, in seconds
+const dashboardRefresh = 5
+
+// maxDashboardImports caps the finished imports the dashboard lists
+const maxDashboardImports = 50
+
+//go:embed templates/dashboard.html
+var dashboardFS embed.FS
+
+var dashboardTemplate = template.Must(template.New("dashboard.html").Funcs(template.FuncMap{
+	"since": func(t time.Time) string { return time.Since(t).Round(time.Second).String() },
+	"took": func(from, to time.Time) string {
+		return to.Sub(from).Round(time.Millisecond).String()
+	},
+	"ts": func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+}).ParseFS(dashboardFS, "templates/dashboard.html"))
+
+// dashboardImport is an import as the dashboard shows it
+type dashboardImport struct {
+	ID         string
+	Name       string
+	State      jobs.State
+	Priority   int
+	By         string
+	QueuedAt   time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Summary    ImportSummary
+	Codes      []codeCount
+	Error      string
+	Review     *jobs.Review
+}
+
+// codeCount is how many leads of an import failed with an error code
+type codeCount struct {
+	Code  string
+	Count int
+}
+
+type dashboardView struct {
+	Refresh int
+	Active  []dashboardImport
+	Queued  []dashboardImport
+	// Pending lists imports awaiting review, with their plans
+	Pending []dashboardImport
+	Recent  []dashboardImport
+	Codes   []codeCount
+}
+
+// handleDashboard renders the imports of this server: those running, with
+// their progress so far, those queued, those pending review with their
+// plans, and those finished recently with their failures by error code
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	view := s.dashboard()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, view); err != nil {
+		log.Printf("Rendering dashboard failed: %v", err)
+	}
+}
+
+func (s *Server) dashboard() dashboardView {
+	view := dashboardView{Refresh: dashboardRefresh}
+	tracked := make(map[string]bool)
+	for _, progress := range s.progress.list() {
+		tracked[progress.ID] = true
+		item := dashboardImport{
+			ID:         progress.ID,
+			Name:       progress.Input,
+			State:      jobs.StateCompleted,
+			StartedAt:  progress.StartedAt,
+			FinishedAt: progress.FinishedAt,
+			Summary:    progress.Summary,
+			Codes:      breakdown(progress.Summary.Failures),
+			Error:      progress.Error,
+		}
+		switch {
+		case progress.Running():
+			item.State = jobs.StateRunning
+		case progress.Error != "":
+			item.State = jobs.StateFailed
+		}
+		if s.cfg.Jobs != nil {
+			if job, ok := s.cfg.Jobs.Get(progress.ID); ok {
+				item.Name, item.Priority, item.By, item.QueuedAt, item.Review = jobName(job), job.Priority, job.SubmittedBy, job.CreatedAt, job.Review
+			}
+		}
+		if item.State == jobs.StateRunning {
+			view.Active = append(view.Active, item)
+		} else {
+			view.Recent = append(view.Recent, item)
+		}
+	}
+
+	// Jobs finished before a restart are only known to the queue
+	if s.cfg.Jobs != nil {
+		for _, job := range s.cfg.Jobs.List(jobs.Filter{}) {
+			if tracked[job.ID] {
+				continue
+			}
+			item := dashboardImport{ID: job.ID, Name: jobName(job), State: job.State, Priority: job.Priority, By: job.SubmittedBy, QueuedAt: job.CreatedAt, Error: job.Error, Review: job.Review}
+			switch job.State {
+			case jobs.StateQueued, jobs.StateRunning, jobs.StatePlanning:
+				view.Queued = append(view.Queued, item)
+				continue
+			}
+			if job.StartedAt != nil {
+				item.StartedAt = *job.StartedAt
+			}
+			if job.FinishedAt != nil {
+				item.FinishedAt = *job.FinishedAt
+			}
+			if summary, ok := jobSummary(job); ok {
+				item.Summary = *summary
+				item.Codes = breakdown(summary.Failures)
+			}
+			if job.State == jobs.StatePendingReview {
+				view.Pending = append(view.Pending, item)
+				continue
+			}
+			view.Recent = append(view.Recent, item)
+		}
+	}
+
+	// Queued jobs are listed in the order they will run
+	sort.SliceStable(view.Queued, func(i, j int) bool {
+		if view.Queued[i].Priority != view.Queued[j].Priority {
+			return view.Queued[i].Priority > view.Queued[j].Priority
+		}
+		return view.Queued[i].QueuedAt.Before(view.Queued[j].QueuedAt)
+	})
+	sort.SliceStable(view.Pending, func(i, j int) bool {
+		return view.Pending[i].QueuedAt.Before(view.Pending[j].QueuedAt)
+	})
+	sort.SliceStable(view.Recent, func(i, j int) bool {
+		return view.Recent[i].FinishedAt.After(view.Recent[j].FinishedAt)
+	})
+	if len(view.Recent) > maxDashboardImports {
+		view.Recent = view.Recent[:maxDashboardImports]
+	}
+
+	var failures []ImportFailure
+	for _, items := range [][]dashboardImport{view.Active, view.Recent} {
+		for _, item := range items {
+			failures = append(failures, item.Summary.Failures...)
+		}
+	}
+	view.Codes = breakdown(failures)
+	return view
+}
+
+// handleSummaryDownload serves an import's summary as a JSON file
+func (s *Server) handleSummaryDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	summary, ok := s.importSummary(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="import-`+id+`-summary.json"`)
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// handleRejectsDownload serves an import's failed leads as a CSV file
+func (s *Server) handleRejectsDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	summary, ok := s.importSummary(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="import-`+id+`-rejects.csv"`)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Row", "Email", "Action", "Code", "Error"})
+	for _, failure := range summary.Failures {
+		writer.Write([]string{strconv.Itoa(failure.Index), failure.Email, failure.Action, failure.Code, failure.Error})
+	}
+	writer.Flush()
+}
+
+// importSummary finds an import's summary, so far if it is still running
+func (s *Server) importSummary(id string) (*ImportSummary, bool) {
+	if progress, ok := s.progress.get(id); ok {
+		return &progress.Summary, true
+	}
+	if s.cfg.Jobs != nil {
+		if job, ok := s.cfg.Jobs.Get(id); ok {
+			return jobSummary(job)
+		}
+	}
+	return nil, false
+}
+
+// jobSummary reads a job's result, or the plan of a job pending review
+func jobSummary(job jobs.Job) (*ImportSummary, bool) {
+	data := job.Result
+	if job.State == jobs.StatePendingReview {
+		data = job.Plan
+	}
+	if len(data) == 0 {
+		return nil, false
+	}
+	var summary ImportSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, false
+	}
+	return &summary, true
+}
+
+func jobName(job jobs.Job) string {
+	if job.Name != "" {
+		return job.Name
+	}
+	return "/imports/" + job.ID
+}
+
+// breakdown counts failures by error code, most common first; failures
+// without a code are counted by action
+func breakdown(failures []ImportFailure) []codeCount {
+	counts := make(map[string]int)
+	for _, failure := range failures {
+		code := failure.Code
+		if code == "" {
+			code = failure.Action
+		}
+		counts[code]++
+	}
+
+	codes := make([]codeCount, 0, len(counts))
+	for code, count := range counts {
+		codes = append(codes, codeCount{Code: code, Count: count})
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i].Count != codes[j].Count {
+			return codes[i].Count > codes[j].Count
+		}
+		return codes[i].Code < codes[j].Code
+	})
+	return codes
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"code/internal/jobs"
+	"code/internal/pipeline"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Dashboard(t *testing.T) {
+	t.Run("is not served unless enabled", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{})
+		defer server.Close()
+
+		// Act
+		resp, err := http.Get(server.URL + "/dashboard")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("shows recent imports with their errors and downloads", func(t *testing.T) {
+		// Arrange
+		s := New(Config{EnableDashboard: true}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		body := "Name,Email,Company,Source\n" +
+			"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n" +
+			"Invalid User,invalid-email,Test Company,LinkedIn\n"
+		resp, _ := http.Post(server.URL+"/imports", "text/csv", strings.NewReader(body))
+		resp.Body.Close()
+		id := s.progress.list()[0].ID
+
+		// Act
+		page, pageErr := http.Get(server.URL + "/dashboard")
+		rejects, rejectsErr := http.Get(server.URL + "/dashboard/imports/" + id + "/rejects.csv")
+		missing, _ := http.Get(server.URL + "/dashboard/imports/unknown/summary.json")
+
+		// Assert
+		assert.NoError(t, pageErr)
+		assert.NoError(t, rejectsErr)
+		html, _ := io.ReadAll(page.Body)
+		assert.Contains(t, string(html), "VALIDATION 1")
+		assert.Contains(t, string(html), "/dashboard/imports/"+id+"/rejects.csv")
+		csv, _ := io.ReadAll(rejects.Body)
+		assert.Equal(t, "Row,Email,Action,Code,Error\n2,invalid-email,VALIDATION_ERROR,VALIDATION,valid email is required\n", string(csv))
+		assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+	})
+
+	t.Run("shows imports pending review with their plans and takes decisions", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		s := New(Config{EnableDashboard: true, Jobs: queue, RequireApproval: true}, func(string) pipeline.LeadProcessor {
+			return planningCreator{writes: &atomic.Int32{}}
+		})
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.StartJobs(ctx)
+		job, _ := queue.Enqueue(strings.NewReader("Name,Email,Company,Source\nAlice Johnson,alice@example.com,Acme Inc,LinkedIn\n"), "webinar.csv", 0, "marketing")
+		waitForJob(t, queue, job.ID, jobs.StatePendingReview)
+
+		// Act
+		page, pageErr := http.Get(server.URL + "/dashboard")
+		decision, decisionErr := http.PostForm(server.URL+"/dashboard/imports/"+job.ID+"/review", url.Values{"decision": {"rejected"}, "comment": {"wrong list"}})
+
+		// Assert
+		assert.NoError(t, pageErr)
+		html, _ := io.ReadAll(page.Body)
+		assert.Contains(t, string(html), "Pending review")
+		assert.Contains(t, string(html), `action="/dashboard/imports/`+job.ID+`/review"`)
+		assert.NoError(t, decisionErr)
+		assert.Equal(t, http.StatusOK, decision.StatusCode, "redirected back to the dashboard")
+		rejected, _ := queue.Get(job.ID)
+		assert.Equal(t, jobs.StateRejected, rejected.State)
+		assert.Equal(t, "wrong list", rejected.Review.Comment)
+	})
+
+	t.Run("refuses decisions posted from another site", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		s := New(Config{EnableDashboard: true, Jobs: queue, RequireApproval: true}, func(string) pipeline.LeadProcessor {
+			return planningCreator{writes: &atomic.Int32{}}
+		})
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.StartJobs(ctx)
+		job, _ := queue.Enqueue(strings.NewReader("Name,Email,Company,Source\nAlice Johnson,alice@example.com,Acme Inc,LinkedIn\n"), "webinar.csv", 0, "marketing")
+		waitForJob(t, queue, job.ID, jobs.StatePendingReview)
+		post := func(header, value string) *http.Response {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/dashboard/imports/"+job.ID+"/review", strings.NewReader("decision=approved"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set(header, value)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+
+		// Act
+		foreignOrigin := post("Origin", "https://attacker.example")
+		crossSiteFetch := post("Sec-Fetch-Site", "cross-site")
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, foreignOrigin.StatusCode)
+		assert.Equal(t, http.StatusForbidden, crossSiteFetch.StatusCode)
+		pending, _ := queue.Get(job.ID)
+		assert.Equal(t, jobs.StatePendingReview, pending.State)
+	})
+}
+
+func TestBreakdown(t *testing.T) {
+	t.Run("counts failures by code, most common first", func(t *testing.T) {
+		// Act
+		codes := breakdown([]ImportFailure{{Code: "API"}, {Code: "VALIDATION"}, {Code: "API"}, {Action: "ERROR"}})
+
+		// Assert
+		assert.Equal(t, []codeCount{{"API", 2}, {"ERROR", 1}, {"VALIDATION", 1}}, codes)
+	})
+}
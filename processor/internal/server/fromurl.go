@@ -0,0 +1,224 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/jobs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/google/uuid"
+)
+
+// maxURLRequestBytes bounds the JSON body of POST /imports/from-url
+const maxURLRequestBytes = 64 << 10
+
+// maxURLRedirects bounds the redirects followed fetching a URL import
+const maxURLRedirects = 5
+
+// DefaultURLHosts are the hosts URL imports are fetched from by default: S3,
+// global and regional, and GCS
+var DefaultURLHosts = []string{"s3.amazonaws.com", "s3.*.amazonaws.com", "storage.googleapis.com"}
+
+// urlImportRequest is the body of POST /imports/from-url
+type urlImportRequest struct {
+	// URL is a pre-signed S3 or GCS URL, or any URL on an allowed host,
+	// the file is fetched from
+	URL      string `json:"url"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// handleURLImport imports a file the server fetches itself, so large files
+// don't pass through the caller's connection. With a job queue the URL is
+// queued and fetched when the job runs, before it might expire; otherwise the
+// file is fetched and processed during the request.
+func (s *Server) handleURLImport(w http.ResponseWriter, r *http.Request) {
+	var req urlImportRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxURLRequestBytes)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	source, err := s.checkImportURL(req.URL)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if req.Name == "" {
+		req.Name = path.Base(source.Path)
+	}
+
+	caller, _ := auth.CallerFrom(r.Context())
+	if s.cfg.Jobs == nil {
+		done, err := s.tenants.admit(caller.Name)
+		if err != nil {
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+			return
+		}
+		defer done()
+
+		id := uuid.NewString()
+		w.Header().Set(auth.ImportIDHeader, id)
+		summary, err := s.runURLImport(r.Context(), req.URL, req.Name, id, caller.Name)
+		if code, ok := unprocessable(err); ok {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error(), "code": string(code)})
+			return
+		}
+		if err != nil {
+			log.Printf("Import from %s failed: %v", redactURL(req.URL), err)
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, summary)
+		return
+	}
+
+	job, err := s.cfg.Jobs.EnqueueURL(req.URL, req.Name, req.Priority, caller.Name)
+	if err != nil {
+		log.Printf("Queueing import failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to queue import"})
+		return
+	}
+	w.Header().Set("Location", "/imports/"+job.ID)
+	w.Header().Set(auth.ImportIDHeader, job.ID)
+	writeJSON(w, http.StatusAccepted, redactJob(job))
+}
+
+// runURLImport fetches the file at rawURL and imports it
+func (s *Server) runURLImport(ctx context.Context, rawURL, name, id, tenant string) (*ImportSummary, error) {
+	body, err := s.fetch(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return s.runImport(ctx, body, redactURL(rawURL), name, id, tenant)
+}
+
+// fetch opens the file at rawURL. Every redirect is checked like the URL
+// itself. Errors name the URL without its query, which holds a pre-signed
+// URL's signature.
+func (s *Server) fetch(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	client := http.Client{}
+	if s.cfg.FetchClient != nil {
+		client = *s.cfg.FetchClient
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxURLRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxURLRedirects)
+		}
+		if _, err := s.checkImportURL(req.URL.String()); err != nil {
+			return fmt.Errorf("redirected: %w", err)
+		}
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", redactURL(rawURL), err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The client's error quotes the whole URL; keep only its cause
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to fetch %s: %w", redactURL(rawURL), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: status %d", redactURL(rawURL), resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// checkImportURL accepts https URLs on the allowed hosts, so the server
+// can't be pointed at internal services. The addresses the hosts resolve to
+// are checked when dialling, by DialPublic.
+func (s *Server) checkImportURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("url must be an https URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range s.cfg.URLHosts {
+		if hostAllowed(host, allowed) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("host %s is not allowed (allowed: %s)", host, strings.Join(s.cfg.URLHosts, ", "))
+}
+
+// hostAllowed reports whether host is allowed, or a subdomain of it. A *
+// label in allowed matches any one label, e.g. an S3 region.
+func hostAllowed(host, allowed string) bool {
+	want := strings.Split(strings.ToLower(strings.TrimPrefix(allowed, ".")), ".")
+	got := strings.Split(host, ".")
+	if len(got) < len(want) {
+		return false
+	}
+	got = got[len(got)-len(want):]
+	for i, label := range want {
+		if label != "*" && label != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// publicDialer refuses to connect to addresses that aren't public
+var publicDialer = net.Dialer{
+	Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !publicIP(ip) {
+			return fmt.Errorf("address %s is not public", host)
+		}
+		return nil
+	},
+}
+
+// DialPublic dials addr unless it resolves to a loopback, private,
+// link-local or otherwise internal address, such as a cloud metadata
+// endpoint. Checking the address dialled, rather than the URL, holds for
+// redirects and for names resolving to internal addresses.
+func DialPublic(ctx context.Context, network, addr string) (net.Conn, error) {
+	return publicDialer.DialContext(ctx, network, addr)
+}
+
+// publicIP reports whether ip is a public unicast address
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// redactURL drops the query and credentials of a URL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// redactJob hides the signature of a job's URL before it is shown
+func redactJob(job jobs.Job) jobs.Job {
+	if job.URL != "" {
+		job.URL = redactURL(job.URL)
+	}
+	return job
+}
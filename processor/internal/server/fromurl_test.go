@@ -0,0 +1,198 @@
+package server
+
+import (
+	"code/internal/jobs"
+	"code/internal/pipeline"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBucket serves leads.csv over https to requests signed with sig=secret,
+// like a pre-signed URL
+func newBucket(t *testing.T) *httptest.Server {
+	bucket := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sig") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("Name,Email,Company,Source\nAlice Johnson,alice@example.com,Acme Inc,LinkedIn\n"))
+	}))
+	t.Cleanup(bucket.Close)
+	return bucket
+}
+
+func postURLImport(t *testing.T, serverURL, body string) *http.Response {
+	resp, err := http.Post(serverURL+"/imports/from-url", "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestServer_URLImports(t *testing.T) {
+	t.Run("fetches and imports the file during the request", func(t *testing.T) {
+		// Arrange
+		bucket := newBucket(t)
+		server := newTestServer(Config{URLHosts: []string{"127.0.0.1"}, FetchClient: bucket.Client()})
+		defer server.Close()
+
+		// Act
+		resp := postURLImport(t, server.URL, `{"url": "`+bucket.URL+`/leads.csv?sig=secret"}`)
+		defer resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var summary ImportSummary
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+		assert.Equal(t, 1, summary.Created)
+	})
+
+	t.Run("queues the URL and keeps its signature out of listings", func(t *testing.T) {
+		// Arrange
+		bucket := newBucket(t)
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		s := New(Config{Jobs: queue, URLHosts: []string{"127.0.0.1"}, FetchClient: bucket.Client()}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Act
+		resp := postURLImport(t, server.URL, `{"url": "`+bucket.URL+`/2026/leads.csv?sig=secret", "priority": 3}`)
+		var queued jobs.Job
+		json.NewDecoder(resp.Body).Decode(&queued)
+		resp.Body.Close()
+		s.StartJobs(ctx)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "leads.csv", queued.Name)
+		assert.Equal(t, 3, queued.Priority)
+		assert.Equal(t, bucket.URL+"/2026/leads.csv", queued.URL)
+		assert.Eventually(t, func() bool {
+			job, _ := queue.Get(queued.ID)
+			return job.State == jobs.StateCompleted
+		}, 5*time.Second, 10*time.Millisecond)
+
+		listResp, err := http.Get(server.URL + "/imports")
+		assert.NoError(t, err)
+		defer listResp.Body.Close()
+		var list struct{ Imports []jobs.Job }
+		json.NewDecoder(listResp.Body).Decode(&list)
+		assert.Len(t, list.Imports, 1)
+		assert.NotContains(t, list.Imports[0].URL, "secret")
+	})
+
+	t.Run("fails the job without leaking the signature when the fetch is refused", func(t *testing.T) {
+		// Arrange
+		bucket := newBucket(t)
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		s := New(Config{Jobs: queue, URLHosts: []string{"127.0.0.1"}, FetchClient: bucket.Client()}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Act
+		resp := postURLImport(t, server.URL, `{"url": "`+bucket.URL+`/leads.csv?sig=expired"}`)
+		var queued jobs.Job
+		json.NewDecoder(resp.Body).Decode(&queued)
+		resp.Body.Close()
+		s.StartJobs(ctx)
+
+		// Assert
+		var job jobs.Job
+		assert.Eventually(t, func() bool {
+			job, _ = queue.Get(queued.ID)
+			return job.State == jobs.StateFailed
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Contains(t, job.Error, "status 403")
+		assert.NotContains(t, job.Error, "expired")
+	})
+
+	t.Run("refuses hosts that aren't allowed", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{URLHosts: DefaultURLHosts})
+		defer server.Close()
+
+		// Act
+		internal := postURLImport(t, server.URL, `{"url": "https://169.254.169.254/latest/meta-data"}`)
+		lookalike := postURLImport(t, server.URL, `{"url": "https://evil-amazonaws.com/leads.csv"}`)
+		otherService := postURLImport(t, server.URL, `{"url": "https://lambda-url.us-east-1.on.aws.amazonaws.com/leads.csv"}`)
+		plain := postURLImport(t, server.URL, `{"url": "http://leads.s3.amazonaws.com/leads.csv"}`)
+		scheme := postURLImport(t, server.URL, `{"url": "file:///etc/passwd"}`)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, internal.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, lookalike.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, otherService.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, plain.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, scheme.StatusCode)
+	})
+
+	t.Run("refuses redirects to hosts that aren't allowed", func(t *testing.T) {
+		// Arrange
+		bucket := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://169.254.169.254/latest/meta-data", http.StatusFound)
+		}))
+		defer bucket.Close()
+		server := newTestServer(Config{URLHosts: []string{"127.0.0.1"}, FetchClient: bucket.Client()})
+		defer server.Close()
+
+		// Act
+		resp := postURLImport(t, server.URL, `{"url": "`+bucket.URL+`/leads.csv?sig=secret"}`)
+		defer resp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Contains(t, body["error"], "host 169.254.169.254 is not allowed")
+		assert.NotContains(t, body["error"], "secret")
+	})
+}
+
+func TestHostAllowed(t *testing.T) {
+	t.Run("matches the host, its subdomains and * labels", func(t *testing.T) {
+		// Assert
+		assert.True(t, hostAllowed("s3.amazonaws.com", "s3.amazonaws.com"))
+		assert.True(t, hostAllowed("leads.s3.amazonaws.com", "s3.amazonaws.com"))
+		assert.True(t, hostAllowed("leads.s3.eu-west-1.amazonaws.com", "s3.*.amazonaws.com"))
+		assert.True(t, hostAllowed("storage.googleapis.com", ".storage.googleapis.com"))
+		assert.False(t, hostAllowed("ec2.eu-west-1.amazonaws.com", "s3.*.amazonaws.com"))
+		assert.False(t, hostAllowed("amazonaws.com", "s3.amazonaws.com"))
+		assert.False(t, hostAllowed("evil-s3.amazonaws.com", "s3.amazonaws.com"))
+	})
+}
+
+func TestDialPublic(t *testing.T) {
+	t.Run("refuses internal addresses", func(t *testing.T) {
+		// Arrange
+		bucket := newBucket(t)
+		transport := bucket.Client().Transport.(*http.Transport).Clone()
+		transport.DialContext = DialPublic
+		client := &http.Client{Transport: transport}
+
+		// Act
+		_, err := client.Get(bucket.URL + "/leads.csv?sig=secret")
+
+		// Assert
+		assert.ErrorContains(t, err, "address 127.0.0.1 is not public")
+	})
+
+	t.Run("tells public addresses from internal ones", func(t *testing.T) {
+		// Assert
+		assert.True(t, publicIP(net.ParseIP("52.216.0.1")))
+		for _, ip := range []string{"127.0.0.1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254", "::1", "fe80::1", "fd00::1", "0.0.0.0"} {
+			assert.False(t, publicIP(net.ParseIP(ip)), ip)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxFinishedImports is how many finished imports the dashboard remembers
+const maxFinishedImports = 100
+
+// importProgress is an import this server ran or is running
+type importProgress struct {
+	ID         string
+	Input      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Summary    ImportSummary
+	Error      string
+}
+
+// Running reports whether the import is still in progress
+func (p importProgress) Running() bool {
+	return p.FinishedAt.IsZero()
+}
+
+// tracker follows the imports of this process, so the dashboard can show
+// them live. Finished imports are kept until maxFinishedImports newer ones
+// finish.
+type tracker struct {
+	mu       sync.Mutex
+	imports  map[string]*importProgress
+	finished []string
+}
+
+func newTracker() *tracker {
+	return &tracker{imports: make(map[string]*importProgress)}
+}
+
+func (t *tracker) start(id, input string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.imports[id] = &importProgress{ID: id, Input: input, StartedAt: time.Now().UTC()}
+}
+
+// update copies the summary so far
+func (t *tracker) update(id string, summary *ImportSummary) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if progress, ok := t.imports[id]; ok {
+		progress.Summary = *summary
+	}
+}
+
+func (t *tracker) finish(id string, summary *ImportSummary, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress, ok := t.imports[id]
+	if !ok {
+		return
+	}
+	progress.Summary = *summary
+	progress.FinishedAt = time.Now().UTC()
+	if err != nil {
+		progress.Error = err.Error()
+	}
+
+	t.finished = append(t.finished, id)
+	if len(t.finished) > maxFinishedImports {
+		delete(t.imports, t.finished[0])
+		t.finished = t.finished[1:]
+	}
+}
+
+func (t *tracker) get(id string) (importProgress, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress, ok := t.imports[id]
+	if !ok {
+		return importProgress{}, false
+	}
+	return *progress, true
+}
+
+// list returns the imports, most recently started first
+func (t *tracker) list() []importProgress {
+	t.mu.Lock()
+	imports := make([]importProgress, 0, len(t.imports))
+	for _, progress := range t.imports {
+		imports = append(imports, *progress)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].StartedAt.After(imports[j].StartedAt)
+	})
+	return imports
+}
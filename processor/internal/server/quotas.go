@@ -0,0 +1,97 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/errcode"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// tenants enforces the quotas of the tokens imports are made with: how many
+// of a token's imports run at once, and how many leads they process per UTC
+// day. Queued imports are capped by the queue instead; the day's counts are
+// kept in memory, so a restart resets them.
+type tenants struct {
+	quotas map[string]auth.Quota
+	now    func() time.Time
+
+	mu     sync.Mutex
+	active map[string]int
+	day    string
+	leads  map[string]int
+}
+
+func newTenants(quotas map[string]auth.Quota) *tenants {
+	return &tenants{quotas: quotas, now: time.Now, active: make(map[string]int), leads: make(map[string]int)}
+}
+
+// admit starts an import of the tenant processed during its request,
+// refusing it while the tenant has as many running as it may or has used up
+// its daily leads. done is called when the import finishes.
+func (t *tenants) admit(tenant string) (done func(), err error) {
+	quota := t.quotas[tenant]
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if quota.MaxConcurrentImports > 0 && t.active[tenant] >= quota.MaxConcurrentImports {
+		return nil, fmt.Errorf("%s already has %d imports running, its most at once", tenant, quota.MaxConcurrentImports)
+	}
+	if quota.DailyLeads > 0 && t.leadsToday(tenant) >= quota.DailyLeads {
+		return nil, fmt.Errorf("%s has used up its quota of %d leads today", tenant, quota.DailyLeads)
+	}
+	t.active[tenant]++
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.active[tenant]--
+	}, nil
+}
+
+// takeLead counts a lead against the tenant's daily quota, failing with
+// errcode.ErrQuotaExceeded once the quota is used up
+func (t *tenants) takeLead(tenant string) error {
+	quota := t.quotas[tenant]
+	if quota.DailyLeads <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.leadsToday(tenant) >= quota.DailyLeads {
+		return errcode.Classify(errcode.ErrQuotaExceeded, fmt.Errorf("%s: %s has used up its quota of %d leads today", errcode.CodeQuotaExceeded, tenant, quota.DailyLeads))
+	}
+	t.leads[tenant]++
+	return nil
+}
+
+// leadsToday returns the leads the tenant processed this UTC day, starting
+// a new count each day; the caller holds t.mu
+func (t *tenants) leadsToday(tenant string) int {
+	if day := t.now().UTC().Format(time.DateOnly); day != t.day {
+		t.day = day
+		t.leads = make(map[string]int)
+	}
+	return t.leads[tenant]
+}
+
+// maxRunning returns the cap on the tenant's imports running at once; 0
+// leaves them uncapped
+func (t *tenants) maxRunning(tenant string) int {
+	return t.quotas[tenant].MaxConcurrentImports
+}
+
+// quotaProcessor defers the leads of a tenant past its daily lead quota
+type quotaProcessor struct {
+	next    pipeline.LeadProcessor
+	tenants *tenants
+	tenant  string
+}
+
+func (p quotaProcessor) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	if err := p.tenants.takeLead(p.tenant); err != nil {
+		return &processor.ProcessResult{Action: "DEFERRED", Lead: lead, Error: err}, nil
+	}
+	return p.next.ProcessLead(lead)
+}
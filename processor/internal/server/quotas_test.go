@@ -0,0 +1,97 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/errcode"
+	"code/internal/pipeline"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenants(t *testing.T) {
+	t.Run("caps a tenant's imports running at once", func(t *testing.T) {
+		// Arrange
+		tenants := newTenants(map[string]auth.Quota{"emea": {MaxConcurrentImports: 1}})
+
+		// Act
+		done, err := tenants.admit("emea")
+		_, busyErr := tenants.admit("emea")
+		_, otherErr := tenants.admit("us")
+		done()
+		_, afterErr := tenants.admit("emea")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.EqualError(t, busyErr, "emea already has 1 imports running, its most at once")
+		assert.NoError(t, otherErr, "other tenants are uncapped")
+		assert.NoError(t, afterErr)
+	})
+
+	t.Run("counts leads against the daily quota, starting again each day", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+		tenants := newTenants(map[string]auth.Quota{"emea": {DailyLeads: 2}})
+		tenants.now = func() time.Time { return now }
+
+		// Act
+		first := tenants.takeLead("emea")
+		second := tenants.takeLead("emea")
+		over := tenants.takeLead("emea")
+		_, admitErr := tenants.admit("emea")
+		now = now.Add(2 * time.Hour)
+		nextDay := tenants.takeLead("emea")
+
+		// Assert
+		assert.NoError(t, first)
+		assert.NoError(t, second)
+		assert.ErrorIs(t, over, errcode.ErrQuotaExceeded)
+		assert.EqualError(t, admitErr, "emea has used up its quota of 2 leads today")
+		assert.NoError(t, nextDay)
+	})
+}
+
+func TestServer_Quotas(t *testing.T) {
+	t.Run("defers the leads of an import past its token's daily quota", func(t *testing.T) {
+		// Arrange
+		authenticator, _ := auth.New(auth.Config{Tokens: []auth.Token{{Name: "emea", Token: "up", Role: auth.RoleUploader}}}, nil)
+		var tenants []string
+		s := New(Config{Auth: authenticator, Quotas: map[string]auth.Quota{"emea": {DailyLeads: 1}}}, func(tenant string) pipeline.LeadProcessor {
+			tenants = append(tenants, tenant)
+			return createAllProcessor{}
+		})
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		post := func() *http.Response {
+			body := "Name,Email,Company,Source\n" +
+				"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n" +
+				"Bob Smith,bob@startup.com,Startup Co,Webinar\n"
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/imports", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer up")
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			return resp
+		}
+
+		// Act
+		first := post()
+		defer first.Body.Close()
+		second := post()
+		second.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, first.StatusCode)
+		var summary ImportSummary
+		assert.NoError(t, json.NewDecoder(first.Body).Decode(&summary))
+		assert.Equal(t, 1, summary.Created)
+		assert.Equal(t, "DEFERRED", summary.Failures[0].Action)
+		assert.Equal(t, string(errcode.CodeQuotaExceeded), summary.Failures[0].Code)
+		assert.Equal(t, http.StatusTooManyRequests, second.StatusCode, "no import starts once the quota is used up")
+		assert.Equal(t, []string{"emea"}, tenants)
+	})
+}
@@ -0,0 +1,576 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"code/internal/auth"
+	"code/internal/csv"
+	"code/internal/deadletter"
+	"code/internal/errcode"
+	"code/internal/jobs"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/stats"
+	"code/internal/upload"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config controls the HTTP server
+type Config struct {
+	Addr        string
+	EnablePprof bool
+	Workers     int
+	// Mapping names the CSV column to read for lead fields, see csv.CSVReader.SetMapping
+	Mapping map[string]string
+	// NewID makes the IDs of leads read from uploads; nil means random UUIDs
+	NewID models.IDGenerator
+	// Transforms rewrite each lead before validation, e.g. to infer its source
+	Transforms []pipeline.Transform
+	// Validation adjusts how leads are validated, see models.ValidationOptions
+	Validation models.ValidationOptions
+	// TypeformSecret, when set, is the secret Typeform signs webhook payloads with
+	TypeformSecret string
+	// FormsToken, when set, is the bearer token Google Forms submissions must carry
+	FormsToken string
+	// DeadLetters, when set, receives the leads that fail for good
+	DeadLetters *deadletter.Writer
+	// Jobs, when set, queues uploads to POST /imports instead of processing
+	// them during the request, and lists them on GET /imports
+	Jobs *jobs.Queue
+	// JobWorkers is how many queued imports run at once
+	JobWorkers int
+	// Uploads, when set with Jobs, takes resumable tus uploads on /uploads,
+	// queueing each as an import once complete
+	Uploads *upload.Store
+	// URLHosts are the hosts POST /imports/from-url fetches files from; a
+	// leading dot or none matches subdomains too, and a * label any one label
+	URLHosts []string
+	// FetchClient fetches the files of URL imports; dialling with DialPublic
+	// keeps it off internal addresses. Its CheckRedirect is replaced.
+	FetchClient *http.Client
+	// MaxUploadSize caps a resumable upload and the body of POST /imports, in
+	// bytes; 0 leaves them uncapped
+	MaxUploadSize int64
+	// UploadExpiry is how long an upload may take before it is dropped; 0
+	// keeps uploads until they are finished or deleted
+	UploadExpiry time.Duration
+	// EnableDashboard serves the web dashboard on /dashboard
+	EnableDashboard bool
+	// DetectProfile, when set, picks the profile each import is read with
+	// from its file's name and header line; files it finds none for fail
+	// with UNMATCHED_PROFILE
+	DetectProfile ProfileDetector
+	// Auth, when it has tokens, restricts every endpoint but the health
+	// check and the form webhooks to callers with the right role
+	Auth *auth.Authenticator
+	// RequireApproval, with Jobs, holds each queued import for review: it is
+	// planned without writing, then runs only once a reviewer approves it
+	RequireApproval bool
+	// Quotas cap the imports of each token, by its name
+	Quotas map[string]auth.Quota
+}
+
+// Profile is how the files of one partner are read
+type Profile struct {
+	Name string
+	// Comma separates the columns; 0 keeps the comma
+	Comma rune
+	// Mapping replaces Config.Mapping for the partner's files
+	Mapping map[string]string
+	// Transforms replace Config.Transforms for the partner's files
+	Transforms []pipeline.Transform
+	// Schema, when set, is the exact header the partner's files have; the
+	// summary lists the differences of a file that doesn't
+	Schema []string
+	// StrictSchema fails files that differ from Schema with SCHEMA_MISMATCH
+	StrictSchema bool
+}
+
+// ProfileDetector picks the profile of a file from its name, which may be
+// empty, and its header line, reporting false when none matches
+type ProfileDetector func(filename, headerLine string) (Profile, bool)
+
+// maxHeaderLine is how much of a file is read to find its header line
+const maxHeaderLine = 64 << 10
+
+// ProcessorFactory builds the lead processor used for one import. tenant
+// names the token the import was made with; it is empty for form
+// submissions and on a server without tokens.
+type ProcessorFactory func(tenant string) pipeline.LeadProcessor
+
+// ImportFailure describes a lead that could not be processed
+type ImportFailure struct {
+	Index  int    `json:"index"`
+	Email  string `json:"email"`
+	Action string `json:"action"`
+	Error  string `json:"error"`
+	Code   string `json:"code,omitempty"`
+}
+
+// ImportSummary is the response body of POST /imports
+type ImportSummary struct {
+	Total    int             `json:"total"`
+	Created  int             `json:"created"`
+	Updated  int             `json:"updated"`
+	Skipped  int             `json:"skipped"`
+	Errors   int             `json:"errors"`
+	Failures []ImportFailure `json:"failures,omitempty"`
+	// SyncErrors counts written leads a secondary sink failed to receive
+	SyncErrors int `json:"syncErrors,omitempty"`
+	// Profile names the import profile the file was read with
+	Profile string `json:"profile,omitempty"`
+	// SchemaChanges describe how the file's header differs from the
+	// profile's schema
+	SchemaChanges []string `json:"schemaChanges,omitempty"`
+
+	counts *stats.Stats
+}
+
+// newImportSummary creates an empty summary
+func newImportSummary() *ImportSummary {
+	return &ImportSummary{counts: stats.New()}
+}
+
+// Server exposes lead processing over HTTP
+type Server struct {
+	cfg          Config
+	newProcessor ProcessorFactory
+	mux          *http.ServeMux
+	progress     *tracker
+	tenants      *tenants
+	// stats totals the leads of every import since the server started
+	stats *stats.Stats
+}
+
+// New creates a server that processes uploaded CSV files
+func New(cfg Config, newProcessor ProcessorFactory) *Server {
+	s := &Server{
+		cfg:          cfg,
+		newProcessor: newProcessor,
+		mux:          http.NewServeMux(),
+		progress:     newTracker(),
+		tenants:      newTenants(cfg.Quotas),
+		stats:        stats.New(),
+	}
+
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /stats", cfg.Auth.Require(auth.RoleViewer, s.handleStats))
+	if cfg.Jobs != nil {
+		if len(cfg.Quotas) > 0 {
+			cfg.Jobs.SetMaxRunning(s.tenants.maxRunning)
+		}
+		s.mux.HandleFunc("POST /imports", cfg.Auth.Require(auth.RoleUploader, s.handleQueueImport))
+		s.mux.HandleFunc("GET /imports", cfg.Auth.Require(auth.RoleViewer, s.handleListImports))
+		s.mux.HandleFunc("GET /imports/{id}", cfg.Auth.Require(auth.RoleViewer, s.handleGetImport))
+		if cfg.RequireApproval {
+			cfg.Jobs.RequireApproval(s.planImport)
+			s.mux.HandleFunc("POST /imports/{id}/approve", cfg.Auth.Require(auth.RoleReviewer, s.reviewHandler(jobs.DecisionApproved)))
+			s.mux.HandleFunc("POST /imports/{id}/reject", cfg.Auth.Require(auth.RoleReviewer, s.reviewHandler(jobs.DecisionRejected)))
+		}
+	} else {
+		s.mux.HandleFunc("POST /imports", cfg.Auth.Require(auth.RoleUploader, s.handleImport))
+	}
+	s.mux.HandleFunc("POST /imports/from-url", cfg.Auth.Require(auth.RoleUploader, s.handleURLImport))
+	if cfg.Jobs != nil && cfg.Uploads != nil {
+		s.mux.HandleFunc("OPTIONS /uploads", s.handleUploadOptions)
+		s.mux.HandleFunc("POST /uploads", cfg.Auth.Require(auth.RoleUploader, requireTus(s.handleCreateUpload)))
+		s.mux.HandleFunc("HEAD /uploads/{id}", cfg.Auth.Require(auth.RoleUploader, requireTus(s.handleUploadOffset)))
+		s.mux.HandleFunc("PATCH /uploads/{id}", cfg.Auth.Require(auth.RoleUploader, requireTus(s.handleUploadChunk)))
+		s.mux.HandleFunc("DELETE /uploads/{id}", cfg.Auth.Require(auth.RoleUploader, requireTus(s.handleDeleteUpload)))
+	}
+
+	var verifyTypeformRequest, verifyFormsRequest func(*http.Request, []byte) bool
+	if cfg.TypeformSecret != "" {
+		verifyTypeformRequest = func(r *http.Request, body []byte) bool {
+			return verifyTypeform(r, body, cfg.TypeformSecret)
+		}
+	}
+	if cfg.FormsToken != "" {
+		verifyFormsRequest = func(r *http.Request, _ []byte) bool {
+			return verifyToken(r, cfg.FormsToken)
+		}
+	}
+	s.mux.HandleFunc("POST /webhooks/typeform", s.webhookHandler("Typeform", verifyTypeformRequest, parseTypeform))
+	s.mux.HandleFunc("POST /webhooks/google-forms", s.webhookHandler("Google Forms", verifyFormsRequest, parseGoogleForms))
+
+	if cfg.EnableDashboard {
+		s.mux.HandleFunc("GET /dashboard", cfg.Auth.Require(auth.RoleViewer, s.handleDashboard))
+		s.mux.HandleFunc("GET /dashboard/imports/{id}/summary.json", cfg.Auth.Require(auth.RoleViewer, s.handleSummaryDownload))
+		s.mux.HandleFunc("GET /dashboard/imports/{id}/rejects.csv", cfg.Auth.Require(auth.RoleViewer, s.handleRejectsDownload))
+		if cfg.Jobs != nil && cfg.RequireApproval {
+			s.mux.HandleFunc("POST /dashboard/imports/{id}/review", cfg.Auth.Require(auth.RoleReviewer, s.handleDashboardReview))
+		}
+	}
+
+	if cfg.EnablePprof {
+		s.mux.HandleFunc("/debug/pprof/", cfg.Auth.Require(auth.RoleAdmin, pprof.Index))
+		s.mux.HandleFunc("/debug/pprof/cmdline", cfg.Auth.Require(auth.RoleAdmin, pprof.Cmdline))
+		s.mux.HandleFunc("/debug/pprof/profile", cfg.Auth.Require(auth.RoleAdmin, pprof.Profile))
+		s.mux.HandleFunc("/debug/pprof/symbol", cfg.Auth.Require(auth.RoleAdmin, pprof.Symbol))
+		s.mux.HandleFunc("/debug/pprof/trace", cfg.Auth.Require(auth.RoleAdmin, pprof.Trace))
+	}
+
+	return s
+}
+
+// Handler returns the server's HTTP handler
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// readHeaderTimeout bounds how long a client may take to send its request
+// headers, and idleTimeout how long a kept-alive connection waits for the
+// next request. Bodies aren't bounded by time, as a large import may take a
+// while to upload; MaxUploadSize bounds their size instead.
+const (
+	readHeaderTimeout = 10 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// ListenAndServe serves HTTP on the configured address, running queued
+// imports alongside
+func (s *Server) ListenAndServe() error {
+	s.StartJobs(context.Background())
+	server := &http.Server{
+		Addr:              s.cfg.Addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	return server.ListenAndServe()
+}
+
+// limitUpload caps the request body at MaxUploadSize, if set
+func (s *Server) limitUpload(w http.ResponseWriter, r *http.Request) io.Reader {
+	if s.cfg.MaxUploadSize > 0 {
+		return http.MaxBytesReader(w, r.Body, s.cfg.MaxUploadSize)
+	}
+	return r.Body
+}
+
+// writeTooLarge answers 413 if err comes from a body over MaxUploadSize
+func writeTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("uploads are limited to %d bytes", tooLarge.Limit)})
+	return true
+}
+
+// StartJobs runs queued imports until ctx is done; it does nothing without
+// a job queue
+func (s *Server) StartJobs(ctx context.Context) {
+	if s.cfg.Jobs == nil {
+		return
+	}
+	s.cfg.Jobs.Start(ctx, s.cfg.JobWorkers, func(ctx context.Context, job jobs.Job, upload io.Reader) (interface{}, error) {
+		if job.URL != "" {
+			return s.runURLImport(ctx, job.URL, job.Name, job.ID, job.SubmittedBy)
+		}
+		return s.runImport(ctx, upload, "/imports/"+job.ID, job.Name, job.ID, job.SubmittedBy)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+}
+
+// handleStats answers with the counts of every lead imported since the
+// server started, per action and error class, and their timings
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.stats.Summary())
+}
+
+// handleImport processes a CSV upload synchronously and returns a summary.
+// ?name= gives the file's name, which may pick its profile.
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	caller, _ := auth.CallerFrom(r.Context())
+	done, err := s.tenants.admit(caller.Name)
+	if err != nil {
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		return
+	}
+	defer done()
+
+	id := uuid.NewString()
+	w.Header().Set(auth.ImportIDHeader, id)
+	summary, err := s.runImport(r.Context(), s.limitUpload(w, r), r.URL.Path, r.URL.Query().Get("name"), id, caller.Name)
+	if writeTooLarge(w, err) {
+		return
+	}
+	if code, ok := unprocessable(err); ok {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error(), "code": string(code)})
+		return
+	}
+	if err != nil {
+		log.Printf("Import failed: %v", err)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// runImport processes a CSV file, dead-lettering the leads that fail for
+// good and reporting its progress under id. The summary counts the leads read
+// before any CSV error. name is the file's name, if known, and tenant the
+// token it was made with, whose daily lead quota applies.
+func (s *Server) runImport(ctx context.Context, body io.Reader, input, name, id, tenant string) (summary *ImportSummary, err error) {
+	s.progress.start(id, input)
+	defer func() { s.progress.finish(id, summary, err) }()
+
+	var failed []*pipeline.Item
+	var leadProcessor pipeline.LeadProcessor = s.newProcessor(tenant)
+	if s.cfg.Quotas[tenant].DailyLeads > 0 {
+		leadProcessor = quotaProcessor{next: leadProcessor, tenants: s.tenants, tenant: tenant}
+	}
+	summary, failed, err = s.processFile(ctx, body, name, leadProcessor, s.stats, func(summary *ImportSummary) {
+		s.progress.update(id, summary)
+	})
+	s.deadLetter(input, failed)
+	return summary, err
+}
+
+// processFile runs a CSV file through leadProcessor, reporting the summary
+// after each lead, and returns the failed items with the summary. Each lead
+// is also counted in totals, unless it is nil.
+func (s *Server) processFile(ctx context.Context, body io.Reader, name string, leadProcessor pipeline.LeadProcessor, totals *stats.Stats, progress func(*ImportSummary)) (*ImportSummary, []*pipeline.Item, error) {
+	summary := newImportSummary()
+	reader := csv.NewCSVReader()
+	reader.SetMapping(s.cfg.Mapping)
+	reader.SetIDGenerator(s.cfg.NewID)
+	transforms := s.cfg.Transforms
+	if s.cfg.DetectProfile != nil {
+		buffered := bufio.NewReaderSize(body, maxHeaderLine)
+		line := headerLine(buffered)
+		profile, ok := s.cfg.DetectProfile(name, line)
+		if name == "" {
+			name = "the file"
+		}
+		if !ok {
+			return summary, nil, errcode.Classify(errcode.ErrUnmatchedProfile, fmt.Errorf("%s: no profile matches %s", errcode.CodeUnmatchedProfile, name))
+		}
+		if len(profile.Schema) > 0 {
+			header, _ := csv.ParseHeader(line, profile.Comma)
+			if diff := csv.DiffHeader(profile.Schema, header); diff != nil {
+				if profile.StrictSchema {
+					return summary, nil, errcode.Classify(errcode.ErrSchemaMismatch, fmt.Errorf("%s: %s does not match the schema of profile %s: %s", errcode.CodeSchemaMismatch, name, profile.Name, diff))
+				}
+				summary.SchemaChanges = diff.Changes()
+			}
+		}
+		body = buffered
+		summary.Profile = profile.Name
+		reader.SetMapping(profile.Mapping)
+		reader.SetDelimiter(profile.Comma)
+		transforms = profile.Transforms
+	}
+	leadPipeline := pipeline.New(leadProcessor, pipeline.Config{
+		Workers:    s.cfg.Workers,
+		Transforms: transforms,
+		Validation: s.cfg.Validation,
+	})
+
+	items := leadPipeline.Run(ctx, func(emit func(*models.Lead) error) error {
+		return reader.StreamLeadsFrom(body, emit)
+	})
+
+	var failed []*pipeline.Item
+	for item := range items {
+		if summary.add(item) {
+			failed = append(failed, item)
+		}
+		if totals != nil {
+			totals.Add(item)
+		}
+		progress(summary)
+	}
+
+	if err := leadPipeline.Err(); err != nil {
+		return summary, failed, fmt.Errorf("invalid CSV: %w", err)
+	}
+	return summary, failed, nil
+}
+
+// unprocessable returns the code of an import failed for its file rather
+// than its leads, such as one no profile matches
+func unprocessable(err error) (errcode.Code, bool) {
+	switch code := errcode.Of(err); code {
+	case errcode.CodeUnmatchedProfile, errcode.CodeSchemaMismatch:
+		return code, true
+	}
+	return "", false
+}
+
+// headerLine peeks at the first line of a file without consuming it
+func headerLine(r *bufio.Reader) string {
+	data, _ := r.Peek(maxHeaderLine)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSuffix(string(data), "\r")
+}
+
+// handleQueueImport stores a CSV upload and queues it, answering 202 with
+// the job. ?priority=N runs it before jobs of lower priority and ?name=
+// labels it.
+func (s *Server) handleQueueImport(w http.ResponseWriter, r *http.Request) {
+	priority := 0
+	if value := r.URL.Query().Get("priority"); value != "" {
+		var err error
+		if priority, err = strconv.Atoi(value); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid priority: " + value})
+			return
+		}
+	}
+
+	caller, _ := auth.CallerFrom(r.Context())
+	job, err := s.cfg.Jobs.Enqueue(s.limitUpload(w, r), r.URL.Query().Get("name"), priority, caller.Name)
+	if writeTooLarge(w, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Queueing import failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to queue import"})
+		return
+	}
+	w.Header().Set("Location", "/imports/"+job.ID)
+	w.Header().Set(auth.ImportIDHeader, job.ID)
+	writeJSON(w, http.StatusAccepted, redactJob(job))
+}
+
+// handleListImports lists queued imports, newest first, filtered by
+// ?state=, ?since= and ?until= (RFC 3339 times or dates) and ?limit=
+func (s *Server) handleListImports(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	imports := s.cfg.Jobs.List(filter)
+	for i := range imports {
+		imports[i] = redactJob(imports[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"imports": imports})
+}
+
+func (s *Server) handleGetImport(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.cfg.Jobs.Get(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "import not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, redactJob(job))
+}
+
+func parseFilter(r *http.Request) (jobs.Filter, error) {
+	query := r.URL.Query()
+	var filter jobs.Filter
+	var err error
+	if value := query.Get("state"); value != "" {
+		if filter.State, err = jobs.ParseState(value); err != nil {
+			return filter, err
+		}
+	}
+	if filter.Since, err = parseTime(query.Get("since")); err != nil {
+		return filter, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseTime(query.Get("until")); err != nil {
+		return filter, fmt.Errorf("invalid until: %w", err)
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+	return filter, nil
+}
+
+// parseTime reads an RFC 3339 time or a date, or returns the zero time for
+// an empty value
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// add counts one processed item and reports whether it failed. Anything not
+// created, updated or skipped, deferred leads included, is an error here.
+func (summary *ImportSummary) add(item *pipeline.Item) bool {
+	summary.counts.Add(item)
+	summary.Total = summary.counts.Total()
+	summary.Created = summary.counts.Count("CREATE")
+	summary.Updated = summary.counts.Count("UPDATE")
+	summary.Skipped = summary.counts.Count("SKIP")
+	summary.Errors = summary.Total - summary.Created - summary.Updated - summary.Skipped
+	summary.SyncErrors = summary.counts.SyncErrors()
+
+	if item.Err != nil {
+		summary.Failures = append(summary.Failures, ImportFailure{Index: item.Index, Email: item.Lead.Email, Action: stats.ActionError, Error: item.Err.Error(), Code: string(errcode.Of(item.Err))})
+		return true
+	}
+
+	if item.Result.SyncError != nil {
+		log.Printf("Sync failed for %s: %v", item.Lead.Email, item.Result.SyncError)
+	}
+
+	switch item.Result.Action {
+	case "CREATE", "UPDATE", "SKIP":
+	default:
+		failure := ImportFailure{Index: item.Index, Email: item.Lead.Email, Action: item.Result.Action}
+		if item.Result.Error != nil {
+			failure.Error = item.Result.Error.Error()
+			failure.Code = string(errcode.Of(item.Result.Error))
+		}
+		summary.Failures = append(summary.Failures, failure)
+		return true
+	}
+	return false
+}
+
+// deadLetter keeps the leads of failed items that failed for good. A lead
+// the client is told about is still kept, since an upload's response is
+// often only logged.
+func (s *Server) deadLetter(input string, failed []*pipeline.Item) {
+	if s.cfg.DeadLetters == nil || len(failed) == 0 {
+		return
+	}
+	for _, item := range failed {
+		action, err := "ERROR", item.Err
+		if err == nil {
+			action, err = item.Result.Action, item.Result.Error
+		}
+		if !deadletter.Terminal(action, err) {
+			continue
+		}
+		if err := s.cfg.DeadLetters.Write(deadletter.NewEntry(input, item.Index, item.Lead, action, err)); err != nil {
+			log.Printf("Failed to dead-letter %s: %v", item.Lead.Email, err)
+		}
+	}
+	if err := s.cfg.DeadLetters.Flush(); err != nil {
+		log.Printf("Failed to write dead-letter entries: %v", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
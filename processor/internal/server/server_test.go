@@ -0,0 +1,385 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/deadletter"
+	"code/internal/errcode"
+	"code/internal/jobs"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"code/internal/stats"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type createAllProcessor struct{}
+
+func (createAllProcessor) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	return &processor.ProcessResult{Action: "CREATE", Lead: lead}, nil
+}
+
+func newTestServer(cfg Config) *httptest.Server {
+	s := New(cfg, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+	return httptest.NewServer(s.Handler())
+}
+
+func TestServer_Imports(t *testing.T) {
+	t.Run("processes an uploaded CSV and returns a summary", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{})
+		defer server.Close()
+		body := "Name,Email,Company,Source\n" +
+			"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n" +
+			"Invalid User,invalid-email,Test Company,LinkedIn\n"
+
+		// Act
+		resp, err := http.Post(server.URL+"/imports", "text/csv", strings.NewReader(body))
+
+		// Assert
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var summary ImportSummary
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+		assert.Equal(t, 2, summary.Total)
+		assert.Equal(t, 1, summary.Created)
+		assert.Equal(t, 1, summary.Errors)
+		assert.Equal(t, "invalid-email", summary.Failures[0].Email)
+		assert.Equal(t, "VALIDATION", summary.Failures[0].Code)
+	})
+
+	t.Run("totals every import at /stats", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{})
+		defer server.Close()
+		body := "Name,Email,Company,Source\n" +
+			"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n" +
+			"Invalid User,invalid-email,Test Company,LinkedIn\n"
+		for range 2 {
+			resp, err := http.Post(server.URL+"/imports", "text/csv", strings.NewReader(body))
+			assert.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		// Act
+		resp, err := http.Get(server.URL + "/stats")
+
+		// Assert
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		var totals stats.Summary
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&totals))
+		assert.Equal(t, 4, totals.Total)
+		assert.Equal(t, 2, totals.Errors)
+		assert.Equal(t, map[string]int{"CREATE": 2, "VALIDATION_ERROR": 2}, totals.Actions)
+		assert.Equal(t, map[errcode.Code]int{errcode.CodeValidation: 2}, totals.Codes)
+		assert.Equal(t, 2, totals.Timings["CREATE"].Count)
+	})
+
+	t.Run("refuses uploads over the upload limit", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		body := "Name,Email,Company,Source\n" +
+			strings.Repeat("Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n", 100)
+
+		for name, cfg := range map[string]Config{
+			"processed": {MaxUploadSize: 1024},
+			"queued":    {MaxUploadSize: 1024, Jobs: queue},
+		} {
+			server := newTestServer(cfg)
+			defer server.Close()
+
+			// Act
+			resp, err := http.Post(server.URL+"/imports", "text/csv", strings.NewReader(body))
+
+			// Assert
+			assert.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, name)
+		}
+		assert.Empty(t, queue.List(jobs.Filter{}))
+	})
+}
+
+type failingProcessor struct{}
+
+func (failingProcessor) ProcessLead(lead *models.Lead) (*processor.ProcessResult, error) {
+	return &processor.ProcessResult{Action: "API_ERROR", Lead: lead, Error: &errcode.StatusError{Status: http.StatusInternalServerError}}, nil
+}
+
+func TestServer_DeadLetters(t *testing.T) {
+	t.Run("dead-letters leads the API failed", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "dead-letter.ndjson")
+		deadLetters, _ := deadletter.Open(path, "serve-1", nil)
+		defer deadLetters.Close()
+		s := New(Config{DeadLetters: deadLetters}, func(string) pipeline.LeadProcessor { return failingProcessor{} })
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		body := "Name,Email,Company,Source\n" +
+			"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n" +
+			"Invalid User,invalid-email,Test Company,LinkedIn\n"
+
+		// Act
+		resp, err := http.Post(server.URL+"/imports", "text/csv", strings.NewReader(body))
+
+		// Assert
+		assert.NoError(t, err)
+		resp.Body.Close()
+		data, _ := os.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		assert.Len(t, lines, 1)
+		var entry deadletter.Entry
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "/imports", entry.Input)
+		assert.Equal(t, "alice@example.com", entry.Lead.Email)
+		assert.Equal(t, "API_ERROR", entry.Action)
+		assert.Equal(t, "serve-1", entry.RunID)
+	})
+}
+
+func TestServer_Pprof(t *testing.T) {
+	t.Run("pprof is disabled by default", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{})
+		defer server.Close()
+
+		// Act
+		resp, err := http.Get(server.URL + "/debug/pprof/")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("pprof is served when enabled", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{EnablePprof: true})
+		defer server.Close()
+
+		// Act
+		resp, err := http.Get(server.URL + "/debug/pprof/")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestServer_Queue(t *testing.T) {
+	t.Run("queues an upload and reports on it", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		s := New(Config{Jobs: queue}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		body := "Name,Email,Company,Source\n" +
+			"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n"
+
+		// Act
+		resp, err := http.Post(server.URL+"/imports?priority=5&name=leads.csv", "text/csv", strings.NewReader(body))
+		assert.NoError(t, err)
+		var queued jobs.Job
+		json.NewDecoder(resp.Body).Decode(&queued)
+		resp.Body.Close()
+		s.StartJobs(ctx)
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "/imports/"+queued.ID, resp.Header.Get("Location"))
+		assert.Equal(t, 5, queued.Priority)
+		var job jobs.Job
+		assert.Eventually(t, func() bool {
+			resp, err := http.Get(server.URL + "/imports/" + queued.ID)
+			if err != nil {
+				return false
+			}
+			defer resp.Body.Close()
+			json.NewDecoder(resp.Body).Decode(&job)
+			return job.State == jobs.StateCompleted
+		}, 5*time.Second, 10*time.Millisecond)
+		var summary ImportSummary
+		assert.NoError(t, json.Unmarshal(job.Result, &summary))
+		assert.Equal(t, 1, summary.Created)
+
+		listResp, err := http.Get(server.URL + "/imports?state=completed&limit=10")
+		assert.NoError(t, err)
+		defer listResp.Body.Close()
+		var list struct{ Imports []jobs.Job }
+		json.NewDecoder(listResp.Body).Decode(&list)
+		assert.Len(t, list.Imports, 1)
+	})
+
+	t.Run("records who queued an upload and refuses viewers", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		authenticator, _ := auth.New(auth.Config{Tokens: []auth.Token{
+			{Name: "marketing", Token: "up", Role: auth.RoleUploader},
+			{Name: "analyst", Token: "view", Role: auth.RoleViewer},
+		}}, nil)
+		server := httptest.NewServer(New(Config{Jobs: queue, Auth: authenticator}, nil).Handler())
+		defer server.Close()
+		post := func(token string) *http.Response {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/imports", strings.NewReader("Name,Email\n"))
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+
+		// Act
+		accepted := post("up")
+		refused := post("view")
+
+		// Assert
+		assert.Equal(t, http.StatusAccepted, accepted.StatusCode)
+		assert.Equal(t, http.StatusForbidden, refused.StatusCode)
+		job, ok := queue.Get(accepted.Header.Get(auth.ImportIDHeader))
+		assert.True(t, ok)
+		assert.Equal(t, "marketing", job.SubmittedBy)
+	})
+
+	t.Run("rejects bad filters", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		server := httptest.NewServer(New(Config{Jobs: queue}, nil).Handler())
+		defer server.Close()
+
+		// Act
+		stateResp, _ := http.Get(server.URL + "/imports?state=done")
+		sinceResp, _ := http.Get(server.URL + "/imports?since=yesterday")
+		missingResp, _ := http.Get(server.URL + "/imports/unknown")
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, stateResp.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, sinceResp.StatusCode)
+		assert.Equal(t, http.StatusNotFound, missingResp.StatusCode)
+	})
+}
+
+func TestServer_DetectProfile(t *testing.T) {
+	detect := func(filename, headerLine string) (Profile, bool) {
+		if strings.HasPrefix(filename, "acme_") || strings.Contains(headerLine, "E-Mail") {
+			return Profile{Name: "acme", Comma: ';', Mapping: map[string]string{"email": "E-Mail"}}, true
+		}
+		return Profile{}, false
+	}
+
+	t.Run("reads the file with the profile it matches", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{DetectProfile: detect})
+		defer server.Close()
+		body := "Name;E-Mail;Company;Source\nJane Roe;jane@example.com;Acme;Website\n"
+
+		// Act
+		resp, err := http.Post(server.URL+"/imports", "text/csv", strings.NewReader(body))
+
+		// Assert
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var summary ImportSummary
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+		assert.Equal(t, "acme", summary.Profile)
+		assert.Equal(t, 1, summary.Created)
+	})
+
+	t.Run("fails files no profile matches", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{DetectProfile: detect})
+		defer server.Close()
+
+		// Act
+		resp, err := http.Post(server.URL+"/imports?name=drop.csv", "text/csv", strings.NewReader("Name,Email\n"))
+
+		// Assert
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		var body map[string]string
+		json.NewDecoder(resp.Body).Decode(&body)
+		assert.Equal(t, "UNMATCHED_PROFILE", body["code"])
+		assert.Contains(t, body["error"], "drop.csv")
+	})
+
+	t.Run("marks queued imports no profile matches", func(t *testing.T) {
+		// Arrange
+		store, _ := jobs.NewFileStore(t.TempDir())
+		queue, _ := jobs.Open(store)
+		s := New(Config{Jobs: queue, DetectProfile: detect}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// Act
+		matched, _ := queue.Enqueue(strings.NewReader("Name;E-Mail;Company;Source\nJane Roe;jane@example.com;Acme;Website\n"), "acme_2026-10.csv", 0, "")
+		unmatched, _ := queue.Enqueue(strings.NewReader("Name,Email\n"), "drop.csv", 0, "")
+		s.StartJobs(ctx)
+
+		// Assert
+		assert.Eventually(t, func() bool {
+			first, _ := queue.Get(matched.ID)
+			second, _ := queue.Get(unmatched.ID)
+			return first.State == jobs.StateCompleted && second.State == jobs.StateFailed
+		}, 5*time.Second, 10*time.Millisecond)
+		job, _ := queue.Get(unmatched.ID)
+		assert.Equal(t, "UNMATCHED_PROFILE", job.Code)
+		job, _ = queue.Get(matched.ID)
+		assert.Empty(t, job.Code)
+	})
+
+	t.Run("checks the header against the profile's schema", func(t *testing.T) {
+		// Arrange
+		schema := []string{"Name", "E-Mail", "Company", "Source"}
+		lenient := newTestServer(Config{DetectProfile: func(filename, headerLine string) (Profile, bool) {
+			profile, ok := detect(filename, headerLine)
+			profile.Schema = schema
+			return profile, ok
+		}})
+		defer lenient.Close()
+		strict := newTestServer(Config{DetectProfile: func(filename, headerLine string) (Profile, bool) {
+			profile, ok := detect(filename, headerLine)
+			profile.Schema, profile.StrictSchema = schema, true
+			return profile, ok
+		}})
+		defer strict.Close()
+		body := "Name;E-Mail;Company;Source;Phone\nJane Roe;jane@example.com;Acme;Website;555\n"
+
+		// Act
+		lenientResp, err := http.Post(lenient.URL+"/imports", "text/csv", strings.NewReader(body))
+		assert.NoError(t, err)
+		defer lenientResp.Body.Close()
+		strictResp, err := http.Post(strict.URL+"/imports?name=acme_2026-10.csv", "text/csv", strings.NewReader(body))
+		assert.NoError(t, err)
+		defer strictResp.Body.Close()
+
+		// Assert
+		assert.Equal(t, http.StatusOK, lenientResp.StatusCode)
+		var summary ImportSummary
+		assert.NoError(t, json.NewDecoder(lenientResp.Body).Decode(&summary))
+		assert.Equal(t, []string{`unexpected column "Phone" at 5`}, summary.SchemaChanges)
+		assert.Equal(t, 1, summary.Created)
+		assert.Equal(t, http.StatusUnprocessableEntity, strictResp.StatusCode)
+		var failure map[string]string
+		json.NewDecoder(strictResp.Body).Decode(&failure)
+		assert.Equal(t, "SCHEMA_MISMATCH", failure["code"])
+		assert.Contains(t, failure["error"], `acme_2026-10.csv does not match the schema of profile acme: unexpected column "Phone" at 5`)
+	})
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/upload"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tusVersion is the version of the tus resumable upload protocol served on
+// /uploads, so tus clients can send large files in chunks and resume them
+const tusVersion = "1.0.0"
+
+// statusChecksumMismatch is the status tus answers a chunk that doesn't
+// match its Upload-Checksum with
+const statusChecksumMismatch = 460
+
+// handleUploadOptions tells tus clients what the server supports
+func (s *Server) handleUploadOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", "creation,termination,checksum")
+	w.Header().Set("Tus-Checksum-Algorithm", "sha256")
+	if s.cfg.MaxUploadSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(s.cfg.MaxUploadSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateUpload starts an upload of Upload-Length bytes. Its
+// Upload-Metadata may give a filename, a priority for the import and the
+// sha256, in hex, of the whole file.
+func (s *Server) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		tusError(w, http.StatusBadRequest, "Upload-Length must be a positive number of bytes")
+		return
+	}
+	if s.cfg.MaxUploadSize > 0 && length > s.cfg.MaxUploadSize {
+		tusError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("uploads are limited to %d bytes", s.cfg.MaxUploadSize))
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err == nil && metadata["priority"] != "" {
+		_, err = strconv.Atoi(metadata["priority"])
+	}
+	if err != nil {
+		tusError(w, http.StatusBadRequest, "invalid Upload-Metadata: "+err.Error())
+		return
+	}
+
+	if s.cfg.UploadExpiry > 0 {
+		if removed, err := s.cfg.Uploads.Expire(time.Now().Add(-s.cfg.UploadExpiry)); err != nil {
+			log.Printf("Expiring uploads failed: %v", err)
+		} else if removed > 0 {
+			log.Printf("Expired %d upload(s) started over %s ago", removed, s.cfg.UploadExpiry)
+		}
+	}
+
+	caller, _ := auth.CallerFrom(r.Context())
+	info, err := s.cfg.Uploads.Create(length, metadata, caller.Name)
+	if err != nil {
+		log.Printf("Creating upload failed: %v", err)
+		tusError(w, http.StatusInternalServerError, "failed to create upload")
+		return
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Location", "/uploads/"+info.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// ownUpload returns the upload the request names, answering 404 unless it
+// was started with the caller's token, so uploads can't be read, written or
+// abandoned with another's
+func (s *Server) ownUpload(w http.ResponseWriter, r *http.Request) (upload.Info, bool) {
+	info, err := s.cfg.Uploads.Get(r.PathValue("id"))
+	if err == nil {
+		if caller, _ := auth.CallerFrom(r.Context()); info.SubmittedBy != caller.Name {
+			err = upload.ErrNotFound
+		}
+	}
+	if err != nil {
+		uploadError(w, err)
+		return upload.Info{}, false
+	}
+	return info, true
+}
+
+// handleUploadOffset tells a client where to resume, and the import the
+// upload became once complete
+func (s *Server) handleUploadOffset(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.ownUpload(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(info.Length, 10))
+	if info.ImportID != "" {
+		w.Header().Set(auth.ImportIDHeader, info.ImportID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleUploadChunk appends a chunk at Upload-Offset. The chunk completing
+// the upload also checks the whole file and queues its import.
+func (s *Server) handleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		tusError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		tusError(w, http.StatusBadRequest, "Upload-Offset must be a number of bytes")
+		return
+	}
+	checksum, err := parseUploadChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		tusError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := s.ownUpload(w, r); !ok {
+		return
+	}
+	id := r.PathValue("id")
+	info, err := s.cfg.Uploads.Append(id, offset, r.Body, checksum)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+	if info.Complete() {
+		info, err = s.cfg.Uploads.Finish(id, s.queueUpload)
+		if errors.Is(err, upload.ErrChecksumMismatch) {
+			// The file can't be trusted; the client has to send it again
+			s.cfg.Uploads.Remove(id)
+			tusError(w, http.StatusUnprocessableEntity, "the file does not match its sha256")
+			return
+		}
+		if err != nil && !errors.Is(err, upload.ErrComplete) {
+			log.Printf("Finishing upload %s failed: %v", id, err)
+			tusError(w, http.StatusInternalServerError, "failed to queue the upload")
+			return
+		}
+		w.Header().Set(auth.ImportIDHeader, info.ImportID)
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queueUpload checks a complete upload and queues it as an import
+func (s *Server) queueUpload(info upload.Info) (string, error) {
+	if sum := info.Metadata["sha256"]; sum != "" {
+		if err := s.cfg.Uploads.Verify(info.ID, sum); err != nil {
+			return "", err
+		}
+	}
+	priority, _ := strconv.Atoi(info.Metadata["priority"])
+	job, err := s.cfg.Jobs.EnqueueFile(s.cfg.Uploads.Path(info.ID), info.Metadata["filename"], priority, info.SubmittedBy)
+	if err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// handleDeleteUpload abandons an upload
+func (s *Server) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.ownUpload(w, r); !ok {
+		return
+	}
+	if err := s.cfg.Uploads.Remove(r.PathValue("id")); err != nil {
+		uploadError(w, err)
+		return
+	}
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireTus refuses requests made for another version of the protocol
+func requireTus(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			tusError(w, http.StatusPreconditionFailed, "Tus-Resumable must be "+tusVersion)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// parseUploadMetadata decodes "key base64value,key base64value"
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseUploadChecksum decodes "sha256 base64digest", or returns nil for an
+// empty header
+func parseUploadChecksum(header string) (*upload.Checksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algorithm, encoded, _ := strings.Cut(header, " ")
+	if algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q (allowed: sha256)", algorithm)
+	}
+	sum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Upload-Checksum is not base64")
+	}
+	return &upload.Checksum{Algorithm: algorithm, Sum: sum}, nil
+}
+
+// uploadError answers with the status tus gives each upload error
+func uploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, upload.ErrNotFound):
+		tusError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, upload.ErrOffsetMismatch), errors.Is(err, upload.ErrComplete):
+		tusError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, upload.ErrBusy):
+		tusError(w, http.StatusLocked, err.Error())
+	case errors.Is(err, upload.ErrTooLarge):
+		tusError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, upload.ErrChecksumMismatch):
+		tusError(w, statusChecksumMismatch, err.Error())
+	default:
+		log.Printf("Upload failed: %v", err)
+		tusError(w, http.StatusInternalServerError, "upload failed")
+	}
+}
+
+func tusError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"code/internal/auth"
+	"code/internal/jobs"
+	"code/internal/pipeline"
+	"code/internal/upload"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newUploadServer(t *testing.T) (*httptest.Server, *jobs.Queue) {
+	dir := t.TempDir()
+	store, _ := jobs.NewFileStore(dir)
+	queue, _ := jobs.Open(store)
+	uploads, _ := upload.Open(filepath.Join(dir, "uploads"))
+	s := New(Config{Jobs: queue, Uploads: uploads, MaxUploadSize: 1 << 20}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return server, queue
+}
+
+func tusRequest(t *testing.T, method, url string, body string, headers map[string]string) *http.Response {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func metadata(pairs ...string) string {
+	var encoded []string
+	for i := 0; i < len(pairs); i += 2 {
+		encoded = append(encoded, pairs[i]+" "+base64.StdEncoding.EncodeToString([]byte(pairs[i+1])))
+	}
+	return strings.Join(encoded, ",")
+}
+
+func TestServer_Uploads(t *testing.T) {
+	body := "Name,Email,Company,Source\n" +
+		"Alice Johnson,alice@example.com,Acme Inc,LinkedIn\n"
+	sum := sha256.Sum256([]byte(body))
+
+	t.Run("assembles chunks, resumes and queues the import", func(t *testing.T) {
+		// Arrange
+		server, queue := newUploadServer(t)
+		var location string
+		chunk := func(offset int, data string) *http.Response {
+			return tusRequest(t, http.MethodPatch, server.URL+location, data, map[string]string{
+				"Content-Type":  "application/offset+octet-stream",
+				"Upload-Offset": strconv.Itoa(offset),
+			})
+		}
+
+		// Act
+		created := tusRequest(t, http.MethodPost, server.URL+"/uploads", "", map[string]string{
+			"Upload-Length":   strconv.Itoa(len(body)),
+			"Upload-Metadata": metadata("filename", "leads.csv", "priority", "7", "sha256", hex.EncodeToString(sum[:])),
+		})
+		location = created.Header.Get("Location")
+		first := chunk(0, body[:20])
+		stale := chunk(0, body[:20])
+		head := tusRequest(t, http.MethodHead, server.URL+location, "", nil)
+		last := chunk(20, body[20:])
+
+		// Assert
+		assert.Equal(t, http.StatusCreated, created.StatusCode)
+		assert.Equal(t, http.StatusNoContent, first.StatusCode)
+		assert.Equal(t, http.StatusConflict, stale.StatusCode)
+		assert.Equal(t, "20", head.Header.Get("Upload-Offset"))
+		assert.Equal(t, http.StatusNoContent, last.StatusCode)
+		assert.Equal(t, strconv.Itoa(len(body)), last.Header.Get("Upload-Offset"))
+		job, ok := queue.Get(last.Header.Get(auth.ImportIDHeader))
+		assert.True(t, ok)
+		assert.Equal(t, "leads.csv", job.Name)
+		assert.Equal(t, 7, job.Priority)
+		assert.Equal(t, int64(len(body)), job.Size)
+		done := tusRequest(t, http.MethodHead, server.URL+location, "", nil)
+		assert.Equal(t, job.ID, done.Header.Get(auth.ImportIDHeader))
+	})
+
+	t.Run("refuses a file that doesn't match its sha256", func(t *testing.T) {
+		// Arrange
+		server, queue := newUploadServer(t)
+		created := tusRequest(t, http.MethodPost, server.URL+"/uploads", "", map[string]string{
+			"Upload-Length":   strconv.Itoa(len(body)),
+			"Upload-Metadata": metadata("sha256", strings.Repeat("0", 64)),
+		})
+
+		// Act
+		resp := tusRequest(t, http.MethodPatch, server.URL+created.Header.Get("Location"), body, map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": "0",
+		})
+		gone := tusRequest(t, http.MethodHead, server.URL+created.Header.Get("Location"), "", nil)
+
+		// Assert
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, http.StatusNotFound, gone.StatusCode)
+		assert.Empty(t, queue.List(jobs.Filter{}))
+	})
+
+	t.Run("keeps uploads to the token that started them", func(t *testing.T) {
+		// Arrange
+		dir := t.TempDir()
+		store, _ := jobs.NewFileStore(dir)
+		queue, _ := jobs.Open(store)
+		uploads, _ := upload.Open(filepath.Join(dir, "uploads"))
+		authenticator, _ := auth.New(auth.Config{Tokens: []auth.Token{
+			{Name: "emea", Token: "emea-token", Role: auth.RoleUploader},
+			{Name: "apac", Token: "apac-token", Role: auth.RoleUploader},
+		}}, nil)
+		s := New(Config{Jobs: queue, Uploads: uploads, Auth: authenticator}, func(string) pipeline.LeadProcessor { return createAllProcessor{} })
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		as := func(token string, headers map[string]string) map[string]string {
+			withToken := map[string]string{"Authorization": "Bearer " + token}
+			for key, value := range headers {
+				withToken[key] = value
+			}
+			return withToken
+		}
+		created := tusRequest(t, http.MethodPost, server.URL+"/uploads", "", as("emea-token", map[string]string{"Upload-Length": strconv.Itoa(len(body))}))
+		location := server.URL + created.Header.Get("Location")
+
+		// Act
+		head := tusRequest(t, http.MethodHead, location, "", as("apac-token", nil))
+		patch := tusRequest(t, http.MethodPatch, location, body, as("apac-token", map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": "0",
+		}))
+		remove := tusRequest(t, http.MethodDelete, location, "", as("apac-token", nil))
+		own := tusRequest(t, http.MethodHead, location, "", as("emea-token", nil))
+
+		// Assert
+		assert.Equal(t, http.StatusNotFound, head.StatusCode)
+		assert.Equal(t, http.StatusNotFound, patch.StatusCode)
+		assert.Equal(t, http.StatusNotFound, remove.StatusCode)
+		assert.Equal(t, http.StatusOK, own.StatusCode)
+		assert.Equal(t, "0", own.Header.Get("Upload-Offset"))
+	})
+
+	t.Run("checks the protocol version and size limit", func(t *testing.T) {
+		// Arrange
+		server, _ := newUploadServer(t)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/uploads", nil)
+		req.Header.Set("Upload-Length", "10")
+
+		// Act
+		noVersion, _ := http.DefaultClient.Do(req)
+		tooLarge := tusRequest(t, http.MethodPost, server.URL+"/uploads", "", map[string]string{"Upload-Length": strconv.Itoa(2 << 20)})
+		options := tusRequest(t, http.MethodOptions, server.URL+"/uploads", "", nil)
+
+		// Assert
+		assert.Equal(t, http.StatusPreconditionFailed, noVersion.StatusCode)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, tooLarge.StatusCode)
+		assert.Equal(t, "creation,termination,checksum", options.Header.Get("Tus-Extension"))
+		assert.Equal(t, strconv.Itoa(1<<20), options.Header.Get("Tus-Max-Size"))
+	})
+}
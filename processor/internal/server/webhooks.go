@@ -0,0 +1,296 @@
+package server
+
+import (
+	"code/internal/csv"
+	"code/internal/errcode"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxWebhookBytes bounds a form submission payload
+const maxWebhookBytes = 1 << 20
+
+// formRecord is one form submission as a header and a row of answers, so it
+// can be read like a one-row CSV file
+type formRecord struct {
+	header []string
+	row    []string
+}
+
+// add appends a column, skipping blank names and names already present
+func (f *formRecord) add(column, value string) {
+	column = strings.TrimSpace(column)
+	if column == "" {
+		return
+	}
+	for _, existing := range f.header {
+		if existing == column {
+			return
+		}
+	}
+	f.header = append(f.header, column)
+	f.row = append(f.row, value)
+}
+
+// typeformPayload is the body of a Typeform form_response webhook
+type typeformPayload struct {
+	EventType    string `json:"event_type"`
+	FormResponse struct {
+		Definition struct {
+			Fields []struct {
+				ID    string `json:"id"`
+				Title string `json:"title"`
+			} `json:"fields"`
+		} `json:"definition"`
+		Answers []typeformAnswer  `json:"answers"`
+		Hidden  map[string]string `json:"hidden"`
+	} `json:"form_response"`
+}
+
+// typeformAnswer is one answer; which value is set depends on its type
+type typeformAnswer struct {
+	Type  string `json:"type"`
+	Field struct {
+		ID  string `json:"id"`
+		Ref string `json:"ref"`
+	} `json:"field"`
+	Text        string   `json:"text"`
+	Email       string   `json:"email"`
+	URL         string   `json:"url"`
+	PhoneNumber string   `json:"phone_number"`
+	Date        string   `json:"date"`
+	FileURL     string   `json:"file_url"`
+	Number      *float64 `json:"number"`
+	Boolean     *bool    `json:"boolean"`
+	Choice      *struct {
+		Label string `json:"label"`
+		Other string `json:"other"`
+	} `json:"choice"`
+	Choices *struct {
+		Labels []string `json:"labels"`
+		Other  string   `json:"other"`
+	} `json:"choices"`
+}
+
+// value renders the answer as the text a CSV cell would hold
+func (a typeformAnswer) value() string {
+	switch a.Type {
+	case "email":
+		return a.Email
+	case "url":
+		return a.URL
+	case "phone_number":
+		return a.PhoneNumber
+	case "date":
+		return a.Date
+	case "file_url":
+		return a.FileURL
+	case "number":
+		if a.Number != nil {
+			return strconv.FormatFloat(*a.Number, 'f', -1, 64)
+		}
+	case "boolean":
+		if a.Boolean != nil {
+			return strconv.FormatBool(*a.Boolean)
+		}
+	case "choice":
+		if a.Choice != nil {
+			if a.Choice.Label != "" {
+				return a.Choice.Label
+			}
+			return a.Choice.Other
+		}
+	case "choices":
+		if a.Choices != nil {
+			labels := a.Choices.Labels
+			if a.Choices.Other != "" {
+				labels = append(labels, a.Choices.Other)
+			}
+			return strings.Join(labels, ", ")
+		}
+	}
+	return a.Text
+}
+
+// parseTypeform turns a Typeform submission into a record. Each answer is a
+// column named after its question's title and, under a second name, its ref;
+// hidden fields such as utm_source are columns named after their key.
+func parseTypeform(body []byte) (*formRecord, error) {
+	var payload typeformPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid Typeform payload: %w", err)
+	}
+	if payload.EventType != "" && payload.EventType != "form_response" {
+		return nil, fmt.Errorf("unsupported Typeform event %q", payload.EventType)
+	}
+
+	titles := make(map[string]string)
+	for _, field := range payload.FormResponse.Definition.Fields {
+		titles[field.ID] = field.Title
+	}
+
+	record := &formRecord{}
+	for _, answer := range payload.FormResponse.Answers {
+		value := answer.value()
+		record.add(titles[answer.Field.ID], value)
+		record.add(answer.Field.Ref, value)
+	}
+	for _, key := range sortedKeys(payload.FormResponse.Hidden) {
+		record.add(key, payload.FormResponse.Hidden[key])
+	}
+	if len(record.header) == 0 {
+		return nil, fmt.Errorf("Typeform payload has no answers")
+	}
+	return record, nil
+}
+
+// parseGoogleForms turns a Google Forms submission into a record. Forms has
+// no webhooks of its own, so the payload is what an Apps Script onFormSubmit
+// trigger posts: the event's namedValues, question title to answers, either
+// wrapped as {"namedValues": {...}} or as the object itself.
+func parseGoogleForms(body []byte) (*formRecord, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid Google Forms payload: %w", err)
+	}
+	if named, ok := payload["namedValues"]; ok {
+		payload = nil
+		if err := json.Unmarshal(named, &payload); err != nil {
+			return nil, fmt.Errorf("invalid Google Forms namedValues: %w", err)
+		}
+	}
+
+	record := &formRecord{}
+	for _, question := range sortedKeys(payload) {
+		var answers []string
+		if err := json.Unmarshal(payload[question], &answers); err != nil {
+			var answer string
+			if err := json.Unmarshal(payload[question], &answer); err != nil {
+				return nil, fmt.Errorf("invalid Google Forms answer for %q", question)
+			}
+			answers = []string{answer}
+		}
+		record.add(question, strings.Join(answers, ", "))
+	}
+	if len(record.header) == 0 {
+		return nil, fmt.Errorf("Google Forms payload has no answers")
+	}
+	return record, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// verifyTypeform checks the Typeform-Signature header, an HMAC-SHA256 of the
+// body keyed with the form's webhook secret
+func verifyTypeform(r *http.Request, body []byte, secret string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(r.Header.Get("Typeform-Signature")), []byte(expected))
+}
+
+// verifyToken checks the bearer token an Apps Script trigger sends
+func verifyToken(r *http.Request, token string) bool {
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// transient reports whether a failure may succeed if the submission is
+// delivered again, so the form provider should retry it
+func transient(summary *ImportSummary) bool {
+	for _, failure := range summary.Failures {
+		switch errcode.Code(failure.Code) {
+		case errcode.CodeNetwork, errcode.CodeRateLimited, errcode.CodeAuth:
+			return true
+		}
+	}
+	return false
+}
+
+// webhookHandler processes form submissions one at a time as they arrive.
+// verify, when set, authenticates the request; parse maps its body to a
+// record whose columns go through the same mapping as CSV uploads.
+func (s *Server) webhookHandler(name string, verify func(*http.Request, []byte) bool, parse func([]byte) (*formRecord, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "payload too large"})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if verify != nil && !verify(r, body) {
+			log.Printf("Rejected %s webhook with a bad signature", name)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid signature"})
+			return
+		}
+
+		record, err := parse(body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		reader := csv.NewCSVReader()
+		reader.SetMapping(s.cfg.Mapping)
+		reader.SetIDGenerator(s.cfg.NewID)
+		leadPipeline := pipeline.New(s.newProcessor(""), pipeline.Config{
+			Transforms: s.cfg.Transforms,
+			Validation: s.cfg.Validation,
+		})
+		items := leadPipeline.Run(r.Context(), func(emit func(*models.Lead) error) error {
+			return reader.StreamRecords(record.header, [][]string{record.row}, emit)
+		})
+
+		summary := newImportSummary()
+		var failed []*pipeline.Item
+		for item := range items {
+			if summary.add(item) {
+				failed = append(failed, item)
+			}
+			s.stats.Add(item)
+		}
+		if err := leadPipeline.Err(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if summary.Total == 0 {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "submission has no name, email, company and source columns; see the config's mapping"})
+			return
+		}
+
+		// Invalid submissions are answered 200 so they aren't redelivered
+		// and transient failures are answered 503 so they are; only the
+		// others are dead-lettered
+		status := http.StatusOK
+		if transient(summary) {
+			status = http.StatusServiceUnavailable
+		} else {
+			s.deadLetter(r.URL.Path, failed)
+		}
+		writeJSON(w, status, summary)
+	}
+}
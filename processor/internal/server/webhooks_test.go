@@ -0,0 +1,136 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const typeformBody = `{
+  "event_type": "form_response",
+  "form_response": {
+    "definition": {"fields": [
+      {"id": "f1", "title": "Your name"},
+      {"id": "f2", "title": "Work email"},
+      {"id": "f3", "title": "Company"},
+      {"id": "f4", "title": "Team size"}
+    ]},
+    "answers": [
+      {"type": "text", "text": "Jane Smith", "field": {"id": "f1", "ref": "name"}},
+      {"type": "email", "email": "jane@techfirm.com", "field": {"id": "f2", "ref": "email"}},
+      {"type": "text", "text": "Tech Firm", "field": {"id": "f3", "ref": "company"}},
+      {"type": "number", "number": 25, "field": {"id": "f4", "ref": "team_size"}}
+    ],
+    "hidden": {"source": "Website"}
+  }
+}`
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func postWebhook(t *testing.T, url, body string, header http.Header) (*http.Response, ImportSummary) {
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var summary ImportSummary
+	json.NewDecoder(resp.Body).Decode(&summary)
+	return resp, summary
+}
+
+func TestParseTypeform(t *testing.T) {
+	t.Run("names columns after question titles, refs and hidden fields", func(t *testing.T) {
+		// Act
+		record, err := parseTypeform([]byte(typeformBody))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Your name", "name", "Work email", "email", "Company", "company", "Team size", "team_size", "source"}, record.header)
+		assert.Equal(t, "jane@techfirm.com", record.row[2])
+		assert.Equal(t, "25", record.row[6])
+		assert.Equal(t, "Website", record.row[8])
+	})
+
+	t.Run("rejects other events", func(t *testing.T) {
+		// Act
+		_, err := parseTypeform([]byte(`{"event_type": "form_deleted"}`))
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestParseGoogleForms(t *testing.T) {
+	t.Run("reads namedValues and flat objects", func(t *testing.T) {
+		// Act
+		named, namedErr := parseGoogleForms([]byte(`{"namedValues": {"Email": ["jane@techfirm.com"], "Interests": ["CRM", "Email"]}}`))
+		flat, flatErr := parseGoogleForms([]byte(`{"Email": "jane@techfirm.com"}`))
+
+		// Assert
+		assert.NoError(t, namedErr)
+		assert.NoError(t, flatErr)
+		assert.Equal(t, []string{"Email", "Interests"}, named.header)
+		assert.Equal(t, []string{"jane@techfirm.com", "CRM, Email"}, named.row)
+		assert.Equal(t, []string{"jane@techfirm.com"}, flat.row)
+	})
+}
+
+func TestServer_Webhooks(t *testing.T) {
+	t.Run("processes a signed Typeform submission", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{TypeformSecret: "s3cret"})
+		defer server.Close()
+		header := http.Header{"Typeform-Signature": {sign("s3cret", typeformBody)}}
+
+		// Act
+		resp, summary := postWebhook(t, server.URL+"/webhooks/typeform", typeformBody, header)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, summary.Total)
+		assert.Equal(t, 1, summary.Created)
+	})
+
+	t.Run("rejects a bad Typeform signature", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{TypeformSecret: "s3cret"})
+		defer server.Close()
+		header := http.Header{"Typeform-Signature": {sign("other", typeformBody)}}
+
+		// Act
+		resp, _ := postWebhook(t, server.URL+"/webhooks/typeform", typeformBody, header)
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("maps Google Forms questions with the column mapping", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(Config{
+			FormsToken: "t0ken",
+			Mapping:    map[string]string{"name": "Full name", "email": "Email address", "company": "Company", "source": "Heard about us"},
+		})
+		defer server.Close()
+		body := `{"namedValues": {"Full name": ["Jane Smith"], "Email address": ["jane@techfirm.com"], "Company": ["Tech Firm"], "Heard about us": ["Webinar"]}}`
+
+		// Act
+		resp, summary := postWebhook(t, server.URL+"/webhooks/google-forms", body, http.Header{"Authorization": {"Bearer t0ken"}})
+		unauthorized, _ := postWebhook(t, server.URL+"/webhooks/google-forms", body, http.Header{})
+
+		// Assert
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, summary.Created)
+		assert.Equal(t, http.StatusUnauthorized, unauthorized.StatusCode)
+	})
+}
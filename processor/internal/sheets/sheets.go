@@ -0,0 +1,343 @@
+package sheets
+
+import (
+	"code/internal/csv"
+	"code/internal/errcode"
+	"code/internal/models"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope is the only access requested: reading spreadsheets
+const Scope = "https://www.googleapis.com/auth/spreadsheets.readonly"
+
+// APIURL is the Google Sheets API
+const APIURL = "https://sheets.googleapis.com"
+
+// DefaultRange reads every row of the first sheet
+const DefaultRange = "A:ZZ"
+
+// urlPrefix starts every Google Sheets document URL
+const urlPrefix = "https://docs.google.com/spreadsheets/d/"
+
+// IsURL reports whether s is a Google Sheets document URL rather than a file
+func IsURL(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), urlPrefix)
+}
+
+// SpreadsheetID extracts the document ID from a sheet URL such as
+// https://docs.google.com/spreadsheets/d/<id>/edit#gid=0
+func SpreadsheetID(sheetURL string) (string, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(sheetURL), urlPrefix)
+	id := strings.SplitN(rest, "/", 2)[0]
+	id = strings.SplitN(id, "?", 2)[0]
+	id = strings.SplitN(id, "#", 2)[0]
+	if !IsURL(sheetURL) || id == "" {
+		return "", fmt.Errorf("not a Google Sheets URL: %s", sheetURL)
+	}
+	return id, nil
+}
+
+// Credentials is a Google service-account key file
+type Credentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// LoadCredentials reads a service-account key file, as downloaded from the
+// Google Cloud console. The sheet must be shared with its client_email.
+func LoadCredentials(path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google credentials: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid Google credentials %s: %w", path, err)
+	}
+	if creds.Type != "service_account" {
+		return nil, fmt.Errorf("invalid Google credentials %s: not a service-account key", path)
+	}
+	if creds.ClientEmail == "" || creds.PrivateKey == "" || creds.TokenURI == "" {
+		return nil, fmt.Errorf("invalid Google credentials %s: client_email, private_key and token_uri are required", path)
+	}
+	return &creds, nil
+}
+
+// parseKey parses the PEM private key of a service account
+func parseKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("private_key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private_key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private_key is not an RSA key")
+	}
+	return key, nil
+}
+
+// tokenSource exchanges signed JWT assertions for access tokens, reusing a
+// token until shortly before it expires
+type tokenSource struct {
+	creds  *Credentials
+	key    *rsa.PrivateKey
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns a valid access token
+func (t *tokenSource) Token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Now().Before(t.expiry.Add(-time.Minute)) {
+		return t.token, nil
+	}
+
+	assertion, err := t.assertion(time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := t.client.PostForm(t.creds.TokenURI, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Google access token: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		err := error(&errcode.StatusError{Status: resp.StatusCode})
+		if resp.StatusCode == http.StatusBadRequest {
+			// Google refuses bad or revoked keys with 400 invalid_grant
+			err = errcode.Classify(errcode.ErrAuth, err)
+		}
+		if body.ErrorDescription != "" {
+			return "", fmt.Errorf("failed to get Google access token: %s: %w", body.ErrorDescription, err)
+		}
+		return "", fmt.Errorf("failed to get Google access token: %w", err)
+	}
+	if decodeErr != nil || body.AccessToken == "" {
+		return "", fmt.Errorf("failed to get Google access token: malformed response")
+	}
+
+	t.token = body.AccessToken
+	t.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return t.token, nil
+}
+
+// assertion builds the RS256-signed JWT a service account authenticates with
+func (t *tokenSource) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": t.creds.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   t.creds.ClientEmail,
+		"scope": Scope,
+		"aud":   t.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Google token request: %w", err)
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// Option configures a Reader
+type Option func(*Reader)
+
+// WithHTTPClient sets the HTTP client used for the token and Sheets requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *Reader) {
+		r.client = client
+		r.tokens.client = client
+	}
+}
+
+// WithAPIURL points the reader at another Sheets API endpoint, e.g. a test server
+func WithAPIURL(apiURL string) Option {
+	return func(r *Reader) {
+		r.apiURL = strings.TrimSuffix(apiURL, "/")
+	}
+}
+
+// Reader reads leads from a range of a Google Sheet, laid out like a CSV
+// file with a header row. The range is fetched once, on first use, so a
+// run sees one snapshot even if the sheet is edited while it runs.
+type Reader struct {
+	sheetURL      string
+	spreadsheetID string
+	rng           string
+	reader        *csv.CSVReader
+	client        *http.Client
+	apiURL        string
+	tokens        *tokenSource
+
+	once   sync.Once
+	header []string
+	rows   [][]string
+	hash   string
+	err    error
+}
+
+// NewReader creates a reader for rng of the sheet at sheetURL, mapping
+// columns like reader does for CSV files
+func NewReader(sheetURL, rng string, creds *Credentials, reader *csv.CSVReader, opts ...Option) (*Reader, error) {
+	id, err := SpreadsheetID(sheetURL)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseKey(creds.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google credentials: %w", err)
+	}
+	if strings.TrimSpace(rng) == "" {
+		rng = DefaultRange
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	r := &Reader{
+		sheetURL:      sheetURL,
+		spreadsheetID: id,
+		rng:           rng,
+		reader:        reader,
+		client:        client,
+		apiURL:        APIURL,
+		tokens:        &tokenSource{creds: creds, key: key, client: client},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// SpreadsheetID returns the ID of the sheet's document
+func (r *Reader) SpreadsheetID() string {
+	return r.spreadsheetID
+}
+
+// Name identifies the sheet and range
+func (r *Reader) Name() string {
+	return fmt.Sprintf("%s%s (%s)", urlPrefix, r.spreadsheetID, r.rng)
+}
+
+// Hash returns the SHA-256 of the range's values
+func (r *Reader) Hash() (string, error) {
+	if err := r.fetch(); err != nil {
+		return "", err
+	}
+	return r.hash, nil
+}
+
+// ReadHeader returns the first row of the range
+func (r *Reader) ReadHeader() ([]string, error) {
+	if err := r.fetch(); err != nil {
+		return nil, err
+	}
+	return r.header, nil
+}
+
+// StreamLeads converts the rows below the header to leads
+func (r *Reader) StreamLeads(emit func(*models.Lead) error) error {
+	if err := r.fetch(); err != nil {
+		return err
+	}
+	return r.reader.StreamRecords(r.header, r.rows, emit)
+}
+
+func (r *Reader) fetch() error {
+	r.once.Do(func() {
+		r.err = r.load()
+	})
+	return r.err
+}
+
+// load reads the range's values. The API leaves out trailing empty cells,
+// so rows are padded to the header's width to keep blank fields blank.
+func (r *Reader) load() error {
+	token, err := r.tokens.Token()
+	if err != nil {
+		return err
+	}
+
+	apiURL := fmt.Sprintf("%s/v4/spreadsheets/%s/values/%s?majorDimension=ROWS", r.apiURL, url.PathEscape(r.spreadsheetID), url.PathEscape(r.rng))
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to read Google Sheet: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to read Google Sheet: %w", &errcode.StatusError{Status: resp.StatusCode})
+	}
+
+	var body struct {
+		Values [][]string `json:"values"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode Google Sheet: %w", err)
+	}
+	if len(body.Values) == 0 {
+		return fmt.Errorf("range %s of the Google Sheet is empty", r.rng)
+	}
+
+	data, _ := json.Marshal(body.Values)
+	sum := sha256.Sum256(data)
+	r.hash = hex.EncodeToString(sum[:])
+
+	r.header = body.Values[0]
+	for _, row := range body.Values[1:] {
+		for len(row) < len(r.header) {
+			row = append(row, "")
+		}
+		r.rows = append(r.rows, row)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package sheets
+
+import (
+	"code/internal/csv"
+	"code/internal/errcode"
+	"code/internal/models"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCredentials returns service-account credentials with a fresh key,
+// whose tokens are issued by tokenURI
+func newTestCredentials(t *testing.T, tokenURI string) (*Credentials, *rsa.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	return &Credentials{
+		Type:        "service_account",
+		ClientEmail: "importer@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURI,
+	}, &key.PublicKey
+}
+
+// verifyAssertion checks a JWT assertion's signature and returns its claims
+func verifyAssertion(t *testing.T, assertion string, key *rsa.PublicKey) map[string]interface{} {
+	parts := strings.Split(assertion, ".")
+	assert.Len(t, parts, 3)
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+
+	var claims map[string]interface{}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(payload, &claims)
+	return claims
+}
+
+func TestSpreadsheetID(t *testing.T) {
+	t.Run("extracts the document ID from sheet URLs", func(t *testing.T) {
+		for _, sheetURL := range []string{
+			"https://docs.google.com/spreadsheets/d/abc123/edit#gid=0",
+			"https://docs.google.com/spreadsheets/d/abc123?usp=sharing",
+			"https://docs.google.com/spreadsheets/d/abc123",
+		} {
+			// Act
+			id, err := SpreadsheetID(sheetURL)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, "abc123", id, sheetURL)
+		}
+	})
+
+	t.Run("rejects other URLs", func(t *testing.T) {
+		// Act
+		_, err := SpreadsheetID("https://example.com/leads.csv")
+
+		// Assert
+		assert.Error(t, err)
+		assert.False(t, IsURL("leads.csv"))
+	})
+}
+
+func TestReader(t *testing.T) {
+	t.Run("reads leads from the sheet with a service-account token", func(t *testing.T) {
+		// Arrange
+		var tokenRequests int64
+		var publicKey *rsa.PublicKey
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/token":
+				atomic.AddInt64(&tokenRequests, 1)
+				r.ParseForm()
+				claims := verifyAssertion(t, r.Form.Get("assertion"), publicKey)
+				assert.Equal(t, Scope, claims["scope"])
+				w.Write([]byte(`{"access_token":"t0ken","expires_in":3600}`))
+			default:
+				assert.Equal(t, "/v4/spreadsheets/abc123/values/Leads!A:E", r.URL.Path)
+				assert.Equal(t, "Bearer t0ken", r.Header.Get("Authorization"))
+				w.Write([]byte(`{"values":[["Name","Email","Company","Source","Status"],["Jane Smith","jane@example.com","Tech Firm","LinkedIn"],["John Doe","john@example.com","Acme","Webinar","qualified"]]}`))
+			}
+		}))
+		defer server.Close()
+
+		creds, key := newTestCredentials(t, server.URL+"/token")
+		publicKey = key
+		reader, err := NewReader("https://docs.google.com/spreadsheets/d/abc123/edit#gid=0", "Leads!A:E", creds, csv.NewCSVReader(), WithAPIURL(server.URL))
+		assert.NoError(t, err)
+
+		// Act
+		header, _ := reader.ReadHeader()
+		hash, _ := reader.Hash()
+		var leads []*models.Lead
+		err = reader.StreamLeads(func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "Name", header[0])
+		assert.Len(t, hash, 64)
+		assert.Len(t, leads, 2)
+		assert.Equal(t, "", leads[0].Status)
+		assert.Equal(t, "qualified", leads[1].Status)
+		assert.Equal(t, int64(1), tokenRequests, "the range is fetched once")
+	})
+
+	t.Run("classifies refused access", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				w.Write([]byte(`{"access_token":"t0ken","expires_in":3600}`))
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		creds, _ := newTestCredentials(t, server.URL+"/token")
+		reader, _ := NewReader("https://docs.google.com/spreadsheets/d/abc123", "", creds, csv.NewCSVReader(), WithAPIURL(server.URL))
+
+		// Act
+		_, err := reader.ReadHeader()
+
+		// Assert
+		assert.ErrorIs(t, err, errcode.ErrAuth)
+	})
+}
+
+func TestLoadCredentials(t *testing.T) {
+	t.Run("rejects keys that are not service accounts", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "key.json")
+		os.WriteFile(path, []byte(`{"type":"authorized_user"}`), 0o600)
+
+		// Act
+		_, err := LoadCredentials(path)
+
+		// Assert
+		assert.ErrorContains(t, err, "service-account")
+	})
+}
@@ -0,0 +1,201 @@
+package stats
+
+import (
+	"code/internal/errcode"
+	"code/internal/pipeline"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ActionError is the action counted for an item that failed before it had
+// a result, e.g. one whose row couldn't be read
+const ActionError = "ERROR"
+
+// settled are the actions that aren't failures: the lead was written, left
+// as it is, or held back on purpose
+var settled = []string{"CREATE", "UPDATE", "SKIP", "DEFERRED", "REVIEW", "ROLLED_BACK"}
+
+// Buckets are the upper bounds of the timing histograms; a last bucket
+// holds everything slower
+var Buckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Stats counts the outcomes of processed leads: how many there were, per
+// action and per error class, and how long they took from being read to
+// being done. It is safe for concurrent use, so one Stats can total the
+// imports a server runs side by side.
+type Stats struct {
+	total      atomic.Int64
+	errors     atomic.Int64
+	syncErrors atomic.Int64
+
+	mu      sync.Mutex
+	actions map[string]int
+	codes   map[errcode.Code]int
+	timings map[string]*Histogram
+}
+
+// New creates an empty Stats
+func New() *Stats {
+	return &Stats{
+		actions: make(map[string]int),
+		codes:   make(map[errcode.Code]int),
+		timings: make(map[string]*Histogram),
+	}
+}
+
+// Add counts one processed item and reports whether it failed. Deferred,
+// held and rolled-back leads aren't failures, and their errors aren't counted
+// under an error class.
+func (s *Stats) Add(item *pipeline.Item) bool {
+	action, err := ActionError, item.Err
+	if item.Err == nil {
+		action, err = item.Result.Action, item.Result.Error
+		if item.Result.SyncError != nil {
+			s.syncErrors.Add(1)
+		}
+	}
+	failed := !slices.Contains(settled, action)
+
+	s.total.Add(1)
+	if failed {
+		s.errors.Add(1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions[action]++
+	if err != nil && action != "DEFERRED" && action != "ROLLED_BACK" {
+		s.codes[errcode.Of(err)]++
+	}
+	if !item.ReadAt.IsZero() {
+		histogram, ok := s.timings[action]
+		if !ok {
+			histogram = newHistogram()
+			s.timings[action] = histogram
+		}
+		histogram.observe(time.Since(item.ReadAt))
+	}
+	return failed
+}
+
+// Total returns how many items were counted
+func (s *Stats) Total() int {
+	return int(s.total.Load())
+}
+
+// Errors returns how many items failed
+func (s *Stats) Errors() int {
+	return int(s.errors.Load())
+}
+
+// SyncErrors returns how many written leads a secondary sink failed to receive
+func (s *Stats) SyncErrors() int {
+	return int(s.syncErrors.Load())
+}
+
+// Count returns how many items ended with action
+func (s *Stats) Count(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.actions[action]
+}
+
+// Codes returns a copy of the failure counts per error class
+func (s *Stats) Codes() map[errcode.Code]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.codes)
+}
+
+// Summary returns a copy of the counts, for reporting
+func (s *Stats) Summary() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary := Summary{
+		Total:      s.Total(),
+		Errors:     s.Errors(),
+		SyncErrors: s.SyncErrors(),
+		Actions:    maps.Clone(s.actions),
+		Codes:      maps.Clone(s.codes),
+		Timings:    make(map[string]Histogram, len(s.timings)),
+	}
+	for action, histogram := range s.timings {
+		summary.Timings[action] = histogram.clone()
+	}
+	return summary
+}
+
+// Summary is a copy of the counts of a Stats
+type Summary struct {
+	Total      int                  `json:"total"`
+	Errors     int                  `json:"errors"`
+	SyncErrors int                  `json:"syncErrors"`
+	Actions    map[string]int       `json:"actions"`
+	Codes      map[errcode.Code]int `json:"codes,omitempty"`
+	// Timings are per action, from a lead being read to it being done
+	Timings map[string]Histogram `json:"timings,omitempty"`
+}
+
+// Histogram counts durations in the Buckets
+type Histogram struct {
+	// Counts holds one count per bucket, then the count of slower durations
+	Counts []int         `json:"counts"`
+	Count  int           `json:"count"`
+	Sum    time.Duration `json:"sumNanos"`
+}
+
+func newHistogram() *Histogram {
+	return &Histogram{Counts: make([]int, len(Buckets)+1)}
+}
+
+func (h *Histogram) observe(took time.Duration) {
+	i, _ := slices.BinarySearch(Buckets, took)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += took
+}
+
+func (h *Histogram) clone() Histogram {
+	clone := *h
+	clone.Counts = slices.Clone(h.Counts)
+	return clone
+}
+
+// Mean returns the average duration
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the upper bound of the bucket holding the q quantile,
+// e.g. 0.95 for the duration 95% of leads took at most. It is the last
+// bound when the quantile is slower than every bucket.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int(q*float64(h.Count) + 0.5)
+	seen := 0
+	for i, count := range h.Counts {
+		seen += count
+		if seen >= rank && i < len(Buckets) {
+			return Buckets[i]
+		}
+	}
+	return Buckets[len(Buckets)-1]
+}
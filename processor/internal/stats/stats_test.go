@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func item(action string, err error) *pipeline.Item {
+	return &pipeline.Item{
+		Lead:   models.NewLead("John Doe", "john@example.com", "Acme", "Website"),
+		Result: &processor.ProcessResult{Action: action, Error: err},
+		ReadAt: time.Now(),
+	}
+}
+
+func TestStats_Add(t *testing.T) {
+	t.Run("counts actions, failures and error classes", func(t *testing.T) {
+		// Arrange
+		stats := New()
+		timeout := errcode.Classify(errcode.ErrNetwork, errors.New("timeout"))
+
+		// Act
+		stats.Add(item("CREATE", nil))
+		stats.Add(item("SKIP", nil))
+		stats.Add(item("DEFERRED", errors.New("budget exhausted")))
+		failed := stats.Add(item("API_ERROR", timeout))
+		stats.Add(&pipeline.Item{Lead: &models.Lead{}, Err: errors.New("bad row")})
+
+		// Assert
+		assert.True(t, failed)
+		assert.Equal(t, 5, stats.Total())
+		assert.Equal(t, 2, stats.Errors())
+		assert.Equal(t, 1, stats.Count("DEFERRED"))
+		assert.Equal(t, 1, stats.Count(ActionError))
+		assert.Equal(t, map[errcode.Code]int{errcode.CodeNetwork: 1, errcode.CodeUnknown: 1}, stats.Codes())
+		assert.Equal(t, 1, stats.Summary().Timings["CREATE"].Count)
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		// Arrange
+		stats := New()
+		var wg sync.WaitGroup
+
+		// Act
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					stats.Add(item("CREATE", nil))
+					stats.Summary()
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Assert
+		assert.Equal(t, 800, stats.Total())
+		assert.Equal(t, 800, stats.Count("CREATE"))
+	})
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	t.Run("returns the upper bound of the quantile's bucket", func(t *testing.T) {
+		// Arrange
+		histogram := newHistogram()
+		for range 90 {
+			histogram.observe(5 * time.Millisecond)
+		}
+		for range 10 {
+			histogram.observe(300 * time.Millisecond)
+		}
+
+		// Act
+		p50, p95 := histogram.Quantile(0.5), histogram.Quantile(0.95)
+
+		// Assert
+		assert.Equal(t, 10*time.Millisecond, p50)
+		assert.Equal(t, 500*time.Millisecond, p95)
+		assert.Equal(t, 34500*time.Microsecond, histogram.Mean())
+	})
+}
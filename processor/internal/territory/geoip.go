@@ -0,0 +1,131 @@
+package territory
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ipRange is a block of addresses located in one country
+type ipRange struct {
+	first, last netip.Addr
+	country     string
+}
+
+// GeoIP finds the country of IP addresses in a table of address ranges
+type GeoIP struct {
+	ranges []ipRange
+}
+
+// LoadGeoIP reads a country table from a CSV file whose rows are either
+// first,last,country — the layout of the free DB-IP and IP2Location "lite"
+// country files — or network,country with a CIDR network. Rows whose
+// addresses don't parse, such as a header, are skipped.
+func LoadGeoIP(path string) (*GeoIP, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP file: %w", err)
+	}
+	defer file.Close()
+
+	geo, err := ReadGeoIP(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP file %s: %w", path, err)
+	}
+	return geo, nil
+}
+
+// ReadGeoIP reads a country table like LoadGeoIP
+func ReadGeoIP(r io.Reader) (*GeoIP, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	geo := &GeoIP{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if block, ok := parseRange(record); ok {
+			geo.ranges = append(geo.ranges, block)
+		}
+	}
+	if len(geo.ranges) == 0 {
+		return nil, fmt.Errorf("no address ranges found")
+	}
+	slices.SortFunc(geo.ranges, func(a, b ipRange) int { return a.first.Compare(b.first) })
+	return geo, nil
+}
+
+func parseRange(record []string) (ipRange, bool) {
+	switch len(record) {
+	case 0, 1:
+		return ipRange{}, false
+	case 2:
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return ipRange{}, false
+		}
+		prefix = prefix.Masked()
+		return ipRange{first: prefix.Addr().Unmap(), last: lastAddr(prefix), country: strings.TrimSpace(record[1])}, true
+	}
+	first, ok := parseAddr(record[0])
+	if !ok {
+		return ipRange{}, false
+	}
+	last, ok := parseAddr(record[1])
+	if !ok || last.Less(first) || first.Is4() != last.Is4() {
+		return ipRange{}, false
+	}
+	return ipRange{first: first, last: last, country: strings.TrimSpace(record[2])}, true
+}
+
+// lastAddr returns the highest address of a masked prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().Unmap().AsSlice()
+	bits := prefix.Bits()
+	if prefix.Addr().Is4In6() {
+		bits -= 96
+	}
+	for i := range bytes {
+		switch {
+		case bits >= 8:
+			bits -= 8
+		case bits > 0:
+			bytes[i] |= byte(0xff >> bits)
+			bits = 0
+		default:
+			bytes[i] = 0xff
+		}
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// Country returns the country of an IP address, as the table names it,
+// usually an ISO 3166 code such as "DE"
+func (g *GeoIP) Country(ip string) (string, bool) {
+	addr, ok := parseAddr(ip)
+	if !ok {
+		return "", false
+	}
+	// The last range starting at or before addr is the only one that can
+	// hold it
+	i, found := slices.BinarySearchFunc(g.ranges, addr, func(r ipRange, addr netip.Addr) int { return r.first.Compare(addr) })
+	if !found {
+		i--
+	}
+	if i < 0 || g.ranges[i].last.Less(addr) || g.ranges[i].country == "" {
+		return "", false
+	}
+	return g.ranges[i].country, true
+}
@@ -0,0 +1,181 @@
+package territory
+
+import (
+	"code/internal/models"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// Default columns the country, state and IP address are read from
+const (
+	DefaultCountryColumn = "Country"
+	DefaultStateColumn   = "State"
+)
+
+// Rule assigns a territory to leads from some countries, or some of their
+// states. Values are matched case-insensitively, so list each spelling the
+// files use, e.g. both "US" and "United States".
+type Rule struct {
+	Territory string   `yaml:"territory"`
+	Countries []string `yaml:"countries"`
+	// States, if set, narrow the rule to these states or provinces
+	States []string `yaml:"states"`
+}
+
+// Validate checks that the rule names a territory and a country or state
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.Territory) == "" {
+		return fmt.Errorf("a rule needs a territory")
+	}
+	if len(r.Countries) == 0 && len(r.States) == 0 {
+		return fmt.Errorf("rule for %s needs countries or states", r.Territory)
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a country and state
+func (r Rule) Matches(country, state string) bool {
+	if len(r.Countries) > 0 && !containsFold(r.Countries, country) {
+		return false
+	}
+	if len(r.States) > 0 && !containsFold(r.States, state) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config maps where leads are to the sales territories that work them
+type Config struct {
+	// Rules are tried in order and the first match wins, so list the rules
+	// with states before the country-wide ones
+	Rules []Rule `yaml:"rules"`
+	// Default is the territory of leads no rule matches; empty leaves them
+	// unassigned
+	Default string `yaml:"default"`
+	// CountryColumn and StateColumn name the columns read; default Country
+	// and State
+	CountryColumn string `yaml:"country_column"`
+	StateColumn   string `yaml:"state_column"`
+	// IPColumn, with GeoIPFile, looks the country up from the lead's IP
+	// address when the country column is empty
+	IPColumn  string `yaml:"ip_column"`
+	GeoIPFile string `yaml:"geoip_file"`
+}
+
+// IsZero reports whether no territories are configured
+func (c Config) IsZero() bool {
+	return len(c.Rules) == 0 && c.Default == ""
+}
+
+// Validate checks the rules, and that an IP column comes with a GeoIP file
+func (c Config) Validate() error {
+	for i, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	if (c.IPColumn == "") != (c.GeoIPFile == "") {
+		return fmt.Errorf("set both ip_column and geoip_file, or neither")
+	}
+	return nil
+}
+
+// Columns returns the source columns the config reads
+func (c Config) Columns() []string {
+	columns := []string{c.CountryColumn, c.StateColumn}
+	if columns[0] == "" {
+		columns[0] = DefaultCountryColumn
+	}
+	if columns[1] == "" {
+		columns[1] = DefaultStateColumn
+	}
+	if c.IPColumn != "" {
+		columns = append(columns, c.IPColumn)
+	}
+	return columns
+}
+
+// Assigner sets the territory of leads from their country and state
+// columns, or the country of their IP address
+type Assigner struct {
+	cfg      Config
+	geo      *GeoIP
+	assigned atomic.Int64
+	geoHits  atomic.Int64
+}
+
+// New creates an assigner. geo may be nil when the config looks up no IP
+// addresses; load it once with LoadGeoIP and share it between assigners.
+func New(cfg Config, geo *GeoIP) *Assigner {
+	if cfg.CountryColumn == "" {
+		cfg.CountryColumn = DefaultCountryColumn
+	}
+	if cfg.StateColumn == "" {
+		cfg.StateColumn = DefaultStateColumn
+	}
+	return &Assigner{cfg: cfg, geo: geo}
+}
+
+// Assign sets lead's territory if it has none; it has the shape of
+// pipeline.Transform
+func (a *Assigner) Assign(lead *models.Lead) *models.Lead {
+	if strings.TrimSpace(lead.Territory) != "" {
+		return lead
+	}
+	country, _ := lead.RawValue(a.cfg.CountryColumn)
+	state, _ := lead.RawValue(a.cfg.StateColumn)
+	if strings.TrimSpace(country) == "" && a.geo != nil && a.cfg.IPColumn != "" {
+		ip, _ := lead.RawValue(a.cfg.IPColumn)
+		if found, ok := a.geo.Country(ip); ok {
+			country = found
+			a.geoHits.Add(1)
+		}
+	}
+
+	lead.Territory = a.cfg.Default
+	for _, rule := range a.cfg.Rules {
+		if rule.Matches(country, state) {
+			lead.Territory = rule.Territory
+			break
+		}
+	}
+	if lead.Territory != "" {
+		a.assigned.Add(1)
+	}
+	return lead
+}
+
+// Count returns how many leads were assigned a territory
+func (a *Assigner) Count() int {
+	return int(a.assigned.Load())
+}
+
+// GeoHits returns how many countries were looked up from IP addresses
+func (a *Assigner) GeoHits() int {
+	return int(a.geoHits.Load())
+}
+
+// parseAddr reads an IP address, ignoring surrounding whitespace and an
+// IPv4-mapped IPv6 form
+func parseAddr(value string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,158 @@
+package territory
+
+import (
+	"code/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lead(raw map[string]string) *models.Lead {
+	l := models.NewLead("Jane Smith", "jane@example.com", "Tech Firm", "Website")
+	l.Raw = raw
+	return l
+}
+
+func TestAssigner_Assign(t *testing.T) {
+	cfg := Config{
+		Rules: []Rule{
+			{Territory: "US West", Countries: []string{"US", "United States"}, States: []string{"CA", "OR", "WA"}},
+			{Territory: "US East", Countries: []string{"US", "United States"}},
+			{Territory: "DACH", Countries: []string{"DE", "AT", "CH"}},
+		},
+		Default: "Rest of World",
+	}
+
+	t.Run("applies the first matching rule", func(t *testing.T) {
+		// Arrange
+		assigner := New(cfg, nil)
+
+		// Act
+		west := assigner.Assign(lead(map[string]string{"Country": "US", "State": "ca"}))
+		east := assigner.Assign(lead(map[string]string{"country": "united states", "state": "NY"}))
+		dach := assigner.Assign(lead(map[string]string{"Country": " de "}))
+
+		// Assert
+		assert.Equal(t, "US West", west.Territory)
+		assert.Equal(t, "US East", east.Territory)
+		assert.Equal(t, "DACH", dach.Territory)
+		assert.Equal(t, 3, assigner.Count())
+	})
+
+	t.Run("falls back to the default", func(t *testing.T) {
+		// Act
+		other := New(cfg, nil).Assign(lead(map[string]string{"Country": "BR"}))
+		unknown := New(cfg, nil).Assign(lead(nil))
+		unassigned := New(Config{Rules: cfg.Rules}, nil).Assign(lead(map[string]string{"Country": "BR"}))
+
+		// Assert
+		assert.Equal(t, "Rest of World", other.Territory)
+		assert.Equal(t, "Rest of World", unknown.Territory)
+		assert.Empty(t, unassigned.Territory)
+	})
+
+	t.Run("keeps a territory the lead already has", func(t *testing.T) {
+		// Arrange
+		assigner := New(cfg, nil)
+		l := lead(map[string]string{"Country": "DE"})
+		l.Territory = "Key Accounts"
+
+		// Act
+		assigner.Assign(l)
+
+		// Assert
+		assert.Equal(t, "Key Accounts", l.Territory)
+		assert.Equal(t, 0, assigner.Count())
+	})
+
+	t.Run("reads configured columns", func(t *testing.T) {
+		// Arrange
+		custom := cfg
+		custom.CountryColumn = "Land"
+
+		// Act
+		l := New(custom, nil).Assign(lead(map[string]string{"Land": "AT", "Country": "US"}))
+
+		// Assert
+		assert.Equal(t, "DACH", l.Territory)
+	})
+
+	t.Run("looks the country up from the IP address", func(t *testing.T) {
+		// Arrange
+		geo, err := ReadGeoIP(strings.NewReader("203.0.113.0/24,DE\n"))
+		assert.NoError(t, err)
+		withIP := cfg
+		withIP.IPColumn = "IP"
+		assigner := New(withIP, geo)
+
+		// Act
+		located := assigner.Assign(lead(map[string]string{"IP": "203.0.113.9"}))
+		given := assigner.Assign(lead(map[string]string{"IP": "203.0.113.9", "Country": "US"}))
+
+		// Assert
+		assert.Equal(t, "DACH", located.Territory)
+		assert.Equal(t, "US East", given.Territory)
+		assert.Equal(t, 1, assigner.GeoHits())
+	})
+}
+
+func TestGeoIP_Country(t *testing.T) {
+	t.Run("finds countries in ranges and networks", func(t *testing.T) {
+		// Arrange
+		data := "first,last,country\n" +
+			"1.0.0.0,1.0.0.255,AU\n" +
+			"198.51.100.0/25,FR\n" +
+			"2001:db8::,2001:db8::ffff,NL\n" +
+			"not,an,address\n"
+
+		// Act
+		geo, err := ReadGeoIP(strings.NewReader(data))
+
+		// Assert
+		assert.NoError(t, err)
+		for ip, want := range map[string]string{
+			"1.0.0.0":             "AU",
+			"1.0.0.255":           "AU",
+			"198.51.100.127":      "FR",
+			"::ffff:198.51.100.1": "FR",
+			"2001:db8::1234":      "NL",
+		} {
+			country, ok := geo.Country(ip)
+			assert.True(t, ok, ip)
+			assert.Equal(t, want, country, ip)
+		}
+		for _, ip := range []string{"1.0.1.0", "198.51.100.128", "0.0.0.1", "2001:db9::", "", "unknown"} {
+			_, ok := geo.Country(ip)
+			assert.False(t, ok, ip)
+		}
+	})
+
+	t.Run("rejects tables without ranges", func(t *testing.T) {
+		// Act
+		_, err := ReadGeoIP(strings.NewReader("network,country\n"))
+
+		// Assert
+		assert.ErrorContains(t, err, "no address ranges")
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects rules without a territory or a place", func(t *testing.T) {
+		// Act
+		unnamed := Config{Rules: []Rule{{Countries: []string{"US"}}}}.Validate()
+		empty := Config{Rules: []Rule{{Territory: "EMEA"}}}.Validate()
+
+		// Assert
+		assert.ErrorContains(t, unnamed, "rules[0]: a rule needs a territory")
+		assert.ErrorContains(t, empty, "rule for EMEA needs countries or states")
+	})
+
+	t.Run("needs ip_column and geoip_file together", func(t *testing.T) {
+		// Act
+		err := Config{Default: "EMEA", IPColumn: "IP"}.Validate()
+
+		// Assert
+		assert.ErrorContains(t, err, "set both ip_column and geoip_file")
+	})
+}
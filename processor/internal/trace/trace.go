@@ -0,0 +1,182 @@
+package trace
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events written for the pipeline's stages; a lead's outcome is written as
+// its action, e.g. created, skipped or create-error
+const (
+	EventParsed      = "parsed"
+	EventTransformed = "transformed"
+	EventValidated   = "validated"
+	EventInvalid     = "invalid"
+	// EventError is a lead the processor failed on without a result
+	EventError = "error"
+	// EventAPI is an API call made for a lead, named in Event.Call
+	EventAPI = "api"
+)
+
+// Event is one line of the trace
+type Event struct {
+	// At is when the step started
+	At    time.Time `json:"at"`
+	RunID string    `json:"runId,omitempty"`
+	// Row is the lead's row in the input, when the step knows it
+	Row   int    `json:"row,omitempty"`
+	Email string `json:"email,omitempty"`
+	Event string `json:"event"`
+	// Call is the API call of an api event: lookup, create or update
+	Call string `json:"call,omitempty"`
+	// Action is the processor's outcome for the lead, e.g. CREATE
+	Action string `json:"action,omitempty"`
+	// Reason explains a skip, e.g. only protected fields differ
+	Reason     string  `json:"reason,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+	// TotalMs is how long the lead took from being read to its outcome,
+	// queueing included
+	TotalMs float64 `json:"totalMs,omitempty"`
+	Code    string  `json:"code,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// outcomes names the events of the common actions
+var outcomes = map[string]string{
+	"CREATE": "created",
+	"UPDATE": "updated",
+	"SKIP":   "skipped",
+}
+
+// Log writes events as NDJSON, one line per event, as they happen, so a
+// run that stalls or is killed still leaves a trace up to that point. It is
+// safe for concurrent use.
+type Log struct {
+	runID string
+
+	mu     sync.Mutex
+	file   *os.File
+	count  int
+	err    error
+	closed bool
+}
+
+// Open creates, or truncates, the trace at path
+func Open(path, runID string) (*Log, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace log: %w", err)
+	}
+	return &Log{runID: runID, file: file}, nil
+}
+
+// Write appends an event. A failed write is kept for Close rather than
+// failing the run.
+func (l *Log) Write(event Event) {
+	event.RunID = l.runID
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.err != nil {
+		return
+	}
+	if _, err := l.file.Write(line); err != nil {
+		l.err = fmt.Errorf("failed to write trace log: %w", err)
+		return
+	}
+	l.count++
+}
+
+// Count returns how many events were written
+func (l *Log) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+// Close closes the file, returning the first write error
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return l.err
+	}
+	l.closed = true
+	if err := l.file.Close(); err != nil && l.err == nil {
+		l.err = fmt.Errorf("failed to write trace log: %w", err)
+	}
+	return l.err
+}
+
+// Stage records a lead finishing a pipeline stage; it has the shape of
+// pipeline.Config.Observe
+func (l *Log) Stage(stage string, item *pipeline.Item, started time.Time) {
+	now := time.Now()
+	event := Event{At: started, Row: item.Index, Email: item.Lead.Email, DurationMs: millis(now.Sub(started))}
+	switch stage {
+	case pipeline.StageRead:
+		event.Event = EventParsed
+	case pipeline.StageTransform:
+		event.Event = EventTransformed
+	case pipeline.StageValidate:
+		event.Event = EventValidated
+		if item.Result != nil {
+			event.Event = EventInvalid
+			setError(&event, item.Result.Error)
+		}
+	case pipeline.StageProcess:
+		event.TotalMs = millis(now.Sub(item.ReadAt))
+		if item.Err != nil {
+			event.Event = EventError
+			setError(&event, item.Err)
+			break
+		}
+		event.Action = item.Result.Action
+		event.Reason = item.Result.Reason
+		event.Event = outcome(item.Result.Action)
+		setError(&event, item.Result.Error)
+	default:
+		return
+	}
+	l.Write(event)
+}
+
+// Call records an API call made for a lead; it implements processor.Tracer
+func (l *Log) Call(lead *models.Lead, call string, started time.Time, err error) {
+	event := Event{At: started, Email: lead.Email, Event: EventAPI, Call: call, DurationMs: millis(time.Since(started))}
+	setError(&event, err)
+	l.Write(event)
+}
+
+// outcome names the event of an action: CREATE_ERROR becomes create-error
+func outcome(action string) string {
+	if name, ok := outcomes[action]; ok {
+		return name
+	}
+	return strings.ReplaceAll(strings.ToLower(action), "_", "-")
+}
+
+func setError(event *Event, err error) {
+	if err == nil {
+		return
+	}
+	event.Error = err.Error()
+	event.Code = string(errcode.Of(err))
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,99 @@
+package trace
+
+import (
+	"bufio"
+	"code/internal/models"
+	"code/internal/pipeline"
+	"code/internal/processor"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readEvents(t *testing.T, path string) []Event {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestLog(t *testing.T) {
+	t.Run("writes a line per stage and API call", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "events.ndjson")
+		log, err := Open(path, "run-1")
+		assert.NoError(t, err)
+		lead := models.NewLead("John Doe", "john@example.com", "Test Corp", "LinkedIn")
+		item := &pipeline.Item{Index: 3, Lead: lead, ReadAt: time.Now().Add(-time.Second)}
+
+		// Act
+		log.Stage(pipeline.StageRead, item, item.ReadAt)
+		log.Stage(pipeline.StageValidate, item, time.Now())
+		log.Call(lead, processor.CallLookup, time.Now(), nil)
+		log.Call(lead, processor.CallCreate, time.Now(), errors.New("API returned 500"))
+		item.Result = &processor.ProcessResult{Action: "CREATE_ERROR", Lead: lead, Error: errors.New("API returned 500")}
+		log.Stage(pipeline.StageProcess, item, time.Now())
+		closeErr := log.Close()
+
+		// Assert
+		assert.NoError(t, closeErr)
+		assert.Equal(t, 5, log.Count())
+		events := readEvents(t, path)
+		assert.Len(t, events, 5)
+		assert.Equal(t, []string{"parsed", "validated", "api", "api", "create-error"},
+			[]string{events[0].Event, events[1].Event, events[2].Event, events[3].Event, events[4].Event})
+		assert.Equal(t, "run-1", events[0].RunID)
+		assert.Equal(t, 3, events[0].Row)
+		assert.Equal(t, "john@example.com", events[2].Email)
+		assert.Equal(t, "lookup", events[2].Call)
+		assert.Equal(t, "API returned 500", events[3].Error)
+		assert.Equal(t, "CREATE_ERROR", events[4].Action)
+		assert.GreaterOrEqual(t, events[4].TotalMs, float64(1000))
+	})
+
+	t.Run("names common outcomes and invalid leads", func(t *testing.T) {
+		// Arrange
+		path := filepath.Join(t.TempDir(), "events.ndjson")
+		log, _ := Open(path, "")
+		invalid := &pipeline.Item{Index: 1, Lead: &models.Lead{}, Result: &processor.ProcessResult{Action: "VALIDATION_ERROR", Error: errors.New("name is required")}}
+		skipped := &pipeline.Item{Index: 2, Lead: &models.Lead{Email: "jane@techfirm.com"}, Result: &processor.ProcessResult{Action: "SKIP", Reason: "no changes"}}
+
+		// Act
+		log.Stage(pipeline.StageValidate, invalid, time.Now())
+		log.Stage(pipeline.StageProcess, skipped, time.Now())
+		log.Close()
+
+		// Assert
+		events := readEvents(t, path)
+		assert.Equal(t, "invalid", events[0].Event)
+		assert.Equal(t, "name is required", events[0].Error)
+		assert.Equal(t, "skipped", events[1].Event)
+		assert.Equal(t, "no changes", events[1].Reason)
+	})
+
+	t.Run("drops events after close", func(t *testing.T) {
+		// Arrange
+		log, _ := Open(filepath.Join(t.TempDir(), "events.ndjson"), "")
+		log.Close()
+
+		// Act
+		log.Call(&models.Lead{}, processor.CallLookup, time.Now(), nil)
+
+		// Assert
+		assert.Equal(t, 0, log.Count())
+		assert.NoError(t, log.Close())
+	})
+}
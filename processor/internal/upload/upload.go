@@ -0,0 +1,322 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	infoSuffix = ".json"
+	partSuffix = ".part"
+)
+
+// Errors returned for uploads that can't take a chunk
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrOffsetMismatch   = errors.New("offset does not match the bytes received")
+	ErrTooLarge         = errors.New("chunk goes past the upload's length")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrBusy             = errors.New("upload is already receiving a chunk")
+	ErrComplete         = errors.New("upload is complete")
+)
+
+// Info describes an upload in progress
+type Info struct {
+	ID     string `json:"id"`
+	Length int64  `json:"length"`
+	// Offset is how many bytes were received; it is not stored, but read
+	// from the file
+	Offset   int64             `json:"-"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// SubmittedBy names the token the upload was started with
+	SubmittedBy string    `json:"submittedBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// ImportID is the import the upload became once complete
+	ImportID string `json:"importId,omitempty"`
+}
+
+// Complete reports whether every byte was received
+func (i Info) Complete() bool {
+	return i.Offset == i.Length
+}
+
+// Store keeps uploads in progress in a directory, each as a partial file
+// next to a JSON file describing it. Chunks are appended at the offset the
+// client says it has reached, so an upload cut off by a dropped connection
+// resumes from the last byte written.
+type Store struct {
+	dir string
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// Open creates the directory if needed
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	return &Store{dir: dir, active: make(map[string]bool)}, nil
+}
+
+// Create starts an upload of length bytes
+func (s *Store) Create(length int64, metadata map[string]string, submittedBy string) (Info, error) {
+	info := Info{ID: uuid.NewString(), Length: length, Metadata: metadata, SubmittedBy: submittedBy, CreatedAt: time.Now().UTC()}
+	file, err := os.OpenFile(s.partPath(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to create upload: %w", err)
+	}
+	file.Close()
+	if err := s.save(info); err != nil {
+		os.Remove(s.partPath(info.ID))
+		return Info{}, err
+	}
+	return info, nil
+}
+
+// Get returns an upload with the bytes received so far
+func (s *Store) Get(id string) (Info, error) {
+	if !validID(id) {
+		return Info{}, ErrNotFound
+	}
+	data, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Info{}, ErrNotFound
+		}
+		return Info{}, fmt.Errorf("failed to read upload: %w", err)
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return Info{}, fmt.Errorf("failed to decode upload %s: %w", id, err)
+	}
+
+	if info.ImportID != "" {
+		// The file was handed over to the import
+		info.Offset = info.Length
+		return info, nil
+	}
+	stat, err := os.Stat(s.partPath(id))
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read upload: %w", err)
+	}
+	info.Offset = stat.Size()
+	return info, nil
+}
+
+// Checksum is the expected digest of a chunk
+type Checksum struct {
+	// Algorithm names the hash; only sha256 is supported
+	Algorithm string
+	Sum       []byte
+}
+
+// Append writes a chunk at offset, which must be where the upload stands.
+// When the body fails part way, the bytes read so far are kept, so the
+// client can resume after them; with a checksum, a chunk that doesn't match
+// is dropped whole.
+func (s *Store) Append(id string, offset int64, body io.Reader, checksum *Checksum) (Info, error) {
+	if !s.claim(id) {
+		return Info{}, ErrBusy
+	}
+	defer s.release(id)
+
+	info, err := s.Get(id)
+	if err != nil {
+		return Info{}, err
+	}
+	if info.ImportID != "" {
+		return info, ErrComplete
+	}
+	if offset != info.Offset {
+		return info, ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return info, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer file.Close()
+
+	var digest hash.Hash
+	writer := io.Writer(file)
+	if checksum != nil {
+		digest = sha256.New()
+		writer = io.MultiWriter(file, digest)
+	}
+	// One byte past the length is read so an oversized chunk is noticed
+	written, copyErr := io.Copy(writer, io.LimitReader(body, info.Length-offset+1))
+	switch {
+	case offset+written > info.Length:
+		copyErr = ErrTooLarge
+	case copyErr == nil && checksum != nil && !bytes.Equal(digest.Sum(nil), checksum.Sum):
+		copyErr = ErrChecksumMismatch
+	}
+	if copyErr != nil && (checksum != nil || errors.Is(copyErr, ErrTooLarge)) {
+		file.Truncate(offset)
+		written = 0
+	}
+	if err := file.Sync(); err != nil && copyErr == nil {
+		copyErr = fmt.Errorf("failed to write upload: %w", err)
+	}
+	info.Offset = offset + written
+	return info, copyErr
+}
+
+// Verify checks a complete upload against the SHA-256, in hex, the client
+// declared for the whole file
+func (s *Store) Verify(id, sha256Hex string) error {
+	file, err := os.Open(s.partPath(id))
+	if err != nil {
+		return fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer file.Close()
+	digest := sha256.New()
+	if _, err := io.Copy(digest, file); err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	if hex.EncodeToString(digest.Sum(nil)) != strings.ToLower(sha256Hex) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Path returns where a partial upload is kept, to hand it over once complete
+func (s *Store) Path(id string) string {
+	return s.partPath(id)
+}
+
+// Finish hands a complete upload over to fn, which returns the import it
+// became, once: an upload already finished returns ErrComplete
+func (s *Store) Finish(id string, fn func(Info) (string, error)) (Info, error) {
+	if !s.claim(id) {
+		return Info{}, ErrBusy
+	}
+	defer s.release(id)
+
+	info, err := s.Get(id)
+	if err != nil {
+		return Info{}, err
+	}
+	if info.ImportID != "" {
+		return info, ErrComplete
+	}
+	if !info.Complete() {
+		return info, fmt.Errorf("upload has %d of %d bytes", info.Offset, info.Length)
+	}
+	importID, err := fn(info)
+	if err != nil {
+		return info, err
+	}
+	info.ImportID = importID
+	if err := s.save(info); err != nil {
+		// fn took the file, so the upload can't be finished again; dropping
+		// it keeps it from being queued twice
+		os.Remove(s.infoPath(id))
+		return info, err
+	}
+	return info, nil
+}
+
+// Remove drops an upload and whatever of it was received
+func (s *Store) Remove(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	if err := os.Remove(s.infoPath(id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to remove upload: %w", err)
+	}
+	if err := os.Remove(s.partPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload: %w", err)
+	}
+	return nil
+}
+
+// Expire removes uploads started before the cutoff, finished or not, and
+// returns how many it removed
+func (s *Store) Expire(cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read upload directory: %w", err)
+	}
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), infoSuffix)
+		if !ok {
+			continue
+		}
+		info, err := s.Get(id)
+		if err != nil || !info.CreatedAt.Before(cutoff) || s.busy(id) {
+			continue
+		}
+		if s.Remove(id) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *Store) save(info Info) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload %s: %w", info.ID, err)
+	}
+	tmpPath := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write upload %s: %w", info.ID, err)
+	}
+	return os.Rename(tmpPath, s.infoPath(info.ID))
+}
+
+// claim lets one chunk at a time be written to an upload
+func (s *Store) claim(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[id] {
+		return false
+	}
+	s.active[id] = true
+	return true
+}
+
+func (s *Store) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, id)
+}
+
+func (s *Store) busy(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active[id]
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.dir, id+infoSuffix)
+}
+
+func (s *Store) partPath(id string) string {
+	return filepath.Join(s.dir, id+partSuffix)
+}
+
+// validID keeps IDs from the URL from reaching outside the directory
+func validID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenReader returns some bytes, then fails like a dropped connection
+type brokenReader struct {
+	data string
+	read bool
+}
+
+func (b *brokenReader) Read(p []byte) (int, error) {
+	if b.read {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b.read = true
+	return copy(p, b.data), nil
+}
+
+func TestStore(t *testing.T) {
+	t.Run("resumes after a dropped chunk at the last byte written", func(t *testing.T) {
+		// Arrange
+		store, _ := Open(t.TempDir())
+		info, _ := store.Create(10, map[string]string{"filename": "leads.csv"}, "marketing")
+
+		// Act
+		dropped, droppedErr := store.Append(info.ID, 0, &brokenReader{data: "0123"}, nil)
+		_, conflictErr := store.Append(info.ID, 0, strings.NewReader("0123456789"), nil)
+		resumed, resumedErr := store.Append(info.ID, dropped.Offset, strings.NewReader("456789"), nil)
+
+		// Assert
+		assert.ErrorIs(t, droppedErr, io.ErrUnexpectedEOF)
+		assert.Equal(t, int64(4), dropped.Offset)
+		assert.ErrorIs(t, conflictErr, ErrOffsetMismatch)
+		assert.NoError(t, resumedErr)
+		assert.True(t, resumed.Complete())
+		data, _ := os.ReadFile(store.Path(info.ID))
+		assert.Equal(t, "0123456789", string(data))
+	})
+
+	t.Run("drops chunks that fail their checksum or overrun the length", func(t *testing.T) {
+		// Arrange
+		store, _ := Open(t.TempDir())
+		info, _ := store.Create(4, nil, "")
+		wrong := sha256.Sum256([]byte("other"))
+		right := sha256.Sum256([]byte("ab"))
+
+		// Act
+		_, mismatchErr := store.Append(info.ID, 0, strings.NewReader("ab"), &Checksum{Algorithm: "sha256", Sum: wrong[:]})
+		_, overrunErr := store.Append(info.ID, 0, strings.NewReader("abcdef"), nil)
+		matched, matchedErr := store.Append(info.ID, 0, strings.NewReader("ab"), &Checksum{Algorithm: "sha256", Sum: right[:]})
+
+		// Assert
+		assert.ErrorIs(t, mismatchErr, ErrChecksumMismatch)
+		assert.ErrorIs(t, overrunErr, ErrTooLarge)
+		assert.NoError(t, matchedErr)
+		assert.Equal(t, int64(2), matched.Offset)
+	})
+
+	t.Run("finishes a complete upload once", func(t *testing.T) {
+		// Arrange
+		store, _ := Open(t.TempDir())
+		info, _ := store.Create(3, nil, "")
+		store.Append(info.ID, 0, strings.NewReader("abc"), nil)
+		sum := sha256.Sum256([]byte("abc"))
+
+		// Act
+		verifyErr := store.Verify(info.ID, hex.EncodeToString(sum[:]))
+		badErr := store.Verify(info.ID, strings.Repeat("0", 64))
+		finished, err := store.Finish(info.ID, func(Info) (string, error) { return "import-1", nil })
+		_, againErr := store.Finish(info.ID, func(Info) (string, error) { return "import-2", nil })
+		reread, _ := store.Get(info.ID)
+
+		// Assert
+		assert.NoError(t, verifyErr)
+		assert.ErrorIs(t, badErr, ErrChecksumMismatch)
+		assert.NoError(t, err)
+		assert.Equal(t, "import-1", finished.ImportID)
+		assert.ErrorIs(t, againErr, ErrComplete)
+		assert.Equal(t, "import-1", reread.ImportID)
+	})
+
+	t.Run("won't finish an incomplete upload", func(t *testing.T) {
+		// Arrange
+		store, _ := Open(t.TempDir())
+		info, _ := store.Create(3, nil, "")
+
+		// Act
+		_, err := store.Finish(info.ID, func(Info) (string, error) { return "", errors.New("not called") })
+
+		// Assert
+		assert.EqualError(t, err, "upload has 0 of 3 bytes")
+	})
+
+	t.Run("expires old uploads and rejects unknown IDs", func(t *testing.T) {
+		// Arrange
+		store, _ := Open(t.TempDir())
+		old, _ := store.Create(3, nil, "")
+		old.CreatedAt = time.Now().Add(-48 * time.Hour)
+		store.save(old)
+		recent, _ := store.Create(3, nil, "")
+
+		// Act
+		removed, err := store.Expire(time.Now().Add(-24 * time.Hour))
+		_, oldErr := store.Get(old.ID)
+		_, recentErr := store.Get(recent.ID)
+		_, traversalErr := store.Get("../../etc/passwd")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+		assert.ErrorIs(t, oldErr, ErrNotFound)
+		assert.NoError(t, recentErr)
+		assert.ErrorIs(t, traversalErr, ErrNotFound)
+	})
+}
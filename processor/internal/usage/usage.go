@@ -0,0 +1,238 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Call types API requests are counted under
+const (
+	CallLookup       = "lookup"
+	CallDomainLookup = "domain-lookup"
+	CallCreate       = "create"
+	CallUpdate       = "update"
+	CallPatch        = "patch"
+	CallAnonymize    = "anonymize"
+	CallArchive      = "archive"
+	CallAttach       = "attach"
+	CallNote         = "note"
+	CallDelete       = "delete"
+	CallExport       = "export"
+	CallVersion      = "version"
+	CallOther        = "other"
+)
+
+// CallTypes returns every call type, for validating per-type prices
+func CallTypes() []string {
+	return []string{CallLookup, CallDomainLookup, CallCreate, CallUpdate, CallPatch, CallAnonymize, CallArchive, CallAttach, CallNote, CallDelete, CallExport, CallVersion, CallOther}
+}
+
+// CallType classifies a request by its method and path
+func CallType(method, path string) string {
+	path = strings.TrimSuffix(path, "/")
+
+	switch {
+	case strings.HasSuffix(path, "/lookup"):
+		return CallLookup
+	case strings.HasSuffix(path, "/lookup-by-domain"):
+		return CallDomainLookup
+	case strings.HasSuffix(path, "/create"):
+		return CallCreate
+	case strings.HasSuffix(path, "/update"):
+		return CallUpdate
+	case strings.HasSuffix(path, "/anonymize"):
+		return CallAnonymize
+	case strings.HasSuffix(path, "/archive"):
+		return CallArchive
+	case strings.HasSuffix(path, "/attachments"):
+		return CallAttach
+	case strings.HasSuffix(path, "/notes"):
+		return CallNote
+	case strings.HasSuffix(path, "/export"):
+		return CallExport
+	case strings.HasSuffix(path, "/version"):
+		return CallVersion
+	case method == http.MethodPatch:
+		return CallPatch
+	case method == http.MethodDelete:
+		return CallDelete
+	}
+
+	return CallOther
+}
+
+// Pricing is what the API provider bills per request
+type Pricing struct {
+	Currency string `yaml:"currency"`
+	// PerCall is charged for every request without a PerType price
+	PerCall float64            `yaml:"per_call"`
+	PerType map[string]float64 `yaml:"per_type"`
+}
+
+// Validate rejects negative prices and unknown call types
+func (p Pricing) Validate() error {
+	if p.PerCall < 0 {
+		return fmt.Errorf("per_call cannot be negative")
+	}
+	for callType, price := range p.PerType {
+		if !isCallType(callType) {
+			return fmt.Errorf("per_type: unknown call type %q (allowed: %s)", callType, strings.Join(CallTypes(), ", "))
+		}
+		if price < 0 {
+			return fmt.Errorf("per_type: %s cannot be negative", callType)
+		}
+	}
+	return nil
+}
+
+// IsZero reports whether no prices are configured
+func (p Pricing) IsZero() bool {
+	return p.PerCall == 0 && len(p.PerType) == 0
+}
+
+// price returns the cost of one call of the given type
+func (p Pricing) price(callType string) float64 {
+	if price, ok := p.PerType[callType]; ok {
+		return price
+	}
+	return p.PerCall
+}
+
+// Summary is the API usage of one run
+type Summary struct {
+	Calls         map[string]int
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// TotalCalls returns the number of requests of every type
+func (s Summary) TotalCalls() int {
+	total := 0
+	for _, count := range s.Calls {
+		total += count
+	}
+	return total
+}
+
+// Cost estimates what the calls in the summary are billed under pricing
+func (s Summary) Cost(pricing Pricing) float64 {
+	cost := 0.0
+	for callType, count := range s.Calls {
+		cost += float64(count) * pricing.price(callType)
+	}
+	return cost
+}
+
+// FormatCalls renders call counts as type=count pairs in a stable order
+func (s Summary) FormatCalls() string {
+	types := make([]string, 0, len(s.Calls))
+	for callType := range s.Calls {
+		types = append(types, callType)
+	}
+	sort.Strings(types)
+
+	parts := make([]string, 0, len(types))
+	for _, callType := range types {
+		parts = append(parts, fmt.Sprintf("%s=%d", callType, s.Calls[callType]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Meter counts API requests and the bytes they transfer. Install it inside
+// any retry middleware so retried requests are counted too; providers bill
+// them like any other.
+type Meter struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	total    int
+	sent     int64
+	received int64
+
+	maxCalls  int
+	exhausted error
+}
+
+// NewMeter creates a meter with no usage recorded
+func NewMeter() *Meter {
+	return &Meter{calls: make(map[string]int)}
+}
+
+// SetMaxCalls makes the meter refuse requests beyond max, failing them with
+// err instead of sending them. Zero means no limit.
+func (m *Meter) SetMaxCalls(max int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxCalls = max
+	m.exhausted = err
+}
+
+// Middleware wraps next so every round trip is counted; it has the shape of
+// api.Middleware
+func (m *Meter) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		m.mu.Lock()
+		if m.maxCalls > 0 && m.total >= m.maxCalls {
+			m.mu.Unlock()
+			return nil, m.exhausted
+		}
+		m.total++
+		m.calls[CallType(req.Method, req.URL.Path)]++
+		if req.ContentLength > 0 {
+			m.sent += req.ContentLength
+		}
+		m.mu.Unlock()
+
+		resp, err := next.RoundTrip(req)
+		if resp != nil && resp.Body != nil {
+			resp.Body = &countingBody{ReadCloser: resp.Body, meter: m}
+		}
+		return resp, err
+	})
+}
+
+// Summary returns the usage recorded so far
+func (m *Meter) Summary() Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	calls := make(map[string]int, len(m.calls))
+	for callType, count := range m.calls {
+		calls[callType] = count
+	}
+	return Summary{Calls: calls, BytesSent: m.sent, BytesReceived: m.received}
+}
+
+// countingBody adds the bytes read from a response body to its meter
+type countingBody struct {
+	io.ReadCloser
+	meter *Meter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.meter.mu.Lock()
+		b.meter.received += int64(n)
+		b.meter.mu.Unlock()
+	}
+	return n, err
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func isCallType(callType string) bool {
+	for _, known := range CallTypes() {
+		if callType == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package usage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallType(t *testing.T) {
+	t.Run("classifies requests by method and path", func(t *testing.T) {
+		// Arrange
+		cases := []struct {
+			method, path, expected string
+		}{
+			{http.MethodGet, "/api/leads/lookup", CallLookup},
+			{http.MethodGet, "/api/leads/lookup-by-domain", CallDomainLookup},
+			{http.MethodPost, "/api/leads/create", CallCreate},
+			{http.MethodPost, "/api/leads/update", CallUpdate},
+			{http.MethodPatch, "/api/leads/42", CallPatch},
+			{http.MethodPost, "/api/leads/42/anonymize", CallAnonymize},
+			{http.MethodPost, "/api/leads/42/archive", CallArchive},
+			{http.MethodPost, "/api/leads/42/attachments", CallAttach},
+			{http.MethodPost, "/api/leads/42/notes", CallNote},
+			{http.MethodDelete, "/api/leads/42", CallDelete},
+			{http.MethodGet, "/api/version", CallVersion},
+			{http.MethodGet, "/api/health", CallOther},
+		}
+
+		for _, c := range cases {
+			// Act
+			actual := CallType(c.method, c.path)
+
+			// Assert
+			assert.Equal(t, c.expected, actual, "%s %s", c.method, c.path)
+		}
+	})
+}
+
+func TestMeter(t *testing.T) {
+	t.Run("counts calls and bytes in both directions", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"found":false}`))
+		}))
+		defer server.Close()
+
+		meter := NewMeter()
+		client := &http.Client{Transport: meter.Middleware(http.DefaultTransport)}
+
+		// Act
+		for _, req := range []*http.Request{
+			mustRequest(t, http.MethodGet, server.URL+"/api/leads/lookup?email=a%40example.com", ""),
+			mustRequest(t, http.MethodGet, server.URL+"/api/leads/lookup?email=b%40example.com", ""),
+			mustRequest(t, http.MethodPost, server.URL+"/api/leads/create", `{"name":"Jane"}`),
+		} {
+			resp, err := client.Do(req)
+			assert.NoError(t, err)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		summary := meter.Summary()
+
+		// Assert
+		assert.Equal(t, map[string]int{CallLookup: 2, CallCreate: 1}, summary.Calls)
+		assert.Equal(t, 3, summary.TotalCalls())
+		assert.Equal(t, int64(len(`{"name":"Jane"}`)), summary.BytesSent)
+		assert.Equal(t, int64(3*len(`{"found":false}`)), summary.BytesReceived)
+		assert.Equal(t, "create=1 lookup=2", summary.FormatCalls())
+	})
+}
+
+func TestMeter_SetMaxCalls(t *testing.T) {
+	t.Run("refuses requests beyond the limit", func(t *testing.T) {
+		// Arrange
+		reached := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached++
+		}))
+		defer server.Close()
+
+		exhausted := errors.New("budget exhausted")
+		meter := NewMeter()
+		meter.SetMaxCalls(2, exhausted)
+		client := &http.Client{Transport: meter.Middleware(http.DefaultTransport)}
+
+		// Act
+		var errs []error
+		for i := 0; i < 3; i++ {
+			resp, err := client.Get(server.URL + "/api/leads/lookup")
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs = append(errs, err)
+		}
+
+		// Assert
+		assert.NoError(t, errs[0])
+		assert.NoError(t, errs[1])
+		assert.ErrorIs(t, errs[2], exhausted)
+		assert.Equal(t, 2, reached)
+		assert.Equal(t, 2, meter.Summary().TotalCalls())
+	})
+}
+
+func TestSummary_Cost(t *testing.T) {
+	t.Run("prices calls per type with a default", func(t *testing.T) {
+		// Arrange
+		summary := Summary{Calls: map[string]int{CallLookup: 100, CallCreate: 10}}
+		pricing := Pricing{PerCall: 0.001, PerType: map[string]float64{CallCreate: 0.05}}
+
+		// Act
+		cost := summary.Cost(pricing)
+
+		// Assert
+		assert.InDelta(t, 0.6, cost, 1e-9)
+	})
+}
+
+func TestPricing_Validate(t *testing.T) {
+	t.Run("rejects unknown call types", func(t *testing.T) {
+		// Act
+		err := Pricing{PerType: map[string]float64{"enrich": 0.1}}.Validate()
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects negative prices", func(t *testing.T) {
+		// Act
+		err := Pricing{PerCall: -1}.Validate()
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func mustRequest(t *testing.T, method, url, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	return req
+}
@@ -0,0 +1,157 @@
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build metadata, set at build time with
+//
+//	go build -ldflags "-X code/internal/version.Version=v1.4.0 -X code/internal/version.Commit=$(git rev-parse HEAD) -X code/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// ReleasesURL is the GitHub endpoint describing the latest published release
+const ReleasesURL = "https://api.github.com/repos/mohsinfi/lead-processor/releases/latest"
+
+// Info describes the running build
+type Info struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+	Platform  string
+}
+
+// Get returns the build metadata. Commit and date fall back to the VCS
+// details the Go toolchain stamps into binaries built from a checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok && Commit == "" {
+		var modified bool
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+
+	return info
+}
+
+// Release is a published release
+type Release struct {
+	TagName string `json:"tag_name"`
+	URL     string `json:"html_url"`
+}
+
+// LatestRelease fetches the latest published release from url
+func LatestRelease(client *http.Client, url string) (*Release, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release check returned status %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+
+	return &release, nil
+}
+
+// IsNewer reports whether latest is a later release than current. Versions
+// that are not vMAJOR.MINOR.PATCH, such as dev builds, are never older.
+func IsNewer(current, latest string) bool {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	lat, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+
+	for i := range cur {
+		if lat[i] != cur[i] {
+			return lat[i] > cur[i]
+		}
+	}
+
+	return false
+}
+
+// parseVersion parses "v1.2.3" or "1.2.3", ignoring any pre-release suffix
+func parseVersion(value string) ([3]int, bool) {
+	var parts [3]int
+
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if i := strings.IndexAny(value, "-+"); i >= 0 {
+		value = value[:i]
+	}
+
+	fields := strings.Split(value, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+
+	return parts, true
+}
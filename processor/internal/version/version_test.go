@@ -0,0 +1,67 @@
+package version
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestRelease(t *testing.T) {
+	t.Run("reads the latest release tag", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/vnd.github+json", r.Header.Get("Accept"))
+			w.Write([]byte(`{"tag_name":"v1.3.0","html_url":"https://github.com/mohsinfi/lead-processor/releases/tag/v1.3.0"}`))
+		}))
+		defer server.Close()
+
+		// Act
+		release, err := LatestRelease(server.Client(), server.URL)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "v1.3.0", release.TagName)
+		assert.Contains(t, release.URL, "/releases/tag/v1.3.0")
+	})
+
+	t.Run("reports unexpected statuses", func(t *testing.T) {
+		// Arrange
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		// Act
+		_, err := LatestRelease(server.Client(), server.URL)
+
+		// Assert
+		assert.EqualError(t, err, "release check returned status 404")
+	})
+}
+
+func TestIsNewer(t *testing.T) {
+	t.Run("compares semantic versions", func(t *testing.T) {
+		// Arrange
+		cases := []struct {
+			current, latest string
+			expected        bool
+		}{
+			{"v1.2.0", "v1.3.0", true},
+			{"v1.2.9", "v1.10.0", true},
+			{"1.2.0", "v1.2.1", true},
+			{"v1.3.0", "v1.3.0", false},
+			{"v2.0.0", "v1.9.9", false},
+			{"v1.3.0-rc.1", "v1.3.0", false},
+			{"dev", "v1.3.0", false},
+			{"v1.3.0", "nightly", false},
+		}
+
+		for _, c := range cases {
+			// Act
+			actual := IsNewer(c.current, c.latest)
+
+			// Assert
+			assert.Equal(t, c.expected, actual, "%s -> %s", c.current, c.latest)
+		}
+	})
+}
@@ -0,0 +1,448 @@
+package xmlfeed
+
+import (
+	"code/internal/csv"
+	"code/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// DefaultRecordPath reads every child of the document's root element as a lead
+const DefaultRecordPath = "/*/*"
+
+// Config holds the XML settings of the config file
+type Config struct {
+	// RecordPath selects the elements that are one lead each, e.g.
+	// /feed/leads/lead or //lead (default /*/*)
+	RecordPath string `yaml:"record_path"`
+
+	// Fields maps column names to paths relative to a record, e.g.
+	// Email: contact/email or Company: "@company". Without fields, every
+	// attribute and child element of a record is a column of its name.
+	Fields map[string]string `yaml:"fields"`
+}
+
+// Validate checks the record path and field paths
+func (c *Config) Validate() error {
+	if _, err := parseRecordPath(c.recordPath()); err != nil {
+		return fmt.Errorf("record_path: %w", err)
+	}
+	for column, path := range c.Fields {
+		if strings.TrimSpace(column) == "" {
+			return fmt.Errorf("fields: a column name is empty")
+		}
+		if _, err := parseFieldPath(path); err != nil {
+			return fmt.Errorf("fields: %s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) recordPath() string {
+	if strings.TrimSpace(c.RecordPath) == "" {
+		return DefaultRecordPath
+	}
+	return strings.TrimSpace(c.RecordPath)
+}
+
+// IsFile reports whether path names an XML file, by extension
+func IsFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".xml")
+}
+
+// recordPath is a compiled record path: element names from the root, or
+// from anywhere in the document when descendant is set
+type recordPath struct {
+	steps      []string
+	descendant bool
+}
+
+// parseRecordPath compiles the supported XPath subset: /a/b, //b and * steps
+func parseRecordPath(path string) (recordPath, error) {
+	var p recordPath
+	switch {
+	case strings.HasPrefix(path, "//"):
+		p.descendant = true
+		path = path[2:]
+	case strings.HasPrefix(path, "/"):
+		path = path[1:]
+	default:
+		return p, fmt.Errorf("%q must start with / or //", path)
+	}
+	for _, step := range strings.Split(path, "/") {
+		if !validName(step) {
+			return p, fmt.Errorf("unsupported step %q (use element names or *)", step)
+		}
+		p.steps = append(p.steps, localName(step))
+	}
+	return p, nil
+}
+
+// matches reports whether the open elements, root first, end at a record
+func (p recordPath) matches(stack []string) bool {
+	if len(stack) < len(p.steps) || (!p.descendant && len(stack) != len(p.steps)) {
+		return false
+	}
+	offset := len(stack) - len(p.steps)
+	for i, step := range p.steps {
+		if step != "*" && step != stack[offset+i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldPath is a compiled field path: child element names, then optionally
+// an attribute
+type fieldPath struct {
+	steps []string
+	attr  string
+}
+
+// parseFieldPath compiles the supported XPath subset: a/b, a/@x, @x, * and .
+func parseFieldPath(path string) (fieldPath, error) {
+	var p fieldPath
+	path = strings.TrimSpace(path)
+	if path == "" || path == "." {
+		return p, nil
+	}
+	if strings.HasPrefix(path, "/") {
+		return p, fmt.Errorf("%q must be relative to the record", path)
+	}
+	steps := strings.Split(path, "/")
+	for i, step := range steps {
+		if strings.HasPrefix(step, "@") && i == len(steps)-1 && validName(step[1:]) && step != "@*" {
+			p.attr = localName(step[1:])
+			continue
+		}
+		if step == "." {
+			continue
+		}
+		if !validName(step) {
+			return p, fmt.Errorf("unsupported step %q in %q (use element names, *, . or a final @attribute)", step, path)
+		}
+		p.steps = append(p.steps, localName(step))
+	}
+	return p, nil
+}
+
+// validName accepts element names, with or without a namespace prefix, and *
+func validName(name string) bool {
+	if name == "*" {
+		return true
+	}
+	return name != "" && !strings.ContainsAny(name, "[]()@/|=\"' ")
+}
+
+// localName drops a namespace prefix; names are matched without namespaces
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// node is a record element with its attributes, children and text
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	children []*node
+	text     strings.Builder
+}
+
+// content returns the element's text, including that of its children
+func (n *node) content() string {
+	var b strings.Builder
+	n.appendText(&b)
+	return strings.TrimSpace(b.String())
+}
+
+func (n *node) appendText(b *strings.Builder) {
+	b.WriteString(n.text.String())
+	for _, child := range n.children {
+		child.appendText(b)
+	}
+}
+
+// attr returns the value of the attribute with the local name, if present
+func (n *node) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value), true
+		}
+	}
+	return "", false
+}
+
+// find returns the first value at path below n
+func (n *node) find(path fieldPath) string {
+	if len(path.steps) == 0 {
+		if path.attr != "" {
+			value, _ := n.attr(path.attr)
+			return value
+		}
+		return n.content()
+	}
+	rest := fieldPath{steps: path.steps[1:], attr: path.attr}
+	for _, child := range n.children {
+		if path.steps[0] != "*" && path.steps[0] != child.name {
+			continue
+		}
+		if value := child.find(rest); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// columns lists the attributes and child elements of a record, in document order
+func (n *node) columns() []string {
+	var names []string
+	for _, a := range n.attrs {
+		if a.Name.Space != "xmlns" && a.Name.Local != "xmlns" {
+			names = append(names, a.Name.Local)
+		}
+	}
+	for _, child := range n.children {
+		names = append(names, child.name)
+	}
+	return names
+}
+
+// column is one column of a feed and where its value is read from
+type column struct {
+	name string
+	path fieldPath
+}
+
+// Reader reads the records of an XML feed as rows
+type Reader struct {
+	file    *os.File
+	decoder *xml.Decoder
+	records recordPath
+	columns []column
+	stack   []string
+}
+
+// Open opens the feed at path. Without configured fields, the file is read
+// once first to find the columns its records use.
+func Open(path string, cfg Config) (*Reader, error) {
+	records, err := parseRecordPath(cfg.recordPath())
+	if err != nil {
+		return nil, fmt.Errorf("record_path: %w", err)
+	}
+
+	var columns []column
+	if len(cfg.Fields) > 0 {
+		names := make([]string, 0, len(cfg.Fields))
+		for name := range cfg.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p, err := parseFieldPath(cfg.Fields[name])
+			if err != nil {
+				return nil, fmt.Errorf("fields: %s: %w", name, err)
+			}
+			columns = append(columns, column{name: name, path: p})
+		}
+	} else {
+		if columns, err = discoverColumns(path, records); err != nil {
+			return nil, err
+		}
+	}
+
+	r, err := open(path, records)
+	if err != nil {
+		return nil, err
+	}
+	r.columns = columns
+	return r, nil
+}
+
+func open(path string, records recordPath) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := xml.NewDecoder(file)
+	decoder.CharsetReader = charsetReader
+	return &Reader{file: file, decoder: decoder, records: records}, nil
+}
+
+// charsetReader decodes feeds declared in encodings other than UTF-8, such
+// as ISO-8859-1
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := ianaindex.IANA.Encoding(label)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unsupported XML encoding %q", label)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// discoverColumns reads every record for the attribute and element names
+// they use, in order of first appearance
+func discoverColumns(path string, records recordPath) ([]column, error) {
+	r, err := open(path, records)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	seen := make(map[string]bool)
+	var columns []column
+	for {
+		record, err := r.nextRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range record.columns() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			p := fieldPath{steps: []string{name}}
+			if _, ok := record.attr(name); ok {
+				p = fieldPath{attr: name}
+			}
+			columns = append(columns, column{name: name, path: p})
+		}
+	}
+	return columns, nil
+}
+
+// Header returns the column names
+func (r *Reader) Header() []string {
+	names := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Next returns the next record's values, or io.EOF after the last one
+func (r *Reader) Next() ([]string, error) {
+	record, err := r.nextRecord()
+	if err != nil {
+		return nil, err
+	}
+	row := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		row[i] = record.find(c.path)
+	}
+	return row, nil
+}
+
+// Close closes the file
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// nextRecord reads up to the end of the next record element, keeping only
+// that element in memory
+func (r *Reader) nextRecord() (*node, error) {
+	var open []*node
+	for {
+		tok, err := r.decoder.Token()
+		if err == io.EOF {
+			if len(r.stack) > 0 {
+				return nil, fmt.Errorf("invalid XML: unexpected end of file inside <%s>", r.stack[len(r.stack)-1])
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			r.stack = append(r.stack, t.Name.Local)
+			if len(open) > 0 {
+				child := &node{name: t.Name.Local, attrs: t.Attr}
+				parent := open[len(open)-1]
+				parent.children = append(parent.children, child)
+				open = append(open, child)
+			} else if r.records.matches(r.stack) {
+				open = append(open, &node{name: t.Name.Local, attrs: t.Attr})
+			}
+		case xml.EndElement:
+			r.stack = r.stack[:len(r.stack)-1]
+			if len(open) == 1 {
+				return open[0], nil
+			}
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			if len(open) > 0 {
+				open[len(open)-1].text.Write(t)
+			}
+		}
+	}
+}
+
+// Source is an XML feed read as a lead source. Its columns are mapped to
+// lead fields like a CSV file's header.
+type Source struct {
+	path   string
+	cfg    Config
+	reader *csv.CSVReader
+}
+
+// NewSource returns the feed at path as a lead source, mapping columns
+// with reader
+func NewSource(path string, cfg Config, reader *csv.CSVReader) *Source {
+	return &Source{path: path, cfg: cfg, reader: reader}
+}
+
+// Name returns the file's path
+func (s *Source) Name() string {
+	return s.path
+}
+
+// Hash returns the SHA-256 of the file's content
+func (s *Source) Hash() (string, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadHeader returns the column names
+func (s *Source) ReadHeader() ([]string, error) {
+	r, err := Open(s.path, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.Header(), nil
+}
+
+// StreamLeads streams the feed's leads, one record at a time
+func (s *Source) StreamLeads(emit func(*models.Lead) error) error {
+	r, err := Open(s.path, s.cfg)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.reader.StreamRows(r.Header(), r.Next, emit)
+}
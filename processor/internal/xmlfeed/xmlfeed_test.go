@@ -0,0 +1,166 @@
+package xmlfeed
+
+import (
+	"code/internal/csv"
+	"code/internal/models"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const feed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:p="urn:partner">
+  <generated>2026-10-16</generated>
+  <leads>
+    <p:lead id="1" source="Webinar">
+      <name>Jane Smith</name>
+      <contact><email>jane@techfirm.com</email><phone>555-0100</phone></contact>
+      <company>TechFirm</company>
+    </p:lead>
+    <p:lead id="2" source="Referral">
+      <name>John Doe</name>
+      <contact><email>john@acme.com</email></contact>
+    </p:lead>
+  </leads>
+</feed>`
+
+func writeFeed(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "feed.xml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func readAll(t *testing.T, r *Reader) [][]string {
+	var rows [][]string
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows
+		}
+		assert.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Run("reads configured fields of each record", func(t *testing.T) {
+		// Arrange
+		cfg := Config{
+			RecordPath: "/feed/leads/lead",
+			Fields:     map[string]string{"Name": "name", "Email": "contact/email", "Source": "@source", "Company": "company"},
+		}
+
+		// Act
+		r, err := Open(writeFeed(t, feed), cfg)
+		assert.NoError(t, err)
+		defer r.Close()
+		rows := readAll(t, r)
+
+		// Assert
+		assert.Equal(t, []string{"Company", "Email", "Name", "Source"}, r.Header())
+		assert.Equal(t, [][]string{
+			{"TechFirm", "jane@techfirm.com", "Jane Smith", "Webinar"},
+			{"", "john@acme.com", "John Doe", "Referral"},
+		}, rows)
+	})
+
+	t.Run("finds records anywhere with //", func(t *testing.T) {
+		// Arrange
+		cfg := Config{RecordPath: "//lead", Fields: map[string]string{"Email": "*/email"}}
+
+		// Act
+		r, _ := Open(writeFeed(t, feed), cfg)
+		defer r.Close()
+		rows := readAll(t, r)
+
+		// Assert
+		assert.Equal(t, [][]string{{"jane@techfirm.com"}, {"john@acme.com"}}, rows)
+	})
+
+	t.Run("uses attributes and child elements as columns without fields", func(t *testing.T) {
+		// Arrange
+		content := `<leads>
+  <lead id="1"><Name>Jane Smith</Name><Email>jane@techfirm.com</Email></lead>
+  <lead id="2"><Name>John Doe</Name><Company>Acme</Company></lead>
+</leads>`
+
+		// Act
+		r, err := Open(writeFeed(t, content), Config{})
+		assert.NoError(t, err)
+		defer r.Close()
+		rows := readAll(t, r)
+
+		// Assert
+		assert.Equal(t, []string{"id", "Name", "Email", "Company"}, r.Header())
+		assert.Equal(t, [][]string{
+			{"1", "Jane Smith", "jane@techfirm.com", ""},
+			{"2", "John Doe", "", "Acme"},
+		}, rows)
+	})
+
+	t.Run("decodes feeds declared as ISO-8859-1", func(t *testing.T) {
+		// Arrange
+		content := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<leads><lead><Name>Ren\xe9e M\xfcller</Name></lead></leads>"
+
+		// Act
+		r, _ := Open(writeFeed(t, content), Config{})
+		defer r.Close()
+		rows := readAll(t, r)
+
+		// Assert
+		assert.Equal(t, [][]string{{"Renée Müller"}}, rows)
+	})
+
+	t.Run("reports malformed XML", func(t *testing.T) {
+		// Arrange
+		r, _ := Open(writeFeed(t, "<leads><lead><Name>Jane</lead></leads>"), Config{Fields: map[string]string{"Name": "Name"}})
+		defer r.Close()
+
+		// Act
+		_, err := r.Next()
+
+		// Assert
+		assert.ErrorContains(t, err, "invalid XML")
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects relative record paths and predicates", func(t *testing.T) {
+		// Arrange
+		relative := Config{RecordPath: "feed/lead"}
+		predicate := Config{Fields: map[string]string{"Email": "contact[@type='work']"}}
+
+		// Assert
+		assert.ErrorContains(t, relative.Validate(), "must start with / or //")
+		assert.ErrorContains(t, predicate.Validate(), "unsupported step")
+	})
+}
+
+func TestSource(t *testing.T) {
+	t.Run("maps columns to lead fields", func(t *testing.T) {
+		// Arrange
+		reader := csv.NewCSVReader()
+		reader.SetMapping(map[string]string{"email": "work_email"})
+		cfg := Config{
+			RecordPath: "/feed/leads/*",
+			Fields:     map[string]string{"Name": "name", "work_email": "contact/email", "Company": "company", "Source": "@source"},
+		}
+		source := NewSource(writeFeed(t, feed), cfg, reader)
+
+		// Act
+		var leads []*models.Lead
+		err := source.StreamLeads(func(lead *models.Lead) error {
+			leads = append(leads, lead)
+			return nil
+		})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Len(t, leads, 2)
+		assert.Equal(t, "jane@techfirm.com", leads[0].Email)
+		assert.Equal(t, "Webinar", leads[0].Source)
+	})
+}
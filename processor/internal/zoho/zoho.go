@@ -0,0 +1,360 @@
+package zoho
+
+import (
+	"bytes"
+	"code/internal/errcode"
+	"code/internal/fieldmap"
+	"code/internal/models"
+	"code/internal/processor"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AccountsURL is Zoho's US accounts server; other data centres have their
+// own, e.g. https://accounts.zoho.com.au
+const AccountsURL = "https://accounts.zoho.com"
+
+// apiVersion is the CRM API version requests are made against
+const apiVersion = "v6"
+
+// ErrRecordNotFound is returned when Zoho has no lead with the requested ID
+var ErrRecordNotFound = errors.New("record not found")
+
+// defaultFields are the Leads module fields lead fields are stored in, unless
+// Config.Fields names others. Name is split into First_Name and Last_Name.
+var defaultFields = map[string]string{
+	"firstName": "First_Name",
+	"lastName":  "Last_Name",
+	"email":     "Email",
+	"company":   "Company",
+	"source":    "Lead_Source",
+	"status":    "Lead_Status",
+}
+
+// mappableFields are the lead fields Config.Fields can map
+var mappableFields = []string{"company", "source", "status", "territory", "language", "consentGiven", "consentTimestamp", "consentSource"}
+
+// Config holds the Zoho CRM settings of the config file
+type Config struct {
+	// ClientID and ClientSecret identify the self-client created in the Zoho
+	// API console; $ZOHO_CLIENT_SECRET is used when the secret is empty
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+
+	// RefreshToken is exchanged for access tokens; $ZOHO_REFRESH_TOKEN is
+	// used when empty. It needs the ZohoCRM.modules.leads.ALL and
+	// ZohoCRM.coql.READ scopes.
+	RefreshToken string `yaml:"refresh_token"`
+
+	// AccountsURL is the accounts server of the organization's data centre
+	AccountsURL string `yaml:"accounts_url"`
+
+	// APIURL overrides the API domain the token response names
+	APIURL string `yaml:"api_url"`
+
+	// Fields maps lead fields to Leads module field API names, for fields
+	// kept somewhere other than the standard ones
+	Fields map[string]string `yaml:"fields"`
+}
+
+// Validate checks the field mapping, normalising field names
+func (c *Config) Validate() error {
+	if c.AccountsURL != "" {
+		if u, err := url.Parse(c.AccountsURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("accounts_url: %q is not a URL", c.AccountsURL)
+		}
+	}
+
+	fields, err := fieldmap.Normalize(c.Fields, mappableFields)
+	if err != nil {
+		return fmt.Errorf("fields: %w", err)
+	}
+	c.Fields = fields
+	return nil
+}
+
+// tokenSource exchanges the refresh token for access tokens, reusing a token
+// until shortly before it expires
+type tokenSource struct {
+	cfg    Config
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	apiDomain string
+	expiry    time.Time
+}
+
+// Token returns a valid access token and the API domain it is for
+func (t *tokenSource) Token() (string, string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Now().Before(t.expiry.Add(-time.Minute)) {
+		return t.token, t.apiDomain, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {t.cfg.RefreshToken},
+		"client_id":     {t.cfg.ClientID},
+		"client_secret": {t.cfg.ClientSecret},
+	}
+	resp, err := t.client.PostForm(strings.TrimSuffix(t.cfg.AccountsURL, "/")+"/oauth/v2/token", form)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get Zoho access token: %w", errcode.Network(err))
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		APIDomain   string `json:"api_domain"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to get Zoho access token: %w", &errcode.StatusError{Status: resp.StatusCode})
+	}
+	// Zoho answers a bad client or revoked refresh token with 200 and an error
+	if body.Error != "" {
+		return "", "", fmt.Errorf("failed to get Zoho access token: %s: %w", body.Error, errcode.ErrAuth)
+	}
+	if decodeErr != nil || body.AccessToken == "" {
+		return "", "", fmt.Errorf("failed to get Zoho access token: malformed response")
+	}
+
+	t.token = body.AccessToken
+	t.apiDomain = strings.TrimSuffix(body.APIDomain, "/")
+	t.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return t.token, t.apiDomain, nil
+}
+
+// Invalidate drops the cached token, e.g. after Zoho refused it
+func (t *tokenSource) Invalidate() {
+	t.mu.Lock()
+	t.token = ""
+	t.mu.Unlock()
+}
+
+// Client stores leads in the Zoho CRM Leads module, implementing
+// processor.APIClient. Leads are found with a COQL query on Email and written
+// with the upsert API, which matches existing records by Email too.
+type Client struct {
+	apiURL string
+	fields fieldmap.Mapping
+	tokens *tokenSource
+	client *http.Client
+}
+
+// New creates a Zoho CRM client sending requests through httpClient
+func New(cfg Config, httpClient *http.Client) (*Client, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.RefreshToken == "" {
+		return nil, fmt.Errorf("zoho: client_id, client_secret and refresh_token are required (or ZOHO_CLIENT_SECRET and ZOHO_REFRESH_TOKEN)")
+	}
+	if cfg.AccountsURL == "" {
+		cfg.AccountsURL = AccountsURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &Client{
+		apiURL: strings.TrimSuffix(cfg.APIURL, "/"),
+		fields: fieldmap.New(defaultFields, cfg.Fields),
+		tokens: &tokenSource{cfg: cfg, client: httpClient},
+		client: httpClient,
+	}, nil
+}
+
+// LookupLead finds the lead with this email with a COQL query
+func (c *Client) LookupLead(email string) (*processor.LookupResponse, error) {
+	columns := append([]string{"id", "Created_Time", "Modified_Time"}, c.fields.Names()...)
+	query := fmt.Sprintf("select %s from Leads where %s = '%s' limit 1", strings.Join(columns, ", "), c.fields.Fields["email"], escapeCOQL(email))
+	var result struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	found, err := c.do(http.MethodPost, "/coql", map[string]string{"select_query": query}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if !found || len(result.Data) == 0 {
+		return &processor.LookupResponse{Found: false}, nil
+	}
+	return &processor.LookupResponse{Found: true, Lead: c.toLead(result.Data[0])}, nil
+}
+
+// escapeCOQL escapes a value for a single-quoted COQL string
+func escapeCOQL(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+// CreateLead upserts the lead, so a lead added since the lookup is updated
+// rather than duplicated
+func (c *Client) CreateLead(lead *models.Lead) (*models.Lead, error) {
+	return c.upsert(lead)
+}
+
+// UpdateLead upserts the lead, matching the existing record by email
+func (c *Client) UpdateLead(lead *models.Lead) (*models.Lead, error) {
+	return c.upsert(lead)
+}
+
+// DeleteLead moves a lead to the recycle bin
+func (c *Client) DeleteLead(id string) error {
+	_, err := c.do(http.MethodDelete, "/Leads?ids="+url.QueryEscape(id), nil, nil)
+	return err
+}
+
+func (c *Client) upsert(lead *models.Lead) (*models.Lead, error) {
+	payload := map[string]interface{}{
+		"data":                   []map[string]interface{}{c.fields.Payload(lead)},
+		"duplicate_check_fields": []string{c.fields.Fields["email"]},
+	}
+	var result struct {
+		Data []struct {
+			Code    string `json:"code"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details struct {
+				ID           string `json:"id"`
+				CreatedTime  string `json:"Created_Time"`
+				ModifiedTime string `json:"Modified_Time"`
+			} `json:"details"`
+		} `json:"data"`
+	}
+	if _, err := c.do(http.MethodPost, "/Leads/upsert", payload, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("Zoho upsert response did not include a record")
+	}
+
+	// Records are rejected one by one, with 200 or 202 for the request
+	outcome := result.Data[0]
+	if outcome.Status != "success" {
+		return nil, fmt.Errorf("zoho: %s: %s: %w", outcome.Code, outcome.Message, errcode.ErrValidation)
+	}
+
+	written := *lead
+	written.ID = outcome.Details.ID
+	if created, err := time.Parse(time.RFC3339, outcome.Details.CreatedTime); err == nil {
+		written.CreatedAt = created
+	}
+	if modified, err := time.Parse(time.RFC3339, outcome.Details.ModifiedTime); err == nil {
+		written.UpdatedAt = &modified
+	}
+	return &written, nil
+}
+
+// toLead converts a COQL result row to a lead
+func (c *Client) toLead(row map[string]interface{}) *models.Lead {
+	text := func(key string) string {
+		switch value := row[key].(type) {
+		case string:
+			return value
+		case bool:
+			return strconv.FormatBool(value)
+		}
+		return ""
+	}
+
+	lead := c.fields.Lead(text)
+	lead.ID = text("id")
+	if created, err := time.Parse(time.RFC3339, text("Created_Time")); err == nil {
+		lead.CreatedAt = created
+	}
+	if modified, err := time.Parse(time.RFC3339, text("Modified_Time")); err == nil {
+		lead.UpdatedAt = &modified
+	}
+	return lead
+}
+
+// do sends a request to the CRM API and decodes the response into v,
+// reporting false when Zoho answers 204 No Content, as COQL does for no
+// matches. A refused token is refreshed and the request sent once more.
+func (c *Client) do(method, path string, payload interface{}, v interface{}) (bool, error) {
+	var data []byte
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode request: %w", err)
+		}
+		data = encoded
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, apiDomain, err := c.tokens.Token()
+		if err != nil {
+			return false, err
+		}
+		apiURL := c.apiURL
+		if apiURL == "" {
+			apiURL = apiDomain
+		}
+
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequest(method, fmt.Sprintf("%s/crm/%s%s", apiURL, apiVersion, path), body)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Zoho-oauthtoken "+token)
+		if data != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("failed to make request: %w", errcode.Network(err))
+		}
+		found, retry, err := c.read(resp, v)
+		if retry && attempt == 0 {
+			c.tokens.Invalidate()
+			continue
+		}
+		return found, err
+	}
+}
+
+// read decodes a response, reporting whether a refused token should be
+// refreshed and the request retried
+func (c *Client) read(resp *http.Response, v interface{}) (found, retry bool, err error) {
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return false, false, nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		return false, true, &errcode.StatusError{Status: resp.StatusCode}
+	case resp.StatusCode == http.StatusNotFound:
+		return false, false, ErrRecordNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		var body struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Message != "" {
+			return false, false, fmt.Errorf("zoho: %s: %s: %w", body.Code, body.Message, &errcode.StatusError{Status: resp.StatusCode})
+		}
+		return false, false, &errcode.StatusError{Status: resp.StatusCode}
+	}
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return false, false, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return true, false, nil
+}
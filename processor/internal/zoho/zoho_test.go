@@ -0,0 +1,194 @@
+package zoho
+
+import (
+	"code/internal/errcode"
+	"code/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeZoho issues tokens and answers COQL and upsert requests
+type fakeZoho struct {
+	server        *httptest.Server
+	tokenRequests int64
+	// refuse makes the first CRM request fail with an expired token
+	refuse   bool
+	query    string
+	upserted map[string]interface{}
+}
+
+func newFakeZoho(t *testing.T) *fakeZoho {
+	f := &fakeZoho{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/v2/token" {
+			n := atomic.AddInt64(&f.tokenRequests, 1)
+			r.ParseForm()
+			if r.Form.Get("refresh_token") != "1000.refresh" {
+				w.Write([]byte(`{"error":"invalid_code"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": fmt.Sprintf("1000.access%d", n),
+				"api_domain":   f.server.URL,
+				"expires_in":   3600,
+			})
+			return
+		}
+
+		if f.refuse {
+			f.refuse = false
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":"INVALID_TOKEN","message":"invalid oauth token"}`))
+			return
+		}
+		assert.Contains(t, r.Header.Get("Authorization"), "Zoho-oauthtoken 1000.access")
+
+		switch r.URL.Path {
+		case "/crm/v6/coql":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			f.query = body["select_query"]
+			if strings.Contains(f.query, "Email = 'jane@techfirm.com'") {
+				w.Write([]byte(`{"data":[{"id":"4150868000000231001","First_Name":"Jane","Last_Name":"Smith","Email":"jane@techfirm.com","Company":"Tech Firm","Lead_Source":"LinkedIn","Lead_Status":"contacted","Created_Time":"2026-01-02T03:04:05+10:00"}],"info":{"count":1,"more_records":false}}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case "/crm/v6/Leads/upsert":
+			var body struct {
+				Data                 []map[string]interface{} `json:"data"`
+				DuplicateCheckFields []string                 `json:"duplicate_check_fields"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, []string{"Email"}, body.DuplicateCheckFields)
+			f.upserted = body.Data[0]
+			if f.upserted["Lead_Source"] == "" {
+				w.Write([]byte(`{"data":[{"code":"MANDATORY_NOT_FOUND","status":"error","message":"required field not found","details":{}}]}`))
+				return
+			}
+			w.Write([]byte(`{"data":[{"code":"SUCCESS","status":"success","message":"record added","action":"insert","details":{"id":"4150868000000231002","Created_Time":"2026-10-16T10:00:00+10:00"}}]}`))
+		}
+	}))
+	return f
+}
+
+func (f *fakeZoho) client(t *testing.T) *Client {
+	client, err := New(Config{ClientID: "1000.client", ClientSecret: "secret", RefreshToken: "1000.refresh", AccountsURL: f.server.URL}, nil)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestClient_LookupLead(t *testing.T) {
+	t.Run("finds the lead by email with COQL", func(t *testing.T) {
+		// Arrange
+		fake := newFakeZoho(t)
+		defer fake.server.Close()
+		client := fake.client(t)
+
+		// Act
+		resp, err := client.LookupLead("jane@techfirm.com")
+		missing, missingErr := client.LookupLead("nobody@techfirm.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NoError(t, missingErr)
+		assert.True(t, resp.Found)
+		assert.Equal(t, "4150868000000231001", resp.Lead.ID)
+		assert.Equal(t, "Jane Smith", resp.Lead.Name)
+		assert.Equal(t, "LinkedIn", resp.Lead.Source)
+		assert.Equal(t, "contacted", resp.Lead.Status)
+		assert.False(t, missing.Found)
+		assert.Equal(t, int64(1), fake.tokenRequests, "the access token is reused")
+	})
+
+	t.Run("escapes quotes in the query", func(t *testing.T) {
+		// Arrange
+		fake := newFakeZoho(t)
+		defer fake.server.Close()
+
+		// Act
+		fake.client(t).LookupLead("o'brien@techfirm.com")
+
+		// Assert
+		assert.Contains(t, fake.query, `Email = 'o\'brien@techfirm.com'`)
+	})
+
+	t.Run("refreshes a refused token once", func(t *testing.T) {
+		// Arrange
+		fake := newFakeZoho(t)
+		defer fake.server.Close()
+		fake.refuse = true
+
+		// Act
+		resp, err := fake.client(t).LookupLead("jane@techfirm.com")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, resp.Found)
+		assert.Equal(t, int64(2), fake.tokenRequests)
+	})
+
+	t.Run("classifies a revoked refresh token", func(t *testing.T) {
+		// Arrange
+		fake := newFakeZoho(t)
+		defer fake.server.Close()
+		client, _ := New(Config{ClientID: "1000.client", ClientSecret: "secret", RefreshToken: "revoked", AccountsURL: fake.server.URL}, nil)
+
+		// Act
+		_, err := client.LookupLead("jane@techfirm.com")
+
+		// Assert
+		assert.ErrorIs(t, err, errcode.ErrAuth)
+	})
+}
+
+func TestClient_Upsert(t *testing.T) {
+	t.Run("upserts the lead by email", func(t *testing.T) {
+		// Arrange
+		fake := newFakeZoho(t)
+		defer fake.server.Close()
+
+		// Act
+		created, err := fake.client(t).CreateLead(models.NewLead("Mary Ann Lee", "mary@acme.com", "Acme", "Webinar"))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "4150868000000231002", created.ID)
+		assert.Equal(t, "Mary Ann", fake.upserted["First_Name"])
+		assert.Equal(t, "Lee", fake.upserted["Last_Name"])
+		assert.Equal(t, "Webinar", fake.upserted["Lead_Source"])
+		assert.NotContains(t, fake.upserted, "Lead_Status", "an empty status is left alone")
+	})
+
+	t.Run("reports rejected records", func(t *testing.T) {
+		// Arrange
+		fake := newFakeZoho(t)
+		defer fake.server.Close()
+
+		// Act
+		_, err := fake.client(t).UpdateLead(models.NewLead("Mary Lee", "mary@acme.com", "Acme", ""))
+
+		// Assert
+		assert.ErrorIs(t, err, errcode.ErrValidation)
+		assert.ErrorContains(t, err, "MANDATORY_NOT_FOUND")
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("rejects fields that cannot be mapped", func(t *testing.T) {
+		// Arrange
+		cfg := Config{Fields: map[string]string{"email": "Secondary_Email"}}
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		assert.Error(t, err)
+	})
+}